@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// admissionFilterPattern selects the audit events of requests rejected by PodSecurity or a
+// ValidatingAdmissionPolicy, and those annotated with the violations of policies in audit
+// mode; the events are parsed client-side
+const admissionFilterPattern = `?"violates PodSecurity" ?"would violate PodSecurity" ?"ValidatingAdmissionPolicy" ?"validation_failure"`
+
+var admissionOptions reportOptions
+
+var admissionCmd = &cobra.Command{
+	Use:   "admission [cluster-name]",
+	Short: "Report PodSecurity and ValidatingAdmissionPolicy violations per namespace and policy",
+	Long: `Find the requests violating a Pod Security Standards level or a ValidatingAdmissionPolicy
+in the audit log, and count them per namespace, policy and action:
+
+  deny   the request was rejected (PodSecurity enforce mode, VAP Deny action)
+  audit  the request was let through and annotated (PodSecurity audit mode, VAP Audit action)
+
+Before switching a namespace to enforce, or a policy binding to Deny, the audit violations
+list the workloads that would break. Warnings returned to clients are not logged.
+
+Requires audit logging to be enabled on the cluster.`,
+	Example: `  ekslogs admission my-cluster -s -7d    # Violations of the past week
+  ekslogs admission my-cluster -o json   # One JSON object per namespace, policy and action`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &admissionOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		entries, err := r.collect([]string{"audit"}, r.start, r.end, admissionFilterPattern, 0)
+		if err != nil {
+			return err
+		}
+
+		var denials []log.AdmissionDenial
+		for _, entry := range entries {
+			denials = append(denials, log.ParseAdmissionDenials(entry)...)
+		}
+		r.verbosef("Found %d policy violations in %d audit events", len(denials), len(entries))
+
+		groups := log.GroupAdmissionDenials(denials)
+		if r.format == log.OutputFormatJSON {
+			if err := printAdmissionJSON(os.Stdout, groups); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printAdmissionReport(r.output(), groups)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(admissionCmd)
+
+	admissionOptions.documents = true
+	admissionOptions.addFlags(admissionCmd, "one JSON object per namespace, policy and action")
+}
+
+// printAdmissionReport writes a table of the violations per namespace, policy and action,
+// the namespaces with the most violations first
+func printAdmissionReport(w io.Writer, groups []log.AdmissionGroup) {
+	if len(groups) == 0 {
+		_, _ = fmt.Fprintln(w, "No PodSecurity or ValidatingAdmissionPolicy violations found.")
+		return
+	}
+
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "NAMESPACE\tCOUNT\tACTION\tCONTROLLER\tPOLICY\tREASON")
+	for _, group := range groups {
+		policy := group.Policy
+		if group.Binding != "" {
+			policy += " (" + group.Binding + ")"
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\t%s\n", orDash(group.Namespace), group.Count, group.Action, group.Controller, orDash(policy), group.Reason)
+	}
+	_ = table.Flush()
+}
+
+// printAdmissionJSON writes one JSON object per namespace, policy and action
+func printAdmissionJSON(w io.Writer, groups []log.AdmissionGroup) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, group := range groups {
+		if err := encoder.Encode(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
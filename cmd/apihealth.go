@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/filter"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var apiHealthOptions reportOptions
+
+var apiHealthCmd = &cobra.Command{
+	Use:   "api-health [cluster-name]",
+	Short: "Report unavailable APIServices, OpenAPI aggregation failures and CRD schema errors",
+	Long: `Scan the API server log for the problems of aggregated APIs and CustomResourceDefinitions
+and report the APIService or CRD affected, with when it was first and last seen:
+
+  APIService unavailable  the backend of an APIService fails its availability checks
+  OpenAPI aggregation     the OpenAPI spec of an APIService cannot be downloaded or merged
+  CRD schema              the schema of a CRD is not structural or its OpenAPI spec fails to build
+  discovery               the discovery document of an APIService cannot be fetched
+
+These break discovery, and so "kubectl get" and controllers, for every user of the cluster.
+The same messages can be followed with the aggregated-api-issues preset:
+ekslogs my-cluster -p aggregated-api-issues -f`,
+	Example: `  ekslogs api-health my-cluster -s -1d    # Problems of the past day
+  ekslogs api-health my-cluster -o json   # One JSON object per problem and object`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &apiHealthOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		preset, _ := filter.GetUnifiedPreset("aggregated-api-issues")
+		entries, err := r.collect(preset.LogTypes, r.start, r.end, preset.Pattern, 0)
+		if err != nil {
+			return err
+		}
+		r.verbosef("Scanned %d candidate API server messages", len(entries))
+
+		issues := log.ScanAPIHealth(entries)
+		if r.format == log.OutputFormatJSON {
+			if err := printAPIHealthJSON(os.Stdout, issues); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printAPIHealthReport(r.output(), issues)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(apiHealthCmd)
+
+	apiHealthOptions.documents = true
+	apiHealthOptions.addFlags(apiHealthCmd, "one JSON object per problem and object")
+}
+
+// printAPIHealthReport writes a table of the aggregated API and CRD problems, the most
+// recently seen first
+func printAPIHealthReport(w io.Writer, issues []log.APIHealthIssue) {
+	if len(issues) == 0 {
+		_, _ = fmt.Fprintln(w, "No aggregated API or CRD problems found.")
+		return
+	}
+
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "PROBLEM\tOBJECT\tCOUNT\tFIRST SEEN\tLAST SEEN")
+	for _, issue := range issues {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\n", issue.Problem, issue.Object, issue.Count,
+			issue.FirstSeen.UTC().Format(time.RFC3339), issue.LastSeen.UTC().Format(time.RFC3339))
+	}
+	_ = table.Flush()
+}
+
+// printAPIHealthJSON writes one JSON object per problem and object
+func printAPIHealthJSON(w io.Writer, issues []log.APIHealthIssue) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, issue := range issues {
+		if err := encoder.Encode(issue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
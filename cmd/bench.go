@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchDuration   time.Duration
+	benchCase       string
+	benchCPUProfile string
+	benchMemProfile string
+	benchOutput     string
+)
+
+// benchResult is the cost per event of an output configuration
+type benchResult struct {
+	Case           string  `json:"case"`
+	Events         int     `json:"events"`
+	EventsPerSec   float64 `json:"events_per_sec"`
+	NsPerEvent     float64 `json:"ns_per_event"`
+	AllocsPerEvent float64 `json:"allocs_per_event"`
+	BytesPerEvent  float64 `json:"bytes_per_event"`
+}
+
+var benchCmd = &cobra.Command{
+	Use:    "bench [corpus]",
+	Short:  "Measure the cost per event of the output pipeline",
+	Hidden: true,
+	Long: `Replay a corpus of log events through the formatting, colorizing, jq, redaction and
+JSON output pipeline for each output configuration, and report the events per second and
+allocations per event. The corpus is the built-in set of synthetic events, or a file of
+events recorded as JSON lines or as the output of 'aws logs filter-log-events'.
+
+--cpuprofile and --memprofile write pprof profiles to inspect with 'go tool pprof'. The
+same configurations are measured by 'go test ./pkg/log -bench Pipeline -benchmem'.`,
+	Example: `  ekslogs bench
+  ekslogs bench --case color --cpuprofile cpu.out
+  aws logs filter-log-events --log-group-name /aws/eks/prod/cluster > events.json && ekslogs bench events.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := log.ParseOutputFormat(benchOutput)
+		if err != nil {
+			return err
+		}
+		if benchDuration <= 0 {
+			return fmt.Errorf("--duration must be a positive duration")
+		}
+
+		entries := log.BenchCorpus()
+		if len(args) == 1 {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			if entries, err = log.ReadRecordedEvents(f, log.BenchLogGroup); err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				return fmt.Errorf("%s holds no events", args[0])
+			}
+		}
+
+		var cases []log.PipelineCase
+		for _, c := range log.PipelineCases() {
+			if benchCase == "" || c.Name == benchCase {
+				cases = append(cases, c)
+			}
+		}
+		if len(cases) == 0 {
+			return fmt.Errorf("unknown case '%s'", benchCase)
+		}
+
+		if benchCPUProfile != "" {
+			f, err := os.Create(benchCPUProfile)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			if err := pprof.StartCPUProfile(f); err != nil {
+				return err
+			}
+			defer pprof.StopCPUProfile()
+		}
+
+		results := make([]benchResult, 0, len(cases))
+		for _, c := range cases {
+			results = append(results, measurePipeline(c, entries, benchDuration))
+		}
+
+		if benchMemProfile != "" {
+			if err := writeHeapProfile(benchMemProfile); err != nil {
+				return err
+			}
+		}
+
+		if format == log.OutputFormatJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			for _, result := range results {
+				if err := encoder.Encode(result); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		printBenchResults(os.Stdout, results, len(entries))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().DurationVar(&benchDuration, "duration", time.Second, "How long each output configuration is measured")
+	benchCmd.Flags().StringVar(&benchCase, "case", "", "Measure only this output configuration (text, color, message-only, pretty, fields, format, jq, redact, json)")
+	benchCmd.Flags().StringVar(&benchCPUProfile, "cpuprofile", "", "Write a CPU profile to this file")
+	benchCmd.Flags().StringVar(&benchMemProfile, "memprofile", "", "Write a heap profile to this file")
+	benchCmd.Flags().StringVarP(&benchOutput, "output", "o", "text", "Output format: text, json (one JSON object per configuration)")
+}
+
+// measurePipeline prints the entries over and over with the configuration to a discarding
+// writer for about duration
+func measurePipeline(c log.PipelineCase, entries []log.LogEntry, duration time.Duration) benchResult {
+	printer := c.NewPrinter(io.Discard)
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	events := 0
+	start := time.Now()
+	var elapsed time.Duration
+	for elapsed < duration {
+		// Check the clock once per pass over the corpus
+		for _, entry := range entries {
+			printer.Print(entry)
+		}
+		events += len(entries)
+		elapsed = time.Since(start)
+	}
+	runtime.ReadMemStats(&after)
+
+	return benchResult{
+		Case:           c.Name,
+		Events:         events,
+		EventsPerSec:   float64(events) / elapsed.Seconds(),
+		NsPerEvent:     float64(elapsed.Nanoseconds()) / float64(events),
+		AllocsPerEvent: float64(after.Mallocs-before.Mallocs) / float64(events),
+		BytesPerEvent:  float64(after.TotalAlloc-before.TotalAlloc) / float64(events),
+	}
+}
+
+// writeHeapProfile writes the allocations so far to path
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printBenchResults writes a table of the cost per event of each configuration
+func printBenchResults(w io.Writer, results []benchResult, corpusSize int) {
+	_, _ = fmt.Fprintf(w, "Corpus: %d events, %s/%s\n", corpusSize, runtime.GOOS, runtime.GOARCH)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "CASE\tEVENTS/SEC\tNS/EVENT\tALLOCS/EVENT\tBYTES/EVENT")
+	for _, r := range results {
+		_, _ = fmt.Fprintf(table, "%s\t%.0f\t%.0f\t%.1f\t%.0f\n", r.Case, r.EventsPerSec, r.NsPerEvent, r.AllocsPerEvent, r.BytesPerEvent)
+	}
+	_ = table.Flush()
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// breakGlassFilterPattern narrows the audit logs to events mentioning impersonation or
+// system:masters; ClassifyPrivileged then checks the impersonated user and the whole
+// groups array, and selects the write verbs
+const breakGlassFilterPattern = `?"impersonatedUser" ?"system:masters"`
+
+var (
+	breakGlassOptions reportOptions
+	breakGlassColor   string
+)
+
+var breakGlassCmd = &cobra.Command{
+	Use:   "break-glass [cluster-name]",
+	Short: "Report impersonation and system:masters writes per user",
+	Long: `Report the audit events where a user impersonated another (kubectl --as, Impersonate-User
+headers) or where a member of system:masters, which bypasses RBAC, changed the cluster.
+The events are grouped into a timeline per user, users with the most events first.
+
+Requires audit logging to be enabled on the cluster.`,
+	Example: `  ekslogs break-glass my-cluster -s -7d       # Privileged activity of the past week
+  ekslogs break-glass my-cluster -o json | jq  # One JSON timeline per user`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &breakGlassOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		colorConfig := log.NewColorConfig()
+		colorConfig.Mode = parseColorMode(breakGlassColor)
+
+		entries, err := r.collect([]string{"audit"}, r.start, r.end, breakGlassFilterPattern, 0)
+		if err != nil {
+			return err
+		}
+
+		var events []log.PrivilegedEvent
+		for _, entry := range entries {
+			if event, ok := log.ClassifyPrivileged(entry); ok {
+				events = append(events, event)
+			}
+		}
+		r.verbosef("Found %d privileged events in %d audit events", len(events), len(entries))
+
+		timelines := log.GroupByUser(events)
+		if r.format == log.OutputFormatJSON {
+			if err := printBreakGlassJSON(os.Stdout, timelines); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printBreakGlassReport(r.output(), timelines, colorConfig.ShouldUseColor() && !r.format.IsDocument())
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(breakGlassCmd)
+
+	breakGlassOptions.documents = true
+	breakGlassOptions.addFlags(breakGlassCmd, "one JSON timeline per user")
+	breakGlassCmd.Flags().StringVar(&breakGlassColor, "color", "auto", "Color output mode: auto, always, never (auto honors EKSLOGS_COLOR, NO_COLOR and CLICOLOR_FORCE)")
+}
+
+// printBreakGlassReport writes the timeline of each user, e.g.
+//
+//	alice (2 events)
+//	  2024-01-01T10:00:00Z  impersonation as bob  get pods default/web  200  from 10.0.1.5
+func printBreakGlassReport(w io.Writer, timelines []log.UserTimeline, useColor bool) {
+	if len(timelines) == 0 {
+		_, _ = fmt.Fprintln(w, "No impersonation or system:masters writes found.")
+		return
+	}
+
+	userColor := color.New(color.FgCyan, color.Bold)
+	reasonColor := color.New(color.FgRed)
+	if useColor {
+		userColor.EnableColor()
+		reasonColor.EnableColor()
+	} else {
+		userColor.DisableColor()
+		reasonColor.DisableColor()
+	}
+
+	for i, timeline := range timelines {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = userColor.Fprintf(w, "%s", timeline.User)
+		noun := "events"
+		if len(timeline.Events) == 1 {
+			noun = "event"
+		}
+		_, _ = fmt.Fprintf(w, " (%d %s)\n", len(timeline.Events), noun)
+
+		for _, event := range timeline.Events {
+			reason := event.Reason
+			if event.Impersonated != "" {
+				reason += " as " + event.Impersonated
+			}
+			line := fmt.Sprintf("  %s  %s  %s %s", event.Time.Format(time.RFC3339), reasonColor.Sprint(reason), event.Verb, event.Target())
+			if event.Code != 0 {
+				line += fmt.Sprintf("  %d", event.Code)
+			}
+			if event.SourceIP != "" {
+				line += "  from " + event.SourceIP
+			}
+			_, _ = fmt.Fprintln(w, line)
+		}
+	}
+}
+
+// printBreakGlassJSON writes one JSON object per user timeline
+func printBreakGlassJSON(w io.Writer, timelines []log.UserTimeline) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, timeline := range timelines {
+		if err := encoder.Encode(timeline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultPreflightBytes is the estimated range volume above which a retrieval requires --yes
+const defaultPreflightBytes = 1 << 30 // 1 GiB
+
+var (
+	maxBytesSpec string
+	maxAPICalls  int64
+	assumeYes    bool
+)
+
+// byteUnits maps size suffixes to their multipliers
+var byteUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// parseByteSize parses a size such as "500MB", "2GiB", "100k" or a plain number of bytes
+func parseByteSize(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s' (examples: 500MB, 2GiB, 1048576)", size)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. "1.5 GiB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// checkPreflightEstimate refuses to start a retrieval whose estimated volume exceeds the
+// byte budget (or 1 GiB without one) unless --yes was given
+func checkPreflightEstimate(estimate, maxBytes int64) error {
+	threshold := int64(defaultPreflightBytes)
+	if maxBytes > 0 {
+		threshold = maxBytes
+	}
+	if assumeYes || estimate <= threshold {
+		return nil
+	}
+
+	return fmt.Errorf("the requested time range holds an estimated %s of logs (threshold %s). "+
+		"Narrow the time range, or pass --yes to continue", formatBytes(estimate), formatBytes(threshold))
+}
@@ -0,0 +1,406 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleOptions  reportOptions
+	bundleOut      string
+	bundleMaxBytes string
+	bundleCompress string
+)
+
+// bundleChecksums is the file of a bundle listing the SHA-256 of the other files, in the
+// format of sha256sum, so the extracted bundle can be checked with sha256sum -c
+const bundleChecksums = "SHA256SUMS"
+
+// bundleIndex is the index.json of an incident bundle, describing its contents: the manifest
+// of the bundle, with the query that collected it and the checksum of each file
+type bundleIndex struct {
+	Cluster   string       `json:"cluster"`
+	Region    string       `json:"region"`
+	Start     time.Time    `json:"start"`
+	End       time.Time    `json:"end"`
+	CreatedAt time.Time    `json:"created_at"`
+	Complete  bool         `json:"complete"`
+	Note      string       `json:"note,omitempty"`
+	Query     bundleQuery  `json:"query"`
+	Files     []bundleFile `json:"files"`
+}
+
+// bundleQuery records how a bundle was collected
+type bundleQuery struct {
+	Args     []string `json:"args"`
+	LogTypes []string `json:"log_types,omitempty"`
+	Version  string   `json:"ekslogs_version"`
+}
+
+// bundleFile describes a file of an incident bundle
+type bundleFile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Events      int    `json:"events,omitempty"`
+	Bytes       int64  `json:"bytes"`
+	SHA256      string `json:"sha256"`
+
+	data []byte // content of a small file
+	path string // temporary file holding the content of a log file
+}
+
+// bundleLogs writes the log events of each log type to a JSON lines file of a temporary
+// directory as they are retrieved, so a large window is not held in memory
+type bundleLogs struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]*bundleLogFile
+	err   error
+}
+
+// bundleLogFile is the JSON lines file of the events of one log type
+type bundleLogFile struct {
+	file    *os.File
+	encoder *json.Encoder
+	events  int
+}
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle [cluster-name]",
+	Short: "Collect the logs and configuration of a time window into a tarball",
+	Long: `Collect all the control plane logs of a time window, the cluster description, its logging
+configuration and the retrieval statistics into a compressed tarball with an index.json,
+a shareable artifact for postmortems and AWS support cases. The tarball is compressed
+with gzip as it is written; --compress zstd compresses large exports faster and smaller
+with the zstd command.
+
+The logs of each log type are written to logs/<type>.jsonl, one JSON object per event.
+index.json is the manifest of the bundle: the query that collected it, and the event
+count and SHA-256 of each file. SHA256SUMS lists the SHA-256 of every file, and
+<out>.sha256 that of the tarball, so archived evidence can be verified with sha256sum -c.
+A retrieval cut short by --max-bytes, --timeout or Ctrl+C still writes the
+bundle, marked as incomplete in index.json.`,
+	Example: `  ekslogs bundle my-cluster -s -2h                     # Writes ekslogs-my-cluster-<time>.tar.gz
+  ekslogs bundle my-cluster -s -2h --out incident.tar.gz
+  tar -xzOf incident.tar.gz logs/audit.jsonl | jq 'select(.message | contains("forbidden"))'
+  sha256sum -c incident.tar.gz.sha256                   # Verify the tarball
+  ekslogs bundle my-cluster -s -24h -l audit --compress zstd   # Writes ekslogs-my-cluster-<time>.tar.zst`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		maxBytes, err := parseByteSize(bundleMaxBytes)
+		if err != nil {
+			return err
+		}
+		if err := validateCompression(bundleCompress); err != nil {
+			return err
+		}
+		r, err := setupReport(cmd, args, &bundleOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+		r.client.SetBudget(aws.Budget{MaxBytes: maxBytes})
+
+		now := time.Now().UTC()
+		index := bundleIndex{Cluster: r.clusterName, Region: r.region, End: now, CreatedAt: now, Complete: true,
+			Query: bundleQuery{Args: os.Args[1:], LogTypes: r.logTypes, Version: version}}
+		if r.end != nil {
+			index.End = r.end.UTC()
+		}
+		index.Start = index.End.Add(-time.Hour)
+		if r.start != nil {
+			index.Start = r.start.UTC()
+		}
+		out := bundleOut
+		if out == "" {
+			out = fmt.Sprintf("ekslogs-%s-%s.tar%s", r.clusterName, now.Format("20060102T150405Z"), compressionExtensions[bundleCompress])
+		}
+
+		dir, err := os.MkdirTemp("", "ekslogs-bundle-")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		logs := &bundleLogs{dir: dir, files: make(map[string]*bundleLogFile)}
+		err = r.client.GetLogs(r.ctx, r.clusterName, r.logTypes, &index.Start, &index.End, nil, 0, logs.write)
+		if closeErr := logs.close(); closeErr != nil {
+			return closeErr
+		}
+		switch {
+		case r.ctx.Err() != nil:
+			index.Complete = false
+			index.Note = "retrieval interrupted by Ctrl+C or --timeout"
+		case err == nil:
+		case errors.Is(err, aws.ErrBudgetExceeded):
+			index.Complete = false
+			index.Note = fmt.Sprintf("stopped after retrieving %s (--max-bytes)", formatBytes(maxBytes))
+			r.logger.Warn("Bundle is incomplete", "reason", index.Note)
+		case errors.Is(err, aws.ErrNoLogGroups):
+			index.Note = "control plane logging is not enabled"
+			r.logger.Warn("No control plane logs found, bundling the cluster description only", "cluster", r.clusterName)
+		default:
+			return err
+		}
+
+		files, err := bundleClusterFiles(r)
+		if err != nil {
+			return err
+		}
+		files = append(files, logs.bundleFiles()...)
+		index.Files = files
+
+		if err := writeBundleFile(out, index, bundleCompress); err != nil {
+			return err
+		}
+		if r.format == log.OutputFormatJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			if err := encoder.Encode(index); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printBundleSummary(os.Stdout, out, index)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+
+	bundleOptions.addFlags(bundleCmd, "the index.json of the bundle")
+	bundleCmd.Flags().StringVar(&bundleOut, "out", "", "Path of the tarball (default ekslogs-<cluster>-<time>.tar.gz, .tar.zst with --compress zstd)")
+	bundleCmd.Flags().StringVar(&bundleCompress, "compress", "gzip", "Compression of the tarball: gzip, zstd (needs the zstd command) or none")
+	bundleCmd.Flags().StringVar(&bundleMaxBytes, "max-bytes", "1GiB", "Stop retrieving logs after this much log data (e.g. 500MB, 2GiB, 0 for unlimited)")
+}
+
+// write appends an event to the file of its log type. It is called concurrently by the
+// retrieval of each log group; the first write error is kept for close.
+func (b *bundleLogs) write(entry log.LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return
+	}
+
+	logType := log.LogTypeFor(entry.LogGroup, entry.LogStream)
+	if logType == "" {
+		logType = "other"
+	}
+	file, ok := b.files[logType]
+	if !ok {
+		f, err := os.Create(filepath.Join(b.dir, logType+".jsonl"))
+		if err != nil {
+			b.err = err
+			return
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetEscapeHTML(false)
+		file = &bundleLogFile{file: f, encoder: encoder}
+		b.files[logType] = file
+	}
+	if err := file.encoder.Encode(entry); err != nil {
+		b.err = err
+		return
+	}
+	file.events++
+}
+
+// close closes the log files and returns the first error writing them
+func (b *bundleLogs) close() error {
+	for _, file := range b.files {
+		if err := file.file.Close(); err != nil && b.err == nil {
+			b.err = err
+		}
+	}
+	return b.err
+}
+
+// bundleFiles returns the log files in the order of their log type
+func (b *bundleLogs) bundleFiles() []bundleFile {
+	types := make([]string, 0, len(b.files))
+	for logType := range b.files {
+		types = append(types, logType)
+	}
+	sort.Strings(types)
+
+	files := make([]bundleFile, 0, len(types))
+	for _, logType := range types {
+		file := b.files[logType]
+		files = append(files, bundleFile{
+			Name:        "logs/" + logType + ".jsonl",
+			Description: logType + " log events, one JSON object per line",
+			Events:      file.events,
+			path:        file.file.Name(),
+		})
+	}
+	return files
+}
+
+// bundleClusterFiles returns the cluster description, its logging configuration and the
+// retrieval statistics as bundle files
+func bundleClusterFiles(r *report) ([]bundleFile, error) {
+	contents := []struct {
+		name, description string
+		value             interface{}
+	}{
+		{"cluster.json", "eks:DescribeCluster output", r.cluster},
+		{"logging.json", "control plane logging configuration", r.cluster.Logging},
+		{"stats.json", "retrieval statistics", r.client.Stats()},
+	}
+	files := make([]bundleFile, 0, len(contents))
+	for _, content := range contents {
+		data, err := json.MarshalIndent(content.value, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", content.name, err)
+		}
+		files = append(files, bundleFile{Name: content.name, Description: content.description, data: append(data, '\n')})
+	}
+	return files, nil
+}
+
+// writeBundleFile writes the bundle to path, and its SHA-256 to path.sha256, removing the
+// partial files on failure
+func writeBundleFile(path string, index bundleIndex, compression string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	hash := sha256.New()
+	if err := writeBundle(io.MultiWriter(f, hash), index, compression); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	checksum := fmt.Sprintf("%s  %s\n", hex.EncodeToString(hash.Sum(nil)), filepath.Base(path))
+	if err := os.WriteFile(path+".sha256", []byte(checksum), 0o644); err != nil {
+		return fmt.Errorf("failed to write the checksum of %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeBundle writes a tarball compressed as it is written, holding index.json followed by
+// the files of the index and SHA256SUMS. The sizes and checksums of the files are filled in
+// before the index is written.
+func writeBundle(w io.Writer, index bundleIndex, compression string) error {
+	for i := range index.Files {
+		if err := index.Files[i].measure(); err != nil {
+			return err
+		}
+	}
+	indexData, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	cw, err := newCompressWriter(w, compression)
+	if err != nil {
+		return err
+	}
+	if err := writeBundleTar(cw, index, indexData); err != nil {
+		_ = cw.Close()
+		return err
+	}
+	return cw.Close()
+}
+
+// writeBundleTar writes the tar stream of a bundle
+func writeBundleTar(w io.Writer, index bundleIndex, indexData []byte) error {
+	tw := tar.NewWriter(w)
+	add := func(name string, size int64, content io.Reader) error {
+		header := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: index.CreatedAt, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := io.Copy(tw, content)
+		return err
+	}
+
+	indexData = append(indexData, '\n')
+	if err := add("index.json", int64(len(indexData)), bytes.NewReader(indexData)); err != nil {
+		return err
+	}
+	indexSum := sha256.Sum256(indexData)
+	checksums := []string{hex.EncodeToString(indexSum[:]) + "  index.json"}
+	for _, file := range index.Files {
+		if file.path == "" {
+			if err := add(file.Name, file.Bytes, bytes.NewReader(file.data)); err != nil {
+				return err
+			}
+			continue
+		}
+		f, err := os.Open(file.path)
+		if err != nil {
+			return err
+		}
+		err = add(file.Name, file.Bytes, f)
+		_ = f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	for _, file := range index.Files {
+		checksums = append(checksums, file.SHA256+"  "+file.Name)
+	}
+	sums := strings.Join(checksums, "\n") + "\n"
+	if err := add(bundleChecksums, int64(len(sums)), strings.NewReader(sums)); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// measure fills in the size and SHA-256 of the content of a bundle file
+func (f *bundleFile) measure() error {
+	hash := sha256.New()
+	if f.path == "" {
+		hash.Write(f.data)
+		f.Bytes = int64(len(f.data))
+	} else {
+		content, err := os.Open(f.path)
+		if err != nil {
+			return err
+		}
+		f.Bytes, err = io.Copy(hash, content)
+		_ = content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	f.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// printBundleSummary writes where the bundle was written and what it holds
+func printBundleSummary(w io.Writer, path string, index bundleIndex) {
+	var events int
+	var size int64
+	for _, file := range index.Files {
+		events += file.Events
+		size += file.Bytes
+	}
+	_, _ = fmt.Fprintf(w, "Wrote %s: %d log events, %d files, %s uncompressed\n", path, events, len(index.Files), formatBytes(size))
+	if !index.Complete {
+		_, _ = fmt.Fprintf(w, "The bundle is incomplete: %s\n", index.Note)
+	}
+}
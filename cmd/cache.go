@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/cache"
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+var (
+	noCache  bool
+	offline  bool
+	cacheTTL time.Duration
+)
+
+// defaultCacheTTL is how long a retrieved time range is reused by the next runs
+const defaultCacheTTL = 10 * time.Minute
+
+// maxCachedEntries bounds the events kept in memory to cache a streamed retrieval; larger
+// retrievals are not cached
+const maxCachedEntries = 100000
+
+// errNotCached is returned by --offline when no result of the same retrieval is cached
+var errNotCached = errors.New("no cached result for this cluster, time range and pattern: run without --offline first")
+
+// cachedEntry is a log event in the cache, with its ingestion time which JSON output omits
+type cachedEntry struct {
+	log.LogEntry
+	IngestionTime time.Time `json:"ingestion_time"`
+}
+
+// cachedLogs is a retrieval of the root command in the cache
+type cachedLogs struct {
+	Metadata log.Metadata  `json:"metadata"`
+	Entries  []cachedEntry `json:"entries"`
+}
+
+// newCachedLogs returns the cache content for the retrieved entries
+func newCachedLogs(metadata log.Metadata, entries []log.LogEntry) cachedLogs {
+	cached := cachedLogs{Metadata: metadata, Entries: make([]cachedEntry, len(entries))}
+	for i, entry := range entries {
+		cached.Entries[i] = cachedEntry{LogEntry: entry, IngestionTime: entry.IngestionTime}
+	}
+	return cached
+}
+
+// logEntries returns the cached entries
+func (c cachedLogs) logEntries() []log.LogEntry {
+	entries := make([]log.LogEntry, len(c.Entries))
+	for i, cached := range c.Entries {
+		entries[i] = cached.LogEntry
+		entries[i].IngestionTime = cached.IngestionTime
+	}
+	return entries
+}
+
+// resultCache returns the cache of retrieval results, or nil with --no-cache. --offline
+// reads results of any age; otherwise they expire after --cache-ttl.
+func resultCache(noCache, offline bool, ttl time.Duration) *cache.Cache {
+	dir := cache.DefaultDir()
+	if noCache || dir == "" || ttl <= 0 && !offline {
+		return nil
+	}
+	if offline {
+		ttl = 0
+	}
+	return cache.New(dir, ttl)
+}
+
+// logsCacheKey identifies a retrieval of the root command. Relative times are part of the
+// key as given, so "-s -1h" reuses the result of an earlier run within the TTL.
+func logsCacheKey(region, cluster string, logTypes []string, start, end, pattern string, limit int32, tail int, containerInsights, unknownStreams bool) string {
+	return cache.Key("logs", region, cluster, strings.Join(logTypes, ","), start, end, pattern,
+		strconv.Itoa(int(limit)), strconv.Itoa(tail), strconv.FormatBool(containerInsights), strconv.FormatBool(unknownStreams))
+}
+
+// printCacheNotice tells that a cached result is shown instead of fetching the logs again
+func printCacheNotice(w io.Writer, storedAt time.Time) {
+	_, _ = fmt.Fprintf(w, "Using the result cached %s ago (--no-cache to fetch it again)\n",
+		time.Since(storedAt).Round(time.Second))
+}
+
+// entryRecorder keeps the entries passing through a print function, up to
+// maxCachedEntries, to cache them after the retrieval. The log groups are retrieved
+// concurrently.
+type entryRecorder struct {
+	mu       sync.Mutex
+	entries  []log.LogEntry
+	overflow bool
+}
+
+// wrap returns printFunc recording each entry first
+func (r *entryRecorder) wrap(printFunc func(log.LogEntry)) func(log.LogEntry) {
+	return func(entry log.LogEntry) {
+		r.mu.Lock()
+		switch {
+		case r.overflow:
+		case len(r.entries) < maxCachedEntries:
+			r.entries = append(r.entries, entry)
+		default:
+			// Too many to cache: free them
+			r.entries, r.overflow = nil, true
+		}
+		r.mu.Unlock()
+		printFunc(entry)
+	}
+}
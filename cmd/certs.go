@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// certsFilterPattern selects the messages mentioning certificates or x509 errors
+const certsFilterPattern = "?x509 ?certificate"
+
+var certsOptions reportOptions
+
+var certsCmd = &cobra.Command{
+	Use:   "certs [cluster-name]",
+	Short: "Report certificate expiry and x509 validation errors per identity",
+	Long: `Scan the API server and authenticator logs for expired certificates and x509 validation
+errors (unknown authority, name mismatch) and report the identities affected, such as a
+client certificate serial number, a webhook or a server, with when they were first and
+last seen. Expiring client certificates cause authentication failures that build up slowly.`,
+	Example: `  ekslogs certs my-cluster -s -7d   # Certificate errors of the past week
+  ekslogs certs my-cluster -o json  # One JSON object per identity`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &certsOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		entries, err := r.collect([]string{"api", "authenticator"}, r.start, r.end, certsFilterPattern, 0)
+		if err != nil {
+			return err
+		}
+		r.verbosef("Scanned %d log events mentioning certificates", len(entries))
+
+		issues := log.ScanCertificateErrors(entries)
+		if r.format == log.OutputFormatJSON {
+			if err := printCertsJSON(os.Stdout, issues); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printCertsReport(r.output(), issues)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(certsCmd)
+
+	certsOptions.documents = true
+	certsOptions.addFlags(certsCmd, "one JSON object per identity")
+}
+
+// printCertsReport writes a table of the certificate errors, the most recently seen first
+func printCertsReport(w io.Writer, issues []log.CertificateIssue) {
+	if len(issues) == 0 {
+		_, _ = fmt.Fprintln(w, "No certificate errors found.")
+		return
+	}
+
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "PROBLEM\tIDENTITY\tCOUNT\tFIRST SEEN\tLAST SEEN")
+	for _, issue := range issues {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\n", issue.Problem, issue.Identity, issue.Count,
+			issue.FirstSeen.UTC().Format(time.RFC3339), issue.LastSeen.UTC().Format(time.RFC3339))
+	}
+	_ = table.Flush()
+}
+
+// printCertsJSON writes one JSON object per affected identity
+func printCertsJSON(w io.Writer, issues []log.CertificateIssue) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, issue := range issues {
+		if err := encoder.Encode(issue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloudTrailOptions        reportOptions
+	cloudTrailFilterPatterns []string
+	cloudTrailIgnorePatterns []string
+	cloudTrailLimit          int32
+	cloudTrailColor          string
+)
+
+var cloudTrailCmd = &cobra.Command{
+	Use:   "cloudtrail [cluster-name] [log-types...]",
+	Short: "Show CloudTrail changes to a cluster interleaved with its control plane logs",
+	Long: `Show the CloudTrail events that changed a cluster interleaved with its control plane logs,
+to explain who changed what.
+
+The CloudTrail events are the write calls of the EKS API on the cluster, such as
+UpdateClusterConfig, UpdateClusterVersion and access entry changes, and the calls on the
+cluster IAM role, such as AssumeRole. They are shown with the log type "cloudtrail".
+Only the control plane logs mentioning the IAM users and roles that made the changes are
+shown, unless filter patterns are given with -F and -I.
+
+CloudTrail keeps the events of the past 90 days. Requires the cloudtrail:LookupEvents permission.`,
+	Example: `  ekslogs cloudtrail my-cluster                 # Changes and control plane logs of the past hour
+  ekslogs cloudtrail my-cluster audit -s "-6h"  # Changes and audit logs of the past 6 hours
+  ekslogs cloudtrail my-cluster -F error        # Changes and the error logs of all users`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &cloudTrailOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		logTypes := r.logTypes
+		if len(logTypes) == 0 && r.context != nil {
+			logTypes = r.context.LogTypes
+		}
+
+		// CloudTrail lookups need both ends of the range
+		rangeStart := time.Now().Add(-90 * 24 * time.Hour)
+		if r.start != nil {
+			rangeStart = *r.start
+		}
+		rangeEnd := time.Now()
+		if r.end != nil {
+			rangeEnd = *r.end
+		}
+
+		colorConfig := log.NewColorConfig()
+		colorConfig.Mode = parseColorMode(cloudTrailColor)
+		colorConfig.Theme, err = log.ParseTheme(r.config.Theme)
+		if err != nil {
+			return err
+		}
+
+		var roleArn string
+		if r.cluster.RoleArn != nil {
+			roleArn = *r.cluster.RoleArn
+		}
+		changes, err := r.client.GetCloudTrailEvents(r.ctx, r.clusterName, roleArn, rangeStart, rangeEnd)
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return r.finish()
+			}
+			return err
+		}
+		r.verbosef("Found %d CloudTrail events by %d principals", len(changes.Entries), len(changes.Principals))
+
+		// Without a filter pattern, only the logs mentioning the principals that made the
+		// changes are read instead of every event of the range
+		pattern := buildCombinedFilterPattern(cloudTrailFilterPatterns, cloudTrailIgnorePatterns, false)
+		if pattern == "" {
+			pattern = changes.Pattern()
+		}
+		entries := changes.Entries
+		if pattern == "" {
+			r.logger.Info("No IAM principal made changes, showing CloudTrail events only", "cluster", r.clusterName)
+		} else {
+			r.verbosef("Using filter pattern: %s", pattern)
+			// The changes are still worth showing for a cluster without control plane logging
+			logs, err := r.collect(logTypes, r.start, r.end, pattern, cloudTrailLimit)
+			if err != nil && !errors.Is(err, aws.ErrNoLogGroups) {
+				return err
+			}
+			if errors.Is(err, aws.ErrNoLogGroups) {
+				r.logger.Warn("No control plane logs found, showing CloudTrail events only", "cluster", r.clusterName)
+			}
+			if cloudTrailLimit > 0 && len(logs) >= int(cloudTrailLimit) {
+				r.logger.Warn("Showing only the first control plane log events", "limit", cloudTrailLimit)
+			}
+			entries = append(entries, logs...)
+		}
+		log.SortEntries(entries, log.SortOrderAsc, log.TimestampSourceEvent)
+
+		printer := log.NewPrinter(log.OutputOptions{Format: r.format}, colorConfig)
+		for _, entry := range entries {
+			printer.Print(entry)
+		}
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cloudTrailCmd)
+
+	cloudTrailOptions.addFlags(cloudTrailCmd, "one JSON object per line")
+	cloudTrailCmd.Flags().StringArrayVarP(&cloudTrailFilterPatterns, "filter-pattern", "F", []string{}, "Log filter pattern (can be specified multiple times for AND condition)")
+	cloudTrailCmd.Flags().StringArrayVarP(&cloudTrailIgnorePatterns, "ignore-filter-pattern", "I", []string{}, "Log ignore filter pattern (can be specified multiple times for OR condition)")
+	cloudTrailCmd.Flags().Int32VarP(&cloudTrailLimit, "limit", "l", 1000, "Maximum number of control plane log events to retrieve (0 means unlimited)")
+	cloudTrailCmd.Flags().StringVar(&cloudTrailColor, "color", "auto", "Color output mode: auto, always, never (auto honors EKSLOGS_COLOR, NO_COLOR and CLICOLOR_FORCE)")
+}
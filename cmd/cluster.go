@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/kzcat/ekslogs/pkg/aws"
+)
+
+// getCluster describes the named cluster. When it does not exist, a single similar name is
+// selected automatically, and several are offered in a picker on a terminal. It returns the
+// cluster and its name.
+func getCluster(ctx context.Context, client *aws.EKSLogsClient, name string) (*ekstypes.Cluster, string, error) {
+	info, err := client.GetClusterInfo(ctx, name)
+	if err == nil {
+		return info, name, nil
+	}
+
+	var notFound *aws.ClusterNotFoundError
+	if !errors.As(err, &notFound) || len(notFound.Matches) == 0 {
+		return nil, name, err
+	}
+
+	var selected string
+	switch {
+	case len(notFound.Matches) == 1:
+		selected = notFound.Matches[0]
+		if !quiet {
+			_, _ = fmt.Fprintf(os.Stderr, "Cluster '%s' not found, using '%s'\n", name, selected)
+		}
+	case interactiveTerminal():
+		selected, err = pickCluster(os.Stdin, os.Stderr, name, notFound.Matches)
+		if err != nil {
+			return nil, name, err
+		}
+	default:
+		return nil, name, err
+	}
+
+	info, err = client.GetClusterInfo(ctx, selected)
+	return info, selected, err
+}
+
+// pickCluster asks on w to choose one of the matching clusters by number, read from r
+func pickCluster(r io.Reader, w io.Writer, name string, matches []string) (string, error) {
+	_, _ = fmt.Fprintf(w, "Cluster '%s' not found. Did you mean:\n", name)
+	for i, match := range matches {
+		_, _ = fmt.Fprintf(w, "  %d) %s\n", i+1, match)
+	}
+	_, _ = fmt.Fprintf(w, "Select a cluster [1-%d]: ", len(matches))
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("no cluster selected: %w", err)
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(matches) {
+		return "", fmt.Errorf("invalid selection '%s'", strings.TrimSpace(line))
+	}
+	return matches[choice-1], nil
+}
+
+// findClusterRegion searches the enabled regions for the named cluster and returns its region
+func findClusterRegion(ctx context.Context, name, roleARN string, logger *slog.Logger) (string, error) {
+	client, err := aws.NewEKSLogsClient(region, endpointURL, logger)
+	if err == nil && roleARN != "" {
+		err = client.SetAssumeRole(roleARN)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to create client: %w", err)
+	}
+
+	regions, err := client.FindClusterRegions(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	switch len(regions) {
+	case 0:
+		return "", fmt.Errorf("cluster '%s' %w in any enabled region", name, aws.ErrClusterNotFound)
+	case 1:
+		verbosef("Found cluster '%s' in %s", name, regions[0])
+		return regions[0], nil
+	default:
+		return "", fmt.Errorf("cluster '%s' exists in several regions (%s), select one with --region", name, strings.Join(regions, ", "))
+	}
+}
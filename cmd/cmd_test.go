@@ -1,14 +1,34 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/smithy-go"
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/config"
 	"github.com/kzcat/ekslogs/pkg/filter"
 	"github.com/kzcat/ekslogs/pkg/log"
 	"github.com/spf13/cobra"
@@ -49,6 +69,16 @@ func TestCommandInitialization(t *testing.T) {
 		}
 	}
 	assert.True(t, found, "presets command should be registered")
+
+	// Test that the cloudtrail command is registered
+	found = false
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == "cloudtrail" {
+			found = true
+			break
+		}
+	}
+	assert.True(t, found, "cloudtrail command should be registered")
 }
 
 // TestVersionCommandOutput tests the version command
@@ -103,7 +133,7 @@ func TestLogTypesCommandOutput(t *testing.T) {
 	os.Stdout = w
 
 	// Execute the logtypes command
-	logTypesCmd.Run(logTypesCmd, []string{})
+	assert.NoError(t, logTypesCmd.RunE(logTypesCmd, []string{}))
 
 	// Close the write end of the pipe to flush the buffer
 	if err := w.Close(); err != nil {
@@ -487,6 +517,51 @@ func TestPresetApplication(t *testing.T) {
 	}
 }
 
+func TestApplyAuditFilter(t *testing.T) {
+	origFilterPatterns, origIgnorePatterns, origLogTypes := filterPatterns, ignoreFilterPatterns, logTypes
+	defer func() {
+		filterPatterns, ignoreFilterPatterns, logTypes = origFilterPatterns, origIgnorePatterns, origLogTypes
+	}()
+
+	filterPatterns, ignoreFilterPatterns, logTypes = nil, nil, nil
+	assert.NoError(t, applyAuditFilter(filter.AuditFilter{}))
+	assert.Empty(t, filterPatterns)
+
+	assert.NoError(t, applyAuditFilter(filter.AuditFilter{WritesOnly: true, NonSystem: true}))
+	assert.Equal(t, []string{"audit"}, logTypes)
+	assert.Equal(t, []string{`{ ($.verb != "get" && $.verb != "list" && $.verb != "watch") && $.user.username != "system:*" }`}, filterPatterns)
+
+	filterPatterns, logTypes = nil, []string{"api"}
+	assert.ErrorContains(t, applyAuditFilter(filter.AuditFilter{NonSystem: true}), "do not include audit")
+
+	filterPatterns, ignoreFilterPatterns, logTypes = nil, []string{"healthz"}, nil
+	assert.Error(t, applyAuditFilter(filter.AuditFilter{ReadsOnly: true}))
+}
+
+func TestApplyNamespaceFilter(t *testing.T) {
+	origFilterPatterns, origIgnorePatterns, origLogTypes, origAuditFilter := filterPatterns, ignoreFilterPatterns, logTypes, auditFilter
+	defer func() {
+		filterPatterns, ignoreFilterPatterns, logTypes, auditFilter = origFilterPatterns, origIgnorePatterns, origLogTypes, origAuditFilter
+	}()
+
+	filterPatterns, ignoreFilterPatterns, logTypes, auditFilter = nil, nil, []string{"audit"}, filter.AuditFilter{}
+	assert.Nil(t, applyNamespaceFilter(""))
+	assert.Nil(t, applyNamespaceFilter("team-a"), "audit-only queries are filtered server-side")
+	assert.Equal(t, "team-a", auditFilter.Namespace)
+	assert.NoError(t, applyAuditFilter(auditFilter))
+	assert.Equal(t, []string{`{ $.objectRef.namespace = "team-a" }`}, filterPatterns)
+
+	filterPatterns, logTypes, auditFilter = nil, []string{"audit", "scheduler"}, filter.AuditFilter{}
+	assert.NotNil(t, applyNamespaceFilter("team-a"))
+	assert.Empty(t, auditFilter.Namespace)
+
+	filterPatterns, logTypes = []string{"error"}, []string{"audit"}
+	assert.NotNil(t, applyNamespaceFilter("team-a"), "text patterns cannot be combined with a JSON pattern")
+
+	filterPatterns, logTypes = nil, nil
+	assert.NotNil(t, applyNamespaceFilter("team-a"))
+}
+
 // TestErrorHandling tests the error handling of the root command
 func TestErrorHandling(t *testing.T) {
 	// Create a test command that returns an error
@@ -507,11 +582,16 @@ func TestErrorHandling(t *testing.T) {
 
 // TestInvalidArguments tests the root command with invalid arguments
 func TestInvalidArguments(t *testing.T) {
+	// Do not pick up a current context from the developer's config file
+	configPath = filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(configPath, nil, 0o600))
+	defer func() { configPath = "" }()
+
 	// Test with no arguments (should fail)
 	rootCmd.SetArgs([]string{})
 	err := rootCmd.Execute()
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "requires at least 1 arg")
+	assert.Contains(t, err.Error(), "requires a cluster name")
 
 	// Test with invalid flag
 	rootCmd.SetArgs([]string{"test-cluster", "--invalid-flag"})
@@ -588,3 +668,1336 @@ func TestTimeRangeParsing(t *testing.T) {
 	_, err = log.ParseTimeString(startTime)
 	assert.NoError(t, err)
 }
+
+// TestApplyHighlightConfig tests applying highlight settings from the config file
+func TestApplyHighlightConfig(t *testing.T) {
+	colorConfig := log.NewColorConfig()
+	err := applyHighlightConfig(colorConfig, config.HighlightConfig{
+		Rules:   []config.HighlightRule{{LogType: "api", Pattern: "etcd", Color: "magenta"}},
+		Disable: []string{"api.keywords"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, colorConfig.Rules, 1)
+
+	err = applyHighlightConfig(log.NewColorConfig(), config.HighlightConfig{
+		Rules: []config.HighlightRule{{Pattern: "(", Color: "red"}},
+	})
+	assert.Error(t, err)
+
+	err = applyHighlightConfig(log.NewColorConfig(), config.HighlightConfig{
+		Disable: []string{"no-such-rule"},
+	})
+	assert.Error(t, err)
+}
+
+func TestApplyPresetConfig(t *testing.T) {
+	t.Cleanup(func() { delete(filter.UnifiedPresets, "prod-triage") })
+
+	err := applyPresetConfig(map[string]config.Preset{
+		"prod-triage": {Include: []string{"api-errors", "auth-failures"}},
+	})
+	assert.NoError(t, err)
+	preset, exists := filter.GetUnifiedPreset("prod-triage")
+	assert.True(t, exists)
+	assert.Equal(t, "?ERROR ?unauthorized", preset.Pattern)
+	assert.Equal(t, []string{"api", "authenticator"}, preset.LogTypes)
+
+	err = applyPresetConfig(map[string]config.Preset{"broken": {Include: []string{"nope"}}})
+	assert.ErrorContains(t, err, "invalid preset in config")
+}
+
+func TestApplyPresetDefaults(t *testing.T) {
+	newCmd := func() (*cobra.Command, *string, *int32, *string) {
+		var start, output string
+		var limit int32
+		cmd := &cobra.Command{}
+		cmd.Flags().StringVarP(&start, "start-time", "s", "", "")
+		cmd.Flags().StringVarP(new(string), "end-time", "e", "", "")
+		cmd.Flags().Int32VarP(&limit, "limit", "l", 1000, "")
+		cmd.Flags().StringVarP(&output, "output", "o", "text", "")
+		return cmd, &start, &limit, &output
+	}
+	preset := filter.UnifiedPresetFilter{Since: "-24h", Limit: 200, Output: "json"}
+
+	cmd, start, limit, output := newCmd()
+	assert.NoError(t, applyPresetDefaults(preset, cmd))
+	assert.Equal(t, "-24h", *start)
+	assert.Equal(t, int32(200), *limit)
+	assert.Equal(t, "json", *output)
+	assert.True(t, cmd.Flags().Changed("limit"))
+
+	// Flags given by the user win, and an end time alone keeps the default time range
+	cmd, start, limit, output = newCmd()
+	assert.NoError(t, cmd.ParseFlags([]string{"-e", "-1h", "-l", "50", "-o", "text"}))
+	assert.NoError(t, applyPresetDefaults(preset, cmd))
+	assert.Empty(t, *start)
+	assert.Equal(t, int32(50), *limit)
+	assert.Equal(t, "text", *output)
+
+	// Commands without a flag ignore its default
+	bare := &cobra.Command{}
+	assert.NoError(t, applyPresetDefaults(preset, bare))
+}
+
+func TestPresetChannels(t *testing.T) {
+	origFollow, origLogTypes, origFilters, origIgnores := follow, logTypes, filterPatterns, ignoreFilterPatterns
+	defer func() {
+		follow, logTypes, filterPatterns, ignoreFilterPatterns = origFollow, origLogTypes, origFilters, origIgnores
+	}()
+	follow, logTypes, filterPatterns, ignoreFilterPatterns = true, nil, nil, nil
+
+	channels, err := presetChannels([]string{"api-errors", "auth-failures", "api-errors"}, &cobra.Command{})
+	assert.NoError(t, err)
+	assert.Len(t, channels, 2)
+	apiErrors, _ := filter.GetUnifiedPreset("api-errors")
+	authFailures, _ := filter.GetUnifiedPreset("auth-failures")
+	assert.Equal(t, "api-errors", channels[0].Label)
+	assert.Equal(t, apiErrors.LogTypes, channels[0].LogTypes)
+	assert.Equal(t, "auth-failures", channels[1].Label)
+	assert.Equal(t, authFailures.LogTypes, channels[1].LogTypes)
+
+	// Log types given apply to every preset
+	logTypes = []string{"audit"}
+	channels, err = presetChannels([]string{"api-errors", "auth-failures"}, &cobra.Command{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"audit"}, channels[1].LogTypes)
+
+	_, err = presetChannels([]string{"api-errors", "missing"}, &cobra.Command{})
+	assert.ErrorContains(t, err, "preset filter 'missing' not found")
+
+	filterPatterns = []string{"timeout"}
+	_, err = presetChannels([]string{"api-errors", "auth-failures"}, &cobra.Command{})
+	assert.ErrorContains(t, err, "cannot be combined with --filter-pattern")
+
+	follow = false
+	_, err = presetChannels([]string{"api-errors", "auth-failures"}, &cobra.Command{})
+	assert.ErrorContains(t, err, "add --follow")
+}
+
+func TestValidatePreset(t *testing.T) {
+	for _, name := range filter.ListUnifiedPresets() {
+		preset, _ := filter.GetUnifiedPreset(name)
+		assert.Empty(t, validatePreset(preset), name)
+	}
+
+	problems := validatePreset(filter.UnifiedPresetFilter{
+		LogTypes: []string{"api", "apiserver"},
+		Pattern:  `"unterminated`,
+		Since:    "yesterday",
+		Limit:    -1,
+		Output:   "xml",
+	})
+	assert.Len(t, problems, 5)
+	assert.ErrorContains(t, problems[0], "unbalanced quotes")
+	assert.ErrorContains(t, problems[1], "unknown log type 'apiserver'")
+
+	// Broken presets of the config file fail when the config is read
+	t.Cleanup(func() { delete(filter.UnifiedPresets, "typo") })
+	err := applyPresetConfig(map[string]config.Preset{"typo": {LogTypes: []string{"apiserver"}, Pattern: "error"}})
+	assert.ErrorContains(t, err, "preset 'typo': unknown log type")
+}
+
+func TestRerunArgs(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	entry := historyEntry{
+		Args:  []string{"my-cluster", "api", "-s", "-1h", "-F", "error", "--end-time=-5m", "-f", "-s-2h"},
+		Start: &start,
+		End:   &end,
+	}
+
+	assert.Equal(t, []string{"my-cluster", "api", "-F", "error",
+		"--start-time", "2024-01-01T10:00:00.000Z", "--end-time", "2024-01-01T11:00:00.000Z"}, rerunArgs(entry, false))
+	assert.Equal(t, []string{"my-cluster", "api", "-F", "error", "--follow"}, rerunArgs(entry, true))
+
+	// Arguments after -- are kept as they are
+	entry = historyEntry{Args: []string{"-F", "x", "--", "-s"}}
+	assert.Equal(t, []string{"-F", "x", "--", "-s"}, rerunArgs(entry, false))
+}
+
+func TestAppendHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ekslogs", "history.jsonl")
+	entries, err := readHistory(path)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	for i := 0; i < maxHistoryEntries+2; i++ {
+		assert.NoError(t, appendHistory(path, historyEntry{Cluster: "my-cluster", Events: i}))
+	}
+	entries, err = readHistory(path)
+	assert.NoError(t, err)
+	assert.Len(t, entries, maxHistoryEntries)
+	assert.Equal(t, 3, entries[0].ID)
+	assert.Equal(t, maxHistoryEntries+2, entries[len(entries)-1].ID)
+
+	entry, ok := findHistory(entries, 42)
+	assert.True(t, ok)
+	assert.Equal(t, 41, entry.Events)
+	_, ok = findHistory(entries, 1)
+	assert.False(t, ok)
+}
+
+func TestPrintHistory(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	followEnd := start.Add(5 * time.Minute)
+	entries := []historyEntry{
+		{ID: 1, Time: end, Args: []string{"my-cluster", "-F", "connection refused"}, Cluster: "my-cluster", Start: &start, End: &end, Events: 12},
+		{ID: 2, Time: followEnd, Args: []string{"my-cluster", "-f"}, Cluster: "my-cluster", Start: &start, End: &followEnd, Follow: true, ExitCode: 1},
+	}
+
+	var out bytes.Buffer
+	printHistory(&out, entries)
+	assert.Equal(t, "ID  TIME                  CLUSTER     RANGE        EVENTS  EXIT  COMMAND\n"+
+		"1   2024-01-01T11:00:00Z  my-cluster  1h0m0s       12      0     ekslogs my-cluster -F 'connection refused'\n"+
+		"2   2024-01-01T10:05:00Z  my-cluster  follow 5m0s  0       1     ekslogs my-cluster -f\n", out.String())
+
+	out.Reset()
+	printHistory(&out, nil)
+	assert.Equal(t, "No queries in the history.\n", out.String())
+}
+
+func TestShellJoin(t *testing.T) {
+	assert.Equal(t, `my-cluster -F '{ $.verb = "delete" }' -I 'it'\''s' ''`,
+		shellJoin([]string{"my-cluster", "-F", `{ $.verb = "delete" }`, "-I", "it's", ""}))
+}
+
+func TestSavedQueryArgs(t *testing.T) {
+	query := config.SavedQuery{Args: []string{"prod-cluster", "audit", "-s", "-24h"}}
+	assert.Equal(t, []string{"prod-cluster", "audit", "-s", "-24h"}, savedQueryArgs(query, "", nil))
+	assert.Equal(t, []string{"--config", "/tmp/ekslogs.yaml", "prod-cluster", "audit", "-s", "-24h", "-o", "json"},
+		savedQueryArgs(query, "/tmp/ekslogs.yaml", []string{"-o", "json"}))
+}
+
+func TestPrintNamedQueries(t *testing.T) {
+	var out bytes.Buffer
+	printNamedQueries(&out, map[string]config.SavedQuery{
+		"prod-denied": {Description: "Denied requests", Args: []string{"prod-cluster", "audit", "-F", "forbidden"}},
+		"api-errors":  {Args: []string{"prod-cluster", "-p", "api-errors", "-s", "-1h"}},
+	})
+	assert.Equal(t, "NAME         DESCRIPTION      COMMAND\n"+
+		"api-errors   -                ekslogs prod-cluster -p api-errors -s -1h\n"+
+		"prod-denied  Denied requests  ekslogs prod-cluster audit -F forbidden\n", out.String())
+
+	out.Reset()
+	printNamedQueries(&out, nil)
+	assert.Contains(t, out.String(), "No saved queries.")
+}
+
+func TestApplyLogTypeConfig(t *testing.T) {
+	t.Cleanup(func() { log.UnregisterLogType("karpenter") })
+
+	err := applyLogTypeConfig(map[string]config.LogType{
+		"karpenter": {Description: "Karpenter controller logs", LogGroup: "/aws/eks/{cluster}/karpenter", Streams: []string{"controller-"}, Aliases: []string{"kpt"}},
+	})
+	assert.NoError(t, err)
+	assert.True(t, isLogTypeArg("kpt"))
+
+	var out bytes.Buffer
+	printCustomLogTypes(&out, log.CustomLogTypes())
+	assert.Equal(t, "  karpenter     - Karpenter controller logs (log group /aws/eks/{cluster}/karpenter)\n"+
+		"                  Streams: controller-\n"+
+		"                  Alias: kpt\n", out.String())
+
+	err = applyLogTypeConfig(map[string]config.LogType{"mine": {Aliases: []string{"auth"}}})
+	assert.ErrorContains(t, err, "invalid log type in config: log type 'mine' needs a log group or stream prefixes")
+}
+
+// TestLogTypeStatuses tests the availability of the log types of a cluster from its logging
+// config and the last event of each log type
+func TestLogTypeStatuses(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	enabled, disabled := true, false
+	cluster := &ekstypes.Cluster{Logging: &ekstypes.Logging{ClusterLogging: []ekstypes.LogSetup{
+		{Enabled: &enabled, Types: []ekstypes.LogType{ekstypes.LogTypeApi, ekstypes.LogTypeAudit, ekstypes.LogTypeControllerManager}},
+		{Enabled: &disabled, Types: []ekstypes.LogType{ekstypes.LogTypeAuthenticator, ekstypes.LogTypeScheduler}},
+	}}}
+	lastEvents := map[string]time.Time{
+		"api":       now.Add(-time.Minute),
+		"audit":     now.Add(-48 * time.Hour),
+		"kcm":       now.Add(-time.Hour),
+		"scheduler": now.Add(-time.Hour),
+	}
+
+	statuses := logTypeStatuses(cluster, lastEvents, now.Add(-24*time.Hour))
+	got := map[string]string{}
+	for _, status := range statuses {
+		got[status.LogType] = status.Status
+	}
+	assert.Equal(t, map[string]string{
+		"api":           logTypeActive,
+		"audit":         logTypeSilent,
+		"authenticator": logTypeDisabled,
+		"kcm":           logTypeActive,
+		"ccm":           logTypeSilent,
+		"scheduler":     logTypeDisabled,
+	}, got)
+
+	var buf bytes.Buffer
+	printLogTypeStatuses(&buf, "prod", statuses)
+	output := buf.String()
+	assert.Contains(t, output, "LOG TYPE")
+	assert.Contains(t, output, "Enabled but silent: audit, ccm.")
+	assert.Contains(t, output, "Disabled: authenticator, scheduler.")
+	assert.Contains(t, output, `--name prod --logging '{"clusterLogging":[{"types":["authenticator","scheduler"],"enabled":true}]}'`)
+}
+
+// TestShutdownSignals tests that Ctrl+C stops a retrieval on every platform, and SIGTERM
+// where it exists
+func TestShutdownSignals(t *testing.T) {
+	assert.Contains(t, shutdownSignals, os.Interrupt)
+	if runtime.GOOS == "windows" {
+		assert.Len(t, shutdownSignals, 1)
+	} else {
+		assert.Contains(t, shutdownSignals, os.Signal(syscall.SIGTERM))
+	}
+}
+
+func TestCompressWriter(t *testing.T) {
+	content := strings.Repeat("I0101 12:00:00.000000 1 controller.go:42] synced\n", 100)
+
+	var buf bytes.Buffer
+	w, err := newCompressWriter(&buf, "gzip")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, content)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	gz, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+
+	buf.Reset()
+	w, err = newCompressWriter(&buf, "none")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, content)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, content, buf.String())
+
+	_, err = newCompressWriter(&buf, "bzip2")
+	assert.ErrorContains(t, err, "invalid compression 'bzip2'")
+
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd is not installed")
+	}
+	assert.NoError(t, validateCompression("zstd"))
+	buf.Reset()
+	w, err = newCompressWriter(&buf, "zstd")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, content)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Less(t, buf.Len(), len(content))
+	decompress := exec.Command("zstd", "-d", "-c")
+	decompress.Stdin = &buf
+	data, err = decompress.Output()
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+}
+
+func TestEventSelection(t *testing.T) {
+	// --limit keeps the most recent events, like --tail
+	recent, forward := eventSelection(0, 50, false)
+	assert.Equal(t, 50, recent)
+	assert.Equal(t, int32(0), forward)
+
+	recent, forward = eventSelection(0, 50, true)
+	assert.Equal(t, 0, recent)
+	assert.Equal(t, int32(50), forward)
+
+	recent, forward = eventSelection(20, 0, false)
+	assert.Equal(t, 20, recent)
+	assert.Equal(t, int32(0), forward)
+}
+
+// TestParseByteSize tests parsing of --max-bytes sizes
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{input: "1048576", expected: 1048576},
+		{input: "500MB", expected: 500 * 1000 * 1000},
+		{input: "2GiB", expected: 2 << 30},
+		{input: "100k", expected: 100 << 10},
+		{input: "1.5 G", expected: 3 << 29},
+		{input: "lots", wantErr: true},
+		{input: "-1MB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseByteSize(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// TestCheckPreflightEstimate tests the confirmation required for large time ranges
+func TestCheckPreflightEstimate(t *testing.T) {
+	defer func() { assumeYes = false }()
+
+	assert.Equal(t, "1.5 GiB", formatBytes(3<<29))
+	assert.NoError(t, checkPreflightEstimate(100<<20, 0))
+	assert.Error(t, checkPreflightEstimate(2<<30, 0))
+	assert.Error(t, checkPreflightEstimate(100<<20, 50<<20))
+
+	assumeYes = true
+	assert.NoError(t, checkPreflightEstimate(2<<30, 0))
+}
+
+// TestPrintStats tests the retrieval statistics report
+func TestPrintStats(t *testing.T) {
+	stats := aws.Stats{Events: 10, Pages: 2, APICalls: 4, Retries: 1, Throttles: 1, Duplicates: 3, Bytes: 2048, Elapsed: 2 * time.Second, ElapsedSeconds: 2, EventsPerSecond: 5}
+
+	var buf bytes.Buffer
+	printStats(&buf, stats, "text")
+	assert.Contains(t, buf.String(), "Events:     10")
+	assert.Contains(t, buf.String(), "Duplicates: 3")
+	assert.Contains(t, buf.String(), "Bytes:      2.0 KiB (2048)")
+	assert.Contains(t, buf.String(), "Events/sec: 5.0")
+
+	buf.Reset()
+	printStats(&buf, stats, "json")
+	assert.Equal(t, `{"events":10,"pages":2,"api_calls":4,"retries":1,"throttles":1,"duplicates":3,"bytes":2048,"elapsed_seconds":2,"events_per_second":5}`+"\n", buf.String())
+
+	assert.NoError(t, validateStatsFormat("json"))
+	assert.Error(t, validateStatsFormat("yaml"))
+}
+
+// TestParseLogLevel tests resolving the diagnostic log level from the flags
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		verbose  bool
+		debug    bool
+		quiet    bool
+		expected slog.Level
+		wantErr  bool
+	}{
+		{expected: slog.LevelWarn},
+		{verbose: true, expected: slog.LevelInfo},
+		{debug: true, verbose: true, expected: slog.LevelDebug},
+		{quiet: true, verbose: true, expected: slog.LevelError},
+		{level: "info", quiet: true, expected: slog.LevelInfo},
+		{level: "error", debug: true, expected: slog.LevelError},
+		{level: "INFO", expected: slog.LevelInfo},
+		{level: "trace", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		level, err := parseLogLevel(tt.level, tt.verbose, tt.debug, tt.quiet)
+		if tt.wantErr {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, level)
+	}
+}
+
+// TestExitCode tests mapping errors to the documented exit codes
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"success", nil, exitOK},
+		{"generic error", errors.New("boom"), exitError},
+		{"no events", errNoEvents, exitNoEvents},
+		{"access denied", fmt.Errorf("failed to get log groups: %w", &smithy.GenericAPIError{Code: "AccessDeniedException"}), exitAuth},
+		{"expired token", &smithy.GenericAPIError{Code: "ExpiredTokenException"}, exitAuth},
+		{"sso session expired", fmt.Errorf("failed to refresh cached credentials, %w", &ssocreds.InvalidTokenError{}), exitAuth},
+		{"throttled", fmt.Errorf("warning: %w", &smithy.GenericAPIError{Code: "ThrottlingException"}), exitThrottled},
+		{"cluster not found", fmt.Errorf("cluster 'test' %w", aws.ErrClusterNotFound), exitNotFound},
+		{"no log groups", fmt.Errorf("%w for cluster 'test'", aws.ErrNoLogGroups), exitNotFound},
+		{"resource not found", &smithy.GenericAPIError{Code: "ResourceNotFoundException"}, exitNotFound},
+		{"silence", fmt.Errorf("%w: no audit events for 10m0s", aws.ErrSilence), exitSilence},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exitCode(tt.err))
+		})
+	}
+}
+
+// TestFormatProgress tests rendering the progress line
+func TestFormatProgress(t *testing.T) {
+	progress := aws.Progress{
+		Events: 1200,
+		Groups: []aws.GroupProgress{
+			{LogGroup: "/aws/eks/test/a", Reached: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC), Coverage: 0.25},
+			{LogGroup: "/aws/eks/test/b", Done: true, Coverage: 1},
+			{LogGroup: "/aws/eks/test/c"},
+		},
+	}
+
+	line := formatProgress("⠋", progress, 3400*time.Millisecond)
+	assert.Equal(t, "⠋ 1200 events · /aws/eks/test/a 25% (at 2024-01-01T01:00:00Z) · /aws/eks/test/b done · /aws/eks/test/c waiting · 3s", line)
+}
+
+// TestPrintInterruptSummary tests the summary printed after Ctrl+C
+func TestPrintInterruptSummary(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	progress := aws.Progress{
+		Events: 42,
+		Start:  start,
+		End:    start.Add(time.Hour),
+		Groups: []aws.GroupProgress{
+			{LogGroup: "/aws/eks/test/a", Reached: start.Add(1500 * time.Millisecond), LastEventID: "38012345"},
+			{LogGroup: "/aws/eks/test/b", Done: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	printInterruptSummary(&buf, progress, resumeRange)
+	assert.Equal(t, "Interrupted after 42 events\n"+
+		"  /aws/eks/test/a: read up to 2024-01-01T00:00:01.500Z (event 38012345)\n"+
+		"  /aws/eks/test/b: complete\n"+
+		"Resume with: -s 2024-01-01T00:00:01.500Z -e 2024-01-01T01:00:00.000Z\n", buf.String())
+
+	buf.Reset()
+	printInterruptSummary(&buf, progress, resumeFollow)
+	assert.Contains(t, buf.String(), "Resume with: -s 2024-01-01T00:00:01.500Z\n")
+
+	buf.Reset()
+	printInterruptSummary(&buf, progress, resumeNone)
+	assert.NotContains(t, buf.String(), "Resume with")
+
+	// The resume times are accepted by --start-time
+	parsed, err := log.ParseTimeString(formatResumeTime(start.Add(1500 * time.Millisecond)))
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(1500*time.Millisecond), parsed.UTC())
+}
+
+// TestApplyContext tests resolving the cluster and log types with a context
+func TestApplyContext(t *testing.T) {
+	t.Setenv("AWS_PROFILE", "")
+
+	// Without a context, the cluster name is required
+	_, _, err := applyContext(nil, nil)
+	assert.Error(t, err)
+	cluster, types, err := applyContext(nil, []string{"my-cluster", "api"})
+	assert.NoError(t, err)
+	assert.Equal(t, "my-cluster", cluster)
+	assert.Equal(t, []string{"api"}, types)
+
+	prod := &config.Context{Cluster: "prod-cluster", Region: "us-west-2", Profile: "prod-admin"}
+
+	cluster, types, err = applyContext(prod, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-cluster", cluster)
+	assert.Empty(t, types)
+	assert.Equal(t, "us-west-2", contextRegion(prod, cluster))
+	assert.Equal(t, "prod-admin", os.Getenv("AWS_PROFILE"))
+
+	// Log types alone select the context's cluster, another name overrides it
+	cluster, types, err = applyContext(prod, []string{"audit", "auth"})
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-cluster", cluster)
+	assert.Equal(t, []string{"audit", "auth"}, types)
+	cluster, types, err = applyContext(prod, []string{"other-cluster", "audit"})
+	assert.NoError(t, err)
+	assert.Equal(t, "other-cluster", cluster)
+	assert.Equal(t, []string{"audit"}, types)
+
+	// The region and profile of the context do not apply to another cluster
+	t.Setenv("AWS_PROFILE", "")
+	_, _, err = applyContext(prod, []string{"other-cluster"})
+	assert.NoError(t, err)
+	assert.Empty(t, contextRegion(prod, "other-cluster"))
+	assert.Empty(t, os.Getenv("AWS_PROFILE"))
+	assert.Empty(t, contextRoleARN(&config.Context{Cluster: "prod-cluster", RoleARN: "arn:aws:iam::123456789012:role/r"}, "other-cluster"))
+
+	// An exported AWS_PROFILE wins over the context
+	t.Setenv("AWS_PROFILE", "personal")
+	_, _, err = applyContext(prod, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "personal", os.Getenv("AWS_PROFILE"))
+
+	// Node log types are cluster names unless Container Insights logs are read
+	cluster, types, err = applyContext(prod, []string{"app", "audit"})
+	assert.NoError(t, err)
+	assert.Equal(t, "app", cluster)
+	assert.Equal(t, []string{"audit"}, types)
+	containerInsights = true
+	defer func() { containerInsights = false }()
+	cluster, _, err = applyContext(prod, []string{"app"})
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-cluster", cluster)
+}
+
+// TestPrintContexts tests listing the contexts of the config file
+func TestPrintContexts(t *testing.T) {
+	var buf bytes.Buffer
+	printContexts(&buf, &config.Config{})
+	assert.Equal(t, "No contexts defined in the config file.\n", buf.String())
+
+	buf.Reset()
+	printContexts(&buf, &config.Config{
+		CurrentContext: "prod",
+		Contexts: map[string]config.Context{
+			"staging": {Cluster: "staging-cluster"},
+			"prod":    {Cluster: "prod-cluster", Region: "us-west-2", Profile: "prod-admin"},
+		},
+	})
+	assert.Equal(t, "* prod                 cluster=prod-cluster region=us-west-2 profile=prod-admin\n"+
+		"  staging              cluster=staging-cluster\n", buf.String())
+}
+
+// TestFleetAccountName tests naming the accounts of the fleet
+func TestFleetAccountName(t *testing.T) {
+	assert.Equal(t, "prod", fleetAccountName(config.FleetAccount{Name: "prod", RoleARN: "arn:aws:iam::123456789012:role/reader"}))
+	assert.Equal(t, "123456789012", fleetAccountName(config.FleetAccount{RoleARN: "arn:aws:iam::123456789012:role/reader"}))
+	assert.Equal(t, "default", fleetAccountName(config.FleetAccount{}))
+}
+
+// TestFleetOutput tests tagging fleet entries with their account and cluster
+func TestFleetOutput(t *testing.T) {
+	entry := log.LogEntry{
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Level:     "ERROR",
+		Component: "kube-apiserver",
+		Message:   "boom",
+		LogGroup:  "/aws/eks/prod-cluster/cluster",
+		LogStream: "kube-apiserver-123",
+	}
+	colorConfig := log.NewColorConfig()
+	colorConfig.Mode = log.ColorModeNever
+
+	var buf bytes.Buffer
+	out := &fleetOutput{w: &buf, printer: log.NewPrinter(log.OutputOptions{}, colorConfig)}
+	target := fleetTarget{account: "prod", region: "us-east-1", cluster: "prod-cluster"}
+	out.print(target, entry)
+	assert.Equal(t, "[prod/prod-cluster] 2024-01-01T00:00:00Z [ERROR] [kube-apiserver] boom\n", buf.String())
+
+	buf.Reset()
+	out = &fleetOutput{w: &buf, printer: log.NewPrinter(log.OutputOptions{Format: log.OutputFormatJSON}, colorConfig), json: true}
+	out.print(target, entry)
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "prod", decoded["account"])
+	assert.Equal(t, "prod-cluster", decoded["cluster"])
+	assert.Equal(t, "us-east-1", decoded["region"])
+	assert.Equal(t, "boom", decoded["message"])
+}
+
+// TestPickCluster tests choosing among similar cluster names
+func TestPickCluster(t *testing.T) {
+	matches := []string{"prod", "prod-eu"}
+
+	var prompt bytes.Buffer
+	selected, err := pickCluster(strings.NewReader("2\n"), &prompt, "prd", matches)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-eu", selected)
+	assert.Equal(t, "Cluster 'prd' not found. Did you mean:\n  1) prod\n  2) prod-eu\nSelect a cluster [1-2]: ", prompt.String())
+
+	for _, input := range []string{"3\n", "x\n", ""} {
+		_, err := pickCluster(strings.NewReader(input), io.Discard, "prd", matches)
+		assert.Error(t, err, input)
+	}
+}
+
+// TestPromptTarget tests choosing a cluster, log types and a preset without arguments
+func TestPromptTarget(t *testing.T) {
+	clusters := []string{"dev", "prod", "prod-eu", "staging"}
+	presets := []string{"api-errors", "auth-failures"}
+
+	// Filter, then pick by number; two log types; no preset
+	input := bufio.NewReader(strings.NewReader("prod\n2\n1,2\n\n"))
+	cluster, types, preset, err := promptTarget(input, io.Discard, clusters, presets)
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-eu", cluster)
+	assert.Equal(t, []string{"api", "audit"}, types)
+	assert.Empty(t, preset)
+
+	// A single fuzzy match is selected; all log types; preset by filter
+	input = bufio.NewReader(strings.NewReader("stg\n\nauthf\n"))
+	cluster, types, preset, err = promptTarget(input, io.Discard, clusters, presets)
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", cluster)
+	assert.Empty(t, types)
+	assert.Equal(t, "auth-failures", preset)
+
+	// Running out of input is an error
+	_, _, _, err = promptTarget(bufio.NewReader(strings.NewReader("\n")), io.Discard, clusters, presets)
+	assert.Error(t, err)
+}
+
+// TestFuzzyMatch tests matching items by characters in order
+func TestFuzzyMatch(t *testing.T) {
+	items := []string{"prod", "prod-eu", "staging", "Dev"}
+	assert.Equal(t, []string{"prod", "prod-eu"}, fuzzyMatch("prd", items))
+	assert.Equal(t, []string{"prod-eu"}, fuzzyMatch("peu", items))
+	assert.Equal(t, []string{"Dev"}, fuzzyMatch("dv", items))
+	assert.Empty(t, fuzzyMatch("xyz", items))
+}
+
+// TestPrintBreakGlassReport tests the per-user timeline of privileged events
+func TestPrintBreakGlassReport(t *testing.T) {
+	timelines := []log.UserTimeline{{
+		User: "alice",
+		Events: []log.PrivilegedEvent{{
+			Time:         time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			User:         "alice",
+			Reason:       log.ReasonImpersonation,
+			Impersonated: "bob",
+			Verb:         "get",
+			Resource:     "pods",
+			Namespace:    "default",
+			Name:         "web",
+			Code:         200,
+			SourceIP:     "10.0.1.5",
+		}},
+	}}
+
+	var out bytes.Buffer
+	printBreakGlassReport(&out, timelines, false)
+	assert.Equal(t, "alice (1 event)\n  2024-01-01T10:00:00Z  impersonation as bob  get pods default/web  200  from 10.0.1.5\n", out.String())
+
+	out.Reset()
+	printBreakGlassReport(&out, nil, false)
+	assert.Equal(t, "No impersonation or system:masters writes found.\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, printBreakGlassJSON(&out, timelines))
+	assert.Contains(t, out.String(), `"user":"alice","events":[{"time":"2024-01-01T10:00:00Z"`)
+}
+
+// TestPrintEtcdReport tests the histogram of storage warnings
+func TestPrintEtcdReport(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	buckets := []log.StorageBucket{
+		{Start: start, Latency: 3, Timeout: 1},
+		{Start: start.Add(10 * time.Minute)},
+		{Start: start.Add(20 * time.Minute), Size: 2},
+	}
+
+	var out bytes.Buffer
+	printEtcdReport(&out, buckets, 10*time.Minute)
+	assert.Equal(t, "Storage warnings per 10m0s\n"+
+		"2024-01-01T10:00:00Z  latency    3  timeout    1  size    0  "+strings.Repeat("#", 40)+"\n"+
+		"2024-01-01T10:10:00Z  latency    0  timeout    0  size    0  \n"+
+		"2024-01-01T10:20:00Z  latency    0  timeout    0  size    2  "+strings.Repeat("#", 20)+"\n"+
+		"Total: 3 latency, 1 timeout, 2 size warnings\n", out.String())
+
+	assert.Equal(t, time.Hour, defaultEtcdBucket(24*time.Hour))
+	assert.Equal(t, time.Minute, defaultEtcdBucket(10*time.Minute))
+}
+
+// TestPrintThrottlingReport tests the table of throttled clients
+func TestPrintThrottlingReport(t *testing.T) {
+	groups := []log.ThrottleGroup{
+		{FlowSchema: "service-accounts", PriorityLevel: "workload-low", UserAgent: "karpenter/v0.33.0 (linux/amd64)", User: "karpenter", Count: 12},
+		{UserAgent: "kubectl/v1.29.0", Count: 1},
+	}
+
+	var out bytes.Buffer
+	printThrottlingReport(&out, groups)
+	assert.Equal(t, "COUNT  FLOW SCHEMA       PRIORITY LEVEL  USER AGENT         USER\n"+
+		"12     service-accounts  workload-low    karpenter/v0.33.0  karpenter\n"+
+		"1      -                 -               kubectl/v1.29.0    -\n", out.String())
+
+	out.Reset()
+	printThrottlingReport(&out, nil)
+	assert.Equal(t, "No requests rejected with 429 found.\n", out.String())
+}
+
+// TestPrintSourceIPsReport tests the table of source IPs
+func TestPrintSourceIPsReport(t *testing.T) {
+	summaries := []log.SourceIPSummary{
+		{IP: "203.0.113.7", Scope: log.ScopePublic, Requests: 7, Outside: true, Users: []log.UserCount{
+			{User: "alice", Requests: 3}, {User: "bob", Requests: 2}, {User: "carol", Requests: 1}, {User: "dave", Requests: 1},
+		}},
+		{IP: "10.0.1.5", Scope: log.ScopePrivate, Requests: 2, Users: []log.UserCount{{User: "system:node:ip-10-0-1-5", Requests: 2}}},
+	}
+
+	var out bytes.Buffer
+	printSourceIPsReport(&out, summaries, true)
+	assert.Equal(t, "   SOURCE IP    SCOPE    REQUESTS  USERS\n"+
+		"!  203.0.113.7  public   7         alice (3), bob (2), carol (1), +1 more\n"+
+		"   10.0.1.5     private  2         system:node:ip-10-0-1-5 (2)\n", out.String())
+
+	out.Reset()
+	printSourceIPsReport(&out, summaries[1:], false)
+	assert.Equal(t, "SOURCE IP  SCOPE    REQUESTS  USERS\n"+
+		"10.0.1.5   private  2         system:node:ip-10-0-1-5 (2)\n", out.String())
+
+	assert.Equal(t, summaries[:1], outsideSourceIPs(summaries))
+
+	out.Reset()
+	printSourceIPsReport(&out, nil, false)
+	assert.Equal(t, "No audit events with a source IP found.\n", out.String())
+}
+
+// TestPrintWatchChurnReport tests the table of watch clients
+func TestPrintWatchChurnReport(t *testing.T) {
+	clients := []log.WatchClient{
+		{UserAgent: "operator/v1.2.0 (linux/amd64)", User: "system:serviceaccount:ops:operator", Started: 120, Ended: 118, ShortLived: 110,
+			MeanDurationSeconds: 4.6, PerMinute: 2, Resources: []string{"configmaps", "secrets", "pods", "leases"}},
+		{UserAgent: "kubelet/v1.29.0", Started: 3, PerMinute: 0.5, Resources: []string{"pods"}},
+	}
+
+	var out bytes.Buffer
+	printWatchChurnReport(&out, clients)
+	assert.Equal(t, "PER MIN  STARTED  ENDED  UNDER 1M  MEAN  USER AGENT       USER                                RESOURCES\n"+
+		"2.0      120      118    110       5s    operator/v1.2.0  system:serviceaccount:ops:operator  configmaps,secrets,pods,+1 more\n"+
+		"0.5      3        0      0         -     kubelet/v1.29.0  -                                   pods\n", out.String())
+	assert.Len(t, clients[0].Resources, 4, "the resources of the client are left unchanged")
+
+	out.Reset()
+	printWatchChurnReport(&out, nil)
+	assert.Equal(t, "No watch requests found.\n", out.String())
+
+	start := time.Now().Add(-time.Hour)
+	end := start.Add(30 * time.Minute)
+	assert.Equal(t, 30*time.Minute, reportWindow(&start, &end))
+	assert.Zero(t, reportWindow(nil, &end))
+}
+
+// TestPrintQuotaReport tests the table of quota and limit range denials
+func TestPrintQuotaReport(t *testing.T) {
+	groups := []log.QuotaGroup{
+		{Namespace: "team-a", Kind: log.KindResourceQuota, Policy: "compute", Count: 12, Reason: "exceeded quota: compute, requested: limits.cpu=2"},
+		{Namespace: "team-a", Kind: log.KindLimitRange, Policy: "maximum cpu per Container", Count: 1, Reason: "maximum cpu usage per Container is 1, but limit is 2"},
+	}
+
+	var out bytes.Buffer
+	printQuotaReport(&out, groups)
+	assert.Equal(t, "NAMESPACE  COUNT  KIND           POLICY                     REASON\n"+
+		"team-a     12     ResourceQuota  compute                    exceeded quota: compute, requested: limits.cpu=2\n"+
+		"team-a     1      LimitRange     maximum cpu per Container  maximum cpu usage per Container is 1, but limit is 2\n", out.String())
+
+	out.Reset()
+	printQuotaReport(&out, nil)
+	assert.Equal(t, "No requests denied by a ResourceQuota or LimitRange found.\n", out.String())
+}
+
+// TestPrintAdmissionReport tests the table of admission policy violations
+func TestPrintAdmissionReport(t *testing.T) {
+	groups := []log.AdmissionGroup{
+		{Namespace: "team-a", Controller: log.AdmissionPodSecurity, Policy: "restricted:latest", Action: log.AdmissionDeny, Count: 4, Reason: "privileged"},
+		{Namespace: "team-a", Controller: log.AdmissionVAP, Policy: "max-replicas", Binding: "prod", Action: log.AdmissionAudit, Count: 1, Reason: "replicas must be at most 5"},
+	}
+
+	var out bytes.Buffer
+	printAdmissionReport(&out, groups)
+	assert.Equal(t, "NAMESPACE  COUNT  ACTION  CONTROLLER                 POLICY               REASON\n"+
+		"team-a     4      deny    PodSecurity                restricted:latest    privileged\n"+
+		"team-a     1      audit   ValidatingAdmissionPolicy  max-replicas (prod)  replicas must be at most 5\n", out.String())
+
+	out.Reset()
+	printAdmissionReport(&out, nil)
+	assert.Equal(t, "No PodSecurity or ValidatingAdmissionPolicy violations found.\n", out.String())
+}
+
+func TestPrintAPIHealthReport(t *testing.T) {
+	issues := []log.APIHealthIssue{{
+		Problem:   log.APIServiceUnavailable,
+		Object:    "v1beta1.metrics.k8s.io",
+		Count:     12,
+		FirstSeen: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+		LastSeen:  time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC),
+	}}
+
+	var out bytes.Buffer
+	printAPIHealthReport(&out, issues)
+	assert.Equal(t, "PROBLEM                 OBJECT                  COUNT  FIRST SEEN            LAST SEEN\n"+
+		"APIService unavailable  v1beta1.metrics.k8s.io  12     2024-01-01T01:00:00Z  2024-01-01T03:00:00Z\n", out.String())
+
+	out.Reset()
+	printAPIHealthReport(&out, nil)
+	assert.Equal(t, "No aggregated API or CRD problems found.\n", out.String())
+}
+
+// TestPrintReportDocument tests a report printed to a Markdown document
+func TestPrintReportDocument(t *testing.T) {
+	workload := log.Workload{Namespace: "shop", Name: "web"}
+	events := []log.ScalingEvent{{
+		Time:    time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		LogType: "kcm",
+		Kind:    "hpa rescale",
+		Summary: "3 -> 5 replicas",
+	}}
+
+	var out bytes.Buffer
+	document := log.NewDocument(&out, log.OutputFormatMarkdown, "ekslogs scaling my-cluster")
+	printScalingReport(document, workload, events)
+	assert.NoError(t, document.Close())
+	assert.Equal(t, "# ekslogs scaling my-cluster\n\n"+
+		"```\nshop/web (1 decision)\n```\n\n"+
+		"| TIME | LOG | KIND | DETAILS |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| 2024-01-01T10:00:00Z | kcm | hpa rescale | 3 -> 5 replicas |\n", out.String())
+}
+
+// TestPrintCertsReport tests the table of certificate errors
+func TestPrintCertsReport(t *testing.T) {
+	issues := []log.CertificateIssue{{
+		Problem:   log.CertExpired,
+		Identity:  "client certificate SN=42",
+		Count:     2,
+		FirstSeen: time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+		LastSeen:  time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC),
+	}}
+
+	var out bytes.Buffer
+	printCertsReport(&out, issues)
+	assert.Equal(t, "PROBLEM  IDENTITY                  COUNT  FIRST SEEN            LAST SEEN\n"+
+		"expired  client certificate SN=42  2      2024-01-01T01:00:00Z  2024-01-01T03:00:00Z\n", out.String())
+
+	out.Reset()
+	printCertsReport(&out, nil)
+	assert.Equal(t, "No certificate errors found.\n", out.String())
+}
+
+// TestReportFlags tests that each report subcommand has its own flags
+func TestReportFlags(t *testing.T) {
+	for _, c := range []*cobra.Command{breakGlassCmd, bundleCmd, certsCmd, cloudTrailCmd, etcdCmd, throttlingCmd, timelineCmd} {
+		for _, name := range []string{"region", "start-time", "end-time", "output", "timeout", "verbose", "quiet", "debug", "log-level"} {
+			assert.NotNil(t, c.Flags().Lookup(name), "%s --%s", c.Name(), name)
+		}
+	}
+
+	assert.NoError(t, etcdCmd.Flags().Set("output", "json"))
+	defer func() { _ = etcdCmd.Flags().Set("output", "text") }()
+	assert.Equal(t, "json", etcdOptions.output)
+	assert.Equal(t, "text", certsOptions.output)
+	assert.Equal(t, "text", outputFormat)
+}
+
+// TestReportFinish tests that a report stopped by Ctrl+C or --timeout is marked as partial
+func TestReportFinish(t *testing.T) {
+	r := &report{cmd: &cobra.Command{}, options: &reportOptions{timeout: time.Minute}, ctx: context.Background()}
+	assert.NoError(t, r.finish())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ctx = ctx
+	err := r.finish()
+	assert.ErrorIs(t, err, errPartialReport)
+	assert.NotErrorIs(t, err, errTimeout)
+	assert.True(t, r.cmd.SilenceUsage)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	r.ctx = ctx
+	err = r.finish()
+	assert.ErrorIs(t, err, errPartialReport)
+	assert.ErrorIs(t, err, errTimeout)
+	assert.Equal(t, "report is partial: retrieval timed out after 1m0s (--timeout)", err.Error())
+	assert.Equal(t, exitError, exitCode(err))
+}
+
+func TestPrintTimelineReport(t *testing.T) {
+	ref := log.ObjectRef{Resource: "pods", Namespace: "shop", Name: "web"}
+	events := []log.TimelineEvent{
+		{Time: time.Date(2024, 1, 1, 10, 0, 1, 0, time.UTC), LogType: "audit", Stage: log.StageCreated, Summary: "create", User: "alice"},
+		{Time: time.Date(2024, 1, 1, 10, 0, 3, 0, time.UTC), LogType: "scheduler", Stage: log.StageScheduled, Summary: `"Successfully bound pod to node"`},
+	}
+
+	var out bytes.Buffer
+	printTimelineReport(&out, ref, events)
+	assert.Equal(t, "pods shop/web (2 steps)\n"+
+		"TIME                  LOG        STAGE      DETAILS\n"+
+		"2024-01-01T10:00:01Z  audit      created    create by alice\n"+
+		"2024-01-01T10:00:03Z  scheduler  scheduled  \"Successfully bound pod to node\"\n", out.String())
+
+	out.Reset()
+	printTimelineReport(&out, ref, nil)
+	assert.Equal(t, "No changes to pods shop/web found.\n", out.String())
+}
+
+// TestPrintScalingReport tests the scaling decisions of a workload
+func TestPrintScalingReport(t *testing.T) {
+	workload := log.Workload{Namespace: "shop", Name: "web"}
+	events := []log.ScalingEvent{
+		{Time: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), LogType: "kcm", Kind: log.ScalingRescale, Summary: "3 -> 5 replicas: cpu"},
+		{Time: time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC), LogType: "audit", Kind: log.ScalingEviction, Summary: "eviction of shop/web-0", User: "karpenter"},
+	}
+
+	var out bytes.Buffer
+	printScalingReport(&out, workload, events)
+	assert.Equal(t, "shop/web (2 decisions)\n"+
+		"TIME                  LOG    KIND         DETAILS\n"+
+		"2024-01-01T10:00:00Z  kcm    hpa rescale  3 -> 5 replicas: cpu\n"+
+		"2024-01-01T10:05:00Z  audit  evicted      eviction of shop/web-0 by karpenter\n", out.String())
+
+	out.Reset()
+	printScalingReport(&out, workload, nil)
+	assert.Equal(t, "No scaling decisions or evictions of shop/web found.\n", out.String())
+}
+
+func TestWriteBundle(t *testing.T) {
+	logs := &bundleLogs{dir: t.TempDir(), files: make(map[string]*bundleLogFile)}
+	logs.write(log.LogEntry{LogStream: "kube-apiserver-audit-abc", Message: `{"verb":"get"}`})
+	logs.write(log.LogEntry{LogStream: "kube-apiserver-abc", Message: "I0101 started"})
+	logs.write(log.LogEntry{LogStream: "kube-apiserver-audit-abc", Message: `{"verb":"list"}`})
+	assert.NoError(t, logs.close())
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	index := bundleIndex{Cluster: "my-cluster", Region: "us-west-2", CreatedAt: created, Complete: true,
+		Files: append([]bundleFile{{Name: "cluster.json", Description: "eks:DescribeCluster output", data: []byte("{}\n")}}, logs.bundleFiles()...)}
+
+	var buf bytes.Buffer
+	assert.NoError(t, writeBundle(&buf, index, "gzip"))
+
+	gz, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	tr := tar.NewReader(gz)
+	contents := make(map[string]string)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		names = append(names, header.Name)
+		contents[header.Name] = string(data)
+	}
+	assert.Equal(t, []string{"index.json", "cluster.json", "logs/api.jsonl", "logs/audit.jsonl", "SHA256SUMS"}, names)
+	assert.Equal(t, 2, strings.Count(contents["logs/audit.jsonl"], "\n"))
+
+	var written bundleIndex
+	assert.NoError(t, json.Unmarshal([]byte(contents["index.json"]), &written))
+	assert.Equal(t, "my-cluster", written.Cluster)
+	assert.Len(t, written.Files, 3)
+	assert.Equal(t, "logs/audit.jsonl", written.Files[2].Name)
+	assert.Equal(t, 2, written.Files[2].Events)
+	assert.Equal(t, int64(len(contents["logs/audit.jsonl"])), written.Files[2].Bytes)
+
+	// The manifest and SHA256SUMS hold the checksum of each file
+	for _, name := range []string{"index.json", "cluster.json", "logs/api.jsonl", "logs/audit.jsonl"} {
+		sum := sha256.Sum256([]byte(contents[name]))
+		assert.Contains(t, contents["SHA256SUMS"], hex.EncodeToString(sum[:])+"  "+name+"\n")
+	}
+	auditSum := sha256.Sum256([]byte(contents["logs/audit.jsonl"]))
+	assert.Equal(t, hex.EncodeToString(auditSum[:]), written.Files[2].SHA256)
+
+	// The tarball written to a file gets a sha256sum file
+	path := filepath.Join(t.TempDir(), "incident.tar.gz")
+	assert.NoError(t, writeBundleFile(path, index, "gzip"))
+	tarball, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	checksum, err := os.ReadFile(path + ".sha256")
+	assert.NoError(t, err)
+	tarballSum := sha256.Sum256(tarball)
+	assert.Equal(t, hex.EncodeToString(tarballSum[:])+"  incident.tar.gz\n", string(checksum))
+
+	var out bytes.Buffer
+	written.Complete = false
+	written.Note = "stopped after retrieving 1.0 GiB (--max-bytes)"
+	printBundleSummary(&out, "incident.tar.gz", written)
+	assert.Equal(t, fmt.Sprintf("Wrote incident.tar.gz: 3 log events, 3 files, %s uncompressed\n", formatBytes(int64(len(contents["logs/audit.jsonl"])+len(contents["logs/api.jsonl"])+3)))+
+		"The bundle is incomplete: stopped after retrieving 1.0 GiB (--max-bytes)\n", out.String())
+}
+
+func TestParseRangeSpec(t *testing.T) {
+	start, end, err := parseRangeSpec("-25h..-24h")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, end.Sub(start).Round(time.Second))
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), end, time.Minute)
+
+	start, end, err = parseRangeSpec("2024-01-01T10:00:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), start.UTC())
+	assert.WithinDuration(t, time.Now(), end, time.Minute)
+
+	for _, spec := range []string{"", "..-1h", "-1h..-2h", "yesterday"} {
+		_, _, err := parseRangeSpec(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestPrintDiffReport(t *testing.T) {
+	changes := []log.TemplateChange{
+		{Change: log.ChangeNew, LogType: "kcm", Template: `"Error syncing pod" pod=<*>`, Count: 42},
+		{Change: log.ChangeIncreased, LogType: "api", Template: "audit create pods 403", Baseline: 10, Count: 35, Ratio: 3.5},
+	}
+
+	var out bytes.Buffer
+	printDiffReport(&out, changes)
+	assert.Equal(t, "CHANGE     LOG  BASELINE  COUNT  RATIO  TEMPLATE\n"+
+		"new        kcm  0         42     -      \"Error syncing pod\" pod=<*>\n"+
+		"increased  api  10        35     x3.5   audit create pods 403\n", out.String())
+
+	out.Reset()
+	printDiffReport(&out, nil)
+	assert.Equal(t, "No new or more frequent messages found.\n", out.String())
+}
+
+func TestSpikeNotifier(t *testing.T) {
+	received := make(chan spikeWebhookPayload, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload spikeWebhookPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received <- payload
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	spike := log.Spike{Time: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), Events: 540, Baseline: 87, Ratio: 6.2, Window: time.Minute}
+	spikeNotifier(&buf, server.URL, "my-cluster", newLogger(io.Discard, slog.LevelWarn))(spike)
+
+	assert.Contains(t, buf.String(), "Spike: 540 events in 1m0s, 6.2x the baseline of 87.0")
+	select {
+	case payload := <-received:
+		assert.Equal(t, "my-cluster", payload.Cluster)
+		assert.Equal(t, "1m0s", payload.Window)
+		assert.Equal(t, 540, payload.Events)
+		assert.Equal(t, "ekslogs: spike in my-cluster: 540 events in 1m0s, 6.2x the baseline of 87.0", payload.Text)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the webhook was not called")
+	}
+}
+
+func TestPostSpikeWebhookError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := postSpikeWebhook(server.Client(), server.URL, "my-cluster", log.Spike{Window: time.Minute})
+	assert.ErrorContains(t, err, "403 Forbidden")
+}
+
+func TestPrintMaterializeResult(t *testing.T) {
+	result := materializeResult{
+		Preset: "api-errors",
+		MetricFilter: aws.MetricFilter{
+			Name: "ekslogs-api-errors", LogGroup: "/aws/eks/prod/cluster", Pattern: "ERROR", Namespace: "EKS/Logs", MetricName: "prod/api-errors",
+		},
+		LogTypes: []string{"api"},
+	}
+	var buf bytes.Buffer
+	printMaterializeResult(&buf, result)
+	assert.Equal(t, `Metric filter ekslogs-api-errors on /aws/eks/prod/cluster
+  Pattern: ERROR
+  Metric:  EKS/Logs prod/api-errors (count of matching events)
+  Note:    the preset is meant for the api logs; the filter counts matches in every log type
+`, buf.String())
+
+	result.Alarm = &aws.MetricAlarm{Name: "ekslogs-prod-api-errors", Threshold: 10, Period: 5 * time.Minute, EvaluationPeriods: 2, Actions: []string{"arn:aws:sns:us-east-1:123456789012:oncall"}}
+	buf.Reset()
+	printMaterializeResult(&buf, result)
+	assert.Contains(t, buf.String(), "Alarm ekslogs-prod-api-errors: Sum >= 10 over 5m0s for 2 periods\n  Action: arn:aws:sns:us-east-1:123456789012:oncall\n")
+}
+
+func TestDestinationName(t *testing.T) {
+	assert.Equal(t, "siem", destinationName("arn:aws:firehose:us-east-1:123456789012:deliverystream/siem"))
+	assert.Equal(t, "alerts", destinationName("arn:aws:lambda:us-east-1:123456789012:function:alerts:live"))
+	assert.Equal(t, "central-logging", destinationName("arn:aws:logs:us-east-1:210987654321:destination:central-logging"))
+}
+
+func TestPrintSubscribeResult(t *testing.T) {
+	result := subscribeResult{
+		SubscriptionFilter: aws.SubscriptionFilter{
+			Name: "ekslogs-alerts", LogGroup: "/aws/eks/prod/cluster", DestinationARN: "arn:aws:lambda:us-east-1:123456789012:function:alerts",
+		},
+		SubscriptionResult: aws.SubscriptionResult{LambdaPermission: true},
+		Destination:        aws.DestinationLambda,
+	}
+	var buf bytes.Buffer
+	printSubscribeResult(&buf, result)
+	assert.Equal(t, `Created subscription filter ekslogs-alerts on /aws/eks/prod/cluster
+  Destination: arn:aws:lambda:us-east-1:123456789012:function:alerts (lambda)
+  Pattern:     (every event)
+  Allowed the log group to invoke the function (lambda:AddPermission)
+`, buf.String())
+
+	data, err := json.Marshal(result)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"filter_name":"ekslogs-alerts","log_group":"/aws/eks/prod/cluster","pattern":"","destination_arn":"arn:aws:lambda:us-east-1:123456789012:function:alerts","updated":false,"lambda_permission_added":true,"destination_kind":"lambda"}`, string(data))
+}
+
+func TestPrintInsightsResult(t *testing.T) {
+	result := aws.InsightsResult{
+		Fields: []string{"verb", "objectRef.resource", "requests"},
+		Rows: []map[string]string{
+			{"verb": "list", "objectRef.resource": "pods", "requests": "120"},
+			{"verb": "watch", "requests": "8"},
+		},
+	}
+	var buf bytes.Buffer
+	printInsightsResult(&buf, result)
+	assert.Equal(t, `verb   objectRef.resource  requests
+list   pods                120
+watch  -                   8
+`, buf.String())
+
+	buf.Reset()
+	printInsightsResult(&buf, aws.InsightsResult{Rows: []map[string]string{}})
+	assert.Equal(t, "No results.\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, printInsightsJSON(&buf, result))
+	assert.Equal(t, `{"objectRef.resource":"pods","requests":"120","verb":"list"}
+{"requests":"8","verb":"watch"}
+`, buf.String())
+}
+
+func TestPrintSavedQueries(t *testing.T) {
+	var buf bytes.Buffer
+	printSavedQueries(&buf, []savedQuery{
+		{QueryDefinition: aws.QueryDefinition{Name: "ekslogs/prod/audit-top-verbs", LogGroups: []string{"/aws/eks/prod/cluster"}}, Preset: "audit-top-verbs"},
+		{QueryDefinition: aws.QueryDefinition{Name: "ekslogs/prod/slow-requests", LogGroups: []string{"/aws/eks/prod/cluster"}}, Preset: "slow-requests", Updated: true},
+	})
+	assert.Equal(t, `Created saved query ekslogs/prod/audit-top-verbs on /aws/eks/prod/cluster
+Updated saved query ekslogs/prod/slow-requests on /aws/eks/prod/cluster
+`, buf.String())
+}
+
+func TestResultCache(t *testing.T) {
+	t.Setenv("EKSLOGS_CACHE_DIR", t.TempDir())
+	assert.NotNil(t, resultCache(false, false, defaultCacheTTL))
+	assert.Nil(t, resultCache(true, false, defaultCacheTTL))
+	assert.Nil(t, resultCache(false, false, 0))
+	// --offline reads the cache even with the cache disabled for new results
+	assert.NotNil(t, resultCache(false, true, 0))
+}
+
+func TestLogsCacheKey(t *testing.T) {
+	key := logsCacheKey("us-east-1", "prod", []string{"api"}, "-1h", "", "ERROR", 0, 0, false, false)
+	assert.Equal(t, key, logsCacheKey("us-east-1", "prod", []string{"api"}, "-1h", "", "ERROR", 0, 0, false, false))
+	assert.NotEqual(t, key, logsCacheKey("us-east-1", "prod", []string{"api"}, "-2h", "", "ERROR", 0, 0, false, false))
+	assert.NotEqual(t, key, logsCacheKey("us-east-1", "prod", []string{"api"}, "-1h", "", "WARN", 0, 0, false, false))
+	assert.NotEqual(t, key, logsCacheKey("us-east-1", "staging", []string{"api"}, "-1h", "", "ERROR", 0, 0, false, false))
+	assert.NotEqual(t, key, logsCacheKey("us-east-1", "prod", []string{"api"}, "-1h", "", "ERROR", 0, 50, false, false))
+	assert.NotEqual(t, key, logsCacheKey("us-east-1", "prod", []string{"api"}, "-1h", "", "ERROR", 0, 0, false, true))
+}
+
+func TestCachedLogsRoundTrip(t *testing.T) {
+	ts := time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC)
+	entries := []log.LogEntry{{
+		Timestamp: ts, Component: "kube-apiserver", Message: "E0612 error", LogGroup: "/aws/eks/prod/cluster",
+		LogStream: "kube-apiserver-abc", EventID: "1", IngestionTime: ts.Add(2 * time.Second),
+	}}
+	data, err := json.Marshal(newCachedLogs(log.Metadata{ClusterARN: "arn:aws:eks:us-east-1:123456789012:cluster/prod"}, entries))
+	assert.NoError(t, err)
+
+	var cached cachedLogs
+	assert.NoError(t, json.Unmarshal(data, &cached))
+	assert.Equal(t, "arn:aws:eks:us-east-1:123456789012:cluster/prod", cached.Metadata.ClusterARN)
+	got := cached.logEntries()
+	if assert.Len(t, got, 1) {
+		assert.True(t, got[0].Timestamp.Equal(ts))
+		assert.True(t, got[0].IngestionTime.Equal(ts.Add(2*time.Second)), "the ingestion time is kept for --show-lag")
+		assert.Equal(t, "E0612 error", got[0].Message)
+	}
+}
+
+func TestEntryRecorder(t *testing.T) {
+	var recorder entryRecorder
+	printed := 0
+	record := recorder.wrap(func(log.LogEntry) { printed++ })
+	for i := 0; i < 3; i++ {
+		record(log.LogEntry{Message: fmt.Sprint(i)})
+	}
+	assert.Equal(t, 3, printed)
+	assert.Len(t, recorder.entries, 3)
+	assert.False(t, recorder.overflow)
+
+	for i := 3; i <= maxCachedEntries; i++ {
+		record(log.LogEntry{})
+	}
+	assert.Equal(t, maxCachedEntries+1, printed)
+	assert.True(t, recorder.overflow)
+	assert.Nil(t, recorder.entries)
+}
+
+func TestMeasurePipeline(t *testing.T) {
+	entries := log.BenchCorpus()
+	result := measurePipeline(log.PipelineCases()[0], entries, time.Millisecond)
+	assert.Equal(t, "text", result.Case)
+	assert.GreaterOrEqual(t, result.Events, len(entries))
+	assert.Zero(t, result.Events%len(entries), "whole passes over the corpus")
+	assert.Positive(t, result.NsPerEvent)
+
+	var buf bytes.Buffer
+	printBenchResults(&buf, []benchResult{{Case: "color", EventsPerSec: 14000, NsPerEvent: 71000, AllocsPerEvent: 239, BytesPerEvent: 11546}}, 200)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], "Corpus: 200 events")
+	assert.Equal(t, strings.Fields("CASE EVENTS/SEC NS/EVENT ALLOCS/EVENT BYTES/EVENT"), strings.Fields(lines[1]))
+	assert.Equal(t, strings.Fields("color 14000 71000 239.0 11546"), strings.Fields(lines[2]))
+}
+
+func TestNewerVersion(t *testing.T) {
+	assert.True(t, newerVersion("v1.4.0", "1.3.9"))
+	assert.True(t, newerVersion("v2.0.0", "v1.10.0"))
+	assert.True(t, newerVersion("v1.3.1", "1.3.1-rc1"))
+	assert.False(t, newerVersion("v1.3.0", "1.3.0"))
+	assert.False(t, newerVersion("v1.2.0", "1.10.0"))
+	assert.False(t, newerVersion("nightly", "1.0.0"))
+	assert.False(t, newerVersion("v1.0.0", "dev"))
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "ekslogs", "update-check.json")
+	now := time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC)
+	lookups := 0
+	fetch := func(context.Context) (string, error) {
+		lookups++
+		return "v1.4.0", nil
+	}
+
+	var out bytes.Buffer
+	checkForUpdate(context.Background(), &out, "1.3.0", statePath, fetch, now)
+	assert.Equal(t, "ekslogs v1.4.0 is available (running 1.3.0): https://github.com/kzcat/ekslogs/releases/latest\n", out.String())
+
+	// Checked at most once a day
+	out.Reset()
+	checkForUpdate(context.Background(), &out, "1.3.0", statePath, fetch, now.Add(time.Hour))
+	assert.Empty(t, out.String())
+	assert.Equal(t, 1, lookups)
+
+	checkForUpdate(context.Background(), &out, "1.4.0", statePath, fetch, now.Add(25*time.Hour))
+	assert.Empty(t, out.String(), "no notice when running the latest release")
+	assert.Equal(t, 2, lookups)
+
+	// A failed lookup waits for the next day too
+	failing := func(context.Context) (string, error) {
+		lookups++
+		return "", errors.New("offline")
+	}
+	checkForUpdate(context.Background(), &out, "1.3.0", statePath, failing, now.Add(50*time.Hour))
+	checkForUpdate(context.Background(), &out, "1.3.0", statePath, failing, now.Add(51*time.Hour))
+	assert.Empty(t, out.String())
+	assert.Equal(t, 3, lookups)
+}
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		var out bytes.Buffer
+		assert.NoError(t, writeCompletion(&out, rootCmd, shell), shell)
+		assert.Contains(t, out.String(), "ekslogs", shell)
+	}
+	assert.Error(t, writeCompletion(io.Discard, rootCmd, "tcsh"))
+}
+
+func TestWriteManPages(t *testing.T) {
+	root := &cobra.Command{Use: "ekslogs", Short: "EKS logs"}
+	root.AddCommand(&cobra.Command{Use: "ctx", Short: "List contexts", Run: func(*cobra.Command, []string) {}})
+	dir := filepath.Join(t.TempDir(), "man1")
+	t.Setenv("SOURCE_DATE_EPOCH", "1718186400")
+
+	assert.NoError(t, writeManPages(root, dir))
+	page, err := os.ReadFile(filepath.Join(dir, "ekslogs-ctx.1"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(page), `.TH "EKSLOGS" "1" "Jun 2024"`)
+	assert.FileExists(t, filepath.Join(dir, "ekslogs.1"))
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// completionShells are the shells 'ekslogs completion' generates a script for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate the shell completion script",
+	Long: `Generate the completion script of a shell on stdout.
+
+To load the completions in the current shell:
+
+  source <(ekslogs completion bash)
+  source <(ekslogs completion zsh)
+  ekslogs completion fish | source
+  ekslogs completion powershell | Out-String | Invoke-Expression
+
+Packages install the scripts at build time, e.g.:
+
+  ekslogs completion bash > /usr/share/bash-completion/completions/ekslogs
+  ekslogs completion zsh > /usr/share/zsh/site-functions/_ekslogs
+  ekslogs completion fish > /usr/share/fish/vendor_completions.d/ekslogs.fish`,
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs:             completionShells,
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return writeCompletion(cmd.OutOrStdout(), cmd.Root(), args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+// writeCompletion writes the completion script of a shell for the commands of root
+func writeCompletion(w io.Writer, root *cobra.Command, shell string) error {
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(w, true)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(w)
+	default:
+		return fmt.Errorf("unsupported shell %q: use bash, zsh, fish or powershell", shell)
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// compressionExtensions are the values of --compress, with the extension of the files they
+// write
+var compressionExtensions = map[string]string{
+	"none": "",
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// validateCompression checks the value of --compress
+func validateCompression(compression string) error {
+	if _, ok := compressionExtensions[compression]; !ok {
+		return fmt.Errorf("invalid compression '%s' (supported: gzip, zstd, none)", compression)
+	}
+	if compression == "zstd" {
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return errors.New("zstd compression needs the zstd command, which was not found in PATH")
+		}
+	}
+	return nil
+}
+
+// newCompressWriter returns a writer compressing what is written to it into w as it goes,
+// so large exports are never held in memory or written uncompressed. Close flushes the
+// compressed stream without closing w. zstd runs the zstd command, like tar --zstd.
+func newCompressWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case "none":
+		return nopWriteCloser{w}, nil
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "zstd":
+		return newZstdWriter(w)
+	default:
+		return nil, validateCompression(compression)
+	}
+}
+
+// nopWriteCloser writes uncompressed
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// zstdWriter pipes what is written to it through the zstd command into a writer
+type zstdWriter struct {
+	stdin  io.WriteCloser
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+}
+
+// newZstdWriter starts the zstd command compressing into w
+func newZstdWriter(w io.Writer) (*zstdWriter, error) {
+	z := &zstdWriter{cmd: exec.Command("zstd", "-q", "-c", "-T0")}
+	z.cmd.Stdout = w
+	z.cmd.Stderr = &z.stderr
+	stdin, err := z.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	z.stdin = stdin
+	if err := z.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run zstd: %w", err)
+	}
+	return z, nil
+}
+
+func (z *zstdWriter) Write(p []byte) (int, error) {
+	return z.stdin.Write(p)
+}
+
+// Close ends the input of zstd and waits for it to write the end of the compressed stream
+func (z *zstdWriter) Close() error {
+	closeErr := z.stdin.Close()
+	if err := z.cmd.Wait(); err != nil {
+		if message := strings.TrimSpace(z.stderr.String()); message != "" {
+			return fmt.Errorf("zstd failed: %s", message)
+		}
+		return fmt.Errorf("zstd failed: %w", err)
+	}
+	return closeErr
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kzcat/ekslogs/pkg/config"
+	"github.com/kzcat/ekslogs/pkg/filter"
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+var configPath string
+
+// loadConfig reads the config file given by --config, or the default one if present, and
+// registers its log types and presets
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyLogTypeConfig(cfg.LogTypes); err != nil {
+		return nil, err
+	}
+	if err := applyPresetConfig(cfg.Presets); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyLogTypeConfig registers the log types of the config file, in name order so errors
+// are reported the same way on every run
+func applyLogTypeConfig(logTypes map[string]config.LogType) error {
+	names := make([]string, 0, len(logTypes))
+	for name := range logTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		logType := logTypes[name]
+		err := log.RegisterLogType(log.LogTypeDefinition{
+			Name:        name,
+			Description: logType.Description,
+			LogGroup:    logType.LogGroup,
+			Streams:     logType.Streams,
+			Aliases:     logType.Aliases,
+		})
+		if err != nil {
+			return fmt.Errorf("invalid log type in config: %w", err)
+		}
+	}
+	return nil
+}
+
+// applyPresetConfig registers the presets of the config file, combining the ones they
+// include, and validates them so that a broken preset fails when the config is read
+func applyPresetConfig(presets map[string]config.Preset) error {
+	if len(presets) == 0 {
+		return nil
+	}
+
+	if err := filter.RegisterPresets(configPresetFilters(presets)); err != nil {
+		return fmt.Errorf("invalid preset in config: %w", err)
+	}
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		preset, _ := filter.GetUnifiedPreset(name)
+		if problems := validatePreset(preset); len(problems) > 0 {
+			return fmt.Errorf("invalid preset in config: preset '%s': %w", name, problems[0])
+		}
+	}
+	return nil
+}
+
+// configPresetFilters converts the presets of the config file to preset filters
+func configPresetFilters(presets map[string]config.Preset) map[string]filter.UnifiedPresetFilter {
+	defs := make(map[string]filter.UnifiedPresetFilter, len(presets))
+	for name, preset := range presets {
+		defs[name] = filter.UnifiedPresetFilter{
+			Description: preset.Description,
+			LogTypes:    preset.LogTypes,
+			Pattern:     preset.Pattern,
+			Includes:    preset.Include,
+			Since:       preset.Since,
+			Limit:       preset.Limit,
+			Output:      preset.Output,
+		}
+	}
+	return defs
+}
+
+// applyHighlightConfig adds the custom highlight rules from the config file to the
+// color configuration and turns off the disabled built-in rules
+func applyHighlightConfig(colorConfig *log.ColorConfig, highlight config.HighlightConfig) error {
+	for _, r := range highlight.Rules {
+		rule, err := log.NewHighlightRule(r.LogType, r.Pattern, r.Color)
+		if err != nil {
+			return fmt.Errorf("invalid highlight rule in config: %w", err)
+		}
+		colorConfig.Rules = append(colorConfig.Rules, rule)
+	}
+
+	for _, name := range highlight.Disable {
+		if err := colorConfig.DisableRule(name); err != nil {
+			return fmt.Errorf("invalid highlight config: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kzcat/ekslogs/pkg/config"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var contextName string
+
+// errNoCluster is returned by applyContext when neither the arguments nor the context name a cluster
+var errNoCluster = errors.New("requires a cluster name, or a context naming one (see 'ekslogs ctx')")
+
+var contextCmd = &cobra.Command{
+	Use:   "ctx",
+	Short: "List the contexts of the config file",
+	Long: `List the contexts of the config file. The current context is marked with '*'.
+
+A context names a cluster with its region, AWS profile, IAM role and default log types and
+preset. With a current context, the cluster name can be left out. The region, profile and
+role only apply to the context's cluster; --region and an exported AWS_PROFILE win over them:
+
+  contexts:
+    prod:
+      cluster: prod-cluster
+      region: us-west-2
+      profile: prod-admin
+      role-arn: arn:aws:iam::123456789012:role/eks-log-reader
+      log-types: [audit, authenticator]
+      preset: auth-failures
+
+Examples:
+  ekslogs ctx                    # List contexts
+  ekslogs ctx use prod           # Make prod the current context
+  ekslogs -f                     # Follow the logs of the current context's cluster
+  ekslogs --context staging api  # Use another context for one run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		printContexts(cmd.OutOrStdout(), appConfig)
+		return nil
+	},
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <context>",
+	Short: "Set the current context in the config file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.SetCurrentContext(configPath, args[0]); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Switched to context \"%s\".\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	contextCmd.AddCommand(contextUseCmd)
+	rootCmd.AddCommand(contextCmd)
+
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Context of the config file to use (default current-context, see 'ekslogs ctx')")
+}
+
+// printContexts lists the contexts of the config file, marking the current one
+func printContexts(w io.Writer, appConfig *config.Config) {
+	if len(appConfig.Contexts) == 0 {
+		_, _ = fmt.Fprintln(w, "No contexts defined in the config file.")
+		return
+	}
+
+	names := make([]string, 0, len(appConfig.Contexts))
+	for name := range appConfig.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ctx := appConfig.Contexts[name]
+		marker := " "
+		if name == appConfig.CurrentContext {
+			marker = "*"
+		}
+		details := []string{"cluster=" + ctx.Cluster}
+		if ctx.Region != "" {
+			details = append(details, "region="+ctx.Region)
+		}
+		if ctx.Profile != "" {
+			details = append(details, "profile="+ctx.Profile)
+		}
+		_, _ = fmt.Fprintf(w, "%s %-20s %s\n", marker, name, strings.Join(details, " "))
+	}
+}
+
+// applyContext resolves the cluster name and log types from the command arguments and
+// the active context. With a context naming a cluster, the arguments may consist of log
+// types only; node log types are only recognized with --container-insights, so a cluster
+// named like one (e.g. "app") is not mistaken for it. When the context applies to the
+// cluster (see usesContext), its AWS profile is applied unless AWS_PROFILE is set; see
+// contextRegion for its region. It returns errNoCluster when no cluster is named.
+func applyContext(ctx *config.Context, args []string) (string, []string, error) {
+	if ctx == nil {
+		if len(args) == 0 {
+			return "", nil, errNoCluster
+		}
+		return args[0], args[1:], nil
+	}
+
+	cluster := ctx.Cluster
+	types := args
+	if len(args) > 0 && (cluster == "" || !isLogTypeArg(args[0])) {
+		cluster = args[0]
+		types = args[1:]
+	}
+	if cluster == "" {
+		return "", nil, errNoCluster
+	}
+
+	if usesContext(ctx, cluster) && ctx.Profile != "" && os.Getenv("AWS_PROFILE") == "" {
+		if err := os.Setenv("AWS_PROFILE", ctx.Profile); err != nil {
+			return "", nil, err
+		}
+	}
+	return cluster, types, nil
+}
+
+// isLogTypeArg reports whether a command argument names a log type rather than a cluster
+func isLogTypeArg(arg string) bool {
+	return log.IsLogType(arg) && (containerInsights || !log.IsNodeLogType(arg))
+}
+
+// usesContext reports whether the context's region, AWS profile and IAM role apply to a
+// cluster: it is the context's cluster, or the context names none
+func usesContext(ctx *config.Context, cluster string) bool {
+	return ctx != nil && (ctx.Cluster == "" || ctx.Cluster == cluster)
+}
+
+// contextRegion returns the region of the context if it applies to the cluster; an
+// explicit --region wins over it
+func contextRegion(ctx *config.Context, cluster string) string {
+	if !usesContext(ctx, cluster) {
+		return ""
+	}
+	return ctx.Region
+}
+
+// contextRoleARN returns the IAM role of the context if it applies to the cluster
+func contextRoleARN(ctx *config.Context, cluster string) string {
+	if !usesContext(ctx, cluster) {
+		return ""
+	}
+	return ctx.RoleARN
+}
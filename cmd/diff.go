@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// diffBaselineOffset is how far back the default baseline is: the same window a day earlier
+const diffBaselineOffset = 24 * time.Hour
+
+var (
+	diffOptions        reportOptions
+	diffWindow         string
+	diffBaseline       string
+	diffFilterPatterns []string
+	diffIgnorePatterns []string
+	diffMinCount       int
+	diffMinRatio       float64
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [cluster-name] [log-types...]",
+	Short: "Report the messages that are new or more frequent than in a baseline window",
+	Long: `Compare the frequency of each message template between a window and a baseline window,
+and report the templates that are new or whose hourly rate increased, to answer "what
+changed since yesterday?".
+
+Messages are reduced to templates by removing the klog header and replacing the values
+of structured fields, IDs, IP addresses and numbers, so the messages logged by the same
+code are counted together. Audit events are counted per verb, resource and status code.
+
+Ranges are written start..end, or start for a range ending now, with RFC3339 or relative
+times. The baseline defaults to the window a day earlier.`,
+	Example: `  ekslogs diff my-cluster --window -1h                          # Past hour vs the same hour yesterday
+  ekslogs diff my-cluster --window -1h --baseline -25h..-24h -F error
+  ekslogs diff my-cluster api --window -30m --baseline -2h..-30m  # Windows of different lengths`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		windowStart, windowEnd, err := parseRangeSpec(diffWindow)
+		if err != nil {
+			return fmt.Errorf("invalid --window: %w", err)
+		}
+		baselineStart, baselineEnd := windowStart.Add(-diffBaselineOffset), windowEnd.Add(-diffBaselineOffset)
+		if diffBaseline != "" {
+			baselineStart, baselineEnd, err = parseRangeSpec(diffBaseline)
+			if err != nil {
+				return fmt.Errorf("invalid --baseline: %w", err)
+			}
+		}
+		if diffMinRatio <= 1 {
+			return fmt.Errorf("--min-ratio must be greater than 1")
+		}
+
+		r, err := setupReport(cmd, args, &diffOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		pattern := buildCombinedFilterPattern(diffFilterPatterns, diffIgnorePatterns, false)
+		windowEntries, err := r.collect(r.logTypes, &windowStart, &windowEnd, pattern, 0)
+		if err != nil {
+			return err
+		}
+		baselineEntries, err := r.collect(r.logTypes, &baselineStart, &baselineEnd, pattern, 0)
+		if err != nil {
+			return err
+		}
+		r.verbosef("Compared %d events of %s..%s with %d events of %s..%s", len(windowEntries),
+			formatResumeTime(windowStart), formatResumeTime(windowEnd), len(baselineEntries),
+			formatResumeTime(baselineStart), formatResumeTime(baselineEnd))
+
+		changes := log.DiffTemplates(log.CountTemplates(windowEntries), log.CountTemplates(baselineEntries),
+			windowEnd.Sub(windowStart), baselineEnd.Sub(baselineStart),
+			log.DiffOptions{MinCount: diffMinCount, MinRatio: diffMinRatio})
+		if r.format == log.OutputFormatJSON {
+			if err := printDiffJSON(os.Stdout, changes); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printDiffReport(r.output(), changes)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffOptions.documents = true
+	diffOptions.addCommonFlags(diffCmd, "one JSON object per template")
+	diffCmd.Flags().StringVar(&diffWindow, "window", "-1h", "Time range to examine: start..end, or start for a range ending now (e.g. -1h, -25h..-24h)")
+	diffCmd.Flags().StringVar(&diffBaseline, "baseline", "", "Time range to compare with (default the window a day earlier)")
+	diffCmd.Flags().StringArrayVarP(&diffFilterPatterns, "filter-pattern", "F", []string{}, "Log filter pattern (can be specified multiple times for AND condition)")
+	diffCmd.Flags().StringArrayVarP(&diffIgnorePatterns, "ignore-filter-pattern", "I", []string{}, "Log ignore filter pattern (can be specified multiple times for OR condition)")
+	diffCmd.Flags().IntVar(&diffMinCount, "min-count", 5, "Ignore templates seen fewer times than this in the window")
+	diffCmd.Flags().Float64Var(&diffMinRatio, "min-ratio", 2, "Report templates whose hourly rate grew by at least this factor")
+}
+
+// parseRangeSpec parses a time range written "start..end", or "start" for a range ending
+// now; both ends are RFC3339 or relative times such as -25h
+func parseRangeSpec(spec string) (time.Time, time.Time, error) {
+	start, end, _ := strings.Cut(spec, "..")
+	if start == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("the range '%s' has no start", spec)
+	}
+	startT, endT, err := parseTimeRange(start, end)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	rangeEnd := time.Now()
+	if endT != nil {
+		rangeEnd = *endT
+	}
+	if !rangeEnd.After(*startT) {
+		return time.Time{}, time.Time{}, fmt.Errorf("the range '%s' ends before it starts", spec)
+	}
+	return *startT, rangeEnd, nil
+}
+
+// printDiffReport writes a table of the new and more frequent templates, e.g.
+//
+//	CHANGE     LOG  BASELINE  COUNT  RATIO  TEMPLATE
+//	new        kcm  0         42     -      "Error syncing pod" pod=<*> err="context deadline exceeded"
+func printDiffReport(w io.Writer, changes []log.TemplateChange) {
+	if len(changes) == 0 {
+		_, _ = fmt.Fprintln(w, "No new or more frequent messages found.")
+		return
+	}
+
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "CHANGE\tLOG\tBASELINE\tCOUNT\tRATIO\tTEMPLATE")
+	for _, change := range changes {
+		ratio := "-"
+		if change.Ratio > 0 {
+			ratio = fmt.Sprintf("x%.1f", change.Ratio)
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\t%s\n", change.Change, orDash(change.LogType), change.Baseline, change.Count, ratio, change.Template)
+	}
+	_ = table.Flush()
+}
+
+// printDiffJSON writes one JSON object per new or more frequent template
+func printDiffJSON(w io.Writer, changes []log.TemplateChange) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, change := range changes {
+		if err := encoder.Encode(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var manDir string
+
+var docsCmd = &cobra.Command{
+	Use:   "docs",
+	Short: "Generate the documentation of the commands",
+}
+
+var docsManCmd = &cobra.Command{
+	Use:   "man",
+	Short: "Generate man pages",
+	Long: `Generate a man page in section 1 for ekslogs and each of its subcommands
+(ekslogs.1, ekslogs-ctx.1, ...) in a directory.
+
+The pages are dated SOURCE_DATE_EPOCH when it is set, so that package builds are
+reproducible.
+
+Examples:
+  ekslogs docs man --dir ./man
+  ekslogs docs man --dir /usr/share/man/man1`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := writeManPages(cmd.Root(), manDir); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Man pages written to %s\n", manDir)
+		return nil
+	},
+}
+
+func init() {
+	docsCmd.AddCommand(docsManCmd)
+	rootCmd.AddCommand(docsCmd)
+
+	docsManCmd.Flags().StringVar(&manDir, "dir", ".", "Directory to write the man pages to (created if missing)")
+}
+
+// writeManPages writes the man pages of root and its subcommands to dir
+func writeManPages(root *cobra.Command, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create man page directory: %w", err)
+	}
+	// The generation date footer would make every build differ
+	root.DisableAutoGenTag = true
+	header := &doc.GenManHeader{
+		Title:   "EKSLOGS",
+		Section: "1",
+		Source:  "ekslogs " + version,
+		Manual:  "ekslogs Manual",
+	}
+	if err := doc.GenManTree(root, header, dir); err != nil {
+		return fmt.Errorf("failed to generate man pages: %w", err)
+	}
+	return nil
+}
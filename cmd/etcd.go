@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/filter"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// etcdBarWidth is the width of the longest bar of the storage warning histogram
+const etcdBarWidth = 40
+
+var (
+	etcdOptions reportOptions
+	etcdBucket  time.Duration
+)
+
+var etcdCmd = &cobra.Command{
+	Use:   "etcd [cluster-name]",
+	Short: "Chart etcd latency, timeout and object size warnings over time",
+	Long: `Count the API server messages about etcd latency ("took too long"), failed etcd requests
+("etcdserver: request timed out") and object or database size ("request is too large",
+"database space exceeded") in time buckets, to catch storage pressure building up.
+
+The buckets default to 1/24 of the time range. The same messages can be followed with
+the etcd-issues preset: ekslogs my-cluster -p etcd-issues -f`,
+	Example: `  ekslogs etcd my-cluster -s -1d             # Warnings of the past day in 1h buckets
+  ekslogs etcd my-cluster -s -6h --bucket 5m  # Finer buckets`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if etcdBucket < 0 {
+			return fmt.Errorf("--bucket must be a positive duration")
+		}
+		r, err := setupReport(cmd, args, &etcdOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		rangeEnd := time.Now()
+		if r.end != nil {
+			rangeEnd = *r.end
+		}
+		rangeStart := rangeEnd.Add(-time.Hour)
+		if r.start != nil {
+			rangeStart = *r.start
+		}
+		bucket := etcdBucket
+		if bucket == 0 {
+			bucket = defaultEtcdBucket(rangeEnd.Sub(rangeStart))
+		}
+
+		preset, _ := filter.GetUnifiedPreset("etcd-issues")
+		entries, err := r.collect(preset.LogTypes, &rangeStart, &rangeEnd, preset.Pattern, 0)
+		if err != nil {
+			return err
+		}
+		r.verbosef("Found %d candidate API server messages", len(entries))
+
+		buckets := log.BucketStorageWarnings(entries, rangeStart, rangeEnd, bucket)
+		if r.format == log.OutputFormatJSON {
+			if err := printEtcdJSON(os.Stdout, buckets); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printEtcdReport(r.output(), buckets, bucket)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(etcdCmd)
+
+	etcdOptions.documents = true
+	etcdOptions.addFlags(etcdCmd, "one JSON object per bucket")
+	etcdCmd.Flags().DurationVar(&etcdBucket, "bucket", 0, "Width of the time buckets, e.g. 5m (default 1/24 of the time range)")
+}
+
+// defaultEtcdBucket splits a time range into about 24 buckets of whole minutes
+func defaultEtcdBucket(span time.Duration) time.Duration {
+	bucket := (span / 24).Round(time.Minute)
+	if bucket < time.Minute {
+		return time.Minute
+	}
+	return bucket
+}
+
+// printEtcdReport writes a histogram of the storage warnings per bucket, e.g.
+//
+//	2024-01-01T10:00:00Z  latency    3  timeout    1  size    0  ####
+func printEtcdReport(w io.Writer, buckets []log.StorageBucket, width time.Duration) {
+	var latency, timeout, size, highest int
+	for _, b := range buckets {
+		latency += b.Latency
+		timeout += b.Timeout
+		size += b.Size
+		if b.Total() > highest {
+			highest = b.Total()
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, "Storage warnings per %s\n", width)
+	for _, b := range buckets {
+		bar := ""
+		if highest > 0 {
+			bar = strings.Repeat("#", (b.Total()*etcdBarWidth+highest-1)/highest)
+		}
+		_, _ = fmt.Fprintf(w, "%s  latency %4d  timeout %4d  size %4d  %s\n",
+			b.Start.Format(time.RFC3339), b.Latency, b.Timeout, b.Size, bar)
+	}
+	_, _ = fmt.Fprintf(w, "Total: %d latency, %d timeout, %d size warnings\n", latency, timeout, size)
+}
+
+// printEtcdJSON writes one JSON object per bucket
+func printEtcdJSON(w io.Writer, buckets []log.StorageBucket) error {
+	encoder := json.NewEncoder(w)
+	for _, b := range buckets {
+		if err := encoder.Encode(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
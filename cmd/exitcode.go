@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the root command
+const (
+	exitOK        = 0
+	exitError     = 1
+	exitNoEvents  = 2
+	exitAuth      = 3
+	exitThrottled = 4
+	exitNotFound  = 5
+	exitSilence   = 6
+)
+
+// errNoEvents is returned with --fail-on-empty when no log event matched
+var errNoEvents = errors.New("no log events matched")
+
+var failOnEmpty bool
+
+// exitCode maps an error returned by the root command to the process exit code
+func exitCode(err error) int {
+	var queryExit *exec.ExitError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &queryExit) && queryExit.ExitCode() > 0:
+		// The status of a query run again by rerun
+		return queryExit.ExitCode()
+	case errors.Is(err, errNoEvents):
+		return exitNoEvents
+	case aws.IsAuthError(err):
+		return exitAuth
+	case aws.IsThrottleError(err):
+		return exitThrottled
+	case aws.IsNotFoundError(err):
+		return exitNotFound
+	case errors.Is(err, aws.ErrSilence):
+		return exitSilence
+	default:
+		return exitError
+	}
+}
+
+// checkEmpty returns errNoEvents with --fail-on-empty when nothing was printed
+func checkEmpty(cmd *cobra.Command, printer *log.Printer) error {
+	if !failOnEmpty || printer.Printed() > 0 {
+		return nil
+	}
+	// Not a usage error: keep cobra from printing the error and the usage text
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return errNoEvents
+}
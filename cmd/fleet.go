@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/config"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// fleetConcurrency is the number of clusters of the fleet queried at the same time
+const fleetConcurrency = 8
+
+var fleetLimit int32
+
+var fleetCmd = &cobra.Command{
+	Use:   "fleet [log-types...]",
+	Short: "Query the clusters of every account of the fleet",
+	Long: `Query the control plane logs of every cluster of the fleet concurrently. For each account
+listed under 'fleet' in the config file, the role is assumed, the clusters of its regions
+are listed, and the query runs on all of them. Each line is prefixed with the account and
+cluster it came from, in a color of its own.
+
+  fleet:
+    - name: prod
+      role-arn: arn:aws:iam::123456789012:role/eks-log-reader
+      regions: [us-east-1, eu-west-1]
+    - name: staging
+      role-arn: arn:aws:iam::210987654321:role/eks-log-reader
+
+Accounts without regions are searched in --region or the default region. In JSON output,
+the account, region and cluster are added to each object.`,
+	Example: `  ekslogs fleet -p api-errors               # API errors of the past hour across the fleet
+  ekslogs fleet audit -F "delete" -s "-6h"  # Audit events mentioning delete in every cluster`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if len(appConfig.Fleet) == 0 {
+			return fmt.Errorf("no fleet accounts in the config file. Run 'ekslogs fleet --help' for an example")
+		}
+
+		logTypes = args
+		if err := applyPreset(presetName, cmd); err != nil {
+			return err
+		}
+
+		format, err := log.ParseOutputFormat(outputFormat)
+		if err != nil {
+			return err
+		}
+		startT, endT, err := parseTimeRange(startTime, endTime)
+		if err != nil {
+			return err
+		}
+
+		var fp *string
+		if len(filterPatterns) > 0 || len(ignoreFilterPatterns) > 0 {
+			combinedPattern := buildCombinedFilterPattern(filterPatterns, ignoreFilterPatterns, verbose)
+			if combinedPattern != "" {
+				fp = &combinedPattern
+			}
+		}
+
+		colorConfig := log.NewColorConfig()
+		colorConfig.Mode = parseColorMode(colorMode)
+		colorConfig.Theme, err = log.ParseTheme(appConfig.Theme)
+		if err != nil {
+			return err
+		}
+
+		if region == "" {
+			region = defaultRegion()
+		}
+		level, err := parseLogLevel(logLevel, verbose, debug, quiet)
+		if err != nil {
+			return err
+		}
+
+		ctx, stopSignals := interruptContext(cmd.Context())
+		defer stopSignals()
+
+		out := &fleetOutput{
+			w:       os.Stdout,
+			printer: log.NewPrinter(log.OutputOptions{Format: format}, colorConfig),
+			theme:   colorConfig.Theme,
+			json:    format == log.OutputFormatJSON,
+		}
+		query := func(ctx context.Context, client *aws.EKSLogsClient, cluster string, printFunc func(log.LogEntry)) error {
+			return client.GetLogs(ctx, cluster, logTypes, startT, endT, fp, fleetLimit, printFunc)
+		}
+		err = runFleet(ctx, appConfig.Fleet, newLogger(os.Stderr, level), query, out)
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fleetCmd)
+
+	fleetCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region of the accounts without regions in the config file")
+	fleetCmd.Flags().StringVarP(&startTime, "start-time", "s", "", "Start time (RFC3339 format or relative: -1h, -15m, -30s, -2d)")
+	fleetCmd.Flags().StringVarP(&endTime, "end-time", "e", "", "End time (RFC3339 format or relative: -1h, -15m, -30s, -2d)")
+	fleetCmd.Flags().StringArrayVarP(&filterPatterns, "filter-pattern", "F", []string{}, "Log filter pattern (can be specified multiple times for AND condition)")
+	fleetCmd.Flags().StringArrayVarP(&ignoreFilterPatterns, "ignore-filter-pattern", "I", []string{}, "Log ignore filter pattern (can be specified multiple times for OR condition)")
+	fleetCmd.Flags().StringVarP(&presetName, "preset", "p", "", "Use filter preset (run 'ekslogs presets' to list available presets)")
+	fleetCmd.Flags().Int32VarP(&fleetLimit, "limit", "l", 0, "Maximum number of logs to retrieve per cluster (0 means unlimited)")
+	fleetCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json (one JSON object per line)")
+	fleetCmd.Flags().StringVar(&colorMode, "color", "auto", "Color output mode: auto, always, never (auto honors EKSLOGS_COLOR, NO_COLOR and CLICOLOR_FORCE)")
+	fleetCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	fleetCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing but log events (errors are still reported on stderr)")
+	fleetCmd.Flags().BoolVar(&debug, "debug", false, "Write the timing and request ID of each AWS API call to stderr (same as --log-level debug)")
+	fleetCmd.Flags().StringVar(&logLevel, "log-level", "", "Level of diagnostics written to stderr: debug, info, warn, error (default warn, info with --verbose)")
+}
+
+// fleetQuery retrieves the logs of one cluster of the fleet
+type fleetQuery func(ctx context.Context, client *aws.EKSLogsClient, cluster string, printFunc func(log.LogEntry)) error
+
+// fleetTarget is a cluster of the fleet
+type fleetTarget struct {
+	account string
+	region  string
+	client  *aws.EKSLogsClient
+	cluster string
+}
+
+// runFleet lists the clusters of every account and region of the fleet and runs the query
+// on them concurrently. Failing accounts and clusters are logged as warnings without
+// stopping the others; an error is returned if any failed.
+func runFleet(ctx context.Context, accounts []config.FleetAccount, logger *slog.Logger, query fleetQuery, out *fleetOutput) error {
+	var mu sync.Mutex
+	var targets []fleetTarget
+	var failures []error
+	fail := func(err error, attrs ...any) {
+		mu.Lock()
+		failures = append(failures, err)
+		mu.Unlock()
+		logger.Warn("Fleet query failed", append(attrs, "error", err)...)
+	}
+
+	var wg sync.WaitGroup
+	for _, account := range accounts {
+		name := fleetAccountName(account)
+		regions := account.Regions
+		if len(regions) == 0 {
+			regions = []string{region}
+		}
+		for _, accountRegion := range regions {
+			wg.Add(1)
+			go func(account config.FleetAccount, name, accountRegion string) {
+				defer wg.Done()
+
+				client, err := aws.NewEKSLogsClient(accountRegion, endpointURL, logger)
+				if err == nil && account.RoleARN != "" {
+					err = client.SetAssumeRole(account.RoleARN)
+				}
+				if err != nil {
+					fail(fmt.Errorf("account %s (%s): %w", name, accountRegion, err), "account", name, "region", accountRegion)
+					return
+				}
+
+				clusters, err := client.ListClusters(ctx)
+				if err != nil {
+					fail(fmt.Errorf("account %s (%s): %w", name, accountRegion, err), "account", name, "region", accountRegion)
+					return
+				}
+				verbosef("Account %s (%s): %d clusters", name, accountRegion, len(clusters))
+
+				mu.Lock()
+				defer mu.Unlock()
+				for _, cluster := range clusters {
+					targets = append(targets, fleetTarget{account: name, region: accountRegion, client: client, cluster: cluster})
+				}
+			}(account, name, accountRegion)
+		}
+	}
+	wg.Wait()
+
+	slots := make(chan struct{}, fleetConcurrency)
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target fleetTarget) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if ctx.Err() != nil {
+				return
+			}
+			err := query(ctx, target.client, target.cluster, func(entry log.LogEntry) {
+				out.print(target, entry)
+			})
+			// Clusters without control plane logging are expected in a fleet
+			if err != nil && !errors.Is(err, aws.ErrNoLogGroups) && ctx.Err() == nil {
+				fail(fmt.Errorf("cluster %s/%s: %w", target.account, target.cluster, err), "account", target.account, "cluster", target.cluster)
+			}
+		}(target)
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%d fleet queries failed: %w", len(failures), errors.Join(failures...))
+	}
+	return nil
+}
+
+// fleetAccountName returns the name prefixing the lines of an account: its configured name,
+// or the account ID of its role ARN
+func fleetAccountName(account config.FleetAccount) string {
+	if account.Name != "" {
+		return account.Name
+	}
+	// arn:aws:iam::123456789012:role/name
+	if parts := strings.Split(account.RoleARN, ":"); len(parts) >= 5 && parts[4] != "" {
+		return parts[4]
+	}
+	return "default"
+}
+
+// fleetOutput writes the entries of the fleet's clusters, tagged with their account and cluster
+type fleetOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	printer *log.Printer
+	theme   *log.Theme
+	json    bool
+}
+
+// print writes an entry prefixed with "[account/cluster]", colored per cluster, or with
+// account, region and cluster fields added to the JSON object
+func (o *fleetOutput) print(target fleetTarget, entry log.LogEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	line, ok := o.printer.Format(entry)
+	if !ok {
+		return
+	}
+	if o.json {
+		accountJSON, _ := json.Marshal(target.account)
+		regionJSON, _ := json.Marshal(target.region)
+		clusterJSON, _ := json.Marshal(target.cluster)
+		line = fmt.Sprintf(`{"account":%s,"region":%s,"cluster":%s,%s`, accountJSON, regionJSON, clusterJSON, strings.TrimPrefix(line, "{"))
+	} else {
+		label := target.account + "/" + target.cluster
+		line = fmt.Sprintf("[%s] %s", o.theme.Color(log.LabelColor(label)).Sprint(label), line)
+	}
+	_, _ = fmt.Fprintln(o.w, line)
+}
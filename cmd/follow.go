@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+var (
+	heartbeat      time.Duration
+	alertOnSilence time.Duration
+	exitOnSilence  bool
+	streamCacheTTL time.Duration
+	spikeFactor    float64
+	spikeWindow    time.Duration
+	spikeWebhook   string
+	bellMode       string
+)
+
+// spikeWebhookTimeout bounds a spike webhook call, so a slow endpoint does not pile up requests
+const spikeWebhookTimeout = 10 * time.Second
+
+// statusPrinter returns a function writing follow mode status notices to w, prefixed with the local time
+func statusPrinter(w io.Writer) func(message string) {
+	notice := color.New(color.FgYellow)
+	return func(message string) {
+		_, _ = notice.Fprintf(w, "[%s] %s\n", time.Now().Format(time.TimeOnly), message)
+	}
+}
+
+// spikeWebhookPayload is the JSON body posted to --spike-webhook. Text makes it readable
+// as is by Slack and Microsoft Teams incoming webhooks.
+type spikeWebhookPayload struct {
+	Text    string `json:"text"`
+	Cluster string `json:"cluster"`
+	Window  string `json:"window"`
+	log.Spike
+}
+
+// spikeNotifier returns the function reporting a spike: a highlighted warning written to w
+// unless w is nil, and a POST to webhook unless it is empty. Webhook calls are made in the
+// background; a failure is logged as a warning.
+func spikeNotifier(w io.Writer, webhook, cluster string, logger *slog.Logger) func(log.Spike) {
+	warning := color.New(color.FgRed, color.Bold)
+	client := &http.Client{Timeout: spikeWebhookTimeout}
+	return func(spike log.Spike) {
+		if w != nil {
+			_, _ = warning.Fprintf(w, "[%s] Spike: %s\n", spike.Time.Local().Format(time.TimeOnly), spike)
+		}
+		if webhook == "" {
+			return
+		}
+		go func() {
+			if err := postSpikeWebhook(client, webhook, cluster, spike); err != nil {
+				logger.Warn("Spike webhook failed", "error", err)
+			}
+		}()
+	}
+}
+
+// postSpikeWebhook posts a spike of cluster to url as JSON
+func postSpikeWebhook(client *http.Client, url, cluster string, spike log.Spike) error {
+	body, err := json.Marshal(spikeWebhookPayload{
+		Text:    fmt.Sprintf("ekslogs: spike in %s: %s", cluster, spike),
+		Cluster: cluster,
+		Window:  spike.Window.String(),
+		Spike:   spike,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// flushIdleEntries passes on the entries joined by --join-multiline once their stream is
+// idle for an update interval, until ctx is done
+func flushIdleEntries(ctx context.Context, joiner *log.MultilineJoiner, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			joiner.FlushIdle(interval)
+		}
+	}
+}
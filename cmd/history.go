@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// envNoHistory disables the query history, e.g. for queries with sensitive patterns
+const envNoHistory = "EKSLOGS_NO_HISTORY"
+
+// maxHistoryEntries is the number of queries kept in the history file
+const maxHistoryEntries = 1000
+
+// historyEntry is a query of the root command recorded in the history file
+type historyEntry struct {
+	ID   int       `json:"id"`
+	Time time.Time `json:"time"`
+	// Args are the command line arguments of the query, without the program name
+	Args    []string `json:"args"`
+	Cluster string   `json:"cluster"`
+	Region  string   `json:"region,omitempty"`
+	// Start and End are the time range read, or followed until the query stopped
+	Start    *time.Time `json:"start,omitempty"`
+	End      *time.Time `json:"end,omitempty"`
+	Follow   bool       `json:"follow,omitempty"`
+	Events   int        `json:"events"`
+	ExitCode int        `json:"exit_code"`
+}
+
+// queryRun is the query of the root command being run, recorded in the history when the
+// command exits
+type queryRun struct {
+	entry   historyEntry
+	printer *log.Printer
+}
+
+// runningQuery is set by the root command once the cluster and time range are resolved
+var runningQuery *queryRun
+
+// startQueryRun records the query about to run for the history
+func startQueryRun(printer *log.Printer, start, end *time.Time, follow bool) {
+	now := time.Now()
+	if follow {
+		start, end = &now, nil
+	} else if end == nil {
+		end = &now
+	}
+	runningQuery = &queryRun{
+		entry: historyEntry{
+			Args:    os.Args[1:],
+			Cluster: clusterName,
+			Region:  region,
+			Start:   start,
+			End:     end,
+			Follow:  follow,
+		},
+		printer: printer,
+	}
+}
+
+// recordQueryRun appends the query run by the root command, if any, to the history file.
+// The history is best effort: failing to write it does not fail the query.
+func recordQueryRun(err error) {
+	if runningQuery == nil || os.Getenv(envNoHistory) != "" {
+		return
+	}
+	entry := runningQuery.entry
+	entry.Time = time.Now()
+	entry.Events = runningQuery.printer.Printed()
+	entry.ExitCode = exitCode(err)
+	if entry.Follow {
+		entry.End = &entry.Time
+	}
+	_ = appendHistory(historyPath(), entry)
+}
+
+// historyPath returns the history file, under $XDG_STATE_HOME (defaulting to ~/.local/state)
+func historyPath() string {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "ekslogs", "history.jsonl")
+}
+
+// readHistory returns the queries of the history file, oldest first. A missing file is an
+// empty history, and unreadable lines are skipped.
+func readHistory(path string) ([]historyEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// appendHistory adds entry to the history file with the next ID, keeping the most recent
+// maxHistoryEntries queries
+func appendHistory(path string, entry historyEntry) error {
+	if path == "" {
+		return errors.New("no history file")
+	}
+	entries, err := readHistory(path)
+	if err != nil {
+		return err
+	}
+	entry.ID = 1
+	if len(entries) > 0 {
+		entry.ID = entries[len(entries)-1].ID + 1
+	}
+	entries = append(entries, entry)
+	if len(entries) > maxHistoryEntries {
+		entries = entries[len(entries)-maxHistoryEntries:]
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, e := range entries {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// findHistory returns the query of the history with the given ID
+func findHistory(entries []historyEntry, id int) (historyEntry, bool) {
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, true
+		}
+	}
+	return historyEntry{}, false
+}
+
+var (
+	historyLimit  int
+	historyOutput string
+	rerunFollow   bool
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the previous log queries",
+	Long: `List the log queries run with ekslogs, most recent last, with their time range, the number
+of events printed and the exit code. Queries are recorded in
+$XDG_STATE_HOME/ekslogs/history.jsonl (~/.local/state by default), which keeps the last
+1000; set EKSLOGS_NO_HISTORY=1 to leave a query out of the history.
+
+Run a query again with 'ekslogs rerun <id>'.`,
+	Example: `  ekslogs history           # The last 20 queries
+  ekslogs history -n 100    # The last 100 queries
+  ekslogs history -o json   # One JSON object per query`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := log.ParseOutputFormat(historyOutput)
+		if err != nil {
+			return err
+		}
+		entries, err := readHistory(historyPath())
+		if err != nil {
+			return err
+		}
+		if historyLimit > 0 && len(entries) > historyLimit {
+			entries = entries[len(entries)-historyLimit:]
+		}
+
+		if format == log.OutputFormatJSON {
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetEscapeHTML(false)
+			for _, entry := range entries {
+				if err := encoder.Encode(entry); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		printHistory(cmd.OutOrStdout(), entries)
+		return nil
+	},
+}
+
+var rerunCmd = &cobra.Command{
+	Use:   "rerun <id>",
+	Short: "Run a previous log query again",
+	Long: `Run a query of the history again with the same arguments. The time range is the one the
+query read, as absolute times, so the events of an incident can be retrieved again later
+even if the query used a relative range such as -s -1h. With --follow, the query follows
+new events instead of reading its time range.`,
+	Example: `  ekslogs rerun 42            # The events query 42 read
+  ekslogs rerun 42 --follow   # Follow the events matching query 42`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid query ID '%s'. Run 'ekslogs history' to list the queries", args[0])
+		}
+		entries, err := readHistory(historyPath())
+		if err != nil {
+			return err
+		}
+		entry, ok := findHistory(entries, id)
+		if !ok {
+			return fmt.Errorf("query %d not found in the history. Run 'ekslogs history' to list the queries", id)
+		}
+
+		return runQuery(cmd, rerunArgs(entry, rerunFollow))
+	},
+}
+
+// runQuery runs ekslogs with args in a child process sharing the terminal, and returns its
+// exit status as an *exec.ExitError, which executeRoot exits with
+func runQuery(cmd *cobra.Command, args []string) error {
+	_, _ = fmt.Fprintf(os.Stderr, "Running: ekslogs %s\n", shellJoin(args))
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// The query handles Ctrl+C itself, printing what it read before exiting
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	query := exec.Command(executable, args...)
+	query.Stdin, query.Stdout, query.Stderr = os.Stdin, os.Stdout, os.Stderr
+	err = query.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// The query printed its error; exit with its status
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(rerunCmd)
+
+	historyCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of most recent queries to list (0 for all)")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "text", "Output format: text, json (one JSON object per query)")
+	rerunCmd.Flags().BoolVarP(&rerunFollow, "follow", "f", false, "Follow new events instead of reading the time range of the query")
+}
+
+// rangeFlags are the flags of the root command selecting the time range, with a value
+var rangeFlags = map[string]bool{"-s": true, "--start-time": true, "-e": true, "--end-time": true}
+
+// rerunArgs returns the arguments running a query of the history again: its arguments with
+// the time range it read as absolute times, or following new events instead
+func rerunArgs(entry historyEntry, follow bool) []string {
+	var args []string
+	for i := 0; i < len(entry.Args); i++ {
+		arg := entry.Args[i]
+		name, _, hasValue := strings.Cut(arg, "=")
+		switch {
+		case arg == "--":
+			args = append(args, entry.Args[i:]...)
+			i = len(entry.Args)
+		case rangeFlags[name]:
+			if !hasValue {
+				i++
+			}
+		case arg == "-f" || arg == "--follow" || name == "--follow":
+		case len(arg) > 2 && !strings.HasPrefix(arg, "--") && rangeFlags[arg[:2]]:
+			// -s-1h: the value follows the shorthand
+		default:
+			args = append(args, arg)
+		}
+	}
+
+	if follow {
+		return append(args, "--follow")
+	}
+	if entry.Start != nil {
+		args = append(args, "--start-time", entry.Start.UTC().Format(resumeTimeFormat))
+	}
+	if entry.End != nil {
+		args = append(args, "--end-time", entry.End.UTC().Format(resumeTimeFormat))
+	}
+	return args
+}
+
+// printHistory writes a table of the queries of the history
+func printHistory(w io.Writer, entries []historyEntry) {
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintln(w, "No queries in the history.")
+		return
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "ID\tTIME\tCLUSTER\tRANGE\tEVENTS\tEXIT\tCOMMAND")
+	for _, entry := range entries {
+		_, _ = fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%d\t%d\tekslogs %s\n", entry.ID, entry.Time.UTC().Format(time.RFC3339),
+			orDash(entry.Cluster), historyRange(entry), entry.Events, entry.ExitCode, shellJoin(entry.Args))
+	}
+	_ = table.Flush()
+}
+
+// historyRange describes the time range of a query: its duration, and whether it followed
+// new events
+func historyRange(entry historyEntry) string {
+	if entry.Start == nil || entry.End == nil {
+		return "-"
+	}
+	duration := entry.End.Sub(*entry.Start).Round(time.Second).String()
+	if entry.Follow {
+		return "follow " + duration
+	}
+	return duration
+}
+
+// shellJoin joins arguments into a command line, quoting those the shell would split or
+// expand
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n\"'`$\\|&;<>()*?[]{}#~!") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+var (
+	logLevel string
+	quiet    bool
+)
+
+// verbosef writes a line of verbose output to stderr
+func verbosef(format string, args ...interface{}) {
+	if verbose && !quiet {
+		_, _ = fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// parseLogLevel resolves the diagnostic log level. An explicit --log-level wins;
+// otherwise --debug selects debug, --quiet errors only, --verbose info, and warnings are shown by default.
+func parseLogLevel(level string, verbose, debug, quiet bool) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "":
+		switch {
+		case debug:
+			return slog.LevelDebug, nil
+		case quiet:
+			return slog.LevelError, nil
+		case verbose:
+			return slog.LevelInfo, nil
+		default:
+			return slog.LevelWarn, nil
+		}
+	default:
+		return 0, fmt.Errorf("invalid log level '%s' (supported: debug, info, warn, error)", level)
+	}
+}
+
+// newLogger creates the logger for diagnostics, writing text records to w
+func newLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logTypesOptions reportOptions
+	logTypesRecent  time.Duration
+)
+
+// Statuses of a log type of a cluster
+const (
+	logTypeActive   = "active"   // Events were written recently
+	logTypeSilent   = "silent"   // Enabled, but no events were written recently
+	logTypeDisabled = "disabled" // Not enabled in the logging config of the cluster
+)
+
+// controlPlaneLogTypes are the log types of the control plane, in the order they are listed
+var controlPlaneLogTypes = []string{"api", "audit", "authenticator", "kcm", "ccm", "scheduler"}
+
+// eksLogTypes maps the log types of the EKS logging config to the log types they enable:
+// the controller manager logs hold the streams of both controller managers
+var eksLogTypes = map[ekstypes.LogType][]string{
+	ekstypes.LogTypeApi:               {"api"},
+	ekstypes.LogTypeAudit:             {"audit"},
+	ekstypes.LogTypeAuthenticator:     {"authenticator"},
+	ekstypes.LogTypeControllerManager: {"kcm", "ccm"},
+	ekstypes.LogTypeScheduler:         {"scheduler"},
+}
+
+var logTypesCmd = &cobra.Command{
+	Use:   "logtypes [cluster-name]",
+	Short: "Show detailed information about available log types",
+	Long: `Show detailed information about available log types for EKS Control Plane logs.
+
+Each log type corresponds to a specific component of the EKS Control Plane.
+You can specify one or more log types when retrieving logs to focus on specific components.
+
+With a cluster name, show which log types are enabled in the logging config of the cluster,
+and when each last wrote an event: a log type is active when it wrote events within
+--recent, silent when it is enabled but did not, and disabled otherwise. The last event
+times come from the log streams, which CloudWatch Logs updates with a delay of up to an
+hour.
+
+Examples:
+  ekslogs my-cluster api audit     # Get logs from API server and audit logs
+  ekslogs my-cluster auth          # Get authentication logs
+  ekslogs my-cluster scheduler     # Get scheduler logs
+  ekslogs logtypes my-cluster      # Show the log types enabled and active in my-cluster`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			printLogTypes()
+			return nil
+		}
+
+		r, err := setupReport(cmd, args, &logTypesOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		lastEvents, err := r.client.LastEventTimes(r.ctx, r.clusterName, nil)
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return r.finish()
+			}
+			return fmt.Errorf("failed to list log streams: %w", err)
+		}
+		statuses := logTypeStatuses(r.cluster, lastEvents, time.Now().Add(-logTypesRecent))
+
+		if r.format == log.OutputFormatJSON {
+			if err := printLogTypeStatusesJSON(os.Stdout, statuses); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printLogTypeStatuses(r.output(), r.clusterName, statuses)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logTypesCmd)
+
+	logTypesOptions.documents = true
+	logTypesOptions.addCommonFlags(logTypesCmd, "one JSON object per log type")
+	logTypesCmd.Flags().DurationVar(&logTypesRecent, "recent", 24*time.Hour, "With a cluster name, how recent the last event of a log type must be for it to be active")
+}
+
+// printLogTypes writes the log types ekslogs knows, and those of the config file
+func printLogTypes() {
+	fmt.Println("Available log types for EKS Control Plane logs:")
+	fmt.Println()
+	fmt.Println("  api           - API Server logs (kube-apiserver)")
+	fmt.Println("  audit         - Audit logs (kube-apiserver-audit)")
+	fmt.Println("  authenticator - Authentication logs (aws-iam-authenticator)")
+	fmt.Println("                  Alias: auth")
+	fmt.Println("  kcm           - Kube Controller Manager logs (kube-controller-manager)")
+	fmt.Println("                  Aliases: controller, kube-controller-manager")
+	fmt.Println("  ccm           - Cloud Controller Manager logs (cloud-controller-manager)")
+	fmt.Println("                  Aliases: cloud, cloud-controller-manager")
+	fmt.Println("  scheduler     - Scheduler logs (kube-scheduler)")
+	fmt.Println("                  Alias: sched")
+	fmt.Println()
+	fmt.Println("Node and pod logs from Container Insights (with --container-insights):")
+	fmt.Println()
+	fmt.Println("  kubelet       - kubelet service logs (dataplane log group)")
+	fmt.Println("  containerd    - containerd service logs (dataplane log group)")
+	fmt.Println("  docker        - Docker service logs (dataplane log group)")
+	fmt.Println("  dataplane     - Other node component logs (dataplane log group)")
+	fmt.Println("  host          - Host logs such as /var/log/messages and dmesg (host log group)")
+	fmt.Println("  application   - Container logs of pods (application log group)")
+	fmt.Println("                  Alias: app")
+	fmt.Println()
+	if _, err := loadConfig(); err != nil {
+		newLogger(os.Stderr, slog.LevelWarn).Warn("Could not load the config file", "error", err)
+	}
+	if customTypes := log.CustomLogTypes(); len(customTypes) > 0 {
+		fmt.Println("Log types of the config file:")
+		fmt.Println()
+		printCustomLogTypes(os.Stdout, customTypes)
+		fmt.Println()
+	}
+	fmt.Println("Note: Not all log types may be available for every cluster.")
+	fmt.Println("Control plane logging must be enabled in the EKS console for logs to be available.")
+	fmt.Println("If no log types are specified, all available log types will be retrieved.")
+	fmt.Println("Run 'ekslogs logtypes <cluster-name>' to see which are enabled and active in a cluster.")
+}
+
+// printCustomLogTypes lists the log types of the config file like the built-in ones
+func printCustomLogTypes(w io.Writer, customTypes []log.LogTypeDefinition) {
+	for _, logType := range customTypes {
+		description := logType.Description
+		if description == "" {
+			description = "Custom log type"
+		}
+		if logType.LogGroup != "" {
+			description += " (log group " + logType.LogGroup + ")"
+		}
+		_, _ = fmt.Fprintf(w, "  %-13s - %s\n", logType.Name, description)
+		if len(logType.Streams) > 0 {
+			_, _ = fmt.Fprintf(w, "                  Streams: %s\n", strings.Join(logType.Streams, ", "))
+		}
+		switch len(logType.Aliases) {
+		case 0:
+		case 1:
+			_, _ = fmt.Fprintf(w, "                  Alias: %s\n", logType.Aliases[0])
+		default:
+			_, _ = fmt.Fprintf(w, "                  Aliases: %s\n", strings.Join(logType.Aliases, ", "))
+		}
+	}
+}
+
+// logTypeStatus is the availability of a log type in a cluster
+type logTypeStatus struct {
+	LogType string `json:"log_type"`
+	// Enabled is nil for the log types of the config file, which the logging config of the
+	// cluster does not cover
+	Enabled   *bool      `json:"enabled"`
+	LastEvent *time.Time `json:"last_event,omitempty"`
+	Status    string     `json:"status"`
+}
+
+// logTypeStatuses returns the availability of the control plane log types and those of the
+// config file, from the logging config of the cluster and the last event of each log type.
+// A log type is active when its last event is after recent.
+func logTypeStatuses(cluster *ekstypes.Cluster, lastEvents map[string]time.Time, recent time.Time) []logTypeStatus {
+	enabled := make(map[string]bool)
+	if cluster.Logging != nil {
+		for _, setup := range cluster.Logging.ClusterLogging {
+			if setup.Enabled == nil || !*setup.Enabled {
+				continue
+			}
+			for _, eksType := range setup.Types {
+				for _, logType := range eksLogTypes[eksType] {
+					enabled[logType] = true
+				}
+			}
+		}
+	}
+
+	var statuses []logTypeStatus
+	add := func(logType string, isEnabled *bool) {
+		status := logTypeStatus{LogType: logType, Enabled: isEnabled, Status: logTypeSilent}
+		if last, ok := lastEvents[logType]; ok {
+			status.LastEvent = &last
+			if last.After(recent) {
+				status.Status = logTypeActive
+			}
+		}
+		if isEnabled != nil && !*isEnabled {
+			status.Status = logTypeDisabled
+		}
+		statuses = append(statuses, status)
+	}
+	for _, logType := range controlPlaneLogTypes {
+		isEnabled := enabled[logType]
+		add(logType, &isEnabled)
+	}
+	for _, def := range log.CustomLogTypes() {
+		if !slices.Contains(controlPlaneLogTypes, def.Name) {
+			add(def.Name, nil)
+		}
+	}
+	return statuses
+}
+
+// printLogTypeStatuses writes a table of the availability of the log types of a cluster,
+// explaining the log types that will not return events
+func printLogTypeStatuses(w io.Writer, clusterName string, statuses []logTypeStatus) {
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "LOG TYPE\tLOGGING\tLAST EVENT\tSTATUS")
+	var silent, disabled, eksTypes []string
+	for _, status := range statuses {
+		logging := "-"
+		if status.Enabled != nil {
+			logging = "disabled"
+			if *status.Enabled {
+				logging = "enabled"
+			}
+		}
+		lastEvent := "-"
+		if status.LastEvent != nil {
+			lastEvent = status.LastEvent.UTC().Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", status.LogType, logging, lastEvent, status.Status)
+
+		switch status.Status {
+		case logTypeSilent:
+			silent = append(silent, status.LogType)
+		case logTypeDisabled:
+			disabled = append(disabled, status.LogType)
+			if eksType := eksLogTypeOf(status.LogType); !slices.Contains(eksTypes, eksType) {
+				eksTypes = append(eksTypes, eksType)
+			}
+		}
+	}
+	_ = table.Flush()
+
+	if len(silent) > 0 {
+		_, _ = fmt.Fprintf(w, "\nEnabled but silent: %s. Their components wrote no recent events.\n", strings.Join(silent, ", "))
+	}
+	if len(disabled) > 0 {
+		_, _ = fmt.Fprintf(w, "\nDisabled: %s. Enable them in the logging config of the cluster:\n", strings.Join(disabled, ", "))
+		_, _ = fmt.Fprintf(w, "  aws eks update-cluster-config --name %s --logging '{\"clusterLogging\":[{\"types\":[\"%s\"],\"enabled\":true}]}'\n",
+			clusterName, strings.Join(eksTypes, `","`))
+	}
+}
+
+// eksLogTypeOf returns the log type of the EKS logging config enabling a control plane log
+// type
+func eksLogTypeOf(logType string) string {
+	for eksType, logTypes := range eksLogTypes {
+		if slices.Contains(logTypes, logType) {
+			return string(eksType)
+		}
+	}
+	return logType
+}
+
+// printLogTypeStatusesJSON writes one JSON object per log type
+func printLogTypeStatusesJSON(w io.Writer, statuses []logTypeStatus) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, status := range statuses {
+		if err := encoder.Encode(status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
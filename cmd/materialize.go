@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/filter"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	materializeOptions          reportOptions
+	materializeNamespace        string
+	materializeMetricName       string
+	materializeFilterName       string
+	materializeAlarmThreshold   float64
+	materializeAlarmPeriod      time.Duration
+	materializeAlarmEvaluations int32
+	materializeAlarmActions     []string
+)
+
+// materializeResult is what preset materialize created
+type materializeResult struct {
+	Preset       string           `json:"preset"`
+	MetricFilter aws.MetricFilter `json:"metric_filter"`
+	Alarm        *aws.MetricAlarm `json:"alarm,omitempty"`
+	LogTypes     []string         `json:"log_types"`
+}
+
+var presetCmd = &cobra.Command{
+	Use:   "preset",
+	Short: "Turn filter presets into CloudWatch resources",
+	Long:  `Turn filter presets into CloudWatch resources. Run 'ekslogs presets' to list the presets.`,
+}
+
+var materializeCmd = &cobra.Command{
+	Use:   "materialize <preset> [cluster-name]",
+	Short: "Create a CloudWatch Logs metric filter, and optionally an alarm, from a preset",
+	Long: `Create a CloudWatch Logs metric filter counting the events of the cluster log group that
+match the pattern of a preset, turning an ad-hoc search into permanent monitoring. With
+--alarm-threshold, also create a CloudWatch alarm raised when the count over --alarm-period
+reaches the threshold.
+
+The filter is named ekslogs-<preset> and the metric <cluster>/<preset> in --metric-namespace,
+unless --filter-name or --metric-name are given. Running the command again updates them.
+A metric filter applies to every log stream of the log group, whatever the log types of
+the preset.
+
+Requires logs:PutMetricFilter, and cloudwatch:PutMetricAlarm for the alarm.`,
+	Example: `  ekslogs preset materialize auth-failures my-cluster
+  ekslogs preset materialize system-masters-usage my-cluster --metric-namespace EKS/Security
+  ekslogs preset materialize api-errors my-cluster --alarm-threshold 10 --alarm-period 5m \
+    --alarm-action arn:aws:sns:us-east-1:123456789012:oncall`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		presetName := args[0]
+		preset, ok := filter.GetUnifiedPreset(presetName)
+		if !ok {
+			return fmt.Errorf("preset filter '%s' not found. Run 'ekslogs presets' to see available presets", presetName)
+		}
+		if materializeNamespace == "" {
+			return fmt.Errorf("--metric-namespace must not be empty")
+		}
+		createAlarm := materializeAlarmThreshold > 0
+		if createAlarm {
+			if materializeAlarmPeriod < time.Minute || materializeAlarmPeriod%time.Minute != 0 {
+				return fmt.Errorf("--alarm-period must be a whole number of minutes, e.g. 5m")
+			}
+			if materializeAlarmEvaluations < 1 {
+				return fmt.Errorf("--alarm-evaluation-periods must be at least 1")
+			}
+		} else if len(materializeAlarmActions) > 0 {
+			return fmt.Errorf("--alarm-action requires --alarm-threshold")
+		}
+
+		r, err := setupReport(cmd, args[1:], &materializeOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		result := materializeResult{
+			Preset: presetName,
+			MetricFilter: aws.MetricFilter{
+				Name:       materializeFilterName,
+				LogGroup:   aws.ClusterLogGroup(r.clusterName),
+				Pattern:    preset.Pattern,
+				Namespace:  materializeNamespace,
+				MetricName: materializeMetricName,
+			},
+			LogTypes: preset.LogTypes,
+		}
+		if result.MetricFilter.Name == "" {
+			result.MetricFilter.Name = "ekslogs-" + presetName
+		}
+		if result.MetricFilter.MetricName == "" {
+			result.MetricFilter.MetricName = r.clusterName + "/" + presetName
+		}
+		r.verbosef("Using preset filter pattern: %s", preset.Pattern)
+		if err := r.client.PutMetricFilter(r.ctx, result.MetricFilter); err != nil {
+			return err
+		}
+
+		if createAlarm {
+			result.Alarm = &aws.MetricAlarm{
+				Name:              fmt.Sprintf("ekslogs-%s-%s", r.clusterName, presetName),
+				Description:       fmt.Sprintf("%s in the control plane logs of %s (ekslogs preset %s)", preset.Description, r.clusterName, presetName),
+				Namespace:         result.MetricFilter.Namespace,
+				MetricName:        result.MetricFilter.MetricName,
+				Threshold:         materializeAlarmThreshold,
+				Period:            materializeAlarmPeriod,
+				EvaluationPeriods: materializeAlarmEvaluations,
+				Actions:           materializeAlarmActions,
+			}
+			if err := r.client.PutMetricAlarm(r.ctx, *result.Alarm); err != nil {
+				return err
+			}
+		}
+
+		if r.format == log.OutputFormatJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			return encoder.Encode(result)
+		}
+		printMaterializeResult(os.Stdout, result)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(presetCmd)
+	presetCmd.AddCommand(materializeCmd)
+
+	materializeOptions.addCommonFlags(materializeCmd, "the created metric filter and alarm")
+	materializeCmd.Flags().StringVar(&materializeNamespace, "metric-namespace", "EKS/Logs", "CloudWatch namespace of the metric")
+	materializeCmd.Flags().StringVar(&materializeMetricName, "metric-name", "", "Name of the metric (default <cluster>/<preset>)")
+	materializeCmd.Flags().StringVar(&materializeFilterName, "filter-name", "", "Name of the metric filter (default ekslogs-<preset>)")
+	materializeCmd.Flags().Float64Var(&materializeAlarmThreshold, "alarm-threshold", 0, "Also create an alarm raised when this many events match within --alarm-period (0 creates no alarm)")
+	materializeCmd.Flags().DurationVar(&materializeAlarmPeriod, "alarm-period", 5*time.Minute, "Period the matching events are summed over for the alarm, in whole minutes")
+	materializeCmd.Flags().Int32Var(&materializeAlarmEvaluations, "alarm-evaluation-periods", 1, "Number of consecutive periods reaching the threshold that raise the alarm")
+	materializeCmd.Flags().StringArrayVar(&materializeAlarmActions, "alarm-action", nil, "ARN of an action of the alarm, e.g. an SNS topic (can be specified multiple times)")
+}
+
+// printMaterializeResult describes the created metric filter and alarm
+func printMaterializeResult(w io.Writer, result materializeResult) {
+	f := result.MetricFilter
+	_, _ = fmt.Fprintf(w, "Metric filter %s on %s\n", f.Name, f.LogGroup)
+	_, _ = fmt.Fprintf(w, "  Pattern: %s\n", f.Pattern)
+	_, _ = fmt.Fprintf(w, "  Metric:  %s %s (count of matching events)\n", f.Namespace, f.MetricName)
+	if len(result.LogTypes) > 0 {
+		_, _ = fmt.Fprintf(w, "  Note:    the preset is meant for the %s logs; the filter counts matches in every log type\n", strings.Join(result.LogTypes, ", "))
+	}
+	if a := result.Alarm; a != nil {
+		_, _ = fmt.Fprintf(w, "Alarm %s: Sum >= %g over %s", a.Name, a.Threshold, a.Period)
+		if a.EvaluationPeriods > 1 {
+			_, _ = fmt.Fprintf(w, " for %d periods", a.EvaluationPeriods)
+		}
+		_, _ = fmt.Fprintln(w)
+		for _, action := range a.Actions {
+			_, _ = fmt.Fprintf(w, "  Action: %s\n", action)
+		}
+	}
+}
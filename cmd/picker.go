@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/filter"
+	"golang.org/x/term"
+)
+
+// pickerLogTypes are the log types offered by the interactive picker
+var pickerLogTypes = []string{"api", "audit", "authenticator", "kcm", "ccm", "scheduler"}
+
+// interactiveTerminal reports whether prompts can be shown on stderr and answered on stdin
+func interactiveTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// pickTarget lists the clusters of the region and lets the user choose a cluster, then
+// optionally log types and a preset, when ekslogs is run without arguments on a terminal
+func pickTarget(ctx context.Context, roleARN string, logger *slog.Logger) (string, []string, string, error) {
+	client, err := aws.NewEKSLogsClient(region, endpointURL, logger)
+	if err == nil && roleARN != "" {
+		err = client.SetAssumeRole(roleARN)
+	}
+	if err != nil {
+		return "", nil, "", fmt.Errorf("failed to create client: %w", err)
+	}
+
+	clusters, err := client.ListClusters(ctx)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if len(clusters) == 0 {
+		return "", nil, "", fmt.Errorf("no clusters found in %s", region)
+	}
+	sort.Strings(clusters)
+
+	presets := filter.ListUnifiedPresets()
+	sort.Strings(presets)
+
+	return promptTarget(bufio.NewReader(os.Stdin), os.Stderr, clusters, presets)
+}
+
+// promptTarget asks on w for a cluster, log types and a preset, reading the answers from r
+func promptTarget(r *bufio.Reader, w io.Writer, clusters, presets []string) (string, []string, string, error) {
+	selected, err := fuzzySelect(r, w, "Cluster", clusters, false, false)
+	if err != nil {
+		return "", nil, "", err
+	}
+	cluster := selected[0]
+
+	types, err := fuzzySelect(r, w, "Log types (comma-separated numbers, Enter for all)", pickerLogTypes, true, true)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	var preset string
+	selected, err = fuzzySelect(r, w, "Preset (Enter for none)", presets, false, true)
+	if err != nil {
+		return "", nil, "", err
+	}
+	if len(selected) > 0 {
+		preset = selected[0]
+	}
+	return cluster, types, preset, nil
+}
+
+// fuzzySelect shows the items on w as a numbered list and reads the choice from r. Numbers
+// select items (several separated by commas when multi is set); other text narrows the
+// list to the items containing its characters in order. An empty line selects nothing when
+// allowNone is set.
+func fuzzySelect(r *bufio.Reader, w io.Writer, prompt string, items []string, multi, allowNone bool) ([]string, error) {
+	shown := items
+	for {
+		for i, item := range shown {
+			_, _ = fmt.Fprintf(w, "  %d) %s\n", i+1, item)
+		}
+		_, _ = fmt.Fprintf(w, "%s [1-%d, or type to filter]: ", prompt, len(shown))
+
+		line, err := r.ReadString('\n')
+		if err != nil && line == "" {
+			return nil, fmt.Errorf("no %s selected: %w", strings.ToLower(strings.Fields(prompt)[0]), err)
+		}
+		answer := strings.TrimSpace(line)
+
+		if answer == "" {
+			if allowNone {
+				return nil, nil
+			}
+			continue
+		}
+
+		if selected, ok := parseSelection(answer, shown, multi); ok {
+			return selected, nil
+		}
+
+		matches := fuzzyMatch(answer, items)
+		if len(matches) == 0 {
+			_, _ = fmt.Fprintf(w, "Nothing matches '%s'\n", answer)
+			shown = items
+			continue
+		}
+		if len(matches) == 1 && !multi {
+			return matches, nil
+		}
+		shown = matches
+	}
+}
+
+// parseSelection converts an answer of item numbers into the items they select
+func parseSelection(answer string, items []string, multi bool) ([]string, bool) {
+	fields := strings.Split(answer, ",")
+	if len(fields) > 1 && !multi {
+		return nil, false
+	}
+
+	var selected []string
+	for _, field := range fields {
+		choice, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || choice < 1 || choice > len(items) {
+			return nil, false
+		}
+		selected = append(selected, items[choice-1])
+	}
+	return selected, true
+}
+
+// fuzzyMatch returns the items containing the characters of query in order, ignoring case
+func fuzzyMatch(query string, items []string) []string {
+	query = strings.ToLower(query)
+
+	var matches []string
+	for _, item := range items {
+		rest := strings.ToLower(item)
+		found := true
+		for _, c := range query {
+			i := strings.IndexRune(rest, c)
+			if i < 0 {
+				found = false
+				break
+			}
+			rest = rest[i+len(string(c)):]
+		}
+		if found {
+			matches = append(matches, item)
+		}
+	}
+	return matches
+}
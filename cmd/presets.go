@@ -1,18 +1,28 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/fatih/color"
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/config"
 	"github.com/kzcat/ekslogs/pkg/filter"
+	"github.com/kzcat/ekslogs/pkg/log"
 	"github.com/spf13/cobra"
 )
 
 var (
-	showAdvanced bool
-	showAll      bool
+	showAdvanced   bool
+	showAll        bool
+	presetCategory string
+	auditFilter    filter.AuditFilter
 )
 
 var unifiedPresetsCmd = &cobra.Command{
@@ -22,19 +32,34 @@ var unifiedPresetsCmd = &cobra.Command{
 
 Presets provide pre-configured filters for common scenarios, making it easy to find specific types of logs.
 Use the --advanced flag to see more complex filter patterns, or --all to see all available presets.
+Use --category security to see the presets detecting suspicious activity in audit logs.
+Presets defined in the config file, including those combining other presets, are advanced.
 
 Examples:
   ekslogs presets                # Show basic presets
   ekslogs presets --advanced     # Show advanced presets
   ekslogs presets --all          # Show all presets
+  ekslogs presets --category security  # Show security presets
+  ekslogs presets --category connectivity  # Show connectivity presets
   
   # Using presets with the main command:
   ekslogs my-cluster -p api-errors
   ekslogs my-cluster -p network-issues -F`,
 	Run: func(cmd *cobra.Command, args []string) {
+		// The built-in presets are listed even if the presets of the config file are invalid
+		if _, err := loadConfig(); err != nil {
+			newLogger(os.Stderr, slog.LevelWarn).Warn("Listing the built-in presets only", "error", err)
+		}
+
 		var presetNames []string
 
-		if showAll {
+		if presetCategory != "" {
+			presetNames = filter.ListCategoryPresets(presetCategory)
+			if len(presetNames) == 0 {
+				fmt.Printf("No presets in category '%s'. Available categories: %s\n", presetCategory, strings.Join(filter.PresetCategories, ", "))
+				return
+			}
+		} else if showAll {
 			presetNames = filter.ListUnifiedPresets()
 		} else if showAdvanced {
 			presetNames = filter.ListAdvancedPresets()
@@ -45,7 +70,9 @@ Examples:
 		// Sort preset names for consistent output
 		sort.Strings(presetNames)
 
-		if showAdvanced {
+		if presetCategory != "" {
+			fmt.Printf("Available %s filter presets:\n", presetCategory)
+		} else if showAdvanced {
 			fmt.Println("Available advanced filter presets:")
 		} else if showAll {
 			fmt.Println("Available filter presets (basic and advanced):")
@@ -67,9 +94,18 @@ Examples:
 			fmt.Printf("    Log types: %s\n", strings.Join(preset.LogTypes, ", "))
 			fmt.Printf("    Pattern: %s\n", preset.Pattern)
 
-			if showAll || showAdvanced {
+			if showAll || showAdvanced || presetCategory != "" {
 				fmt.Printf("    Pattern type: %s\n", preset.PatternType)
 			}
+			if preset.Category != "" && presetCategory == "" {
+				fmt.Printf("    Category: %s\n", preset.Category)
+			}
+			if len(preset.Includes) > 0 {
+				fmt.Printf("    Includes: %s\n", strings.Join(preset.Includes, ", "))
+			}
+			if defaults := presetDefaults(preset); len(defaults) > 0 {
+				fmt.Printf("    Defaults: %s\n", strings.Join(defaults, ", "))
+			}
 			fmt.Println()
 		}
 
@@ -88,15 +124,263 @@ Examples:
 			fmt.Println()
 		}
 
-		if !showAdvanced && !showAll {
+		if !showAdvanced && !showAll && presetCategory == "" {
 			fmt.Println("To see advanced presets, run: ekslogs presets --advanced")
 			fmt.Println("To see all presets, run: ekslogs presets --all")
+			fmt.Println("To see security presets, run: ekslogs presets --category security")
+			fmt.Println("To see connectivity presets, run: ekslogs presets --category connectivity")
 		}
 	},
 }
 
+var presetsValidateCmd = &cobra.Command{
+	Use:   "validate [preset...]",
+	Short: "Check the patterns, log types and defaults of presets",
+	Long: `Check the presets, built in and defined in the config file, or the named ones: the filter
+pattern against the CloudWatch Logs filter pattern syntax (quotes, brackets, JSON
+expressions, regular expressions and length), the log types against the known ones, and
+the default time range, limit and output format. Exits with status 1 if a preset is invalid.
+
+Presets of the config file are also checked whenever the config file is read, so a broken
+preset fails when it is defined rather than when it is needed.`,
+	Example: `  ekslogs presets validate               # Check every preset
+  ekslogs presets validate prod-triage   # Check one preset`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return err
+		}
+		invalid := 0
+		if err := filter.RegisterPresets(configPresetFilters(cfg.Presets)); err != nil {
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "config: %v\n", err)
+			invalid++
+		}
+
+		names := args
+		if len(names) == 0 {
+			names = filter.ListUnifiedPresets()
+			sort.Strings(names)
+		}
+		for _, name := range names {
+			preset, exists := filter.GetUnifiedPreset(name)
+			if !exists {
+				return fmt.Errorf("preset filter '%s' not found. Run 'ekslogs presets' to see available presets", name)
+			}
+			problems := validatePreset(preset)
+			for _, problem := range problems {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s: %v\n", name, problem)
+			}
+			if len(problems) > 0 {
+				invalid++
+			}
+		}
+
+		if invalid > 0 {
+			return fmt.Errorf("%d of %d presets are invalid", invalid, len(names))
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "All %d presets are valid.\n", len(names))
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(unifiedPresetsCmd)
+	unifiedPresetsCmd.AddCommand(presetsValidateCmd)
 	unifiedPresetsCmd.Flags().BoolVar(&showAdvanced, "advanced", false, "Show only advanced presets")
 	unifiedPresetsCmd.Flags().BoolVar(&showAll, "all", false, "Show all presets (basic and advanced)")
+	unifiedPresetsCmd.Flags().StringVar(&presetCategory, "category", "", "Show only the presets of a category: "+strings.Join(filter.PresetCategories, ", "))
+}
+
+// validatePreset returns the problems of a preset: a pattern CloudWatch Logs rejects,
+// unknown log types, and invalid defaults
+func validatePreset(preset filter.UnifiedPresetFilter) []error {
+	var problems []error
+	if err := filter.ValidatePattern(preset.Pattern); err != nil {
+		problems = append(problems, fmt.Errorf("pattern %s: %w", preset.Pattern, err))
+	}
+	for _, logType := range preset.LogTypes {
+		if !log.IsLogType(logType) {
+			problems = append(problems, fmt.Errorf("unknown log type '%s' (run 'ekslogs logtypes' to list them)", logType))
+		}
+	}
+	if preset.Since != "" {
+		if _, err := log.ParseTimeString(preset.Since); err != nil {
+			problems = append(problems, fmt.Errorf("invalid default start time '%s': %w", preset.Since, err))
+		}
+	}
+	if preset.Limit < 0 {
+		problems = append(problems, fmt.Errorf("negative default limit %d", preset.Limit))
+	}
+	if preset.Output != "" {
+		if _, err := log.ParseEntryOutputFormat(preset.Output); err != nil {
+			problems = append(problems, fmt.Errorf("invalid default output format: %w", err))
+		}
+	}
+	return problems
+}
+
+// presetDefaults describes the default time range, limit and output format of a preset
+func presetDefaults(preset filter.UnifiedPresetFilter) []string {
+	var defaults []string
+	if preset.Since != "" {
+		defaults = append(defaults, "since "+preset.Since)
+	}
+	if preset.Limit > 0 {
+		defaults = append(defaults, fmt.Sprintf("limit %d", preset.Limit))
+	}
+	if preset.Output != "" {
+		defaults = append(defaults, "output "+preset.Output)
+	}
+	return defaults
+}
+
+// applyPreset applies the filter pattern and log types of a preset, unless filter patterns
+// or log types were given, and its default time range, limit and output format to the flags
+// of cmd not given. An empty name applies nothing.
+func applyPreset(name string, cmd *cobra.Command) error {
+	if name == "" {
+		return nil
+	}
+
+	preset, exists := filter.GetUnifiedPreset(name)
+	if !exists {
+		return fmt.Errorf("preset filter '%s' not found. Run 'ekslogs presets' to see available presets", name)
+	}
+
+	// Apply preset filter pattern if no custom filter pattern is provided
+	if len(filterPatterns) == 0 {
+		filterPatterns = []string{preset.Pattern}
+		if preset.Advanced {
+			verbosef("Using preset filter pattern: %s (type: %s)", preset.Pattern, preset.PatternType)
+		} else {
+			verbosef("Using preset filter pattern: %s", preset.Pattern)
+		}
+	}
+
+	// Apply preset log types if no custom log types are provided
+	if len(logTypes) == 0 {
+		logTypes = preset.LogTypes
+		verbosef("Using preset log types: %s", strings.Join(logTypes, ", "))
+	}
+
+	return applyPresetDefaults(preset, cmd)
+}
+
+// presetChannels returns the channels following several presets at once, labeled with their
+// names: the pattern of each preset combined with the ignore patterns, on the log types given
+// or those of the preset. The defaults of the first preset having one apply to the flags not
+// given. Filter patterns and the audit quick filters would replace the patterns of the
+// presets, so they cannot be combined.
+func presetChannels(names []string, cmd *cobra.Command) ([]aws.TailChannel, error) {
+	if !follow {
+		return nil, errors.New("several presets can only be followed: add --follow, or give a single --preset")
+	}
+	if len(filterPatterns) > 0 || auditFilter != (filter.AuditFilter{}) {
+		return nil, errors.New("several presets cannot be combined with --filter-pattern, --writes-only, --reads-only or --non-system")
+	}
+
+	var channels []aws.TailChannel
+	for _, name := range names {
+		if slices.ContainsFunc(channels, func(channel aws.TailChannel) bool { return channel.Label == name }) {
+			continue
+		}
+		preset, exists := filter.GetUnifiedPreset(name)
+		if !exists {
+			return nil, fmt.Errorf("preset filter '%s' not found. Run 'ekslogs presets' to see available presets", name)
+		}
+
+		channel := aws.TailChannel{Label: name, LogTypes: logTypes}
+		if len(channel.LogTypes) == 0 {
+			channel.LogTypes = preset.LogTypes
+		}
+		if pattern := buildCombinedFilterPattern([]string{preset.Pattern}, ignoreFilterPatterns, false); pattern != "" {
+			channel.FilterPattern = &pattern
+		}
+		verbosef("Following preset %s: pattern %s, log types %s", name, preset.Pattern, strings.Join(channel.LogTypes, ", "))
+		if err := applyPresetDefaults(preset, cmd); err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, nil
+}
+
+// applyPresetDefaults sets the flags a preset has a default for, unless they were given. The
+// time range default applies only when neither --start-time nor --end-time is given.
+func applyPresetDefaults(preset filter.UnifiedPresetFilter, cmd *cobra.Command) error {
+	flags := cmd.Flags()
+	var defaults [][2]string
+	if preset.Since != "" && !flags.Changed("start-time") && !flags.Changed("end-time") {
+		defaults = append(defaults, [2]string{"start-time", preset.Since})
+	}
+	if preset.Limit > 0 {
+		defaults = append(defaults, [2]string{"limit", strconv.Itoa(int(preset.Limit))})
+	}
+	if preset.Output != "" {
+		defaults = append(defaults, [2]string{"output", preset.Output})
+	}
+
+	for _, d := range defaults {
+		name, value := d[0], d[1]
+		if flags.Lookup(name) == nil || flags.Changed(name) {
+			continue
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid --%s default of the preset: %w", name, err)
+		}
+		verbosef("Using preset default --%s %s", name, value)
+	}
+	return nil
+}
+
+// applyNamespaceFilter scopes the query to a namespace (--namespace). Audit events are
+// filtered by CloudWatch Logs through the audit filter when only audit events are read and
+// the filter patterns are JSON patterns; otherwise the entries have to be matched
+// client-side, and a namespace filter is returned.
+func applyNamespaceFilter(namespace string) *log.NamespaceFilter {
+	if namespace == "" {
+		return nil
+	}
+	serverSide := auditFilter != (filter.AuditFilter{}) || (len(logTypes) > 0 && len(ignoreFilterPatterns) == 0)
+	for _, logType := range logTypes {
+		serverSide = serverSide && log.NormalizeLogType(logType) == "audit"
+	}
+	for _, pattern := range filterPatterns {
+		serverSide = serverSide && filter.IsJSONPattern(pattern)
+	}
+	if serverSide {
+		auditFilter.Namespace = namespace
+		return nil
+	}
+	verbosef("Matching namespace %s client-side", namespace)
+	return log.NewNamespaceFilter(namespace)
+}
+
+// applyAuditFilter replaces the filter patterns with the JSON pattern of the audit quick
+// filters (--writes-only, --reads-only, --non-system), combined with JSON filter patterns
+// given or from the preset. Without log types, only the audit log is read.
+func applyAuditFilter(auditFilter filter.AuditFilter) error {
+	if auditFilter == (filter.AuditFilter{}) {
+		return nil
+	}
+	if len(ignoreFilterPatterns) > 0 {
+		return errors.New("--writes-only, --reads-only and --non-system cannot be combined with --ignore-filter-pattern")
+	}
+	pattern, err := auditFilter.Pattern(filterPatterns)
+	if err != nil {
+		return err
+	}
+	filterPatterns = []string{pattern}
+	verbosef("Using audit filter pattern: %s", pattern)
+
+	if len(logTypes) == 0 {
+		logTypes = []string{"audit"}
+		return nil
+	}
+	for _, logType := range logTypes {
+		if log.NormalizeLogType(logType) == "audit" {
+			return nil
+		}
+	}
+	return fmt.Errorf("--writes-only, --reads-only and --non-system filter audit events, but the log types %s do not include audit", strings.Join(logTypes, ", "))
 }
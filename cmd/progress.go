@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"golang.org/x/term"
+)
+
+const progressInterval = 200 * time.Millisecond
+
+var noProgress bool
+
+// spinnerFrames are the animation frames of the progress indicator
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// progressEnabled reports whether the progress indicator should be shown. It is drawn
+// only when stderr is a terminal and stdout is redirected, so it never interleaves with log lines.
+func progressEnabled() bool {
+	if noProgress || quiet {
+		return false
+	}
+	return term.IsTerminal(int(os.Stderr.Fd())) && !term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// startProgress redraws the progress line on w until the returned function is called.
+// The returned function may be called more than once.
+func startProgress(w io.Writer, client *aws.EKSLogsClient) func() {
+	done := make(chan struct{})
+	var stopOnce sync.Once
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+
+		started := time.Now()
+		for frame := 0; ; frame++ {
+			_, _ = fmt.Fprintf(w, "\r\033[K%s", formatProgress(spinnerFrames[frame%len(spinnerFrames)], client.Progress(), time.Since(started)))
+			select {
+			case <-done:
+				_, _ = fmt.Fprint(w, "\r\033[K")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
+
+// formatProgress renders the progress line: events so far and the time range coverage of each log group
+func formatProgress(frame string, progress aws.Progress, elapsed time.Duration) string {
+	parts := []string{fmt.Sprintf("%s %d events", frame, progress.Events)}
+	for _, group := range progress.Groups {
+		switch {
+		case group.Done:
+			parts = append(parts, fmt.Sprintf("%s done", group.LogGroup))
+		case group.Reached.IsZero():
+			parts = append(parts, fmt.Sprintf("%s waiting", group.LogGroup))
+		default:
+			parts = append(parts, fmt.Sprintf("%s %.0f%% (at %s)", group.LogGroup, group.Coverage*100, group.Reached.UTC().Format(time.RFC3339)))
+		}
+	}
+	parts = append(parts, elapsed.Round(time.Second).String())
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,249 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/cache"
+	"github.com/kzcat/ekslogs/pkg/filter"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	queryOptions   reportOptions
+	queryPreset    string
+	queryString    string
+	queryLimit     int32
+	queryList      bool
+	querySaveToCWL bool
+)
+
+// savedQuery is a preset saved by query --save-to-cloudwatch
+type savedQuery struct {
+	aws.QueryDefinition
+	Preset  string `json:"preset"`
+	Updated bool   `json:"updated"`
+}
+
+var queryCmd = &cobra.Command{
+	Use:   "query [cluster-name]",
+	Short: "Run a CloudWatch Logs Insights query over the control plane logs",
+	Long: `Run a CloudWatch Logs Insights query over the cluster log group and print its results as a
+table. Unlike the filter patterns of the root command, Insights queries aggregate, so they
+answer questions like which users send the most requests or which webhooks are slowest.
+
+Give either a preset of the query library with -p (--list shows them) or a query of your
+own with --query. The time range defaults to the past hour. Insights bills by the data
+scanned: narrow the time range on large clusters. The result is cached for --cache-ttl,
+so running the same query again (e.g. with -o json) does not scan the logs twice.
+
+With --save-to-cloudwatch the preset (or every preset without -p) is saved in the account
+as ekslogs/<cluster>/<preset>, listed under Saved queries in the CloudWatch console, instead
+of being run. Saving again updates it.
+
+Requires logs:StartQuery, logs:GetQueryResults and logs:StopQuery, and
+logs:DescribeQueryDefinitions and logs:PutQueryDefinition to save queries.`,
+	Example: `  ekslogs query --list
+  ekslogs query my-cluster -p audit-top-verbs -s -6h
+  ekslogs query my-cluster -p webhook-latency -o json
+  ekslogs query my-cluster --query 'filter @message like /OOMKilled/ | stats count(*) by bin(5m)'
+  ekslogs query my-cluster --save-to-cloudwatch`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if queryList {
+			printInsightsPresets(os.Stdout)
+			return nil
+		}
+		if querySaveToCWL && queryOptions.offline {
+			return fmt.Errorf("--save-to-cloudwatch cannot be combined with --offline")
+		}
+		var presets []string
+		switch {
+		case queryPreset != "" && queryString != "":
+			return fmt.Errorf("--preset and --query cannot be combined")
+		case queryPreset != "":
+			if _, ok := filter.GetInsightsPreset(queryPreset); !ok {
+				return fmt.Errorf("query preset '%s' not found. Run 'ekslogs query --list' to see available presets", queryPreset)
+			}
+			presets = []string{queryPreset}
+		case querySaveToCWL && queryString != "":
+			return fmt.Errorf("--save-to-cloudwatch saves presets and cannot be combined with --query")
+		case querySaveToCWL:
+			presets = filter.ListInsightsPresets()
+		case queryString == "":
+			return fmt.Errorf("a query is required: give a preset with -p (see 'ekslogs query --list') or --query")
+		}
+		if queryLimit < 1 || queryLimit > aws.MaxInsightsResults {
+			return fmt.Errorf("--limit must be between 1 and %d", aws.MaxInsightsResults)
+		}
+
+		r, err := setupReport(cmd, args, &queryOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+		logGroups := []string{aws.ClusterLogGroup(r.clusterName)}
+
+		if querySaveToCWL {
+			saved := make([]savedQuery, 0, len(presets))
+			for _, name := range presets {
+				preset, _ := filter.GetInsightsPreset(name)
+				query := savedQuery{
+					QueryDefinition: aws.QueryDefinition{
+						Name:      fmt.Sprintf("ekslogs/%s/%s", r.clusterName, name),
+						Query:     preset.Query,
+						LogGroups: logGroups,
+					},
+					Preset: name,
+				}
+				if query.Updated, err = r.client.SaveQueryDefinition(r.ctx, query.QueryDefinition); err != nil {
+					return err
+				}
+				saved = append(saved, query)
+			}
+			if r.format == log.OutputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetEscapeHTML(false)
+				for _, query := range saved {
+					if err := encoder.Encode(query); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			printSavedQueries(os.Stdout, saved)
+			return nil
+		}
+
+		query := queryString
+		if queryPreset != "" {
+			preset, _ := filter.GetInsightsPreset(queryPreset)
+			query = preset.Query
+		}
+		rangeEnd := time.Now()
+		if r.end != nil {
+			rangeEnd = *r.end
+		}
+		rangeStart := rangeEnd.Add(-time.Hour)
+		if r.start != nil {
+			rangeStart = *r.start
+		}
+
+		// Relative times are part of the key as given, like for the root command
+		cacheKey := cache.Key("insights", r.region, r.clusterName, queryOptions.startTime, queryOptions.endTime, query, strconv.Itoa(int(queryLimit)))
+		results := r.cache()
+		var result aws.InsightsResult
+		var storedAt time.Time
+		var cached bool
+		if results != nil {
+			if storedAt, cached, err = results.Get(cacheKey, &result); err != nil {
+				r.logger.Warn("Ignoring the cached result", "error", err)
+			}
+		}
+		switch {
+		case cached:
+			if !queryOptions.quiet {
+				printCacheNotice(os.Stderr, storedAt)
+			}
+		case queryOptions.offline:
+			return errNotCached
+		default:
+			r.verbosef("Running Logs Insights query from %s to %s:\n%s", rangeStart.Format(time.RFC3339), rangeEnd.Format(time.RFC3339), query)
+			result, err = r.client.RunInsightsQuery(r.ctx, logGroups, query, rangeStart, rangeEnd, queryLimit)
+			if err != nil {
+				if r.ctx.Err() != nil {
+					return r.finish()
+				}
+				return err
+			}
+			if results != nil {
+				if err := results.Put(cacheKey, result); err != nil {
+					r.logger.Warn("Could not cache the result", "error", err)
+				}
+			}
+		}
+		r.verbosef("%d rows, %.0f records matched of %.0f scanned (%.1f MB)",
+			len(result.Rows), result.RecordsMatched, result.RecordsScanned, result.BytesScanned/1e6)
+		if r.format == log.OutputFormatJSON {
+			return printInsightsJSON(os.Stdout, result)
+		}
+		printInsightsResult(r.output(), result)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	queryOptions.documents = true
+	queryOptions.addFlags(queryCmd, "one JSON object per result row")
+	queryOptions.addCacheFlags(queryCmd)
+	queryCmd.Flags().StringVarP(&queryPreset, "preset", "p", "", "Run a query preset (see --list)")
+	queryCmd.Flags().StringVar(&queryString, "query", "", "Logs Insights query to run")
+	queryCmd.Flags().Int32VarP(&queryLimit, "limit", "l", 1000, fmt.Sprintf("Maximum number of result rows (at most %d)", aws.MaxInsightsResults))
+	queryCmd.Flags().BoolVar(&queryList, "list", false, "List the query presets")
+	queryCmd.Flags().BoolVar(&querySaveToCWL, "save-to-cloudwatch", false, "Save the preset, or every preset without -p, as a saved query of the account instead of running it")
+}
+
+// printInsightsPresets lists the query presets
+func printInsightsPresets(w io.Writer) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "PRESET\tLOG TYPES\tDESCRIPTION")
+	for _, name := range filter.ListInsightsPresets() {
+		preset, _ := filter.GetInsightsPreset(name)
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\n", name, strings.Join(preset.LogTypes, ","), preset.Description)
+	}
+	_ = table.Flush()
+}
+
+// printInsightsResult writes the rows of a query as a table, one column per field
+func printInsightsResult(w io.Writer, result aws.InsightsResult) {
+	if len(result.Rows) == 0 {
+		_, _ = fmt.Fprintln(w, "No results.")
+		return
+	}
+	// Multi-line values, e.g. a whole @message, would break the table
+	cell := strings.NewReplacer("\t", " ", "\n", " ", "\r", "")
+
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, strings.Join(result.Fields, "\t"))
+	for _, row := range result.Rows {
+		values := make([]string, len(result.Fields))
+		for i, field := range result.Fields {
+			values[i] = orDash(cell.Replace(row[field]))
+		}
+		_, _ = fmt.Fprintln(table, strings.Join(values, "\t"))
+	}
+	_ = table.Flush()
+}
+
+// printSavedQueries lists the saved queries
+func printSavedQueries(w io.Writer, saved []savedQuery) {
+	for _, query := range saved {
+		action := "Created"
+		if query.Updated {
+			action = "Updated"
+		}
+		_, _ = fmt.Fprintf(w, "%s saved query %s on %s\n", action, query.Name, strings.Join(query.LogGroups, ", "))
+	}
+}
+
+// printInsightsJSON writes one JSON object per result row
+func printInsightsJSON(w io.Writer, result aws.InsightsResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, row := range result.Rows {
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
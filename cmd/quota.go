@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kzcat/ekslogs/pkg/filter"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var quotaOptions reportOptions
+
+var quotaCmd = &cobra.Command{
+	Use:   "quota [cluster-name]",
+	Short: "Report the requests denied by ResourceQuotas and LimitRanges per namespace",
+	Long: `Find the requests the API server denied because of a ResourceQuota ("exceeded quota",
+"failed quota: must specify limits.cpu") or a LimitRange ("maximum cpu usage per
+Container is 1") of their namespace, and count them per namespace and policy, with the
+users denied and the reason of the latest denial. A quota sized too small or a
+LimitRange stricter than the workloads shows up as pods silently missing from their
+ReplicaSets.
+
+The denials are read from the audit log. The same messages can be followed with the
+quota-denials preset: ekslogs my-cluster -p quota-denials -f`,
+	Example: `  ekslogs quota my-cluster -s -1d    # Denials of the past day
+  ekslogs quota my-cluster -o json   # One JSON object per namespace and policy`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &quotaOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		preset, _ := filter.GetUnifiedPreset("quota-denials")
+		entries, err := r.collect([]string{"audit"}, r.start, r.end, preset.Pattern, 0)
+		if err != nil {
+			return err
+		}
+
+		var denials []log.QuotaDenial
+		for _, entry := range entries {
+			if denial, ok := log.ParseQuotaDenial(entry); ok {
+				denials = append(denials, denial)
+			}
+		}
+		r.verbosef("Found %d quota and limit range denials in %d audit events", len(denials), len(entries))
+
+		groups := log.GroupQuotaDenials(denials)
+		if r.format == log.OutputFormatJSON {
+			if err := printQuotaJSON(os.Stdout, groups); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printQuotaReport(r.output(), groups)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(quotaCmd)
+
+	quotaOptions.documents = true
+	quotaOptions.addFlags(quotaCmd, "one JSON object per namespace and policy")
+}
+
+// printQuotaReport writes a table of the denials per namespace and policy, the namespaces
+// with the most denials first
+func printQuotaReport(w io.Writer, groups []log.QuotaGroup) {
+	if len(groups) == 0 {
+		_, _ = fmt.Fprintln(w, "No requests denied by a ResourceQuota or LimitRange found.")
+		return
+	}
+
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "NAMESPACE\tCOUNT\tKIND\tPOLICY\tREASON")
+	for _, group := range groups {
+		_, _ = fmt.Fprintf(table, "%s\t%d\t%s\t%s\t%s\n", orDash(group.Namespace), group.Count, group.Kind, orDash(group.Policy), group.Reason)
+	}
+	_ = table.Flush()
+}
+
+// printQuotaJSON writes one JSON object per namespace and policy
+func printQuotaJSON(w io.Writer, groups []log.QuotaGroup) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, group := range groups {
+		if err := encoder.Encode(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
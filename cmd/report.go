@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/cache"
+	"github.com/kzcat/ekslogs/pkg/config"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// errPartialReport is returned after printing a report whose retrieval was stopped by
+// Ctrl+C or --timeout, as it only covers part of the time range
+var errPartialReport = errors.New("report is partial")
+
+// reportOptions holds the flags shared by the report subcommands (admission, api-health,
+// break-glass, bundle, certs, cloudtrail, diff, etcd, quota, scaling, source-ips, throttling,
+// timeline, watch-churn), preset materialize, query and subscribe. Each subcommand has its
+// own, so the flags given to one do not leak into another or into the root command.
+type reportOptions struct {
+	region    string
+	startTime string
+	endTime   string
+	output    string
+	logLevel  string
+	timeout   time.Duration
+	verbose   bool
+	quiet     bool
+	debug     bool
+	// noCache, offline and cacheTTL are only registered by the reports caching their
+	// results (addCacheFlags)
+	noCache  bool
+	offline  bool
+	cacheTTL time.Duration
+	// documents is set by the reports printed as tables and text, which --output markdown
+	// and html render as documents; it must be set before the flags are added
+	documents bool
+}
+
+// addFlags registers the shared report flags on cmd; jsonOutput describes the JSON output
+func (o *reportOptions) addFlags(cmd *cobra.Command, jsonOutput string) {
+	cmd.Flags().StringVarP(&o.startTime, "start-time", "s", "", "Start time (RFC3339 format or relative: -1h, -15m, -30s, -2d)")
+	cmd.Flags().StringVarP(&o.endTime, "end-time", "e", "", "End time (RFC3339 format or relative: -1h, -15m, -30s, -2d)")
+	o.addCommonFlags(cmd, jsonOutput)
+}
+
+// addCommonFlags registers the shared report flags but --start-time and --end-time, for
+// reports taking their time range otherwise
+func (o *reportOptions) addCommonFlags(cmd *cobra.Command, jsonOutput string) {
+	cmd.Flags().StringVarP(&o.region, "region", "r", "", "AWS region")
+	formats := "text, json (" + jsonOutput + ")"
+	if o.documents {
+		formats += ", markdown, html (a document for incident notes)"
+	}
+	cmd.Flags().StringVarP(&o.output, "output", "o", "text", "Output format: "+formats)
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 0, "Stop the retrieval after this duration and report what was read as partial, e.g. 5m (0 means no timeout)")
+	cmd.Flags().BoolVarP(&o.verbose, "verbose", "v", false, "Verbose output")
+	cmd.Flags().BoolVarP(&o.quiet, "quiet", "q", false, "Print nothing but the report (errors are still reported on stderr)")
+	cmd.Flags().BoolVar(&o.debug, "debug", false, "Write the timing and request ID of each AWS API call to stderr (same as --log-level debug)")
+	cmd.Flags().StringVar(&o.logLevel, "log-level", "", "Level of diagnostics written to stderr: debug, info, warn, error (default warn, info with --verbose)")
+}
+
+// addCacheFlags registers the flags of the result cache, for reports whose retrieval is
+// cached
+func (o *reportOptions) addCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&o.noCache, "no-cache", false, "Run the query even when its result is cached, and do not cache the result")
+	cmd.Flags().BoolVar(&o.offline, "offline", false, "Print the cached result of the same query, of any age, without calling AWS")
+	cmd.Flags().DurationVar(&o.cacheTTL, "cache-ttl", defaultCacheTTL, "How long the result of a query is reused by later runs with the same cluster and time range (0 disables the cache)")
+	cmd.MarkFlagsMutuallyExclusive("no-cache", "offline")
+}
+
+// report is a run of a report subcommand after the shared setup: the configuration and
+// flags are resolved, the client is created and the cluster is found
+type report struct {
+	cmd         *cobra.Command
+	options     *reportOptions
+	config      *config.Config
+	context     *config.Context
+	ctx         context.Context
+	stop        context.CancelFunc
+	logger      *slog.Logger
+	client      *aws.EKSLogsClient
+	cluster     *ekstypes.Cluster
+	clusterName string
+	region      string
+	logTypes    []string // log types given as arguments
+	format      log.OutputFormat
+	start, end  *time.Time
+	// document is the Markdown or HTML document the report is printed to, written by finish
+	document *log.Document
+}
+
+// setupReport resolves the context, output format, time range and region of a report
+// subcommand, creates its client, and finds the cluster. The context of the report is
+// cancelled by Ctrl+C or --timeout; the caller must call stop when done.
+func setupReport(cmd *cobra.Command, args []string, options *reportOptions) (*report, error) {
+	appConfig, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	activeContext, err := appConfig.Context(contextName)
+	if err != nil {
+		return nil, err
+	}
+	clusterName, logTypes, err := applyContext(activeContext, args)
+	if err != nil {
+		return nil, err
+	}
+
+	parseFormat := log.ParseOutputFormat
+	if options.documents {
+		parseFormat = log.ParseReportFormat
+	}
+	format, err := parseFormat(options.output)
+	if err != nil {
+		return nil, err
+	}
+	startT, endT, err := parseTimeRange(options.startTime, options.endTime)
+	if err != nil {
+		return nil, err
+	}
+	if options.timeout < 0 {
+		return nil, fmt.Errorf("--timeout must be a positive duration")
+	}
+
+	level, err := parseLogLevel(options.logLevel, options.verbose, options.debug, options.quiet)
+	if err != nil {
+		return nil, err
+	}
+	logger := newLogger(os.Stderr, level)
+
+	reportRegion := options.region
+	if reportRegion == "" {
+		reportRegion = contextRegion(activeContext, clusterName)
+	}
+	if reportRegion == "" {
+		reportRegion = defaultRegion()
+	}
+	client, err := aws.NewEKSLogsClient(reportRegion, endpointURL, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	if roleARN := contextRoleARN(activeContext, clusterName); roleARN != "" {
+		if err := client.SetAssumeRole(roleARN); err != nil {
+			return nil, fmt.Errorf("failed to create client: %w", err)
+		}
+	}
+
+	ctx, stopSignals := interruptContext(cmd.Context())
+	stop := stopSignals
+	if options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.timeout)
+		stop = func() {
+			cancel()
+			stopSignals()
+		}
+	}
+
+	r := &report{
+		cmd:      cmd,
+		options:  options,
+		config:   appConfig,
+		context:  activeContext,
+		ctx:      ctx,
+		stop:     stop,
+		logger:   logger,
+		client:   client,
+		region:   reportRegion,
+		logTypes: logTypes,
+		format:   format,
+		start:    startT,
+		end:      endT,
+	}
+	if options.offline {
+		// Only the cache is read: the cluster is known by the name given
+		r.cluster, r.clusterName = &ekstypes.Cluster{Name: &clusterName}, clusterName
+		return r, nil
+	}
+	r.cluster, r.clusterName, err = getCluster(ctx, client, clusterName)
+	if err != nil {
+		defer stop()
+		if ctx.Err() != nil {
+			return nil, r.finish()
+		}
+		return nil, fmt.Errorf("failed to get cluster info: %w", err)
+	}
+	return r, nil
+}
+
+// collect reads the log events of the report matching pattern, or all of them for an empty
+// pattern. An error caused by Ctrl+C
+// or --timeout is not returned: the events read so far are reported, and finish marks the
+// report as partial.
+func (r *report) collect(logTypes []string, start, end *time.Time, pattern string, limit int32) ([]log.LogEntry, error) {
+	var filterPattern *string
+	if pattern != "" {
+		filterPattern = &pattern
+	}
+	entries, err := r.client.CollectLogs(r.ctx, r.clusterName, logTypes, start, end, filterPattern, limit)
+	if err != nil && r.ctx.Err() == nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// cache returns the cache of the report results, or nil when disabled
+func (r *report) cache() *cache.Cache {
+	return resultCache(r.options.noCache, r.options.offline, r.options.cacheTTL)
+}
+
+// verbosef writes a line of verbose output to stderr, like verbosef for the root command
+func (r *report) verbosef(format string, args ...interface{}) {
+	if r.options.verbose && !r.options.quiet {
+		_, _ = fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// output returns the writer the text report is printed to: stdout, or with --output
+// markdown or html a document titled with the command, written to stdout by finish
+func (r *report) output() io.Writer {
+	if !r.format.IsDocument() {
+		return os.Stdout
+	}
+	if r.document == nil {
+		details := []string{"Cluster: " + r.clusterName + " (" + r.region + ")"}
+		if r.start != nil || r.end != nil {
+			details = append(details, "Time range: "+documentTime(r.start, "the start of the log group")+" to "+documentTime(r.end, "now"))
+		}
+		details = append(details, "Generated: "+time.Now().UTC().Format(time.RFC3339))
+		if r.ctx.Err() != nil {
+			details = append(details, "Partial: the retrieval was stopped by Ctrl+C or --timeout")
+		}
+		title := "ekslogs " + r.cmd.Name() + " " + r.clusterName
+		r.document = log.NewDocument(os.Stdout, r.format, title, details...)
+	}
+	return r.document
+}
+
+// documentTime formats a bound of the time range of a document, or describes a missing one
+func documentTime(t *time.Time, missing string) string {
+	if t == nil {
+		return missing
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// tableWriter writes the tab-separated rows of a table, aligned by Flush
+type tableWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// newTable returns a writer of the rows of a table printed to w: a table of the document
+// when w is the document of a report, aligned columns otherwise
+func newTable(w io.Writer) tableWriter {
+	if document, ok := w.(*log.Document); ok {
+		return document.Table()
+	}
+	return tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+}
+
+// finish is called after printing the report, and writes its document if any. It returns
+// errPartialReport when the retrieval was stopped by Ctrl+C or --timeout, so the report is
+// not mistaken for a complete one and the command exits with a non-zero status.
+func (r *report) finish() error {
+	if r.document != nil {
+		if err := r.document.Close(); err != nil {
+			return err
+		}
+	}
+	if r.ctx.Err() == nil {
+		return nil
+	}
+	// Not a usage error: keep cobra from printing the error and the usage text
+	r.cmd.SilenceErrors = true
+	r.cmd.SilenceUsage = true
+	if errors.Is(r.ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w after %s (--timeout)", errPartialReport, errTimeout, r.options.timeout)
+	}
+	return fmt.Errorf("%w: interrupted", errPartialReport)
+}
@@ -2,39 +2,75 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"os/signal"
+	"os/exec"
 	"strings"
-	"syscall"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/config"
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
 	"github.com/fatih/color"
 	"github.com/kzcat/ekslogs/pkg/aws"
-	"github.com/kzcat/ekslogs/pkg/filter"
 	"github.com/kzcat/ekslogs/pkg/log"
 	"github.com/spf13/cobra"
 )
 
 var (
-	version              = "dev"
-	commit               = "none"
-	date                 = "unknown"
-	clusterName          string
-	region               string
-	logTypes             []string
-	startTime            string
-	endTime              string
-	filterPatterns       []string
-	ignoreFilterPatterns []string
-	presetName           string
-	limit                int32
-	limitSpecified       bool // Whether the limit was explicitly specified by the user
-	verbose              bool
-	follow               bool
-	interval             time.Duration
-	colorMode            string
+	version               = "dev"
+	commit                = "none"
+	date                  = "unknown"
+	clusterName           string
+	region                string
+	endpointURL           string
+	logTypes              []string
+	startTime             string
+	endTime               string
+	filterPatterns        []string
+	ignoreFilterPatterns  []string
+	presetName            string
+	presetNames           []string // Presets of -p; several are followed at once
+	namespace             string
+	limit                 int32
+	limitSpecified        bool // Whether the limit was explicitly specified by the user
+	verbose               bool
+	follow                bool
+	interval              time.Duration
+	intervalMax           time.Duration
+	colorMode             string
+	pretty                bool
+	flatten               bool
+	fields                []string
+	jqExpression          string
+	timestampSource       string
+	outputFormat          string
+	showLag               bool
+	cloudWatchMetadata    bool
+	redact                bool
+	redactIdentities      bool
+	themeName             string
+	lineFormat            string
+	truncateWidth         int
+	messagePrefix         []string
+	joinMultiline         bool
+	wrapLines             bool
+	sortOrder             string
+	tailCount             int
+	limitOldest           bool // --limit keeps the oldest events instead of the most recent
+	sampleSpec            string
+	debug                 bool
+	containerInsights     bool
+	includeUnknownStreams bool
+	findRegion            bool
+	rawOutput             bool
+	splitParts            int
+	skipClusterCheck      bool
+	recordDir             string
+	replayDir             string
 
 	// Execute is the function that executes the root command
 	// It can be replaced in tests
@@ -42,7 +78,7 @@ var (
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "ekslogs <cluster-name> [log-types...]",
+	Use:   "ekslogs [cluster-name] [log-types...]",
 	Short: "A CLI tool for retrieving and monitoring EKS cluster Control Plane logs.",
 	Long: `A fast and intuitive CLI tool for retrieving and monitoring Amazon EKS cluster Control Plane logs.
 
@@ -56,163 +92,591 @@ Features:
 
 Log types: api, audit, auth, kcm, ccm, scheduler (or sched)
 If no log types are specified, all available log types will be retrieved.
-Run 'ekslogs logtypes' for more detailed information about available log types.`,
+Run 'ekslogs logtypes' for more detailed information about available log types.
+
+The cluster name can be left out when the current context of the config file names a
+cluster. Run 'ekslogs ctx' to list the contexts.`,
 	Example: `  ekslogs my-cluster                         # Get all logs from past hour
   ekslogs my-cluster api audit -f -F "error" # Monitor API/audit errors in real-time
   ekslogs my-cluster -s "-1h" -e "now"       # Get logs from specific time range
   ekslogs my-cluster -p api-errors -F        # Monitor API errors in real-time using preset
   ekslogs my-cluster -F "volume" -I "health" # Include volume logs but exclude health checks
   ekslogs my-cluster -F "error" -F "warning" -I "debug" -I "info" # Include errors AND warnings, exclude debug OR info`,
-	Args: cobra.MinimumNArgs(1),
+	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		clusterName = args[0]
-		if len(args) > 1 {
-			logTypes = args[1:]
-		}
-
-		// Apply preset filter if specified
-		if presetName != "" {
-			preset, exists := filter.GetUnifiedPreset(presetName)
-			if !exists {
-				return fmt.Errorf("preset filter '%s' not found. Run 'ekslogs presets' to see available presets", presetName)
-			}
-
-			// Apply preset filter pattern if no custom filter pattern is provided
-			if len(filterPatterns) == 0 {
-				filterPatterns = []string{preset.Pattern}
-				if verbose {
-					if preset.Advanced {
-						fmt.Printf("Using preset filter pattern: %s (type: %s)\n", preset.Pattern, preset.PatternType)
-					} else {
-						fmt.Printf("Using preset filter pattern: %s\n", preset.Pattern)
-					}
-				}
-			}
+		appConfig, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		activeContext, err := appConfig.Context(contextName)
+		if err != nil {
+			return err
+		}
+
+		var roleARN string
+		if activeContext != nil {
+			roleARN = activeContext.RoleARN
+		}
 
-			// Apply preset log types if no custom log types are provided
+		presetName = ""
+		if len(presetNames) == 1 {
+			presetName = presetNames[0]
+		}
+
+		var argLogTypes []string
+		clusterName, argLogTypes, err = applyContext(activeContext, args)
+		if errors.Is(err, errNoCluster) && interactiveTerminal() && replayDir == "" {
+			// Without arguments on a terminal, offer the clusters of the region instead
+			if region == "" {
+				region = defaultRegion()
+			}
+			var pickedPreset string
+			clusterName, argLogTypes, pickedPreset, err = pickTarget(cmd.Context(), roleARN, newLogger(os.Stderr, slog.LevelWarn))
+			if presetName == "" && len(presetNames) == 0 {
+				presetName = pickedPreset
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if !usesContext(activeContext, clusterName) {
+			roleARN = ""
+		}
+		if region == "" {
+			region = contextRegion(activeContext, clusterName)
+		}
+		if len(argLogTypes) > 0 {
+			logTypes = argLogTypes
+		}
+		if activeContext != nil {
 			if len(logTypes) == 0 {
-				logTypes = preset.LogTypes
-				if verbose {
-					fmt.Printf("Using preset log types: %s\n", strings.Join(logTypes, ", "))
-				}
+				logTypes = activeContext.LogTypes
+			}
+			if presetName == "" && len(presetNames) == 0 {
+				presetName = activeContext.Preset
 			}
 		}
 
-		if region == "" {
-			cfg, err := config.LoadDefaultConfig(context.TODO())
-			if err == nil && cfg.Region != "" {
-				region = cfg.Region
-			} else {
-				region = "us-east-1"
+		var channels []aws.TailChannel
+		if len(presetNames) > 1 {
+			if channels, err = presetChannels(presetNames, cmd); err != nil {
+				return err
 			}
 		}
+		if err := applyPreset(presetName, cmd); err != nil {
+			return err
+		}
+		// The preset may have set a default limit
+		limitSpecified = cmd.Flags().Changed("limit")
+		var namespaceFilter *log.NamespaceFilter
+		if len(channels) > 0 && namespace != "" {
+			// The patterns of the presets are not combined with the namespace of audit events
+			namespaceFilter = log.NewNamespaceFilter(namespace)
+		} else {
+			namespaceFilter = applyNamespaceFilter(namespace)
+		}
+		if err := applyAuditFilter(auditFilter); err != nil {
+			return err
+		}
 
-		client, err := aws.NewEKSLogsClient(region, verbose)
+		tsSource, err := log.ParseTimestampSource(timestampSource)
 		if err != nil {
-			return fmt.Errorf("failed to create client: %w", err)
+			return err
 		}
 
-		ctx := context.Background()
+		format, err := log.ParseEntryOutputFormat(outputFormat)
+		if err != nil {
+			return err
+		}
+		if cloudWatchMetadata && !format.IsJSON() {
+			return fmt.Errorf("--cloudwatch-metadata requires --output json or json-array")
+		}
 
-		clusterInfo, err := client.GetClusterInfo(ctx, clusterName)
+		order, err := log.ParseSortOrder(sortOrder)
 		if err != nil {
-			return fmt.Errorf("failed to get cluster info: %w", err)
+			return err
+		}
+		if tailCount < 0 {
+			return fmt.Errorf("--tail must be a positive number")
+		}
+		if follow && (order == log.SortOrderDesc || tailCount > 0) {
+			return fmt.Errorf("--order desc and --tail cannot be used with --follow")
+		}
+		if splitParts < 0 || splitParts > aws.MaxSplitParts {
+			return fmt.Errorf("--split must be between 1 and %d", aws.MaxSplitParts)
+		}
+		if limitOldest && !limitSpecified {
+			return fmt.Errorf("--oldest requires --limit")
+		}
+		if splitParts > 1 && (follow || order == log.SortOrderDesc || tailCount > 0 || limitSpecified && !limitOldest || startTime == "") {
+			return fmt.Errorf("--split requires --start-time, and cannot be used with --follow, --tail, --limit without --oldest or --order desc")
+		}
+		if intervalMax > 0 && intervalMax < interval {
+			return fmt.Errorf("--interval-max must not be shorter than --interval")
 		}
 
-		messageOnly, err := cmd.Flags().GetBool("message-only")
+		var sampler *log.Sampler
+		if sampleSpec != "" {
+			sampler, err = log.ParseSampler(sampleSpec)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := validateStatsFormat(statsFormat); err != nil {
+			return err
+		}
+
+		level, err := parseLogLevel(logLevel, verbose, debug, quiet)
 		if err != nil {
 			return err
 		}
+		logger := newLogger(os.Stderr, level)
+
+		var maxBytes int64
+		if maxBytesSpec != "" {
+			maxBytes, err = parseByteSize(maxBytesSpec)
+			if err != nil {
+				return err
+			}
+		}
+
+		var lineTemplate *log.LineFormat
+		if lineFormat != "" {
+			lineTemplate, err = log.ParseLineFormat(lineFormat)
+			if err != nil {
+				return err
+			}
+		}
+
+		if rawOutput && format != log.OutputFormatText {
+			return fmt.Errorf("--raw prints the messages as text, and cannot be used with --output %s", format)
+		}
+
+		if len(messagePrefix) > 0 {
+			if messageOnly, _ := cmd.Flags().GetBool("message-only"); !messageOnly {
+				return fmt.Errorf("--prefix requires --message-only")
+			}
+			if err := log.ValidatePrefixFields(messagePrefix); err != nil {
+				return err
+			}
+		}
+
+		// Compile the jq expression up front so syntax errors fail before any AWS calls
+		var jqFilter *log.JQFilter
+		if jqExpression != "" {
+			jqFilter, err = log.NewJQFilter(jqExpression)
+			if err != nil {
+				return err
+			}
+			jqFilter.SetErrorHandler(func(err error) {
+				logger.Warn("jq expression failed, printing messages unchanged", "error", err)
+			})
+		}
 
 		// Set up color configuration
 		colorConfig := log.NewColorConfig()
-		switch colorMode {
-		case "auto":
-			colorConfig.Mode = log.ColorModeAuto
-		case "always":
-			colorConfig.Mode = log.ColorModeAlways
-		case "never":
-			colorConfig.Mode = log.ColorModeNever
-		default:
-			colorConfig.Mode = log.ColorModeAuto
+		colorConfig.Mode = parseColorMode(colorMode)
+		if themeName == "" {
+			themeName = appConfig.Theme
+		}
+		colorConfig.Theme, err = log.ParseTheme(themeName)
+		if err != nil {
+			return err
+		}
+		if err := applyHighlightConfig(colorConfig, appConfig.Highlight); err != nil {
+			return err
 		}
 
-		if verbose {
-			color.Cyan("=== EKS Control Plane Logs CLI ===")
-			color.Cyan("Cluster: %s", clusterName)
-			color.Cyan("Region: %s", region)
+		if region == "" {
+			region = defaultRegion()
+		}
+		if findRegion {
+			region, err = findClusterRegion(cmd.Context(), clusterName, roleARN, logger)
+			if err != nil {
+				return err
+			}
+		}
+
+		var client *aws.EKSLogsClient
+		if replayDir != "" {
+			// The recorded responses are served instead of calling AWS
+			client, err = aws.NewReplayClient(region, replayDir, logger)
+		} else {
+			client, err = aws.NewEKSLogsClient(region, endpointURL, logger)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		if roleARN != "" && replayDir == "" {
+			if err := client.SetAssumeRole(roleARN); err != nil {
+				return fmt.Errorf("failed to create client: %w", err)
+			}
+		}
+		if recordDir != "" {
+			if err := client.SetRecordDir(recordDir); err != nil {
+				return err
+			}
+		}
+
+		client.SetBudget(aws.Budget{MaxBytes: maxBytes, MaxAPICalls: maxAPICalls})
+		client.SetCallTimeout(apiTimeout)
+		if exitOnSilence && alertOnSilence <= 0 {
+			return fmt.Errorf("--exit-on-silence requires --alert-on-silence")
+		}
+		if spikeFactor < 0 || spikeFactor > 0 && spikeFactor <= 1 {
+			return fmt.Errorf("--spike-factor must be greater than 1")
+		}
+		if spikeFactor > 0 && !follow {
+			return fmt.Errorf("--spike-factor requires --follow")
+		}
+		if spikeWebhook != "" && spikeFactor == 0 {
+			return fmt.Errorf("--spike-webhook requires --spike-factor")
+		}
+		var bell *log.Bell
+		if bellMode != "" {
+			if !follow || len(filterPatterns) == 0 && len(channels) == 0 {
+				return fmt.Errorf("--bell requires --follow and a filter pattern (-F or a preset)")
+			}
+			if bell, err = log.NewBell(os.Stderr, bellMode, "ekslogs "+clusterName); err != nil {
+				return err
+			}
+		}
+		if offline && (follow || noCache) {
+			return fmt.Errorf("--offline cannot be combined with --follow or --no-cache")
+		}
+		followOptions := aws.FollowOptions{
+			MaxInterval:   intervalMax,
+			Heartbeat:     heartbeat,
+			SilenceAlert:  alertOnSilence,
+			ExitOnSilence: exitOnSilence,
+		}
+		if (heartbeat > 0 || alertOnSilence > 0) && !quiet {
+			followOptions.OnStatus = statusPrinter(os.Stderr)
+		}
+		client.SetFollowOptions(followOptions)
+		client.SetStreamCacheTTL(streamCacheTTL)
+		client.SetContainerInsights(containerInsights)
+		client.SetIncludeUnknownStreams(includeUnknownStreams)
+		if statsFormat != "" {
+			defer func() { printStats(os.Stderr, client.Stats(), statsFormat) }()
+		}
+
+		// Ctrl+C cancels the retrieval; what was retrieved is printed followed by a summary
+		ctx, stopSignals := interruptContext(cmd.Context())
+		defer stopSignals()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		// interrupted reports a retrieval stopped by Ctrl+C or --timeout; only the timeout is an error
+		interrupted := func(mode resumeMode) error {
+			if !quiet {
+				printInterruptSummary(os.Stderr, client.Progress(), mode)
+			}
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w after %s (--timeout)", errTimeout, timeout)
+			}
+			return nil
+		}
+
+		// Offline, or without the cluster check, the cluster is only known by the name given
+		// and its cached metadata
+		clusterInfo := &ekstypes.Cluster{Name: awssdk.String(clusterName)}
+		if skipClusterCheck {
+			verbosef("Skipping the cluster check: reading the log group of cluster '%s'", clusterName)
+		}
+		if !offline && !skipClusterCheck {
+			var resolvedName string
+			info, resolvedName, err := getCluster(ctx, client, clusterName)
+			switch {
+			case err == nil:
+				clusterInfo, clusterName = info, resolvedName
+			case ctx.Err() != nil:
+				return interrupted(resumeNone)
+			case aws.IsAccessDenied(err):
+				// Roles with CloudWatch Logs permissions only can still read the log group
+				logger.Warn("eks:DescribeCluster is denied: reading the log group of the cluster by the name given, as with --skip-cluster-check", "cluster", clusterName)
+			default:
+				return fmt.Errorf("failed to get cluster info: %w", err)
+			}
+		}
+		if !offline && recordDir == "" && replayDir == "" {
+			permissions, err := client.ProbePermissions(ctx, clusterName)
+			if err != nil {
+				if ctx.Err() != nil {
+					return interrupted(resumeNone)
+				}
+				return fmt.Errorf("cannot read the logs of cluster '%s': %w", clusterName, err)
+			}
+			warnDegraded(logger, permissions)
+		}
+
+		messageOnly, err := cmd.Flags().GetBool("message-only")
+		if err != nil {
+			return err
+		}
+
+		// The account tells apart clusters of the same name in terminals of several accounts
+		var identity aws.CallerIdentity
+		showHeader := verbose && !quiet && !offline
+		if !offline && replayDir == "" && (showHeader || format.IsJSON()) {
+			identity, err = client.GetCallerIdentity(ctx)
+			if err != nil {
+				logger.Warn("Could not resolve the AWS account", "error", err)
+			}
+		}
+
+		if showHeader {
+			banner := color.New(color.FgCyan)
+			_, _ = banner.Fprintln(os.Stderr, "=== EKS Control Plane Logs CLI ===")
+			_, _ = banner.Fprintf(os.Stderr, "Cluster: %s\n", clusterName)
+			_, _ = banner.Fprintf(os.Stderr, "Cluster ARN: %s\n", awssdk.ToString(clusterInfo.Arn))
+			_, _ = banner.Fprintf(os.Stderr, "Platform Version: %s\n", awssdk.ToString(clusterInfo.PlatformVersion))
+			_, _ = banner.Fprintf(os.Stderr, "Region: %s\n", region)
+			if identity.Account != "" {
+				_, _ = banner.Fprintf(os.Stderr, "Account: %s (%s)\n", identity.Account, identity.ARN)
+			}
 			if len(logTypes) > 0 {
-				color.Cyan("Log Types: %v", logTypes)
+				_, _ = banner.Fprintf(os.Stderr, "Log Types: %v\n", logTypes)
 			} else {
-				color.Cyan("Log Types: all")
+				_, _ = banner.Fprintln(os.Stderr, "Log Types: all")
 			}
-			color.Cyan("Cluster Status: %s", string(clusterInfo.Status))
-			color.Green("Cluster found")
+			_, _ = banner.Fprintf(os.Stderr, "Cluster Status: %s\n", string(clusterInfo.Status))
+			_, _ = color.New(color.FgGreen).Fprintln(os.Stderr, "Cluster found")
 		}
 
 		var fp *string
 		if len(filterPatterns) > 0 || len(ignoreFilterPatterns) > 0 {
-			combinedPattern := buildCombinedFilterPattern(filterPatterns, ignoreFilterPatterns, verbose)
+			combinedPattern := buildCombinedFilterPattern(filterPatterns, ignoreFilterPatterns, verbose && !quiet)
 			if combinedPattern != "" {
 				fp = &combinedPattern
 			}
 		}
 
-		printLogEntry := func(entry log.LogEntry) {
-			log.PrintLog(entry, messageOnly, colorConfig)
+		outputOptions := log.OutputOptions{
+			Format:          format,
+			MessageOnly:     messageOnly,
+			Raw:             rawOutput,
+			Prefix:          messagePrefix,
+			Pretty:          pretty,
+			Flatten:         flatten,
+			Fields:          fields,
+			JQ:              jqFilter,
+			TimestampSource: tsSource,
+			ShowLag:         showLag,
+			IngestionTime:   cloudWatchMetadata,
+			LineFormat:      lineTemplate,
+			Truncate:        truncateWidth,
+			Metadata: &log.Metadata{
+				Account:         identity.Account,
+				ClusterARN:      awssdk.ToString(clusterInfo.Arn),
+				PlatformVersion: awssdk.ToString(clusterInfo.PlatformVersion),
+			},
+		}
+		if wrapLines {
+			outputOptions.WrapWidth = log.TerminalWidth()
+		}
+		if redact || redactIdentities {
+			outputOptions.Redactor = log.NewRedactor(redactIdentities)
+		}
+		printer := log.NewPrinter(outputOptions, colorConfig)
+		// A json-array is closed on every exit, including errors and interruptions
+		defer printer.Close()
+		printFunc := printer.Print
+		if bell != nil {
+			// The terminal is alerted of the matches actually printed
+			printFunc = bell.Wrap(printFunc)
+		}
+		if sampler != nil {
+			printFunc = sampler.Wrap(printFunc)
+		}
+		if spikeFactor > 0 {
+			// Spikes are measured on all matching events, before sampling
+			var warnings io.Writer
+			if !quiet {
+				warnings = os.Stderr
+			}
+			detector := log.NewSpikeDetector(log.SpikeOptions{Factor: spikeFactor, Window: spikeWindow},
+				spikeNotifier(warnings, spikeWebhook, clusterName, logger))
+			printFunc = detector.Wrap(printFunc)
+		}
+		if namespaceFilter != nil {
+			printFunc = namespaceFilter.Wrap(printFunc)
+		}
+		// streamFunc receives the entries as they are retrieved; buffered entries are joined
+		// with log.JoinMultiline instead
+		streamFunc := printFunc
+		flushJoined := func() {}
+		if joinMultiline {
+			joiner := log.NewMultilineJoiner(printFunc)
+			streamFunc, flushJoined = joiner.Print, joiner.Flush
+			if follow {
+				// Continuation lines arrive in the same poll as the line they follow
+				go flushIdleEntries(ctx, joiner, interval)
+			}
 		}
 
 		if follow {
-			ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
-			defer cancel()
-
-			err := client.TailLogs(ctx, clusterName, logTypes, fp, interval, messageOnly, colorConfig)
+			startQueryRun(printer, nil, nil, true)
+			var err error
+			if len(channels) > 0 {
+				err = client.TailChannels(ctx, clusterName, channels, interval, streamFunc)
+			} else {
+				err = client.TailLogs(ctx, clusterName, logTypes, fp, interval, streamFunc)
+			}
+			flushJoined()
 			// If context was cancelled (Ctrl+C), treat it as a normal exit
-			if err != nil && ctx.Err() == context.Canceled {
-				return nil
+			if ctx.Err() != nil {
+				return interrupted(resumeFollow)
 			}
 			return err
 		}
 
-		var startT, endT *time.Time
+		startT, endT, err := parseTimeRange(startTime, endTime)
+		if err != nil {
+			return err
+		}
+		startQueryRun(printer, startT, endT, false)
 
-		if startTime != "" {
-			t, err := log.ParseTimeString(startTime)
+		// Apply limit only if explicitly specified by the user
+		var effectiveLimit int32
+		if limitSpecified {
+			effectiveLimit = limit
+		} else {
+			effectiveLimit = 0 // 0 means unlimited
+		}
+		recentCount, effectiveLimit := eventSelection(tailCount, effectiveLimit, limitOldest)
+		// Sorting or selecting the most recent events requires buffering them
+		buffered := recentCount > 0 || order == log.SortOrderDesc
+
+		// A cached result would leave nothing to record or replay
+		results := resultCache(noCache || recordDir != "" || replayDir != "", offline, cacheTTL)
+		var cacheKey string
+		if results != nil {
+			cacheKey = logsCacheKey(region, clusterName, logTypes, startTime, endTime, awssdk.ToString(fp), effectiveLimit, recentCount, containerInsights, includeUnknownStreams)
+			var cached cachedLogs
+			storedAt, ok, err := results.Get(cacheKey, &cached)
 			if err != nil {
-				return fmt.Errorf("failed to parse start time: %w", err)
+				logger.Warn("Ignoring the cached result", "error", err)
+			}
+			if ok {
+				if !quiet {
+					printCacheNotice(os.Stderr, storedAt)
+				}
+				*outputOptions.Metadata = cached.Metadata
+				entries := cached.logEntries()
+				if joinMultiline {
+					entries = log.JoinMultiline(entries)
+				}
+				if buffered {
+					log.SortEntries(entries, order, tsSource)
+				}
+				for _, entry := range entries {
+					printFunc(entry)
+				}
+				return checkEmpty(cmd, printer)
+			}
+			if offline {
+				return errNotCached
+			}
+		}
+		// storeResult caches the entries of a complete retrieval
+		storeResult := func(entries []log.LogEntry) {
+			if results == nil {
+				return
+			}
+			if err := results.Put(cacheKey, newCachedLogs(*outputOptions.Metadata, entries)); err != nil {
+				logger.Warn("Could not cache the result", "error", err)
 			}
-			startT = t
 		}
 
-		if endTime != "" {
-			t, err := log.ParseTimeString(endTime)
-			if err != nil {
-				return fmt.Errorf("failed to parse end time: %w", err)
+		// Warn before scanning a range likely to hold a large volume of logs
+		if !assumeYes && startT != nil {
+			rangeEnd := time.Now()
+			if endT != nil {
+				rangeEnd = *endT
+			}
+			estimate, err := client.EstimateRangeBytes(ctx, clusterName, *startT, rangeEnd)
+			if err == nil {
+				if err := checkPreflightEstimate(estimate, maxBytes); err != nil {
+					return err
+				}
+			} else {
+				logger.Info("Could not estimate the log volume", "error", err)
 			}
-			endT = t
 		}
 
-		if startT == nil && endT == nil {
-			now := time.Now()
-			oneHourAgo := now.Add(-1 * time.Hour)
-			startT = &oneHourAgo
-			endT = &now
+		stopProgress := func() {}
+		if progressEnabled() {
+			stopProgress = startProgress(os.Stderr, client)
+			defer stopProgress()
 		}
 
-		// Apply limit only if explicitly specified by the user
-		var effectiveLimit int32
-		if limitSpecified {
-			effectiveLimit = limit
-		} else {
-			effectiveLimit = 0 // 0 means unlimited
+		if buffered {
+			var entries []log.LogEntry
+			if recentCount > 0 {
+				entries, err = client.GetRecentLogs(ctx, clusterName, logTypes, startT, endT, fp, recentCount)
+			} else {
+				entries, err = client.CollectLogs(ctx, clusterName, logTypes, startT, endT, fp, effectiveLimit)
+			}
+			if err != nil && ctx.Err() == nil {
+				return err
+			}
+			if ctx.Err() == nil {
+				storeResult(entries)
+			}
+
+			// Entries collected before an interruption are still printed
+			if joinMultiline {
+				entries = log.JoinMultiline(entries)
+			}
+			log.SortEntries(entries, order, tsSource)
+			for _, entry := range entries {
+				printFunc(entry)
+			}
+			if ctx.Err() != nil {
+				stopProgress()
+				if recentCount > 0 {
+					return interrupted(resumeNone)
+				}
+				return interrupted(resumeRange)
+			}
+			return checkEmpty(cmd, printer)
 		}
 
-		err = client.GetLogs(ctx, clusterName, logTypes, startT, endT, fp, effectiveLimit, printLogEntry)
+		var recorder entryRecorder
+		fetchFunc := streamFunc
+		if results != nil {
+			// The cache holds the entries as retrieved, before they are joined
+			fetchFunc = recorder.wrap(streamFunc)
+		}
+		if splitParts > 1 {
+			rangeEnd := time.Now()
+			if endT != nil {
+				rangeEnd = *endT
+			}
+			err = client.GetLogsSplit(ctx, clusterName, logTypes, *startT, rangeEnd, fp, effectiveLimit, splitParts, fetchFunc)
+		} else {
+			err = client.GetLogs(ctx, clusterName, logTypes, startT, endT, fp, effectiveLimit, fetchFunc)
+		}
+		flushJoined()
+		if ctx.Err() != nil {
+			stopProgress()
+			return interrupted(resumeRange)
+		}
 		if err != nil {
 			return err
 		}
+		if !recorder.overflow {
+			storeResult(recorder.entries)
+		} else {
+			logger.Info("Not caching the result", "reason", fmt.Sprintf("more than %d events", maxCachedEntries))
+		}
 
-		return nil
+		return checkEmpty(cmd, printer)
 	},
 }
 
@@ -227,54 +691,104 @@ var versionCmd = &cobra.Command{
 	},
 }
 
-var logTypesCmd = &cobra.Command{
-	Use:   "logtypes",
-	Short: "Show detailed information about available log types",
-	Long: `Show detailed information about available log types for EKS Control Plane logs.
-
-Each log type corresponds to a specific component of the EKS Control Plane.
-You can specify one or more log types when retrieving logs to focus on specific components.
-
-Examples:
-  ekslogs my-cluster api audit     # Get logs from API server and audit logs
-  ekslogs my-cluster auth          # Get authentication logs
-  ekslogs my-cluster scheduler     # Get scheduler logs`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Available log types for EKS Control Plane logs:")
-		fmt.Println()
-		fmt.Println("  api           - API Server logs (kube-apiserver)")
-		fmt.Println("  audit         - Audit logs (kube-apiserver-audit)")
-		fmt.Println("  authenticator - Authentication logs (aws-iam-authenticator)")
-		fmt.Println("                  Alias: auth")
-		fmt.Println("  kcm           - Kube Controller Manager logs (kube-controller-manager)")
-		fmt.Println("                  Aliases: controller, kube-controller-manager")
-		fmt.Println("  ccm           - Cloud Controller Manager logs (cloud-controller-manager)")
-		fmt.Println("                  Aliases: cloud, cloud-controller-manager")
-		fmt.Println("  scheduler     - Scheduler logs (kube-scheduler)")
-		fmt.Println("                  Alias: sched")
-		fmt.Println()
-		fmt.Println("Note: Not all log types may be available for every cluster.")
-		fmt.Println("Control plane logging must be enabled in the EKS console for logs to be available.")
-		fmt.Println("If no log types are specified, all available log types will be retrieved.")
-	},
+// warnDegraded tells how the logs are read with the API calls denied to the credentials
+func warnDegraded(logger *slog.Logger, permissions aws.Permissions) {
+	if permissions.DescribeLogStreamsDenied {
+		logger.Warn("logs:DescribeLogStreams is denied: searching whole log groups, and selecting the log types from the stream names of the events")
+	}
+	if permissions.FilterLogEventsDenied {
+		logger.Warn("logs:FilterLogEvents is denied: reading the log streams one after the other with logs:GetLogEvents; events are in order within each stream only, and filter patterns cannot be applied")
+	}
 }
 
 func init() {
 	rootCmd.AddCommand(versionCmd)
-	rootCmd.AddCommand(logTypesCmd)
+
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default $EKSLOGS_CONFIG or ~/.config/ekslogs/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&endpointURL, "endpoint-url", "", "Send AWS API calls to this endpoint, e.g. http://localhost:4566 for LocalStack (default $AWS_ENDPOINT_URL or the regional endpoint)")
 
 	rootCmd.Flags().StringVarP(&region, "region", "r", "", "AWS region")
+	rootCmd.Flags().BoolVar(&findRegion, "find-region", false, "Search all enabled regions for the cluster instead of using --region")
+	rootCmd.MarkFlagsMutuallyExclusive("region", "find-region")
+	rootCmd.Flags().BoolVar(&skipClusterCheck, "skip-cluster-check", false, "Read the log group of the cluster without calling eks:DescribeCluster, for roles with CloudWatch Logs permissions only")
+	rootCmd.MarkFlagsMutuallyExclusive("find-region", "skip-cluster-check")
 	rootCmd.Flags().StringVarP(&startTime, "start-time", "s", "", "Start time (RFC3339 format or relative: -1h, -15m, -30s, -2d)")
 	rootCmd.Flags().StringVarP(&endTime, "end-time", "e", "", "End time (RFC3339 format or relative: -1h, -15m, -30s, -2d)")
 	rootCmd.Flags().StringArrayVarP(&filterPatterns, "filter-pattern", "F", []string{}, "Log filter pattern (can be specified multiple times for AND condition)")
 	rootCmd.Flags().StringArrayVarP(&ignoreFilterPatterns, "ignore-filter-pattern", "I", []string{}, "Log ignore filter pattern (can be specified multiple times for OR condition)")
-	rootCmd.Flags().StringVarP(&presetName, "preset", "p", "", "Use filter preset (run 'ekslogs presets' to list available presets)")
-	rootCmd.Flags().Int32VarP(&limit, "limit", "l", 1000, "Maximum number of logs to retrieve")
+	rootCmd.Flags().BoolVar(&containerInsights, "container-insights", false, "Also read node and pod logs from the Container Insights log groups (kubelet, containerd, host, application)")
+	rootCmd.Flags().BoolVar(&includeUnknownStreams, "include-unknown-streams", false, "With log types, also read the streams of control plane components unknown to ekslogs")
+	rootCmd.Flags().StringArrayVarP(&presetNames, "preset", "p", nil, "Use filter preset (run 'ekslogs presets' to list available presets); with --follow, can be specified multiple times to follow each, labeling lines with the preset")
+	rootCmd.Flags().BoolVar(&auditFilter.WritesOnly, "writes-only", false, "Show only the audit events of requests other than get, list and watch")
+	rootCmd.Flags().BoolVar(&auditFilter.ReadsOnly, "reads-only", false, "Show only the audit events of get, list and watch requests")
+	rootCmd.MarkFlagsMutuallyExclusive("writes-only", "reads-only")
+	rootCmd.Flags().BoolVar(&auditFilter.NonSystem, "non-system", false, "Hide the audit events of system: users (controllers, nodes, service accounts)")
+	rootCmd.Flags().StringVar(&namespace, "namespace", "", "Show only the entries of a namespace (server-side for audit-only queries, client-side otherwise)")
+	rootCmd.Flags().Int32VarP(&limit, "limit", "l", 1000, "Show only the N most recent events of the time range (the N oldest with --oldest)")
+	rootCmd.Flags().BoolVar(&limitOldest, "oldest", false, "With --limit, keep the N oldest events of the time range, reading forward from its start")
 	rootCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	rootCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Do not show the progress indicator on stderr while retrieving historical logs")
+	rootCmd.Flags().BoolVar(&failOnEmpty, "fail-on-empty", false, "Exit with status 2 when no log event matched")
+	rootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print nothing but log events (errors are still reported on stderr)")
+	rootCmd.Flags().BoolVar(&debug, "debug", false, "Write the timing and request ID of each AWS API call to stderr (same as --log-level debug)")
+	rootCmd.Flags().StringVar(&logLevel, "log-level", "", "Level of diagnostics written to stderr: debug, info, warn, error (default warn, info with --verbose)")
+	rootCmd.Flags().StringVar(&sortOrder, "order", "asc", "Print order: asc (oldest first), desc (newest first)")
+	rootCmd.Flags().IntVar(&splitParts, "split", 0, "Divide the time range into N sub-ranges retrieved in parallel and printed in order, for faster dumps of long ranges (at most 32)")
+	rootCmd.Flags().IntVar(&tailCount, "tail", 0, "Show only the N most recent events of the time range")
+	rootCmd.MarkFlagsMutuallyExclusive("tail", "limit")
+	rootCmd.Flags().StringVar(&sampleSpec, "sample", "", "Keep only a sample of matching events: 1/N (every Nth), or a fraction such as 0.02 or 2%")
+	rootCmd.Flags().StringVar(&maxBytesSpec, "max-bytes", "", "Stop after retrieving this much log data (e.g. 500MB, 2GiB)")
+	rootCmd.Flags().Int64Var(&maxAPICalls, "max-api-calls", 0, "Stop after this many AWS API calls (0 means unlimited)")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Skip the confirmation required for time ranges estimated to hold large volumes of logs")
+	rootCmd.Flags().DurationVar(&timeout, "timeout", 0, "Stop the retrieval after this duration, e.g. 5m (0 means no timeout)")
+	rootCmd.Flags().DurationVar(&apiTimeout, "api-timeout", 2*time.Minute, "Fail an AWS API call that does not complete within this duration, including retries (0 disables)")
+	rootCmd.Flags().StringVar(&statsFormat, "stats", "", "Print retrieval statistics to stderr after the run: text, json")
+	rootCmd.Flags().Lookup("stats").NoOptDefVal = "text"
 	rootCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Continuously monitor logs (tail mode)")
 	rootCmd.Flags().DurationVar(&interval, "interval", 1*time.Second, "Update interval for tail mode")
+	rootCmd.Flags().DurationVar(&heartbeat, "heartbeat", 0, "In tail mode, report on stderr when no new events arrived for this long, and connection problems and recovery (e.g. 5m)")
+	rootCmd.Flags().DurationVar(&alertOnSilence, "alert-on-silence", 0, "In tail mode, warn on stderr when a log type produced no matching events for this long (e.g. 10m)")
+	rootCmd.Flags().Float64Var(&spikeFactor, "spike-factor", 0, "In tail mode, warn on stderr when the rate of matching events exceeds this many times its recent average (e.g. 5, 0 disables)")
+	rootCmd.Flags().DurationVar(&spikeWindow, "spike-window", log.DefaultSpikeWindow, "Window the rate of matching events is measured over for --spike-factor; the baseline averages the previous 10 windows")
+	rootCmd.Flags().StringVar(&bellMode, "bell", "", "In tail mode, alert the terminal when an event matching -F arrives, at most once a second: bell (terminal bell), osc (desktop notification)")
+	rootCmd.Flags().Lookup("bell").NoOptDefVal = log.BellModeBell
+	rootCmd.Flags().StringVar(&spikeWebhook, "spike-webhook", "", "Also POST each spike as JSON to this URL (Slack and Teams incoming webhooks accepted)")
+	rootCmd.Flags().BoolVar(&exitOnSilence, "exit-on-silence", false, "Exit with status 6 instead of warning when --alert-on-silence triggers")
+	rootCmd.Flags().DurationVar(&streamCacheTTL, "stream-cache-ttl", aws.DefaultStreamCacheTTL, "How long tail mode reuses the list of log streams before listing them again (0 lists them on every update)")
+	rootCmd.Flags().DurationVar(&intervalMax, "interval-max", 0, "Longest update interval tail mode backs off to while no new events arrive (default: fixed --interval)")
+	rootCmd.Flags().BoolVar(&noCache, "no-cache", false, "Fetch the logs even when the same retrieval is cached, and do not cache the result")
+	rootCmd.Flags().BoolVar(&offline, "offline", false, "Print the cached result of the same retrieval, of any age, without calling AWS")
+	rootCmd.Flags().DurationVar(&cacheTTL, "cache-ttl", defaultCacheTTL, "How long the result of a retrieval is reused by later runs with the same cluster, time range and pattern (0 disables the cache)")
+	rootCmd.Flags().StringVar(&recordDir, "record", "", "Save every EKS and CloudWatch Logs API call and its raw response to this directory, to attach to bug reports")
+	rootCmd.Flags().StringVar(&replayDir, "replay", "", "Serve the API responses saved by --record in this directory instead of calling AWS")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay", "offline")
+	rootCmd.MarkFlagsMutuallyExclusive("replay", "follow")
+	rootCmd.MarkFlagsMutuallyExclusive("replay", "find-region")
 	rootCmd.Flags().BoolP("message-only", "m", false, "Output only the log message")
-	rootCmd.Flags().StringVar(&colorMode, "color", "auto", "Color output mode: auto, always, never")
+	rootCmd.Flags().BoolVar(&joinMultiline, "join-multiline", false, "Join continuation lines (stack traces, wrapped klog messages) into the entry they belong to")
+	rootCmd.Flags().StringSliceVar(&messagePrefix, "prefix", nil, "With --message-only, write these fields tab-separated before the message: type, stream, cluster (e.g. --prefix type,stream)")
+	rootCmd.Flags().StringVar(&colorMode, "color", "auto", "Color output mode: auto, always, never (auto honors EKSLOGS_COLOR, NO_COLOR and CLICOLOR_FORCE)")
+	rootCmd.Flags().StringVar(&themeName, "theme", "", "Color theme: dark, light, monochrome-bold, solarized (default dark, or the config file theme)")
+	rootCmd.Flags().BoolVar(&pretty, "pretty", false, "Pretty-print JSON messages (audit and structured logs) with indentation")
+	rootCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", "Output format: text, json (one JSON object per line), json-array (a single JSON array)")
+	rootCmd.Flags().StringVar(&lineFormat, "format", "", "Line template for text output, e.g. '{time} {type} {level} {msg}' (fields: time, type, level, component, msg, log_group, log_stream, lag)")
+	rootCmd.Flags().IntVar(&truncateWidth, "truncate", 0, "Truncate text lines to N characters (ANSI-aware, 0 disables)")
+	rootCmd.Flags().BoolVar(&wrapLines, "wrap", false, "Wrap long text lines to the terminal width with a hanging indent")
+	rootCmd.MarkFlagsMutuallyExclusive("truncate", "wrap")
+	rootCmd.Flags().BoolVar(&showLag, "show-lag", false, "Show the delay between the logged time and CloudWatch ingestion")
+	rootCmd.Flags().BoolVar(&cloudWatchMetadata, "cloudwatch-metadata", false, "In JSON output, include the CloudWatch ingestion time (ingestion_time) along with the event ID (event_id), for deduplication and lag analysis")
+	rootCmd.Flags().StringVar(&timestampSource, "timestamp-source", "event", "Timestamp to display: event (CloudWatch event time), ingestion, message (embedded klog/audit timestamp)")
+	rootCmd.Flags().StringVar(&jqExpression, "jq", "", "jq expression applied to JSON messages before printing (e.g. '{verb, user: .user.username}')")
+	rootCmd.Flags().BoolVar(&redact, "redact", false, "Mask bearer tokens, Authorization headers, authenticator tokens and Secret data so output can be shared")
+	rootCmd.Flags().BoolVar(&redactIdentities, "redact-identities", false, "Like --redact, and also replace source IPs, usernames, user IDs and IAM identities with hashes that are stable within the run")
+	rootCmd.Flags().BoolVar(&flatten, "flatten", false, "Render JSON messages as key.path=value pairs on one line (logfmt style)")
+	rootCmd.Flags().StringSliceVar(&fields, "fields", nil, "Comma-separated JSON fields to extract from JSON messages (e.g. verb,user.username,responseStatus.code)")
+	rootCmd.MarkFlagsMutuallyExclusive("flatten", "pretty")
+	rootCmd.MarkFlagsMutuallyExclusive("flatten", "fields")
+	rootCmd.Flags().BoolVar(&rawOutput, "raw", false, "Print the messages exactly as stored in CloudWatch Logs, one per line, without decoration, color or any rewriting")
+	for _, rewriting := range []string{"message-only", "prefix", "pretty", "flatten", "fields", "jq", "format", "truncate", "wrap", "redact", "redact-identities", "join-multiline", "show-lag"} {
+		rootCmd.MarkFlagsMutuallyExclusive("raw", rewriting)
+	}
 
 	// Add PreRun to check if flags were explicitly specified
 	rootCmd.PreRun = func(cmd *cobra.Command, args []string) {
@@ -283,21 +797,85 @@ func init() {
 }
 
 func executeRoot() {
-	// Set up a channel to receive OS signals
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	// Start a goroutine to handle signals
-	go func() {
-		<-c
-		// Just exit silently with status 0 when Ctrl+C is pressed
-		os.Exit(0)
-	}()
-
-	if err := rootCmd.Execute(); err != nil {
-		color.Red("Error: %v", err)
-		os.Exit(1)
+	err := rootCmd.Execute()
+	recordQueryRun(err)
+	if err != nil {
+		// A query run again by rerun printed its own error
+		var queryExit *exec.ExitError
+		if !errors.Is(err, errNoEvents) && !errors.As(err, &queryExit) {
+			_, _ = color.New(color.FgRed).Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if aws.IsSSOSessionExpired(err) {
+			_, _ = fmt.Fprintf(os.Stderr, "Your AWS SSO session has expired. Log in again with:\n  %s\n", aws.SSOLoginCommand())
+		}
+		if aws.IsMFARequired(err) {
+			_, _ = fmt.Fprintln(os.Stderr, "Your AWS profile requires an MFA token code. Run ekslogs in a terminal to be prompted for it.")
+		}
+		os.Exit(exitCode(err))
+	}
+	notifyUpdate(context.Background())
+}
+
+// parseColorMode converts the --color value, falling back to auto
+func parseColorMode(mode string) log.ColorMode {
+	switch mode {
+	case "always":
+		return log.ColorModeAlways
+	case "never":
+		return log.ColorModeNever
+	default:
+		return log.ColorModeAuto
+	}
+}
+
+// defaultRegion returns the region of the AWS configuration, or us-east-1 if none is configured
+func defaultRegion() string {
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err == nil && cfg.Region != "" {
+		return cfg.Region
+	}
+	return "us-east-1"
+}
+
+// parseTimeRange parses the --start-time and --end-time values. Without either, the range
+// is the past hour.
+func parseTimeRange(start, end string) (*time.Time, *time.Time, error) {
+	var startT, endT *time.Time
+
+	if start != "" {
+		t, err := log.ParseTimeString(start)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse start time: %w", err)
+		}
+		startT = t
+	}
+
+	if end != "" {
+		t, err := log.ParseTimeString(end)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse end time: %w", err)
+		}
+		endT = t
+	}
+
+	if startT == nil && endT == nil {
+		now := time.Now()
+		oneHourAgo := now.Add(-1 * time.Hour)
+		startT = &oneHourAgo
+		endT = &now
+	}
+
+	return startT, endT, nil
+}
+
+// eventSelection returns the number of most recent events of the time range to keep, and the
+// limit of the events read forward from its start: --limit keeps the most recent events, like
+// --tail, unless --oldest
+func eventSelection(tail int, limit int32, oldest bool) (int, int32) {
+	if limit > 0 && !oldest {
+		return int(limit), 0
 	}
+	return tail, limit
 }
 
 // buildCombinedFilterPattern builds a combined CloudWatch Logs filter pattern
@@ -310,7 +888,7 @@ func buildCombinedFilterPattern(includePatterns, ignorePatterns []string, verbos
 		var includeParts []string
 		for _, pattern := range includePatterns {
 			if verbose {
-				fmt.Printf("Processing include pattern: '%s'\n", pattern)
+				fmt.Fprintf(os.Stderr, "Processing include pattern: '%s'\n", pattern)
 			}
 			processedPattern := processFilterPattern(pattern, false, verbose)
 			includeParts = append(includeParts, processedPattern)
@@ -325,7 +903,7 @@ func buildCombinedFilterPattern(includePatterns, ignorePatterns []string, verbos
 		}
 
 		if verbose {
-			fmt.Printf("Combined include patterns (AND): %s\n", parts[len(parts)-1])
+			fmt.Fprintf(os.Stderr, "Combined include patterns (AND): %s\n", parts[len(parts)-1])
 		}
 	}
 
@@ -333,20 +911,20 @@ func buildCombinedFilterPattern(includePatterns, ignorePatterns []string, verbos
 	if len(ignorePatterns) > 0 {
 		for _, pattern := range ignorePatterns {
 			if verbose {
-				fmt.Printf("Processing ignore pattern: '%s'\n", pattern)
+				fmt.Fprintf(os.Stderr, "Processing ignore pattern: '%s'\n", pattern)
 			}
 			processedPattern := processFilterPattern(pattern, true, verbose)
 			parts = append(parts, processedPattern)
 		}
 
 		if verbose {
-			fmt.Printf("Added %d ignore patterns (OR condition)\n", len(ignorePatterns))
+			fmt.Fprintf(os.Stderr, "Added %d ignore patterns (OR condition)\n", len(ignorePatterns))
 		}
 	}
 
 	combinedPattern := strings.Join(parts, " ")
 	if verbose && combinedPattern != "" {
-		fmt.Printf("Final combined filter pattern: %s\n", combinedPattern)
+		fmt.Fprintf(os.Stderr, "Final combined filter pattern: %s\n", combinedPattern)
 	}
 
 	return combinedPattern
@@ -372,12 +950,12 @@ func processFilterPattern(pattern string, isIgnore bool, verbose bool) string {
 	if needsQuoting {
 		result = fmt.Sprintf("\"%s\"", pattern)
 		if verbose {
-			fmt.Printf("  Quoted pattern: %s\n", result)
+			fmt.Fprintf(os.Stderr, "  Quoted pattern: %s\n", result)
 		}
 	} else {
 		result = pattern
 		if verbose {
-			fmt.Printf("  Using original pattern: %s\n", result)
+			fmt.Fprintf(os.Stderr, "  Using original pattern: %s\n", result)
 		}
 	}
 
@@ -385,7 +963,7 @@ func processFilterPattern(pattern string, isIgnore bool, verbose bool) string {
 	if isIgnore {
 		result = "-" + result
 		if verbose {
-			fmt.Printf("  Added ignore prefix: %s\n", result)
+			fmt.Fprintf(os.Stderr, "  Added ignore prefix: %s\n", result)
 		}
 	}
 
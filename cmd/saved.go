@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kzcat/ekslogs/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	saveDescription string
+	saveFromHistory int
+)
+
+var saveCmd = &cobra.Command{
+	Use:   "save <name> [-- query arguments]",
+	Short: "Save a log query under a name in the config file",
+	Long: `Save the arguments of a log query, with its cluster, log types, filters and output flags,
+under a name in the queries section of the config file. Unlike a preset, a saved query is a
+whole invocation, to be run again with 'ekslogs run <name>' for recurring reports.
+
+The arguments follow --, or are taken from a query of the history with --from-history.
+Relative time ranges such as -s -24h are kept, so each run reads the latest events.
+Saving a query with the name of an existing one replaces it.`,
+	Example: `  ekslogs save prod-denied -- prod-cluster audit -p audit-denied -s -24h -o json
+  ekslogs save auth-triage --from-history 42 --description "Authenticator failures"
+  ekslogs run prod-denied`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.ArgsLenAtDash() == 0 || cmd.ArgsLenAtDash() > 1 || (cmd.ArgsLenAtDash() < 0 && len(args) != 1) {
+			return errors.New("requires a query name, followed by the query arguments after --")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		var queryArgs []string
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			queryArgs = args[dash:]
+		}
+
+		if saveFromHistory > 0 {
+			if len(queryArgs) > 0 {
+				return errors.New("--from-history cannot be used with query arguments")
+			}
+			entries, err := readHistory(historyPath())
+			if err != nil {
+				return err
+			}
+			entry, ok := findHistory(entries, saveFromHistory)
+			if !ok {
+				return fmt.Errorf("query %d not found in the history. Run 'ekslogs history' to list the queries", saveFromHistory)
+			}
+			queryArgs = entry.Args
+		}
+		if len(queryArgs) == 0 {
+			return errors.New("no query to save: give its arguments after --, or use --from-history")
+		}
+
+		appConfig, err := config.Load(configPath)
+		if err != nil {
+			return err
+		}
+		_, replaced := appConfig.Queries[name]
+		if err := config.SaveQuery(configPath, name, config.SavedQuery{Description: saveDescription, Args: queryArgs}); err != nil {
+			return err
+		}
+		action := "Saved"
+		if replaced {
+			action = "Replaced"
+		}
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s query \"%s\": ekslogs %s\n", action, name, shellJoin(queryArgs))
+		return nil
+	},
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [name] [-- extra arguments]",
+	Short: "Run a saved log query, or list the saved queries",
+	Long: `Run a query saved with 'ekslogs save', or defined in the queries section of the config
+file. Arguments after -- are added to the saved ones, e.g. to change the time range or the
+output format for one run. Without a name, the saved queries are listed.
+
+  queries:
+    prod-denied:
+      description: Denied requests of the past day
+      args: [prod-cluster, audit, -p, audit-denied, -s, -24h, -o, json]`,
+	Example: `  ekslogs run                        # List the saved queries
+  ekslogs run prod-denied            # Run a saved query
+  ekslogs run prod-denied -- -s -7d  # Run it over the past week`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.ArgsLenAtDash() == 0 || cmd.ArgsLenAtDash() > 1 || (cmd.ArgsLenAtDash() < 0 && len(args) > 1) {
+			return errors.New("accepts a query name, followed by extra query arguments after --")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			printNamedQueries(cmd.OutOrStdout(), appConfig.Queries)
+			return nil
+		}
+
+		query, ok := appConfig.Queries[args[0]]
+		if !ok {
+			return fmt.Errorf("saved query '%s' not found. Run 'ekslogs run' to list the saved queries", args[0])
+		}
+		if len(query.Args) == 0 {
+			return fmt.Errorf("saved query '%s' has no arguments", args[0])
+		}
+		return runQuery(cmd, savedQueryArgs(query, configPath, args[1:]))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(saveCmd)
+	rootCmd.AddCommand(runCmd)
+
+	saveCmd.Flags().StringVar(&saveDescription, "description", "", "Description of the query, listed by 'ekslogs run'")
+	saveCmd.Flags().IntVar(&saveFromHistory, "from-history", 0, "Save the query with this ID of the history (see 'ekslogs history')")
+}
+
+// savedQueryArgs returns the arguments running a saved query: the config file it was read
+// from, if not the default one, its arguments and the extra arguments of the run
+func savedQueryArgs(query config.SavedQuery, configFile string, extra []string) []string {
+	var args []string
+	if configFile != "" {
+		args = append(args, "--config", configFile)
+	}
+	args = append(args, query.Args...)
+	return append(args, extra...)
+}
+
+// printNamedQueries writes a table of the queries saved in the config file, sorted by name
+func printNamedQueries(w io.Writer, queries map[string]config.SavedQuery) {
+	if len(queries) == 0 {
+		_, _ = fmt.Fprintln(w, "No saved queries. Save one with 'ekslogs save <name> -- <query arguments>'.")
+		return
+	}
+
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "NAME\tDESCRIPTION\tCOMMAND")
+	for _, name := range names {
+		query := queries[name]
+		_, _ = fmt.Fprintf(table, "%s\t%s\tekslogs %s\n", name, orDash(strings.TrimSpace(query.Description)), shellJoin(query.Args))
+	}
+	_ = table.Flush()
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scalingOptions           reportOptions
+	scalingName              string
+	scalingNamespace         string
+	scalingContainerInsights bool
+)
+
+var scalingCmd = &cobra.Command{
+	Use:   "scaling [cluster-name]",
+	Short: "Trace the HPA decisions and evictions that changed the pods of a workload",
+	Long: `Reconstruct why the replicas of a workload changed or its pods were evicted, from the
+controller manager and audit logs:
+
+  hpa rescale       the HorizontalPodAutoscaler decision, with its replica counts and reason
+  hpa failed        the HPA could not compute the replicas, e.g. missing metrics
+  scaled, updated   scale subresource and spec changes of the workload, with their user
+  evicted           API-initiated evictions of its pods (kubectl drain, Karpenter)
+  eviction blocked  evictions refused by a PodDisruptionBudget
+  taint eviction    pods deleted by the taint eviction controller (NoExecute taints)
+
+With --container-insights, the node-pressure evictions of the kubelet are read from the
+Container Insights dataplane logs too. The HPA is expected to be named like the workload.`,
+	Example: `  ekslogs scaling my-cluster --name web -n shop -s -6h
+  ekslogs scaling my-cluster --name web -n shop --container-insights -o json`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workload := log.Workload{Namespace: scalingNamespace, Name: scalingName}
+		if workload.Namespace == "" {
+			workload.Namespace = "default"
+		}
+		r, err := setupReport(cmd, args, &scalingOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		logTypes := []string{"kcm", "audit"}
+		if scalingContainerInsights {
+			r.client.SetContainerInsights(true)
+			logTypes = append(logTypes, "kubelet")
+		}
+		entries, err := r.collect(logTypes, r.start, r.end, workload.FilterPattern(), 0)
+		if err != nil {
+			return err
+		}
+
+		events := log.TraceScaling(entries, workload)
+		r.verbosef("Found %d scaling decisions and evictions in %d log events", len(events), len(entries))
+		if r.format == log.OutputFormatJSON {
+			if err := printScalingJSON(os.Stdout, events); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printScalingReport(r.output(), workload, events)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(scalingCmd)
+
+	scalingOptions.documents = true
+	scalingOptions.addFlags(scalingCmd, "one JSON object per decision")
+	scalingCmd.Flags().StringVar(&scalingName, "name", "", "Name of the Deployment or StatefulSet (and of its HPA)")
+	scalingCmd.Flags().StringVarP(&scalingNamespace, "namespace", "n", "default", "Namespace of the workload")
+	scalingCmd.Flags().BoolVar(&scalingContainerInsights, "container-insights", false, "Also read the node-pressure evictions of the kubelet from the Container Insights logs")
+	_ = scalingCmd.MarkFlagRequired("name")
+}
+
+// printScalingReport writes the scaling decisions and evictions of a workload, oldest
+// first, e.g.
+//
+//	shop/web (1 decision)
+//	TIME                  LOG  KIND         DETAILS
+//	2024-01-01T10:00:00Z  kcm  hpa rescale  3 -> 5 replicas: cpu resource utilization (percentage of request) above target
+func printScalingReport(w io.Writer, workload log.Workload, events []log.ScalingEvent) {
+	if len(events) == 0 {
+		_, _ = fmt.Fprintf(w, "No scaling decisions or evictions of %s/%s found.\n", workload.Namespace, workload.Name)
+		return
+	}
+
+	noun := "decisions"
+	if len(events) == 1 {
+		noun = "decision"
+	}
+	_, _ = fmt.Fprintf(w, "%s/%s (%d %s)\n", workload.Namespace, workload.Name, len(events), noun)
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "TIME\tLOG\tKIND\tDETAILS")
+	for _, event := range events {
+		details := event.Summary
+		if event.User != "" {
+			details += " by " + event.User
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", event.Time.Format(time.RFC3339), event.LogType, event.Kind, details)
+	}
+	_ = table.Flush()
+}
+
+// printScalingJSON writes one JSON object per scaling decision or eviction
+func printScalingJSON(w io.Writer, events []log.ScalingEvent) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
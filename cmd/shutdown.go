@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/signal"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+)
+
+// resumeTimeFormat is RFC3339 with milliseconds, the precision of CloudWatch timestamps
+const resumeTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+var (
+	timeout    time.Duration
+	apiTimeout time.Duration
+)
+
+// errTimeout is returned when the retrieval does not complete within --timeout
+var errTimeout = errors.New("retrieval timed out")
+
+// interruptContext returns a context cancelled by the first Ctrl+C or SIGTERM (see
+// shutdownSignals). Later signals get their default behavior back, so a second Ctrl+C
+// terminates immediately.
+func interruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, shutdownSignals...)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// resumeMode selects how an interrupted retrieval can be resumed
+type resumeMode int
+
+const (
+	// resumeRange resumes a historical retrieval with -s and -e
+	resumeRange resumeMode = iota
+	// resumeFollow retrieves what follow mode missed with -s up to now
+	resumeFollow
+	// resumeNone is used when the retrieval cannot be resumed, e.g. --tail scanning backwards
+	resumeNone
+)
+
+// printInterruptSummary reports what was retrieved before an interruption and the
+// -s/-e arguments that resume the retrieval
+func printInterruptSummary(w io.Writer, progress aws.Progress, mode resumeMode) {
+	_, _ = fmt.Fprintf(w, "Interrupted after %d events\n", progress.Events)
+	for _, group := range progress.Groups {
+		switch {
+		case group.Done:
+			_, _ = fmt.Fprintf(w, "  %s: complete\n", group.LogGroup)
+		case group.Reached.IsZero():
+			_, _ = fmt.Fprintf(w, "  %s: nothing read\n", group.LogGroup)
+		case group.LastEventID != "":
+			_, _ = fmt.Fprintf(w, "  %s: read up to %s (event %s)\n", group.LogGroup, formatResumeTime(group.Reached), group.LastEventID)
+		default:
+			_, _ = fmt.Fprintf(w, "  %s: read up to %s\n", group.LogGroup, formatResumeTime(group.Reached))
+		}
+	}
+
+	resume := progress.ResumeFrom()
+	if resume.IsZero() {
+		return
+	}
+	switch mode {
+	case resumeRange:
+		_, _ = fmt.Fprintf(w, "Resume with: -s %s -e %s\n", formatResumeTime(resume), formatResumeTime(progress.End))
+	case resumeFollow:
+		_, _ = fmt.Fprintf(w, "Resume with: -s %s\n", formatResumeTime(resume))
+	}
+}
+
+// formatResumeTime renders a timestamp accepted by --start-time and --end-time
+func formatResumeTime(t time.Time) string {
+	return t.UTC().Format(resumeTimeFormat)
+}
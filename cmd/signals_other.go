@@ -0,0 +1,12 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"syscall"
+)
+
+// shutdownSignals stop a retrieval gracefully: Ctrl+C, and SIGTERM from kill, timeout or a
+// container runtime
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
@@ -0,0 +1,8 @@
+package cmd
+
+import "os"
+
+// shutdownSignals stop a retrieval gracefully. Windows has no SIGTERM: Ctrl+C and
+// Ctrl+Break are delivered as os.Interrupt, and closing the console terminates the process
+// before a summary could be read.
+var shutdownSignals = []os.Signal{os.Interrupt}
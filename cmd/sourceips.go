@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// maxSourceIPUsers bounds the users listed per address in the text report
+const maxSourceIPUsers = 3
+
+var (
+	sourceIPsOptions   reportOptions
+	sourceIPsAllowlist []string
+	sourceIPsOutside   bool
+)
+
+var sourceIPsCmd = &cobra.Command{
+	Use:   "source-ips [cluster-name]",
+	Short: "Count the API requests per source IP and flag those outside an allowlist",
+	Long: `Count the requests of the audit log per source IP, with the users behind each address,
+to spot unexpected exposure of the API server. The source IP of a request is the first of
+its sourceIPs; the following ones are proxies.
+
+Addresses outside the CIDRs of --allow-cidr, or of source-ip-allowlist in the context
+of the config file, are flagged and listed first. Each address is classified as public,
+private (RFC 1918, RFC 4193, 100.64.0.0/10), loopback or link-local; no GeoIP lookup
+is made.
+
+Requires audit logging to be enabled on the cluster.`,
+	Example: `  ekslogs source-ips my-cluster -s -1d --allow-cidr 10.0.0.0/8 --allow-cidr 203.0.113.0/24
+  ekslogs source-ips my-cluster --allow-cidr 10.0.0.0/8 --outside-only -o json`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &sourceIPsOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		cidrs := sourceIPsAllowlist
+		if len(cidrs) == 0 && r.context != nil {
+			cidrs = r.context.SourceIPAllowlist
+		}
+		allowlist, err := log.ParseCIDRs(cidrs)
+		if err != nil {
+			return err
+		}
+		if sourceIPsOutside && len(allowlist) == 0 {
+			return fmt.Errorf("--outside-only requires --allow-cidr or a source-ip-allowlist in the context")
+		}
+
+		entries, err := r.collect([]string{"audit"}, r.start, r.end, "", 0)
+		if err != nil {
+			return err
+		}
+
+		summaries := log.SummarizeSourceIPs(entries, allowlist)
+		r.verbosef("Found %d source IPs in %d audit events", len(summaries), len(entries))
+		if sourceIPsOutside {
+			summaries = outsideSourceIPs(summaries)
+		}
+
+		if r.format == log.OutputFormatJSON {
+			if err := printSourceIPsJSON(os.Stdout, summaries); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printSourceIPsReport(r.output(), summaries, len(allowlist) > 0)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sourceIPsCmd)
+
+	sourceIPsOptions.documents = true
+	sourceIPsOptions.addFlags(sourceIPsCmd, "one JSON object per source IP")
+	sourceIPsCmd.Flags().StringSliceVar(&sourceIPsAllowlist, "allow-cidr", nil, "CIDR expected to reach the API server, e.g. 10.0.0.0/8 (repeatable; default: source-ip-allowlist of the context)")
+	sourceIPsCmd.Flags().BoolVar(&sourceIPsOutside, "outside-only", false, "Only report the source IPs outside the allowlist")
+}
+
+// outsideSourceIPs keeps the addresses outside the allowlist
+func outsideSourceIPs(summaries []log.SourceIPSummary) []log.SourceIPSummary {
+	var outside []log.SourceIPSummary
+	for _, summary := range summaries {
+		if summary.Outside {
+			outside = append(outside, summary)
+		}
+	}
+	return outside
+}
+
+// printSourceIPsReport writes a table of the source IPs, e.g.
+//
+//	   SOURCE IP    SCOPE   REQUESTS  USERS
+//	!  203.0.113.7  public  3         alice (2), bob (1)
+//
+// The ! column marks the addresses outside the allowlist and is only written with one.
+func printSourceIPsReport(w io.Writer, summaries []log.SourceIPSummary, withAllowlist bool) {
+	if len(summaries) == 0 {
+		_, _ = fmt.Fprintln(w, "No audit events with a source IP found.")
+		return
+	}
+
+	table := newTable(w)
+	header := "SOURCE IP\tSCOPE\tREQUESTS\tUSERS"
+	if withAllowlist {
+		header = "\t" + header
+	}
+	_, _ = fmt.Fprintln(table, header)
+	for _, summary := range summaries {
+		users := make([]string, 0, maxSourceIPUsers+1)
+		for i, user := range summary.Users {
+			if i == maxSourceIPUsers {
+				users = append(users, fmt.Sprintf("+%d more", len(summary.Users)-maxSourceIPUsers))
+				break
+			}
+			users = append(users, fmt.Sprintf("%s (%d)", orDash(user.User), user.Requests))
+		}
+		line := fmt.Sprintf("%s\t%s\t%d\t%s", summary.IP, summary.Scope, summary.Requests, strings.Join(users, ", "))
+		if withAllowlist {
+			mark := ""
+			if summary.Outside {
+				mark = "!"
+			}
+			line = mark + "\t" + line
+		}
+		_, _ = fmt.Fprintln(table, line)
+	}
+	_ = table.Flush()
+}
+
+// printSourceIPsJSON writes one JSON object per source IP
+func printSourceIPsJSON(w io.Writer, summaries []log.SourceIPSummary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, summary := range summaries {
+		if err := encoder.Encode(summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/aws"
+)
+
+var statsFormat string
+
+// validateStatsFormat checks the --stats value
+func validateStatsFormat(format string) error {
+	switch format {
+	case "", "text", "json":
+		return nil
+	default:
+		return fmt.Errorf("invalid stats format '%s' (supported: text, json)", format)
+	}
+}
+
+// printStats writes the retrieval statistics report
+func printStats(w io.Writer, stats aws.Stats, format string) {
+	if format == "json" {
+		encoded, err := json.Marshal(stats)
+		if err == nil {
+			_, _ = fmt.Fprintln(w, string(encoded))
+		}
+		return
+	}
+
+	_, _ = fmt.Fprintln(w, "=== Retrieval statistics ===")
+	_, _ = fmt.Fprintf(w, "Events:     %d\n", stats.Events)
+	_, _ = fmt.Fprintf(w, "Pages:      %d\n", stats.Pages)
+	_, _ = fmt.Fprintf(w, "API calls:  %d\n", stats.APICalls)
+	_, _ = fmt.Fprintf(w, "Retries:    %d\n", stats.Retries)
+	_, _ = fmt.Fprintf(w, "Throttles:  %d\n", stats.Throttles)
+	_, _ = fmt.Fprintf(w, "Duplicates: %d\n", stats.Duplicates)
+	_, _ = fmt.Fprintf(w, "Bytes:      %s (%d)\n", formatBytes(stats.Bytes), stats.Bytes)
+	_, _ = fmt.Fprintf(w, "Elapsed:    %s\n", stats.Elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(w, "Events/sec: %.1f\n", stats.EventsPerSecond)
+}
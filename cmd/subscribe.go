@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/filter"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var (
+	subscribeOptions        reportOptions
+	subscribeDestination    string
+	subscribeRoleARN        string
+	subscribeFilterName     string
+	subscribePreset         string
+	subscribeFilterPatterns []string
+	subscribeIgnorePatterns []string
+)
+
+// subscribeResult is what subscribe created or updated
+type subscribeResult struct {
+	aws.SubscriptionFilter
+	aws.SubscriptionResult
+	Destination string `json:"destination_kind"`
+}
+
+var subscribeCmd = &cobra.Command{
+	Use:   "subscribe [cluster-name]",
+	Short: "Stream the matching control plane logs to Kinesis, Firehose or Lambda",
+	Long: `Create or update a CloudWatch Logs subscription filter on the cluster log group, streaming
+the events matching -F/-I (the same syntax as the root command) or a preset to a Kinesis
+data stream, a Firehose delivery stream, a Lambda function or a CloudWatch Logs
+destination of another account. Without a pattern every event is streamed.
+
+The filter is named ekslogs-<destination name> unless --filter-name is given; running the
+command again with the same name updates it. A log group accepts two subscription
+filters: when it already has two, they are listed so one can be replaced.
+
+Kinesis and Firehose destinations require --role-arn, a role CloudWatch Logs
+(logs.amazonaws.com) can assume that may put records to the stream. A Lambda function
+is allowed to be invoked by the log group (lambda:AddPermission) before the filter is
+created.
+
+Requires logs:DescribeSubscriptionFilters and logs:PutSubscriptionFilter, iam:PassRole
+on --role-arn, and lambda:AddPermission for a Lambda destination.`,
+	Example: `  ekslogs subscribe my-cluster --destination arn:aws:lambda:us-east-1:123456789012:function:alerts -F error
+  ekslogs subscribe my-cluster -p system-masters-usage \
+    --destination arn:aws:firehose:us-east-1:123456789012:deliverystream/siem \
+    --role-arn arn:aws:iam::123456789012:role/cwl-to-firehose`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kind, err := aws.DestinationKind(subscribeDestination)
+		if err != nil {
+			return err
+		}
+		if (kind == aws.DestinationKinesis || kind == aws.DestinationFirehose) && subscribeRoleARN == "" {
+			return fmt.Errorf("--role-arn is required for a %s destination: a role CloudWatch Logs (logs.amazonaws.com) can assume that may put records to it", kind)
+		}
+		pattern := buildCombinedFilterPattern(subscribeFilterPatterns, subscribeIgnorePatterns, false)
+		if subscribePreset != "" {
+			preset, ok := filter.GetUnifiedPreset(subscribePreset)
+			if !ok {
+				return fmt.Errorf("preset filter '%s' not found. Run 'ekslogs presets' to see available presets", subscribePreset)
+			}
+			if len(subscribeFilterPatterns) > 0 || len(subscribeIgnorePatterns) > 0 {
+				return fmt.Errorf("--preset cannot be combined with -F or -I")
+			}
+			pattern = preset.Pattern
+		}
+
+		r, err := setupReport(cmd, args, &subscribeOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		result := subscribeResult{
+			SubscriptionFilter: aws.SubscriptionFilter{
+				Name:           subscribeFilterName,
+				LogGroup:       aws.ClusterLogGroup(r.clusterName),
+				Pattern:        pattern,
+				DestinationARN: subscribeDestination,
+				RoleARN:        subscribeRoleARN,
+			},
+			Destination: kind,
+		}
+		if result.Name == "" {
+			result.Name = "ekslogs-" + destinationName(subscribeDestination)
+		}
+		if kind == aws.DestinationLambda {
+			result.LogGroupARN, err = aws.LogGroupARN(awssdk.ToString(r.cluster.Arn), result.LogGroup)
+			if err != nil {
+				return err
+			}
+		}
+		r.verbosef("Using filter pattern: %s", orDash(pattern))
+
+		result.SubscriptionResult, err = r.client.PutSubscriptionFilter(r.ctx, result.SubscriptionFilter)
+		if err != nil {
+			return err
+		}
+		if r.format == log.OutputFormatJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			return encoder.Encode(result)
+		}
+		printSubscribeResult(os.Stdout, result)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(subscribeCmd)
+
+	subscribeOptions.addCommonFlags(subscribeCmd, "the subscription filter")
+	subscribeCmd.Flags().StringVar(&subscribeDestination, "destination", "", "ARN of the Kinesis data stream, Firehose delivery stream, Lambda function or CloudWatch Logs destination")
+	_ = subscribeCmd.MarkFlagRequired("destination")
+	subscribeCmd.Flags().StringVar(&subscribeRoleARN, "role-arn", "", "Role CloudWatch Logs assumes to put records to a Kinesis or Firehose destination")
+	subscribeCmd.Flags().StringVar(&subscribeFilterName, "filter-name", "", "Name of the subscription filter (default ekslogs-<destination name>)")
+	subscribeCmd.Flags().StringVarP(&subscribePreset, "preset", "p", "", "Stream the events matching a filter preset")
+	subscribeCmd.Flags().StringArrayVarP(&subscribeFilterPatterns, "filter-pattern", "F", []string{}, "Log filter pattern (can be specified multiple times for AND condition)")
+	subscribeCmd.Flags().StringArrayVarP(&subscribeIgnorePatterns, "ignore-filter-pattern", "I", []string{}, "Log ignore filter pattern (can be specified multiple times for OR condition)")
+}
+
+// destinationName returns the name of the resource of a destination ARN, e.g. "siem" for
+// arn:aws:firehose:us-east-1:123456789012:deliverystream/siem, without the version or alias
+// of a Lambda function
+func destinationName(destinationARN string) string {
+	parsed, err := arn.Parse(destinationARN)
+	if err != nil {
+		return destinationARN
+	}
+	parts := strings.FieldsFunc(parsed.Resource, func(r rune) bool { return r == '/' || r == ':' })
+	if len(parts) < 2 {
+		return parsed.Resource
+	}
+	return parts[1]
+}
+
+// printSubscribeResult describes the created or updated subscription filter
+func printSubscribeResult(w io.Writer, result subscribeResult) {
+	action := "Created"
+	if result.Updated {
+		action = "Updated"
+	}
+	pattern := result.Pattern
+	if pattern == "" {
+		pattern = "(every event)"
+	}
+	_, _ = fmt.Fprintf(w, "%s subscription filter %s on %s\n", action, result.Name, result.LogGroup)
+	_, _ = fmt.Fprintf(w, "  Destination: %s (%s)\n", result.DestinationARN, result.Destination)
+	_, _ = fmt.Fprintf(w, "  Pattern:     %s\n", pattern)
+	if result.RoleARN != "" {
+		_, _ = fmt.Fprintf(w, "  Role:        %s\n", result.RoleARN)
+	}
+	if result.LambdaPermission {
+		_, _ = fmt.Fprintln(w, "  Allowed the log group to invoke the function (lambda:AddPermission)")
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// throttlingFilterPattern selects the 429 responses of the audit log and API server httplog
+// lines; the responses are parsed client-side
+const throttlingFilterPattern = "429"
+
+var throttlingOptions reportOptions
+
+var throttlingCmd = &cobra.Command{
+	Use:   "throttling [cluster-name]",
+	Short: "Find the clients rejected with 429 by API Priority and Fairness",
+	Long: `Find the requests rejected with 429 Too Many Requests in the audit and API server logs and
+count them per flow schema, priority level, user agent and user, to pinpoint the client
+exhausting the concurrency shares of API Priority and Fairness (APF).
+
+The flow schema and priority level come from the apf_fs and apf_pl audit annotations
+(Kubernetes 1.26 and later) or the httplog lines of the API server. A request found in
+both logs is counted once.`,
+	Example: `  ekslogs throttling my-cluster -s -6h   # 429 responses of the past 6 hours
+  ekslogs throttling my-cluster -o json  # One JSON object per client`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &throttlingOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		entries, err := r.collect([]string{"api", "audit"}, r.start, r.end, throttlingFilterPattern, 0)
+		if err != nil {
+			return err
+		}
+
+		var requests []log.ThrottledRequest
+		for _, entry := range entries {
+			if request, ok := log.ParseThrottled(entry); ok {
+				requests = append(requests, request)
+			}
+		}
+		r.verbosef("Found %d throttled requests in %d log events", len(requests), len(entries))
+
+		groups := log.GroupThrottled(requests)
+		if r.format == log.OutputFormatJSON {
+			if err := printThrottlingJSON(os.Stdout, groups); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printThrottlingReport(r.output(), groups)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(throttlingCmd)
+
+	throttlingOptions.documents = true
+	throttlingOptions.addFlags(throttlingCmd, "one JSON object per client")
+}
+
+// printThrottlingReport writes a table of the throttled clients, the most throttled first
+func printThrottlingReport(w io.Writer, groups []log.ThrottleGroup) {
+	if len(groups) == 0 {
+		_, _ = fmt.Fprintln(w, "No requests rejected with 429 found.")
+		return
+	}
+
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "COUNT\tFLOW SCHEMA\tPRIORITY LEVEL\tUSER AGENT\tUSER")
+	for _, group := range groups {
+		_, _ = fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\n", group.Count,
+			orDash(group.FlowSchema), orDash(group.PriorityLevel), orDash(shortUserAgent(group.UserAgent)), orDash(group.User))
+	}
+	_ = table.Flush()
+}
+
+// printThrottlingJSON writes one JSON object per throttled client
+func printThrottlingJSON(w io.Writer, groups []log.ThrottleGroup) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, group := range groups {
+		if err := encoder.Encode(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shortUserAgent keeps the product of a user agent, e.g. "kubectl/v1.29.0" out of
+// "kubectl/v1.29.0 (linux/amd64) kubernetes/3f7a50f"
+func shortUserAgent(userAgent string) string {
+	if product, _, found := strings.Cut(userAgent, " "); found {
+		return product
+	}
+	return userAgent
+}
+
+// orDash returns "-" for empty table cells
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
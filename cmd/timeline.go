@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// timelineLogTypes are the logs reporting the changes to an object
+var timelineLogTypes = []string{"api", "audit", "scheduler", "kcm"}
+
+var (
+	timelineOptions   reportOptions
+	timelineKind      string
+	timelineName      string
+	timelineNamespace string
+)
+
+var timelineCmd = &cobra.Command{
+	Use:   "timeline [cluster-name]",
+	Short: "Show the life of one object across the control plane components",
+	Long: `Search the audit, API server, scheduler and controller manager logs for one object and
+merge what each component reports about it into a single chronological narrative: created,
+admission webhooks, scheduled, status updates, deleted, and the controller and API server
+messages mentioning it.
+
+Reads (get, list, watch) are left out. Requires audit logging to be enabled on the cluster
+for the created, updated and deleted steps.`,
+	Example: `  ekslogs timeline my-cluster --kind pod --name web-5d8f7 -n shop -s -2h
+  ekslogs timeline my-cluster --kind node --name ip-10-0-1-5.ec2.internal -o json`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ref, err := log.ParseObjectKind(timelineKind, timelineNamespace, timelineName)
+		if err != nil {
+			return err
+		}
+		r, err := setupReport(cmd, args, &timelineOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		entries, err := r.collect(timelineLogTypes, r.start, r.end, ref.FilterPattern(), 0)
+		if err != nil {
+			return err
+		}
+
+		events := log.BuildTimeline(entries, ref)
+		r.verbosef("Found %d steps of %s in %d log events", len(events), ref, len(entries))
+		if r.format == log.OutputFormatJSON {
+			if err := printTimelineJSON(os.Stdout, events); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printTimelineReport(r.output(), ref, events)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(timelineCmd)
+
+	timelineOptions.documents = true
+	timelineOptions.addFlags(timelineCmd, "one JSON object per step")
+	timelineCmd.Flags().StringVar(&timelineKind, "kind", "pod", "Kind of the object, e.g. pod, deployment, node")
+	timelineCmd.Flags().StringVar(&timelineName, "name", "", "Name of the object")
+	timelineCmd.Flags().StringVarP(&timelineNamespace, "namespace", "n", "default", "Namespace of the object (ignored for cluster-scoped kinds)")
+	_ = timelineCmd.MarkFlagRequired("name")
+}
+
+// printTimelineReport writes the steps in the life of an object, oldest first, e.g.
+//
+//	pods shop/web (2 steps)
+//	TIME                  LOG        STAGE      DETAILS
+//	2024-01-01T10:00:00Z  audit      created    create by system:serviceaccount:kube-system:replicaset-controller
+func printTimelineReport(w io.Writer, ref log.ObjectRef, events []log.TimelineEvent) {
+	if len(events) == 0 {
+		_, _ = fmt.Fprintf(w, "No changes to %s found.\n", ref)
+		return
+	}
+
+	noun := "steps"
+	if len(events) == 1 {
+		noun = "step"
+	}
+	_, _ = fmt.Fprintf(w, "%s (%d %s)\n", ref, len(events), noun)
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "TIME\tLOG\tSTAGE\tDETAILS")
+	for _, event := range events {
+		details := event.Summary
+		if event.User != "" {
+			details += " by " + event.User
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", event.Time.Format(time.RFC3339), event.LogType, event.Stage, details)
+	}
+	_ = table.Flush()
+}
+
+// printTimelineJSON writes one JSON object per step in the life of an object
+func printTimelineJSON(w io.Writer, events []log.TimelineEvent) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// envNoUpdateCheck disables the update check enabled in the config file, e.g. in CI
+const envNoUpdateCheck = "EKSLOGS_NO_UPDATE_CHECK"
+
+const (
+	latestReleaseAPI = "https://api.github.com/repos/kzcat/ekslogs/releases/latest"
+	releasesPage     = "https://github.com/kzcat/ekslogs/releases/latest"
+)
+
+// updateCheckInterval is how long the result of an update check is kept before checking again
+const updateCheckInterval = 24 * time.Hour
+
+// updateCheckTimeout bounds the release lookup, which delays the exit of the command
+const updateCheckTimeout = 2 * time.Second
+
+// updateState records the last update check
+type updateState struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest,omitempty"`
+}
+
+// updateStatePath returns the file recording the last update check, under $XDG_CACHE_HOME
+// (defaulting to ~/.cache)
+func updateStatePath() string {
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		cacheHome = filepath.Join(home, ".cache")
+	}
+	return filepath.Join(cacheHome, "ekslogs", "update-check.json")
+}
+
+// notifyUpdate tells on stderr when a newer release than the running version exists.
+// It only runs when update-check is enabled in the config file, for release builds
+// printing to a terminal.
+func notifyUpdate(ctx context.Context) {
+	if version == "dev" || os.Getenv(envNoUpdateCheck) != "" || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return
+	}
+	appConfig, err := loadConfig()
+	if err != nil || !appConfig.UpdateCheck {
+		return
+	}
+	checkForUpdate(ctx, os.Stderr, version, updateStatePath(), fetchLatestRelease, time.Now())
+}
+
+// checkForUpdate looks up the latest release with fetch, unless it was looked up less than
+// updateCheckInterval ago, and writes a one-line notice to w when it is newer than current
+func checkForUpdate(ctx context.Context, w io.Writer, current, statePath string, fetch func(context.Context) (string, error), now time.Time) {
+	if statePath == "" {
+		return
+	}
+	var state updateState
+	if data, err := os.ReadFile(statePath); err == nil {
+		_ = json.Unmarshal(data, &state)
+	}
+	if now.Sub(state.CheckedAt) < updateCheckInterval {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+	latest, fetchErr := fetch(ctx)
+
+	// A failed lookup is also only retried the next day, so being offline costs one timeout
+	state = updateState{CheckedAt: now, Latest: latest}
+	if data, err := json.Marshal(state); err == nil && os.MkdirAll(filepath.Dir(statePath), 0o700) == nil {
+		_ = os.WriteFile(statePath, data, 0o600)
+	}
+
+	if fetchErr == nil && newerVersion(latest, current) {
+		_, _ = fmt.Fprintf(w, "ekslogs %s is available (running %s): %s\n", latest, current, releasesPage)
+	}
+}
+
+// fetchLatestRelease returns the tag of the latest release on GitHub
+func fetchLatestRelease(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseAPI, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("release lookup returned %s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", err
+	}
+	return release.TagName, nil
+}
+
+// newerVersion reports whether the release latest is newer than current, both versions of
+// the form v1.2.3 or 1.2.3, and a release newer than its pre-releases (1.2.3-rc1).
+// Versions that do not parse are never newer.
+func newerVersion(latest, current string) bool {
+	l, latestPre, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, currentPre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return currentPre && !latestPre
+}
+
+// parseVersion returns the major, minor and patch numbers of a version, and whether it is
+// a pre-release. A build suffix (+...) is ignored.
+func parseVersion(v string) (numbers [3]int, preRelease bool, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		v, preRelease = v[:i], true
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return numbers, false, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return numbers, false, false
+		}
+		numbers[i] = n
+	}
+	return numbers, preRelease, true
+}
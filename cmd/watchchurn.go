@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// watchChurnFilterPattern selects the watch requests of the audit log ("verb":"watch") and
+// the API server httplog lines (verb="WATCH"); the requests are parsed client-side
+const watchChurnFilterPattern = "?watch ?WATCH"
+
+// maxWatchResources bounds the resources listed per client in the text report
+const maxWatchResources = 3
+
+var watchChurnOptions reportOptions
+
+var watchChurnCmd = &cobra.Command{
+	Use:   "watch-churn [cluster-name]",
+	Short: "Find the clients opening and closing the most watches",
+	Long: `Count the watches started and ended per user agent and user in the audit and API server
+logs, to find the controllers re-establishing their watches far more often than the 5 to
+10 minutes the API server keeps a healthy watch open. Watch churn costs the API server
+and etcd a relist each time, and is a common hidden scalability problem.
+
+Starts come from the ResponseStarted stage of the audit log, ends from its
+ResponseComplete stage or the httplog lines of the API server; the clients are sorted by
+the larger of both counts. A watch found in both logs is counted once.`,
+	Example: `  ekslogs watch-churn my-cluster -s -1h   # Busiest watch clients of the past hour
+  ekslogs watch-churn my-cluster -o json  # One JSON object per client`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := setupReport(cmd, args, &watchChurnOptions)
+		if err != nil {
+			return err
+		}
+		defer r.stop()
+
+		entries, err := r.collect([]string{"api", "audit"}, r.start, r.end, watchChurnFilterPattern, 0)
+		if err != nil {
+			return err
+		}
+
+		var events []log.WatchEvent
+		for _, entry := range entries {
+			if event, ok := log.ParseWatch(entry); ok {
+				events = append(events, event)
+			}
+		}
+		r.verbosef("Found %d watch starts and ends in %d log events", len(events), len(entries))
+
+		clients := log.GroupWatches(events, reportWindow(r.start, r.end))
+		if r.format == log.OutputFormatJSON {
+			if err := printWatchChurnJSON(os.Stdout, clients); err != nil {
+				return err
+			}
+			return r.finish()
+		}
+		printWatchChurnReport(r.output(), clients)
+		return r.finish()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchChurnCmd)
+
+	watchChurnOptions.documents = true
+	watchChurnOptions.addFlags(watchChurnCmd, "one JSON object per client")
+}
+
+// reportWindow returns the length of the time range of a report, up to now when it has no
+// end, or 0 when it has no start
+func reportWindow(start, end *time.Time) time.Duration {
+	if start == nil {
+		return 0
+	}
+	if end == nil {
+		return time.Since(*start)
+	}
+	return end.Sub(*start)
+}
+
+// printWatchChurnReport writes a table of the watch clients, the busiest first
+func printWatchChurnReport(w io.Writer, clients []log.WatchClient) {
+	if len(clients) == 0 {
+		_, _ = fmt.Fprintln(w, "No watch requests found.")
+		return
+	}
+
+	table := newTable(w)
+	_, _ = fmt.Fprintln(table, "PER MIN\tSTARTED\tENDED\tUNDER 1M\tMEAN\tUSER AGENT\tUSER\tRESOURCES")
+	for _, client := range clients {
+		mean := "-"
+		if client.MeanDurationSeconds > 0 {
+			mean = (time.Duration(client.MeanDurationSeconds * float64(time.Second))).Round(time.Second).String()
+		}
+		resources := client.Resources
+		if len(resources) > maxWatchResources {
+			resources = append(resources[:maxWatchResources:maxWatchResources], fmt.Sprintf("+%d more", len(client.Resources)-maxWatchResources))
+		}
+		_, _ = fmt.Fprintf(table, "%.1f\t%d\t%d\t%d\t%s\t%s\t%s\t%s\n", client.PerMinute, client.Started, client.Ended, client.ShortLived,
+			mean, orDash(shortUserAgent(client.UserAgent)), orDash(client.User), orDash(strings.Join(resources, ",")))
+	}
+	_ = table.Flush()
+}
+
+// printWatchChurnJSON writes one JSON object per watch client
+func printWatchChurnJSON(w io.Writer, clients []log.WatchClient) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, client := range clients {
+		if err := encoder.Encode(client); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBudgetExceeded is returned when a retrieval exceeds the configured byte or API call budget
+var ErrBudgetExceeded = errors.New("retrieval budget exceeded")
+
+// Budget limits the amount of data and the number of API calls of a retrieval.
+// Zero values disable the corresponding limit.
+type Budget struct {
+	MaxBytes    int64
+	MaxAPICalls int64
+}
+
+// SetBudget sets the byte and API call limits applied to subsequent retrievals
+func (c *EKSLogsClient) SetBudget(budget Budget) {
+	c.budget = budget
+}
+
+// countAPICall records an AWS API call and fails once the API call budget is exhausted
+func (c *EKSLogsClient) countAPICall() error {
+	calls := c.apiCalls.Add(1)
+	if c.budget.MaxAPICalls > 0 && calls > c.budget.MaxAPICalls {
+		return fmt.Errorf("%w: more than %d API calls (--max-api-calls)", ErrBudgetExceeded, c.budget.MaxAPICalls)
+	}
+	return nil
+}
+
+// countBytes records retrieved message bytes and fails once the byte budget is exhausted
+func (c *EKSLogsClient) countBytes(n int) error {
+	total := c.bytesFetched.Add(int64(n))
+	if c.budget.MaxBytes > 0 && total > c.budget.MaxBytes {
+		return fmt.Errorf("%w: more than %d bytes retrieved (--max-bytes)", ErrBudgetExceeded, c.budget.MaxBytes)
+	}
+	return nil
+}
+
+// EstimateRangeBytes estimates the volume of log data stored between startTime and endTime,
+// assuming the stored bytes of the cluster log groups are spread evenly over their retained period
+func (c *EKSLogsClient) EstimateRangeBytes(ctx context.Context, clusterName string, startTime, endTime time.Time) (int64, error) {
+	logGroups, err := c.describeLogGroups(ctx, clusterName)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	var estimate float64
+	for _, lg := range logGroups {
+		if lg.StoredBytes == nil || *lg.StoredBytes == 0 {
+			continue
+		}
+
+		// The retained period starts at the later of the creation time and the retention cutoff
+		retainedFrom := time.Time{}
+		if lg.CreationTime != nil {
+			retainedFrom = time.UnixMilli(*lg.CreationTime)
+		}
+		if lg.RetentionInDays != nil {
+			if cutoff := now.AddDate(0, 0, -int(*lg.RetentionInDays)); cutoff.After(retainedFrom) {
+				retainedFrom = cutoff
+			}
+		}
+
+		retained := now.Sub(retainedFrom)
+		if retainedFrom.IsZero() || retained <= 0 {
+			continue
+		}
+
+		// Only the part of the range within the retained period holds data
+		from, to := startTime, endTime
+		if from.Before(retainedFrom) {
+			from = retainedFrom
+		}
+		if to.After(now) {
+			to = now
+		}
+		if !to.After(from) {
+			continue
+		}
+
+		estimate += float64(*lg.StoredBytes) * float64(to.Sub(from)) / float64(retained)
+	}
+
+	return int64(estimate), nil
+}
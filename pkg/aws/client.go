@@ -2,7 +2,11 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"path"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -10,14 +14,26 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
 	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
-	"github.com/fatih/color"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
 	"github.com/kzcat/ekslogs/pkg/log"
 )
 
+// maxFilterStreams is the maximum number of stream names accepted by FilterLogEvents
+const maxFilterStreams = 100
+
+// maxRecentLogsWindow bounds the backward search of GetRecentLogs when no start time is given
+const maxRecentLogsWindow = 7 * 24 * time.Hour
+
 // EKSAPI defines the interface for the EKS client.
 type EKSAPI interface {
 	ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error)
@@ -29,37 +45,148 @@ type CloudWatchLogsAPI interface {
 	DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error)
 	DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error)
 	FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error)
+	GetLogEvents(ctx context.Context, params *cloudwatchlogs.GetLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error)
 }
 
 type EKSLogsClient struct {
 	logsClient CloudWatchLogsAPI
 	eksClient  EKSAPI
-	region     string
-	verbose    bool
+	// trailClient looks up the CloudTrail events of a cluster
+	trailClient CloudTrailAPI
+	// ec2Client lists the enabled regions
+	ec2Client EC2API
+	// stsClient resolves the account of the credentials in use
+	stsClient STSAPI
+	// metricFilterClient creates metric filters on the cluster log group
+	metricFilterClient MetricFilterAPI
+	// alarmClient creates alarms on the metrics of metric filters
+	alarmClient AlarmAPI
+	// subscriptionClient creates subscription filters on the cluster log group
+	subscriptionClient SubscriptionAPI
+	// lambdaClient allows the cluster log group to invoke a Lambda destination
+	lambdaClient LambdaAPI
+	// insightsClient runs Logs Insights queries and saves them
+	insightsClient InsightsAPI
+	// eksForRegion creates EKS clients for other regions, to search them for a cluster
+	eksForRegion func(region string) EKSAPI
+	region       string
+	endpointURL  string
+	roleARN      string
+	logger       *slog.Logger
+
+	budget       Budget
+	apiCalls     atomic.Int64
+	bytesFetched atomic.Int64
+	counters     counters
+	progress     progressTracker
+	callTimeout  time.Duration
+	follow       FollowOptions
+	// containerInsights adds the Container Insights log groups of node and pod logs
+	containerInsights bool
+	// includeUnknownStreams reads the streams of unknown control plane components along with
+	// the log types asked for
+	includeUnknownStreams bool
+	// permissions are the read API calls denied to the credentials, worked around by GetLogs
+	permissions Permissions
+	streamCache streamCache
+	// streamObserver is told about every stream listing while follow mode watches for stream changes
+	streamObserver func(logGroup string, streams []cwt.LogStream)
+	started        time.Time
 }
 
-func NewEKSLogsClient(region string, verbose bool) (*EKSLogsClient, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(region),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+// NewEKSLogsClient creates a client for the given region. A non-empty endpointURL sends
+// every AWS API call to that endpoint (e.g. LocalStack or a FIPS endpoint) instead of the
+// AWS_ENDPOINT_URL environment variable or the regional default. Diagnostics are written to
+// logger; a nil logger discards them.
+func NewEKSLogsClient(region, endpointURL string, logger *slog.Logger) (*EKSLogsClient, error) {
+	if endpointURL != "" {
+		if err := validateEndpointURL(endpointURL); err != nil {
+			return nil, err
+		}
+	}
+
+	client := &EKSLogsClient{
+		region:      region,
+		endpointURL: endpointURL,
+		logger:      logger,
+		streamCache: streamCache{ttl: DefaultStreamCacheTTL},
+		started:     time.Now(),
 	}
 
+	if err := client.loadClients(context.TODO()); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// NewEKSLogsClientWithAPIs creates a client calling the given EKS and CloudWatch Logs APIs,
+// e.g. the in-memory fake of the awstest package, instead of loading the AWS configuration.
+// Only the retrieval of clusters, log groups and log events is available: the features
+// calling other services (CloudTrail, STS, metric filters, Insights...) must not be used.
+func NewEKSLogsClientWithAPIs(region string, eksClient EKSAPI, logsClient CloudWatchLogsAPI, logger *slog.Logger) *EKSLogsClient {
 	return &EKSLogsClient{
-		logsClient: cloudwatchlogs.NewFromConfig(cfg),
-		eksClient:  eks.NewFromConfig(cfg),
-		region:     region,
-		verbose:    verbose,
-	}, nil
+		eksClient:   eksClient,
+		logsClient:  logsClient,
+		region:      region,
+		logger:      logger,
+		streamCache: streamCache{ttl: DefaultStreamCacheTTL},
+		started:     time.Now(),
+	}
+}
+
+// loadClients loads the AWS configuration, including the credentials, and creates the service clients from it
+func (c *EKSLogsClient) loadClients(ctx context.Context) error {
+	options := append([]func(*config.LoadOptions) error{
+		config.WithRegion(c.region),
+		config.WithRetryer(func() aws.Retryer {
+			return newCountingRetryer(&c.counters)
+		}),
+		config.WithAPIOptions([]func(*middleware.Stack) error{c.addTimeoutMiddleware, c.addDebugMiddleware}),
+	}, interactiveCredentialOptions()...)
+
+	cfg, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	if c.endpointURL != "" {
+		cfg.BaseEndpoint = aws.String(c.endpointURL)
+	}
+	if c.roleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), c.roleARN))
+	}
+
+	logsClient := cloudwatchlogs.NewFromConfig(cfg)
+	c.logsClient = logsClient
+	c.metricFilterClient = logsClient
+	c.alarmClient = cloudwatch.NewFromConfig(cfg)
+	c.subscriptionClient = logsClient
+	c.insightsClient = logsClient
+	c.lambdaClient = lambda.NewFromConfig(cfg)
+	c.eksClient = eks.NewFromConfig(cfg)
+	c.trailClient = cloudtrail.NewFromConfig(cfg)
+	c.ec2Client = ec2.NewFromConfig(cfg)
+	c.stsClient = sts.NewFromConfig(cfg)
+	c.eksForRegion = func(region string) EKSAPI {
+		return eks.NewFromConfig(cfg, func(o *eks.Options) { o.Region = region })
+	}
+	return nil
+}
+
+// validateEndpointURL checks that a custom endpoint is an absolute http or https URL
+func validateEndpointURL(endpointURL string) error {
+	u, err := url.Parse(endpointURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid endpoint URL '%s': expected an http or https URL such as http://localhost:4566", endpointURL)
+	}
+	return nil
 }
 
 func (c *EKSLogsClient) ListClusters(ctx context.Context) ([]string, error) {
-	resp, err := c.eksClient.ListClusters(ctx, &eks.ListClustersInput{})
+	clusters, err := listClusters(ctx, c.eksClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list clusters: %w", err)
 	}
-	return resp.Clusters, nil
+	return clusters, nil
 }
 
 func (c *EKSLogsClient) GetClusterInfo(ctx context.Context, clusterName string) (*ekstypes.Cluster, error) {
@@ -71,7 +198,7 @@ func (c *EKSLogsClient) GetClusterInfo(ctx context.Context, clusterName string)
 		if strings.Contains(err.Error(), "ResourceNotFoundException") {
 			clusters, listErr := c.ListClusters(ctx)
 			if listErr == nil && len(clusters) > 0 {
-				return nil, fmt.Errorf("cluster '%s' not found. Available clusters: %v", clusterName, clusters)
+				return nil, &ClusterNotFoundError{Name: clusterName, Available: clusters, Matches: MatchClusterNames(clusterName, clusters)}
 			}
 		}
 		return nil, err
@@ -80,17 +207,13 @@ func (c *EKSLogsClient) GetClusterInfo(ctx context.Context, clusterName string)
 }
 
 func (c *EKSLogsClient) GetLogGroups(ctx context.Context, clusterName string) ([]string, error) {
-	prefix := fmt.Sprintf("/aws/eks/%s/cluster", clusterName)
-
-	resp, err := c.logsClient.DescribeLogGroups(ctx, &cloudwatchlogs.DescribeLogGroupsInput{
-		LogGroupNamePrefix: aws.String(prefix),
-	})
+	groups, err := c.describeLogGroups(ctx, clusterName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get log groups: %w", err)
+		return nil, err
 	}
 
 	var logGroups []string
-	for _, lg := range resp.LogGroups {
+	for _, lg := range groups {
 		if lg.LogGroupName != nil {
 			logGroups = append(logGroups, *lg.LogGroupName)
 		}
@@ -99,18 +222,74 @@ func (c *EKSLogsClient) GetLogGroups(ctx context.Context, clusterName string) ([
 	return logGroups, nil
 }
 
+// describeLogGroups returns the control plane log groups of a cluster, its Container
+// Insights log groups of node and pod logs when enabled, and the log groups of the log types
+// registered with log.RegisterLogType
+func (c *EKSLogsClient) describeLogGroups(ctx context.Context, clusterName string) ([]cwt.LogGroup, error) {
+	prefixes := []string{ClusterLogGroup(clusterName)}
+	if c.containerInsights {
+		prefixes = append(prefixes, fmt.Sprintf("%s%s/", log.ContainerInsightsPrefix, clusterName))
+	}
+	customGroups := log.CustomLogGroups(clusterName)
+	prefixes = append(prefixes, customGroups...)
+
+	var logGroups []cwt.LogGroup
+	listed := make(map[string]bool)
+	for _, prefix := range prefixes {
+		if err := c.countAPICall(); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.logsClient.DescribeLogGroups(ctx, &cloudwatchlogs.DescribeLogGroupsInput{
+			LogGroupNamePrefix: aws.String(prefix),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get log groups: %w", err)
+		}
+
+		for _, lg := range resp.LogGroups {
+			if lg.LogGroupName == nil {
+				continue
+			}
+			if log.IsContainerInsightsGroup(*lg.LogGroupName) && !contains(log.ContainerInsightsGroups, path.Base(*lg.LogGroupName)) {
+				continue
+			}
+			// A custom log group is read alone, not the log groups its name is a prefix of
+			if contains(customGroups, prefix) && *lg.LogGroupName != prefix {
+				continue
+			}
+			// A log group matched by several prefixes is read once
+			if listed[*lg.LogGroupName] {
+				continue
+			}
+			listed[*lg.LogGroupName] = true
+			logGroups = append(logGroups, lg)
+		}
+	}
+
+	return logGroups, nil
+}
+
 func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logTypes []string, startTime, endTime *time.Time, filterPattern *string, limit int32, printFunc func(log.LogEntry)) error {
+	return c.getLogs(ctx, clusterName, logTypes, startTime, endTime, filterPattern, limit, &c.progress, printFunc)
+}
+
+// getLogs is GetLogs recording its progress in progress
+func (c *EKSLogsClient) getLogs(ctx context.Context, clusterName string, logTypes []string, startTime, endTime *time.Time, filterPattern *string, limit int32, progress *progressTracker, printFunc func(log.LogEntry)) error {
 	logGroups, err := c.GetLogGroups(ctx, clusterName)
 	if err != nil {
 		return fmt.Errorf("failed to get log groups: %w\nPlease check your AWS credentials and permissions", err)
 	}
 
 	if len(logGroups) == 0 {
-		return fmt.Errorf(`no log groups found for cluster '%s'. Please ensure:
+		return fmt.Errorf(`%w for cluster '%s'. Please ensure:
   1. The cluster exists in the specified region
   2. Control plane logging is enabled for the cluster (check EKS console -> cluster -> Logging tab)
   3. You have the required permissions (logs:DescribeLogGroups, logs:FilterLogEvents, eks:DescribeCluster)
-  4. Try using the -v flag for more detailed output`, clusterName)
+  4. Try using the -v flag for more detailed output`, ErrNoLogGroups, clusterName)
+	}
+	if c.permissions.FilterLogEventsDenied && filterPattern != nil {
+		return errors.New("logs:FilterLogEvents is denied, and filter patterns can only be applied by it")
 	}
 
 	var normalizedLogTypes []string
@@ -124,12 +303,25 @@ func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logType
 	limitEnabled := limit > 0
 	var totalEvents atomic.Int32
 	var cancelOnce sync.Once
+	var firstEventOnce sync.Once
+	retrievalStart := time.Now()
+	received := newEventIDSet()
 
 	// Filter log groups by log types if specified
 	if len(logTypes) > 0 {
 		logGroups = c.filterLogGroupsByTypes(ctx, logGroups, normalizedLogTypes)
 	}
 
+	var rangeStart time.Time
+	rangeEnd := time.Now()
+	if startTime != nil {
+		rangeStart = *startTime
+	}
+	if endTime != nil {
+		rangeEnd = *endTime
+	}
+	progress.begin(logGroups, rangeStart, rangeEnd)
+
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(logGroups)) // Buffer for errors
 
@@ -144,31 +336,57 @@ func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logType
 
 			var currentLogStreamNames []string
 			var getLogsErr error
+			listStart := time.Now()
 
-			if len(logTypes) > 0 {
-				currentLogStreamNames, getLogsErr = c.getLogStreamsForTypes(ctx, lg, normalizedLogTypes)
+			switch {
+			case c.permissions.DescribeLogStreamsDenied:
+				// The whole log group is searched; the events of other log types are dropped below
+			case len(logTypes) > 0:
+				currentLogStreamNames, getLogsErr = c.getLogStreamsForTypes(ctx, lg, normalizedLogTypes, startTime)
 				if getLogsErr != nil {
 					if ctx.Err() != nil {
 						return
 					}
-					errChan <- fmt.Errorf("warning: failed to get log streams for log group '%s': %v", lg, getLogsErr)
+					errChan <- fmt.Errorf("warning: failed to get log streams for log group '%s': %w", lg, getLogsErr)
 					return
 				}
-			} else {
-				currentLogStreamNames, getLogsErr = c.listLogStreamNames(ctx, lg)
+			default:
+				currentLogStreamNames, getLogsErr = c.listLogStreamNames(ctx, lg, startTime)
 				if getLogsErr != nil {
 					if ctx.Err() != nil {
 						return
 					}
-					errChan <- fmt.Errorf("warning: failed to describe log streams for log group '%s': %v", lg, getLogsErr)
+					errChan <- fmt.Errorf("warning: failed to describe log streams for log group '%s': %w", lg, getLogsErr)
 					return
 				}
 			}
 
+			c.log().Debug("Listed log streams", "log_group", lg, "streams", len(currentLogStreamNames), "duration", time.Since(listStart).Round(time.Millisecond))
+
+			// Without a stream, FilterLogEvents would search every stream of the log group
+			if len(currentLogStreamNames) == 0 && !c.permissions.DescribeLogStreamsDenied {
+				c.log().Info("No matching log streams with events in the time range", "log_group", lg)
+				progress.finish(lg)
+				return
+			}
+
 			input := &cloudwatchlogs.FilterLogEventsInput{
 				LogGroupName: aws.String(lg),
 			}
-			if len(currentLogStreamNames) > 0 {
+			// FilterLogEvents accepts a limited number of stream names; beyond that the whole
+			// log group is searched and events of other streams are dropped here
+			var selectedStreams map[string]bool
+			var selectLogTypes bool
+			if c.permissions.DescribeLogStreamsDenied {
+				selectLogTypes = len(logTypes) > 0
+			} else if len(currentLogStreamNames) > maxFilterStreams {
+				if len(logTypes) > 0 {
+					selectedStreams = make(map[string]bool, len(currentLogStreamNames))
+					for _, name := range currentLogStreamNames {
+						selectedStreams[name] = true
+					}
+				}
+			} else {
 				input.LogStreamNames = currentLogStreamNames
 			}
 
@@ -180,9 +398,25 @@ func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logType
 			}
 			if filterPattern != nil {
 				input.FilterPattern = filterPattern
-				if c.verbose {
-					fmt.Printf("Applying filter pattern: '%s' to log group: %s\n", *filterPattern, lg)
+				c.log().Info("Applying filter pattern", "pattern", *filterPattern, "log_group", lg)
+			}
+
+			fetchPage := func(token *string, pageLimit int32) ([]cwt.FilteredLogEvent, *string, error) {
+				input.Limit = aws.Int32(pageLimit)
+				input.NextToken = token
+				resp, err := c.logsClient.FilterLogEvents(ctx, input)
+				if err != nil {
+					return nil, nil, err
 				}
+				return resp.Events, resp.NextToken, nil
+			}
+			// A single stream without a filter pattern is read with the cheaper, strictly ordered GetLogEvents
+			if filterPattern == nil && len(currentLogStreamNames) == 1 {
+				c.log().Debug("Reading a single log stream with GetLogEvents", "log_group", lg, "log_stream", currentLogStreamNames[0])
+				fetchPage = c.streamPageFetcher(ctx, lg, currentLogStreamNames[0], startTime, endTime)
+			} else if c.permissions.FilterLogEventsDenied {
+				c.log().Debug("Reading the log streams one after the other with GetLogEvents", "log_group", lg, "streams", len(currentLogStreamNames))
+				fetchPage = c.streamsPageFetcher(ctx, lg, currentLogStreamNames, startTime, endTime)
 			}
 
 			// Use pagination to retrieve all log events
@@ -195,18 +429,14 @@ func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logType
 				pageSize = limit
 			}
 
-			if c.verbose {
-				fmt.Printf("Retrieving logs from %s\n", lg)
-				fmt.Printf("Start time: %v\n", startTime)
-				fmt.Printf("End time: %v\n", endTime)
-				fmt.Printf("Limit: %d\n", limit)
-			}
+			c.log().Info("Retrieving logs", "log_group", lg, "start_time", startTime, "end_time", endTime, "limit", limit)
 
 			for {
 				if ctx.Err() != nil {
 					return
 				}
 
+				pageLimit := pageSize
 				if limitEnabled {
 					remaining := limit - totalEvents.Load()
 					if remaining <= 0 {
@@ -214,51 +444,71 @@ func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logType
 						return
 					}
 					if remaining < pageSize {
-						input.Limit = aws.Int32(remaining)
-					} else {
-						input.Limit = aws.Int32(pageSize)
+						pageLimit = remaining
 					}
-				} else {
-					input.Limit = aws.Int32(pageSize)
 				}
 
 				pageCount++
-				if nextToken != nil {
-					input.NextToken = nextToken
-				} else {
-					input.NextToken = nil
+
+				if err := c.countAPICall(); err != nil {
+					errChan <- err
+					cancelOnce.Do(cancel)
+					return
 				}
 
-				resp, err := c.logsClient.FilterLogEvents(ctx, input)
+				events, pageToken, err := fetchPage(nextToken, pageLimit)
 				if err != nil {
 					if ctx.Err() != nil {
 						return
 					}
-					if c.verbose {
-						fmt.Printf("Error details for log group '%s': %v\n", lg, err)
-						fmt.Printf("Request parameters: StartTime=%v, EndTime=%v, FilterPattern=%v\n",
-							startTime, endTime, filterPattern)
-					}
-					errChan <- fmt.Errorf("warning: failed to get logs from log group '%s': %v", lg, err)
+					c.log().Warn("Failed to get logs", "log_group", lg, "error", err,
+						"start_time", startTime, "end_time", endTime, "filter_pattern", aws.ToString(filterPattern))
+					errChan <- fmt.Errorf("warning: failed to get logs from log group '%s': %w", lg, err)
 					return
 				}
 
-				if c.verbose {
-					fmt.Printf("Page %d, Events in response: %d, HasNextToken: %v\n",
-						pageCount, len(resp.Events), resp.NextToken != nil)
-				}
+				c.counters.pages.Add(1)
 
-				for _, event := range resp.Events {
+				c.log().Debug("Received page", "log_group", lg, "page", pageCount, "events", len(events), "has_next_token", pageToken != nil)
+
+				for i, event := range events {
+					// Once cancelled, the rest of the page is dropped rather than printed
+					if ctx.Err() != nil {
+						if i > 0 && events[i-1].Timestamp != nil {
+							progress.advance(lg, time.UnixMilli(*events[i-1].Timestamp), aws.ToString(events[i-1].EventId))
+						}
+						return
+					}
+					if selectedStreams != nil && !selectedStreams[aws.ToString(event.LogStreamName)] {
+						continue
+					}
+					if selectLogTypes && !c.selectsStream(lg, aws.ToString(event.LogStreamName), normalizedLogTypes) {
+						continue
+					}
 					if event.Timestamp != nil && event.LogStreamName != nil && event.Message != nil {
 						var newTotal int32
 
 						entry := log.LogEntry{
 							Timestamp: time.UnixMilli(*event.Timestamp),
 							Level:     log.ExtractLogLevel(*event.Message),
-							Component: log.ExtractComponentFromStreamName(*event.LogStreamName),
+							Component: log.ComponentFor(lg, *event.LogStreamName),
 							Message:   *event.Message,
 							LogGroup:  lg,
 							LogStream: *event.LogStreamName,
+							EventID:   aws.ToString(event.EventId),
+						}
+						if event.IngestionTime != nil {
+							entry.IngestionTime = time.UnixMilli(*event.IngestionTime)
+						}
+
+						if err := c.countBytes(len(*event.Message)); err != nil {
+							errChan <- err
+							cancelOnce.Do(cancel)
+							return
+						}
+						if !received.add(entry) {
+							c.counters.duplicates.Add(1)
+							continue
 						}
 
 						if limitEnabled {
@@ -270,6 +520,10 @@ func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logType
 							}
 						}
 
+						firstEventOnce.Do(func() {
+							c.log().Debug("First event received", "after", time.Since(retrievalStart).Round(time.Millisecond))
+						})
+						c.counters.events.Add(1)
 						printFunc(entry) // Call the print function directly
 
 						if limitEnabled && newTotal >= limit {
@@ -279,13 +533,18 @@ func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logType
 					}
 				}
 
+				if n := len(events); n > 0 && events[n-1].Timestamp != nil {
+					progress.advance(lg, time.UnixMilli(*events[n-1].Timestamp), aws.ToString(events[n-1].EventId))
+				}
+
 				// If no more pages, break the loop
-				if resp.NextToken == nil {
+				if pageToken == nil {
+					progress.finish(lg)
 					break
 				}
 
 				// Otherwise, continue with the next page
-				nextToken = resp.NextToken
+				nextToken = pageToken
 			}
 		}(logGroup)
 	}
@@ -295,71 +554,252 @@ func (c *EKSLogsClient) GetLogs(ctx context.Context, clusterName string, logType
 
 	var collectedErrors []error
 	for err := range errChan {
+		if errors.Is(err, ErrBudgetExceeded) {
+			return err
+		}
 		if err != nil {
 			collectedErrors = append(collectedErrors, err)
 		}
 	}
 	if len(collectedErrors) > 0 {
-		return fmt.Errorf("encountered errors during log retrieval: %v", collectedErrors)
+		return fmt.Errorf("encountered errors during log retrieval: %w", errors.Join(collectedErrors...))
 	}
 
 	return nil
 }
 
+// CollectLogs retrieves log events like GetLogs and returns them sorted by timestamp
+func (c *EKSLogsClient) CollectLogs(ctx context.Context, clusterName string, logTypes []string, startTime, endTime *time.Time, filterPattern *string, limit int32) ([]log.LogEntry, error) {
+	var mu sync.Mutex
+	var entries []log.LogEntry
+
+	err := c.GetLogs(ctx, clusterName, logTypes, startTime, endTime, filterPattern, limit, func(entry log.LogEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.SortEntries(entries, log.SortOrderAsc, log.TimestampSourceEvent)
+	return entries, nil
+}
+
+// GetRecentLogs returns the n most recent log events between startTime and endTime,
+// sorted by timestamp. FilterLogEvents only reads forward in time, so the range is
+// scanned backwards from endTime in windows that double in size until n events are found.
+func (c *EKSLogsClient) GetRecentLogs(ctx context.Context, clusterName string, logTypes []string, startTime, endTime *time.Time, filterPattern *string, n int) ([]log.LogEntry, error) {
+	windowEnd := time.Now()
+	if endTime != nil {
+		windowEnd = *endTime
+	}
+	window := time.Minute
+
+	var collected []log.LogEntry
+	for len(collected) < n {
+		windowStart := windowEnd.Add(-window)
+		reachedStart := startTime != nil && !windowStart.After(*startTime)
+		if reachedStart {
+			windowStart = *startTime
+		}
+
+		c.log().Info("Searching for recent events", "from", windowStart, "to", windowEnd)
+
+		entries, err := c.CollectLogs(ctx, clusterName, logTypes, &windowStart, &windowEnd, filterPattern, 0)
+		if err != nil {
+			// Keep what was found so far when interrupted
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, err
+		}
+		collected = append(entries, collected...)
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		if reachedStart || (startTime == nil && window >= maxRecentLogsWindow) {
+			break
+		}
+
+		// Time bounds are inclusive, so continue just before the current window
+		windowEnd = windowStart.Add(-time.Millisecond)
+		window *= 2
+	}
+
+	if len(collected) > n {
+		collected = collected[len(collected)-n:]
+	}
+	return collected, nil
+}
+
 func (c *EKSLogsClient) filterLogGroupsByTypes(ctx context.Context, logGroups []string, logTypes []string) []string {
 	// For EKS, all log types are in the same log group, so no filtering needed
 	return logGroups
 }
 
-func (c *EKSLogsClient) listLogStreamNames(ctx context.Context, logGroup string) ([]string, error) {
-	var nextToken *string
+// listLogStreamNames returns the names of the log streams of a log group, leaving out
+// streams without activity since the given time (if any)
+func (c *EKSLogsClient) listLogStreamNames(ctx context.Context, logGroup string, since *time.Time) ([]string, error) {
+	streams, err := c.listLogStreams(ctx, logGroup, since)
+	if err != nil {
+		return nil, err
+	}
+	return c.activeStreamNames(logGroup, streams, since), nil
+}
+
+// activeStreamNames returns the names of streams, leaving out streams without activity since
+// the given time (if any)
+func (c *EKSLogsClient) activeStreamNames(logGroup string, streams []cwt.LogStream, since *time.Time) []string {
 	var streamNames []string
+	stale := 0
+	for _, stream := range streams {
+		if stream.LogStreamName == nil {
+			continue
+		}
+		if since != nil && isStaleStream(stream, *since) {
+			stale++
+			continue
+		}
+		streamNames = append(streamNames, *stream.LogStreamName)
+	}
+	if stale > 0 {
+		c.log().Debug("Skipped stale log streams", "log_group", logGroup, "streams", stale)
+	}
+	return streamNames
+}
+
+// listLogStreams returns the log streams of a log group, most recently active first. As the
+// streams are listed by last event time, listing stops at the first page ending with a stream
+// without activity since the given time, if any; when more than a page is needed, the streams
+// are listed by name from both ends as well, and the first listing complete is used. Without
+// a time, and in follow mode, which lists every stream so streams going idle are noticed, the
+// streams are only listed by name from both ends.
+func (c *EKSLogsClient) listLogStreams(ctx context.Context, logGroup string, since *time.Time) ([]cwt.LogStream, error) {
+	if c.streamObserver != nil {
+		since = nil
+	}
+	if streams, ok := c.streamCache.get(logGroup, time.Now(), since); ok {
+		return streams, nil
+	}
+
+	if since == nil {
+		streams, err := c.listLogStreamsFromBothEnds(ctx, logGroup)
+		if err != nil {
+			return nil, err
+		}
+		c.storeLogStreams(logGroup, streams, nil, false)
+		return streams, nil
+	}
+	listing, err := c.raceLogStreamListings(ctx, logGroup, since, func(ctx context.Context) ([]cwt.LogStream, error) {
+		return c.listLogStreamsFromBothEnds(ctx, logGroup)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.storeLogStreams(logGroup, listing.streams, since, listing.truncated)
+	return listing.streams, nil
+}
+
+// pageLogStreams lists the log streams of a log group by last event time, most recent first,
+// up to the first page ending with a stream without activity since the given time (if any),
+// which is reported as truncated. more, if not nil, is called once the first page was read
+// and more are needed.
+func (c *EKSLogsClient) pageLogStreams(ctx context.Context, logGroup string, since *time.Time, more func()) ([]cwt.LogStream, bool, error) {
+	var nextToken *string
+	var streams []cwt.LogStream
 
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		if err := c.countAPICall(); err != nil {
+			return nil, false, err
+		}
+
 		resp, err := c.logsClient.DescribeLogStreams(ctx, &cloudwatchlogs.DescribeLogStreamsInput{
 			LogGroupName: aws.String(logGroup),
-			Limit:        aws.Int32(50), // Maximum limit for CloudWatch Logs
+			Limit:        aws.Int32(maxStreamsPerPage),
 			OrderBy:      cwt.OrderBy("LastEventTime"),
 			Descending:   aws.Bool(true), // Get the most recent streams first
 			NextToken:    nextToken,
 		})
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
-		for _, stream := range resp.LogStreams {
-			if stream.LogStreamName != nil {
-				streamNames = append(streamNames, *stream.LogStreamName)
-			}
-		}
+		streams = append(streams, resp.LogStreams...)
 
 		if resp.NextToken == nil {
-			break
+			return streams, false, nil
+		}
+		// The following pages hold streams that were last active even earlier
+		if since != nil && len(resp.LogStreams) > 0 && isStaleStream(resp.LogStreams[len(resp.LogStreams)-1], *since) {
+			return streams, true, nil
 		}
 
+		if more != nil && nextToken == nil {
+			more()
+		}
 		nextToken = resp.NextToken
 	}
+}
 
-	return streamNames, nil
+// storeLogStreams caches the listing of a log group made by pageLogStreams, and reports it to
+// the stream observer
+func (c *EKSLogsClient) storeLogStreams(logGroup string, streams []cwt.LogStream, since *time.Time, truncated bool) {
+	if truncated {
+		c.log().Debug("Stopped listing log streams at the first stale stream", "log_group", logGroup, "streams", len(streams))
+		c.streamCache.put(logGroup, streams, time.Now(), since)
+	} else {
+		c.streamCache.put(logGroup, streams, time.Now(), nil)
+	}
+	if c.streamObserver != nil {
+		c.streamObserver(logGroup, streams)
+	}
 }
 
-func (c *EKSLogsClient) getLogStreamsForTypes(ctx context.Context, logGroup string, logTypes []string) ([]string, error) {
-	streamNames, err := c.listLogStreamNames(ctx, logGroup)
+func (c *EKSLogsClient) getLogStreamsForTypes(ctx context.Context, logGroup string, logTypes []string, since *time.Time) ([]string, error) {
+	var streams []cwt.LogStream
+	var err error
+	if prefixes, ok := c.typedStreamPrefixes(logGroup, logTypes); ok {
+		streams, err = c.listTypedLogStreams(ctx, logGroup, prefixes, since)
+	} else {
+		streams, err = c.listLogStreams(ctx, logGroup, since)
+	}
 	if err != nil {
 		return nil, err
 	}
+	streamNames := c.activeStreamNames(logGroup, streams, since)
 
 	var matchingStreams []string
 	for _, streamName := range streamNames {
-		streamLogType := log.ExtractLogTypeFromStreamName(streamName)
-		if contains(logTypes, streamLogType) {
+		streamLogType := log.LogTypeFor(logGroup, streamName)
+		switch {
+		case contains(logTypes, streamLogType):
 			matchingStreams = append(matchingStreams, streamName)
+		case streamLogType != "":
+		case c.includeUnknownStreams:
+			c.log().Info("Reading a log stream of an unknown component", "log_group", logGroup, "stream", streamName)
+			matchingStreams = append(matchingStreams, streamName)
+		default:
+			c.log().Info("Skipping a log stream of an unknown component (see --include-unknown-streams)", "log_group", logGroup, "stream", streamName)
 		}
 	}
 
 	return matchingStreams, nil
 }
 
+// selectsStream reports whether a log stream is of one of logTypes, or of an unknown
+// component read with SetIncludeUnknownStreams, like getLogStreamsForTypes, for the events
+// of a log group searched without listing its streams
+func (c *EKSLogsClient) selectsStream(logGroup, streamName string, logTypes []string) bool {
+	streamLogType := log.LogTypeFor(logGroup, streamName)
+	return contains(logTypes, streamLogType) || (streamLogType == "" && c.includeUnknownStreams)
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if s == item {
@@ -369,31 +809,58 @@ func contains(slice []string, item string) bool {
 	return false
 }
 
-func (c *EKSLogsClient) TailLogs(ctx context.Context, clusterName string, logTypes []string, filterPattern *string, interval time.Duration, messageOnly bool, colorConfig *log.ColorConfig) error {
+func (c *EKSLogsClient) TailLogs(ctx context.Context, clusterName string, logTypes []string, filterPattern *string, interval time.Duration, printFunc func(log.LogEntry)) error {
+	return c.TailChannels(ctx, clusterName, []TailChannel{{LogTypes: logTypes, FilterPattern: filterPattern}}, interval, printFunc)
+}
+
+// TailChannel is a query followed by TailChannels: the events of its log types matching its
+// filter pattern
+type TailChannel struct {
+	// Label is set on the entries of the channel, to tell the channels apart in the output
+	Label         string
+	LogTypes      []string
+	FilterPattern *string
+}
+
+// TailChannels follows several queries of a cluster in one session: each poll runs the query
+// of every channel from a cursor of its own, and passes its new entries to printFunc with the
+// label of the channel. An event matching several channels is printed once per channel.
+func (c *EKSLogsClient) TailChannels(ctx context.Context, clusterName string, channels []TailChannel, interval time.Duration, printFunc func(log.LogEntry)) error {
 	logGroups, err := c.GetLogGroups(ctx, clusterName)
 	if err != nil {
 		return fmt.Errorf("failed to get log groups: %w\nPlease check your AWS credentials and permissions", err)
 	}
 
 	if len(logGroups) == 0 {
-		return fmt.Errorf(`no log groups found for cluster '%s'. Please ensure:
+		return fmt.Errorf(`%w for cluster '%s'. Please ensure:
   1. The cluster exists in the specified region
   2. Control plane logging is enabled for the cluster (check EKS console -> cluster -> Logging tab)
   3. You have the required permissions (logs:DescribeLogGroups, logs:FilterLogEvents, eks:DescribeCluster)
-  4. Try using the -v flag for more detailed output`, clusterName)
+  4. Try using the -v flag for more detailed output`, ErrNoLogGroups, clusterName)
 	}
 
-	lastTimestamp := time.Now().Add(-1 * time.Minute) // Start from 1 minute ago
-	var mu sync.Mutex                                 // Mutex to protect lastTimestamp and prevent duplicate prints
-	seenEntries := make(map[string]time.Time)         // Track seen log entries to prevent duplicates
-
-	if c.verbose {
-		fmt.Printf("Starting tail mode with interval: %v\n", interval)
-		fmt.Printf("Initial start time: %v\n", lastTimestamp)
+	initial := time.Now().Add(-1 * time.Minute) // Start from 1 minute ago
+	cursors := make([]*tailCursor, len(channels))
+	for i := range channels {
+		cursors[i] = newTailCursor(initial)
 	}
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	var mu sync.Mutex // Mutex to protect the cursors and prevent duplicate prints
+
+	c.log().Info("Starting tail mode", "interval", interval, "max_interval", c.follow.MaxInterval, "start_time", initial, "channels", len(channels))
+
+	pollInterval := newAdaptiveInterval(interval, c.follow.MaxInterval)
+	status := newFollowStatus(c.follow.Heartbeat, time.Now())
+	silence := newSilenceWatch(c.follow.SilenceAlert, watchedLogTypes(channels), time.Now())
+	// A new stream may have been missed while the stream listing was cached, so read it again from its creation
+	defer c.watchStreams(func(created time.Time) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, cursor := range cursors {
+			cursor.rewind(created)
+		}
+	})()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 
 	for {
 		select {
@@ -403,56 +870,92 @@ func (c *EKSLogsClient) TailLogs(ctx context.Context, clusterName string, logTyp
 				return nil
 			}
 			return ctx.Err()
-		case <-ticker.C:
+		case <-timer.C:
 			now := time.Now()
+			newEvents := 0
+
+			var pollErr error
+			for i, channel := range channels {
+				cursor := cursors[i]
+				printAndTrackTimestamp := func(entry log.LogEntry) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					if cursor.accept(entry) {
+						newEvents++
+						silence.observe(entry, time.Now())
+						entry.Label = channel.Label
+						printFunc(entry)
+					}
+				}
 
-			printAndTrackTimestamp := func(entry log.LogEntry) {
 				mu.Lock()
-				defer mu.Unlock()
-
-				// Create a unique key for this log entry to prevent duplicates
-				entryKey := fmt.Sprintf("%d-%s-%s", entry.Timestamp.UnixNano(), entry.LogStream, entry.Message)
-
-				// Skip if we've already seen this entry
-				if _, exists := seenEntries[entryKey]; exists {
-					return
-				}
+				start := cursor.start()
+				mu.Unlock()
 
-				// Only print entries newer than or equal to our last timestamp
-				if entry.Timestamp.Before(lastTimestamp) {
-					return
+				// The range since the previous poll is bounded, so it is read without a limit
+				err := c.GetLogs(ctx, clusterName, channel.LogTypes, &start, &now, channel.FilterPattern, 0, printAndTrackTimestamp)
+				if err == nil {
+					continue
 				}
-
-				log.PrintLog(entry, messageOnly, colorConfig)
-				seenEntries[entryKey] = entry.Timestamp
-				lastTimestamp = entry.Timestamp
-			}
-
-			mu.Lock()
-			start := lastTimestamp
-			mu.Unlock()
-
-			err := c.GetLogs(ctx, clusterName, logTypes, &start, &now, filterPattern, 100, printAndTrackTimestamp)
-			if err != nil {
 				// If context was cancelled during GetLogs execution, exit gracefully
 				if ctx.Err() == context.Canceled {
 					return nil
 				}
-				color.Red("Log retrieval error: %v", err)
-				continue
+				if errors.Is(err, ErrBudgetExceeded) {
+					return err
+				}
+				if IsSSOSessionExpired(err) {
+					c.log().Error("AWS SSO session expired, waiting for a new login", "run", SSOLoginCommand())
+				} else {
+					attrs := []any{"error", err}
+					if channel.Label != "" {
+						attrs = append(attrs, "channel", channel.Label)
+					}
+					c.log().Error("Log retrieval error", attrs...)
+				}
+				// Expired credentials may have been renewed outside the process since they were loaded
+				if IsAuthError(err) {
+					if refreshErr := c.refreshCredentials(ctx); refreshErr != nil {
+						c.log().Warn("Failed to reload AWS credentials", "error", refreshErr)
+					}
+				}
+				pollErr = err
 			}
 
-			// Clean up old entries from the seen map to prevent memory growth
 			mu.Lock()
-			if len(seenEntries) > 2000 {
-				cutoff := lastTimestamp.Add(-2 * time.Minute)
-				for key, ts := range seenEntries {
-					if ts.Before(cutoff) {
-						delete(seenEntries, key)
-					}
+			delay := pollInterval.next(newEvents)
+			notices := status.update(time.Now(), newEvents, pollErr)
+			silent := silence.check(time.Now())
+			mu.Unlock()
+			for _, notice := range notices {
+				c.notify(notice)
+			}
+			if len(silent) > 0 {
+				silenceErr := silenceError(silent, c.follow.SilenceAlert)
+				if c.follow.ExitOnSilence {
+					return silenceErr
 				}
+				c.notify(silenceErr.Error())
+			}
+			timer.Reset(delay)
+		}
+	}
+}
+
+// watchedLogTypes returns the normalized log types of the channels watched for silence:
+// none, watching every log type seen, when a channel reads all log types
+func watchedLogTypes(channels []TailChannel) []string {
+	var logTypes []string
+	for _, channel := range channels {
+		if len(channel.LogTypes) == 0 {
+			return nil
+		}
+		for _, logType := range channel.LogTypes {
+			if normalized := log.NormalizeLogType(logType); !contains(logTypes, normalized) {
+				logTypes = append(logTypes, normalized)
 			}
-			mu.Unlock()
 		}
 	}
+	return logTypes
 }
@@ -1,10 +1,36 @@
 package aws
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctt "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/kzcat/ekslogs/pkg/log"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,3 +63,2005 @@ func TestTailLogsHandlesCancellation(t *testing.T) {
 	// Verify that no error is returned when context is cancelled
 	assert.NoError(t, err, "Should return nil when context is canceled")
 }
+
+// mockLogsClient serves a fixed set of log events from a single log group
+type mockLogsClient struct {
+	logGroup    cwt.LogGroup
+	events      []cwt.FilteredLogEvent
+	pageSize    int
+	filterCalls int
+	getCalls    int
+}
+
+func (m *mockLogsClient) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	logGroup := m.logGroup
+	logGroup.LogGroupName = aws.String("/aws/eks/test/cluster")
+	return &cloudwatchlogs.DescribeLogGroupsOutput{LogGroups: []cwt.LogGroup{logGroup}}, nil
+}
+
+func (m *mockLogsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	// Two streams, so that log groups are searched with FilterLogEvents
+	return &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: []cwt.LogStream{
+		{LogStreamName: aws.String("kube-apiserver-123")},
+		{LogStreamName: aws.String("kube-apiserver-audit-123")},
+	}}, nil
+}
+
+func (m *mockLogsClient) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	m.filterCalls++
+	var events []cwt.FilteredLogEvent
+	for _, event := range m.events {
+		if params.StartTime != nil && *event.Timestamp < *params.StartTime {
+			continue
+		}
+		if params.EndTime != nil && *event.Timestamp > *params.EndTime {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	// Paginate when a page size is set, using the event offset as the token
+	if m.pageSize > 0 {
+		offset := 0
+		if params.NextToken != nil {
+			offset, _ = strconv.Atoi(*params.NextToken)
+		}
+		events = events[offset:]
+		if len(events) > m.pageSize {
+			return &cloudwatchlogs.FilterLogEventsOutput{
+				Events:    events[:m.pageSize],
+				NextToken: aws.String(strconv.Itoa(offset + m.pageSize)),
+			}, nil
+		}
+	}
+	return &cloudwatchlogs.FilterLogEventsOutput{Events: events}, nil
+}
+
+func (m *mockLogsClient) GetLogEvents(ctx context.Context, params *cloudwatchlogs.GetLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	m.getCalls++
+	var events []cwt.OutputLogEvent
+	for _, event := range m.events {
+		if aws.ToString(event.LogStreamName) != aws.ToString(params.LogStreamName) {
+			continue
+		}
+		if params.StartTime != nil && *event.Timestamp < *params.StartTime {
+			continue
+		}
+		// The end time is exclusive for GetLogEvents
+		if params.EndTime != nil && *event.Timestamp >= *params.EndTime {
+			continue
+		}
+		events = append(events, cwt.OutputLogEvent{Timestamp: event.Timestamp, Message: event.Message, IngestionTime: event.IngestionTime})
+	}
+
+	// The forward token is the event offset; the end is signaled by returning the same token
+	offset := 0
+	if params.NextToken != nil {
+		offset, _ = strconv.Atoi(*params.NextToken)
+	}
+	events = events[offset:]
+	if m.pageSize > 0 && len(events) > m.pageSize {
+		events = events[:m.pageSize]
+	}
+	return &cloudwatchlogs.GetLogEventsOutput{
+		Events:           events,
+		NextForwardToken: aws.String(strconv.Itoa(offset + len(events))),
+	}, nil
+}
+
+// TestGetRecentLogs tests that the most recent events are found by scanning backwards
+func TestGetRecentLogs(t *testing.T) {
+	end := time.Date(2024, 7, 19, 12, 0, 0, 0, time.UTC)
+	start := end.Add(-time.Hour)
+
+	mock := &mockLogsClient{}
+	for _, offset := range []time.Duration{-50 * time.Minute, -20 * time.Minute, -10 * time.Minute, -90 * time.Second, -30 * time.Second} {
+		mock.events = append(mock.events, cwt.FilteredLogEvent{
+			Timestamp:     aws.Int64(end.Add(offset).UnixMilli()),
+			LogStreamName: aws.String("kube-apiserver-123"),
+			Message:       aws.String(offset.String()),
+		})
+	}
+	client := &EKSLogsClient{logsClient: mock}
+
+	entries, err := client.GetRecentLogs(context.Background(), "test", nil, &start, &end, nil, 3)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "-10m0s", entries[0].Message)
+	assert.Equal(t, "-30s", entries[2].Message)
+
+	// The whole range is scanned when there are fewer events than requested
+	mock.filterCalls = 0
+	entries, err = client.GetRecentLogs(context.Background(), "test", nil, &start, &end, nil, 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 5)
+	assert.Equal(t, "-50m0s", entries[0].Message)
+	assert.Equal(t, 6, mock.filterCalls) // 1+2+4+8+16+32 minute windows
+}
+
+// mockEvents creates n events one second apart ending at end
+func mockEvents(end time.Time, n int, message string) []cwt.FilteredLogEvent {
+	var events []cwt.FilteredLogEvent
+	for i := n - 1; i >= 0; i-- {
+		events = append(events, cwt.FilteredLogEvent{
+			Timestamp:     aws.Int64(end.Add(-time.Duration(i) * time.Second).UnixMilli()),
+			LogStreamName: aws.String("kube-apiserver-123"),
+			Message:       aws.String(message),
+		})
+	}
+	return events
+}
+
+// TestBudget tests that retrieval stops once the byte or API call budget is exhausted
+func TestBudget(t *testing.T) {
+	end := time.Now()
+	start := end.Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		budget   Budget
+		expected int
+	}{
+		{name: "no budget", budget: Budget{}, expected: 10},
+		{name: "api calls", budget: Budget{MaxAPICalls: 3}, expected: 4}, // DescribeLogGroups, DescribeLogStreams and one page
+		{name: "bytes", budget: Budget{MaxBytes: 25}, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockLogsClient{events: mockEvents(end, 10, "0123456789"), pageSize: 4}
+			client := &EKSLogsClient{logsClient: mock}
+			client.SetBudget(tt.budget)
+
+			printed := 0
+			err := client.GetLogs(context.Background(), "test", nil, &start, &end, nil, 0, func(entry log.LogEntry) {
+				printed++
+			})
+			if tt.budget == (Budget{}) {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, ErrBudgetExceeded))
+			}
+			assert.Equal(t, tt.expected, printed)
+		})
+	}
+}
+
+// TestEstimateRangeBytes tests the estimate of the log volume in a time range
+func TestEstimateRangeBytes(t *testing.T) {
+	now := time.Now()
+	mock := &mockLogsClient{logGroup: cwt.LogGroup{
+		StoredBytes:     aws.Int64(30 * 1000),
+		CreationTime:    aws.Int64(now.AddDate(0, 0, -100).UnixMilli()),
+		RetentionInDays: aws.Int32(30),
+	}}
+	client := &EKSLogsClient{logsClient: mock}
+
+	// One day out of 30 retained days
+	estimate, err := client.EstimateRangeBytes(context.Background(), "test", now.AddDate(0, 0, -1), now)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1000, estimate, 1)
+
+	// Ranges before the retention cutoff hold no data
+	estimate, err = client.EstimateRangeBytes(context.Background(), "test", now.AddDate(0, 0, -60), now.AddDate(0, 0, -40))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), estimate)
+}
+
+// TestStats tests the statistics collected during a retrieval
+func TestStats(t *testing.T) {
+	end := time.Now()
+	start := end.Add(-time.Hour)
+
+	mock := &mockLogsClient{events: mockEvents(end, 10, "0123456789"), pageSize: 4}
+	client := &EKSLogsClient{logsClient: mock, started: time.Now()}
+
+	err := client.GetLogs(context.Background(), "test", nil, &start, &end, nil, 0, func(entry log.LogEntry) {})
+	assert.NoError(t, err)
+
+	stats := client.Stats()
+	assert.Equal(t, int64(10), stats.Events)
+	assert.Equal(t, int64(3), stats.Pages)
+	assert.Equal(t, int64(5), stats.APICalls)
+	assert.Equal(t, int64(100), stats.Bytes)
+
+	// Retries and throttles are counted by the retryer
+	retryer := newCountingRetryer(&client.counters)
+	_, _ = retryer.RetryDelay(1, &smithy.GenericAPIError{Code: "ThrottlingException"})
+	_, _ = retryer.RetryDelay(1, errors.New("connection reset"))
+	stats = client.Stats()
+	assert.Equal(t, int64(2), stats.Retries)
+	assert.Equal(t, int64(1), stats.Throttles)
+}
+
+// TestDebugOutput tests the debug log lines written for AWS API calls
+func TestDebugOutput(t *testing.T) {
+	client := &EKSLogsClient{}
+
+	// Without a logger the diagnostics are discarded
+	client.logAPICall(context.Background(), nil, middleware.Metadata{}, time.Second, nil)
+
+	var buf bytes.Buffer
+	client.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	params := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:   aws.String("/aws/eks/test/cluster"),
+		LogStreamNames: []string{"a", "b"},
+	}
+	client.logAPICall(context.Background(), params, middleware.Metadata{}, 1234*time.Millisecond, nil)
+	assert.Contains(t, buf.String(), "log_group=/aws/eks/test/cluster streams=2 paginated=false duration=1.234s attempts=1")
+
+	buf.Reset()
+	client.logAPICall(context.Background(), &cloudwatchlogs.DescribeLogStreamsInput{}, middleware.Metadata{}, time.Millisecond, errors.New("denied"))
+	assert.Contains(t, buf.String(), "error=denied")
+
+	// Debug lines are skipped at higher levels
+	buf.Reset()
+	client.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+	client.logAPICall(context.Background(), params, middleware.Metadata{}, time.Second, nil)
+	assert.Empty(t, buf.String())
+}
+
+// TestProgress tests the time range coverage tracked per log group
+func TestProgress(t *testing.T) {
+	client := &EKSLogsClient{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+	client.progress.begin([]string{"/aws/eks/test/b", "/aws/eks/test/a"}, start, end)
+
+	client.progress.advance("/aws/eks/test/a", start.Add(time.Hour), "38012345")
+	// Older timestamps and unknown log groups are ignored
+	client.progress.advance("/aws/eks/test/a", start, "38012344")
+	client.progress.advance("/aws/eks/test/c", start.Add(time.Hour), "")
+	client.progress.finish("/aws/eks/test/b")
+	client.counters.events.Add(42)
+
+	progress := client.Progress()
+	assert.Equal(t, int64(42), progress.Events)
+	assert.Len(t, progress.Groups, 2)
+	assert.Equal(t, "/aws/eks/test/a", progress.Groups[0].LogGroup)
+	assert.Equal(t, start.Add(time.Hour), progress.Groups[0].Reached)
+	assert.Equal(t, "38012345", progress.Groups[0].LastEventID)
+	assert.InDelta(t, 0.25, progress.Groups[0].Coverage, 0.001)
+	assert.False(t, progress.Groups[0].Done)
+	assert.Equal(t, 1.0, progress.Groups[1].Coverage)
+	assert.True(t, progress.Groups[1].Done)
+}
+
+// TestProgressResumeFrom tests the resume point of an interrupted retrieval
+func TestProgressResumeFrom(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(4 * time.Hour)
+
+	progress := Progress{
+		Start: start,
+		End:   end,
+		Groups: []GroupProgress{
+			{LogGroup: "a", Reached: start.Add(2 * time.Hour)},
+			{LogGroup: "b", Reached: start.Add(time.Hour)},
+			{LogGroup: "c", Done: true},
+		},
+	}
+	assert.Equal(t, start.Add(time.Hour), progress.ResumeFrom())
+
+	// A group that has not read anything resumes from the start
+	progress.Groups = append(progress.Groups, GroupProgress{LogGroup: "d"})
+	assert.Equal(t, start, progress.ResumeFrom())
+
+	// A complete retrieval resumes from the end
+	progress.Groups = []GroupProgress{{LogGroup: "a", Done: true}}
+	assert.Equal(t, end, progress.ResumeFrom())
+}
+
+// TestCallTimeout tests that the call timeout middleware bounds each API call
+func TestCallTimeout(t *testing.T) {
+	client := &EKSLogsClient{}
+	client.SetCallTimeout(10 * time.Millisecond)
+
+	stack := middleware.NewStack("test", func() interface{} { return nil })
+	assert.NoError(t, client.addTimeoutMiddleware(stack))
+
+	blocking := middleware.HandlerFunc(func(ctx context.Context, input interface{}) (interface{}, middleware.Metadata, error) {
+		<-ctx.Done()
+		return nil, middleware.Metadata{}, ctx.Err()
+	})
+
+	_, _, err := stack.HandleMiddleware(context.Background(), &cloudwatchlogs.FilterLogEventsInput{}, blocking)
+	assert.ErrorIs(t, err, ErrCallTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// The expiry of the caller's context is not reported as a call timeout
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	client.SetCallTimeout(time.Second)
+	_, _, err = stack.HandleMiddleware(ctx, &cloudwatchlogs.FilterLogEventsInput{}, blocking)
+	assert.NotErrorIs(t, err, ErrCallTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestRecentKeys tests that the dedup set forgets the oldest keys beyond its capacity
+func TestRecentKeys(t *testing.T) {
+	keys := newRecentKeys[string](3)
+	assert.True(t, keys.add("a"))
+	assert.True(t, keys.add("b"))
+	assert.False(t, keys.add("a"))
+	assert.True(t, keys.add("c"))
+	assert.True(t, keys.add("d"))
+	assert.Equal(t, 3, keys.len())
+
+	// "a" was evicted to make room for "d"
+	assert.True(t, keys.add("a"))
+	assert.False(t, keys.add("c"))
+	assert.Equal(t, 3, keys.len())
+}
+
+// TestGetLogsDedup tests that events received twice are printed once, by event ID
+func TestGetLogsDedup(t *testing.T) {
+	now := time.Now()
+	event := func(id, message string) cwt.FilteredLogEvent {
+		return cwt.FilteredLogEvent{
+			Timestamp:     aws.Int64(now.UnixMilli()),
+			LogStreamName: aws.String("kube-apiserver-123"),
+			Message:       aws.String(message),
+			EventId:       aws.String(id),
+		}
+	}
+	mock := &mockLogsClient{events: []cwt.FilteredLogEvent{
+		event("1", "a"), event("2", "b"), event("1", "a"),
+		// Identical messages without an event ID are distinct events
+		event("", "c"), event("", "c"),
+	}}
+	client := &EKSLogsClient{logsClient: mock}
+
+	var messages []string
+	start := now.Add(-time.Minute)
+	err := client.GetLogs(context.Background(), "test", nil, &start, &now, nil, 0, func(entry log.LogEntry) {
+		messages = append(messages, entry.Message)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "c"}, messages)
+	assert.Equal(t, int64(1), client.Stats().Duplicates)
+	assert.Equal(t, int64(4), client.Stats().Events)
+}
+
+// TestTailCursor tests the per-stream positions of follow mode
+func TestTailCursor(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(stream string, offset time.Duration, message string) log.LogEntry {
+		return log.LogEntry{Timestamp: base.Add(offset), LogStream: stream, Message: message}
+	}
+
+	cursor := newTailCursor(base)
+	assert.Equal(t, base, cursor.start())
+
+	assert.True(t, cursor.accept(entry("a", 10*time.Second, "a1")))
+	// A stream whose clock lags behind still gets its events printed
+	assert.True(t, cursor.accept(entry("b", 5*time.Second, "b1")))
+	assert.Equal(t, base.Add(5*time.Second), cursor.start())
+
+	// Events polled again are skipped, including older events of a stream
+	assert.False(t, cursor.accept(entry("a", 10*time.Second, "a1")))
+	assert.False(t, cursor.accept(entry("a", 8*time.Second, "a0")))
+	assert.False(t, cursor.accept(entry("c", -time.Second, "before start")))
+
+	// Identical timestamps with different messages are distinct events
+	assert.True(t, cursor.accept(entry("a", 10*time.Second, "a2")))
+
+	// A stream too far behind no longer holds back the poll start
+	assert.True(t, cursor.accept(entry("a", time.Minute, "a3")))
+	assert.Equal(t, base.Add(time.Minute), cursor.start())
+}
+
+// TestTailLogs tests that follow mode prints each event once across overlapping polls
+func TestTailLogs(t *testing.T) {
+	now := time.Now()
+	mock := &mockLogsClient{}
+	for i, offset := range []time.Duration{-30 * time.Second, -20 * time.Second, -20 * time.Second, -time.Second} {
+		mock.events = append(mock.events, cwt.FilteredLogEvent{
+			Timestamp:     aws.Int64(now.Add(offset).UnixMilli()),
+			LogStreamName: aws.String("kube-apiserver-123"),
+			Message:       aws.String(fmt.Sprintf("event %d", i)),
+		})
+	}
+	client := &EKSLogsClient{logsClient: mock}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var messages []string
+	err := client.TailLogs(ctx, "test", nil, nil, 10*time.Millisecond, func(entry log.LogEntry) {
+		messages = append(messages, entry.Message)
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Greater(t, mock.filterCalls, 1)
+	assert.Equal(t, []string{"event 0", "event 1", "event 2", "event 3"}, messages)
+}
+
+// TestTailChannels tests that each channel of follow mode prints the events it matches once,
+// labeled with its name
+func TestTailChannels(t *testing.T) {
+	now := time.Now()
+	mock := &mockLogsClient{}
+	for i, offset := range []time.Duration{-30 * time.Second, -20 * time.Second, -time.Second} {
+		mock.events = append(mock.events, cwt.FilteredLogEvent{
+			Timestamp:     aws.Int64(now.Add(offset).UnixMilli()),
+			LogStreamName: aws.String("kube-apiserver-123"),
+			Message:       aws.String(fmt.Sprintf("event %d", i)),
+		})
+	}
+	client := &EKSLogsClient{logsClient: mock}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	channels := []TailChannel{
+		{Label: "api-errors", FilterPattern: aws.String("ERROR")},
+		{Label: "auth-failures", FilterPattern: aws.String("Unauthorized")},
+	}
+	messages := make(map[string][]string)
+	err := client.TailChannels(ctx, "test", channels, 10*time.Millisecond, func(entry log.LogEntry) {
+		messages[entry.Label] = append(messages[entry.Label], entry.Message)
+	})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, map[string][]string{
+		"api-errors":    {"event 0", "event 1", "event 2"},
+		"auth-failures": {"event 0", "event 1", "event 2"},
+	}, messages)
+}
+
+// TestWatchedLogTypes tests the log types watched for silence when following several channels
+func TestWatchedLogTypes(t *testing.T) {
+	assert.Equal(t, []string{"api", "authenticator"}, watchedLogTypes([]TailChannel{
+		{LogTypes: []string{"api"}},
+		{LogTypes: []string{"auth", "api"}},
+	}))
+	assert.Nil(t, watchedLogTypes([]TailChannel{{LogTypes: []string{"api"}}, {}}))
+}
+
+// TestSplitRange tests the division of a time range into sub-ranges
+func TestSplitRange(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ranges := splitRange(start, start.Add(3*time.Hour), 3)
+	assert.Equal(t, [][2]time.Time{
+		{start, start.Add(time.Hour - time.Millisecond)},
+		{start.Add(time.Hour), start.Add(2*time.Hour - time.Millisecond)},
+		{start.Add(2 * time.Hour), start.Add(3 * time.Hour)},
+	}, ranges)
+
+	// Sub-ranges are at least a minute long
+	assert.Len(t, splitRange(start, start.Add(150*time.Second), 8), 2)
+	assert.Len(t, splitRange(start, start.Add(time.Second), 8), 1)
+}
+
+// slowLogsClient delays the FilterLogEvents calls starting at a time, and serializes the
+// calls of the mock
+type slowLogsClient struct {
+	*mockLogsClient
+	mu        sync.Mutex
+	slowStart int64
+}
+
+func (s *slowLogsClient) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	if aws.ToInt64(params.StartTime) == s.slowStart {
+		time.Sleep(50 * time.Millisecond)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mockLogsClient.FilterLogEvents(ctx, params, optFns...)
+}
+
+// TestGetLogsSplit tests that the sub-ranges of a split retrieval are printed in order, each
+// event once, though the first sub-range completes last
+func TestGetLogsSplit(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock := &mockLogsClient{pageSize: 2}
+	var want []string
+	for i := range 12 {
+		// Events on the boundaries of the sub-ranges are read by one of them only
+		timestamp := start.Add(time.Duration(i) * 20 * time.Minute)
+		message := fmt.Sprintf("event %d", i)
+		mock.events = append(mock.events, cwt.FilteredLogEvent{
+			Timestamp:     aws.Int64(timestamp.UnixMilli()),
+			LogStreamName: aws.String("kube-apiserver-123"),
+			Message:       aws.String(message),
+			EventId:       aws.String(message),
+		})
+		want = append(want, message)
+	}
+	client := &EKSLogsClient{logsClient: &slowLogsClient{mockLogsClient: mock, slowStart: start.UnixMilli()}}
+
+	var messages []string
+	end := start.Add(4 * time.Hour)
+	err := client.GetLogsSplit(context.Background(), "test", nil, start, end, nil, 0, 4, func(entry log.LogEntry) {
+		messages = append(messages, entry.Message)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, want, messages)
+	progress := client.Progress()
+	assert.Len(t, progress.Groups, 1)
+	assert.True(t, progress.Groups[0].Done)
+
+	// The limit keeps the first events of the range
+	messages = nil
+	err = client.GetLogsSplit(context.Background(), "test", nil, start, end, nil, 5, 4, func(entry log.LogEntry) {
+		messages = append(messages, entry.Message)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, want[:5], messages)
+}
+
+// endlessLogsClient serves log groups of endless full pages of events, to interrupt a
+// retrieval in the middle of it. With block, FilterLogEvents waits for the cancellation of
+// its context instead.
+type endlessLogsClient struct {
+	*mockLogsClient
+	groups int
+	block  bool
+}
+
+func (e *endlessLogsClient) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	var logGroups []cwt.LogGroup
+	for i := range e.groups {
+		logGroups = append(logGroups, cwt.LogGroup{LogGroupName: aws.String(fmt.Sprintf("/aws/eks/test/cluster-%d", i))})
+	}
+	return &cloudwatchlogs.DescribeLogGroupsOutput{LogGroups: logGroups}, nil
+}
+
+func (e *endlessLogsClient) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	if e.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	offset := 0
+	if params.NextToken != nil {
+		offset, _ = strconv.Atoi(*params.NextToken)
+	}
+	events := make([]cwt.FilteredLogEvent, aws.ToInt32(params.Limit))
+	for i := range events {
+		n := offset + i
+		events[i] = cwt.FilteredLogEvent{
+			Timestamp:     aws.Int64(aws.ToInt64(params.StartTime) + int64(n)),
+			LogStreamName: aws.String("kube-apiserver-123"),
+			Message:       aws.String(fmt.Sprintf("event %d", n)),
+			EventId:       aws.String(strconv.Itoa(n)),
+		}
+	}
+	return &cloudwatchlogs.FilterLogEventsOutput{
+		Events:    events,
+		NextToken: aws.String(strconv.Itoa(offset + len(events))),
+	}, nil
+}
+
+// TestGetLogsCancel tests that every log group goroutine stops printing as soon as the
+// retrieval is cancelled, without printing the rest of the page it holds
+func TestGetLogsCancel(t *testing.T) {
+	const groups = 4
+	client := &EKSLogsClient{logsClient: &endlessLogsClient{mockLogsClient: &mockLogsClient{}, groups: groups}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	printed := 0
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	err := client.GetLogs(ctx, "test", nil, &start, &end, nil, 0, func(entry log.LogEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+		printed++
+		if printed == 10 {
+			cancel()
+		}
+	})
+	assert.NoError(t, err)
+	// A goroutine may have checked the context just before the cancellation
+	assert.GreaterOrEqual(t, printed, 10)
+	assert.Less(t, printed, 10+groups)
+
+	// The progress stops at the events read, so a resumed retrieval reads the rest of the page
+	progress := client.Progress()
+	assert.Len(t, progress.Groups, groups)
+	for _, group := range progress.Groups {
+		assert.False(t, group.Done, group.LogGroup)
+		assert.Less(t, group.Reached.Sub(start), time.Duration(10+groups)*time.Millisecond, group.LogGroup)
+	}
+}
+
+// TestGetLogsCancelInFlight tests that a cancellation interrupts the API calls in flight
+func TestGetLogsCancelInFlight(t *testing.T) {
+	client := &EKSLogsClient{logsClient: &endlessLogsClient{mockLogsClient: &mockLogsClient{}, groups: 2, block: true}}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	start := started.Add(-time.Hour)
+	err := client.GetLogs(ctx, "test", nil, &start, nil, nil, 0, func(log.LogEntry) {
+		t.Error("no event is printed")
+	})
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+// TestGetLogsSplitCancel tests that the events held by a split retrieval are not printed
+// after a cancellation
+func TestGetLogsSplitCancel(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mock := &mockLogsClient{pageSize: 2}
+	for i := range 12 {
+		message := fmt.Sprintf("event %d", i)
+		mock.events = append(mock.events, cwt.FilteredLogEvent{
+			Timestamp:     aws.Int64(start.Add(time.Duration(i) * 20 * time.Minute).UnixMilli()),
+			LogStreamName: aws.String("kube-apiserver-123"),
+			Message:       aws.String(message),
+			EventId:       aws.String(message),
+		})
+	}
+	// The later sub-ranges are held while the first one is slow
+	client := &EKSLogsClient{logsClient: &slowLogsClient{mockLogsClient: mock, slowStart: start.UnixMilli()}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var messages []string
+	err := client.GetLogsSplit(ctx, "test", nil, start, start.Add(4*time.Hour), nil, 0, 4, func(entry log.LogEntry) {
+		messages = append(messages, entry.Message)
+		if len(messages) == 5 {
+			cancel()
+		}
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"event 0", "event 1", "event 2", "event 3", "event 4"}, messages)
+}
+
+// TestEventKey tests that duplicates are identified by CloudWatch event ID when available
+func TestEventKey(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := log.LogEntry{Timestamp: ts, LogGroup: "g", LogStream: "s", Message: "same", EventID: "1"}
+	second := log.LogEntry{Timestamp: ts, LogGroup: "g", LogStream: "s", Message: "same", EventID: "2"}
+	assert.NotEqual(t, eventKey(first), eventKey(second))
+
+	// Repeated identical messages are all printed in follow mode
+	cursor := newTailCursor(ts)
+	assert.True(t, cursor.accept(first))
+	assert.True(t, cursor.accept(second))
+	assert.False(t, cursor.accept(second))
+
+	// Without event IDs the composite key is used
+	first.EventID, second.EventID = "", ""
+	assert.Equal(t, eventKey(first), eventKey(second))
+}
+
+// TestAdaptiveInterval tests the back-off of the follow mode poll interval
+func TestAdaptiveInterval(t *testing.T) {
+	interval := newAdaptiveInterval(time.Second, 10*time.Second)
+	assert.Equal(t, 2*time.Second, interval.next(0))
+	assert.Equal(t, 4*time.Second, interval.next(0))
+	assert.Equal(t, 8*time.Second, interval.next(0))
+	assert.Equal(t, 10*time.Second, interval.next(0))
+	assert.Equal(t, 10*time.Second, interval.next(0))
+
+	// A few events tighten the interval, a burst resets it
+	assert.Equal(t, 5*time.Second, interval.next(3))
+	assert.Equal(t, time.Second, interval.next(highVolumeEvents))
+	assert.Equal(t, time.Second, interval.next(1))
+
+	// Without a longer maximum the interval is fixed
+	fixed := newAdaptiveInterval(time.Second, 0)
+	assert.Equal(t, time.Second, fixed.next(0))
+}
+
+// TestFollowStatus tests the heartbeat and connection status notices of follow mode
+func TestFollowStatus(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	status := newFollowStatus(5*time.Minute, start)
+
+	assert.Empty(t, status.update(start.Add(time.Minute), 0, nil))
+	assert.Equal(t, []string{"no new events for 5m0s, still following"}, status.update(start.Add(5*time.Minute), 0, nil))
+	// The heartbeat repeats once per period
+	assert.Empty(t, status.update(start.Add(6*time.Minute), 0, nil))
+	assert.Equal(t, []string{"no new events for 10m0s, still following"}, status.update(start.Add(10*time.Minute), 0, nil))
+
+	// Events reset the heartbeat
+	assert.Empty(t, status.update(start.Add(11*time.Minute), 3, nil))
+	assert.Empty(t, status.update(start.Add(15*time.Minute), 0, nil))
+
+	// Failures are reported once, then the recovery
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+	notices := status.update(start.Add(16*time.Minute), 0, throttled)
+	assert.Len(t, notices, 1)
+	assert.Contains(t, notices[0], "connection problem, retrying")
+	assert.Empty(t, status.update(start.Add(17*time.Minute), 0, errors.New("timeout")))
+	assert.Equal(t, []string{"reconnected after throttling (2 failed polls)"}, status.update(start.Add(18*time.Minute), 1, nil))
+
+	// Without a heartbeat only the connection status is reported
+	quiet := newFollowStatus(0, start)
+	assert.Empty(t, quiet.update(start.Add(time.Hour), 0, nil))
+
+	// An expired SSO session tells how to log in again
+	t.Setenv("AWS_PROFILE", "dev")
+	expired := fmt.Errorf("failed to refresh cached credentials, %w", &ssocreds.InvalidTokenError{})
+	assert.Equal(t, []string{"AWS SSO session expired, run 'aws sso login --profile dev' to continue following"}, quiet.update(start.Add(2*time.Hour), 0, expired))
+}
+
+func TestSSOSessionExpired(t *testing.T) {
+	assert.True(t, IsSSOSessionExpired(fmt.Errorf("get identity: %w", &ssocreds.InvalidTokenError{})))
+	assert.True(t, IsSSOSessionExpired(errors.New("cached SSO token is expired, or not present, and cannot be refreshed")))
+	assert.False(t, IsSSOSessionExpired(&smithy.GenericAPIError{Code: "ExpiredTokenException"}))
+	assert.False(t, IsSSOSessionExpired(nil))
+}
+
+func TestSSOLoginCommand(t *testing.T) {
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_DEFAULT_PROFILE", "")
+	assert.Equal(t, "aws sso login", SSOLoginCommand())
+
+	t.Setenv("AWS_DEFAULT_PROFILE", "staging")
+	assert.Equal(t, "aws sso login --profile staging", SSOLoginCommand())
+
+	t.Setenv("AWS_PROFILE", "prod")
+	assert.Equal(t, "aws sso login --profile prod", SSOLoginCommand())
+}
+
+// TestSilenceWatch tests the detection of log types that stopped producing events
+func TestSilenceWatch(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	audit := log.LogEntry{LogStream: "kube-apiserver-audit-123"}
+	api := log.LogEntry{LogStream: "kube-apiserver-123"}
+
+	watch := newSilenceWatch(10*time.Minute, []string{"audit"}, start)
+	watch.observe(api, start.Add(9*time.Minute))
+	assert.Empty(t, watch.check(start.Add(9*time.Minute)))
+	// Only the requested types are watched
+	assert.Equal(t, []string{"audit"}, watch.check(start.Add(10*time.Minute)))
+	// The alert is raised once per silence
+	assert.Empty(t, watch.check(start.Add(11*time.Minute)))
+	watch.observe(audit, start.Add(12*time.Minute))
+	assert.Equal(t, []string{"audit"}, watch.check(start.Add(22*time.Minute)))
+
+	// Without requested types every type seen is watched
+	watch = newSilenceWatch(time.Minute, nil, start)
+	assert.Empty(t, watch.check(start.Add(time.Hour)))
+	watch.observe(api, start)
+	watch.observe(audit, start.Add(30*time.Second))
+	assert.Equal(t, []string{"api"}, watch.check(start.Add(time.Minute)))
+
+	err := silenceError([]string{"api", "audit"}, 10*time.Minute)
+	assert.ErrorIs(t, err, ErrSilence)
+	assert.Contains(t, err.Error(), "no api, audit events for 10m0s")
+}
+
+// TestStreamWatch tests the detection of log streams appearing and going idle
+func TestStreamWatch(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	stream := func(name string, lastIngestion time.Time) cwt.LogStream {
+		return cwt.LogStream{LogStreamName: aws.String(name), LastIngestionTime: aws.Int64(lastIngestion.UnixMilli())}
+	}
+
+	watch := newStreamWatch(5 * time.Minute)
+	// The first listing is the baseline
+	assert.Empty(t, watch.update("g", []cwt.LogStream{stream("kube-apiserver-a", now)}, now))
+
+	changes := watch.update("g", []cwt.LogStream{
+		stream("kube-apiserver-a", now.Add(-5*time.Minute)),
+		stream("kube-apiserver-b", now),
+		stream("kube-apiserver-old", now.Add(-time.Hour)),
+	}, now)
+	assert.Equal(t, []streamChange{
+		{logGroup: "g", stream: "kube-apiserver-a", idle: true},
+		{logGroup: "g", stream: "kube-apiserver-b"},
+	}, changes)
+
+	created := cwt.LogStream{LogStreamName: aws.String("kube-apiserver-c"), CreationTime: aws.Int64(now.Add(-time.Minute).UnixMilli())}
+	changes = watch.update("g", []cwt.LogStream{created}, now)
+	assert.True(t, now.Add(-time.Minute).Equal(changes[0].created))
+
+	// Changes are reported once
+	assert.Empty(t, watch.update("g", []cwt.LogStream{
+		stream("kube-apiserver-a", now.Add(-6*time.Minute)),
+		stream("kube-apiserver-b", now),
+	}, now.Add(time.Minute)))
+}
+
+// TestStreamCache tests that stream listings are reused within the TTL
+func TestStreamCache(t *testing.T) {
+	mock := &countingStreamsClient{}
+	client := &EKSLogsClient{logsClient: mock}
+
+	// The cache is disabled by default on a bare client
+	_, _ = client.listLogStreams(context.Background(), "g", nil)
+	_, _ = client.listLogStreams(context.Background(), "g", nil)
+	assert.Equal(t, 2, mock.describeCalls)
+
+	client.SetStreamCacheTTL(time.Minute)
+	_, _ = client.listLogStreams(context.Background(), "g", nil)
+	_, _ = client.listLogStreams(context.Background(), "g", nil)
+	_, _ = client.listLogStreams(context.Background(), "other", nil)
+	assert.Equal(t, 4, mock.describeCalls)
+
+	// Expired listings are refreshed
+	now := time.Now()
+	_, ok := client.streamCache.get("g", now, nil)
+	assert.True(t, ok)
+	_, ok = client.streamCache.get("g", now.Add(time.Minute), nil)
+	assert.False(t, ok)
+}
+
+// countingStreamsClient counts DescribeLogStreams calls
+type countingStreamsClient struct {
+	mockLogsClient
+	describeCalls int
+}
+
+func (m *countingStreamsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	m.describeCalls++
+	return &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: []cwt.LogStream{{LogStreamName: aws.String("kube-apiserver-a")}}}, nil
+}
+
+// unknownStreamsClient lists the streams of known and unknown control plane components
+type unknownStreamsClient struct {
+	mockLogsClient
+}
+
+func (m *unknownStreamsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	return &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: []cwt.LogStream{
+		{LogStreamName: aws.String("kube-apiserver-0a1b")},
+		{LogStreamName: aws.String("kube-apiserver-audit-0a1b")},
+		{LogStreamName: aws.String("kube-apiserver-egress-0a1b")},
+	}}, nil
+}
+
+// TestGetLogStreamsForTypesUnknownStreams tests that the streams of unknown components are
+// only read with SetIncludeUnknownStreams
+func TestGetLogStreamsForTypesUnknownStreams(t *testing.T) {
+	client := &EKSLogsClient{logsClient: &unknownStreamsClient{}}
+
+	streams, err := client.getLogStreamsForTypes(context.Background(), "/aws/eks/test/cluster", []string{"api"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kube-apiserver-0a1b"}, streams)
+
+	client.SetIncludeUnknownStreams(true)
+	streams, err = client.getLogStreamsForTypes(context.Background(), "/aws/eks/test/cluster", []string{"api"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kube-apiserver-0a1b", "kube-apiserver-egress-0a1b"}, streams)
+}
+
+// shardedStreamsClient lists streams by last event time or by name prefix. The pages by last
+// event time after the first wait for the cancellation of the listing. Without a prefix, the
+// pages by name in ascending order after the first wait for a listing in descending order.
+type shardedStreamsClient struct {
+	mockLogsClient
+	streams []cwt.LogStream // most recently active first
+
+	mu          sync.Mutex
+	prefixes    []string // prefixes listed by name
+	inFlight    int      // calls listing by name in flight
+	maxInFlight int
+	backward    chan struct{} // closed by the first listing in descending order
+}
+
+func (m *shardedStreamsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	streams := m.streams
+	if params.OrderBy == cwt.OrderByLogStreamName {
+		prefix := aws.ToString(params.LogStreamNamePrefix)
+		descending := aws.ToBool(params.Descending)
+		m.mu.Lock()
+		if params.NextToken == nil {
+			m.prefixes = append(m.prefixes, prefix)
+		}
+		m.inFlight++
+		m.maxInFlight = max(m.maxInFlight, m.inFlight)
+		if prefix == "" && descending && m.backward != nil {
+			close(m.backward)
+			m.backward = nil
+		}
+		backward := m.backward
+		m.mu.Unlock()
+		defer func() {
+			m.mu.Lock()
+			m.inFlight--
+			m.mu.Unlock()
+		}()
+		if prefix == "" && !descending && params.NextToken != nil && backward != nil {
+			select {
+			case <-backward:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		streams = nil
+		for _, stream := range m.streams {
+			if strings.HasPrefix(aws.ToString(stream.LogStreamName), prefix) {
+				streams = append(streams, stream)
+			}
+		}
+		sort.Slice(streams, func(i, j int) bool {
+			return aws.ToString(streams[i].LogStreamName) < aws.ToString(streams[j].LogStreamName) != descending
+		})
+	} else if params.NextToken != nil {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	offset := 0
+	if params.NextToken != nil {
+		offset, _ = strconv.Atoi(*params.NextToken)
+	}
+	streams = streams[offset:]
+	if limit := int(aws.ToInt32(params.Limit)); len(streams) > limit {
+		return &cloudwatchlogs.DescribeLogStreamsOutput{
+			LogStreams: streams[:limit],
+			NextToken:  aws.String(strconv.Itoa(offset + limit)),
+		}, nil
+	}
+	return &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: streams}, nil
+}
+
+// TestGetLogStreamsForTypesByPrefix tests that the streams of log types spanning several pages
+// are listed by name prefix, and only by last event time when the listing stops early
+func TestGetLogStreamsForTypesByPrefix(t *testing.T) {
+	now := time.Now()
+	mock := &shardedStreamsClient{}
+	var want []string
+	for i := range 60 {
+		for _, component := range []string{"kube-apiserver-audit", "kube-apiserver", "kube-scheduler"} {
+			name := fmt.Sprintf("%s-%04x", component, i)
+			mock.streams = append(mock.streams, cwt.LogStream{
+				LogStreamName:      aws.String(name),
+				LastEventTimestamp: aws.Int64(now.Add(-time.Duration(i) * time.Minute).UnixMilli()),
+			})
+			if component == "kube-apiserver-audit" {
+				want = append(want, name)
+			}
+		}
+	}
+	client := &EKSLogsClient{logsClient: mock}
+	client.SetStreamCacheTTL(time.Minute)
+
+	since := now.Add(-2 * time.Hour)
+	streams, err := client.getLogStreamsForTypes(context.Background(), "/aws/eks/test/cluster", []string{"audit"}, &since)
+	assert.NoError(t, err)
+	assert.Equal(t, want, streams)
+	assert.Equal(t, []string{"kube-apiserver-audit"}, mock.prefixes)
+
+	// The listing by name is reused
+	_, err = client.getLogStreamsForTypes(context.Background(), "/aws/eks/test/cluster", []string{"audit"}, &since)
+	assert.NoError(t, err)
+	assert.Len(t, mock.prefixes, 1)
+
+	// The first page ends with a stale stream
+	client.SetStreamCacheTTL(0)
+	mock.prefixes = nil
+	since = now.Add(-5*time.Minute - 30*time.Second)
+	streams, err = client.getLogStreamsForTypes(context.Background(), "/aws/eks/test/cluster", []string{"audit"}, &since)
+	assert.NoError(t, err)
+	assert.Equal(t, want[:11], streams)
+	assert.Empty(t, mock.prefixes)
+}
+
+// TestListLogStreamsFromBothEnds tests that the streams of a log group spanning several pages
+// are listed by name from both ends at the same time, without log types and in follow mode
+func TestListLogStreamsFromBothEnds(t *testing.T) {
+	now := time.Now()
+	mock := &shardedStreamsClient{backward: make(chan struct{})}
+	var want []string
+	for i := range 60 {
+		for _, component := range []string{"authenticator", "kube-apiserver", "kube-scheduler"} {
+			name := fmt.Sprintf("%s-%04x", component, i)
+			mock.streams = append(mock.streams, cwt.LogStream{
+				LogStreamName:      aws.String(name),
+				LastEventTimestamp: aws.Int64(now.Add(-time.Duration(i) * time.Minute).UnixMilli()),
+			})
+			want = append(want, name)
+		}
+	}
+	client := &EKSLogsClient{logsClient: mock}
+
+	since := now.Add(-2 * time.Hour)
+	names, err := client.listLogStreamNames(context.Background(), "/aws/eks/test/cluster", &since)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, want, names)
+	assert.Equal(t, 2, mock.maxInFlight)
+	assert.Equal(t, []string{"", ""}, mock.prefixes)
+
+	// Follow mode lists every stream by name right away
+	mock.maxInFlight = 0
+	mock.backward = make(chan struct{})
+	var observed []cwt.LogStream
+	client.streamObserver = func(logGroup string, streams []cwt.LogStream) { observed = streams }
+	streams, err := client.listLogStreams(context.Background(), "/aws/eks/test/cluster", &since)
+	assert.NoError(t, err)
+	assert.Len(t, streams, len(want))
+	assert.Equal(t, streams, observed)
+	assert.Equal(t, 2, mock.maxInFlight)
+	// Most recently active first
+	assert.Equal(t, now.UnixMilli(), aws.ToInt64(streams[0].LastEventTimestamp))
+	assert.Equal(t, now.Add(-59*time.Minute).UnixMilli(), aws.ToInt64(streams[len(streams)-1].LastEventTimestamp))
+}
+
+// activityStreamsClient lists streams of several components with their last event time
+type activityStreamsClient struct {
+	mockLogsClient
+	newest time.Time
+}
+
+func (m *activityStreamsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	return &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: []cwt.LogStream{
+		{LogStreamName: aws.String("kube-apiserver-0a1b"), LastEventTimestamp: aws.Int64(m.newest.UnixMilli())},
+		{LogStreamName: aws.String("kube-apiserver-2c3d"), LastEventTimestamp: aws.Int64(m.newest.Add(-time.Hour).UnixMilli())},
+		{LogStreamName: aws.String("kube-apiserver-egress-0a1b"), LastEventTimestamp: aws.Int64(m.newest.UnixMilli())},
+		{LogStreamName: aws.String("authenticator-0a1b"), LastEventTimestamp: aws.Int64(m.newest.Add(-2 * time.Hour).UnixMilli())},
+		{LogStreamName: aws.String("kube-scheduler-0a1b"), LastEventTimestamp: aws.Int64(m.newest.Add(-48 * time.Hour).UnixMilli())},
+		{LogStreamName: aws.String("kube-controller-manager-0a1b")},
+	}}, nil
+}
+
+// TestLastEventTimes tests that the latest event of each log type is taken from its streams
+func TestLastEventTimes(t *testing.T) {
+	newest := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	client := &EKSLogsClient{logsClient: &activityStreamsClient{newest: newest}}
+
+	lastEvents, err := client.LastEventTimes(context.Background(), "test", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{
+		"api":           newest,
+		"authenticator": newest.Add(-2 * time.Hour),
+		"scheduler":     newest.Add(-48 * time.Hour),
+	}, toUTC(lastEvents))
+
+	since := newest.Add(-24 * time.Hour)
+	lastEvents, err = client.LastEventTimes(context.Background(), "test", &since)
+	assert.NoError(t, err)
+	assert.NotContains(t, lastEvents, "scheduler")
+}
+
+// toUTC returns times in UTC, so they compare equal to the times they were made from
+func toUTC(times map[string]time.Time) map[string]time.Time {
+	utc := make(map[string]time.Time, len(times))
+	for key, t := range times {
+		utc[key] = t.UTC()
+	}
+	return utc
+}
+
+// pagedStreamsClient lists one stream per page, each last active an hour before the previous one.
+// The listings by name wait until byName is closed.
+type pagedStreamsClient struct {
+	mockLogsClient
+	newest time.Time
+	pages  int
+	byName chan struct{}
+
+	mu        sync.Mutex
+	timeCalls int // Calls listing by last event time
+	nameCalls int // Calls listing by name
+}
+
+func (m *pagedStreamsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	page := 0
+	if params.NextToken != nil {
+		page, _ = strconv.Atoi(*params.NextToken)
+	}
+	// Stream i is both the i-th by name and the i-th most recently active
+	i := page
+	if params.OrderBy == cwt.OrderByLogStreamName {
+		select {
+		case <-m.byName:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if aws.ToBool(params.Descending) {
+			i = m.pages - 1 - page
+		}
+		m.mu.Lock()
+		m.nameCalls++
+		m.mu.Unlock()
+	} else {
+		m.mu.Lock()
+		m.timeCalls++
+		m.mu.Unlock()
+	}
+
+	output := &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: []cwt.LogStream{{
+		LogStreamName:      aws.String(fmt.Sprintf("kube-apiserver-%d", i)),
+		LastEventTimestamp: aws.Int64(m.newest.Add(-time.Duration(i) * time.Hour).UnixMilli()),
+	}}}
+	if page < m.pages-1 {
+		output.NextToken = aws.String(fmt.Sprint(page + 1))
+	}
+	return output, nil
+}
+
+// calls returns the number of calls listing by last event time and by name
+func (m *pagedStreamsClient) calls() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.timeCalls, m.nameCalls
+}
+
+// TestListLogStreamsStopsAtStaleStream tests that paging stops once the streams predate the range
+func TestListLogStreamsStopsAtStaleStream(t *testing.T) {
+	newest := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock := &pagedStreamsClient{newest: newest, pages: 10, byName: make(chan struct{})}
+	client := &EKSLogsClient{logsClient: mock}
+	client.SetStreamCacheTTL(time.Minute)
+
+	since := newest.Add(-90 * time.Minute)
+	names, err := client.listLogStreamNames(context.Background(), "g", &since)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"kube-apiserver-0", "kube-apiserver-1"}, names)
+	timeCalls, _ := mock.calls()
+	assert.Equal(t, 3, timeCalls)
+
+	// The truncated listing serves later starts, but not earlier ones
+	later := since.Add(time.Minute)
+	_, err = client.listLogStreams(context.Background(), "g", &later)
+	assert.NoError(t, err)
+	timeCalls, _ = mock.calls()
+	assert.Equal(t, 3, timeCalls)
+
+	// A complete listing is made by name from both ends
+	close(mock.byName)
+	streams, err := client.listLogStreams(context.Background(), "g", nil)
+	assert.NoError(t, err)
+	assert.Len(t, streams, 10)
+	assert.Equal(t, "kube-apiserver-0", aws.ToString(streams[0].LogStreamName))
+	assert.Equal(t, "kube-apiserver-9", aws.ToString(streams[9].LogStreamName))
+	timeCalls, nameCalls := mock.calls()
+	assert.Equal(t, 3, timeCalls)
+	// Each stream is listed once, but for the pages where the listings meet
+	assert.GreaterOrEqual(t, nameCalls, 10)
+	assert.LessOrEqual(t, nameCalls, 12)
+}
+
+// TestTailCursorRewind tests that a late stream is read again from its creation
+func TestTailCursorRewind(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursor := newTailCursor(base)
+	cursor.accept(log.LogEntry{Timestamp: base.Add(time.Minute), LogStream: "a", Message: "m"})
+
+	cursor.rewind(time.Time{})
+	assert.Equal(t, base.Add(time.Minute), cursor.start())
+
+	cursor.rewind(base.Add(20 * time.Second))
+	cursor.rewind(base.Add(40 * time.Second))
+	assert.Equal(t, base.Add(20*time.Second), cursor.start())
+	// The rewind applies to a single poll
+	assert.Equal(t, base.Add(time.Minute), cursor.start())
+
+	// Never before the start of the session
+	cursor.rewind(base.Add(-time.Hour))
+	assert.Equal(t, base, cursor.start())
+}
+
+// TestIsStaleStream tests skipping streams without activity in the requested range
+func TestIsStaleStream(t *testing.T) {
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ms := func(t time.Time) *int64 { return aws.Int64(t.UnixMilli()) }
+
+	assert.True(t, isStaleStream(cwt.LogStream{LastEventTimestamp: ms(since.Add(-time.Hour))}, since))
+	assert.False(t, isStaleStream(cwt.LogStream{LastEventTimestamp: ms(since.Add(time.Minute))}, since))
+	// A recent ingestion wins over a lagging last event timestamp
+	assert.False(t, isStaleStream(cwt.LogStream{LastEventTimestamp: ms(since.Add(-time.Hour)), LastIngestionTime: ms(since)}, since))
+	// Activity shortly before the start is kept for clock skew
+	assert.False(t, isStaleStream(cwt.LogStream{LastEventTimestamp: ms(since.Add(-time.Minute))}, since))
+	// Streams without activity information are kept
+	assert.False(t, isStaleStream(cwt.LogStream{}, since))
+}
+
+// staleStreamsClient lists only streams last active long ago
+type staleStreamsClient struct {
+	mockLogsClient
+}
+
+func (m *staleStreamsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	return &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: []cwt.LogStream{{
+		LogStreamName:      aws.String("kube-apiserver-old"),
+		LastEventTimestamp: aws.Int64(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()),
+	}}}, nil
+}
+
+// TestGetLogsSkipsStaleStreams tests that log groups without active streams are not searched
+func TestGetLogsSkipsStaleStreams(t *testing.T) {
+	mock := &staleStreamsClient{}
+	client := &EKSLogsClient{logsClient: mock}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := client.GetLogs(context.Background(), "test", nil, &start, nil, nil, 0, func(log.LogEntry) {})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, mock.filterCalls)
+	assert.True(t, client.Progress().Groups[0].Done)
+
+	// Requested log types without a matching stream are not searched either
+	err = client.GetLogs(context.Background(), "test", []string{"scheduler"}, nil, nil, nil, 0, func(log.LogEntry) {})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, mock.filterCalls)
+}
+
+// singleStreamClient lists a single log stream
+type singleStreamClient struct {
+	mockLogsClient
+}
+
+func (m *singleStreamClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	return &cloudwatchlogs.DescribeLogStreamsOutput{LogStreams: []cwt.LogStream{{LogStreamName: aws.String("kube-apiserver-123")}}}, nil
+}
+
+// TestGetLogsSingleStream tests that a single stream without a filter pattern is read with GetLogEvents
+func TestGetLogsSingleStream(t *testing.T) {
+	end := time.Now()
+	start := end.Add(-time.Hour)
+
+	mock := &singleStreamClient{mockLogsClient{events: mockEvents(end, 10, "message"), pageSize: 4}}
+	client := &EKSLogsClient{logsClient: mock}
+
+	var entries []log.LogEntry
+	err := client.GetLogs(context.Background(), "test", nil, &start, &end, nil, 0, func(entry log.LogEntry) {
+		entries = append(entries, entry)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, mock.filterCalls)
+	assert.Equal(t, 4, mock.getCalls) // 4+4+2 events, then the unchanged token
+	assert.Len(t, entries, 10)
+	// The end time is included like with FilterLogEvents
+	assert.Equal(t, end.UnixMilli(), entries[9].Timestamp.UnixMilli())
+	assert.Equal(t, "kube-apiserver-123", entries[0].LogStream)
+
+	// The limit is applied
+	entries = nil
+	err = client.GetLogs(context.Background(), "test", nil, &start, &end, nil, 5, func(entry log.LogEntry) {
+		entries = append(entries, entry)
+	})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 5)
+
+	// A filter pattern needs FilterLogEvents
+	pattern := "message"
+	err = client.GetLogs(context.Background(), "test", nil, &start, &end, &pattern, 0, func(log.LogEntry) {})
+	assert.NoError(t, err)
+	assert.Greater(t, mock.filterCalls, 0)
+}
+
+// prefixLogGroupsClient returns the log groups matching the requested prefix
+type prefixLogGroupsClient struct {
+	mockLogsClient
+	logGroups []string
+}
+
+func (m *prefixLogGroupsClient) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	var groups []cwt.LogGroup
+	for _, name := range m.logGroups {
+		if strings.HasPrefix(name, aws.ToString(params.LogGroupNamePrefix)) {
+			groups = append(groups, cwt.LogGroup{LogGroupName: aws.String(name)})
+		}
+	}
+	return &cloudwatchlogs.DescribeLogGroupsOutput{LogGroups: groups}, nil
+}
+
+// TestContainerInsightsLogGroups tests that node log groups are only read when enabled
+func TestContainerInsightsLogGroups(t *testing.T) {
+	mock := &prefixLogGroupsClient{logGroups: []string{
+		"/aws/eks/test/cluster",
+		"/aws/containerinsights/test/application",
+		"/aws/containerinsights/test/dataplane",
+		"/aws/containerinsights/test/host",
+		"/aws/containerinsights/test/performance",
+		"/aws/containerinsights/test-other/host",
+	}}
+	client := &EKSLogsClient{logsClient: mock}
+
+	groups, err := client.GetLogGroups(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/aws/eks/test/cluster"}, groups)
+
+	client.SetContainerInsights(true)
+	groups, err = client.GetLogGroups(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"/aws/eks/test/cluster",
+		"/aws/containerinsights/test/application",
+		"/aws/containerinsights/test/dataplane",
+		"/aws/containerinsights/test/host",
+	}, groups)
+}
+
+// TestCustomLogGroups tests that the log groups of registered log types are read
+func TestCustomLogGroups(t *testing.T) {
+	assert.NoError(t, log.RegisterLogType(log.LogTypeDefinition{Name: "karpenter", LogGroup: "/aws/eks/{cluster}/karpenter"}))
+	t.Cleanup(func() { log.UnregisterLogType("karpenter") })
+
+	mock := &prefixLogGroupsClient{logGroups: []string{
+		"/aws/eks/test/cluster",
+		"/aws/eks/test/karpenter",
+		"/aws/eks/test/karpenter-old",
+		"/aws/eks/other/karpenter",
+	}}
+	client := &EKSLogsClient{logsClient: mock}
+
+	groups, err := client.GetLogGroups(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/aws/eks/test/cluster", "/aws/eks/test/karpenter"}, groups)
+	assert.Equal(t, "karpenter", log.LogTypeFor("/aws/eks/test/karpenter", "controller-7d9f"))
+}
+
+// mockTrailClient serves CloudTrail events by lookup attribute value, one event per page
+type mockTrailClient struct {
+	events map[string][]ctt.Event
+}
+
+func (m *mockTrailClient) LookupEvents(ctx context.Context, params *cloudtrail.LookupEventsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.LookupEventsOutput, error) {
+	events := m.events[aws.ToString(params.LookupAttributes[0].AttributeValue)]
+	page := 0
+	if params.NextToken != nil {
+		page, _ = strconv.Atoi(*params.NextToken)
+	}
+	if page >= len(events) {
+		return &cloudtrail.LookupEventsOutput{}, nil
+	}
+	output := &cloudtrail.LookupEventsOutput{Events: events[page : page+1]}
+	if page+1 < len(events) {
+		output.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return output, nil
+}
+
+func trailEvent(id, name, readOnly string, at time.Time, raw string) ctt.Event {
+	return ctt.Event{
+		EventId:         aws.String(id),
+		EventName:       aws.String(name),
+		EventSource:     aws.String("eks.amazonaws.com"),
+		EventTime:       aws.Time(at),
+		ReadOnly:        aws.String(readOnly),
+		CloudTrailEvent: aws.String(raw),
+	}
+}
+
+func TestGetCloudTrailEvents(t *testing.T) {
+	roleArn := "arn:aws:iam::123456789012:role/eks-cluster-role"
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	update := trailEvent("1", "UpdateClusterConfig", "false", base.Add(2*time.Minute),
+		`{"userIdentity":{"arn":"arn:aws:iam::123456789012:user/alice"},"sourceIPAddress":"203.0.113.10","requestParameters":{"name":"test"}}`)
+	mock := &mockTrailClient{events: map[string][]ctt.Event{
+		"eks.amazonaws.com": {
+			update,
+			trailEvent("2", "DescribeCluster", "true", base, `{"requestParameters":{"name":"test"}}`),
+			trailEvent("3", "UpdateClusterConfig", "false", base, `{"requestParameters":{"name":"other"}}`),
+			trailEvent("4", "CreateAccessEntry", "false", base.Add(time.Minute),
+				`{"userIdentity":{"arn":"arn:aws:iam::123456789012:user/bob"},"errorCode":"AccessDeniedException","requestParameters":{"clusterName":"test"}}`),
+		},
+		roleArn: {
+			trailEvent("5", "AssumeRole", "true", base.Add(3*time.Minute), `{"userIdentity":{"arn":"eks.amazonaws.com"}}`),
+			trailEvent("6", "GetRole", "true", base, `{}`),
+			update,
+		},
+	}}
+	client := &EKSLogsClient{trailClient: mock}
+
+	changes, err := client.GetCloudTrailEvents(context.Background(), "test", roleArn, base, base.Add(time.Hour))
+	assert.NoError(t, err)
+	entries := changes.Entries
+
+	var ids []string
+	for _, entry := range entries {
+		ids = append(ids, entry.EventID)
+		assert.Equal(t, log.CloudTrailLogGroup, entry.LogGroup)
+	}
+	assert.Equal(t, []string{"4", "1", "5"}, ids)
+
+	assert.Equal(t, "error", entries[0].Level)
+	assert.Equal(t, `CreateAccessEntry by arn:aws:iam::123456789012:user/bob failed: AccessDeniedException {"clusterName":"test"}`, entries[0].Message)
+	assert.Equal(t, "info", entries[1].Level)
+	assert.Equal(t, `UpdateClusterConfig by arn:aws:iam::123456789012:user/alice from 203.0.113.10 {"name":"test"}`, entries[1].Message)
+	assert.Equal(t, "eks.amazonaws.com", entries[1].Component)
+	assert.Equal(t, int64(7), client.Stats().APICalls)
+
+	// The service assuming the cluster role is not a principal
+	assert.Equal(t, []string{"alice", "bob"}, changes.Principals)
+	assert.Equal(t, `?"alice" ?"bob"`, changes.Pattern())
+}
+
+func TestPrincipalName(t *testing.T) {
+	assert.Equal(t, "alice", principalName("arn:aws:iam::123456789012:user/alice"))
+	assert.Equal(t, "admin", principalName("arn:aws:iam::123456789012:role/service-role/admin"))
+	assert.Equal(t, "admin", principalName("arn:aws:sts::123456789012:assumed-role/admin/session"))
+	assert.Equal(t, "", principalName("arn:aws:iam::123456789012:root"))
+	assert.Equal(t, "", principalName("eks.amazonaws.com"))
+}
+
+func TestEndpointURL(t *testing.T) {
+	client, err := NewEKSLogsClient("us-east-1", "http://localhost:4566", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:4566", aws.ToString(client.logsClient.(*cloudwatchlogs.Client).Options().BaseEndpoint))
+
+	// Without the flag, AWS_ENDPOINT_URL is honored
+	t.Setenv("AWS_ENDPOINT_URL", "http://localhost:5000")
+	client, err = NewEKSLogsClient("us-east-1", "", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:5000", aws.ToString(client.logsClient.(*cloudwatchlogs.Client).Options().BaseEndpoint))
+
+	for _, endpoint := range []string{"localhost:4566", "ftp://localhost", "http://"} {
+		_, err := NewEKSLogsClient("us-east-1", endpoint, nil)
+		assert.Error(t, err, endpoint)
+	}
+}
+
+func TestReadMFAToken(t *testing.T) {
+	var prompt bytes.Buffer
+	code, err := readMFAToken(strings.NewReader(" 123456 \n"), &prompt)
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", code)
+	assert.Equal(t, "MFA token code: ", prompt.String())
+
+	// A code without a trailing newline is accepted, an empty one is not
+	code, err = readMFAToken(strings.NewReader("654321"), &prompt)
+	assert.NoError(t, err)
+	assert.Equal(t, "654321", code)
+	_, err = readMFAToken(strings.NewReader("\n"), &prompt)
+	assert.Error(t, err)
+	_, err = readMFAToken(strings.NewReader(""), &prompt)
+	assert.Error(t, err)
+}
+
+func TestMFARequired(t *testing.T) {
+	err := fmt.Errorf("failed to refresh cached credentials, %w", errors.New("assume role with MFA enabled, but TokenProvider is not set"))
+	assert.True(t, IsMFARequired(err))
+	assert.True(t, IsAuthError(err))
+	assert.False(t, IsMFARequired(errors.New("boom")))
+}
+
+func TestAccessDenied(t *testing.T) {
+	denied := fmt.Errorf("failed to get cluster info: %w", &smithy.GenericAPIError{Code: "AccessDeniedException"})
+	assert.True(t, IsAccessDenied(denied))
+	assert.True(t, IsAuthError(denied))
+	assert.False(t, IsAccessDenied(&smithy.GenericAPIError{Code: "ExpiredToken"}))
+	assert.False(t, IsAccessDenied(errors.New("boom")))
+}
+
+// pagedEKSClient lists clusters two per page
+type pagedEKSClient struct {
+	EKSAPI
+	clusters []string
+}
+
+func (m *pagedEKSClient) ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	start := 0
+	if params.NextToken != nil {
+		start, _ = strconv.Atoi(*params.NextToken)
+	}
+	end := min(start+2, len(m.clusters))
+	output := &eks.ListClustersOutput{Clusters: m.clusters[start:end]}
+	if end < len(m.clusters) {
+		output.NextToken = aws.String(strconv.Itoa(end))
+	}
+	return output, nil
+}
+
+func TestListClustersPaginates(t *testing.T) {
+	client := &EKSLogsClient{eksClient: &pagedEKSClient{clusters: []string{"a", "b", "c", "d", "e"}}}
+	clusters, err := client.ListClusters(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, clusters)
+}
+
+func TestMatchClusterNames(t *testing.T) {
+	clusters := []string{"prod-eu", "prod", "staging", "dev-prod-copy", "Prod-US", "sandbox"}
+
+	assert.Equal(t, []string{"prod", "prod-eu", "Prod-US", "dev-prod-copy"}, MatchClusterNames("prod", clusters))
+	assert.Equal(t, []string{"staging"}, MatchClusterNames("stagign", clusters))
+	assert.Equal(t, []string{"staging"}, MatchClusterNames("stag", clusters))
+	assert.Empty(t, MatchClusterNames("analytics", clusters))
+	assert.Empty(t, MatchClusterNames("", clusters))
+}
+
+func TestClusterNotFoundError(t *testing.T) {
+	err := error(&ClusterNotFoundError{Name: "stagign", Available: []string{"prod", "staging"}, Matches: []string{"staging"}})
+	assert.Equal(t, "cluster 'stagign' not found. Did you mean: staging? Available clusters: [prod staging]", err.Error())
+	assert.True(t, errors.Is(err, ErrClusterNotFound))
+	assert.True(t, IsNotFoundError(fmt.Errorf("failed to get cluster info: %w", err)))
+
+	err = &ClusterNotFoundError{Name: "analytics", Available: []string{"prod"}}
+	assert.Equal(t, "cluster 'analytics' not found. Available clusters: [prod]", err.Error())
+}
+
+// mockEC2Client lists a fixed set of enabled regions
+type mockEC2Client struct {
+	regions []string
+}
+
+func (m *mockEC2Client) DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error) {
+	output := &ec2.DescribeRegionsOutput{}
+	for _, region := range m.regions {
+		output.Regions = append(output.Regions, ec2types.Region{RegionName: aws.String(region)})
+	}
+	return output, nil
+}
+
+// failingEKSClient fails every call, like a region denied by a service control policy
+type failingEKSClient struct {
+	EKSAPI
+}
+
+func (m *failingEKSClient) ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "AccessDeniedException"}
+}
+
+func TestFindClusterRegions(t *testing.T) {
+	regional := map[string]EKSAPI{
+		"us-east-1":      &pagedEKSClient{clusters: []string{"dev", "staging", "prod"}},
+		"eu-west-1":      &pagedEKSClient{clusters: []string{"prod-eu"}},
+		"ap-northeast-1": &pagedEKSClient{clusters: []string{"prod"}},
+		"me-south-1":     &failingEKSClient{},
+	}
+	client := &EKSLogsClient{
+		ec2Client:    &mockEC2Client{regions: []string{"us-east-1", "eu-west-1", "ap-northeast-1", "me-south-1"}},
+		eksForRegion: func(region string) EKSAPI { return regional[region] },
+	}
+
+	regions, err := client.FindClusterRegions(context.Background(), "staging")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"us-east-1"}, regions)
+
+	regions, err = client.FindClusterRegions(context.Background(), "prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ap-northeast-1", "us-east-1"}, regions)
+
+	regions, err = client.FindClusterRegions(context.Background(), "analytics")
+	assert.NoError(t, err)
+	assert.Empty(t, regions)
+
+	// Failing every region is an error
+	client.ec2Client = &mockEC2Client{regions: []string{"me-south-1"}}
+	_, err = client.FindClusterRegions(context.Background(), "prod")
+	assert.Error(t, err)
+}
+
+// mockSTSClient returns a fixed caller identity
+type mockSTSClient struct {
+	err error
+}
+
+func (m *mockSTSClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String("123456789012"),
+		Arn:     aws.String("arn:aws:sts::123456789012:assumed-role/eks-log-reader/session"),
+	}, nil
+}
+
+func TestGetCallerIdentity(t *testing.T) {
+	client := &EKSLogsClient{stsClient: &mockSTSClient{}}
+	identity, err := client.GetCallerIdentity(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "123456789012", identity.Account)
+	assert.Equal(t, "arn:aws:sts::123456789012:assumed-role/eks-log-reader/session", identity.ARN)
+
+	client.stsClient = &mockSTSClient{err: errors.New("expired token")}
+	_, err = client.GetCallerIdentity(context.Background())
+	assert.ErrorContains(t, err, "failed to get caller identity")
+}
+
+// mockMetricsClient records the metric filters and alarms created
+type mockMetricsClient struct {
+	filters []*cloudwatchlogs.PutMetricFilterInput
+	alarms  []*cloudwatch.PutMetricAlarmInput
+	err     error
+}
+
+func (m *mockMetricsClient) PutMetricFilter(ctx context.Context, params *cloudwatchlogs.PutMetricFilterInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutMetricFilterOutput, error) {
+	m.filters = append(m.filters, params)
+	return &cloudwatchlogs.PutMetricFilterOutput{}, m.err
+}
+
+func (m *mockMetricsClient) PutMetricAlarm(ctx context.Context, params *cloudwatch.PutMetricAlarmInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricAlarmOutput, error) {
+	m.alarms = append(m.alarms, params)
+	return &cloudwatch.PutMetricAlarmOutput{}, m.err
+}
+
+func TestPutMetricFilterAndAlarm(t *testing.T) {
+	mock := &mockMetricsClient{}
+	client := &EKSLogsClient{metricFilterClient: mock, alarmClient: mock}
+
+	err := client.PutMetricFilter(context.Background(), MetricFilter{
+		Name: "ekslogs-api-errors", LogGroup: ClusterLogGroup("prod"), Pattern: "ERROR", Namespace: "EKS/Logs", MetricName: "prod/api-errors",
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, mock.filters, 1) {
+		input := mock.filters[0]
+		assert.Equal(t, "/aws/eks/prod/cluster", aws.ToString(input.LogGroupName))
+		assert.Equal(t, "ERROR", aws.ToString(input.FilterPattern))
+		if assert.Len(t, input.MetricTransformations, 1) {
+			transformation := input.MetricTransformations[0]
+			assert.Equal(t, "EKS/Logs", aws.ToString(transformation.MetricNamespace))
+			assert.Equal(t, "prod/api-errors", aws.ToString(transformation.MetricName))
+			assert.Equal(t, "1", aws.ToString(transformation.MetricValue))
+			assert.Equal(t, 0.0, aws.ToFloat64(transformation.DefaultValue))
+		}
+	}
+
+	err = client.PutMetricAlarm(context.Background(), MetricAlarm{
+		Name: "ekslogs-prod-api-errors", Namespace: "EKS/Logs", MetricName: "prod/api-errors",
+		Threshold: 10, Period: 5 * time.Minute, EvaluationPeriods: 2, Actions: []string{"arn:aws:sns:us-east-1:123456789012:oncall"},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, mock.alarms, 1) {
+		input := mock.alarms[0]
+		assert.Equal(t, int32(300), aws.ToInt32(input.Period))
+		assert.Equal(t, int32(2), aws.ToInt32(input.EvaluationPeriods))
+		assert.Equal(t, cwtypes.StatisticSum, input.Statistic)
+		assert.Equal(t, cwtypes.ComparisonOperatorGreaterThanOrEqualToThreshold, input.ComparisonOperator)
+		assert.Equal(t, "notBreaching", aws.ToString(input.TreatMissingData))
+		assert.Equal(t, []string{"arn:aws:sns:us-east-1:123456789012:oncall"}, input.AlarmActions)
+		assert.Nil(t, input.AlarmDescription)
+	}
+
+	mock.err = errors.New("AccessDeniedException")
+	err = client.PutMetricFilter(context.Background(), MetricFilter{Name: "ekslogs-api-errors"})
+	assert.ErrorContains(t, err, "failed to create metric filter ekslogs-api-errors")
+}
+
+// mockSubscriptionClient holds the subscription filters of a log group and the Lambda
+// permissions added; putErrs are returned by the first calls to PutSubscriptionFilter
+type mockSubscriptionClient struct {
+	existing    []string
+	puts        []*cloudwatchlogs.PutSubscriptionFilterInput
+	putErrs     []error
+	permissions []*lambda.AddPermissionInput
+	permErr     error
+}
+
+func (m *mockSubscriptionClient) DescribeSubscriptionFilters(ctx context.Context, params *cloudwatchlogs.DescribeSubscriptionFiltersInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeSubscriptionFiltersOutput, error) {
+	var filters []cwt.SubscriptionFilter
+	for _, name := range m.existing {
+		filters = append(filters, cwt.SubscriptionFilter{FilterName: aws.String(name)})
+	}
+	return &cloudwatchlogs.DescribeSubscriptionFiltersOutput{SubscriptionFilters: filters}, nil
+}
+
+func (m *mockSubscriptionClient) PutSubscriptionFilter(ctx context.Context, params *cloudwatchlogs.PutSubscriptionFilterInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutSubscriptionFilterOutput, error) {
+	m.puts = append(m.puts, params)
+	if len(m.putErrs) > 0 {
+		err := m.putErrs[0]
+		m.putErrs = m.putErrs[1:]
+		return nil, err
+	}
+	return &cloudwatchlogs.PutSubscriptionFilterOutput{}, nil
+}
+
+func (m *mockSubscriptionClient) AddPermission(ctx context.Context, params *lambda.AddPermissionInput, optFns ...func(*lambda.Options)) (*lambda.AddPermissionOutput, error) {
+	m.permissions = append(m.permissions, params)
+	if m.permErr != nil {
+		return nil, m.permErr
+	}
+	return &lambda.AddPermissionOutput{}, nil
+}
+
+func TestDestinationKind(t *testing.T) {
+	tests := map[string]string{
+		"arn:aws:kinesis:us-east-1:123456789012:stream/logs":              DestinationKinesis,
+		"arn:aws:firehose:us-east-1:123456789012:deliverystream/siem":     DestinationFirehose,
+		"arn:aws:lambda:us-east-1:123456789012:function:alerts":           DestinationLambda,
+		"arn:aws:lambda:us-east-1:123456789012:function:alerts:live":      DestinationLambda,
+		"arn:aws:logs:us-east-1:210987654321:destination:central-logging": DestinationLogs,
+	}
+	for destination, kind := range tests {
+		got, err := DestinationKind(destination)
+		assert.NoError(t, err, destination)
+		assert.Equal(t, kind, got, destination)
+	}
+
+	_, err := DestinationKind("arn:aws:sqs:us-east-1:123456789012:queue")
+	assert.ErrorContains(t, err, "unsupported destination")
+	_, err = DestinationKind("alerts")
+	assert.ErrorContains(t, err, "invalid destination ARN")
+}
+
+func TestLogGroupARN(t *testing.T) {
+	logGroupARN, err := LogGroupARN("arn:aws-cn:eks:cn-north-1:123456789012:cluster/prod", ClusterLogGroup("prod"))
+	assert.NoError(t, err)
+	assert.Equal(t, "arn:aws-cn:logs:cn-north-1:123456789012:log-group:/aws/eks/prod/cluster:*", logGroupARN)
+}
+
+func TestPutSubscriptionFilter(t *testing.T) {
+	lambdaPermissionDelay = time.Millisecond
+	defer func() { lambdaPermissionDelay = 2 * time.Second }()
+	filter := SubscriptionFilter{
+		Name:           "ekslogs-alerts",
+		LogGroup:       "/aws/eks/prod/cluster",
+		Pattern:        "error",
+		DestinationARN: "arn:aws:lambda:us-east-1:123456789012:function:alerts",
+		LogGroupARN:    "arn:aws:logs:us-east-1:123456789012:log-group:/aws/eks/prod/cluster:*",
+	}
+
+	t.Run("lambda", func(t *testing.T) {
+		// The first attempt fails until the new permission propagates
+		mock := &mockSubscriptionClient{putErrs: []error{&smithy.GenericAPIError{Code: "InvalidParameterException"}}}
+		client := &EKSLogsClient{subscriptionClient: mock, lambdaClient: mock}
+		result, err := client.PutSubscriptionFilter(context.Background(), filter)
+		assert.NoError(t, err)
+		assert.Equal(t, SubscriptionResult{LambdaPermission: true}, result)
+		assert.Len(t, mock.puts, 2)
+		if assert.Len(t, mock.permissions, 1) {
+			assert.Equal(t, "logs.amazonaws.com", aws.ToString(mock.permissions[0].Principal))
+			assert.Equal(t, filter.LogGroupARN, aws.ToString(mock.permissions[0].SourceArn))
+			assert.Equal(t, "aws_eks_prod_cluster-ekslogs-alerts", aws.ToString(mock.permissions[0].StatementId))
+		}
+	})
+
+	t.Run("update with existing permission", func(t *testing.T) {
+		mock := &mockSubscriptionClient{existing: []string{"other", "ekslogs-alerts"}, permErr: &lambdatypes.ResourceConflictException{}}
+		client := &EKSLogsClient{subscriptionClient: mock, lambdaClient: mock}
+		result, err := client.PutSubscriptionFilter(context.Background(), filter)
+		assert.NoError(t, err)
+		assert.Equal(t, SubscriptionResult{Updated: true}, result)
+		assert.Len(t, mock.puts, 1)
+	})
+
+	t.Run("log group full", func(t *testing.T) {
+		mock := &mockSubscriptionClient{existing: []string{"datadog", "splunk"}}
+		client := &EKSLogsClient{subscriptionClient: mock, lambdaClient: mock}
+		_, err := client.PutSubscriptionFilter(context.Background(), filter)
+		assert.ErrorContains(t, err, "already has 2 subscription filters (datadog, splunk)")
+		assert.Empty(t, mock.puts)
+		assert.Empty(t, mock.permissions)
+	})
+
+	t.Run("kinesis requires a role", func(t *testing.T) {
+		mock := &mockSubscriptionClient{}
+		client := &EKSLogsClient{subscriptionClient: mock, lambdaClient: mock}
+		kinesis := filter
+		kinesis.DestinationARN = "arn:aws:kinesis:us-east-1:123456789012:stream/logs"
+		_, err := client.PutSubscriptionFilter(context.Background(), kinesis)
+		assert.ErrorContains(t, err, "requires the ARN of a role")
+
+		kinesis.RoleARN = "arn:aws:iam::123456789012:role/cwl-to-kinesis"
+		_, err = client.PutSubscriptionFilter(context.Background(), kinesis)
+		assert.NoError(t, err)
+		if assert.Len(t, mock.puts, 1) {
+			assert.Equal(t, kinesis.RoleARN, aws.ToString(mock.puts[0].RoleArn))
+		}
+		assert.Empty(t, mock.permissions)
+	})
+}
+
+// mockInsightsClient returns statuses from its list, then Complete with results, and
+// holds the saved queries
+type mockInsightsClient struct {
+	statuses    []cwt.QueryStatus
+	results     [][]cwt.ResultField
+	started     []*cloudwatchlogs.StartQueryInput
+	stopped     int
+	definitions []cwt.QueryDefinition
+	puts        []*cloudwatchlogs.PutQueryDefinitionInput
+}
+
+func (m *mockInsightsClient) StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error) {
+	m.started = append(m.started, params)
+	return &cloudwatchlogs.StartQueryOutput{QueryId: aws.String("q-1")}, nil
+}
+
+func (m *mockInsightsClient) GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error) {
+	if len(m.statuses) > 0 {
+		status := m.statuses[0]
+		m.statuses = m.statuses[1:]
+		return &cloudwatchlogs.GetQueryResultsOutput{Status: status}, nil
+	}
+	return &cloudwatchlogs.GetQueryResultsOutput{
+		Status:     cwt.QueryStatusComplete,
+		Results:    m.results,
+		Statistics: &cwt.QueryStatistics{RecordsMatched: 42, RecordsScanned: 1000, BytesScanned: 2048},
+	}, nil
+}
+
+func (m *mockInsightsClient) StopQuery(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error) {
+	m.stopped++
+	return &cloudwatchlogs.StopQueryOutput{Success: true}, nil
+}
+
+func (m *mockInsightsClient) DescribeQueryDefinitions(ctx context.Context, params *cloudwatchlogs.DescribeQueryDefinitionsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeQueryDefinitionsOutput, error) {
+	return &cloudwatchlogs.DescribeQueryDefinitionsOutput{QueryDefinitions: m.definitions}, nil
+}
+
+func (m *mockInsightsClient) PutQueryDefinition(ctx context.Context, params *cloudwatchlogs.PutQueryDefinitionInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutQueryDefinitionOutput, error) {
+	m.puts = append(m.puts, params)
+	return &cloudwatchlogs.PutQueryDefinitionOutput{QueryDefinitionId: aws.String("d-1")}, nil
+}
+
+func resultRow(fields ...string) []cwt.ResultField {
+	var row []cwt.ResultField
+	for i := 0; i+1 < len(fields); i += 2 {
+		row = append(row, cwt.ResultField{Field: aws.String(fields[i]), Value: aws.String(fields[i+1])})
+	}
+	return row
+}
+
+func TestRunInsightsQuery(t *testing.T) {
+	defer func(interval time.Duration) { insightsPollInterval = interval }(insightsPollInterval)
+	insightsPollInterval = time.Millisecond
+
+	mock := &mockInsightsClient{
+		statuses: []cwt.QueryStatus{cwt.QueryStatusScheduled, cwt.QueryStatusRunning},
+		results: [][]cwt.ResultField{
+			resultRow("verb", "list", "requests", "120", "@ptr", "abc"),
+			resultRow("verb", "get", "objectRef.resource", "pods", "requests", "80"),
+		},
+	}
+	client := &EKSLogsClient{insightsClient: mock}
+	start := time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC)
+	result, err := client.RunInsightsQuery(context.Background(), []string{ClusterLogGroup("prod")}, "stats count(*)", start, start.Add(time.Hour), 100)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"verb", "requests", "objectRef.resource"}, result.Fields)
+	assert.Equal(t, []map[string]string{
+		{"verb": "list", "requests": "120"},
+		{"verb": "get", "objectRef.resource": "pods", "requests": "80"},
+	}, result.Rows)
+	assert.Equal(t, 42.0, result.RecordsMatched)
+	if assert.Len(t, mock.started, 1) {
+		assert.Equal(t, start.Unix(), aws.ToInt64(mock.started[0].StartTime))
+		assert.Equal(t, start.Add(time.Hour).Unix(), aws.ToInt64(mock.started[0].EndTime))
+		assert.Equal(t, int32(100), aws.ToInt32(mock.started[0].Limit))
+	}
+
+	mock.statuses = []cwt.QueryStatus{cwt.QueryStatusFailed}
+	_, err = client.RunInsightsQuery(context.Background(), nil, "stats count(*)", start, start.Add(time.Hour), 0)
+	assert.ErrorContains(t, err, "ended with status Failed")
+
+	// A cancelled run stops the query
+	mock.statuses = []cwt.QueryStatus{cwt.QueryStatusRunning}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.RunInsightsQuery(ctx, nil, "stats count(*)", start, start.Add(time.Hour), 0)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, mock.stopped)
+}
+
+func TestSaveQueryDefinition(t *testing.T) {
+	mock := &mockInsightsClient{definitions: []cwt.QueryDefinition{
+		{Name: aws.String("ekslogs/prod/audit-top-verbs-old"), QueryDefinitionId: aws.String("d-0")},
+		{Name: aws.String("ekslogs/prod/audit-top-verbs"), QueryDefinitionId: aws.String("d-1")},
+	}}
+	client := &EKSLogsClient{insightsClient: mock}
+
+	updated, err := client.SaveQueryDefinition(context.Background(), QueryDefinition{
+		Name: "ekslogs/prod/audit-top-verbs", Query: "stats count(*)", LogGroups: []string{ClusterLogGroup("prod")},
+	})
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	updated, err = client.SaveQueryDefinition(context.Background(), QueryDefinition{Name: "ekslogs/prod/slow-requests", Query: "sort ms desc"})
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	if assert.Len(t, mock.puts, 2) {
+		assert.Equal(t, "d-1", aws.ToString(mock.puts[0].QueryDefinitionId))
+		assert.Equal(t, []string{"/aws/eks/prod/cluster"}, mock.puts[0].LogGroupNames)
+		assert.Nil(t, mock.puts[1].QueryDefinitionId)
+	}
+}
+
+// notFoundEKSClient has no cluster
+type notFoundEKSClient struct{}
+
+func (m *notFoundEKSClient) ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	return &eks.ListClustersOutput{}, nil
+}
+
+func (m *notFoundEKSClient) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	return nil, &smithy.GenericAPIError{Code: "ResourceNotFoundException", Message: "No cluster found for name: " + aws.ToString(params.Name)}
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	end := time.Now()
+	start := end.Add(-time.Hour)
+	mock := &mockLogsClient{events: mockEvents(end, 10, "message"), pageSize: 4}
+	client := &EKSLogsClient{logsClient: mock, eksClient: &notFoundEKSClient{}}
+	assert.NoError(t, client.SetRecordDir(dir))
+
+	recorded, err := client.CollectLogs(context.Background(), "test", nil, &start, &end, nil, 0)
+	assert.NoError(t, err)
+	assert.Len(t, recorded, 10)
+	_, err = client.GetClusterInfo(context.Background(), "test")
+	assert.Error(t, err)
+	files, _ := os.ReadDir(dir)
+	// DescribeLogGroups, DescribeLogStreams, 3 pages of FilterLogEvents, DescribeCluster, ListClusters
+	assert.Len(t, files, 7)
+
+	// The time range is shifted, as by a relative -s
+	replay, err := NewReplayClient("us-east-1", dir, nil)
+	assert.NoError(t, err)
+	start, end = start.Add(time.Minute), end.Add(time.Minute)
+	replayed, err := replay.CollectLogs(context.Background(), "test", nil, &start, &end, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, recorded, replayed)
+
+	// Errors are replayed with their code
+	_, err = replay.GetClusterInfo(context.Background(), "test")
+	assert.True(t, IsNotFoundError(err))
+
+	// Calls that were not recorded fail
+	_, err = replay.CollectLogs(context.Background(), "test", nil, &start, &end, aws.String("ERROR"), 0)
+	assert.ErrorContains(t, err, "no recorded response")
+
+	_, err = NewReplayClient("us-east-1", t.TempDir(), nil)
+	assert.ErrorContains(t, err, "no recorded calls")
+}
+
+// deniedLogsClient denies DescribeLogStreams or FilterLogEvents, like a minimal read-only role
+type deniedLogsClient struct {
+	mockLogsClient
+	denyStreams  bool
+	denyFilter   bool
+	filterInputs []*cloudwatchlogs.FilterLogEventsInput
+}
+
+func (m *deniedLogsClient) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	if m.denyStreams {
+		return nil, &smithy.GenericAPIError{Code: "AccessDeniedException"}
+	}
+	return m.mockLogsClient.DescribeLogStreams(ctx, params, optFns...)
+}
+
+func (m *deniedLogsClient) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	if m.denyFilter {
+		return nil, &smithy.GenericAPIError{Code: "AccessDeniedException"}
+	}
+	m.filterInputs = append(m.filterInputs, params)
+	return m.mockLogsClient.FilterLogEvents(ctx, params, optFns...)
+}
+
+// TestProbePermissions tests that the denied read API calls are found
+func TestProbePermissions(t *testing.T) {
+	client := &EKSLogsClient{logsClient: &deniedLogsClient{}}
+	permissions, err := client.ProbePermissions(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, Permissions{}, permissions)
+
+	client = &EKSLogsClient{logsClient: &deniedLogsClient{denyStreams: true}}
+	permissions, err = client.ProbePermissions(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, Permissions{DescribeLogStreamsDenied: true}, permissions)
+
+	client = &EKSLogsClient{logsClient: &deniedLogsClient{denyStreams: true, denyFilter: true}}
+	_, err = client.ProbePermissions(context.Background(), "test")
+	assert.ErrorIs(t, err, ErrNoReadPermission)
+}
+
+// TestGetLogsDegraded tests that the logs are read without the denied API calls
+func TestGetLogsDegraded(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []cwt.FilteredLogEvent{
+		{Timestamp: aws.Int64(start.UnixMilli()), LogStreamName: aws.String("kube-apiserver-123"), Message: aws.String("api 1")},
+		{Timestamp: aws.Int64(start.Add(time.Second).UnixMilli()), LogStreamName: aws.String("kube-apiserver-audit-123"), Message: aws.String("audit")},
+		{Timestamp: aws.Int64(start.Add(2 * time.Second).UnixMilli()), LogStreamName: aws.String("kube-apiserver-123"), Message: aws.String("api 2")},
+	}
+	messages := func(entries []log.LogEntry) []string {
+		var messages []string
+		for _, entry := range entries {
+			messages = append(messages, entry.Message)
+		}
+		return messages
+	}
+
+	// Without stream listing, the log group is searched and the log types selected here
+	mock := &deniedLogsClient{mockLogsClient: mockLogsClient{events: events}, denyStreams: true}
+	client := &EKSLogsClient{logsClient: mock}
+	_, err := client.ProbePermissions(context.Background(), "test")
+	assert.NoError(t, err)
+	entries, err := client.CollectLogs(context.Background(), "test", []string{"api"}, &start, nil, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api 1", "api 2"}, messages(entries))
+	assert.Empty(t, mock.filterInputs[len(mock.filterInputs)-1].LogStreamNames)
+
+	// Without FilterLogEvents, the streams are read one after the other
+	mock = &deniedLogsClient{mockLogsClient: mockLogsClient{events: events, pageSize: 1}, denyFilter: true}
+	client = &EKSLogsClient{logsClient: mock}
+	_, err = client.ProbePermissions(context.Background(), "test")
+	assert.NoError(t, err)
+	entries, err = client.CollectLogs(context.Background(), "test", nil, &start, nil, nil, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"api 1", "audit", "api 2"}, messages(entries))
+
+	pattern := "error"
+	_, err = client.CollectLogs(context.Background(), "test", nil, &start, nil, &pattern, 0)
+	assert.ErrorContains(t, err, "filter patterns")
+}
@@ -0,0 +1,225 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	ctt "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+// eksEventSource is the CloudTrail event source of the EKS API
+const eksEventSource = "eks.amazonaws.com"
+
+// maxCorrelatedPrincipals bounds the number of principals of CloudTrailChanges.Pattern, as
+// each one is a term of the filter pattern
+const maxCorrelatedPrincipals = 10
+
+// CloudTrailAPI defines the interface for the CloudTrail client.
+type CloudTrailAPI interface {
+	LookupEvents(ctx context.Context, params *cloudtrail.LookupEventsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.LookupEventsOutput, error)
+}
+
+// cloudTrailRecord holds the fields of a raw CloudTrail event used to describe a change
+type cloudTrailRecord struct {
+	UserIdentity struct {
+		ARN string `json:"arn"`
+	} `json:"userIdentity"`
+	SourceIPAddress   string          `json:"sourceIPAddress"`
+	ErrorCode         string          `json:"errorCode"`
+	RequestParameters json.RawMessage `json:"requestParameters"`
+}
+
+// CloudTrailChanges holds the CloudTrail events that changed a cluster, and the IAM
+// principals that made them
+type CloudTrailChanges struct {
+	Entries []log.LogEntry
+	// Principals are the names of the IAM users and roles that made the calls, e.g. "alice"
+	// for arn:aws:iam::123456789012:user/alice, in order of first appearance
+	Principals []string
+}
+
+// Pattern returns a filter pattern selecting the control plane logs that mention one of the
+// principals, such as their authenticator and audit events. It is empty without principals.
+func (c CloudTrailChanges) Pattern() string {
+	terms := make([]string, 0, len(c.Principals))
+	for i, principal := range c.Principals {
+		if i == maxCorrelatedPrincipals {
+			break
+		}
+		terms = append(terms, fmt.Sprintf("?%q", principal))
+	}
+	return strings.Join(terms, " ")
+}
+
+// GetCloudTrailEvents returns the CloudTrail events that changed a cluster between startTime
+// and endTime, sorted by time: write calls of the EKS API on the cluster (including access
+// entry changes), and calls on the cluster IAM role such as AssumeRole. The role is skipped
+// when roleArn is empty.
+func (c *EKSLogsClient) GetCloudTrailEvents(ctx context.Context, clusterName, roleArn string, startTime, endTime time.Time) (CloudTrailChanges, error) {
+	seen := make(map[string]bool)
+	seenPrincipals := make(map[string]bool)
+	var changes CloudTrailChanges
+	add := func(event ctt.Event) {
+		id := aws.ToString(event.EventId)
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+		entry, principal := cloudTrailEntry(event)
+		changes.Entries = append(changes.Entries, entry)
+		if principal != "" && !seenPrincipals[principal] {
+			seenPrincipals[principal] = true
+			changes.Principals = append(changes.Principals, principal)
+		}
+	}
+
+	err := c.lookupEvents(ctx, ctt.LookupAttributeKeyEventSource, eksEventSource, startTime, endTime, func(event ctt.Event) {
+		if !isReadOnlyEvent(event) && cloudTrailCluster(event) == clusterName {
+			add(event)
+		}
+	})
+	if err != nil {
+		return CloudTrailChanges{}, err
+	}
+
+	if roleArn != "" {
+		err := c.lookupEvents(ctx, ctt.LookupAttributeKeyResourceName, roleArn, startTime, endTime, func(event ctt.Event) {
+			if aws.ToString(event.EventName) == "AssumeRole" || !isReadOnlyEvent(event) {
+				add(event)
+			}
+		})
+		if err != nil {
+			return CloudTrailChanges{}, err
+		}
+	}
+
+	log.SortEntries(changes.Entries, log.SortOrderAsc, log.TimestampSourceEvent)
+	return changes, nil
+}
+
+// lookupEvents passes every CloudTrail event matching a lookup attribute in the time range to fn
+func (c *EKSLogsClient) lookupEvents(ctx context.Context, key ctt.LookupAttributeKey, value string, startTime, endTime time.Time, fn func(ctt.Event)) error {
+	input := &cloudtrail.LookupEventsInput{
+		LookupAttributes: []ctt.LookupAttribute{{AttributeKey: key, AttributeValue: aws.String(value)}},
+		StartTime:        aws.Time(startTime),
+		EndTime:          aws.Time(endTime),
+		MaxResults:       aws.Int32(50), // Maximum page size of LookupEvents
+	}
+
+	for {
+		if err := c.countAPICall(); err != nil {
+			return err
+		}
+
+		resp, err := c.trailClient.LookupEvents(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to look up CloudTrail events (%s=%s): %w", key, value, err)
+		}
+		c.counters.pages.Add(1)
+		c.log().Debug("Received CloudTrail page", "attribute", string(key), "value", value, "events", len(resp.Events))
+
+		for _, event := range resp.Events {
+			fn(event)
+		}
+
+		if resp.NextToken == nil {
+			return nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// isReadOnlyEvent reports whether a CloudTrail event records a call that changed nothing
+func isReadOnlyEvent(event ctt.Event) bool {
+	return aws.ToString(event.ReadOnly) == "true"
+}
+
+// cloudTrailCluster returns the name of the cluster an EKS API call was made on. Cluster
+// calls name it "name", calls on access entries, add-ons and node groups "clusterName".
+func cloudTrailCluster(event ctt.Event) string {
+	var record cloudTrailRecord
+	if err := json.Unmarshal([]byte(aws.ToString(event.CloudTrailEvent)), &record); err != nil {
+		return ""
+	}
+	var params struct {
+		Name        string `json:"name"`
+		ClusterName string `json:"clusterName"`
+	}
+	if err := json.Unmarshal(record.RequestParameters, &params); err != nil {
+		return ""
+	}
+	if params.ClusterName != "" {
+		return params.ClusterName
+	}
+	return params.Name
+}
+
+// principalName returns the name of the IAM user or role of an ARN, e.g. "alice" for
+// arn:aws:iam::123456789012:user/alice and "admin" for
+// arn:aws:sts::123456789012:assumed-role/admin/session. It is empty for other callers,
+// such as AWS services.
+func principalName(arn string) string {
+	if !strings.HasPrefix(arn, "arn:") {
+		return ""
+	}
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	resource := strings.Split(parts[5], "/")
+	switch {
+	case resource[0] == "assumed-role" && len(resource) >= 2:
+		return resource[1]
+	case (resource[0] == "user" || resource[0] == "role") && len(resource) >= 2:
+		// Users and roles may have a path, e.g. role/service-role/admin
+		return resource[len(resource)-1]
+	default:
+		return ""
+	}
+}
+
+// cloudTrailEntry converts a CloudTrail event into a log entry describing who made which call,
+// e.g. "UpdateClusterConfig by arn:aws:iam::123456789012:user/alice from 203.0.113.10 {...}",
+// and returns the name of the principal that made it
+func cloudTrailEntry(event ctt.Event) (log.LogEntry, string) {
+	var record cloudTrailRecord
+	_ = json.Unmarshal([]byte(aws.ToString(event.CloudTrailEvent)), &record)
+
+	name := aws.ToString(event.EventName)
+	who := record.UserIdentity.ARN
+	if who == "" {
+		who = aws.ToString(event.Username)
+	}
+
+	message := name
+	if who != "" {
+		message += " by " + who
+	}
+	if record.SourceIPAddress != "" {
+		message += " from " + record.SourceIPAddress
+	}
+	level := "info"
+	if record.ErrorCode != "" {
+		message += " failed: " + record.ErrorCode
+		level = "error"
+	}
+	if len(record.RequestParameters) > 0 && string(record.RequestParameters) != "null" {
+		message += " " + string(record.RequestParameters)
+	}
+
+	return log.LogEntry{
+		Timestamp: aws.ToTime(event.EventTime),
+		Level:     level,
+		Component: aws.ToString(event.EventSource),
+		Message:   message,
+		LogGroup:  log.CloudTrailLogGroup,
+		LogStream: name,
+		EventID:   aws.ToString(event.EventId),
+	}, principalName(record.UserIdentity.ARN)
+}
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"golang.org/x/term"
+)
+
+// interactiveProcessTimeout bounds a credential_process run from a terminal, leaving time for
+// the process to prompt for input such as an MFA code (the SDK default is one minute)
+const interactiveProcessTimeout = 5 * time.Minute
+
+// SSOLoginCommand returns the command renewing the AWS SSO session of the active profile
+func SSOLoginCommand() string {
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = os.Getenv("AWS_DEFAULT_PROFILE")
+	}
+	if profile == "" || profile == "default" {
+		return "aws sso login"
+	}
+	return "aws sso login --profile " + profile
+}
+
+// SetAssumeRole makes every API call with the credentials of an IAM role, assumed with the
+// credentials of the AWS configuration
+func (c *EKSLogsClient) SetAssumeRole(roleARN string) error {
+	c.roleARN = roleARN
+	return c.loadClients(context.TODO())
+}
+
+// refreshCredentials reloads the AWS configuration, so that a long follow session picks up
+// credentials renewed outside the process (a new `aws sso login`, a rewritten credentials
+// file or a new role session) instead of failing every poll with an expired token.
+// It must not run while API calls are in flight.
+func (c *EKSLogsClient) refreshCredentials(ctx context.Context) error {
+	c.log().Info("Reloading AWS credentials")
+	return c.loadClients(ctx)
+}
+
+// interactiveCredentialOptions lets credential providers prompt on the terminal: profiles with
+// mfa_serial ask for the MFA token code, and credential_process commands, which already share
+// stdin and stderr, get more time to complete. Without a terminal there is nobody to answer a
+// prompt, so the SDK defaults are kept.
+func interactiveCredentialOptions() []func(*config.LoadOptions) error {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return nil
+	}
+	return []func(*config.LoadOptions) error{
+		config.WithAssumeRoleCredentialOptions(func(o *stscreds.AssumeRoleOptions) {
+			o.TokenProvider = func() (string, error) {
+				return readMFAToken(os.Stdin, os.Stderr)
+			}
+		}),
+		config.WithProcessCredentialOptions(func(o *processcreds.Options) {
+			o.Timeout = interactiveProcessTimeout
+		}),
+	}
+}
+
+// readMFAToken prompts for an MFA token code on w and reads it from r. The prompt is not
+// written to stdout, which carries the log events.
+func readMFAToken(r io.Reader, w io.Writer) (string, error) {
+	_, _ = fmt.Fprint(w, "MFA token code: ")
+	line, err := bufio.NewReader(r).ReadString('\n')
+	code := strings.TrimSpace(line)
+	if code == "" {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", fmt.Errorf("failed to read MFA token code: %w", err)
+	}
+	return code, nil
+}
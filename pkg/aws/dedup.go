@@ -0,0 +1,176 @@
+package aws
+
+import (
+	"fmt"
+	"hash/maphash"
+	"sync"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+// recentKeys remembers the most recently added keys up to a fixed capacity, forgetting
+// the oldest first, so duplicate suppression uses bounded memory
+type recentKeys[K comparable] struct {
+	keys  map[K]struct{}
+	ring  []K
+	next  int
+	limit int
+}
+
+// newRecentKeys creates a set remembering up to limit keys
+func newRecentKeys[K comparable](limit int) *recentKeys[K] {
+	return &recentKeys[K]{
+		keys:  make(map[K]struct{}),
+		ring:  make([]K, 0, min(limit, 1024)),
+		limit: limit,
+	}
+}
+
+// add records key and reports whether it was not already present
+func (r *recentKeys[K]) add(key K) bool {
+	if _, ok := r.keys[key]; ok {
+		return false
+	}
+
+	if len(r.ring) < r.limit {
+		r.ring = append(r.ring, key)
+	} else {
+		delete(r.keys, r.ring[r.next])
+		r.ring[r.next] = key
+		r.next = (r.next + 1) % r.limit
+	}
+	r.keys[key] = struct{}{}
+	return true
+}
+
+// len returns the number of remembered keys
+func (r *recentKeys[K]) len() int {
+	return len(r.keys)
+}
+
+const (
+	// tailDedupSize is the number of recently printed events remembered by follow mode
+	tailDedupSize = 10000
+	// maxStreamSkew bounds how far behind the newest stream a stream's events are still polled for.
+	// Streams further behind, or quiet for longer, no longer hold back the poll start.
+	maxStreamSkew = 30 * time.Second
+)
+
+// tailCursor tracks the position of follow mode in each log stream, so that streams whose
+// clocks lag behind the others do not lose events, and remembers printed events to skip
+// them when overlapping ranges are polled again
+type tailCursor struct {
+	initial  time.Time
+	streams  map[string]time.Time
+	newest   time.Time
+	rewindTo time.Time
+	printed  *recentKeys[string]
+}
+
+// newTailCursor creates a cursor starting at initial
+func newTailCursor(initial time.Time) *tailCursor {
+	return &tailCursor{
+		initial: initial,
+		streams: make(map[string]time.Time),
+		newest:  initial,
+		printed: newRecentKeys[string](tailDedupSize),
+	}
+}
+
+// start returns the start time of the next poll: the oldest position among streams
+// that are within maxStreamSkew of the newest event
+func (t *tailCursor) start() time.Time {
+	start := t.newest
+	if !t.rewindTo.IsZero() {
+		start = t.rewindTo
+		t.rewindTo = time.Time{}
+	}
+	for stream, last := range t.streams {
+		if t.newest.Sub(last) > maxStreamSkew {
+			// Forget streams that fell behind so the map does not grow with rotated streams
+			delete(t.streams, stream)
+			continue
+		}
+		if last.Before(start) {
+			start = last
+		}
+	}
+	return start
+}
+
+// rewind makes the next poll start at t at the latest, to read events that were not polled
+// for, such as those of a stream found late. Events printed before are still skipped.
+func (t *tailCursor) rewind(to time.Time) {
+	if to.IsZero() {
+		return
+	}
+	if to.Before(t.initial) {
+		to = t.initial
+	}
+	if t.rewindTo.IsZero() || to.Before(t.rewindTo) {
+		t.rewindTo = to
+	}
+}
+
+// accept records entry and reports whether it has not been printed yet
+func (t *tailCursor) accept(entry log.LogEntry) bool {
+	if entry.Timestamp.Before(t.initial) {
+		return false
+	}
+	if last, ok := t.streams[entry.LogStream]; ok && entry.Timestamp.Before(last) {
+		return false
+	}
+
+	// Identical timestamps are possible within a stream, so the position alone is not enough
+	if !t.printed.add(eventKey(entry)) {
+		return false
+	}
+
+	t.streams[entry.LogStream] = entry.Timestamp
+	if entry.Timestamp.After(t.newest) {
+		t.newest = entry.Timestamp
+	}
+	return true
+}
+
+// eventKey identifies a log event for duplicate suppression. The CloudWatch event ID is exact;
+// the timestamp, stream and message are only used for entries without one and collide for
+// identical messages repeated within a millisecond.
+func eventKey(entry log.LogEntry) string {
+	if entry.EventID != "" {
+		return entry.LogGroup + "/" + entry.EventID
+	}
+	return fmt.Sprintf("%d-%s-%s", entry.Timestamp.UnixNano(), entry.LogStream, entry.Message)
+}
+
+// retrievalDedupSize is the number of event IDs a retrieval remembers to drop the events
+// returned twice
+const retrievalDedupSize = 1 << 20
+
+// eventIDSet drops the events a retrieval receives more than once, such as those of log
+// groups matched by several prefixes or of a page read again, by their CloudWatch event ID.
+// The IDs are remembered as 64-bit hashes to bound memory. It is safe for concurrent use.
+type eventIDSet struct {
+	mu   sync.Mutex
+	seed maphash.Seed
+	seen *recentKeys[uint64]
+}
+
+// newEventIDSet creates an empty set
+func newEventIDSet() *eventIDSet {
+	return &eventIDSet{seed: maphash.MakeSeed(), seen: newRecentKeys[uint64](retrievalDedupSize)}
+}
+
+// add records an entry and reports whether it was not received before. Entries without an
+// event ID are always new: identical messages within a millisecond are distinct events.
+func (s *eventIDSet) add(entry log.LogEntry) bool {
+	if entry.EventID == "" {
+		return true
+	}
+	hash := maphash.String(s.seed, eventKey(entry))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen.add(hash)
+}
@@ -0,0 +1,114 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials/ssocreds"
+	"github.com/aws/smithy-go"
+)
+
+var (
+	// ErrClusterNotFound is returned when the cluster does not exist in the region
+	ErrClusterNotFound = errors.New("not found")
+	// ErrNoLogGroups is returned when the cluster has no control plane log groups
+	ErrNoLogGroups = errors.New("no log groups found")
+)
+
+// ClusterNotFoundError is returned when the cluster does not exist in the region but other
+// clusters do. Matches lists those with a similar name, most likely first.
+type ClusterNotFoundError struct {
+	Name      string
+	Available []string
+	Matches   []string
+}
+
+func (e *ClusterNotFoundError) Error() string {
+	msg := fmt.Sprintf("cluster '%s' %v.", e.Name, ErrClusterNotFound)
+	if len(e.Matches) > 0 {
+		msg += fmt.Sprintf(" Did you mean: %s?", strings.Join(e.Matches, ", "))
+	}
+	return msg + fmt.Sprintf(" Available clusters: %v", e.Available)
+}
+
+func (e *ClusterNotFoundError) Unwrap() error {
+	return ErrClusterNotFound
+}
+
+// authErrorCodes lists the AWS error codes of missing, invalid or insufficient credentials
+var authErrorCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"UnauthorizedOperation":       true,
+	"UnrecognizedClientException": true,
+	"InvalidClientTokenId":        true,
+	"ExpiredToken":                true,
+	"ExpiredTokenException":       true,
+	"SignatureDoesNotMatch":       true,
+	"InvalidSignatureException":   true,
+}
+
+// notFoundErrorCodes lists the AWS error codes of missing resources
+var notFoundErrorCodes = map[string]bool{
+	"ResourceNotFoundException": true,
+	"NotFoundException":         true,
+}
+
+// IsAuthError reports whether err was caused by missing, expired or insufficient AWS credentials
+func IsAuthError(err error) bool {
+	var signingErr *v4.SigningError
+	if errors.As(err, &signingErr) || IsSSOSessionExpired(err) || IsMFARequired(err) {
+		return true
+	}
+	return authErrorCodes[apiErrorCode(err)]
+}
+
+// IsAccessDenied reports whether err was caused by valid credentials lacking the permission
+// for an API call
+func IsAccessDenied(err error) bool {
+	switch apiErrorCode(err) {
+	case "AccessDenied", "AccessDeniedException", "UnauthorizedOperation":
+		return true
+	}
+	return false
+}
+
+// IsSSOSessionExpired reports whether err was caused by an expired or missing AWS SSO
+// session, which only a new `aws sso login` renews
+func IsSSOSessionExpired(err error) bool {
+	var tokenErr *ssocreds.InvalidTokenError
+	if errors.As(err, &tokenErr) {
+		return true
+	}
+	// The SSO token provider of sso-session profiles reports a plain error
+	return err != nil && strings.Contains(err.Error(), "cached SSO token is expired")
+}
+
+// IsThrottleError reports whether err was caused by AWS API throttling
+func IsThrottleError(err error) bool {
+	return err != nil && retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
+
+// IsNotFoundError reports whether err was caused by a missing cluster or log group
+func IsNotFoundError(err error) bool {
+	return errors.Is(err, ErrClusterNotFound) || errors.Is(err, ErrNoLogGroups) || notFoundErrorCodes[apiErrorCode(err)]
+}
+
+// IsMFARequired reports whether err was caused by a profile requiring an MFA token code that
+// could not be prompted for, because ekslogs did not run in a terminal
+func IsMFARequired(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "MFA enabled, but TokenProvider is not set")
+}
+
+// apiErrorCode returns the AWS error code wrapped in err, if any
+func apiErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
@@ -0,0 +1,274 @@
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+// streamIdleAfter is the time without ingestion after which follow mode reports a log stream as idle
+const streamIdleAfter = 5 * time.Minute
+
+// ErrSilence is returned by TailLogs with FollowOptions.ExitOnSilence when a log type stops producing events
+var ErrSilence = errors.New("log type silent")
+
+// highVolumeEvents is the number of new events in a poll from which follow mode polls at its minimum interval
+const highVolumeEvents = 100
+
+// FollowOptions controls follow mode (TailLogs)
+type FollowOptions struct {
+	// MaxInterval is the longest poll interval follow mode backs off to while no new events
+	// arrive. Zero, or a value not above the poll interval, keeps the interval fixed.
+	MaxInterval time.Duration
+	// Heartbeat is the period of silence after which OnStatus is told that no new events
+	// arrived, repeated every period. Zero disables the heartbeat.
+	Heartbeat time.Duration
+	// SilenceAlert is the period without matching events after which a log type is reported
+	// as silent through OnStatus. Zero disables silence detection.
+	SilenceAlert time.Duration
+	// ExitOnSilence makes TailLogs return ErrSilence instead of only reporting a silent log type
+	ExitOnSilence bool
+	// OnStatus receives the heartbeat, silence and connection status notices of follow mode
+	// (failing polls and recovery). A nil function discards them.
+	OnStatus func(message string)
+}
+
+// SetFollowOptions sets the options applied by subsequent TailLogs calls
+func (c *EKSLogsClient) SetFollowOptions(options FollowOptions) {
+	c.follow = options
+}
+
+// adaptiveInterval computes the delay between follow mode polls: it doubles toward max
+// while polls return nothing, halves toward min when events arrive, and drops straight to
+// min when the volume is high
+type adaptiveInterval struct {
+	min     time.Duration
+	max     time.Duration
+	current time.Duration
+}
+
+// newAdaptiveInterval creates an interval starting at min
+func newAdaptiveInterval(min, max time.Duration) *adaptiveInterval {
+	if max < min {
+		max = min
+	}
+	return &adaptiveInterval{min: min, max: max, current: min}
+}
+
+// next returns the delay before the next poll, given the number of new events of the last poll
+func (a *adaptiveInterval) next(newEvents int) time.Duration {
+	switch {
+	case newEvents >= highVolumeEvents:
+		a.current = a.min
+	case newEvents > 0:
+		a.current = max(a.current/2, a.min)
+	default:
+		a.current = min(a.current*2, a.max)
+	}
+	return a.current
+}
+
+// followStatus derives the heartbeat and connection status notices of follow mode from
+// the outcome of each poll
+type followStatus struct {
+	heartbeat  time.Duration
+	lastEvent  time.Time
+	lastNotice time.Time
+	failures   int
+	throttled  bool
+}
+
+// newFollowStatus creates a status tracker for a follow session starting at now
+func newFollowStatus(heartbeat time.Duration, now time.Time) *followStatus {
+	return &followStatus{heartbeat: heartbeat, lastEvent: now, lastNotice: now}
+}
+
+// update records the outcome of a poll completed at now and returns the notices to report
+func (f *followStatus) update(now time.Time, newEvents int, err error) []string {
+	var notices []string
+
+	if err != nil {
+		f.failures++
+		if IsThrottleError(err) {
+			f.throttled = true
+		}
+		if f.failures == 1 {
+			if IsSSOSessionExpired(err) {
+				notices = append(notices, fmt.Sprintf("AWS SSO session expired, run '%s' to continue following", SSOLoginCommand()))
+			} else {
+				notices = append(notices, fmt.Sprintf("connection problem, retrying: %v", err))
+			}
+		}
+		return notices
+	}
+
+	if f.failures > 0 {
+		cause := "errors"
+		if f.throttled {
+			cause = "throttling"
+		}
+		notices = append(notices, fmt.Sprintf("reconnected after %s (%d failed polls)", cause, f.failures))
+		f.failures = 0
+		f.throttled = false
+	}
+
+	if newEvents > 0 {
+		f.lastEvent = now
+		f.lastNotice = now
+		return notices
+	}
+
+	if f.heartbeat > 0 && now.Sub(f.lastNotice) >= f.heartbeat {
+		notices = append(notices, fmt.Sprintf("no new events for %s, still following", now.Sub(f.lastEvent).Round(time.Second)))
+		f.lastNotice = now
+	}
+	return notices
+}
+
+// notify passes a status notice to the OnStatus callback
+func (c *EKSLogsClient) notify(message string) {
+	if c.follow.OnStatus != nil {
+		c.follow.OnStatus(message)
+	}
+}
+
+// silenceWatch detects log types that stopped producing events. The watched types are the
+// requested ones, or every type seen during the session when none were requested.
+type silenceWatch struct {
+	limit    time.Duration
+	explicit bool
+	lastSeen map[string]time.Time
+	alerted  map[string]bool
+}
+
+// newSilenceWatch creates a watch of logTypes (normalized) starting at now
+func newSilenceWatch(limit time.Duration, logTypes []string, now time.Time) *silenceWatch {
+	w := &silenceWatch{
+		limit:    limit,
+		explicit: len(logTypes) > 0,
+		lastSeen: make(map[string]time.Time),
+		alerted:  make(map[string]bool),
+	}
+	for _, logType := range logTypes {
+		w.lastSeen[logType] = now
+	}
+	return w
+}
+
+// observe records an event printed at now
+func (w *silenceWatch) observe(entry log.LogEntry, now time.Time) {
+	logType := log.LogTypeFor(entry.LogGroup, entry.LogStream)
+	if logType == "" {
+		return
+	}
+	if _, watched := w.lastSeen[logType]; watched || !w.explicit {
+		w.lastSeen[logType] = now
+		w.alerted[logType] = false
+	}
+}
+
+// check returns the log types that became silent since the previous check, sorted by name
+func (w *silenceWatch) check(now time.Time) []string {
+	if w.limit <= 0 {
+		return nil
+	}
+
+	var silent []string
+	for logType, last := range w.lastSeen {
+		if !w.alerted[logType] && now.Sub(last) >= w.limit {
+			w.alerted[logType] = true
+			silent = append(silent, logType)
+		}
+	}
+	sort.Strings(silent)
+	return silent
+}
+
+// silenceError reports silent log types
+func silenceError(silent []string, limit time.Duration) error {
+	return fmt.Errorf("%w: no %s events for %s", ErrSilence, strings.Join(silent, ", "), limit)
+}
+
+// streamWatch detects log streams appearing or going idle between the stream listings of
+// follow mode, e.g. new kube-apiserver instances after the control plane scaled.
+// Ingestion times are used because the last event timestamps are only eventually consistent.
+type streamWatch struct {
+	mu       sync.Mutex
+	groups   map[string]map[string]bool // log group -> stream name -> idle
+	idleTime time.Duration
+}
+
+// streamChange is a log stream that appeared or went idle
+type streamChange struct {
+	logGroup string
+	stream   string
+	idle     bool
+	created  time.Time
+}
+
+// newStreamWatch creates an empty stream watch
+func newStreamWatch(idleAfter time.Duration) *streamWatch {
+	return &streamWatch{groups: make(map[string]map[string]bool), idleTime: idleAfter}
+}
+
+// update compares a listing of a log group with the previous one. The first listing of
+// a group only records its streams.
+func (w *streamWatch) update(logGroup string, streams []cwt.LogStream, now time.Time) []streamChange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	known, seen := w.groups[logGroup]
+	if !seen {
+		known = make(map[string]bool)
+		w.groups[logGroup] = known
+	}
+
+	var changes []streamChange
+	for _, stream := range streams {
+		if stream.LogStreamName == nil {
+			continue
+		}
+		name := *stream.LogStreamName
+		idle := stream.LastIngestionTime != nil && now.Sub(time.UnixMilli(*stream.LastIngestionTime)) >= w.idleTime
+
+		wasIdle, exists := known[name]
+		known[name] = idle
+		switch {
+		case !seen:
+		case !exists && !idle:
+			change := streamChange{logGroup: logGroup, stream: name}
+			if stream.CreationTime != nil {
+				change.created = time.UnixMilli(*stream.CreationTime)
+			}
+			changes = append(changes, change)
+		case exists && idle && !wasIdle:
+			changes = append(changes, streamChange{logGroup: logGroup, stream: name, idle: true})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].stream < changes[j].stream })
+	return changes
+}
+
+// watchStreams reports streams appearing or going idle in the listings made until the returned
+// function is called, and passes the creation time of new streams to onNew
+func (c *EKSLogsClient) watchStreams(onNew func(created time.Time)) func() {
+	watch := newStreamWatch(streamIdleAfter)
+	c.streamObserver = func(logGroup string, streams []cwt.LogStream) {
+		for _, change := range watch.update(logGroup, streams, time.Now()) {
+			if change.idle {
+				c.log().Info("Log stream went idle", "log_group", change.logGroup, "log_stream", change.stream, "idle_for", streamIdleAfter)
+			} else {
+				c.log().Info("New log stream", "log_group", change.logGroup, "log_stream", change.stream)
+				onNew(change.created)
+			}
+		}
+	}
+	return func() { c.streamObserver = nil }
+}
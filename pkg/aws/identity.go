@@ -0,0 +1,30 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// STSAPI defines the interface for the STS client.
+type STSAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// CallerIdentity is the AWS account and principal the client's credentials belong to
+type CallerIdentity struct {
+	Account string
+	ARN     string
+}
+
+// GetCallerIdentity returns the account and principal of the credentials in use, after
+// assuming the role set with SetAssumeRole
+func (c *EKSLogsClient) GetCallerIdentity(ctx context.Context) (CallerIdentity, error) {
+	resp, err := c.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return CallerIdentity{}, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+	return CallerIdentity{Account: aws.ToString(resp.Account), ARN: aws.ToString(resp.Arn)}, nil
+}
@@ -0,0 +1,166 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// MaxInsightsResults is the most rows a Logs Insights query returns
+const MaxInsightsResults = 10000
+
+// insightsPollInterval is the wait between two polls of the results of a running query
+var insightsPollInterval = time.Second
+
+// InsightsAPI defines the interface for the CloudWatch Logs calls running Logs Insights
+// queries and managing saved queries.
+type InsightsAPI interface {
+	StartQuery(ctx context.Context, params *cloudwatchlogs.StartQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StartQueryOutput, error)
+	GetQueryResults(ctx context.Context, params *cloudwatchlogs.GetQueryResultsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetQueryResultsOutput, error)
+	StopQuery(ctx context.Context, params *cloudwatchlogs.StopQueryInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.StopQueryOutput, error)
+	DescribeQueryDefinitions(ctx context.Context, params *cloudwatchlogs.DescribeQueryDefinitionsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeQueryDefinitionsOutput, error)
+	PutQueryDefinition(ctx context.Context, params *cloudwatchlogs.PutQueryDefinitionInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutQueryDefinitionOutput, error)
+}
+
+// InsightsResult holds the rows of a Logs Insights query. Fields lists the columns in the
+// order of the first row they appear in; the @ptr field is left out.
+type InsightsResult struct {
+	Fields         []string            `json:"fields"`
+	Rows           []map[string]string `json:"rows"`
+	RecordsMatched float64             `json:"records_matched"`
+	RecordsScanned float64             `json:"records_scanned"`
+	BytesScanned   float64             `json:"bytes_scanned"`
+}
+
+// RunInsightsQuery runs a Logs Insights query over the log groups between start and end
+// and waits for its results. When ctx is cancelled the query is stopped, so it does not
+// keep scanning (and costing) in the background.
+func (c *EKSLogsClient) RunInsightsQuery(ctx context.Context, logGroups []string, query string, start, end time.Time, limit int32) (InsightsResult, error) {
+	var result InsightsResult
+	input := &cloudwatchlogs.StartQueryInput{
+		LogGroupNames: logGroups,
+		QueryString:   aws.String(query),
+		StartTime:     aws.Int64(start.Unix()),
+		EndTime:       aws.Int64(end.Unix()),
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(limit)
+	}
+	started, err := c.insightsClient.StartQuery(ctx, input)
+	if err != nil {
+		return result, fmt.Errorf("failed to start Logs Insights query: %w", err)
+	}
+	queryID := started.QueryId
+	c.log().Debug("started Logs Insights query", "query_id", aws.ToString(queryID))
+
+	for {
+		resp, err := c.insightsClient.GetQueryResults(ctx, &cloudwatchlogs.GetQueryResultsInput{QueryId: queryID})
+		if err != nil {
+			c.stopInsightsQuery(ctx, queryID)
+			return result, fmt.Errorf("failed to get Logs Insights query results: %w", err)
+		}
+		switch resp.Status {
+		case cwt.QueryStatusComplete:
+			return insightsResult(resp), nil
+		case cwt.QueryStatusFailed, cwt.QueryStatusCancelled, cwt.QueryStatusTimeout:
+			return result, fmt.Errorf("the Logs Insights query %s ended with status %s", aws.ToString(queryID), resp.Status)
+		}
+		select {
+		case <-ctx.Done():
+			c.stopInsightsQuery(ctx, queryID)
+			return result, ctx.Err()
+		case <-time.After(insightsPollInterval):
+		}
+	}
+}
+
+// stopInsightsQuery stops a running query on a best-effort basis, after ctx may have been
+// cancelled
+func (c *EKSLogsClient) stopInsightsQuery(ctx context.Context, queryID *string) {
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if _, err := c.insightsClient.StopQuery(stopCtx, &cloudwatchlogs.StopQueryInput{QueryId: queryID}); err != nil {
+		c.log().Debug("failed to stop Logs Insights query", "query_id", aws.ToString(queryID), "error", err)
+	}
+}
+
+// insightsResult converts the results of a complete query
+func insightsResult(resp *cloudwatchlogs.GetQueryResultsOutput) InsightsResult {
+	result := InsightsResult{Rows: make([]map[string]string, 0, len(resp.Results))}
+	seen := make(map[string]bool)
+	for _, fields := range resp.Results {
+		row := make(map[string]string, len(fields))
+		for _, field := range fields {
+			name := aws.ToString(field.Field)
+			if name == "@ptr" {
+				continue
+			}
+			row[name] = aws.ToString(field.Value)
+			if !seen[name] {
+				seen[name] = true
+				result.Fields = append(result.Fields, name)
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if stats := resp.Statistics; stats != nil {
+		result.RecordsMatched = stats.RecordsMatched
+		result.RecordsScanned = stats.RecordsScanned
+		result.BytesScanned = stats.BytesScanned
+	}
+	return result
+}
+
+// QueryDefinition is a Logs Insights query saved in the account, listed under Saved
+// queries in the CloudWatch console. A '/' in the name files it in a folder.
+type QueryDefinition struct {
+	Name      string   `json:"name"`
+	Query     string   `json:"query"`
+	LogGroups []string `json:"log_groups"`
+}
+
+// SaveQueryDefinition saves the query, replacing the saved query of the same name. It
+// reports whether a query was replaced.
+func (c *EKSLogsClient) SaveQueryDefinition(ctx context.Context, definition QueryDefinition) (bool, error) {
+	existingID, err := c.queryDefinitionID(ctx, definition.Name)
+	if err != nil {
+		return false, err
+	}
+	_, err = c.insightsClient.PutQueryDefinition(ctx, &cloudwatchlogs.PutQueryDefinitionInput{
+		Name:              aws.String(definition.Name),
+		QueryString:       aws.String(definition.Query),
+		LogGroupNames:     definition.LogGroups,
+		QueryDefinitionId: existingID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to save query %s: %w", definition.Name, err)
+	}
+	return existingID != nil, nil
+}
+
+// queryDefinitionID returns the ID of the saved query named name, or nil when there is none
+func (c *EKSLogsClient) queryDefinitionID(ctx context.Context, name string) (*string, error) {
+	var nextToken *string
+	for {
+		resp, err := c.insightsClient.DescribeQueryDefinitions(ctx, &cloudwatchlogs.DescribeQueryDefinitionsInput{
+			QueryDefinitionNamePrefix: aws.String(name),
+			NextToken:                 nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list saved queries: %w", err)
+		}
+		for _, definition := range resp.QueryDefinitions {
+			if aws.ToString(definition.Name) == name {
+				return definition.QueryDefinitionId, nil
+			}
+		}
+		if resp.NextToken == nil {
+			return nil, nil
+		}
+		nextToken = resp.NextToken
+	}
+}
@@ -0,0 +1,88 @@
+package aws
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// discardLogger drops all diagnostics
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// SetLogger sets the logger receiving diagnostics. A nil logger discards them.
+func (c *EKSLogsClient) SetLogger(logger *slog.Logger) {
+	c.logger = logger
+}
+
+// log returns the logger receiving diagnostics
+func (c *EKSLogsClient) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+	return c.logger
+}
+
+// addDebugMiddleware registers a middleware timing each API call, including its retries
+func (c *EKSLogsClient) addDebugMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("EKSLogsDebugTiming",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			c.logAPICall(ctx, in.Parameters, metadata, time.Since(start), err)
+			return out, metadata, err
+		}), middleware.After)
+}
+
+// logAPICall logs the duration, attempts and request ID of a completed API call at debug level
+func (c *EKSLogsClient) logAPICall(ctx context.Context, params interface{}, metadata middleware.Metadata, duration time.Duration, err error) {
+	logger := c.log()
+	if !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+
+	requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+
+	attempts := 1
+	if results, ok := retry.GetAttemptResults(metadata); ok && len(results.Results) > 0 {
+		attempts = len(results.Results)
+	}
+
+	attrs := append(describeParams(params),
+		slog.Duration("duration", duration.Round(time.Millisecond)),
+		slog.Int("attempts", attempts),
+		slog.String("request_id", requestID),
+	)
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	logger.LogAttrs(ctx, slog.LevelDebug, "AWS API call "+awsmiddleware.GetOperationName(ctx), attrs...)
+}
+
+// describeParams summarizes the request parameters relevant for diagnosing slow calls
+func describeParams(params interface{}) []slog.Attr {
+	switch p := params.(type) {
+	case *cloudwatchlogs.FilterLogEventsInput:
+		return []slog.Attr{
+			slog.String("log_group", aws.ToString(p.LogGroupName)),
+			slog.Int("streams", len(p.LogStreamNames)),
+			slog.Bool("paginated", p.NextToken != nil),
+		}
+	case *cloudwatchlogs.DescribeLogStreamsInput:
+		return []slog.Attr{
+			slog.String("log_group", aws.ToString(p.LogGroupName)),
+			slog.Bool("paginated", p.NextToken != nil),
+		}
+	case *cloudwatchlogs.DescribeLogGroupsInput:
+		return []slog.Attr{slog.String("prefix", aws.ToString(p.LogGroupNamePrefix))}
+	default:
+		return nil
+	}
+}
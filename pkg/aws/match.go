@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"sort"
+	"strings"
+)
+
+// MatchClusterNames returns the cluster names similar to name, best match first: names
+// starting with it, then names containing it, then names within a small edit distance
+// (typos). Matching ignores case.
+func MatchClusterNames(name string, clusters []string) []string {
+	query := strings.ToLower(name)
+	if query == "" {
+		return nil
+	}
+
+	type match struct {
+		name  string
+		rank  int
+		score int
+	}
+	var matches []match
+	maxDistance := max(2, len(query)/3)
+	for _, cluster := range clusters {
+		candidate := strings.ToLower(cluster)
+		switch {
+		case strings.HasPrefix(candidate, query):
+			matches = append(matches, match{cluster, 0, len(candidate)})
+		case strings.Contains(candidate, query):
+			matches = append(matches, match{cluster, 1, len(candidate)})
+		default:
+			if d := editDistance(query, candidate); d <= maxDistance {
+				matches = append(matches, match{cluster, 2, d})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].rank != matches[j].rank {
+			return matches[i].rank < matches[j].rank
+		}
+		return matches[i].score < matches[j].score
+	})
+
+	names := make([]string, len(matches))
+	for i, m := range matches {
+		names[i] = m.name
+	}
+	return names
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// MetricFilterAPI defines the interface for the CloudWatch Logs calls managing metric filters.
+type MetricFilterAPI interface {
+	PutMetricFilter(ctx context.Context, params *cloudwatchlogs.PutMetricFilterInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutMetricFilterOutput, error)
+}
+
+// AlarmAPI defines the interface for the CloudWatch client.
+type AlarmAPI interface {
+	PutMetricAlarm(ctx context.Context, params *cloudwatch.PutMetricAlarmInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricAlarmOutput, error)
+}
+
+// ClusterLogGroup returns the log group of the control plane logs of a cluster
+func ClusterLogGroup(clusterName string) string {
+	return fmt.Sprintf("/aws/eks/%s/cluster", clusterName)
+}
+
+// MetricFilter is a CloudWatch Logs metric filter counting the log events of a log group
+// that match a filter pattern
+type MetricFilter struct {
+	Name       string `json:"filter_name"`
+	LogGroup   string `json:"log_group"`
+	Pattern    string `json:"pattern"`
+	Namespace  string `json:"metric_namespace"`
+	MetricName string `json:"metric_name"`
+}
+
+// PutMetricFilter creates the metric filter, or replaces the filter of the same name on the
+// log group. Each matching event adds 1 to the metric, and periods without a match report 0
+// so alarms see a continuous series.
+func (c *EKSLogsClient) PutMetricFilter(ctx context.Context, filter MetricFilter) error {
+	_, err := c.metricFilterClient.PutMetricFilter(ctx, &cloudwatchlogs.PutMetricFilterInput{
+		FilterName:    aws.String(filter.Name),
+		FilterPattern: aws.String(filter.Pattern),
+		LogGroupName:  aws.String(filter.LogGroup),
+		MetricTransformations: []cwt.MetricTransformation{{
+			MetricName:      aws.String(filter.MetricName),
+			MetricNamespace: aws.String(filter.Namespace),
+			MetricValue:     aws.String("1"),
+			DefaultValue:    aws.Float64(0),
+			Unit:            cwt.StandardUnitCount,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create metric filter %s: %w", filter.Name, err)
+	}
+	return nil
+}
+
+// MetricAlarm is a CloudWatch alarm raised when the sum of a metric over Period reaches
+// Threshold in EvaluationPeriods consecutive periods
+type MetricAlarm struct {
+	Name              string        `json:"alarm_name"`
+	Description       string        `json:"description,omitempty"`
+	Namespace         string        `json:"metric_namespace"`
+	MetricName        string        `json:"metric_name"`
+	Threshold         float64       `json:"threshold"`
+	Period            time.Duration `json:"-"`
+	EvaluationPeriods int32         `json:"evaluation_periods"`
+	Actions           []string      `json:"actions,omitempty"`
+}
+
+// PutMetricAlarm creates the alarm, or replaces the alarm of the same name. Missing data,
+// such as before the first matching event, does not raise the alarm.
+func (c *EKSLogsClient) PutMetricAlarm(ctx context.Context, alarm MetricAlarm) error {
+	input := &cloudwatch.PutMetricAlarmInput{
+		AlarmName:          aws.String(alarm.Name),
+		ComparisonOperator: cwtypes.ComparisonOperatorGreaterThanOrEqualToThreshold,
+		EvaluationPeriods:  aws.Int32(alarm.EvaluationPeriods),
+		MetricName:         aws.String(alarm.MetricName),
+		Namespace:          aws.String(alarm.Namespace),
+		Period:             aws.Int32(int32(alarm.Period / time.Second)),
+		Statistic:          cwtypes.StatisticSum,
+		Threshold:          aws.Float64(alarm.Threshold),
+		TreatMissingData:   aws.String("notBreaching"),
+		AlarmActions:       alarm.Actions,
+	}
+	if alarm.Description != "" {
+		input.AlarmDescription = aws.String(alarm.Description)
+	}
+	if _, err := c.alarmClient.PutMetricAlarm(ctx, input); err != nil {
+		return fmt.Errorf("failed to create alarm %s: %w", alarm.Name, err)
+	}
+	return nil
+}
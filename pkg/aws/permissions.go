@@ -0,0 +1,89 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// ErrNoReadPermission is returned when the credentials may neither search log groups nor
+// list their log streams, so no logs can be read
+var ErrNoReadPermission = errors.New("logs:FilterLogEvents and logs:DescribeLogStreams are both denied")
+
+// Permissions are the read API calls on the logs of a cluster denied to the credentials of a
+// client, found by ProbePermissions. The zero value denies none.
+type Permissions struct {
+	// DescribeLogStreamsDenied searches whole log groups, selecting the log types of the
+	// events from their stream names
+	DescribeLogStreamsDenied bool
+	// FilterLogEventsDenied reads the log streams one after the other with GetLogEvents,
+	// which cannot apply filter patterns
+	FilterLogEventsDenied bool
+}
+
+// ProbePermissions finds the read API calls on the control plane log group of a cluster the
+// credentials are denied, with a call of each limited to one result and an empty time range,
+// and makes the client work around them. Errors other than access denied are left for the
+// retrieval to report. eks:DescribeCluster is not probed: its denial is reported by the
+// cluster lookup.
+func (c *EKSLogsClient) ProbePermissions(ctx context.Context, clusterName string) (Permissions, error) {
+	logGroup := ClusterLogGroup(clusterName)
+
+	if err := c.countAPICall(); err != nil {
+		return Permissions{}, err
+	}
+	_, err := c.logsClient.DescribeLogStreams(ctx, &cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName: aws.String(logGroup),
+		Limit:        aws.Int32(1),
+	})
+	var permissions Permissions
+	permissions.DescribeLogStreamsDenied = IsAccessDenied(err)
+
+	if err := c.countAPICall(); err != nil {
+		return Permissions{}, err
+	}
+	now := time.Now().UnixMilli()
+	_, err = c.logsClient.FilterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(logGroup),
+		StartTime:    aws.Int64(now),
+		EndTime:      aws.Int64(now),
+		Limit:        aws.Int32(1),
+	})
+	permissions.FilterLogEventsDenied = IsAccessDenied(err)
+
+	c.permissions = permissions
+	if permissions.DescribeLogStreamsDenied && permissions.FilterLogEventsDenied {
+		return permissions, ErrNoReadPermission
+	}
+	return permissions, nil
+}
+
+// streamsPageFetcher returns a function reading the pages of several log streams with
+// GetLogEvents, one stream after the other, for credentials denied FilterLogEvents. The
+// events are in order within each stream only.
+func (c *EKSLogsClient) streamsPageFetcher(ctx context.Context, logGroup string, logStreams []string, startTime, endTime *time.Time) func(token *string, limit int32) ([]cwt.FilteredLogEvent, *string, error) {
+	current := 0
+	fetch := c.streamPageFetcher(ctx, logGroup, logStreams[current], startTime, endTime)
+	var streamToken *string
+
+	return func(_ *string, limit int32) ([]cwt.FilteredLogEvent, *string, error) {
+		events, next, err := fetch(streamToken, limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		streamToken = next
+		if next == nil {
+			current++
+			if current == len(logStreams) {
+				return events, nil, nil
+			}
+			fetch = c.streamPageFetcher(ctx, logGroup, logStreams[current], startTime, endTime)
+		}
+		// The token only tells the caller there are more pages: the position is kept here
+		return events, aws.String(logStreams[current]), nil
+	}
+}
@@ -0,0 +1,119 @@
+package aws
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Progress is a snapshot of a historical log retrieval
+type Progress struct {
+	Events int64
+	Groups []GroupProgress
+	// Start and End bound the time range of the current retrieval (Start is zero when unbounded)
+	Start time.Time
+	End   time.Time
+}
+
+// ResumeFrom returns the earliest time not yet read by every log group: events before it
+// have all been retrieved, so a retrieval resumed from it misses nothing
+func (p Progress) ResumeFrom() time.Time {
+	resume := p.End
+	for _, group := range p.Groups {
+		if group.Done {
+			continue
+		}
+		reached := group.Reached
+		if reached.Before(p.Start) {
+			reached = p.Start
+		}
+		if reached.Before(resume) {
+			resume = reached
+		}
+	}
+	return resume
+}
+
+// GroupProgress reports how far the time range of a log group has been read
+type GroupProgress struct {
+	LogGroup string
+	// Reached is the timestamp of the newest event read so far (zero before the first event)
+	Reached time.Time
+	// LastEventID is the CloudWatch event ID of the newest event read so far
+	LastEventID string
+	// Coverage is the fraction of the time range read so far, between 0 and 1
+	Coverage float64
+	Done     bool
+}
+
+// progressTracker records the position of each log group within the retrieved time range
+type progressTracker struct {
+	mu     sync.Mutex
+	start  time.Time
+	end    time.Time
+	groups map[string]*GroupProgress
+}
+
+// begin resets the tracker for a retrieval of logGroups between start and end.
+// The coverage stays unknown (0) until a group is done when start is zero.
+func (p *progressTracker) begin(logGroups []string, start, end time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.start, p.end = start, end
+	p.groups = make(map[string]*GroupProgress, len(logGroups))
+	for _, lg := range logGroups {
+		p.groups[lg] = &GroupProgress{LogGroup: lg}
+	}
+}
+
+// advance records that events of a log group up to timestamp, ending with eventID, have been read
+func (p *progressTracker) advance(logGroup string, timestamp time.Time, eventID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	group, ok := p.groups[logGroup]
+	if !ok || !timestamp.After(group.Reached) {
+		return
+	}
+	group.Reached = timestamp
+	group.LastEventID = eventID
+	if span := p.end.Sub(p.start); !p.start.IsZero() && span > 0 {
+		group.Coverage = min(max(float64(timestamp.Sub(p.start))/float64(span), 0), 1)
+	}
+}
+
+// finish marks a log group as completely read
+func (p *progressTracker) finish(logGroup string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if group, ok := p.groups[logGroup]; ok {
+		group.Coverage = 1
+		group.Done = true
+	}
+}
+
+// snapshot returns the progress of every log group sorted by name, and the retrieved time range
+func (p *progressTracker) snapshot() ([]GroupProgress, time.Time, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	groups := make([]GroupProgress, 0, len(p.groups))
+	for _, group := range p.groups {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].LogGroup < groups[j].LogGroup })
+	return groups, p.start, p.end
+}
+
+// Progress returns the progress of the current historical retrieval
+func (c *EKSLogsClient) Progress() Progress {
+	groups, start, end := c.progress.snapshot()
+	return Progress{
+		Events: c.counters.events.Load(),
+		Groups: groups,
+		Start:  start,
+		End:    end,
+	}
+}
@@ -0,0 +1,101 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+)
+
+// EC2API defines the interface for the EC2 client, used to list the enabled regions.
+type EC2API interface {
+	DescribeRegions(ctx context.Context, params *ec2.DescribeRegionsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+}
+
+// EnabledRegions returns the regions enabled for the account, sorted by name
+func (c *EKSLogsClient) EnabledRegions(ctx context.Context) ([]string, error) {
+	resp, err := c.ec2Client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{AllRegions: aws.Bool(false)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list regions: %w", err)
+	}
+
+	var regions []string
+	for _, r := range resp.Regions {
+		if r.RegionName != nil {
+			regions = append(regions, *r.RegionName)
+		}
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// FindClusterRegions lists the clusters of every enabled region in parallel and returns the
+// regions holding a cluster with the given name. Regions that cannot be searched, e.g. when
+// denied by a service control policy, are skipped unless every region fails.
+func (c *EKSLogsClient) FindClusterRegions(ctx context.Context, clusterName string) ([]string, error) {
+	regions, err := c.EnabledRegions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var found []string
+	var failures []error
+	var wg sync.WaitGroup
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			clusters, err := listClusters(ctx, c.regionalEKS(region))
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				c.log().Debug("Failed to list clusters", "region", region, "error", err)
+				failures = append(failures, fmt.Errorf("%s: %w", region, err))
+				return
+			}
+			if contains(clusters, clusterName) {
+				found = append(found, region)
+			}
+		}(region)
+	}
+	wg.Wait()
+
+	if len(regions) > 0 && len(failures) == len(regions) {
+		return nil, fmt.Errorf("failed to list clusters in any region: %w", errors.Join(failures...))
+	}
+	sort.Strings(found)
+	return found, nil
+}
+
+// regionalEKS returns an EKS client for another region with the same configuration
+func (c *EKSLogsClient) regionalEKS(region string) EKSAPI {
+	if c.eksForRegion != nil {
+		return c.eksForRegion(region)
+	}
+	return c.eksClient
+}
+
+// listClusters returns the names of all clusters, following pagination
+func listClusters(ctx context.Context, client EKSAPI) ([]string, error) {
+	var clusters []string
+	input := &eks.ListClustersInput{}
+	for {
+		resp, err := client.ListClusters(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		clusters = append(clusters, resp.Clusters...)
+
+		if resp.NextToken == nil {
+			return clusters, nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
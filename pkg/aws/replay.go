@@ -0,0 +1,276 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/smithy-go"
+)
+
+// recordedCall is an API call saved by --record, one JSON file per call
+type recordedCall struct {
+	Operation string          `json:"operation"`
+	Input     json.RawMessage `json:"input"`
+	Output    json.RawMessage `json:"output,omitempty"`
+	Error     *recordedError  `json:"error,omitempty"`
+}
+
+// recordedError is the error of a recorded call. Code is the AWS error code, if any.
+type recordedError struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// replayKey identifies the recorded calls a call is served from: its operation and input,
+// without the time range, which relative times (-s -1h) move between the recording and
+// the replay
+func replayKey(operation string, input json.RawMessage) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return "", err
+	}
+	delete(fields, "StartTime")
+	delete(fields, "EndTime")
+	// Maps are marshaled with sorted keys
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return operation + " " + string(canonical), nil
+}
+
+// recorder passes the calls of the EKS and CloudWatch Logs APIs through and saves each of
+// them with its response in a directory
+type recorder struct {
+	eks  EKSAPI
+	logs CloudWatchLogsAPI
+	dir  string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// SetRecordDir saves every EKS and CloudWatch Logs API call of the client and its raw
+// response to dir, to be served back by NewReplayClient. Call it after SetAssumeRole,
+// which creates new service clients.
+func (c *EKSLogsClient) SetRecordDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create record directory: %w", err)
+	}
+	r := &recorder{eks: c.eksClient, logs: c.logsClient, dir: dir}
+	c.eksClient, c.logsClient = r, r
+	return nil
+}
+
+// record saves a call, numbered in the order of the calls. Calls interrupted by the
+// cancellation of ctx are not saved, as they would not be made again by a replay.
+func (r *recorder) record(ctx context.Context, operation string, input, output any, callErr error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	call := recordedCall{Operation: operation}
+	var err error
+	if call.Input, err = json.Marshal(input); err != nil {
+		return err
+	}
+	if callErr != nil {
+		call.Error = &recordedError{Code: apiErrorCode(callErr), Message: callErr.Error()}
+		var apiErr smithy.APIError
+		if errors.As(callErr, &apiErr) {
+			call.Error.Message = apiErr.ErrorMessage()
+		}
+	} else if call.Output, err = json.Marshal(output); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(call, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	path := filepath.Join(r.dir, fmt.Sprintf("%05d-%s.json", r.seq, operation))
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to record %s call: %w", operation, err)
+	}
+	return nil
+}
+
+func (r *recorder) ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	output, err := r.eks.ListClusters(ctx, params, optFns...)
+	if recordErr := r.record(ctx, "ListClusters", params, output, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return output, err
+}
+
+func (r *recorder) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	output, err := r.eks.DescribeCluster(ctx, params, optFns...)
+	if recordErr := r.record(ctx, "DescribeCluster", params, output, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return output, err
+}
+
+func (r *recorder) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	output, err := r.logs.DescribeLogGroups(ctx, params, optFns...)
+	if recordErr := r.record(ctx, "DescribeLogGroups", params, output, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return output, err
+}
+
+func (r *recorder) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	output, err := r.logs.DescribeLogStreams(ctx, params, optFns...)
+	if recordErr := r.record(ctx, "DescribeLogStreams", params, output, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return output, err
+}
+
+func (r *recorder) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	output, err := r.logs.FilterLogEvents(ctx, params, optFns...)
+	if recordErr := r.record(ctx, "FilterLogEvents", params, output, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return output, err
+}
+
+func (r *recorder) GetLogEvents(ctx context.Context, params *cloudwatchlogs.GetLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	output, err := r.logs.GetLogEvents(ctx, params, optFns...)
+	if recordErr := r.record(ctx, "GetLogEvents", params, output, err); recordErr != nil {
+		return nil, recordErr
+	}
+	return output, err
+}
+
+// replayer serves the calls recorded by SetRecordDir. Calls with the same operation and
+// input are served the recorded responses in the order they were recorded.
+type replayer struct {
+	mu        sync.Mutex
+	responses map[string][]recordedCall
+}
+
+// NewReplayClient creates a client serving the EKS and CloudWatch Logs API calls from the
+// responses recorded in dir by SetRecordDir, without AWS credentials. A call that was not
+// recorded, e.g. because of different flags, fails.
+func NewReplayClient(region, dir string, logger *slog.Logger) (*EKSLogsClient, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no recorded calls in %s", dir)
+	}
+	sort.Strings(files)
+
+	r := &replayer{responses: make(map[string][]recordedCall)}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var call recordedCall
+		if err := json.Unmarshal(data, &call); err != nil {
+			return nil, fmt.Errorf("failed to read recorded call %s: %w", file, err)
+		}
+		key, err := replayKey(call.Operation, call.Input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recorded call %s: %w", file, err)
+		}
+		r.responses[key] = append(r.responses[key], call)
+	}
+	return NewEKSLogsClientWithAPIs(region, r, r, logger), nil
+}
+
+// replay decodes the next recorded response of a call into output, or returns its
+// recorded error
+func (r *replayer) replay(ctx context.Context, operation string, input, output any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	key, err := replayKey(operation, data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	calls := r.responses[key]
+	if len(calls) == 0 {
+		r.mu.Unlock()
+		return fmt.Errorf("no recorded response for %s with input %s: replay with the flags of the recording", operation, data)
+	}
+	call := calls[0]
+	r.responses[key] = calls[1:]
+	r.mu.Unlock()
+
+	if call.Error != nil {
+		if call.Error.Code == "" {
+			return errors.New(call.Error.Message)
+		}
+		return &smithy.GenericAPIError{Code: call.Error.Code, Message: call.Error.Message}
+	}
+	return json.Unmarshal(call.Output, output)
+}
+
+func (r *replayer) ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	var output eks.ListClustersOutput
+	if err := r.replay(ctx, "ListClusters", params, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+func (r *replayer) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	var output eks.DescribeClusterOutput
+	if err := r.replay(ctx, "DescribeCluster", params, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+func (r *replayer) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	var output cloudwatchlogs.DescribeLogGroupsOutput
+	if err := r.replay(ctx, "DescribeLogGroups", params, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+func (r *replayer) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	var output cloudwatchlogs.DescribeLogStreamsOutput
+	if err := r.replay(ctx, "DescribeLogStreams", params, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+func (r *replayer) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	var output cloudwatchlogs.FilterLogEventsOutput
+	if err := r.replay(ctx, "FilterLogEvents", params, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
+
+func (r *replayer) GetLogEvents(ctx context.Context, params *cloudwatchlogs.GetLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	var output cloudwatchlogs.GetLogEventsOutput
+	if err := r.replay(ctx, "GetLogEvents", params, &output); err != nil {
+		return nil, err
+	}
+	return &output, nil
+}
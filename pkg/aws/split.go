@@ -0,0 +1,217 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+// MaxSplitParts is the largest number of sub-ranges GetLogsSplit retrieves in parallel
+const MaxSplitParts = 32
+
+// minSplitRange is the shortest sub-range GetLogsSplit divides a time range into
+const minSplitRange = time.Minute
+
+// GetLogsSplit retrieves log events like GetLogs, dividing the time range into parts
+// sub-ranges retrieved in parallel, each with its own sequence of FilterLogEvents calls. The
+// events are passed to printFunc in the order of the sub-ranges: those of a sub-range
+// retrieved before the preceding ones are printed wait in a temporary file. The progress
+// reports the position of the events printed, so an interrupted retrieval resumes after them.
+func (c *EKSLogsClient) GetLogsSplit(ctx context.Context, clusterName string, logTypes []string, startTime, endTime time.Time, filterPattern *string, limit int32, parts int, printFunc func(log.LogEntry)) error {
+	ranges := splitRange(startTime, endTime, min(parts, MaxSplitParts))
+	if len(ranges) == 1 {
+		return c.GetLogs(ctx, clusterName, logTypes, &startTime, &endTime, filterPattern, limit, printFunc)
+	}
+
+	logGroups, err := c.GetLogGroups(ctx, clusterName)
+	if err != nil || len(logGroups) == 0 {
+		// GetLogs reports the error
+		return c.GetLogs(ctx, clusterName, logTypes, &startTime, &endTime, filterPattern, limit, printFunc)
+	}
+	if len(logTypes) > 0 {
+		var normalizedLogTypes []string
+		for _, logType := range logTypes {
+			normalizedLogTypes = append(normalizedLogTypes, log.NormalizeLogType(logType))
+		}
+		logGroups = c.filterLogGroupsByTypes(ctx, logGroups, normalizedLogTypes)
+	}
+	c.progress.begin(logGroups, startTime, endTime)
+	c.log().Info("Splitting the time range", "parts", len(ranges), "start_time", startTime, "end_time", endTime)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Only the sub-range being printed prints, so the count needs no lock
+	var printed int32
+	printEntry := func(entry log.LogEntry) {
+		if limit > 0 && printed >= limit {
+			return
+		}
+		printed++
+		c.progress.advance(entry.LogGroup, entry.Timestamp, entry.EventID)
+		printFunc(entry)
+		if limit > 0 && printed == limit {
+			cancel()
+		}
+	}
+
+	segments := make([]*splitSegment, len(ranges))
+	for i := range ranges {
+		segments[i] = &splitSegment{print: printEntry, done: make(chan struct{})}
+	}
+	segments[0].live = true
+	defer func() {
+		for _, segment := range segments {
+			segment.close()
+		}
+	}()
+
+	for i, r := range ranges {
+		go func(segment *splitSegment, from, to time.Time) {
+			defer close(segment.done)
+			// The progress of a sub-range is not that of the events printed
+			segment.err = c.getLogs(ctx, clusterName, logTypes, &from, &to, filterPattern, limit, &progressTracker{}, segment.add)
+		}(segments[i], r[0], r[1])
+	}
+
+	var errs []error
+	for i, segment := range segments {
+		if i > 0 && len(errs) == 0 {
+			if err := segment.release(ctx); err != nil {
+				errs = append(errs, err)
+				cancel()
+			}
+		}
+		<-segment.done
+		if segment.err != nil {
+			errs = append(errs, segment.err)
+			cancel()
+		}
+	}
+	for _, err := range errs {
+		if errors.Is(err, ErrBudgetExceeded) {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, lg := range logGroups {
+		c.progress.finish(lg)
+	}
+	return nil
+}
+
+// splitRange divides the time range from start to end, both included, into at most parts
+// sub-ranges of equal length and at least minSplitRange. A sub-range ends a millisecond
+// before the next starts, as FilterLogEvents includes both ends of a range.
+func splitRange(start, end time.Time, parts int) [][2]time.Time {
+	start, end = start.Truncate(time.Millisecond), end.Truncate(time.Millisecond)
+	span := end.Sub(start)
+	parts = max(min(parts, int(span/minSplitRange)), 1)
+
+	ranges := make([][2]time.Time, 0, parts)
+	for i := range parts {
+		from := start.Add(span * time.Duration(i) / time.Duration(parts)).Truncate(time.Millisecond)
+		to := end
+		if i < parts-1 {
+			to = start.Add(span * time.Duration(i+1) / time.Duration(parts)).Truncate(time.Millisecond).Add(-time.Millisecond)
+		}
+		ranges = append(ranges, [2]time.Time{from, to})
+	}
+	return ranges
+}
+
+// splitSegment is a sub-range of GetLogsSplit. Its entries are printed as they arrive once
+// the preceding sub-ranges were printed (live), and held in a temporary file until then.
+type splitSegment struct {
+	print func(log.LogEntry)
+	done  chan struct{} // closed when the retrieval of the sub-range returned
+	err   error         // error of the retrieval, set before done is closed
+
+	mu       sync.Mutex
+	live     bool
+	file     *os.File
+	writer   *bufio.Writer
+	encoder  *gob.Encoder
+	spillErr error
+}
+
+// add prints an entry of the sub-range, or holds it until the sub-range is released. It is
+// called concurrently by the retrieval of each log group.
+func (s *splitSegment) add(entry log.LogEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.live {
+		s.print(entry)
+		return
+	}
+	if s.spillErr != nil {
+		return
+	}
+	if s.file == nil {
+		file, err := os.CreateTemp("", "ekslogs-split-")
+		if err != nil {
+			s.spillErr = err
+			return
+		}
+		s.file = file
+		s.writer = bufio.NewWriter(file)
+		s.encoder = gob.NewEncoder(s.writer)
+	}
+	s.spillErr = s.encoder.Encode(entry)
+}
+
+// release prints the entries held so far, and makes the sub-range print the following ones as
+// they arrive. It stops printing when ctx is cancelled.
+func (s *splitSegment) release(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.live = true
+	if s.spillErr != nil {
+		return s.spillErr
+	}
+	if s.file == nil {
+		return nil
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	decoder := gob.NewDecoder(bufio.NewReader(s.file))
+	for ctx.Err() == nil {
+		var entry log.LogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		s.print(entry)
+	}
+	return nil
+}
+
+// close removes the temporary file of the sub-range
+func (s *splitSegment) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != nil {
+		_ = s.file.Close()
+		_ = os.Remove(s.file.Name())
+		s.file = nil
+	}
+}
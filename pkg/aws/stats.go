@@ -0,0 +1,72 @@
+package aws
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// Stats summarizes the work done by a retrieval
+type Stats struct {
+	Events          int64         `json:"events"`
+	Pages           int64         `json:"pages"`
+	APICalls        int64         `json:"api_calls"`
+	Retries         int64         `json:"retries"`
+	Throttles       int64         `json:"throttles"`
+	Duplicates      int64         `json:"duplicates"` // Events received more than once and dropped
+	Bytes           int64         `json:"bytes"`
+	Elapsed         time.Duration `json:"-"`
+	ElapsedSeconds  float64       `json:"elapsed_seconds"`
+	EventsPerSecond float64       `json:"events_per_second"`
+}
+
+// counters holds the statistics collected while retrieving logs
+type counters struct {
+	events     atomic.Int64
+	pages      atomic.Int64
+	retries    atomic.Int64
+	throttles  atomic.Int64
+	duplicates atomic.Int64
+}
+
+// Stats returns the statistics collected since the client was created
+func (c *EKSLogsClient) Stats() Stats {
+	elapsed := time.Since(c.started)
+	stats := Stats{
+		Events:         c.counters.events.Load(),
+		Pages:          c.counters.pages.Load(),
+		APICalls:       c.apiCalls.Load(),
+		Retries:        c.counters.retries.Load(),
+		Throttles:      c.counters.throttles.Load(),
+		Duplicates:     c.counters.duplicates.Load(),
+		Bytes:          c.bytesFetched.Load(),
+		Elapsed:        elapsed,
+		ElapsedSeconds: elapsed.Seconds(),
+	}
+	if elapsed > 0 {
+		stats.EventsPerSecond = float64(stats.Events) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// countingRetryer wraps the SDK retryer to count retries and throttled requests
+type countingRetryer struct {
+	aws.RetryerV2
+	counters *counters
+}
+
+// newCountingRetryer creates the standard SDK retryer reporting to the given counters
+func newCountingRetryer(counters *counters) aws.Retryer {
+	return &countingRetryer{RetryerV2: retry.NewStandard(), counters: counters}
+}
+
+// RetryDelay is called by the SDK before each retry attempt
+func (r *countingRetryer) RetryDelay(attempt int, err error) (time.Duration, error) {
+	r.counters.retries.Add(1)
+	if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary {
+		r.counters.throttles.Add(1)
+	}
+	return r.RetryerV2.RetryDelay(attempt, err)
+}
@@ -0,0 +1,447 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+// staleStreamMargin is how long before the start of a time range a stream must have been
+// last active to be skipped, allowing for clock skew between event and ingestion times
+const staleStreamMargin = 5 * time.Minute
+
+// maxStreamsPerPage is the largest number of log streams DescribeLogStreams returns per call
+const maxStreamsPerPage = 50
+
+// maxListingShards is the number of stream name prefixes listed at the same time
+const maxListingShards = 4
+
+// DefaultStreamCacheTTL is how long a stream listing is reused before DescribeLogStreams is called again
+const DefaultStreamCacheTTL = 60 * time.Second
+
+// streamCache keeps the stream listing of each log group for a limited time, so follow mode
+// does not enumerate every stream on every poll
+type streamCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedStreams
+}
+
+// cachedStreams is a stream listing and the time it was made. A listing stopped at the
+// first stream without activity since a time holds only the streams active since then.
+type cachedStreams struct {
+	streams []cwt.LogStream
+	listed  time.Time
+	since   time.Time // Zero for a complete listing
+}
+
+// get returns the listing of a log group if it is younger than the TTL and holds every
+// stream active since the given time (nil requires a complete listing)
+func (s *streamCache) get(logGroup string, now time.Time, since *time.Time) ([]cwt.LogStream, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[logGroup]
+	if !ok || s.ttl <= 0 || now.Sub(entry.listed) >= s.ttl {
+		return nil, false
+	}
+	if !entry.since.IsZero() && (since == nil || since.Before(entry.since)) {
+		return nil, false
+	}
+	return entry.streams, true
+}
+
+// put stores the listing of a log group, complete unless since is given
+func (s *streamCache) put(logGroup string, streams []cwt.LogStream, now time.Time, since *time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ttl <= 0 {
+		return
+	}
+	if s.entries == nil {
+		s.entries = make(map[string]cachedStreams)
+	}
+	entry := cachedStreams{streams: streams, listed: now}
+	if since != nil {
+		entry.since = *since
+	}
+	s.entries[logGroup] = entry
+}
+
+// SetStreamCacheTTL sets how long stream listings are reused. Zero disables the cache.
+func (c *EKSLogsClient) SetStreamCacheTTL(ttl time.Duration) {
+	c.streamCache.mu.Lock()
+	defer c.streamCache.mu.Unlock()
+
+	c.streamCache.ttl = ttl
+	c.streamCache.entries = nil
+}
+
+// isStaleStream reports whether a stream had no activity since the given time. The last
+// ingestion time is used as well because the last event timestamp is only eventually consistent.
+// Streams without activity information are never stale.
+func isStaleStream(stream cwt.LogStream, since time.Time) bool {
+	lastActive := lastActivity(stream)
+	if lastActive == 0 {
+		return false
+	}
+	return time.UnixMilli(lastActive).Before(since.Add(-staleStreamMargin))
+}
+
+// lastActivity returns the later of the last event and ingestion times of a stream in
+// milliseconds, or 0 when unknown
+func lastActivity(stream cwt.LogStream) int64 {
+	lastActive := aws.ToInt64(stream.LastEventTimestamp)
+	if ingested := aws.ToInt64(stream.LastIngestionTime); ingested > lastActive {
+		lastActive = ingested
+	}
+	return lastActive
+}
+
+// typedStreamPrefixes returns the name prefixes of the streams of logTypes in a log group, if
+// the streams to read can be listed by them: those of the control plane log group when the
+// streams of unknown components are left out, outside follow mode, which watches every stream
+func (c *EKSLogsClient) typedStreamPrefixes(logGroup string, logTypes []string) ([]string, bool) {
+	if logGroup != ClusterLogGroup(log.ClusterFor(logGroup)) || c.includeUnknownStreams || c.streamObserver != nil {
+		return nil, false
+	}
+	return log.StreamPrefixes(logTypes), true
+}
+
+// listTypedLogStreams returns the log streams of a log group with names starting with one of
+// prefixes, and possibly others, like listLogStreams. Instead of the whole log group, every
+// prefix is listed by name, at most maxListingShards at a time.
+func (c *EKSLogsClient) listTypedLogStreams(ctx context.Context, logGroup string, prefixes []string, since *time.Time) ([]cwt.LogStream, error) {
+	if streams, ok := c.streamCache.get(logGroup, time.Now(), since); ok {
+		return streams, nil
+	}
+	prefixKey := logGroup + "\x00" + strings.Join(prefixes, "\x00")
+	if streams, ok := c.streamCache.get(prefixKey, time.Now(), nil); ok {
+		return streams, nil
+	}
+
+	listing, err := c.raceLogStreamListings(ctx, logGroup, since, func(ctx context.Context) ([]cwt.LogStream, error) {
+		return c.listLogStreamsByPrefix(ctx, logGroup, prefixes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if listing.byName {
+		c.log().Debug("Listed log streams by name prefix", "log_group", logGroup, "prefixes", len(prefixes), "streams", len(listing.streams))
+		c.streamCache.put(prefixKey, listing.streams, time.Now(), nil)
+	} else {
+		c.storeLogStreams(logGroup, listing.streams, since, listing.truncated)
+	}
+	return listing.streams, nil
+}
+
+// streamListing is a log stream listing made by raceLogStreamListings
+type streamListing struct {
+	streams   []cwt.LogStream
+	truncated bool // Stopped at the first stream without activity since the start
+	byName    bool // Made by the listing by name
+	err       error
+}
+
+// raceLogStreamListings lists the log streams of a log group by last event time with
+// pageLogStreams. When more than a page is needed, listByName lists them by name as well,
+// and the first listing complete is returned. The listing by name cannot stop at the first
+// stale stream, but a log group of thousands of streams is enumerated several pages at a
+// time.
+func (c *EKSLogsClient) raceLogStreamListings(ctx context.Context, logGroup string, since *time.Time, listByName func(context.Context) ([]cwt.LogStream, error)) (streamListing, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered for both listings, so the one losing the race does not block
+	results := make(chan streamListing, 2)
+	more := make(chan struct{})
+	go func() {
+		streams, truncated, err := c.pageLogStreams(ctx, logGroup, since, func() { close(more) })
+		results <- streamListing{streams: streams, truncated: truncated, err: err}
+	}()
+
+	var err error
+	for pending := 1; pending > 0; {
+		select {
+		case <-more:
+			more = nil
+			pending++
+			go func() {
+				streams, err := listByName(ctx)
+				results <- streamListing{streams: streams, byName: true, err: err}
+			}()
+		case result := <-results:
+			pending--
+			if result.err != nil {
+				err = result.err
+				continue
+			}
+			return result, nil
+		}
+	}
+	return streamListing{}, err
+}
+
+// listLogStreamsByPrefix lists the log streams of a log group with names starting with one of
+// prefixes, at most maxListingShards prefixes at a time, most recently active first
+func (c *EKSLogsClient) listLogStreamsByPrefix(ctx context.Context, logGroup string, prefixes []string) ([]cwt.LogStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var streams []cwt.LogStream
+	var firstErr error
+	shards := make(chan struct{}, maxListingShards)
+	var wg sync.WaitGroup
+	for _, prefix := range prefixes {
+		wg.Add(1)
+		go func(prefix string) {
+			defer wg.Done()
+			shards <- struct{}{}
+			defer func() { <-shards }()
+
+			var listed []cwt.LogStream
+			err := c.pageLogStreamsByName(ctx, logGroup, prefix, false, func(page []cwt.LogStream, last bool) bool {
+				listed = append(listed, page...)
+				return true
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			streams = append(streams, listed...)
+		}(prefix)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sortByActivity(streams)
+	return streams, nil
+}
+
+// listLogStreamsFromBothEnds lists every log stream of a log group by name: forward from the
+// first name and, once there is more than a page, backward from the last name at the same
+// time, until one listing reaches its end or the two meet. The streams are returned most
+// recently active first.
+func (c *EKSLogsClient) listLogStreamsFromBothEnds(ctx context.Context, logGroup string) ([]cwt.LogStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var forward, backward []cwt.LogStream
+	complete := false
+	// add records a page of one of the listings, and reports whether it should go on
+	add := func(streams *[]cwt.LogStream, page []cwt.LogStream, last bool) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if complete {
+			return false
+		}
+		*streams = append(*streams, page...)
+		// The forward listing holds every name up to its last one, the backward listing every
+		// name from its last one
+		if last || (len(forward) > 0 && len(backward) > 0 &&
+			aws.ToString(forward[len(forward)-1].LogStreamName) >= aws.ToString(backward[len(backward)-1].LogStreamName)) {
+			complete = true
+		}
+		return !complete
+	}
+
+	results := make(chan error)
+	more := make(chan struct{})
+	go func() {
+		first := true
+		results <- c.pageLogStreamsByName(ctx, logGroup, "", false, func(page []cwt.LogStream, last bool) bool {
+			next := add(&forward, page, last)
+			if first && next {
+				close(more)
+			}
+			first = false
+			return next
+		})
+	}()
+
+	// Once the listing is complete or failed, the other one is stopped and waited for
+	var firstErr error
+	for pending := 1; pending > 0; {
+		select {
+		case <-more:
+			more = nil
+			pending++
+			go func() {
+				results <- c.pageLogStreamsByName(ctx, logGroup, "", true, func(page []cwt.LogStream, last bool) bool {
+					return add(&backward, page, last)
+				})
+			}()
+		case err := <-results:
+			pending--
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			cancel()
+		}
+	}
+	if !complete {
+		return nil, firstErr
+	}
+	return mergeStreams(forward, backward), nil
+}
+
+// mergeStreams returns the streams of both listings once, most recently active first
+func mergeStreams(forward, backward []cwt.LogStream) []cwt.LogStream {
+	streams := make([]cwt.LogStream, 0, len(forward)+len(backward))
+	seen := make(map[string]bool, len(forward))
+	for _, stream := range forward {
+		seen[aws.ToString(stream.LogStreamName)] = true
+		streams = append(streams, stream)
+	}
+	for _, stream := range backward {
+		if !seen[aws.ToString(stream.LogStreamName)] {
+			streams = append(streams, stream)
+		}
+	}
+	sortByActivity(streams)
+	return streams
+}
+
+// sortByActivity sorts streams most recently active first
+func sortByActivity(streams []cwt.LogStream) {
+	sort.SliceStable(streams, func(i, j int) bool { return lastActivity(streams[i]) > lastActivity(streams[j]) })
+}
+
+// pageLogStreamsByName lists the log streams of a log group with names starting with prefix
+// by name, in descending order if asked, passing each page to page until it returns false.
+// last is true for the last page.
+func (c *EKSLogsClient) pageLogStreamsByName(ctx context.Context, logGroup, prefix string, descending bool, page func(streams []cwt.LogStream, last bool) bool) error {
+	input := &cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName: aws.String(logGroup),
+		Limit:        aws.Int32(maxStreamsPerPage),
+		OrderBy:      cwt.OrderByLogStreamName,
+		Descending:   aws.Bool(descending),
+	}
+	if prefix != "" {
+		input.LogStreamNamePrefix = aws.String(prefix)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := c.countAPICall(); err != nil {
+			return err
+		}
+
+		resp, err := c.logsClient.DescribeLogStreams(ctx, input)
+		if err != nil {
+			return err
+		}
+		if !page(resp.LogStreams, resp.NextToken == nil) || resp.NextToken == nil {
+			return nil
+		}
+		input.NextToken = resp.NextToken
+	}
+}
+
+// LastEventTimes returns the time of the latest event of each log type of a cluster, from the
+// last event time of its log streams. Only the streams active since the given time are
+// listed, so the log types silent since then are missing; nil lists every stream. The last
+// event time of a stream is only eventually consistent, and may lag by up to an hour.
+func (c *EKSLogsClient) LastEventTimes(ctx context.Context, clusterName string, since *time.Time) (map[string]time.Time, error) {
+	logGroups, err := c.GetLogGroups(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	lastEvents := make(map[string]time.Time)
+	for _, logGroup := range logGroups {
+		streams, err := c.listLogStreams(ctx, logGroup, since)
+		if err != nil {
+			return nil, err
+		}
+		for _, stream := range streams {
+			logType := log.LogTypeFor(logGroup, aws.ToString(stream.LogStreamName))
+			if logType == "" || stream.LastEventTimestamp == nil {
+				continue
+			}
+			last := time.UnixMilli(*stream.LastEventTimestamp)
+			if since != nil && last.Before(*since) {
+				continue
+			}
+			if last.After(lastEvents[logType]) {
+				lastEvents[logType] = last
+			}
+		}
+	}
+	return lastEvents, nil
+}
+
+// streamPageFetcher returns a function reading pages of a single log stream with GetLogEvents,
+// oldest first. Its events are returned as FilterLogEvents results without event IDs.
+// GetLogEvents returns the token it was given once the end of the range is reached, which
+// is reported as the last page.
+func (c *EKSLogsClient) streamPageFetcher(ctx context.Context, logGroup, logStream string, startTime, endTime *time.Time) func(token *string, limit int32) ([]cwt.FilteredLogEvent, *string, error) {
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  aws.String(logGroup),
+		LogStreamName: aws.String(logStream),
+		StartFromHead: aws.Bool(true),
+	}
+	if startTime != nil {
+		input.StartTime = aws.Int64(startTime.UnixMilli())
+	}
+	if endTime != nil {
+		// GetLogEvents excludes the end time, FilterLogEvents includes it
+		input.EndTime = aws.Int64(endTime.UnixMilli() + 1)
+	}
+
+	return func(token *string, limit int32) ([]cwt.FilteredLogEvent, *string, error) {
+		input.Limit = aws.Int32(limit)
+		input.NextToken = token
+		resp, err := c.logsClient.GetLogEvents(ctx, input)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		events := make([]cwt.FilteredLogEvent, 0, len(resp.Events))
+		for _, event := range resp.Events {
+			events = append(events, cwt.FilteredLogEvent{
+				Timestamp:     event.Timestamp,
+				IngestionTime: event.IngestionTime,
+				Message:       event.Message,
+				LogStreamName: aws.String(logStream),
+			})
+		}
+
+		next := resp.NextForwardToken
+		if next == nil || (token != nil && *next == *token) {
+			next = nil
+		}
+		return events, next, nil
+	}
+}
+
+// SetContainerInsights sets whether the Container Insights log groups of node and pod logs
+// (/aws/containerinsights/<cluster>/application, dataplane and host) are read as well
+func (c *EKSLogsClient) SetContainerInsights(enabled bool) {
+	c.containerInsights = enabled
+}
+
+// SetIncludeUnknownStreams sets whether the log streams of control plane components unknown
+// to ekslogs, such as components added to EKS later, are read along with the log types
+// asked for. Without log types, every stream is read anyway.
+func (c *EKSLogsClient) SetIncludeUnknownStreams(enabled bool) {
+	c.includeUnknownStreams = enabled
+}
@@ -0,0 +1,222 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// maxSubscriptionFilters is the number of subscription filters a log group accepts
+const maxSubscriptionFilters = 2
+
+// Destinations of subscription filters, as returned by DestinationKind
+const (
+	DestinationKinesis  = "kinesis"
+	DestinationFirehose = "firehose"
+	DestinationLambda   = "lambda"
+	// DestinationLogs is a CloudWatch Logs destination, which forwards to another account
+	DestinationLogs = "logs"
+)
+
+// lambdaPermissionRetries bounds the attempts to create a subscription filter to a Lambda
+// function whose invoke permission was just granted, until the permission propagates
+const lambdaPermissionRetries = 5
+
+// lambdaPermissionDelay is the wait between those attempts
+var lambdaPermissionDelay = 2 * time.Second
+
+// SubscriptionAPI defines the interface for the CloudWatch Logs calls managing subscription filters.
+type SubscriptionAPI interface {
+	DescribeSubscriptionFilters(ctx context.Context, params *cloudwatchlogs.DescribeSubscriptionFiltersInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeSubscriptionFiltersOutput, error)
+	PutSubscriptionFilter(ctx context.Context, params *cloudwatchlogs.PutSubscriptionFilterInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.PutSubscriptionFilterOutput, error)
+}
+
+// LambdaAPI defines the interface for the Lambda client.
+type LambdaAPI interface {
+	AddPermission(ctx context.Context, params *lambda.AddPermissionInput, optFns ...func(*lambda.Options)) (*lambda.AddPermissionOutput, error)
+}
+
+// DestinationKind returns the kind of a subscription filter destination from its ARN: a
+// Kinesis data stream, a Firehose delivery stream, a Lambda function or a CloudWatch Logs
+// destination
+func DestinationKind(destinationARN string) (string, error) {
+	parsed, err := arn.Parse(destinationARN)
+	if err != nil {
+		return "", fmt.Errorf("invalid destination ARN '%s': %w", destinationARN, err)
+	}
+	switch {
+	case parsed.Service == "kinesis" && strings.HasPrefix(parsed.Resource, "stream/"):
+		return DestinationKinesis, nil
+	case parsed.Service == "firehose" && strings.HasPrefix(parsed.Resource, "deliverystream/"):
+		return DestinationFirehose, nil
+	case parsed.Service == "lambda" && strings.HasPrefix(parsed.Resource, "function:"):
+		return DestinationLambda, nil
+	case parsed.Service == "logs" && strings.HasPrefix(parsed.Resource, "destination:"):
+		return DestinationLogs, nil
+	}
+	return "", fmt.Errorf("unsupported destination '%s': expected a Kinesis data stream, Firehose delivery stream, Lambda function or CloudWatch Logs destination ARN", destinationARN)
+}
+
+// SubscriptionFilter is a CloudWatch Logs subscription filter streaming the events of a
+// log group that match a filter pattern to a destination
+type SubscriptionFilter struct {
+	Name           string `json:"filter_name"`
+	LogGroup       string `json:"log_group"`
+	Pattern        string `json:"pattern"`
+	DestinationARN string `json:"destination_arn"`
+	// RoleARN is the role CloudWatch Logs assumes to write to a Kinesis or Firehose destination
+	RoleARN string `json:"role_arn,omitempty"`
+	// LogGroupARN is the ARN of LogGroup, which a Lambda destination must allow to invoke it
+	LogGroupARN string `json:"-"`
+}
+
+// SubscriptionResult tells what PutSubscriptionFilter changed
+type SubscriptionResult struct {
+	// Updated is set when a filter of the same name was replaced
+	Updated bool `json:"updated"`
+	// LambdaPermission is set when the Lambda function was allowed to be invoked by the log group
+	LambdaPermission bool `json:"lambda_permission_added"`
+}
+
+// LogGroupARN returns the ARN of a log group of the account and region of a cluster ARN
+func LogGroupARN(clusterARN, logGroup string) (string, error) {
+	parsed, err := arn.Parse(clusterARN)
+	if err != nil {
+		return "", fmt.Errorf("invalid cluster ARN '%s': %w", clusterARN, err)
+	}
+	return arn.ARN{
+		Partition: parsed.Partition,
+		Service:   "logs",
+		Region:    parsed.Region,
+		AccountID: parsed.AccountID,
+		Resource:  "log-group:" + logGroup + ":*",
+	}.String(), nil
+}
+
+// PutSubscriptionFilter creates the subscription filter, or replaces the filter of the same
+// name. A log group accepts two subscription filters: when it already has two others, an
+// error names them. A Lambda destination is first allowed to be invoked by the log group.
+func (c *EKSLogsClient) PutSubscriptionFilter(ctx context.Context, filter SubscriptionFilter) (SubscriptionResult, error) {
+	var result SubscriptionResult
+	kind, err := DestinationKind(filter.DestinationARN)
+	if err != nil {
+		return result, err
+	}
+	if (kind == DestinationKinesis || kind == DestinationFirehose) && filter.RoleARN == "" {
+		return result, fmt.Errorf("a %s destination requires the ARN of a role that CloudWatch Logs (logs.amazonaws.com) can assume and that may write to it", kind)
+	}
+
+	existing, err := c.subscriptionFilterNames(ctx, filter.LogGroup)
+	if err != nil {
+		return result, err
+	}
+	for _, name := range existing {
+		if name == filter.Name {
+			result.Updated = true
+		}
+	}
+	if !result.Updated && len(existing) >= maxSubscriptionFilters {
+		return result, fmt.Errorf("%s already has %d subscription filters (%s), the most a log group accepts: delete one, or replace it by giving its name as the filter name",
+			filter.LogGroup, len(existing), strings.Join(existing, ", "))
+	}
+
+	if kind == DestinationLambda {
+		result.LambdaPermission, err = c.allowLambdaInvoke(ctx, filter)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	input := &cloudwatchlogs.PutSubscriptionFilterInput{
+		DestinationArn: aws.String(filter.DestinationARN),
+		FilterName:     aws.String(filter.Name),
+		FilterPattern:  aws.String(filter.Pattern),
+		LogGroupName:   aws.String(filter.LogGroup),
+	}
+	if filter.RoleARN != "" {
+		input.RoleArn = aws.String(filter.RoleARN)
+	}
+	for attempt := 1; ; attempt++ {
+		_, err = c.subscriptionClient.PutSubscriptionFilter(ctx, input)
+		// A new invoke permission takes a few seconds to be honored
+		if err == nil || !result.LambdaPermission || attempt == lambdaPermissionRetries || apiErrorCode(err) != "InvalidParameterException" {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(lambdaPermissionDelay):
+		}
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to create subscription filter %s: %w", filter.Name, err)
+	}
+	return result, nil
+}
+
+// subscriptionFilterNames returns the names of the subscription filters of a log group
+func (c *EKSLogsClient) subscriptionFilterNames(ctx context.Context, logGroup string) ([]string, error) {
+	var names []string
+	var nextToken *string
+	for {
+		resp, err := c.subscriptionClient.DescribeSubscriptionFilters(ctx, &cloudwatchlogs.DescribeSubscriptionFiltersInput{
+			LogGroupName: aws.String(logGroup),
+			NextToken:    nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the subscription filters of %s: %w", logGroup, err)
+		}
+		for _, f := range resp.SubscriptionFilters {
+			names = append(names, aws.ToString(f.FilterName))
+		}
+		if resp.NextToken == nil {
+			return names, nil
+		}
+		nextToken = resp.NextToken
+	}
+}
+
+// allowLambdaInvoke adds a statement to the resource policy of the Lambda function of the
+// filter allowing the log group to invoke it. It reports false when the statement exists.
+func (c *EKSLogsClient) allowLambdaInvoke(ctx context.Context, filter SubscriptionFilter) (bool, error) {
+	if filter.LogGroupARN == "" {
+		return false, errors.New("the log group ARN is required to allow the Lambda function to be invoked")
+	}
+	_, err := c.lambdaClient.AddPermission(ctx, &lambda.AddPermissionInput{
+		Action:       aws.String("lambda:InvokeFunction"),
+		FunctionName: aws.String(filter.DestinationARN),
+		Principal:    aws.String("logs.amazonaws.com"),
+		StatementId:  aws.String(lambdaStatementID(filter.LogGroup, filter.Name)),
+		SourceArn:    aws.String(filter.LogGroupARN),
+	})
+	if apiErrorCode(err) == "ResourceConflictException" {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to allow CloudWatch Logs to invoke %s: %w", filter.DestinationARN, err)
+	}
+	return true, nil
+}
+
+// lambdaStatementID returns the ID of the resource policy statement allowing a subscription
+// filter of a log group to invoke its function, e.g. aws_eks_prod_cluster-ekslogs-archive.
+// IDs are at most 100 letters, digits, '-' and '_'.
+func lambdaStatementID(logGroup, filterName string) string {
+	id := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.Trim(logGroup, "/")+"-"+filterName)
+	if len(id) > 100 {
+		id = id[:100]
+	}
+	return id
+}
@@ -0,0 +1,39 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/smithy-go/middleware"
+)
+
+// ErrCallTimeout is returned when an AWS API call does not complete within the call timeout
+var ErrCallTimeout = errors.New("AWS API call timed out")
+
+// SetCallTimeout bounds the duration of each AWS API call, including its retries.
+// Zero disables the timeout.
+func (c *EKSLogsClient) SetCallTimeout(timeout time.Duration) {
+	c.callTimeout = timeout
+}
+
+// addTimeoutMiddleware registers a middleware applying the call timeout to each API call
+func (c *EKSLogsClient) addTimeoutMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("EKSLogsCallTimeout",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			if c.callTimeout <= 0 {
+				return next.HandleInitialize(ctx, in)
+			}
+
+			callCtx, cancel := context.WithTimeout(ctx, c.callTimeout)
+			defer cancel()
+
+			out, metadata, err := next.HandleInitialize(callCtx, in)
+			// Only report the call timeout, not the expiry or cancellation of the caller's context
+			if err != nil && ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+				err = fmt.Errorf("%w after %s: %w", ErrCallTimeout, c.callTimeout, err)
+			}
+			return out, metadata, err
+		}), middleware.Before)
+}
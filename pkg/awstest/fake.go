@@ -0,0 +1,288 @@
+// Package awstest provides an in-memory fake of the EKS and CloudWatch Logs APIs used by
+// the ekslogs client, so that code built on the library can be tested without AWS
+// credentials or hand-rolled mocks:
+//
+//	fake := awstest.New()
+//	fake.SeedCluster("prod", time.Now().Add(-time.Hour))
+//	client := aws.NewEKSLogsClientWithAPIs(awstest.Region, fake, fake, nil)
+//	entries, err := client.CollectLogs(ctx, "prod", []string{"audit"}, nil, nil, nil, 0)
+package awstest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/log"
+)
+
+// Region and AccountID appear in the ARNs of the fake resources
+const (
+	Region    = "us-east-1"
+	AccountID = "123456789012"
+)
+
+// Fake implements aws.EKSAPI and aws.CloudWatchLogsAPI in memory. It is safe for concurrent
+// use, as the client retrieves log groups concurrently.
+type Fake struct {
+	// PageSize bounds the results of each page below the API limits, to exercise
+	// pagination with few events. Zero applies the limits of the APIs.
+	PageSize int
+
+	mu       sync.Mutex
+	clusters map[string]ekstypes.Cluster
+	groups   map[string]*logGroup
+	calls    map[string]int
+	errs     map[string]error
+	nextID   int
+}
+
+// Event is a log event to add to a log stream
+type Event struct {
+	Timestamp time.Time
+	// IngestionTime defaults to the timestamp
+	IngestionTime time.Time
+	Message       string
+}
+
+// logGroup is a log group and its streams
+type logGroup struct {
+	created time.Time
+	streams map[string]*logStream
+}
+
+// logStream is a log stream and its events, oldest first
+type logStream struct {
+	created time.Time
+	events  []storedEvent
+}
+
+// storedEvent is an event with the ID assigned when it was added
+type storedEvent struct {
+	Event
+	id string
+}
+
+var (
+	_ aws.EKSAPI            = (*Fake)(nil)
+	_ aws.CloudWatchLogsAPI = (*Fake)(nil)
+)
+
+// New returns a fake without clusters or log groups
+func New() *Fake {
+	return &Fake{
+		clusters: make(map[string]ekstypes.Cluster),
+		groups:   make(map[string]*logGroup),
+		calls:    make(map[string]int),
+		errs:     make(map[string]error),
+	}
+}
+
+// AddCluster adds an active cluster with every control plane log type enabled
+func (f *Fake) AddCluster(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var types []ekstypes.LogType
+	for _, logType := range []string{"api", "audit", "authenticator", "controllerManager", "scheduler"} {
+		types = append(types, ekstypes.LogType(logType))
+	}
+	f.clusters[name] = ekstypes.Cluster{
+		Name:            awssdk.String(name),
+		Arn:             awssdk.String(fmt.Sprintf("arn:aws:eks:%s:%s:cluster/%s", Region, AccountID, name)),
+		Status:          ekstypes.ClusterStatusActive,
+		Version:         awssdk.String("1.30"),
+		PlatformVersion: awssdk.String("eks.1"),
+		Endpoint:        awssdk.String(fmt.Sprintf("https://%s.gr7.%s.eks.amazonaws.com", name, Region)),
+		CreatedAt:       awssdk.Time(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Logging: &ekstypes.Logging{
+			ClusterLogging: []ekstypes.LogSetup{{Enabled: awssdk.Bool(true), Types: types}},
+		},
+	}
+}
+
+// AddLogGroup adds an empty log group, if it does not exist yet
+func (f *Fake) AddLogGroup(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.logGroup(name)
+}
+
+// AddEvents adds events to a log stream, creating it and its log group as needed. Each
+// event is assigned an ID, unique within the fake.
+func (f *Fake) AddEvents(logGroupName, logStreamName string, events ...Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	group := f.logGroup(logGroupName)
+	stream, ok := group.streams[logStreamName]
+	if !ok {
+		stream = &logStream{created: time.Now()}
+		group.streams[logStreamName] = stream
+	}
+	for _, event := range events {
+		if event.IngestionTime.IsZero() {
+			event.IngestionTime = event.Timestamp
+		}
+		f.nextID++
+		stream.events = append(stream.events, storedEvent{Event: event, id: fmt.Sprintf("%056d", f.nextID)})
+		if event.Timestamp.Before(stream.created) {
+			stream.created = event.Timestamp
+		}
+	}
+	sort.SliceStable(stream.events, func(i, j int) bool {
+		return stream.events[i].Timestamp.Before(stream.events[j].Timestamp)
+	})
+}
+
+// SeedCluster adds a cluster whose log group holds the synthetic control plane events of
+// every log type of the built-in benchmark corpus, shifted to begin at start. It returns
+// the number of events added.
+func (f *Fake) SeedCluster(name string, start time.Time) int {
+	f.AddCluster(name)
+
+	entries := log.BenchCorpus()
+	if len(entries) == 0 {
+		return 0
+	}
+	first := entries[0].Timestamp
+	for _, entry := range entries {
+		if entry.Timestamp.Before(first) {
+			first = entry.Timestamp
+		}
+	}
+	shift := start.Sub(first)
+
+	logGroupName := aws.ClusterLogGroup(name)
+	for _, entry := range entries {
+		f.AddEvents(logGroupName, entry.LogStream, Event{
+			Timestamp:     entry.Timestamp.Add(shift),
+			IngestionTime: entry.IngestionTime.Add(shift),
+			Message:       entry.Message,
+		})
+	}
+	return len(entries)
+}
+
+// SetError makes every later call of an operation, e.g. "FilterLogEvents", fail with err.
+// A nil err makes the calls succeed again.
+func (f *Fake) SetError(operation string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.errs, operation)
+		return
+	}
+	f.errs[operation] = err
+}
+
+// Calls returns the number of calls of an operation, e.g. "DescribeLogStreams"
+func (f *Fake) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[operation]
+}
+
+// call records a call of an operation and returns the error it must fail with, if any.
+// The caller holds the lock.
+func (f *Fake) call(ctx context.Context, operation string) error {
+	f.calls[operation]++
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return f.errs[operation]
+}
+
+// logGroup returns a log group, creating it if needed. The caller holds the lock.
+func (f *Fake) logGroup(name string) *logGroup {
+	group, ok := f.groups[name]
+	if !ok {
+		group = &logGroup{created: time.Now(), streams: make(map[string]*logStream)}
+		f.groups[name] = group
+	}
+	return group
+}
+
+// pageSize returns the number of results of a page given the requested limit and the
+// default and maximum of the API
+func (f *Fake) pageSize(limit *int32, defaultSize int) int {
+	size := defaultSize
+	if limit != nil && *limit > 0 && int(*limit) < size {
+		size = int(*limit)
+	}
+	if f.PageSize > 0 && f.PageSize < size {
+		size = f.PageSize
+	}
+	return size
+}
+
+// pageOffset returns the offset of the page of a pagination token
+func pageOffset(token *string) (int, error) {
+	if token == nil {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(*token)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid pagination token '%s'", *token)
+	}
+	return offset, nil
+}
+
+// nextPageToken returns the token of the page following the results up to end, or nil
+// when they are the last ones
+func nextPageToken(end, total int) *string {
+	if end >= total {
+		return nil
+	}
+	return awssdk.String(strconv.Itoa(end))
+}
+
+// ListClusters lists the cluster names in alphabetical order
+func (f *Fake) ListClusters(ctx context.Context, params *eks.ListClustersInput, optFns ...func(*eks.Options)) (*eks.ListClustersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "ListClusters"); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(f.clusters))
+	for name := range f.clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	offset, err := pageOffset(params.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	offset = min(offset, len(names))
+	end := min(offset+f.pageSize(params.MaxResults, 100), len(names))
+	return &eks.ListClustersOutput{
+		Clusters:  names[offset:end],
+		NextToken: nextPageToken(end, len(names)),
+	}, nil
+}
+
+// DescribeCluster returns a cluster added by AddCluster or SeedCluster
+func (f *Fake) DescribeCluster(ctx context.Context, params *eks.DescribeClusterInput, optFns ...func(*eks.Options)) (*eks.DescribeClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "DescribeCluster"); err != nil {
+		return nil, err
+	}
+
+	cluster, ok := f.clusters[awssdk.ToString(params.Name)]
+	if !ok {
+		return nil, &ekstypes.ResourceNotFoundException{
+			Message: awssdk.String(fmt.Sprintf("No cluster found for name: %s.", awssdk.ToString(params.Name))),
+		}
+	}
+	return &eks.DescribeClusterOutput{Cluster: &cluster}, nil
+}
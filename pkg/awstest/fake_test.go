@@ -0,0 +1,111 @@
+package awstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/kzcat/ekslogs/pkg/aws"
+	"github.com/kzcat/ekslogs/pkg/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClusters(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+	fake.PageSize = 2
+	for _, name := range []string{"staging", "prod", "dev"} {
+		fake.AddCluster(name)
+	}
+
+	first, err := fake.ListClusters(ctx, &eks.ListClustersInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "prod"}, first.Clusters)
+	require.NotNil(t, first.NextToken)
+	second, err := fake.ListClusters(ctx, &eks.ListClustersInput{NextToken: first.NextToken})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"staging"}, second.Clusters)
+	assert.Nil(t, second.NextToken)
+
+	resp, err := fake.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: awssdk.String("prod")})
+	require.NoError(t, err)
+	assert.Equal(t, ekstypes.ClusterStatusActive, resp.Cluster.Status)
+	assert.Equal(t, "arn:aws:eks:us-east-1:123456789012:cluster/prod", awssdk.ToString(resp.Cluster.Arn))
+
+	_, err = fake.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: awssdk.String("missing")})
+	var notFound *ekstypes.ResourceNotFoundException
+	assert.ErrorAs(t, err, &notFound)
+	assert.Equal(t, 2, fake.Calls("DescribeCluster"))
+}
+
+func TestFakeSetError(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+	failure := errors.New("throttled")
+
+	fake.SetError("ListClusters", failure)
+	_, err := fake.ListClusters(ctx, &eks.ListClustersInput{})
+	assert.ErrorIs(t, err, failure)
+
+	fake.SetError("ListClusters", nil)
+	_, err = fake.ListClusters(ctx, &eks.ListClustersInput{})
+	assert.NoError(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = fake.ListClusters(canceled, &eks.ListClustersInput{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 3, fake.Calls("ListClusters"))
+}
+
+// TestClientWithFake runs the client of the library against a seeded fake
+func TestClientWithFake(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+	fake.PageSize = 7
+	start := time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC)
+	total := fake.SeedCluster("prod", start)
+	require.Positive(t, total)
+	client := aws.NewEKSLogsClientWithAPIs(Region, fake, fake, nil)
+
+	cluster, err := client.GetClusterInfo(ctx, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", awssdk.ToString(cluster.Name))
+
+	_, err = client.GetClusterInfo(ctx, "prdo")
+	var notFound *aws.ClusterNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	assert.Equal(t, []string{"prod"}, notFound.Matches)
+
+	entries, err := client.CollectLogs(ctx, "prod", nil, &start, nil, nil, 0)
+	require.NoError(t, err)
+	assert.Len(t, entries, total)
+	assert.Equal(t, start, entries[0].Timestamp.UTC())
+	for _, entry := range entries {
+		assert.NotEmpty(t, entry.EventID)
+		assert.Equal(t, "/aws/eks/prod/cluster", entry.LogGroup)
+	}
+
+	wantAudit := 0
+	for _, entry := range log.BenchCorpus() {
+		if log.LogTypeFor(entry.LogGroup, entry.LogStream) == "audit" {
+			wantAudit++
+		}
+	}
+	audit, err := client.CollectLogs(ctx, "prod", []string{"audit"}, &start, nil, nil, 0)
+	require.NoError(t, err)
+	assert.Len(t, audit, wantAudit)
+
+	limited, err := client.CollectLogs(ctx, "prod", nil, &start, nil, awssdk.String(`"kube-system"`), 3)
+	require.NoError(t, err)
+	assert.Len(t, limited, 3)
+	for _, entry := range limited {
+		assert.Contains(t, entry.Message, "kube-system")
+	}
+	assert.Positive(t, fake.Calls("FilterLogEvents"))
+}
@@ -0,0 +1,324 @@
+package awstest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+)
+
+// The default and maximum results per page of the CloudWatch Logs APIs
+const (
+	maxDescribeResults = 50
+	maxFilterResults   = 10000
+	maxGetResults      = 10000
+)
+
+// logGroupARN returns the ARN of a log group
+func logGroupARN(name string) string {
+	return fmt.Sprintf("arn:aws:logs:%s:%s:log-group:%s", Region, AccountID, name)
+}
+
+// groupName returns the log group named by the name or the identifier (ARN) of an input
+func groupName(name, identifier *string) string {
+	if name != nil {
+		return *name
+	}
+	id := awssdk.ToString(identifier)
+	if i := strings.Index(id, ":log-group:"); i >= 0 {
+		return strings.TrimSuffix(id[i+len(":log-group:"):], ":*")
+	}
+	return id
+}
+
+// notFound returns the error of the API for a missing log group or stream
+func notFound(format string, args ...any) error {
+	return &cwt.ResourceNotFoundException{Message: awssdk.String(fmt.Sprintf(format, args...))}
+}
+
+// invalidParameter returns the error of the API for an invalid input
+func invalidParameter(format string, args ...any) error {
+	return &cwt.InvalidParameterException{Message: awssdk.String(fmt.Sprintf(format, args...))}
+}
+
+// millis returns a time as milliseconds since the epoch
+func millis(t time.Time) *int64 {
+	return awssdk.Int64(t.UnixMilli())
+}
+
+// DescribeLogGroups lists the log groups in alphabetical order, by name prefix or pattern
+func (f *Fake) DescribeLogGroups(ctx context.Context, params *cloudwatchlogs.DescribeLogGroupsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "DescribeLogGroups"); err != nil {
+		return nil, err
+	}
+	if params.LogGroupNamePrefix != nil && params.LogGroupNamePattern != nil {
+		return nil, invalidParameter("LogGroupNamePrefix and LogGroupNamePattern are mutually exclusive")
+	}
+
+	var names []string
+	for name := range f.groups {
+		if strings.HasPrefix(name, awssdk.ToString(params.LogGroupNamePrefix)) &&
+			strings.Contains(name, awssdk.ToString(params.LogGroupNamePattern)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	offset, err := pageOffset(params.NextToken)
+	if err != nil {
+		return nil, invalidParameter("%v", err)
+	}
+	offset = min(offset, len(names))
+	end := min(offset+f.pageSize(params.Limit, maxDescribeResults), len(names))
+	output := &cloudwatchlogs.DescribeLogGroupsOutput{NextToken: nextPageToken(end, len(names))}
+	for _, name := range names[offset:end] {
+		group := f.groups[name]
+		var storedBytes int64
+		for _, stream := range group.streams {
+			storedBytes += stream.storedBytes()
+		}
+		output.LogGroups = append(output.LogGroups, cwt.LogGroup{
+			Arn:          awssdk.String(logGroupARN(name) + ":*"),
+			LogGroupName: awssdk.String(name),
+			CreationTime: millis(group.created),
+			StoredBytes:  awssdk.Int64(storedBytes),
+		})
+	}
+	return output, nil
+}
+
+// DescribeLogStreams lists the streams of a log group by name or by last event time,
+// ascending unless Descending is set
+func (f *Fake) DescribeLogStreams(ctx context.Context, params *cloudwatchlogs.DescribeLogStreamsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "DescribeLogStreams"); err != nil {
+		return nil, err
+	}
+
+	name := groupName(params.LogGroupName, params.LogGroupIdentifier)
+	group, ok := f.groups[name]
+	if !ok {
+		return nil, notFound("The specified log group does not exist: %s", name)
+	}
+	byEventTime := params.OrderBy == cwt.OrderByLastEventTime
+	if byEventTime && params.LogStreamNamePrefix != nil {
+		return nil, invalidParameter("Cannot order by LastEventTime with a logStreamNamePrefix.")
+	}
+
+	var names []string
+	for streamName := range group.streams {
+		if strings.HasPrefix(streamName, awssdk.ToString(params.LogStreamNamePrefix)) {
+			names = append(names, streamName)
+		}
+	}
+	sort.Strings(names)
+	if byEventTime {
+		sort.SliceStable(names, func(i, j int) bool {
+			return group.streams[names[i]].lastEventTime().Before(group.streams[names[j]].lastEventTime())
+		})
+	}
+	if awssdk.ToBool(params.Descending) {
+		for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+			names[i], names[j] = names[j], names[i]
+		}
+	}
+
+	offset, err := pageOffset(params.NextToken)
+	if err != nil {
+		return nil, invalidParameter("%v", err)
+	}
+	offset = min(offset, len(names))
+	end := min(offset+f.pageSize(params.Limit, maxDescribeResults), len(names))
+	output := &cloudwatchlogs.DescribeLogStreamsOutput{NextToken: nextPageToken(end, len(names))}
+	for _, streamName := range names[offset:end] {
+		stream := group.streams[streamName]
+		logStream := cwt.LogStream{
+			Arn:           awssdk.String(logGroupARN(name) + ":log-stream:" + streamName),
+			LogStreamName: awssdk.String(streamName),
+			CreationTime:  millis(stream.created),
+			StoredBytes:   awssdk.Int64(stream.storedBytes()),
+		}
+		if n := len(stream.events); n > 0 {
+			logStream.FirstEventTimestamp = millis(stream.events[0].Timestamp)
+			logStream.LastEventTimestamp = millis(stream.events[n-1].Timestamp)
+			logStream.LastIngestionTime = millis(stream.lastIngestionTime())
+		}
+		output.LogStreams = append(output.LogStreams, logStream)
+	}
+	return output, nil
+}
+
+// FilterLogEvents returns the events of a log group in the time range matching the filter
+// pattern, oldest first. The end time is included, as by the API. See matchPattern for the
+// supported filter patterns.
+func (f *Fake) FilterLogEvents(ctx context.Context, params *cloudwatchlogs.FilterLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.FilterLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "FilterLogEvents"); err != nil {
+		return nil, err
+	}
+
+	name := groupName(params.LogGroupName, params.LogGroupIdentifier)
+	group, ok := f.groups[name]
+	if !ok {
+		return nil, notFound("The specified log group does not exist: %s", name)
+	}
+	if len(params.LogStreamNames) > 0 && params.LogStreamNamePrefix != nil {
+		return nil, invalidParameter("LogStreamNames and LogStreamNamePrefix are mutually exclusive")
+	}
+	if len(params.LogStreamNames) > 100 {
+		return nil, invalidParameter("At most 100 log stream names can be given")
+	}
+	selected := make(map[string]bool, len(params.LogStreamNames))
+	for _, streamName := range params.LogStreamNames {
+		selected[streamName] = true
+	}
+
+	var events []cwt.FilteredLogEvent
+	for streamName, stream := range group.streams {
+		if len(selected) > 0 && !selected[streamName] || !strings.HasPrefix(streamName, awssdk.ToString(params.LogStreamNamePrefix)) {
+			continue
+		}
+		for _, event := range stream.events {
+			timestamp := event.Timestamp.UnixMilli()
+			if params.StartTime != nil && timestamp < *params.StartTime || params.EndTime != nil && timestamp > *params.EndTime {
+				continue
+			}
+			if !matchPattern(awssdk.ToString(params.FilterPattern), event.Message) {
+				continue
+			}
+			events = append(events, cwt.FilteredLogEvent{
+				EventId:       awssdk.String(event.id),
+				LogStreamName: awssdk.String(streamName),
+				Timestamp:     awssdk.Int64(timestamp),
+				IngestionTime: millis(event.IngestionTime),
+				Message:       awssdk.String(event.Message),
+			})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if *events[i].Timestamp != *events[j].Timestamp {
+			return *events[i].Timestamp < *events[j].Timestamp
+		}
+		return *events[i].EventId < *events[j].EventId
+	})
+
+	offset, err := pageOffset(params.NextToken)
+	if err != nil {
+		return nil, invalidParameter("%v", err)
+	}
+	offset = min(offset, len(events))
+	end := min(offset+f.pageSize(params.Limit, maxFilterResults), len(events))
+	return &cloudwatchlogs.FilterLogEventsOutput{
+		Events:    events[offset:end],
+		NextToken: nextPageToken(end, len(events)),
+	}, nil
+}
+
+// GetLogEvents returns the events of a log stream in the time range, the end time
+// excluded. Like the API, it starts from the most recent events unless StartFromHead is
+// set, and the end of the stream is reached when a returned token equals the given one.
+func (f *Fake) GetLogEvents(ctx context.Context, params *cloudwatchlogs.GetLogEventsInput, optFns ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.GetLogEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.call(ctx, "GetLogEvents"); err != nil {
+		return nil, err
+	}
+
+	name := groupName(params.LogGroupName, params.LogGroupIdentifier)
+	group, ok := f.groups[name]
+	if !ok {
+		return nil, notFound("The specified log group does not exist: %s", name)
+	}
+	stream, ok := group.streams[awssdk.ToString(params.LogStreamName)]
+	if !ok {
+		return nil, notFound("The specified log stream does not exist: %s", awssdk.ToString(params.LogStreamName))
+	}
+
+	var events []storedEvent
+	for _, event := range stream.events {
+		timestamp := event.Timestamp.UnixMilli()
+		if params.StartTime != nil && timestamp < *params.StartTime || params.EndTime != nil && timestamp >= *params.EndTime {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	// Tokens are f/<offset> to read forward from an offset and b/<offset> to read
+	// backward up to it
+	size := f.pageSize(params.Limit, maxGetResults)
+	var start, end int
+	switch token := awssdk.ToString(params.NextToken); {
+	case strings.HasPrefix(token, "f/"):
+		offset, err := pageOffset(awssdk.String(token[2:]))
+		if err != nil {
+			return nil, invalidParameter("%v", err)
+		}
+		start = min(offset, len(events))
+		end = min(start+size, len(events))
+	case strings.HasPrefix(token, "b/"):
+		offset, err := pageOffset(awssdk.String(token[2:]))
+		if err != nil {
+			return nil, invalidParameter("%v", err)
+		}
+		end = min(offset, len(events))
+		start = max(end-size, 0)
+	case token != "":
+		return nil, invalidParameter("invalid pagination token '%s'", token)
+	case awssdk.ToBool(params.StartFromHead):
+		end = min(size, len(events))
+	default:
+		end = len(events)
+		start = max(end-size, 0)
+	}
+
+	output := &cloudwatchlogs.GetLogEventsOutput{
+		NextForwardToken:  awssdk.String(fmt.Sprintf("f/%d", end)),
+		NextBackwardToken: awssdk.String(fmt.Sprintf("b/%d", start)),
+	}
+	for _, event := range events[start:end] {
+		output.Events = append(output.Events, cwt.OutputLogEvent{
+			Timestamp:     millis(event.Timestamp),
+			IngestionTime: millis(event.IngestionTime),
+			Message:       awssdk.String(event.Message),
+		})
+	}
+	return output, nil
+}
+
+// lastEventTime returns the timestamp of the latest event of a stream, or its creation
+// time when it has none
+func (s *logStream) lastEventTime() time.Time {
+	if len(s.events) == 0 {
+		return s.created
+	}
+	return s.events[len(s.events)-1].Timestamp
+}
+
+// lastIngestionTime returns the latest ingestion time of the events of a stream
+func (s *logStream) lastIngestionTime() time.Time {
+	var last time.Time
+	for _, event := range s.events {
+		if event.IngestionTime.After(last) {
+			last = event.IngestionTime
+		}
+	}
+	return last
+}
+
+// storedBytes returns the size of the messages of a stream
+func (s *logStream) storedBytes() int64 {
+	var size int64
+	for _, event := range s.events {
+		size += int64(len(event.Message))
+	}
+	return size
+}
@@ -0,0 +1,176 @@
+package awstest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	cwt "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGroup = "/aws/eks/prod/cluster"
+
+var base = time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC)
+
+// seedStreams adds an api stream with events at base+0s..2s and an audit stream with
+// events at base+1s and base+5s
+func seedStreams(fake *Fake) {
+	fake.AddEvents(testGroup, "kube-apiserver-abc",
+		Event{Timestamp: base.Add(2 * time.Second), Message: "E0612 watch failed"},
+		Event{Timestamp: base, Message: "I0612 started"},
+		Event{Timestamp: base.Add(time.Second), Message: "W0612 slow request"},
+	)
+	fake.AddEvents(testGroup, "kube-apiserver-audit-abc",
+		Event{Timestamp: base.Add(time.Second), Message: `{"verb":"get"}`},
+		Event{Timestamp: base.Add(5 * time.Second), Message: `{"verb":"delete"}`},
+	)
+}
+
+// messages returns the messages of filtered events
+func messages(events []cwt.FilteredLogEvent) []string {
+	var result []string
+	for _, event := range events {
+		result = append(result, awssdk.ToString(event.Message))
+	}
+	return result
+}
+
+func TestFakeDescribeLogGroups(t *testing.T) {
+	fake := New()
+	seedStreams(fake)
+	fake.AddLogGroup("/aws/eks/staging/cluster")
+
+	resp, err := fake.DescribeLogGroups(context.Background(), &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: awssdk.String("/aws/eks/prod/"),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.LogGroups, 1)
+	assert.Equal(t, testGroup, awssdk.ToString(resp.LogGroups[0].LogGroupName))
+	assert.Positive(t, awssdk.ToInt64(resp.LogGroups[0].StoredBytes))
+
+	resp, err = fake.DescribeLogGroups(context.Background(), &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePattern: awssdk.String("cluster"),
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.LogGroups, 2)
+}
+
+func TestFakeDescribeLogStreams(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+	fake.PageSize = 1
+	seedStreams(fake)
+
+	input := &cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName: awssdk.String(testGroup),
+		OrderBy:      cwt.OrderByLastEventTime,
+		Descending:   awssdk.Bool(true),
+	}
+	first, err := fake.DescribeLogStreams(ctx, input)
+	require.NoError(t, err)
+	require.Len(t, first.LogStreams, 1)
+	stream := first.LogStreams[0]
+	assert.Equal(t, "kube-apiserver-audit-abc", awssdk.ToString(stream.LogStreamName))
+	assert.Equal(t, base.Add(time.Second).UnixMilli(), awssdk.ToInt64(stream.FirstEventTimestamp))
+	assert.Equal(t, base.Add(5*time.Second).UnixMilli(), awssdk.ToInt64(stream.LastEventTimestamp))
+
+	input.NextToken = first.NextToken
+	second, err := fake.DescribeLogStreams(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, "kube-apiserver-abc", awssdk.ToString(second.LogStreams[0].LogStreamName))
+	assert.Nil(t, second.NextToken)
+
+	_, err = fake.DescribeLogStreams(ctx, &cloudwatchlogs.DescribeLogStreamsInput{LogGroupName: awssdk.String("/missing")})
+	var notFound *cwt.ResourceNotFoundException
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestFakeFilterLogEvents(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+	seedStreams(fake)
+
+	resp, err := fake.FilterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{LogGroupName: awssdk.String(testGroup)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"I0612 started", "W0612 slow request", `{"verb":"get"}`, "E0612 watch failed", `{"verb":"delete"}`}, messages(resp.Events))
+	assert.Nil(t, resp.NextToken)
+
+	// The end time is included
+	resp, err = fake.FilterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:   awssdk.String(testGroup),
+		LogStreamNames: []string{"kube-apiserver-abc"},
+		StartTime:      awssdk.Int64(base.Add(time.Second).UnixMilli()),
+		EndTime:        awssdk.Int64(base.Add(2 * time.Second).UnixMilli()),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"W0612 slow request", "E0612 watch failed"}, messages(resp.Events))
+
+	resp, err = fake.FilterLogEvents(ctx, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:  awssdk.String(testGroup),
+		FilterPattern: awssdk.String(`?E0612 ?W0612 -slow`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"E0612 watch failed"}, messages(resp.Events))
+
+	// Pages of two events
+	var all []cwt.FilteredLogEvent
+	input := &cloudwatchlogs.FilterLogEventsInput{LogGroupName: awssdk.String(testGroup), Limit: awssdk.Int32(2)}
+	for pages := 1; ; pages++ {
+		resp, err := fake.FilterLogEvents(ctx, input)
+		require.NoError(t, err)
+		all = append(all, resp.Events...)
+		if resp.NextToken == nil {
+			assert.Equal(t, 3, pages)
+			break
+		}
+		input.NextToken = resp.NextToken
+	}
+	assert.Len(t, all, 5)
+	assert.NotEqual(t, all[0].EventId, all[1].EventId)
+}
+
+func TestFakeGetLogEvents(t *testing.T) {
+	ctx := context.Background()
+	fake := New()
+	seedStreams(fake)
+
+	input := &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  awssdk.String(testGroup),
+		LogStreamName: awssdk.String("kube-apiserver-abc"),
+		StartFromHead: awssdk.Bool(true),
+		Limit:         awssdk.Int32(2),
+		// The end time is excluded
+		EndTime: awssdk.Int64(base.Add(2 * time.Second).UnixMilli()),
+	}
+	first, err := fake.GetLogEvents(ctx, input)
+	require.NoError(t, err)
+	require.Len(t, first.Events, 2)
+	assert.Equal(t, "I0612 started", awssdk.ToString(first.Events[0].Message))
+
+	// The end is reached when the same token is returned
+	input.NextToken = first.NextForwardToken
+	second, err := fake.GetLogEvents(ctx, input)
+	require.NoError(t, err)
+	assert.Empty(t, second.Events)
+	assert.Equal(t, awssdk.ToString(input.NextToken), awssdk.ToString(second.NextForwardToken))
+
+	// Without StartFromHead the most recent events come first
+	latest, err := fake.GetLogEvents(ctx, &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  awssdk.String(testGroup),
+		LogStreamName: awssdk.String("kube-apiserver-abc"),
+		Limit:         awssdk.Int32(1),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "E0612 watch failed", awssdk.ToString(latest.Events[0].Message))
+
+	_, err = fake.GetLogEvents(ctx, &cloudwatchlogs.GetLogEventsInput{
+		LogGroupName:  awssdk.String(testGroup),
+		LogStreamName: awssdk.String("missing"),
+	})
+	var notFound *cwt.ResourceNotFoundException
+	assert.ErrorAs(t, err, &notFound)
+}
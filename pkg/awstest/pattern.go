@@ -0,0 +1,81 @@
+package awstest
+
+import "strings"
+
+// patternTerm is a term of a filter pattern
+type patternTerm struct {
+	text string
+	// optional terms (?term) match when any of them is found
+	optional bool
+	// excluded terms (-term) must not be found
+	excluded bool
+}
+
+// matchPattern reports whether a message matches a CloudWatch Logs filter pattern of
+// terms: every term must be found in the message, terms prefixed with - must not be, and
+// at least one of the terms prefixed with ? must be. Terms are case-sensitive and may be
+// quoted to include spaces and punctuation. JSON ({ $.field = ... }) and space-delimited
+// ([ip, user, ...]) patterns are not evaluated and match every message.
+func matchPattern(pattern, message string) bool {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || strings.HasPrefix(pattern, "{") || strings.HasPrefix(pattern, "[") {
+		return true
+	}
+
+	anyOptional, foundOptional := false, false
+	for _, term := range parsePattern(pattern) {
+		found := strings.Contains(message, term.text)
+		switch {
+		case term.optional:
+			anyOptional = true
+			foundOptional = foundOptional || found
+		case term.excluded:
+			if found {
+				return false
+			}
+		case !found:
+			return false
+		}
+	}
+	return !anyOptional || foundOptional
+}
+
+// parsePattern splits a filter pattern into its terms
+func parsePattern(pattern string) []patternTerm {
+	var terms []patternTerm
+	for rest := pattern; ; {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return terms
+		}
+		var term patternTerm
+		switch rest[0] {
+		case '?':
+			term.optional, rest = true, rest[1:]
+		case '-':
+			term.excluded, rest = true, rest[1:]
+		}
+
+		if strings.HasPrefix(rest, `"`) {
+			// A quoted term ends at the next unescaped quote
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			term.text, rest = b.String(), rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexAny(rest, " \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			term.text, rest = rest[:end], rest[end:]
+		}
+		if term.text != "" {
+			terms = append(terms, term)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package awstest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPattern(t *testing.T) {
+	message := `E0612 10:00:00 reflector.go:147] failed to list *v1.Pod: connection refused`
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"", true},
+		{"failed", true},
+		{"Failed", false},
+		{"failed refused", true},
+		{"failed timeout", false},
+		{`"connection refused"`, true},
+		{`"refused connection"`, false},
+		{"failed -refused", false},
+		{"failed -timeout", true},
+		{"?timeout ?refused", true},
+		{"?timeout ?denied", false},
+		{`{ $.verb = "get" }`, true},
+		{"[ip, user]", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchPattern(tt.pattern, message))
+		})
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	assert.Equal(t, []patternTerm{
+		{text: "a b"},
+		{text: "c", optional: true},
+		{text: `say "hi"`, excluded: true},
+	}, parsePattern(`"a b" ?c -"say \"hi\""`))
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// EnvCacheDir names the environment variable overriding the cache directory
+const EnvCacheDir = "EKSLOGS_CACHE_DIR"
+
+// Retention is how long results are kept on disk, for --offline, whatever the TTL
+const Retention = 7 * 24 * time.Hour
+
+// fileSuffix ends the names of the cache files
+const fileSuffix = ".json.gz"
+
+// Cache stores the results of expensive retrievals as gzip-compressed JSON files in a
+// directory, named after the SHA-256 hash of their key
+type Cache struct {
+	dir string
+	ttl time.Duration
+	now func() time.Time
+}
+
+// entry is the content of a cache file
+type entry struct {
+	StoredAt time.Time       `json:"stored_at"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// DefaultDir returns the cache directory: $EKSLOGS_CACHE_DIR, or ekslogs/results under
+// $XDG_CACHE_HOME (defaulting to ~/.cache)
+func DefaultDir() string {
+	if dir := os.Getenv(EnvCacheDir); dir != "" {
+		return dir
+	}
+
+	cacheHome := os.Getenv("XDG_CACHE_HOME")
+	if cacheHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		cacheHome = filepath.Join(home, ".cache")
+	}
+
+	return filepath.Join(cacheHome, "ekslogs", "results")
+}
+
+// New returns a cache in dir whose results expire after ttl. A zero ttl accepts results of
+// any age still on disk.
+func New(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl, now: time.Now}
+}
+
+// Key hashes the parts identifying a result, e.g. the cluster, time range and pattern
+func Key(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// path returns the file of the result stored under key
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+fileSuffix)
+}
+
+// Get decodes the result stored under key into v and returns when it was stored. ok is
+// false when there is none or it expired.
+func (c *Cache) Get(key string, v any) (storedAt time.Time, ok bool, err error) {
+	f, err := os.Open(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read cached result %s: %w", f.Name(), err)
+	}
+	var e entry
+	if err := json.NewDecoder(gz).Decode(&e); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read cached result %s: %w", f.Name(), err)
+	}
+	if c.ttl > 0 && c.now().Sub(e.StoredAt) > c.ttl {
+		return e.StoredAt, false, nil
+	}
+	if err := json.Unmarshal(e.Value, v); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to read cached result %s: %w", f.Name(), err)
+	}
+	return e.StoredAt, true, nil
+}
+
+// Put stores v under key, replacing the previous result, and removes the results older
+// than Retention. The file is written to a temporary name first, so a concurrent Get
+// never reads a partial result.
+func (c *Cache) Put(key string, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cached result: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	gz := gzip.NewWriter(tmp)
+	err = json.NewEncoder(gz).Encode(entry{StoredAt: c.now(), Value: value})
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write cached result: %w", err)
+	}
+	c.prune()
+	return nil
+}
+
+// prune removes the results stored longer than Retention ago, judged by the modification
+// time of their file
+func (c *Cache) prune() {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), fileSuffix) {
+			continue
+		}
+		if info, err := file.Info(); err == nil && c.now().Sub(info.ModTime()) > Retention {
+			_ = os.Remove(filepath.Join(c.dir, file.Name()))
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type result struct {
+	Messages []string `json:"messages"`
+}
+
+func TestCacheGetPut(t *testing.T) {
+	now := time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC)
+	c := New(t.TempDir(), 10*time.Minute)
+	c.now = func() time.Time { return now }
+	key := Key("logs", "us-east-1", "prod", "-1h", "", "ERROR")
+
+	var got result
+	_, ok, err := c.Get(key, &got)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, c.Put(key, result{Messages: []string{"a", "b"}}))
+	storedAt, ok, err := c.Get(key, &got)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, now, storedAt.UTC())
+	assert.Equal(t, []string{"a", "b"}, got.Messages)
+
+	// Expired for the TTL, still readable without one (--offline)
+	now = now.Add(11 * time.Minute)
+	_, ok, err = c.Get(key, &got)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	offline := New(c.dir, 0)
+	_, ok, err = offline.Get(key, &got)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestCacheKey(t *testing.T) {
+	assert.Equal(t, Key("a", "b"), Key("a", "b"))
+	assert.NotEqual(t, Key("a", "b"), Key("ab"))
+	assert.NotEqual(t, Key("a", "b"), Key("a", "c"))
+	assert.Len(t, Key("a"), 64)
+}
+
+func TestCacheCorruptFile(t *testing.T) {
+	c := New(t.TempDir(), time.Minute)
+	key := Key("x")
+	assert.NoError(t, os.WriteFile(c.path(key), []byte("not gzip"), 0o600))
+	var got result
+	_, ok, err := c.Get(key, &got)
+	assert.Error(t, err)
+	assert.False(t, ok)
+}
+
+func TestCachePrune(t *testing.T) {
+	c := New(t.TempDir(), time.Minute)
+	old := filepath.Join(c.dir, Key("old")+fileSuffix)
+	assert.NoError(t, os.WriteFile(old, nil, 0o600))
+	stale := time.Now().Add(-Retention - time.Hour)
+	assert.NoError(t, os.Chtimes(old, stale, stale))
+
+	assert.NoError(t, c.Put(Key("new"), result{}))
+	assert.NoFileExists(t, old)
+	assert.FileExists(t, c.path(Key("new")))
+}
+
+func TestDefaultDir(t *testing.T) {
+	t.Setenv(EnvCacheDir, "/tmp/ekslogs-cache")
+	assert.Equal(t, "/tmp/ekslogs-cache", DefaultDir())
+
+	t.Setenv(EnvCacheDir, "")
+	t.Setenv("XDG_CACHE_HOME", "/var/cache")
+	assert.Equal(t, filepath.Join("/var/cache", "ekslogs", "results"), DefaultDir())
+}
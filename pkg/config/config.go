@@ -0,0 +1,311 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigPath is the environment variable overriding the default config file path
+const EnvConfigPath = "EKSLOGS_CONFIG"
+
+// Config holds the settings read from the ekslogs config file
+type Config struct {
+	// Theme is the color theme used when --theme is not given
+	Theme     string          `yaml:"theme"`
+	Highlight HighlightConfig `yaml:"highlight"`
+	// CurrentContext is the context used when --context is not given
+	CurrentContext string `yaml:"current-context"`
+	// Contexts are named cluster settings, selected with --context or `ekslogs ctx use`
+	Contexts map[string]Context `yaml:"contexts"`
+	// Fleet lists the accounts searched by `ekslogs fleet`
+	Fleet []FleetAccount `yaml:"fleet"`
+	// UpdateCheck prints a notice on stderr when a newer release is available, checked at
+	// most once a day
+	UpdateCheck bool `yaml:"update-check"`
+	// Presets are filter presets added to the built-in ones, usable with --preset
+	Presets map[string]Preset `yaml:"presets"`
+	// Queries are saved invocations of ekslogs, run with `ekslogs run <name>`
+	Queries map[string]SavedQuery `yaml:"queries"`
+	// LogTypes are log types added to the built-in ones, or streams and aliases added to a
+	// built-in log type
+	LogTypes map[string]LogType `yaml:"log-types"`
+}
+
+// LogType is a log type defined in the config file
+type LogType struct {
+	Description string `yaml:"description"`
+	// LogGroup is read along with the control plane log group, {cluster} standing for the
+	// cluster name
+	LogGroup string `yaml:"log-group"`
+	// Streams are the prefixes of the names of the log streams of the log type
+	Streams []string `yaml:"streams"`
+	Aliases []string `yaml:"aliases"`
+}
+
+// SavedQuery is a named invocation of ekslogs, with its cluster, log types, filters and
+// output flags
+type SavedQuery struct {
+	Description string `yaml:"description,omitempty"`
+	// Args are the command line arguments of the query, without the program name
+	Args []string `yaml:"args"`
+}
+
+// Preset is a filter preset defined in the config file
+type Preset struct {
+	Description string `yaml:"description"`
+	// LogTypes are read by the preset (default: those of the included presets)
+	LogTypes []string `yaml:"log-types"`
+	Pattern  string   `yaml:"pattern"`
+	// Include lists presets, built in or of the config file, whose events the preset
+	// matches too
+	Include []string `yaml:"include"`
+	// Since, Limit and Output are the defaults of --start-time, --limit and --output
+	Since  string `yaml:"since"`
+	Limit  int32  `yaml:"limit"`
+	Output string `yaml:"output"`
+}
+
+// FleetAccount is an AWS account of the fleet, reached by assuming a role
+type FleetAccount struct {
+	// Name prefixes the lines of the account (default: the account ID of the role ARN)
+	Name string `yaml:"name"`
+	// RoleARN is assumed to access the account; without it the default credentials are used
+	RoleARN string `yaml:"role-arn"`
+	// Regions are searched for clusters (default: --region or the default region)
+	Regions []string `yaml:"regions"`
+}
+
+// Context holds the settings of a cluster environment, like a kubectl context
+type Context struct {
+	Cluster string `yaml:"cluster"`
+	Region  string `yaml:"region"`
+	// Profile is the AWS shared config profile to use
+	Profile string `yaml:"profile"`
+	// RoleARN is an IAM role assumed with the profile's credentials
+	RoleARN string `yaml:"role-arn"`
+	// LogTypes are retrieved when no log types are given
+	LogTypes []string `yaml:"log-types"`
+	// Preset is applied when --preset is not given
+	Preset string `yaml:"preset"`
+	// SourceIPAllowlist are the CIDRs expected to reach the API server, used by
+	// `ekslogs source-ips` when --allow-cidr is not given
+	SourceIPAllowlist []string `yaml:"source-ip-allowlist"`
+}
+
+// HighlightConfig customizes the highlighting of log messages
+type HighlightConfig struct {
+	// Rules are custom highlight rules applied after the built-in ones
+	Rules []HighlightRule `yaml:"rules"`
+	// Disable lists built-in rules to turn off, as "type.rule" or a bare rule name
+	Disable []string `yaml:"disable"`
+}
+
+// HighlightRule colors the matches of a regular expression
+type HighlightRule struct {
+	LogType string `yaml:"log-type"`
+	Pattern string `yaml:"pattern"`
+	Color   string `yaml:"color"`
+}
+
+// DefaultPath returns the config file path: $EKSLOGS_CONFIG, or ekslogs/config.yaml
+// under $XDG_CONFIG_HOME (defaulting to ~/.config)
+func DefaultPath() string {
+	if path := os.Getenv(EnvConfigPath); path != "" {
+		return path
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configHome, "ekslogs", "config.yaml")
+}
+
+// Load reads the config file at path. If path is empty, the default path is used
+// and a missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = DefaultPath()
+		if path == "" {
+			return &Config{}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Context returns the named context, or the current context when name is empty. It returns
+// nil when no context is selected.
+func (c *Config) Context(name string) (*Context, error) {
+	if name == "" {
+		name = c.CurrentContext
+		if name == "" {
+			return nil, nil
+		}
+	}
+
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context '%s' not found in the config file", name)
+	}
+	return &ctx, nil
+}
+
+// SetCurrentContext sets current-context in the config file at path (the default path if
+// empty), keeping the rest of the file, including comments, as it is
+func SetCurrentContext(path, name string) error {
+	if path == "" {
+		path = DefaultPath()
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	if _, ok := cfg.Contexts[name]; !ok {
+		return fmt.Errorf("context '%s' not found in the config file", name)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	setMappingValue(doc.Content[0], "current-context", name)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// SaveQuery adds or replaces a saved query in the config file at path (the default path if
+// empty), creating the file if needed and keeping the rest of it, including comments, as
+// it is
+func SaveQuery(path, name string, query SavedQuery) error {
+	if path == "" {
+		path = DefaultPath()
+		if path == "" {
+			return errors.New("no config file path: set $EKSLOGS_CONFIG or use --config")
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if _, err := Parse(data); err != nil {
+		return fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file '%s': %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	queries := mappingValue(doc.Content[0], "queries")
+	if queries.Kind != yaml.MappingNode {
+		*queries = yaml.Node{Kind: yaml.MappingNode}
+	}
+	var queryNode yaml.Node
+	if err := queryNode.Encode(query); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	*mappingValue(queries, name) = queryNode
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value node of a key of a YAML mapping, appending the key with
+// an empty value if missing
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	keyNode := &yaml.Node{}
+	keyNode.SetString(key)
+	valueNode := &yaml.Node{}
+	mapping.Content = append(mapping.Content, keyNode, valueNode)
+	return valueNode
+}
+
+// setMappingValue sets the string value of a key of a YAML mapping, adding the key if missing
+func setMappingValue(mapping *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1].SetString(value)
+			return
+		}
+	}
+
+	keyNode := &yaml.Node{}
+	keyNode.SetString(key)
+	valueNode := &yaml.Node{}
+	valueNode.SetString(value)
+	mapping.Content = append([]*yaml.Node{keyNode, valueNode}, mapping.Content...)
+}
+
+// Parse decodes a YAML config, rejecting unknown keys
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return cfg, nil
+}
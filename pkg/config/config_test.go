@@ -0,0 +1,251 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected *Config
+		wantErr  bool
+	}{
+		{
+			name: "highlight rules",
+			data: `
+theme: light
+highlight:
+  rules:
+    - log-type: api
+      pattern: "etcd"
+      color: magenta
+  disable:
+    - api.keywords
+`,
+			expected: &Config{
+				Theme: "light",
+				Highlight: HighlightConfig{
+					Rules:   []HighlightRule{{LogType: "api", Pattern: "etcd", Color: "magenta"}},
+					Disable: []string{"api.keywords"},
+				},
+			},
+		},
+		{
+			name:     "update check",
+			data:     "update-check: true\n",
+			expected: &Config{UpdateCheck: true},
+		},
+		{
+			name: "presets",
+			data: `
+presets:
+  prod-triage:
+    description: Errors worth a look in production
+    include: [api-errors, auth-failures, scheduler-issues]
+    since: -6h
+    limit: 500
+  webhooks:
+    log-types: [api]
+    pattern: '"failed calling webhook"'
+`,
+			expected: &Config{Presets: map[string]Preset{
+				"prod-triage": {Description: "Errors worth a look in production", Include: []string{"api-errors", "auth-failures", "scheduler-issues"},
+					Since: "-6h", Limit: 500},
+				"webhooks": {LogTypes: []string{"api"}, Pattern: `"failed calling webhook"`},
+			}},
+		},
+		{
+			name: "log types",
+			data: `
+log-types:
+  karpenter:
+    description: Karpenter controller logs
+    log-group: /aws/eks/{cluster}/karpenter
+    aliases: [kpt]
+  api:
+    streams: [kube-apiserver-egress-]
+`,
+			expected: &Config{LogTypes: map[string]LogType{
+				"karpenter": {Description: "Karpenter controller logs", LogGroup: "/aws/eks/{cluster}/karpenter", Aliases: []string{"kpt"}},
+				"api":       {Streams: []string{"kube-apiserver-egress-"}},
+			}},
+		},
+		{
+			name:     "empty file",
+			data:     "",
+			expected: &Config{},
+		},
+		{
+			name:    "unknown key",
+			data:    "highlights: {}\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.data))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, cfg)
+		})
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv(EnvConfigPath, "/tmp/ekslogs.yaml")
+	assert.Equal(t, "/tmp/ekslogs.yaml", DefaultPath())
+
+	t.Setenv(EnvConfigPath, "")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	assert.Equal(t, filepath.Join("/xdg", "ekslogs", "config.yaml"), DefaultPath())
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(EnvConfigPath, filepath.Join(dir, "missing.yaml"))
+
+	// A missing default config file is not an error
+	cfg, err := Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, &Config{}, cfg)
+
+	// An explicitly requested config file must exist
+	_, err = Load(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("highlight:\n  disable: [errors]\n"), 0o600))
+	cfg, err = Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"errors"}, cfg.Highlight.Disable)
+}
+
+func TestContext(t *testing.T) {
+	cfg, err := Parse([]byte(`
+current-context: prod
+contexts:
+  prod:
+    cluster: prod-cluster
+    region: us-west-2
+    profile: prod-admin
+    role-arn: arn:aws:iam::123456789012:role/eks-log-reader
+    log-types: [audit, auth]
+    preset: auth-failures
+    source-ip-allowlist: [10.0.0.0/8, 203.0.113.0/24]
+  staging:
+    cluster: staging-cluster
+`))
+	assert.NoError(t, err)
+
+	ctx, err := cfg.Context("")
+	assert.NoError(t, err)
+	assert.Equal(t, &Context{
+		Cluster:           "prod-cluster",
+		Region:            "us-west-2",
+		Profile:           "prod-admin",
+		RoleARN:           "arn:aws:iam::123456789012:role/eks-log-reader",
+		LogTypes:          []string{"audit", "auth"},
+		Preset:            "auth-failures",
+		SourceIPAllowlist: []string{"10.0.0.0/8", "203.0.113.0/24"},
+	}, ctx)
+
+	ctx, err = cfg.Context("staging")
+	assert.NoError(t, err)
+	assert.Equal(t, "staging-cluster", ctx.Cluster)
+
+	_, err = cfg.Context("dev")
+	assert.Error(t, err)
+
+	// Without a current context, none is selected
+	ctx, err = (&Config{}).Context("")
+	assert.NoError(t, err)
+	assert.Nil(t, ctx)
+}
+
+func TestSetCurrentContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `# Log settings
+theme: light
+contexts:
+  prod:
+    cluster: prod-cluster # production
+  staging:
+    cluster: staging-cluster
+`
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	assert.NoError(t, SetCurrentContext(path, "staging"))
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", cfg.CurrentContext)
+	assert.Equal(t, "light", cfg.Theme)
+
+	// Switching again replaces the value and keeps the comments
+	assert.NoError(t, SetCurrentContext(path, "prod"))
+	written, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(written), "current-context: prod\n")
+	assert.NotContains(t, string(written), "staging\ntheme")
+	assert.Contains(t, string(written), "# production")
+
+	assert.Error(t, SetCurrentContext(path, "dev"))
+}
+
+func TestSaveQuery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ekslogs", "config.yaml")
+
+	// The config file is created if missing
+	query := SavedQuery{Args: []string{"prod-cluster", "api", "-F", "failed calling webhook", "-s", "-24h", "-o", "json"}}
+	assert.NoError(t, SaveQuery(path, "webhooks", query))
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]SavedQuery{"webhooks": query}, cfg.Queries)
+
+	data := `# Log settings
+theme: light # for the office
+queries:
+  daily:
+    description: Daily audit report
+    args: [prod-cluster, audit]
+`
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	assert.NoError(t, SaveQuery(path, "webhooks", query))
+	assert.NoError(t, SaveQuery(path, "daily", SavedQuery{Description: "Daily audit report", Args: []string{"prod-cluster", "audit", "-s", "-1d"}}))
+
+	written, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(written), "# Log settings")
+	assert.Contains(t, string(written), "# for the office")
+	cfg, err = Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "light", cfg.Theme)
+	assert.Equal(t, []string{"prod-cluster", "audit", "-s", "-1d"}, cfg.Queries["daily"].Args)
+	assert.Equal(t, query, cfg.Queries["webhooks"])
+
+	assert.NoError(t, os.WriteFile(path, []byte("themes: light\n"), 0o600))
+	assert.Error(t, SaveQuery(path, "webhooks", query))
+}
+
+func TestFleet(t *testing.T) {
+	cfg, err := Parse([]byte(`
+fleet:
+  - name: prod
+    role-arn: arn:aws:iam::123456789012:role/eks-log-reader
+    regions: [us-east-1, eu-west-1]
+  - role-arn: arn:aws:iam::210987654321:role/eks-log-reader
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, []FleetAccount{
+		{Name: "prod", RoleARN: "arn:aws:iam::123456789012:role/eks-log-reader", Regions: []string{"us-east-1", "eu-west-1"}},
+		{RoleARN: "arn:aws:iam::210987654321:role/eks-log-reader"},
+	}, cfg.Fleet)
+}
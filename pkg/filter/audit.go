@@ -0,0 +1,71 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AuditFilter selects audit events by verb, user and namespace without writing a JSON filter pattern
+type AuditFilter struct {
+	// WritesOnly keeps the requests that are not get, list or watch
+	WritesOnly bool
+	// ReadsOnly keeps the get, list and watch requests
+	ReadsOnly bool
+	// NonSystem drops the requests of system: users (controllers, nodes, service accounts)
+	NonSystem bool
+	// Namespace keeps the requests on objects of a namespace
+	Namespace string
+}
+
+// readVerbs are the verbs of the audit events that do not change the cluster
+var readVerbs = []string{"get", "list", "watch"}
+
+// Pattern returns the JSON filter pattern of the filter, combined with the JSON filter
+// patterns given (AND), or "" when the filter selects nothing. It fails when a given
+// pattern is not a JSON filter pattern, which CloudWatch Logs cannot combine with it.
+func (f AuditFilter) Pattern(patterns []string) (string, error) {
+	if f.WritesOnly && f.ReadsOnly {
+		return "", errors.New("--writes-only and --reads-only cannot be combined")
+	}
+
+	var conditions []string
+	for _, pattern := range patterns {
+		if !IsJSONPattern(pattern) {
+			return "", fmt.Errorf("filter pattern '%s' is not a JSON filter pattern: the audit quick filters only combine with JSON patterns such as '{ $.objectRef.resource = \"secrets\" }'", pattern)
+		}
+		trimmed := strings.TrimSpace(pattern)
+		conditions = append(conditions, "("+strings.TrimSpace(trimmed[1:len(trimmed)-1])+")")
+	}
+
+	verbConditions := make([]string, len(readVerbs))
+	switch {
+	case f.WritesOnly:
+		for i, verb := range readVerbs {
+			verbConditions[i] = fmt.Sprintf("$.verb != %q", verb)
+		}
+		conditions = append(conditions, "("+strings.Join(verbConditions, " && ")+")")
+	case f.ReadsOnly:
+		for i, verb := range readVerbs {
+			verbConditions[i] = fmt.Sprintf("$.verb = %q", verb)
+		}
+		conditions = append(conditions, "("+strings.Join(verbConditions, " || ")+")")
+	}
+	if f.NonSystem {
+		conditions = append(conditions, `$.user.username != "system:*"`)
+	}
+	if f.Namespace != "" {
+		conditions = append(conditions, fmt.Sprintf("$.objectRef.namespace = %q", f.Namespace))
+	}
+
+	if len(conditions) == len(patterns) {
+		return "", nil
+	}
+	return "{ " + strings.Join(conditions, " && ") + " }", nil
+}
+
+// IsJSONPattern reports whether a filter pattern is a JSON filter pattern ({ ... })
+func IsJSONPattern(pattern string) bool {
+	trimmed := strings.TrimSpace(pattern)
+	return strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}")
+}
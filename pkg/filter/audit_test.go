@@ -0,0 +1,40 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditFilterPattern(t *testing.T) {
+	pattern, err := AuditFilter{WritesOnly: true}.Pattern(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{ ($.verb != "get" && $.verb != "list" && $.verb != "watch") }`, pattern)
+
+	pattern, err = AuditFilter{ReadsOnly: true, NonSystem: true}.Pattern(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{ ($.verb = "get" || $.verb = "list" || $.verb = "watch") && $.user.username != "system:*" }`, pattern)
+
+	pattern, err = AuditFilter{NonSystem: true}.Pattern([]string{`{ $.objectRef.resource = "secrets" }`})
+	assert.NoError(t, err)
+	assert.Equal(t, `{ ($.objectRef.resource = "secrets") && $.user.username != "system:*" }`, pattern)
+
+	pattern, err = AuditFilter{Namespace: "team-a"}.Pattern(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{ $.objectRef.namespace = "team-a" }`, pattern)
+
+	pattern, err = AuditFilter{}.Pattern(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, pattern)
+
+	_, err = AuditFilter{NonSystem: true}.Pattern([]string{"error"})
+	assert.Error(t, err)
+	_, err = AuditFilter{WritesOnly: true, ReadsOnly: true}.Pattern(nil)
+	assert.Error(t, err)
+}
+
+func TestIsJSONPattern(t *testing.T) {
+	assert.True(t, IsJSONPattern(` { $.verb = "delete" } `))
+	assert.False(t, IsJSONPattern("error"))
+	assert.False(t, IsJSONPattern(`?"{" ?"}"`))
+}
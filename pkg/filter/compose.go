@@ -0,0 +1,200 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// maxPatternLength is the longest filter pattern CloudWatch Logs accepts
+const maxPatternLength = 1024
+
+// RegisterPresets adds presets defined outside of ekslogs, such as in the config file, to
+// UnifiedPresets. A preset including other presets, built in or registered with it, reads
+// the union of their log types with a pattern matching the events of any of them. Built-in
+// presets cannot be redefined.
+func RegisterPresets(defs map[string]UnifiedPresetFilter) error {
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		if preset, exists := UnifiedPresets[name]; exists && !preset.UserDefined {
+			return fmt.Errorf("preset '%s' is built in and cannot be redefined", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := make(map[string]UnifiedPresetFilter, len(defs))
+	for _, name := range names {
+		preset, err := resolvePreset(name, defs, resolved, nil)
+		if err != nil {
+			return err
+		}
+		resolved[name] = preset
+	}
+	for name, preset := range resolved {
+		UnifiedPresets[name] = preset
+	}
+	return nil
+}
+
+// resolvePreset returns the preset name of defs, or the built-in one, with the log types and
+// patterns of its includes combined. stack holds the presets being resolved, to detect
+// presets including themselves.
+func resolvePreset(name string, defs, resolved map[string]UnifiedPresetFilter, stack []string) (UnifiedPresetFilter, error) {
+	if preset, done := resolved[name]; done {
+		return preset, nil
+	}
+	for _, including := range stack {
+		if including == name {
+			return UnifiedPresetFilter{}, fmt.Errorf("preset '%s' includes itself: %s", name, strings.Join(append(stack, name), " -> "))
+		}
+	}
+
+	def, exists := defs[name]
+	if !exists {
+		builtin, exists := UnifiedPresets[name]
+		if !exists || builtin.UserDefined {
+			return UnifiedPresetFilter{}, fmt.Errorf("preset '%s' included by '%s' not found", name, stack[len(stack)-1])
+		}
+		return builtin, nil
+	}
+	if def.Pattern == "" && len(def.Includes) == 0 {
+		return UnifiedPresetFilter{}, fmt.Errorf("preset '%s' needs a pattern or presets to include", name)
+	}
+
+	var members []UnifiedPresetFilter
+	for _, include := range def.Includes {
+		member, err := resolvePreset(include, defs, resolved, append(stack, name))
+		if err != nil {
+			return UnifiedPresetFilter{}, err
+		}
+		members = append(members, member)
+	}
+	if def.Pattern != "" {
+		members = append(members, UnifiedPresetFilter{Pattern: def.Pattern, PatternType: DetectPatternType(def.Pattern), LogTypes: def.LogTypes})
+	}
+
+	preset := def
+	preset.Advanced = true
+	preset.UserDefined = true
+	pattern, patternType, err := CombinePresetPatterns(members)
+	if err != nil {
+		return UnifiedPresetFilter{}, fmt.Errorf("preset '%s': %w", name, err)
+	}
+	preset.Pattern, preset.PatternType = pattern, patternType
+	if len(def.LogTypes) == 0 {
+		for _, member := range members {
+			preset.LogTypes = appendMissing(preset.LogTypes, member.LogTypes...)
+		}
+	}
+	if preset.Description == "" {
+		preset.Description = "Events of " + strings.Join(def.Includes, ", ")
+	}
+	return preset, nil
+}
+
+// DetectPatternType returns the type of a filter pattern: "json", "regex", "exclude",
+// "optional" or "simple"
+func DetectPatternType(pattern string) string {
+	pattern = strings.TrimSpace(pattern)
+	switch {
+	case strings.HasPrefix(pattern, "{"):
+		return "json"
+	case strings.HasPrefix(pattern, "%"):
+		return "regex"
+	case strings.HasPrefix(pattern, "-") || strings.Contains(pattern, " -"):
+		return "exclude"
+	case strings.HasPrefix(pattern, "?"):
+		return "optional"
+	default:
+		return "simple"
+	}
+}
+
+// CombinePresetPatterns returns a pattern matching the events matched by the pattern of any
+// of presets, and its type. JSON patterns are joined with ||, and text patterns become
+// optional terms; patterns requiring several terms, excluding terms or using a regular
+// expression cannot be combined, nor can JSON and text patterns.
+func CombinePresetPatterns(presets []UnifiedPresetFilter) (string, string, error) {
+	var patterns []string
+	for _, preset := range presets {
+		if !slices.Contains(patterns, preset.Pattern) {
+			patterns = append(patterns, preset.Pattern)
+		}
+	}
+	if len(patterns) == 1 {
+		return patterns[0], DetectPatternType(patterns[0]), nil
+	}
+
+	var jsonConditions, terms []string
+	for _, pattern := range patterns {
+		switch DetectPatternType(pattern) {
+		case "json":
+			condition := strings.TrimSpace(pattern)
+			condition = strings.TrimSpace(condition[1 : len(condition)-1])
+			if strings.Contains(condition, "}") {
+				return "", "", fmt.Errorf("pattern %s holds several JSON conditions and cannot be combined", pattern)
+			}
+			jsonConditions = append(jsonConditions, "("+condition+")")
+		case "optional":
+			terms = append(terms, splitPatternTerms(pattern)...)
+		case "simple":
+			patternTerms := splitPatternTerms(pattern)
+			if len(patternTerms) != 1 {
+				return "", "", fmt.Errorf("pattern %s requires all of its terms and cannot be combined", pattern)
+			}
+			terms = append(terms, "?"+patternTerms[0])
+		default:
+			return "", "", fmt.Errorf("%s pattern %s cannot be combined", DetectPatternType(pattern), pattern)
+		}
+	}
+	if len(jsonConditions) > 0 && len(terms) > 0 {
+		return "", "", fmt.Errorf("JSON and text patterns cannot be combined")
+	}
+
+	combined, patternType := strings.Join(appendMissing(nil, terms...), " "), "optional"
+	if len(jsonConditions) > 0 {
+		combined, patternType = "{ "+strings.Join(jsonConditions, " || ")+" }", "json"
+	}
+	if len(combined) > maxPatternLength {
+		return "", "", fmt.Errorf("combined pattern is %d characters long, more than the %d accepted by CloudWatch Logs", len(combined), maxPatternLength)
+	}
+	return combined, patternType, nil
+}
+
+// splitPatternTerms splits a text filter pattern into its terms, keeping quoted phrases
+// whole
+func splitPatternTerms(pattern string) []string {
+	var terms []string
+	var term strings.Builder
+	quoted := false
+	for _, r := range pattern {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			term.WriteRune(r)
+		case r == ' ' && !quoted:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms
+}
+
+// appendMissing appends the values not in list yet
+func appendMissing(list []string, values ...string) []string {
+	for _, value := range values {
+		if !slices.Contains(list, value) {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,100 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterPresets(t *testing.T) {
+	t.Cleanup(func() {
+		delete(UnifiedPresets, "prod-triage")
+		delete(UnifiedPresets, "prod-triage-etcd")
+		delete(UnifiedPresets, "audit-watch")
+	})
+
+	err := RegisterPresets(map[string]UnifiedPresetFilter{
+		"prod-triage":      {Includes: []string{"api-errors", "auth-failures", "scheduler-issues"}},
+		"prod-triage-etcd": {Description: "Triage with etcd", Includes: []string{"prod-triage", "etcd-issues"}},
+		"audit-watch":      {Includes: []string{"anonymous-access", "impersonation"}},
+	})
+	assert.NoError(t, err)
+
+	preset, exists := GetUnifiedPreset("prod-triage")
+	assert.True(t, exists)
+	assert.Equal(t, "?ERROR ?unauthorized ?error", preset.Pattern)
+	assert.Equal(t, "optional", preset.PatternType)
+	assert.Equal(t, []string{"api", "authenticator", "scheduler"}, preset.LogTypes)
+	assert.Equal(t, "Events of api-errors, auth-failures, scheduler-issues", preset.Description)
+	assert.True(t, preset.Advanced)
+	assert.True(t, preset.UserDefined)
+
+	preset, _ = GetUnifiedPreset("prod-triage-etcd")
+	assert.Equal(t, "Triage with etcd", preset.Description)
+	assert.Contains(t, preset.Pattern, "?unauthorized ?error ?\"took too long\"")
+
+	preset, _ = GetUnifiedPreset("audit-watch")
+	assert.Equal(t, `{ ($.user.username = "system:anonymous") || ($.impersonatedUser.username = "*") }`, preset.Pattern)
+	assert.Equal(t, "json", preset.PatternType)
+	assert.Equal(t, []string{"audit"}, preset.LogTypes)
+
+	// Registering the presets again, as when the config file is loaded twice, is allowed
+	assert.NoError(t, RegisterPresets(map[string]UnifiedPresetFilter{
+		"prod-triage": {Includes: []string{"api-errors"}},
+	}))
+}
+
+func TestRegisterPresetsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		defs map[string]UnifiedPresetFilter
+		err  string
+	}{
+		{"built-in", map[string]UnifiedPresetFilter{"api-errors": {Pattern: "oops"}}, "built in"},
+		{"unknown include", map[string]UnifiedPresetFilter{"mine": {Includes: []string{"nope"}}}, "'nope' included by 'mine' not found"},
+		{"cycle", map[string]UnifiedPresetFilter{
+			"a": {Includes: []string{"b"}},
+			"b": {Includes: []string{"a"}},
+		}, "includes itself: a -> b -> a"},
+		{"empty", map[string]UnifiedPresetFilter{"mine": {}}, "needs a pattern"},
+		{"json and text", map[string]UnifiedPresetFilter{"mine": {Includes: []string{"api-errors", "impersonation"}}}, "JSON and text"},
+		{"several terms", map[string]UnifiedPresetFilter{"mine": {Includes: []string{"api-errors", "pod-scheduling-failures"}}}, "requires all of its terms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterPresets(tt.defs)
+			assert.ErrorContains(t, err, tt.err)
+			for name := range tt.defs {
+				assert.False(t, UnifiedPresets[name].UserDefined, name)
+			}
+		})
+	}
+}
+
+func TestCombinePresetPatterns(t *testing.T) {
+	pattern, patternType, err := CombinePresetPatterns([]UnifiedPresetFilter{
+		{Pattern: `"no route to host"`},
+		{Pattern: `?"no such host" ?error`},
+		{Pattern: "error"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `?"no route to host" ?"no such host" ?error`, pattern)
+	assert.Equal(t, "optional", patternType)
+
+	// A single pattern is kept as it is, whatever its type
+	pattern, patternType, err = CombinePresetPatterns([]UnifiedPresetFilter{{Pattern: "ERROR -warning"}, {Pattern: "ERROR -warning"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "ERROR -warning", pattern)
+	assert.Equal(t, "exclude", patternType)
+
+	_, _, err = CombinePresetPatterns([]UnifiedPresetFilter{{Pattern: "%a.*b%"}, {Pattern: "error"}})
+	assert.ErrorContains(t, err, "regex pattern")
+}
+
+func TestDetectPatternType(t *testing.T) {
+	assert.Equal(t, "json", DetectPatternType(`{ $.verb = "delete" }`))
+	assert.Equal(t, "regex", DetectPatternType("%timeout.*%"))
+	assert.Equal(t, "exclude", DetectPatternType("ERROR -warning"))
+	assert.Equal(t, "optional", DetectPatternType("?a ?b"))
+	assert.Equal(t, "simple", DetectPatternType(`"failed calling webhook" "connection refused"`))
+}
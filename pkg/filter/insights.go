@@ -0,0 +1,83 @@
+package filter
+
+import "sort"
+
+// InsightsPreset is a CloudWatch Logs Insights query over the cluster log group. Unlike
+// filter patterns, Insights queries can aggregate, so these presets answer "which" and
+// "how slow" questions rather than listing events.
+type InsightsPreset struct {
+	Description string
+	LogTypes    []string
+	Query       string
+}
+
+// InsightsPresets is the library of Logs Insights queries run by 'ekslogs query'
+var InsightsPresets = map[string]InsightsPreset{
+	"audit-top-verbs": {
+		Description: "Most frequent verbs and resources in the audit logs",
+		LogTypes:    []string{"audit"},
+		Query: `filter @logStream like /^kube-apiserver-audit/
+| stats count(*) as requests by verb, objectRef.resource
+| sort requests desc
+| limit 25`,
+	},
+	"audit-top-users": {
+		Description: "Users and service accounts sending the most requests",
+		LogTypes:    []string{"audit"},
+		Query: `filter @logStream like /^kube-apiserver-audit/
+| stats count(*) as requests by user.username, userAgent
+| sort requests desc
+| limit 25`,
+	},
+	"audit-forbidden": {
+		Description: "Users and resources of the requests denied by authorization",
+		LogTypes:    []string{"audit"},
+		Query: `filter @logStream like /^kube-apiserver-audit/ and responseStatus.code = 403
+| stats count(*) as denied by user.username, verb, objectRef.resource
+| sort denied desc
+| limit 25`,
+	},
+	"slow-requests": {
+		Description: "Slowest API requests traced by the API server",
+		LogTypes:    []string{"api"},
+		Query: `filter @logStream like /^kube-apiserver-[0-9a-f]/ and @message like /total time: \d+ms/
+| parse @message /Trace\[\d+\]: "(?<operation>[^"]+)" (?<details>.*) \(total time: (?<ms>\d+)ms\)/
+| filter ispresent(ms)
+| sort ms desc
+| display @timestamp, ms, operation, details
+| limit 50`,
+	},
+	"webhook-latency": {
+		Description: "Latency of the admission webhooks called by the API server",
+		LogTypes:    []string{"api"},
+		Query: `filter @logStream like /^kube-apiserver-[0-9a-f]/ and @message like /Call (mutating|validating) webhook/
+| parse @message /"Call (?<type>mutating|validating) webhook" configuration:(?<configuration>[^,]+),webhook:(?<webhook>[^,]+),.* (?<ms>\d+)ms/
+| filter ispresent(webhook)
+| stats count(*) as calls, avg(ms) as avg_ms, max(ms) as max_ms by type, webhook
+| sort max_ms desc`,
+	},
+	"throttled-clients": {
+		Description: "Clients whose requests were throttled (429) by the API server",
+		LogTypes:    []string{"audit"},
+		Query: `filter @logStream like /^kube-apiserver-audit/ and responseStatus.code = 429
+| stats count(*) as throttled by user.username, userAgent
+| sort throttled desc
+| limit 25`,
+	},
+}
+
+// GetInsightsPreset returns a Logs Insights query preset by name
+func GetInsightsPreset(name string) (InsightsPreset, bool) {
+	preset, exists := InsightsPresets[name]
+	return preset, exists
+}
+
+// ListInsightsPresets returns the names of the Logs Insights query presets, sorted
+func ListInsightsPresets() []string {
+	names := make([]string, 0, len(InsightsPresets))
+	for name := range InsightsPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,71 @@
+package filter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsightsPresets(t *testing.T) {
+	names := ListInsightsPresets()
+	assert.Len(t, names, len(InsightsPresets))
+	assert.True(t, sort.StringsAreSorted(names))
+	for _, name := range []string{"audit-top-verbs", "slow-requests", "webhook-latency"} {
+		assert.Contains(t, names, name)
+	}
+	for name, preset := range InsightsPresets {
+		assert.NotEmpty(t, preset.Description, name)
+		assert.NotEmpty(t, preset.LogTypes, name)
+		assert.True(t, strings.HasPrefix(preset.Query, "filter @logStream like "), "%s should only read the streams of its log types", name)
+	}
+
+	_, ok := GetInsightsPreset("audit-top-verbs")
+	assert.True(t, ok)
+	_, ok = GetInsightsPreset("non-existing")
+	assert.False(t, ok)
+}
+
+// insightsParseRegexp returns the regular expression of the parse command of a query
+func insightsParseRegexp(t *testing.T, query string) *regexp.Regexp {
+	t.Helper()
+	for _, line := range strings.Split(query, "\n") {
+		if expr, ok := strings.CutPrefix(line, "| parse @message /"); ok {
+			return regexp.MustCompile(strings.TrimSuffix(expr, "/"))
+		}
+	}
+	t.Fatal("no parse command")
+	return nil
+}
+
+func TestInsightsPresetParse(t *testing.T) {
+	tests := []struct {
+		preset  string
+		message string
+		want    map[string]string
+	}{
+		{
+			preset:  "slow-requests",
+			message: `I0612 10:00:01.000000 11 trace.go:236] Trace[1742]: "List" accept:application/json,audit-id:0b3c,client:10.0.1.5,verb:LIST (12-Jun-2024 10:00:00.000) (total time: 1234ms):`,
+			want:    map[string]string{"operation": "List", "ms": "1234"},
+		},
+		{
+			preset:  "webhook-latency",
+			message: `Trace[1742]: ---"Call validating webhook" configuration:gatekeeper-validating-webhook-configuration,webhook:validation.gatekeeper.sh,resource:/v1, Resource=pods,subresource:,operation:CREATE,UID:4b1e 512ms (10:00:00.512)`,
+			want:    map[string]string{"type": "validating", "webhook": "validation.gatekeeper.sh", "ms": "512"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.preset, func(t *testing.T) {
+			re := insightsParseRegexp(t, InsightsPresets[tt.preset].Query)
+			match := re.FindStringSubmatch(tt.message)
+			if assert.NotNil(t, match) {
+				for field, want := range tt.want {
+					assert.Equal(t, want, match[re.SubexpIndex(field)], field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// jsonComparisonPattern matches a comparison of a JSON filter pattern, e.g. $.verb = "delete"
+// or $.responseStatus.code >= 400
+var jsonComparisonPattern = regexp.MustCompile(`\$\.[\w.\[\]*-]+\s*(=|!=|<=|>=|<|>)\s*("[^"]*"|[^\s)&|]+)|\$\.[\w.\[\]*-]+\s+(IS|NOT EXISTS)\b`)
+
+// ValidatePattern checks a filter pattern against the CloudWatch Logs filter pattern syntax:
+// terms with balanced quotes, a single JSON expression in braces, a space-delimited
+// selector in brackets or a regular expression in percent signs, no longer than CloudWatch
+// Logs accepts. It catches the mistakes CloudWatch Logs rejects or silently mismatches;
+// a pattern it accepts may still match nothing.
+func ValidatePattern(pattern string) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return errors.New("empty pattern")
+	}
+	if len(pattern) > maxPatternLength {
+		return fmt.Errorf("pattern is %d characters long, more than the %d accepted by CloudWatch Logs", len(pattern), maxPatternLength)
+	}
+
+	switch {
+	case strings.HasPrefix(pattern, "["):
+		return validateEnclosed(pattern, ']', "space-delimited")
+	case DetectPatternType(pattern) == "json":
+		return validateJSONPattern(pattern)
+	case DetectPatternType(pattern) == "regex":
+		if len(pattern) < 3 || !strings.HasSuffix(pattern, "%") {
+			return errors.New("regular expression must be enclosed in %")
+		}
+		if _, err := regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return nil
+	default:
+		return validateTextPattern(pattern)
+	}
+}
+
+// validateJSONPattern checks that a JSON pattern is one expression in braces made of
+// comparisons of $. selectors
+func validateJSONPattern(pattern string) error {
+	if err := validateEnclosed(pattern, '}', "JSON"); err != nil {
+		return err
+	}
+	if strings.Count(stripQuoted(pattern), "{") > 1 {
+		return errors.New("JSON pattern must be a single { } expression; combine conditions with && or ||")
+	}
+	if !jsonComparisonPattern.MatchString(pattern) {
+		return errors.New(`JSON pattern has no comparison of a $. selector, e.g. { $.verb = "delete" }`)
+	}
+	return nil
+}
+
+// validateEnclosed checks that a pattern starting with a bracket ends with the closing one
+// and has balanced quotes and brackets
+func validateEnclosed(pattern string, closing byte, kind string) error {
+	if strings.Count(pattern, `"`)%2 != 0 {
+		return errors.New("unbalanced quotes")
+	}
+	if pattern[len(pattern)-1] != closing {
+		return fmt.Errorf("%s pattern must end with %c", kind, closing)
+	}
+
+	depth := map[byte]int{}
+	for i, r := range []byte(stripQuoted(pattern)) {
+		switch r {
+		case '(', '{', '[':
+			depth[r]++
+		case ')':
+			depth['(']--
+		case '}':
+			depth['{']--
+		case ']':
+			depth['[']--
+		}
+		if depth['('] < 0 || depth['{'] < 0 || depth['['] < 0 {
+			return fmt.Errorf("unexpected closing bracket at character %d", i+1)
+		}
+	}
+	for bracket, n := range depth {
+		if n != 0 {
+			return fmt.Errorf("unbalanced %c", bracket)
+		}
+	}
+	return nil
+}
+
+// validateTextPattern checks the terms of a text pattern: balanced quotes and no empty term
+func validateTextPattern(pattern string) error {
+	if strings.Count(pattern, `"`)%2 != 0 {
+		return errors.New("unbalanced quotes")
+	}
+	for _, term := range splitPatternTerms(pattern) {
+		bare := strings.TrimLeft(term, "?-")
+		if bare == "" || bare == `""` {
+			return fmt.Errorf("empty term %q", term)
+		}
+		if strings.HasPrefix(bare, `"`) != strings.HasSuffix(bare, `"`) {
+			return fmt.Errorf("term %s must be quoted as a whole", term)
+		}
+	}
+	return nil
+}
+
+// stripQuoted removes the quoted strings of a pattern, so brackets inside them are ignored
+func stripQuoted(pattern string) string {
+	var b strings.Builder
+	quoted := false
+	for _, r := range pattern {
+		if r == '"' {
+			quoted = !quoted
+			continue
+		}
+		if !quoted {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePattern(t *testing.T) {
+	valid := []string{
+		"ERROR",
+		`"failed calling webhook" "connection refused"`,
+		`?unauthorized ?"permission denied"`,
+		`ERROR CRITICAL -warning -"deadline exceeded"`,
+		`{ $.verb = "delete" }`,
+		`{ ($.objectRef.subresource = "token" || $.objectRef.resource = "tokenreviews") && $.responseStatus.code >= 400 }`,
+		`{ $.user.groups[0] = "system:masters" }`,
+		`{ $.impersonatedUser NOT EXISTS }`,
+		`{ $.message = "{braces} in a string" }`,
+		"%timeout.*network|network.*timeout%",
+		"[ip, user, timestamp, request, status_code = 4*, bytes]",
+	}
+	for _, pattern := range valid {
+		assert.NoError(t, ValidatePattern(pattern), pattern)
+	}
+
+	tests := []struct {
+		pattern string
+		err     string
+	}{
+		{"", "empty pattern"},
+		{`"unterminated`, "unbalanced quotes"},
+		{`? error`, "empty term"},
+		{`-`, "empty term"},
+		{`{ $.verb = "delete"`, "must end with }"},
+		{`{ $.user.username = "admin" } { $.verb = "delete" }`, "single { } expression"},
+		{`{ ($.verb = "delete" }`, "unbalanced ("},
+		{`{ verb = "delete" }`, "no comparison"},
+		{"%unterminated", "enclosed in %"},
+		{"%(%", "invalid regular expression"},
+		{"[ip, user", "must end with ]"},
+		{"?" + strings.Repeat("a", maxPatternLength), "more than the 1024"},
+	}
+	for _, tt := range tests {
+		assert.ErrorContains(t, ValidatePattern(tt.pattern), tt.err, tt.pattern)
+	}
+}
+
+func TestPresetPatternsAreValid(t *testing.T) {
+	for name, preset := range UnifiedPresets {
+		assert.NoError(t, ValidatePattern(preset.Pattern), name)
+	}
+}
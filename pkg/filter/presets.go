@@ -1,12 +1,50 @@
 package filter
 
+import (
+	"fmt"
+	"strings"
+)
+
 // UnifiedPresetFilter defines a filter template with pattern type information
 type UnifiedPresetFilter struct {
 	Description string
 	LogTypes    []string
 	Pattern     string
-	PatternType string // "simple", "optional", "exclude", "json", "regex"
-	Advanced    bool   // Whether this is an advanced pattern
+	PatternType string   // "simple", "optional", "exclude", "json", "regex"
+	Advanced    bool     // Whether this is an advanced pattern
+	Category    string   // Group of related presets, e.g. "security" (empty for general presets)
+	Includes    []string // Presets whose log types and patterns this preset combines
+	UserDefined bool     // Whether the preset was registered from the config file
+	// Defaults applied when the corresponding flags are not given
+	Since  string // Start time, relative (e.g. "-24h") or RFC3339, when no time range is given
+	Limit  int32  // Maximum number of events (0 means unlimited)
+	Output string // Output format, e.g. "json"
+}
+
+// Categories of presets
+const (
+	// CategorySecurity groups the presets detecting suspicious use of the cluster in audit logs
+	CategorySecurity = "security"
+	// CategoryConnectivity groups the presets of control plane components failing to reach
+	// webhooks, nodes and DNS, which show up as dataplane symptoms
+	CategoryConnectivity = "connectivity"
+)
+
+// PresetCategories lists the categories of presets, as accepted by ListCategoryPresets
+var PresetCategories = []string{CategorySecurity, CategoryConnectivity}
+
+// maxGroupIndex bounds the group indexes matched by groupMemberPattern; JSON filter
+// patterns cannot match an element at any position of an array
+const maxGroupIndex = 16
+
+// groupMemberPattern builds a JSON filter pattern matching audit events whose user is a
+// member of group, at any of the first maxGroupIndex positions of $.user.groups
+func groupMemberPattern(group string) string {
+	conditions := make([]string, maxGroupIndex)
+	for i := range conditions {
+		conditions[i] = fmt.Sprintf("$.user.groups[%d] = %q", i, group)
+	}
+	return "{ " + strings.Join(conditions, " || ") + " }"
 }
 
 // UnifiedPresets combines both basic and advanced presets
@@ -25,6 +63,7 @@ var UnifiedPresets = map[string]UnifiedPresetFilter{
 		Pattern:     "ERROR",
 		PatternType: "simple",
 		Advanced:    false,
+		Since:       "-1h",
 	},
 	"audit-privileged": {
 		Description: "Privileged operations in audit logs",
@@ -32,6 +71,7 @@ var UnifiedPresets = map[string]UnifiedPresetFilter{
 		Pattern:     "create",
 		PatternType: "simple",
 		Advanced:    false,
+		Limit:       1000,
 	},
 	"scheduler-issues": {
 		Description: "Scheduler issues",
@@ -83,11 +123,12 @@ var UnifiedPresets = map[string]UnifiedPresetFilter{
 		Pattern:     "ERROR CRITICAL -warning -\"deadline exceeded\"",
 		PatternType: "exclude",
 		Advanced:    true,
+		Since:       "-1h",
 	},
 	"privileged-admin-actions": {
 		Description: "Privileged admin actions in audit logs",
 		LogTypes:    []string{"audit"},
-		Pattern:     "{ $.user.username = \"admin\" } { $.verb = \"delete\" }",
+		Pattern:     "{ $.user.username = \"admin\" && $.verb = \"delete\" }",
 		PatternType: "json",
 		Advanced:    true,
 	},
@@ -126,6 +167,109 @@ var UnifiedPresets = map[string]UnifiedPresetFilter{
 		PatternType: "regex",
 		Advanced:    true,
 	},
+	"etcd-issues": {
+		Description: "etcd latency, timeouts and object size warnings of the API server",
+		LogTypes:    []string{"api"},
+		Pattern:     "?\"took too long\" ?etcdserver ?\"request is too large\" ?\"database space exceeded\" ?\"entity too large\" ?\"too many objects\"",
+		PatternType: "optional",
+		Advanced:    true,
+	},
+	"quota-denials": {
+		Description: "Requests denied by a ResourceQuota or LimitRange",
+		LogTypes:    []string{"api", "audit"},
+		Pattern:     "?\"exceeded quota\" ?\"failed quota\" ?\"usage per Container\" ?\"usage per Pod\" ?\"usage per PersistentVolumeClaim\" ?\"limit to request ratio\"",
+		PatternType: "optional",
+		Advanced:    true,
+	},
+	"aggregated-api-issues": {
+		Description: "Unavailable APIServices, OpenAPI aggregation failures and CRD schema errors",
+		LogTypes:    []string{"api"},
+		Pattern:     "?OpenAPI ?openapi ?APIService ?\"failing or missing response\" ?\"structural schema\" ?discovery",
+		PatternType: "optional",
+		Advanced:    true,
+	},
+
+	// Connectivity presets (per component: the log types are those of the components
+	// reporting the symptom)
+	"webhook-connection-refused": {
+		Description: "Admission webhook calls of the API server refused by the webhook service",
+		LogTypes:    []string{"api"},
+		Pattern:     "\"failed calling webhook\" \"connection refused\"",
+		PatternType: "simple",
+		Advanced:    true,
+		Category:    CategoryConnectivity,
+	},
+	"no-route-to-host": {
+		Description: "Connections of control plane components failing with no route to host",
+		LogTypes:    []string{"api", "kcm", "ccm", "scheduler"},
+		Pattern:     "\"no route to host\"",
+		PatternType: "simple",
+		Advanced:    true,
+		Category:    CategoryConnectivity,
+	},
+	"kubelet-timeouts": {
+		Description: "API server connections to the kubelet port (logs, exec, metrics) timing out",
+		LogTypes:    []string{"api"},
+		Pattern:     "\"i/o timeout\" \":10250\"",
+		PatternType: "simple",
+		Advanced:    true,
+		Category:    CategoryConnectivity,
+	},
+	"dns-failures": {
+		Description: "Host names that control plane components failed to resolve",
+		LogTypes:    []string{"api", "kcm", "ccm", "authenticator"},
+		Pattern:     "?\"no such host\" ?\"server misbehaving\" ?\"Temporary failure in name resolution\"",
+		PatternType: "optional",
+		Advanced:    true,
+		Category:    CategoryConnectivity,
+	},
+
+	// Security presets (JSON patterns on audit events)
+	"anonymous-access": {
+		Description: "Requests made by anonymous users",
+		LogTypes:    []string{"audit"},
+		Pattern:     "{ $.user.username = \"system:anonymous\" }",
+		PatternType: "json",
+		Advanced:    true,
+		Category:    CategorySecurity,
+		Since:       "-24h",
+	},
+	"system-masters-usage": {
+		Description: "Requests made by members of the system:masters group, which bypasses RBAC",
+		LogTypes:    []string{"audit"},
+		Pattern:     groupMemberPattern("system:masters"),
+		PatternType: "json",
+		Advanced:    true,
+		Category:    CategorySecurity,
+		Since:       "-24h",
+	},
+	"token-exchange-failures": {
+		Description: "Failed service account token requests and token reviews",
+		LogTypes:    []string{"audit"},
+		Pattern:     "{ ($.objectRef.subresource = \"token\" || $.objectRef.resource = \"tokenreviews\") && $.responseStatus.code >= 400 }",
+		PatternType: "json",
+		Advanced:    true,
+		Category:    CategorySecurity,
+		Since:       "-24h",
+	},
+	"impersonation": {
+		Description: "Requests impersonating another user (kubectl --as)",
+		LogTypes:    []string{"audit"},
+		Pattern:     "{ $.impersonatedUser.username = \"*\" }",
+		PatternType: "json",
+		Advanced:    true,
+		Category:    CategorySecurity,
+		Since:       "-24h",
+	},
+	"certificate-signing-requests": {
+		Description: "Certificate signing requests created, approved or updated",
+		LogTypes:    []string{"audit"},
+		Pattern:     "{ $.objectRef.resource = \"certificatesigningrequests\" && $.verb != \"get\" && $.verb != \"list\" && $.verb != \"watch\" }",
+		PatternType: "json",
+		Advanced:    true,
+		Category:    CategorySecurity,
+		Since:       "-24h",
+	},
 }
 
 // GetUnifiedPreset returns a preset filter by name
@@ -164,3 +308,14 @@ func ListAdvancedPresets() []string {
 	}
 	return names
 }
+
+// ListCategoryPresets returns the names of the presets of a category
+func ListCategoryPresets(category string) []string {
+	var names []string
+	for name, preset := range UnifiedPresets {
+		if preset.Category == category {
+			names = append(names, name)
+		}
+	}
+	return names
+}
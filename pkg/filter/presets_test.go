@@ -2,6 +2,7 @@ package filter
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -81,3 +82,50 @@ func TestListAdvancedPresets(t *testing.T) {
 		assert.True(t, preset.Advanced)
 	}
 }
+
+func TestListCategoryPresets(t *testing.T) {
+	presets := ListCategoryPresets(CategorySecurity)
+	sort.Strings(presets)
+	assert.Equal(t, []string{
+		"anonymous-access",
+		"certificate-signing-requests",
+		"impersonation",
+		"system-masters-usage",
+		"token-exchange-failures",
+	}, presets)
+
+	// Security presets are JSON patterns on audit events
+	for _, name := range presets {
+		preset, _ := GetUnifiedPreset(name)
+		assert.Equal(t, "json", preset.PatternType, name)
+		assert.Equal(t, []string{"audit"}, preset.LogTypes, name)
+		assert.True(t, strings.HasPrefix(preset.Pattern, "{") && strings.HasSuffix(preset.Pattern, "}"), name)
+	}
+
+	connectivity := ListCategoryPresets(CategoryConnectivity)
+	sort.Strings(connectivity)
+	assert.Equal(t, []string{
+		"dns-failures",
+		"kubelet-timeouts",
+		"no-route-to-host",
+		"webhook-connection-refused",
+	}, connectivity)
+
+	assert.Empty(t, ListCategoryPresets("unknown"))
+	for _, category := range PresetCategories {
+		assert.NotEmpty(t, ListCategoryPresets(category), category)
+	}
+}
+
+func TestGroupMemberPattern(t *testing.T) {
+	pattern := groupMemberPattern("system:masters")
+	assert.True(t, strings.HasPrefix(pattern, `{ $.user.groups[0] = "system:masters" || `))
+	assert.Contains(t, pattern, `$.user.groups[3] = "system:masters"`)
+	assert.True(t, strings.HasSuffix(pattern, `$.user.groups[15] = "system:masters" }`))
+	assert.NotContains(t, pattern, "[16]")
+	// CloudWatch Logs limits filter patterns to 1024 characters
+	assert.LessOrEqual(t, len(pattern), 1024)
+
+	preset, _ := GetUnifiedPreset("system-masters-usage")
+	assert.Equal(t, pattern, preset.Pattern)
+}
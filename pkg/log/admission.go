@@ -0,0 +1,202 @@
+package log
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Admission controllers of the denials reported by ParseAdmissionDenials
+const (
+	AdmissionPodSecurity = "PodSecurity"
+	AdmissionVAP         = "ValidatingAdmissionPolicy"
+)
+
+// Actions of an admission policy on a violating request
+const (
+	// AdmissionDeny is a request rejected by the policy (PodSecurity enforce, VAP Deny)
+	AdmissionDeny = "deny"
+	// AdmissionAudit is a request let through but recorded as violating the policy
+	// (PodSecurity audit, VAP Audit)
+	AdmissionAudit = "audit"
+)
+
+// Audit annotations of the admission policies
+const (
+	podSecurityAuditAnnotation = "pod-security.kubernetes.io/audit-violations"
+	vapFailureAnnotation       = "validation.policy.admission.k8s.io/validation_failure"
+)
+
+var (
+	// podSecurityPattern matches a PodSecurity violation, e.g.
+	// `violates PodSecurity "restricted:latest": privileged (container "app" must not set securityContext.privileged=true)`
+	podSecurityPattern = regexp.MustCompile(`violates? PodSecurity "([^"]+)": (.*)`)
+	// vapDenialPattern matches the rejection of a ValidatingAdmissionPolicy, e.g.
+	// `ValidatingAdmissionPolicy 'replicas.example.com' with binding 'replicas-binding' denied request: failed expression: object.spec.replicas <= 5`
+	vapDenialPattern = regexp.MustCompile(`ValidatingAdmissionPolicy '([^']+)' with binding '([^']+)' denied request: (.*)`)
+)
+
+// AdmissionDenial is a request violating a PodSecurity level or a ValidatingAdmissionPolicy
+type AdmissionDenial struct {
+	Time       time.Time
+	Namespace  string
+	Controller string
+	// Policy is the PodSecurity level ("restricted:latest") or the name of the policy
+	Policy   string
+	Binding  string
+	Action   string
+	Reason   string
+	Resource string
+	Name     string
+	User     string
+}
+
+// AdmissionGroup counts the violations of one policy and action in a namespace
+type AdmissionGroup struct {
+	Namespace  string    `json:"namespace"`
+	Controller string    `json:"controller"`
+	Policy     string    `json:"policy"`
+	Binding    string    `json:"binding,omitempty"`
+	Action     string    `json:"action"`
+	Count      int       `json:"count"`
+	Resources  []string  `json:"resources"`
+	Reason     string    `json:"reason"`
+	First      time.Time `json:"first"`
+	Last       time.Time `json:"last"`
+}
+
+// auditAdmissionEvent holds the fields of an audit event used to find policy violations
+type auditAdmissionEvent struct {
+	Stage                    string    `json:"stage"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	User                     struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectRef struct {
+		Resource  string `json:"resource"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"objectRef"`
+	ResponseStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"responseStatus"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// vapFailure is an element of the validation_failure audit annotation
+type vapFailure struct {
+	Message           string   `json:"message"`
+	Policy            string   `json:"policy"`
+	Binding           string   `json:"binding"`
+	ValidationActions []string `json:"validationActions"`
+}
+
+// ParseAdmissionDenials returns the PodSecurity and ValidatingAdmissionPolicy violations an
+// audit event records: a rejection in its response status, and the violations of policies
+// in audit mode in its annotations
+func ParseAdmissionDenials(entry LogEntry) []AdmissionDenial {
+	var event auditAdmissionEvent
+	if err := json.Unmarshal([]byte(entry.Message), &event); err != nil {
+		return nil
+	}
+	if event.Stage != "" && event.Stage != "ResponseComplete" {
+		return nil
+	}
+
+	timestamp := event.RequestReceivedTimestamp
+	if timestamp.IsZero() {
+		timestamp = entry.Timestamp
+	}
+	base := AdmissionDenial{
+		Time:      timestamp.UTC(),
+		Namespace: event.ObjectRef.Namespace,
+		Resource:  event.ObjectRef.Resource,
+		Name:      event.ObjectRef.Name,
+		User:      event.User.Username,
+	}
+
+	var denials []AdmissionDenial
+	if event.ResponseStatus.Code >= 400 {
+		message := event.ResponseStatus.Message
+		if match := podSecurityPattern.FindStringSubmatch(message); match != nil {
+			denial := base
+			denial.Controller, denial.Policy, denial.Action, denial.Reason = AdmissionPodSecurity, match[1], AdmissionDeny, match[2]
+			denials = append(denials, denial)
+		} else if match := vapDenialPattern.FindStringSubmatch(message); match != nil {
+			denial := base
+			denial.Controller, denial.Policy, denial.Binding, denial.Action, denial.Reason = AdmissionVAP, match[1], match[2], AdmissionDeny, match[3]
+			denials = append(denials, denial)
+		}
+	}
+	if match := podSecurityPattern.FindStringSubmatch(event.Annotations[podSecurityAuditAnnotation]); match != nil {
+		denial := base
+		denial.Controller, denial.Policy, denial.Action, denial.Reason = AdmissionPodSecurity, match[1], AdmissionAudit, match[2]
+		denials = append(denials, denial)
+	}
+	var failures []vapFailure
+	if err := json.Unmarshal([]byte(event.Annotations[vapFailureAnnotation]), &failures); err == nil {
+		for _, failure := range failures {
+			denial := base
+			denial.Controller, denial.Policy, denial.Binding, denial.Action, denial.Reason = AdmissionVAP, failure.Policy, failure.Binding, AdmissionAudit, failure.Message
+			denials = append(denials, denial)
+		}
+	}
+	return denials
+}
+
+// GroupAdmissionDenials counts the violations per namespace, policy and action, keeping
+// the reason of the latest one. Namespaces with the most violations come first, and their
+// policies with the most violations first.
+func GroupAdmissionDenials(denials []AdmissionDenial) []AdmissionGroup {
+	type groupKey struct{ namespace, controller, policy, binding, action string }
+	groups := make(map[groupKey]*AdmissionGroup)
+	resources := make(map[groupKey]map[string]bool)
+	perNamespace := make(map[string]int)
+	for _, denial := range denials {
+		key := groupKey{denial.Namespace, denial.Controller, denial.Policy, denial.Binding, denial.Action}
+		group, exists := groups[key]
+		if !exists {
+			group = &AdmissionGroup{Namespace: denial.Namespace, Controller: denial.Controller, Policy: denial.Policy,
+				Binding: denial.Binding, Action: denial.Action, First: denial.Time, Last: denial.Time, Reason: denial.Reason}
+			groups[key] = group
+			resources[key] = make(map[string]bool)
+		}
+		group.Count++
+		perNamespace[denial.Namespace]++
+		if denial.Resource != "" && !resources[key][denial.Resource] {
+			resources[key][denial.Resource] = true
+			group.Resources = append(group.Resources, denial.Resource)
+		}
+		if denial.Time.Before(group.First) {
+			group.First = denial.Time
+		}
+		if !denial.Time.Before(group.Last) {
+			group.Last = denial.Time
+			group.Reason = denial.Reason
+		}
+	}
+
+	result := make([]AdmissionGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group.Resources)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Namespace != b.Namespace {
+			if perNamespace[a.Namespace] != perNamespace[b.Namespace] {
+				return perNamespace[a.Namespace] > perNamespace[b.Namespace]
+			}
+			return a.Namespace < b.Namespace
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return strings.Join([]string{a.Controller, a.Policy, a.Binding, a.Action}, "\x00") <
+			strings.Join([]string{b.Controller, b.Policy, b.Binding, b.Action}, "\x00")
+	})
+	return result
+}
@@ -0,0 +1,88 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAdmissionDenials(t *testing.T) {
+	enforced := LogEntry{Message: `{"stage":"ResponseComplete","requestReceivedTimestamp":"2024-01-01T10:00:00Z",` +
+		`"user":{"username":"system:serviceaccount:kube-system:replicaset-controller"},` +
+		`"objectRef":{"resource":"pods","namespace":"team-a","name":"web-7d9f"},` +
+		`"responseStatus":{"code":403,"message":"pods \"web-7d9f\" is forbidden: violates PodSecurity \"restricted:latest\": privileged (container \"app\" must not set securityContext.privileged=true)"}}`}
+	denials := ParseAdmissionDenials(enforced)
+	assert.Equal(t, []AdmissionDenial{{
+		Time:       time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Namespace:  "team-a",
+		Controller: AdmissionPodSecurity,
+		Policy:     "restricted:latest",
+		Action:     AdmissionDeny,
+		Reason:     `privileged (container "app" must not set securityContext.privileged=true)`,
+		Resource:   "pods",
+		Name:       "web-7d9f",
+		User:       "system:serviceaccount:kube-system:replicaset-controller",
+	}}, denials)
+
+	audited := LogEntry{Message: `{"objectRef":{"resource":"deployments","namespace":"team-b","name":"api"},"responseStatus":{"code":200},` +
+		`"annotations":{"pod-security.kubernetes.io/audit-violations":"would violate PodSecurity \"baseline:latest\": hostPath volumes (volume \"data\")",` +
+		`"validation.policy.admission.k8s.io/validation_failure":"[{\"message\":\"replicas must be at most 5\",\"policy\":\"max-replicas\",\"binding\":\"max-replicas-binding\",\"expressionIndex\":0,\"validationActions\":[\"Audit\"]}]"}}`}
+	denials = ParseAdmissionDenials(audited)
+	assert.Len(t, denials, 2)
+	assert.Equal(t, AdmissionPodSecurity, denials[0].Controller)
+	assert.Equal(t, "baseline:latest", denials[0].Policy)
+	assert.Equal(t, AdmissionAudit, denials[0].Action)
+	assert.Equal(t, `hostPath volumes (volume "data")`, denials[0].Reason)
+	assert.Equal(t, AdmissionVAP, denials[1].Controller)
+	assert.Equal(t, "max-replicas", denials[1].Policy)
+	assert.Equal(t, "max-replicas-binding", denials[1].Binding)
+	assert.Equal(t, "replicas must be at most 5", denials[1].Reason)
+
+	vapDenied := LogEntry{Message: `{"objectRef":{"resource":"deployments","namespace":"team-b","name":"api"},` +
+		`"responseStatus":{"code":422,"message":"deployments.apps \"api\" is forbidden: ValidatingAdmissionPolicy 'max-replicas' with binding 'max-replicas-binding' denied request: failed expression: object.spec.replicas <= 5"}}`}
+	denials = ParseAdmissionDenials(vapDenied)
+	assert.Len(t, denials, 1)
+	assert.Equal(t, AdmissionDeny, denials[0].Action)
+	assert.Equal(t, "failed expression: object.spec.replicas <= 5", denials[0].Reason)
+
+	for _, message := range []string{
+		`{"objectRef":{"resource":"pods"},"responseStatus":{"code":201},"annotations":{"pod-security.kubernetes.io/enforce-policy":"restricted:latest"}}`,
+		`{"stage":"ResponseStarted","responseStatus":{"code":403,"message":"violates PodSecurity \"restricted:latest\": privileged"}}`,
+		`{"responseStatus":{"code":403,"message":"pods is forbidden: User \"alice\" cannot create resource \"pods\""}}`,
+		`not an audit event`,
+	} {
+		assert.Empty(t, ParseAdmissionDenials(LogEntry{Message: message}), message)
+	}
+}
+
+func TestGroupAdmissionDenials(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2024, 1, 1, 10, minute, 0, 0, time.UTC) }
+	restricted := func(minute int, resource, reason string) AdmissionDenial {
+		return AdmissionDenial{Time: at(minute), Namespace: "team-a", Controller: AdmissionPodSecurity, Policy: "restricted:latest",
+			Action: AdmissionDeny, Resource: resource, Reason: reason}
+	}
+	denials := []AdmissionDenial{
+		restricted(2, "pods", "latest"),
+		restricted(1, "pods", "first"),
+		restricted(3, "deployments", "deployment"),
+		{Time: at(4), Namespace: "team-a", Controller: AdmissionVAP, Policy: "max-replicas", Action: AdmissionAudit, Resource: "deployments"},
+		{Time: at(5), Namespace: "team-b", Controller: AdmissionPodSecurity, Policy: "baseline:latest", Action: AdmissionAudit, Resource: "pods"},
+	}
+
+	groups := GroupAdmissionDenials(denials)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, AdmissionGroup{
+		Namespace:  "team-a",
+		Controller: AdmissionPodSecurity,
+		Policy:     "restricted:latest",
+		Action:     AdmissionDeny,
+		Count:      3,
+		Resources:  []string{"deployments", "pods"},
+		Reason:     "deployment",
+		First:      at(1),
+		Last:       at(3),
+	}, groups[0])
+	assert.Equal(t, AdmissionVAP, groups[1].Controller)
+	assert.Equal(t, "team-b", groups[2].Namespace)
+}
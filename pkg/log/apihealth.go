@@ -0,0 +1,115 @@
+package log
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Problems of aggregated APIs and CustomResourceDefinitions reported by the API server
+const (
+	APIServiceUnavailable = "APIService unavailable"
+	APIOpenAPIAggregation = "OpenAPI aggregation"
+	APIDiscovery          = "discovery"
+	APICRDSchema          = "CRD schema"
+)
+
+var (
+	// apiServiceNamePattern matches the name of an APIService, e.g. v1beta1.metrics.k8s.io
+	apiServiceNamePattern = regexp.MustCompile(`\bv\d+(?:(?:alpha|beta)\d+)?\.[a-z0-9-]+(?:\.[a-z0-9-]+)+\b`)
+	// crdNamePattern matches the name of a CustomResourceDefinition in a structured line
+	// (crd="widgets.example.com") or a message (CRD widgets.example.com, for widgets.example.com:)
+	crdNamePattern = regexp.MustCompile(`(?i)(?:crd="?|customresourcedefinition[ /]"?|crd |for )([a-z0-9-]+(?:\.[a-z0-9-]+)+)`)
+)
+
+// APIHealthIssue counts the errors of one problem and object, an APIService or a CRD
+type APIHealthIssue struct {
+	Problem   string    `json:"problem"`
+	Object    string    `json:"object"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	// Message is the latest message of the problem, without its klog header
+	Message string `json:"message"`
+}
+
+// ClassifyAPIHealth returns the aggregated API or CRD problem an API server message reports
+// and the APIService or CRD it affects: an APIService whose backend fails its availability
+// checks, OpenAPI specs that cannot be aggregated or built, or failing discovery
+func ClassifyAPIHealth(entry LogEntry) (string, string, bool) {
+	lower := strings.ToLower(entry.Message)
+	crd := strings.Contains(lower, "customresourcedefinition") || strings.Contains(lower, "crd")
+
+	var problem string
+	switch {
+	case strings.Contains(lower, "structural schema") || strings.Contains(lower, "nonstructuralschema"),
+		crd && strings.Contains(lower, "openapi") && (strings.Contains(lower, "fail") || strings.Contains(lower, "error")):
+		problem = APICRDSchema
+	case strings.Contains(lower, "failing or missing response"), strings.Contains(lower, "failingormissingresponse"),
+		strings.Contains(lower, "servicenotfound"), strings.Contains(lower, "missingendpoints"),
+		strings.Contains(lower, "apiservice") && strings.Contains(lower, "unavailable"):
+		problem = APIServiceUnavailable
+	case strings.Contains(lower, "openapi") && (strings.Contains(lower, "fail") || strings.Contains(lower, "error") || strings.Contains(lower, "rate limited requeue")):
+		problem = APIOpenAPIAggregation
+	case strings.Contains(lower, "discovery") && (strings.Contains(lower, "fail") || strings.Contains(lower, "error")):
+		problem = APIDiscovery
+	default:
+		return "", "", false
+	}
+
+	if problem == APICRDSchema {
+		if m := crdNamePattern.FindStringSubmatch(entry.Message); m != nil {
+			return problem, m[1], true
+		}
+	} else if name := apiServiceNamePattern.FindString(entry.Message); name != "" {
+		return problem, name, true
+	}
+	if problem == APIDiscovery {
+		// Discovery errors without an APIService are not about aggregation
+		return "", "", false
+	}
+	return problem, "unknown", true
+}
+
+// ScanAPIHealth groups the aggregated API and CRD errors of entries per problem and object,
+// the most recently seen first
+func ScanAPIHealth(entries []LogEntry) []APIHealthIssue {
+	type issueKey struct{ problem, object string }
+	issues := make(map[issueKey]*APIHealthIssue)
+	for _, entry := range entries {
+		problem, object, ok := ClassifyAPIHealth(entry)
+		if !ok {
+			continue
+		}
+		key := issueKey{problem, object}
+		issue, exists := issues[key]
+		if !exists {
+			issue = &APIHealthIssue{Problem: problem, Object: object, FirstSeen: entry.Timestamp, LastSeen: entry.Timestamp}
+			issues[key] = issue
+		}
+		issue.Count++
+		if entry.Timestamp.Before(issue.FirstSeen) {
+			issue.FirstSeen = entry.Timestamp
+		}
+		if !entry.Timestamp.Before(issue.LastSeen) {
+			issue.LastSeen = entry.Timestamp
+			issue.Message = klogMessagePattern.ReplaceAllString(strings.TrimSpace(entry.Message), "")
+		}
+	}
+
+	result := make([]APIHealthIssue, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, *issue)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].LastSeen.Equal(result[j].LastSeen) {
+			return result[i].LastSeen.After(result[j].LastSeen)
+		}
+		if result[i].Problem != result[j].Problem {
+			return result[i].Problem < result[j].Problem
+		}
+		return result[i].Object < result[j].Object
+	})
+	return result
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyAPIHealth(t *testing.T) {
+	tests := []struct {
+		message string
+		problem string
+		object  string
+	}{
+		{`E0101 10:00:00.000000 10 available_controller.go:524] v1beta1.metrics.k8s.io failed with: failing or missing response from https://10.0.1.5:4443/apis/metrics.k8s.io/v1beta1: Get "https://10.0.1.5:4443/apis/metrics.k8s.io/v1beta1": dial tcp 10.0.1.5:4443: i/o timeout`,
+			APIServiceUnavailable, "v1beta1.metrics.k8s.io"},
+		{`E0101 10:00:00.000000 10 controller.go:113] loading OpenAPI spec for "v1beta1.metrics.k8s.io" failed with: failed to retrieve openAPI spec, http error: ResponseCode: 503, Body: service unavailable`,
+			APIOpenAPIAggregation, "v1beta1.metrics.k8s.io"},
+		{`I0101 10:00:00.000000 10 controller.go:126] OpenAPI AggregationController: action for item v1beta1.custom.metrics.k8s.io: Rate Limited Requeue.`,
+			APIOpenAPIAggregation, "v1beta1.custom.metrics.k8s.io"},
+		{`E0101 10:00:00.000000 10 customresource_handler.go:745] "Failed to build OpenAPI spec for CRD" err="unknown type" crd="widgets.example.com"`,
+			APICRDSchema, "widgets.example.com"},
+		{`E0101 10:00:00.000000 10 crd_finalizer.go:1] CustomResourceDefinition gadgets.example.com violates structural schema: spec.validation.openAPIV3Schema.type: Required value`,
+			APICRDSchema, "gadgets.example.com"},
+		{`E0101 10:00:00.000000 10 handler_discovery.go:1] failed to fetch discovery document of v1alpha1.example.io: 503`,
+			APIDiscovery, "v1alpha1.example.io"},
+	}
+	for _, tt := range tests {
+		problem, object, ok := ClassifyAPIHealth(LogEntry{Message: tt.message})
+		assert.True(t, ok, tt.message)
+		assert.Equal(t, tt.problem, problem, tt.message)
+		assert.Equal(t, tt.object, object, tt.message)
+	}
+
+	for _, message := range []string{
+		`I0101 10:00:00.000000 10 handler.go:1] Adding GroupVersion metrics.k8s.io v1beta1 to ResourceManager`,
+		`E0101 10:00:00.000000 10 endpoints.go:1] endpoint discovery failed for service default/web`,
+	} {
+		_, _, ok := ClassifyAPIHealth(LogEntry{Message: message})
+		assert.False(t, ok, message)
+	}
+}
+
+func TestScanAPIHealth(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2024, 1, 1, 10, minute, 0, 0, time.UTC) }
+	unavailable := func(minute int, detail string) LogEntry {
+		return LogEntry{Timestamp: at(minute), Message: "E0101 10:00:00.000000 10 available_controller.go:524] v1beta1.metrics.k8s.io failed with: failing or missing response: " + detail}
+	}
+	entries := []LogEntry{
+		unavailable(3, "latest"),
+		unavailable(1, "first"),
+		{Timestamp: at(2), Message: `"Failed to build OpenAPI spec for CRD" crd="widgets.example.com"`},
+		{Timestamp: at(4), Message: "unrelated"},
+	}
+
+	issues := ScanAPIHealth(entries)
+	assert.Len(t, issues, 2)
+	assert.Equal(t, APIHealthIssue{
+		Problem:   APIServiceUnavailable,
+		Object:    "v1beta1.metrics.k8s.io",
+		Count:     2,
+		FirstSeen: at(1),
+		LastSeen:  at(3),
+		Message:   "v1beta1.metrics.k8s.io failed with: failing or missing response: latest",
+	}, issues[0])
+	assert.Equal(t, APICRDSchema, issues[1].Problem)
+}
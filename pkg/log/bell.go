@@ -0,0 +1,89 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alerts of a Bell
+const (
+	BellModeBell = "bell" // the terminal bell, which tmux and screen flag on background windows
+	BellModeOSC  = "osc"  // an OSC 9 desktop notification, shown by iTerm2, Windows Terminal and others
+)
+
+// defaultBellInterval is the shortest time between two alerts, so a burst of matches alerts
+// once
+const defaultBellInterval = time.Second
+
+// bellSummaryWidth is the number of characters of the matching message in a notification
+const bellSummaryWidth = 120
+
+// Bell alerts the terminal when an entry is printed, at most once per interval
+type Bell struct {
+	w        io.Writer
+	mode     string
+	title    string
+	interval time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	last time.Time // time of the last alert
+}
+
+// NewBell returns a bell writing the alerts of a mode to w, the terminal. title starts the
+// text of notifications.
+func NewBell(w io.Writer, mode, title string) (*Bell, error) {
+	if mode != BellModeBell && mode != BellModeOSC {
+		return nil, fmt.Errorf("invalid bell '%s' (supported: bell, osc)", mode)
+	}
+	return &Bell{w: w, mode: mode, title: title, interval: defaultBellInterval, now: time.Now}, nil
+}
+
+// Ring alerts for an entry, unless an alert was given less than an interval ago. It is safe
+// for concurrent use.
+func (b *Bell) Ring(entry LogEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() && now.Sub(b.last) < b.interval {
+		return
+	}
+	b.last = now
+
+	if b.mode == BellModeBell {
+		_, _ = io.WriteString(b.w, "\a")
+		return
+	}
+	_, _ = fmt.Fprintf(b.w, "\x1b]9;%s\a", bellSummary(b.title, entry.Message))
+}
+
+// Wrap returns a print function that alerts after printing each entry
+func (b *Bell) Wrap(printFunc func(LogEntry)) func(LogEntry) {
+	return func(entry LogEntry) {
+		printFunc(entry)
+		b.Ring(entry)
+	}
+}
+
+// bellSummary returns the text of a notification: the title and the start of the message on
+// one line, without the control characters that would end the escape sequence
+func bellSummary(title, message string) string {
+	message = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, message)
+	message = strings.Join(strings.Fields(message), " ")
+	if runes := []rune(message); len(runes) > bellSummaryWidth {
+		message = string(runes[:bellSummaryWidth-3]) + "..."
+	}
+	if title == "" {
+		return message
+	}
+	return title + ": " + message
+}
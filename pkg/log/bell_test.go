@@ -0,0 +1,44 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBell(t *testing.T) {
+	var out bytes.Buffer
+	bell, err := NewBell(&out, BellModeBell, "my-cluster")
+	assert.NoError(t, err)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	bell.now = func() time.Time { return now }
+
+	var printed []string
+	printFunc := bell.Wrap(func(entry LogEntry) { printed = append(printed, entry.Message) })
+	printFunc(LogEntry{Message: "a"})
+	printFunc(LogEntry{Message: "b"}) // Within the interval
+	now = now.Add(2 * time.Second)
+	printFunc(LogEntry{Message: "c"})
+
+	assert.Equal(t, []string{"a", "b", "c"}, printed)
+	assert.Equal(t, "\a\a", out.String())
+
+	_, err = NewBell(&out, "flash", "")
+	assert.ErrorContains(t, err, "invalid bell 'flash'")
+}
+
+func TestBellOSC(t *testing.T) {
+	var out bytes.Buffer
+	bell, err := NewBell(&out, BellModeOSC, "my-cluster")
+	assert.NoError(t, err)
+
+	bell.Ring(LogEntry{Message: "E0101 12:00:00 leader election lost\n\x1b]0;title\a"})
+	assert.Equal(t, "\x1b]9;my-cluster: E0101 12:00:00 leader election lost ]0;title\a", out.String())
+
+	summary := bellSummary("", strings.Repeat("x", 200))
+	assert.Len(t, summary, bellSummaryWidth)
+	assert.True(t, strings.HasSuffix(summary, "..."))
+}
@@ -0,0 +1,158 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// benchCorpus holds synthetic control plane events of every log type, in the shape of
+// the events returned by FilterLogEvents
+//
+//go:embed testdata/corpus.jsonl
+var benchCorpus []byte
+
+// BenchLogGroup is the log group of the entries of the built-in corpus
+const BenchLogGroup = "/aws/eks/bench/cluster"
+
+// RecordedEvent is a log event as returned by FilterLogEvents, e.g. an element of the
+// events of 'aws logs filter-log-events' output
+type RecordedEvent struct {
+	LogStreamName string `json:"logStreamName"`
+	Timestamp     int64  `json:"timestamp"`
+	IngestionTime int64  `json:"ingestionTime"`
+	Message       string `json:"message"`
+	EventID       string `json:"eventId"`
+}
+
+// entry returns the log entry of an event of logGroup, as the client builds it
+func (e RecordedEvent) entry(logGroup string) LogEntry {
+	entry := LogEntry{
+		Timestamp: time.UnixMilli(e.Timestamp),
+		Level:     ExtractLogLevel(e.Message),
+		Component: ComponentFor(logGroup, e.LogStreamName),
+		Message:   e.Message,
+		LogGroup:  logGroup,
+		LogStream: e.LogStreamName,
+		EventID:   e.EventID,
+	}
+	if e.IngestionTime > 0 {
+		entry.IngestionTime = time.UnixMilli(e.IngestionTime)
+	}
+	return entry
+}
+
+// ReadRecordedEvents reads the log entries of events of logGroup recorded as JSON lines,
+// or as the JSON output of 'aws logs filter-log-events'
+func ReadRecordedEvents(r io.Reader, logGroup string) ([]LogEntry, error) {
+	reader := bufio.NewReader(r)
+	first, err := firstNonSpace(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []RecordedEvent
+	decoder := json.NewDecoder(reader)
+	if first == '{' {
+		// Either a filter-log-events response or the first of JSON lines
+		var object struct {
+			Events []RecordedEvent `json:"events"`
+			RecordedEvent
+		}
+		if err := decoder.Decode(&object); err != nil {
+			return nil, fmt.Errorf("failed to read recorded events: %w", err)
+		}
+		if object.Events != nil {
+			events = object.Events
+		} else {
+			events = append(events, object.RecordedEvent)
+		}
+	}
+	for decoder.More() {
+		var event RecordedEvent
+		if err := decoder.Decode(&event); err != nil {
+			return nil, fmt.Errorf("failed to read recorded event %d: %w", len(events)+1, err)
+		}
+		events = append(events, event)
+	}
+
+	entries := make([]LogEntry, len(events))
+	for i, event := range events {
+		entries[i] = event.entry(logGroup)
+	}
+	return entries, nil
+}
+
+// firstNonSpace peeks at the first character of r that is not white space
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b[0])) {
+			return b[0], nil
+		}
+		_, _ = r.ReadByte()
+	}
+}
+
+// BenchCorpus returns the entries of the built-in corpus of synthetic events
+func BenchCorpus() []LogEntry {
+	entries, err := ReadRecordedEvents(bytes.NewReader(benchCorpus), BenchLogGroup)
+	if err != nil {
+		panic("invalid built-in corpus: " + err.Error())
+	}
+	return entries
+}
+
+// PipelineCase is an output configuration whose cost per event is measured by the
+// benchmarks and 'ekslogs bench'
+type PipelineCase struct {
+	Name    string
+	Options OutputOptions
+	Color   bool
+}
+
+// PipelineCases returns the measured output configurations: plain and colored text, the
+// JSON rendering options, jq, redaction and JSON output
+func PipelineCases() []PipelineCase {
+	jq, err := NewJQFilter(`select(.verb != "watch") | {verb, user: .user.username}`)
+	if err != nil {
+		panic(err)
+	}
+	lineFormat, err := ParseLineFormat("{time} {type} {level} {msg}")
+	if err != nil {
+		panic(err)
+	}
+	return []PipelineCase{
+		{Name: "text", Options: OutputOptions{Format: OutputFormatText}},
+		{Name: "color", Options: OutputOptions{Format: OutputFormatText}, Color: true},
+		{Name: "message-only", Options: OutputOptions{Format: OutputFormatText, MessageOnly: true}, Color: true},
+		{Name: "pretty", Options: OutputOptions{Format: OutputFormatText, Pretty: true}, Color: true},
+		{Name: "fields", Options: OutputOptions{Format: OutputFormatText, Fields: []string{"verb", "user.username", "responseStatus.code"}}, Color: true},
+		{Name: "format", Options: OutputOptions{Format: OutputFormatText, LineFormat: lineFormat, ShowLag: true}, Color: true},
+		{Name: "jq", Options: OutputOptions{Format: OutputFormatText, JQ: jq}, Color: true},
+		{Name: "redact", Options: OutputOptions{Format: OutputFormatText, Redactor: NewRedactor(true)}, Color: true},
+		{Name: "json", Options: OutputOptions{Format: OutputFormatJSON, Metadata: &Metadata{Account: "123456789012"}}},
+	}
+}
+
+// NewPrinter returns a printer of the configuration writing to w
+func (c PipelineCase) NewPrinter(w io.Writer) *Printer {
+	mode := ColorModeNever
+	if c.Color {
+		mode = ColorModeAlways
+	}
+	printer := NewPrinter(c.Options, &ColorConfig{Mode: mode})
+	printer.SetOutput(w)
+	return printer
+}
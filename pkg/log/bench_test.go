@@ -0,0 +1,99 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadRecordedEvents(t *testing.T) {
+	lines := `{"logStreamName":"kube-apiserver-abc","timestamp":1718186400000,"ingestionTime":1718186401500,"message":"E0612 10:00:00.000000 1 status.go:71] failed","eventId":"1"}
+{"logStreamName":"authenticator-abc","timestamp":1718186401000,"message":"time=\"2024-06-12T10:00:01Z\" level=info msg=\"access granted\"","eventId":"2"}
+`
+	entries, err := ReadRecordedEvents(strings.NewReader(lines), "/aws/eks/prod/cluster")
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, time.UnixMilli(1718186400000), entries[0].Timestamp)
+		assert.Equal(t, 1500*time.Millisecond, entries[0].IngestionTime.Sub(entries[0].Timestamp))
+		assert.Equal(t, "kube-apiserver", entries[0].Component)
+		assert.Equal(t, "error", entries[0].Level)
+		assert.Equal(t, "/aws/eks/prod/cluster", entries[0].LogGroup)
+		assert.Equal(t, "2", entries[1].EventID)
+		assert.True(t, entries[1].IngestionTime.IsZero())
+	}
+
+	// The output of aws logs filter-log-events
+	response := `{
+  "events": [
+    {"logStreamName": "kube-scheduler-abc", "timestamp": 1718186400000, "message": "I0612 bound", "eventId": "3"}
+  ],
+  "searchedLogStreams": []
+}`
+	entries, err = ReadRecordedEvents(strings.NewReader(response), "/aws/eks/prod/cluster")
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "kube-scheduler-abc", entries[0].LogStream)
+	}
+
+	entries, err = ReadRecordedEvents(strings.NewReader(""), "/aws/eks/prod/cluster")
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = ReadRecordedEvents(strings.NewReader("{\"timestamp\": 1}\nnot json\n"), "/aws/eks/prod/cluster")
+	assert.ErrorContains(t, err, "recorded event 2")
+}
+
+func TestBenchCorpus(t *testing.T) {
+	entries := BenchCorpus()
+	assert.NotEmpty(t, entries)
+	logTypes := make(map[string]bool)
+	for _, entry := range entries {
+		logTypes[ExtractLogTypeFromStreamName(entry.LogStream)] = true
+	}
+	for _, logType := range []string{"api", "audit", "authenticator", "kcm", "ccm", "scheduler"} {
+		assert.True(t, logTypes[logType], "the corpus should hold %s events", logType)
+	}
+}
+
+func TestPipelineCases(t *testing.T) {
+	entries := BenchCorpus()
+	for _, c := range PipelineCases() {
+		var out bytes.Buffer
+		printer := c.NewPrinter(&out)
+		for _, entry := range entries {
+			printer.Print(entry)
+		}
+		assert.NotZero(t, printer.Printed(), c.Name)
+		assert.GreaterOrEqual(t, strings.Count(out.String(), "\n"), printer.Printed(), c.Name)
+	}
+}
+
+// BenchmarkPipeline measures the cost per event of each output configuration over the
+// built-in corpus, e.g. go test ./pkg/log -run '^$' -bench Pipeline -benchmem
+func BenchmarkPipeline(b *testing.B) {
+	entries := BenchCorpus()
+	for _, c := range PipelineCases() {
+		b.Run(c.Name, func(b *testing.B) {
+			printer := c.NewPrinter(io.Discard)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				printer.Print(entries[i%len(entries)])
+			}
+		})
+	}
+}
+
+func BenchmarkReadRecordedEvents(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchCorpus)))
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadRecordedEvents(bytes.NewReader(benchCorpus), BenchLogGroup); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Problems of certificates reported by the API server and authenticator
+const (
+	CertExpired          = "expired"
+	CertUnknownAuthority = "unknown authority"
+	CertNameMismatch     = "name mismatch"
+	CertInvalid          = "invalid"
+)
+
+var (
+	// certSerialPattern matches the serial number of a client certificate that failed verification
+	certSerialPattern = regexp.MustCompile(`\bSN=(\d+)`)
+	// certWebhookPattern matches the name of a webhook whose certificate failed verification
+	certWebhookPattern = regexp.MustCompile(`failed calling webhook "([^"]+)"`)
+	// certHostPattern matches the host a certificate was not valid for
+	certHostPattern = regexp.MustCompile(`is valid for .*?, not ([^\s"\]]+)`)
+	// certURLPattern matches the server of a failed HTTPS call, e.g. Post "https://host:443/path"
+	certURLPattern = regexp.MustCompile(`(?:Get|Post|Put|Patch|Delete) "https://([^/"]+)`)
+	// certUserPattern matches the user of authenticator messages
+	certUserPattern = regexp.MustCompile(`\buser(?:name)?="([^"]+)"`)
+)
+
+// CertificateIssue counts the certificate errors of one identity, such as a client
+// certificate serial number, a webhook or a server
+type CertificateIssue struct {
+	Problem   string    `json:"problem"`
+	Identity  string    `json:"identity"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ClassifyCertificateError returns the certificate problem a log message reports and the
+// identity it affects, falling back to the logging component when none is named
+func ClassifyCertificateError(entry LogEntry) (string, string, bool) {
+	lower := strings.ToLower(entry.Message)
+	if !strings.Contains(lower, "x509") && !strings.Contains(lower, "certificate") {
+		return "", "", false
+	}
+
+	var problem string
+	switch {
+	case strings.Contains(lower, "certificate has expired"), strings.Contains(lower, "certificate expired"),
+		strings.Contains(lower, "not yet valid"):
+		problem = CertExpired
+	case strings.Contains(lower, "signed by unknown authority"):
+		problem = CertUnknownAuthority
+	case strings.Contains(lower, "certificate is valid for"), strings.Contains(lower, "certificate is not valid for"):
+		problem = CertNameMismatch
+	case strings.Contains(lower, "x509:"):
+		problem = CertInvalid
+	default:
+		return "", "", false
+	}
+
+	return problem, certificateIdentity(entry), true
+}
+
+// certificateIdentity names what a certificate error affects
+func certificateIdentity(entry LogEntry) string {
+	if m := certSerialPattern.FindStringSubmatch(entry.Message); m != nil {
+		return "client certificate SN=" + m[1]
+	}
+	if m := certWebhookPattern.FindStringSubmatch(entry.Message); m != nil {
+		return "webhook " + m[1]
+	}
+	if m := certHostPattern.FindStringSubmatch(entry.Message); m != nil {
+		return "host " + m[1]
+	}
+	if m := certURLPattern.FindStringSubmatch(entry.Message); m != nil {
+		return "server " + m[1]
+	}
+	if m := certUserPattern.FindStringSubmatch(entry.Message); m != nil {
+		return "user " + m[1]
+	}
+	if entry.Component != "" {
+		return entry.Component
+	}
+	return "unknown"
+}
+
+// ScanCertificateErrors groups the certificate errors of entries per problem and identity,
+// the most recently seen first
+func ScanCertificateErrors(entries []LogEntry) []CertificateIssue {
+	type issueKey struct{ problem, identity string }
+	issues := make(map[issueKey]*CertificateIssue)
+	for _, entry := range entries {
+		problem, identity, ok := ClassifyCertificateError(entry)
+		if !ok {
+			continue
+		}
+		key := issueKey{problem, identity}
+		issue, exists := issues[key]
+		if !exists {
+			issue = &CertificateIssue{Problem: problem, Identity: identity, FirstSeen: entry.Timestamp, LastSeen: entry.Timestamp}
+			issues[key] = issue
+		}
+		issue.Count++
+		if entry.Timestamp.Before(issue.FirstSeen) {
+			issue.FirstSeen = entry.Timestamp
+		}
+		if entry.Timestamp.After(issue.LastSeen) {
+			issue.LastSeen = entry.Timestamp
+		}
+	}
+
+	result := make([]CertificateIssue, 0, len(issues))
+	for _, issue := range issues {
+		result = append(result, *issue)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].LastSeen.Equal(result[j].LastSeen) {
+			return result[i].LastSeen.After(result[j].LastSeen)
+		}
+		if result[i].Problem != result[j].Problem {
+			return result[i].Problem < result[j].Problem
+		}
+		return result[i].Identity < result[j].Identity
+	})
+	return result
+}
@@ -0,0 +1,60 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyCertificateError(t *testing.T) {
+	tests := []struct {
+		message  string
+		problem  string
+		identity string
+	}{
+		{`E0101 10:00:00.000000 1 authentication.go:63] "Unable to authenticate the request" err="verifying certificate SN=1234567, SKID=, AKID= failed: x509: certificate has expired or is not yet valid"`,
+			CertExpired, "client certificate SN=1234567"},
+		{`W0101 10:00:00.000000 1 dispatcher.go:1] Failed calling webhook, failing closed: failed calling webhook "vpod.kb.io": Post "https://webhook.default.svc:443/validate": x509: certificate signed by unknown authority`,
+			CertUnknownAuthority, "webhook vpod.kb.io"},
+		{`E0101 10:00:00.000000 1 proxy.go:1] Get "https://metrics.kube-system.svc:443/apis": x509: certificate is valid for metrics.local, not metrics.kube-system.svc`,
+			CertNameMismatch, "host metrics.kube-system.svc"},
+		{`E0101 10:00:00.000000 1 client.go:1] Post "https://10.0.1.5:10250/exec": x509: cannot validate certificate for 10.0.1.5 because it doesn't contain any IP SANs`,
+			CertInvalid, "server 10.0.1.5:10250"},
+	}
+	for _, tt := range tests {
+		problem, identity, ok := ClassifyCertificateError(LogEntry{Message: tt.message})
+		assert.True(t, ok, tt.message)
+		assert.Equal(t, tt.problem, problem, tt.message)
+		assert.Equal(t, tt.identity, identity, tt.message)
+	}
+
+	// The component is the identity when the message names none
+	_, identity, ok := ClassifyCertificateError(LogEntry{Component: "kube-apiserver", Message: "x509: certificate has expired"})
+	assert.True(t, ok)
+	assert.Equal(t, "kube-apiserver", identity)
+
+	for _, message := range []string{
+		"I0101 10:00:00.000000 1 certificate_manager.go:1] Certificate expiration is 2025-01-01, rotation deadline is 2024-10-01",
+		"I0101 10:00:00.000000 1 controller.go:1] Starting controller",
+	} {
+		_, _, ok := ClassifyCertificateError(LogEntry{Message: message})
+		assert.False(t, ok, message)
+	}
+}
+
+func TestScanCertificateErrors(t *testing.T) {
+	at := func(hour int) time.Time { return time.Date(2024, 1, 1, hour, 0, 0, 0, time.UTC) }
+	expired := "verifying certificate SN=42, SKID=, AKID= failed: x509: certificate has expired or is not yet valid"
+	entries := []LogEntry{
+		{Timestamp: at(3), Message: expired},
+		{Timestamp: at(1), Message: expired},
+		{Timestamp: at(2), Message: `failed calling webhook "vpod.kb.io": x509: certificate signed by unknown authority`},
+		{Timestamp: at(4), Message: "Starting controller"},
+	}
+
+	assert.Equal(t, []CertificateIssue{
+		{Problem: CertExpired, Identity: "client certificate SN=42", Count: 2, FirstSeen: at(1), LastSeen: at(3)},
+		{Problem: CertUnknownAuthority, Identity: "webhook vpod.kb.io", Count: 1, FirstSeen: at(2), LastSeen: at(2)},
+	}, ScanCertificateErrors(entries))
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"github.com/fatih/color"
 	"golang.org/x/term"
+	"hash/fnv"
 	"os"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -24,9 +26,65 @@ const (
 	ColorModeNever ColorMode = "never"
 )
 
+// Highlight patterns used by the colorizers, compiled once
+var (
+	// errorPattern matches error keywords
+	errorPattern = regexp.MustCompile(`(error|failed|failure|unable to|cannot|timeout)`)
+	// resourcePattern matches Kubernetes resource references such as pod/name
+	resourcePattern = regexp.MustCompile(`(pod|node|service|deployment|daemonset|statefulset|configmap|secret|namespace)/([a-zA-Z0-9-_.]+)`)
+	// crdPattern matches CRD group names
+	crdPattern = regexp.MustCompile(`([a-zA-Z0-9-]+\.[a-zA-Z0-9.-]+\.(com|io|sh|aws|k8s\.aws))`)
+	// apiKeywordPattern matches API server keywords
+	apiKeywordPattern = regexp.MustCompile(`\b(CRD|CustomResourceDefinition|OpenAPI|spec|controller|webhook|admission)\b`)
+	// filePathPattern matches Go source locations
+	filePathPattern = regexp.MustCompile(`([a-zA-Z0-9_-]+\.go):(\d+)`)
+	// apiSuccessPattern matches success keywords in API server logs
+	apiSuccessPattern = regexp.MustCompile(`(success|successfully|created|updated|deleted|Updating)`)
+	// arnPattern matches AWS ARNs
+	arnPattern = regexp.MustCompile(`arn:aws:[a-zA-Z0-9-]+:[a-zA-Z0-9-]*:[0-9]+:[a-zA-Z0-9-:/]+`)
+	// usernamePattern matches authenticator usernames
+	usernamePattern = regexp.MustCompile(`username="([^"]+)"`)
+	// authErrorPattern matches authenticator error keywords
+	authErrorPattern = regexp.MustCompile(`\b(error|failed|failure|unable to|cannot|timeout|invalid|missing)\b|access (denied|granted)`)
+	// awsErrorCodePattern matches escaped AWS error codes
+	awsErrorCodePattern = regexp.MustCompile(`\\"Code\\":\\"([^"]+)\\"`)
+	// awsErrorTypePattern matches escaped AWS error types
+	awsErrorTypePattern = regexp.MustCompile(`\\"Type\\":\\"([^"]+)\\"`)
+	// httpStatusPattern matches common HTTP status codes
+	httpStatusPattern = regexp.MustCompile(`\b(200|201|204|400|401|403|404|500|502|503)\b`)
+	// ipPortPattern matches IPv4 address and port pairs
+	ipPortPattern = regexp.MustCompile(`\b(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}):(\d+)\b`)
+	// httpMethodPattern matches HTTP methods
+	httpMethodPattern = regexp.MustCompile(`method=(GET|POST|PUT|DELETE|PATCH)`)
+	// httpPathPattern matches request paths
+	httpPathPattern = regexp.MustCompile(`path=(/[^\s]*)`)
+	// logfmtLevelPattern matches logfmt levels
+	logfmtLevelPattern = regexp.MustCompile(`level=(debug|info|warning|error|fatal)`)
+	// controllerPattern matches controller names
+	controllerPattern = regexp.MustCompile(`\b([a-zA-Z0-9-]+)_controller\b`)
+	// awsResourcePattern matches AWS resource IDs
+	awsResourcePattern = regexp.MustCompile(`\b(vpc-|subnet-|sg-|i-|vol-|rtb-|igw-|nat-|eni-|eip-|acl-)[a-f0-9]+\b`)
+	// schedulerKeywordPattern matches scheduler keywords
+	schedulerKeywordPattern = regexp.MustCompile(`\b(schedule|scheduling|scheduled|unschedulable|predicates|priorities|binding|bound)\b`)
+	// podPattern matches pod references
+	podPattern = regexp.MustCompile(`pod/([a-zA-Z0-9-_.]+)`)
+	// nodePattern matches node references
+	nodePattern = regexp.MustCompile(`node/([a-zA-Z0-9-_.]+)`)
+	// successPattern matches success keywords
+	successPattern = regexp.MustCompile(`(success|successfully|created|updated|deleted)`)
+	// accessPattern matches authenticator access decisions
+	accessPattern = regexp.MustCompile(`access (granted|denied)`)
+)
+
 // ColorConfig holds the configuration for color output
 type ColorConfig struct {
 	Mode ColorMode
+	// Theme remaps the built-in colors (nil uses the dark theme)
+	Theme *Theme
+	// Rules are custom highlight rules applied after the built-in ones
+	Rules []HighlightRule
+	// disabledRules holds the built-in highlight rules turned off by the user
+	disabledRules map[string]bool
 }
 
 // NewColorConfig creates a new ColorConfig with default settings
@@ -44,13 +102,36 @@ func (c *ColorConfig) ShouldUseColor() bool {
 	case ColorModeNever:
 		return false
 	case ColorModeAuto:
-		// Check if output is a terminal
-		return isTerminal(os.Stdout)
+		return autoColorEnabled()
 	default:
 		return false
 	}
 }
 
+// autoColorEnabled decides whether to use colors in auto mode from the environment
+// (EKSLOGS_COLOR, NO_COLOR, CLICOLOR_FORCE), falling back to terminal detection
+func autoColorEnabled() bool {
+	switch ColorMode(strings.ToLower(os.Getenv("EKSLOGS_COLOR"))) {
+	case ColorModeAlways:
+		return true
+	case ColorModeNever:
+		return false
+	}
+
+	// https://no-color.org: any non-empty value disables colors
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	// CLICOLOR_FORCE forces colors even when not writing to a terminal
+	if force := os.Getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+		return true
+	}
+
+	// Check if output is a terminal, and a Windows console can render colors
+	return isTerminal(os.Stdout) && enableVirtualTerminal(os.Stdout)
+}
+
 // isTerminal checks if the given file is a terminal
 func isTerminal(file *os.File) bool {
 	// Use golang.org/x/term to properly detect terminal
@@ -59,119 +140,211 @@ func isTerminal(file *os.File) bool {
 
 // LogColorizer provides rich color formatting for logs
 type LogColorizer struct {
-	config *ColorConfig
+	config   *ColorConfig
+	useColor bool        // Resolved once, as it depends on the environment and terminal
+	pretty   bool        // Render colored JSON with indentation
+	format   *LineFormat // Custom line layout (nil uses the default layout)
+	colors   sync.Map    // Themed colors by colorKey, built on first use
+}
+
+// colorKey identifies a combination of color attributes; combinations longer than the
+// array are not cached
+type colorKey struct {
+	n          int
+	attributes [4]color.Attribute
 }
 
 // NewLogColorizer creates a new LogColorizer
 func NewLogColorizer(config *ColorConfig) *LogColorizer {
-	// Force color output when ColorModeAlways is set
-	switch config.Mode {
-	case ColorModeAlways:
-		color.NoColor = false
-	case ColorModeNever:
-		color.NoColor = true
-	case ColorModeAuto:
-		// Let the color package handle detection automatically
+	// Follow the environment conventions and terminal detection once, not on every line
+	useColor := config.ShouldUseColor()
+	color.NoColor = !useColor
+	if useColor {
+		// Colors are also written to stderr, e.g. by the verbose banner
+		enableVirtualTerminal(os.Stdout)
+		enableVirtualTerminal(os.Stderr)
 	}
 
 	return &LogColorizer{
-		config: config,
+		config:   config,
+		useColor: useColor,
 	}
 }
 
 // ColorizeLog applies color formatting to a log entry based on its type and content
 func (lc *LogColorizer) ColorizeLog(entry LogEntry) string {
-	if !lc.config.ShouldUseColor() {
-		// Return plain text if colors are disabled
-		timestamp := entry.Timestamp.UTC().Format(time.RFC3339)
-		return fmt.Sprintf("%s [%s] [%s] %s",
-			timestamp,
-			entry.Level,
-			entry.Component,
-			entry.Message,
-		)
+	return lc.colorizeLog(entry, nil)
+}
+
+// colorizeLog renders a full log line, inserting optional extra columns after the component
+func (lc *LogColorizer) colorizeLog(entry LogEntry, extra map[string]string) string {
+	logType := NormalizeLogType(LogTypeFor(entry.LogGroup, entry.LogStream))
+	columns := map[string]string{
+		"time":       entry.Timestamp.UTC().Format(time.RFC3339),
+		"type":       logType,
+		"level":      entry.Level,
+		"component":  entry.Component,
+		"msg":        entry.Message,
+		"log_group":  entry.LogGroup,
+		"log_stream": entry.LogStream,
+	}
+	for name, value := range extra {
+		columns[name] = value
 	}
 
-	// Apply color based on log type
-	switch NormalizeLogType(ExtractLogTypeFromStreamName(entry.LogStream)) {
-	case "api":
-		return lc.colorizeAPILog(entry)
-	case "audit":
-		return lc.colorizeAuditLog(entry)
-	case "authenticator":
-		return lc.colorizeAuthenticatorLog(entry)
-	case "kcm":
-		return lc.colorizeControllerManagerLog(entry)
-	case "ccm":
-		return lc.colorizeCloudControllerManagerLog(entry)
-	case "scheduler":
-		return lc.colorizeSchedulerLog(entry)
-	default:
-		return lc.colorizeDefaultLog(entry)
+	// Colorize the columns unless colors are disabled
+	if lc.useColor {
+		levelColor := lc.getLevelColor(entry.Level)
+		var message string
+
+		// Apply color based on log type
+		switch logType {
+		case "api":
+			message = lc.colorizeAPILog(entry)
+		case "audit":
+			levelColor = lc.color(color.FgBlue)
+			message = lc.colorizeAuditLog(entry)
+		case "authenticator":
+			message = lc.colorizeAuthenticatorLog(entry)
+		case "kcm":
+			message = lc.colorizeControllerManagerLog(entry)
+		case "ccm":
+			message = lc.colorizeCloudControllerManagerLog(entry)
+		case "scheduler":
+			message = lc.colorizeSchedulerLog(entry)
+		default:
+			message = lc.colorizeDefaultLog(entry)
+		}
+
+		columns["msg"] = lc.applyCustomRules(logType, message)
+		columns["time"] = lc.color(color.FgHiBlack).Sprint(columns["time"])
+		columns["level"] = levelColor.Sprint(entry.Level)
+		// A stable color per log type tells interleaved streams apart
+		tagColor := lc.color(logTypeColor(logType))
+		columns["component"] = tagColor.Sprint(entry.Component)
+		if logType != "" {
+			columns["type"] = tagColor.Sprint(logType)
+		}
+		columns["log_group"] = lc.color(color.FgHiBlack).Sprint(entry.LogGroup)
+		columns["log_stream"] = lc.color(color.FgHiBlack).Sprint(entry.LogStream)
+	}
+
+	if lc.format != nil {
+		return lc.format.Render(columns)
 	}
+	return formatLine(columns)
 }
 
-// colorizeAPILog applies color formatting specific to API server logs
-func (lc *LogColorizer) colorizeAPILog(entry LogEntry) string {
-	timestamp := color.New(color.FgHiBlack).SprintFunc()(entry.Timestamp.UTC().Format(time.RFC3339))
-	component := color.New(color.FgGreen).SprintFunc()(entry.Component)
+// logTypeColors are the colors of the component and type columns per log type
+var logTypeColors = map[string]color.Attribute{
+	"api":           color.FgGreen,
+	"audit":         color.FgBlue,
+	"authenticator": color.FgYellow,
+	"kcm":           color.FgCyan,
+	"ccm":           color.FgHiCyan,
+	"scheduler":     color.FgMagenta,
+	"kubelet":       color.FgHiGreen,
+	"containerd":    color.FgHiBlue,
+	"docker":        color.FgHiBlue,
+	"dataplane":     color.FgHiMagenta,
+	"host":          color.FgWhite,
+	"application":   color.FgHiYellow,
+	"cloudtrail":    color.FgHiRed,
+}
+
+// logTypeColor returns the color of the component and type columns of a log type, green
+// for unknown types
+func logTypeColor(logType string) color.Attribute {
+	if attribute, ok := logTypeColors[logType]; ok {
+		return attribute
+	}
+	return color.FgGreen
+}
+
+// labelColors are the colors LabelColor picks from
+var labelColors = []color.Attribute{
+	color.FgCyan, color.FgMagenta, color.FgYellow, color.FgBlue, color.FgGreen,
+	color.FgHiCyan, color.FgHiMagenta, color.FgHiYellow, color.FgHiBlue, color.FgHiGreen,
+}
+
+// LabelColor returns a color for a label such as a cluster name, the same for the same
+// label in every run, so the lines of several targets merged into one stream are told apart
+func LabelColor(label string) color.Attribute {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(label))
+	return labelColors[hash.Sum32()%uint32(len(labelColors))]
+}
 
-	// Color the level
-	levelColor := getLevelColor(entry.Level)
-	level := levelColor.SprintFunc()(entry.Level)
+// formatLine joins the columns of a log line as "time [level] [component] [extra...] msg"
+func formatLine(columns map[string]string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s [%s] [%s]", columns["time"], columns["level"], columns["component"])
+	for _, name := range extraColumns {
+		if value, ok := columns[name]; ok {
+			fmt.Fprintf(&b, " [%s]", value)
+		}
+	}
+	b.WriteString(" ")
+	b.WriteString(columns["msg"])
+	return b.String()
+}
 
+// colorizeAPILog applies color formatting specific to API server logs and returns the colored message
+func (lc *LogColorizer) colorizeAPILog(entry LogEntry) string {
 	// Colorize specific patterns in the message
 	message := entry.Message
 
 	// Highlight error messages
-	errorPattern := regexp.MustCompile(`(error|failed|failure|unable to|cannot|timeout)`)
-	message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "errors") {
+		message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
 	// Highlight resource names
-	resourcePattern := regexp.MustCompile(`(pod|node|service|deployment|daemonset|statefulset|configmap|secret|namespace)/([a-zA-Z0-9-_.]+)`)
-	message = resourcePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "resources") {
+		message = resourcePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight CRD names and API groups
-	crdPattern := regexp.MustCompile(`([a-zA-Z0-9-]+\.[a-zA-Z0-9.-]+\.(com|io|sh|aws|k8s\.aws))`)
-	message = crdPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgMagenta, color.Bold).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "crds") {
+		message = crdPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgMagenta, color.Bold).Sprint(s)
+		})
+	}
 
 	// Highlight Kubernetes resource types in messages
-	k8sResourcePattern := regexp.MustCompile(`\b(CRD|CustomResourceDefinition|OpenAPI|spec|controller|webhook|admission)\b`)
-	message = k8sResourcePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgYellow).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "keywords") {
+		message = apiKeywordPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgYellow).Sprint(s)
+		})
+	}
 
 	// Highlight file paths and line numbers
-	filePathPattern := regexp.MustCompile(`([a-zA-Z0-9_-]+\.go):(\d+)`)
-	message = filePathPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgHiBlack).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "file-paths") {
+		message = filePathPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgHiBlack).Sprint(s)
+		})
+	}
 
 	// Highlight success messages
-	successPattern := regexp.MustCompile(`(success|successfully|created|updated|deleted|Updating)`)
-	message = successPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgGreen).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "success") {
+		message = apiSuccessPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgGreen).Sprint(s)
+		})
+	}
 
-	return fmt.Sprintf("%s [%s] [%s] %s", timestamp, level, component, message)
+	return message
 }
 
-// colorizeAuditLog applies color formatting specific to audit logs
+// colorizeAuditLog applies color formatting specific to audit logs and returns the colored message
 func (lc *LogColorizer) colorizeAuditLog(entry LogEntry) string {
-	timestamp := color.New(color.FgHiBlack).SprintFunc()(entry.Timestamp.UTC().Format(time.RFC3339))
-	component := color.New(color.FgGreen).SprintFunc()(entry.Component)
-	level := color.New(color.FgBlue).SprintFunc()(entry.Level)
-
 	// For audit logs, try to parse the JSON and highlight specific fields
 	message := entry.Message
 
-	if strings.HasPrefix(strings.TrimSpace(message), "{") {
+	if lc.ruleEnabled("audit", "json") && strings.HasPrefix(strings.TrimSpace(message), "{") {
 		// Try to parse the JSON
 		var auditData map[string]interface{}
 		err := json.Unmarshal([]byte(message), &auditData)
@@ -179,12 +352,12 @@ func (lc *LogColorizer) colorizeAuditLog(entry LogEntry) string {
 			// Create a new colored version of the message
 			coloredMessage := lc.colorizeAuditJSON(auditData)
 			if coloredMessage != "" {
-				return fmt.Sprintf("%s [%s] [%s] %s", timestamp, level, component, coloredMessage)
+				return coloredMessage
 			}
 		}
 	}
 
-	return fmt.Sprintf("%s [%s] [%s] %s", timestamp, level, component, message)
+	return message
 }
 
 // colorizeAuditJSON applies color formatting to audit log JSON data
@@ -198,31 +371,31 @@ func (lc *LogColorizer) colorizeAuditJSON(auditData map[string]interface{}) stri
 	// Apply colors to specific fields
 	if verb, ok := coloredData["verb"].(string); ok {
 		// Color verbs based on their type
-		verbColor := color.New(color.FgMagenta)
+		verbColor := lc.color(color.FgMagenta)
 		switch verb {
 		case "create", "update", "patch":
-			verbColor = color.New(color.FgGreen, color.Bold)
+			verbColor = lc.color(color.FgGreen, color.Bold)
 		case "delete":
-			verbColor = color.New(color.FgRed, color.Bold)
+			verbColor = lc.color(color.FgRed, color.Bold)
 		case "get", "list", "watch":
-			verbColor = color.New(color.FgCyan)
+			verbColor = lc.color(color.FgCyan)
 		}
 		coloredData["verb"] = verbColor.Sprint(verb)
 	}
 
 	if uri, ok := coloredData["requestURI"].(string); ok {
-		coloredData["requestURI"] = color.New(color.FgGreen).Sprint(uri)
+		coloredData["requestURI"] = lc.color(color.FgGreen).Sprint(uri)
 	}
 
 	// Handle user information
 	if user, ok := coloredData["user"].(map[string]interface{}); ok {
 		if username, ok := user["username"].(string); ok {
 			// Color usernames based on type
-			usernameColor := color.New(color.FgYellow)
+			usernameColor := lc.color(color.FgYellow)
 			if strings.HasPrefix(username, "system:") {
-				usernameColor = color.New(color.FgCyan)
+				usernameColor = lc.color(color.FgCyan)
 			} else if strings.HasPrefix(username, "eks:") {
-				usernameColor = color.New(color.FgMagenta)
+				usernameColor = lc.color(color.FgMagenta)
 			}
 			user["username"] = usernameColor.Sprint(username)
 		}
@@ -232,9 +405,9 @@ func (lc *LogColorizer) colorizeAuditJSON(auditData map[string]interface{}) stri
 			coloredGroups := make([]interface{}, len(groups))
 			for i, group := range groups {
 				if groupStr, ok := group.(string); ok {
-					groupColor := color.New(color.FgHiBlack)
+					groupColor := lc.color(color.FgHiBlack)
 					if strings.Contains(groupStr, "system:") {
-						groupColor = color.New(color.FgBlue)
+						groupColor = lc.color(color.FgBlue)
 					}
 					coloredGroups[i] = groupColor.Sprint(groupStr)
 				} else {
@@ -248,13 +421,13 @@ func (lc *LogColorizer) colorizeAuditJSON(auditData map[string]interface{}) stri
 	// Handle object reference
 	if objectRef, ok := coloredData["objectRef"].(map[string]interface{}); ok {
 		if resource, ok := objectRef["resource"].(string); ok {
-			objectRef["resource"] = color.New(color.FgCyan, color.Bold).Sprint(resource)
+			objectRef["resource"] = lc.color(color.FgCyan, color.Bold).Sprint(resource)
 		}
 		if namespace, ok := objectRef["namespace"].(string); ok {
-			objectRef["namespace"] = color.New(color.FgYellow).Sprint(namespace)
+			objectRef["namespace"] = lc.color(color.FgYellow).Sprint(namespace)
 		}
 		if name, ok := objectRef["name"].(string); ok {
-			objectRef["name"] = color.New(color.FgHiCyan).Sprint(name)
+			objectRef["name"] = lc.color(color.FgHiCyan).Sprint(name)
 		}
 	}
 
@@ -263,7 +436,7 @@ func (lc *LogColorizer) colorizeAuditJSON(auditData map[string]interface{}) stri
 		coloredIPs := make([]interface{}, len(sourceIPs))
 		for i, ip := range sourceIPs {
 			if ipStr, ok := ip.(string); ok {
-				coloredIPs[i] = color.New(color.FgHiYellow).Sprint(ipStr)
+				coloredIPs[i] = lc.color(color.FgHiYellow).Sprint(ipStr)
 			} else {
 				coloredIPs[i] = ip
 			}
@@ -273,12 +446,12 @@ func (lc *LogColorizer) colorizeAuditJSON(auditData map[string]interface{}) stri
 
 	// Handle audit level
 	if level, ok := coloredData["level"].(string); ok {
-		levelColor := color.New(color.FgBlue)
+		levelColor := lc.color(color.FgBlue)
 		switch level {
 		case "Request", "RequestResponse":
-			levelColor = color.New(color.FgGreen)
+			levelColor = lc.color(color.FgGreen)
 		case "Metadata":
-			levelColor = color.New(color.FgBlue)
+			levelColor = lc.color(color.FgBlue)
 		}
 		coloredData["level"] = levelColor.Sprint(level)
 	}
@@ -293,28 +466,28 @@ func (lc *LogColorizer) colorizeAuditJSON(auditData map[string]interface{}) stri
 
 		// Highlight error message
 		if errorMsg, ok := coloredStatus["message"].(string); ok {
-			coloredStatus["message"] = color.New(color.FgRed, color.Bold).Sprint(errorMsg)
+			coloredStatus["message"] = lc.color(color.FgRed, color.Bold).Sprint(errorMsg)
 		}
 
 		// Highlight error reason
 		if reason, ok := coloredStatus["reason"].(string); ok {
-			coloredStatus["reason"] = color.New(color.FgRed).Sprint(reason)
+			coloredStatus["reason"] = lc.color(color.FgRed).Sprint(reason)
 		}
 
 		// Highlight status field
 		if statusField, ok := coloredStatus["status"].(string); ok {
-			statusColor := color.New(color.FgGreen)
+			statusColor := lc.color(color.FgGreen)
 			if statusField == "Failure" {
-				statusColor = color.New(color.FgRed, color.Bold)
+				statusColor = lc.color(color.FgRed, color.Bold)
 			}
 			coloredStatus["status"] = statusColor.Sprint(statusField)
 		}
 
 		// Highlight status code
 		if code, ok := coloredStatus["code"].(float64); ok {
-			codeColor := color.New(color.FgGreen)
+			codeColor := lc.color(color.FgGreen)
 			if code >= 400 {
-				codeColor = color.New(color.FgRed, color.Bold)
+				codeColor = lc.color(color.FgRed, color.Bold)
 			}
 			coloredStatus["code"] = codeColor.Sprint(int(code))
 		}
@@ -326,11 +499,11 @@ func (lc *LogColorizer) colorizeAuditJSON(auditData map[string]interface{}) stri
 	// Convert the colored data back to a string
 	// We can't use json.Marshal because it would escape the ANSI color codes
 	// Instead, we'll build a custom string representation
-	return lc.formatColoredJSON(coloredData)
+	return lc.formatColoredJSON(coloredData, 0)
 }
 
 // formatColoredJSON formats a map as a JSON string, preserving ANSI color codes
-func (lc *LogColorizer) formatColoredJSON(data map[string]interface{}) string {
+func (lc *LogColorizer) formatColoredJSON(data map[string]interface{}, depth int) string {
 	var parts []string
 
 	// Sort keys for consistent output
@@ -342,15 +515,30 @@ func (lc *LogColorizer) formatColoredJSON(data map[string]interface{}) string {
 
 	for _, k := range keys {
 		v := data[k]
-		formattedValue := lc.formatJSONValue(v)
-		parts = append(parts, fmt.Sprintf(`"%s":%s`, k, formattedValue))
+		formattedValue := lc.formatJSONValue(v, depth+1)
+		if lc.pretty {
+			parts = append(parts, fmt.Sprintf(`"%s": %s`, k, formattedValue))
+		} else {
+			parts = append(parts, fmt.Sprintf(`"%s":%s`, k, formattedValue))
+		}
 	}
 
-	return "{" + strings.Join(parts, ",") + "}"
+	return lc.joinJSONParts("{", "}", parts, depth)
+}
+
+// joinJSONParts joins formatted object members or array elements,
+// adding newlines and indentation when pretty output is enabled
+func (lc *LogColorizer) joinJSONParts(open, close string, parts []string, depth int) string {
+	if !lc.pretty || len(parts) == 0 {
+		return open + strings.Join(parts, ",") + close
+	}
+
+	indent := strings.Repeat("  ", depth+1)
+	return open + "\n" + indent + strings.Join(parts, ",\n"+indent) + "\n" + strings.Repeat("  ", depth) + close
 }
 
 // formatJSONValue formats a value for JSON output, preserving ANSI color codes
-func (lc *LogColorizer) formatJSONValue(v interface{}) string {
+func (lc *LogColorizer) formatJSONValue(v interface{}, depth int) string {
 	switch val := v.(type) {
 	case string:
 		return fmt.Sprintf(`"%s"`, val)
@@ -363,13 +551,13 @@ func (lc *LogColorizer) formatJSONValue(v interface{}) string {
 	case nil:
 		return "null"
 	case map[string]interface{}:
-		return lc.formatColoredJSON(val)
+		return lc.formatColoredJSON(val, depth)
 	case []interface{}:
 		var parts []string
 		for _, item := range val {
-			parts = append(parts, lc.formatJSONValue(item))
+			parts = append(parts, lc.formatJSONValue(item, depth+1))
 		}
-		return "[" + strings.Join(parts, ",") + "]"
+		return lc.joinJSONParts("[", "]", parts, depth)
 	default:
 		// Fall back to standard JSON for unknown types
 		jsonBytes, err := json.Marshal(val)
@@ -380,243 +568,262 @@ func (lc *LogColorizer) formatJSONValue(v interface{}) string {
 	}
 }
 
-// colorizeAuthenticatorLog applies color formatting specific to authenticator logs
+// colorizeAuthenticatorLog applies color formatting specific to authenticator logs and returns the colored message
 func (lc *LogColorizer) colorizeAuthenticatorLog(entry LogEntry) string {
-	timestamp := color.New(color.FgHiBlack).SprintFunc()(entry.Timestamp.UTC().Format(time.RFC3339))
-	component := color.New(color.FgGreen).SprintFunc()(entry.Component)
-	level := getLevelColor(entry.Level).SprintFunc()(entry.Level)
-
 	message := entry.Message
 
 	// Highlight ARNs
-	arnPattern := regexp.MustCompile(`arn:aws:[a-zA-Z0-9-]+:[a-zA-Z0-9-]*:[0-9]+:[a-zA-Z0-9-:/]+`)
-	message = arnPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgYellow).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "arns") {
+		message = arnPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgYellow).Sprint(s)
+		})
+	}
 
 	// Highlight usernames
-	usernamePattern := regexp.MustCompile(`username="([^"]+)"`)
-	message = usernamePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "usernames") {
+		message = usernamePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight error messages and codes (only standalone words or specific patterns)
-	errorPattern := regexp.MustCompile(`\b(error|failed|failure|unable to|cannot|timeout|invalid|missing)\b|access (denied|granted)`)
-	message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		if strings.Contains(s, "granted") {
-			return color.New(color.FgGreen).Sprint(s)
-		}
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "errors") {
+		message = authErrorPattern.ReplaceAllStringFunc(message, func(s string) string {
+			if strings.Contains(s, "granted") {
+				return lc.color(color.FgGreen).Sprint(s)
+			}
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
 	// Highlight AWS error codes (handle escaped quotes)
-	awsErrorPattern := regexp.MustCompile(`\\"Code\\":\\"([^"]+)\\"`)
-	message = awsErrorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed, color.Bold).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "aws-error-codes") {
+		message = awsErrorCodePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed, color.Bold).Sprint(s)
+		})
+	}
 
 	// Highlight AWS error types (handle escaped quotes)
-	awsErrorTypePattern := regexp.MustCompile(`\\"Type\\":\\"([^"]+)\\"`)
-	message = awsErrorTypePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "aws-error-types") {
+		message = awsErrorTypePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
 	// Highlight HTTP status codes
-	httpStatusPattern := regexp.MustCompile(`\b(200|201|204|400|401|403|404|500|502|503)\b`)
-	message = httpStatusPattern.ReplaceAllStringFunc(message, func(s string) string {
-		statusCode := s
-		statusColor := color.New(color.FgGreen)
-		if statusCode[0] == '4' || statusCode[0] == '5' {
-			statusColor = color.New(color.FgRed, color.Bold)
-		}
-		return statusColor.Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "http-status") {
+		message = httpStatusPattern.ReplaceAllStringFunc(message, func(s string) string {
+			statusCode := s
+			statusColor := lc.color(color.FgGreen)
+			if statusCode[0] == '4' || statusCode[0] == '5' {
+				statusColor = lc.color(color.FgRed, color.Bold)
+			}
+			return statusColor.Sprint(s)
+		})
+	}
 
 	// Highlight IP addresses and ports
-	ipPattern := regexp.MustCompile(`\b(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}):(\d+)\b`)
-	message = ipPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgHiYellow).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "ip-addresses") {
+		message = ipPortPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgHiYellow).Sprint(s)
+		})
+	}
 
 	// Highlight HTTP methods
-	methodPattern := regexp.MustCompile(`method=(GET|POST|PUT|DELETE|PATCH)`)
-	message = methodPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgMagenta).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "http-methods") {
+		message = httpMethodPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgMagenta).Sprint(s)
+		})
+	}
 
 	// Highlight paths
-	pathPattern := regexp.MustCompile(`path=(/[^\s]*)`)
-	message = pathPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "paths") {
+		message = httpPathPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight log levels in the message
-	levelPattern := regexp.MustCompile(`level=(debug|info|warning|error|fatal)`)
-	message = levelPattern.ReplaceAllStringFunc(message, func(s string) string {
-		levelStr := strings.Split(s, "=")[1]
-		levelColor := getLevelColor(levelStr)
-		return fmt.Sprintf("level=%s", levelColor.Sprint(levelStr))
-	})
+	if lc.ruleEnabled("authenticator", "levels") {
+		message = logfmtLevelPattern.ReplaceAllStringFunc(message, func(s string) string {
+			levelStr := strings.Split(s, "=")[1]
+			levelColor := lc.getLevelColor(levelStr)
+			return fmt.Sprintf("level=%s", levelColor.Sprint(levelStr))
+		})
+	}
 
-	return fmt.Sprintf("%s [%s] [%s] %s", timestamp, level, component, message)
+	return message
 }
 
-// colorizeControllerManagerLog applies color formatting specific to controller manager logs
+// colorizeControllerManagerLog applies color formatting specific to controller manager logs and returns the colored message
 func (lc *LogColorizer) colorizeControllerManagerLog(entry LogEntry) string {
-	timestamp := color.New(color.FgHiBlack).SprintFunc()(entry.Timestamp.UTC().Format(time.RFC3339))
-	component := color.New(color.FgGreen).SprintFunc()(entry.Component)
-	level := getLevelColor(entry.Level).SprintFunc()(entry.Level)
-
 	message := entry.Message
 
 	// Highlight controller names
-	controllerPattern := regexp.MustCompile(`\b([a-zA-Z0-9-]+)_controller\b`)
-	message = controllerPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgMagenta).Sprint(s)
-	})
+	if lc.ruleEnabled("kcm", "controllers") {
+		message = controllerPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgMagenta).Sprint(s)
+		})
+	}
 
 	// Highlight resource names
-	resourcePattern := regexp.MustCompile(`(pod|node|service|deployment|daemonset|statefulset|configmap|secret|namespace)/([a-zA-Z0-9-_.]+)`)
-	message = resourcePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("kcm", "resources") {
+		message = resourcePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight error messages
-	errorPattern := regexp.MustCompile(`(error|failed|failure|unable to|cannot|timeout)`)
-	message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("kcm", "errors") {
+		message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
-	return fmt.Sprintf("%s [%s] [%s] %s", timestamp, level, component, message)
+	return message
 }
 
-// colorizeCloudControllerManagerLog applies color formatting specific to cloud controller manager logs
+// colorizeCloudControllerManagerLog applies color formatting specific to cloud controller manager logs and returns the colored message
 func (lc *LogColorizer) colorizeCloudControllerManagerLog(entry LogEntry) string {
-	timestamp := color.New(color.FgHiBlack).SprintFunc()(entry.Timestamp.UTC().Format(time.RFC3339))
-	component := color.New(color.FgGreen).SprintFunc()(entry.Component)
-	level := getLevelColor(entry.Level).SprintFunc()(entry.Level)
-
 	message := entry.Message
 
 	// Highlight AWS resource IDs
-	awsResourcePattern := regexp.MustCompile(`\b(vpc-|subnet-|sg-|i-|vol-|rtb-|igw-|nat-|eni-|eip-|acl-)[a-f0-9]+\b`)
-	message = awsResourcePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("ccm", "aws-resources") {
+		message = awsResourcePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight controller names
-	controllerPattern := regexp.MustCompile(`\b([a-zA-Z0-9-]+)_controller\b`)
-	message = controllerPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgMagenta).Sprint(s)
-	})
+	if lc.ruleEnabled("ccm", "controllers") {
+		message = controllerPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgMagenta).Sprint(s)
+		})
+	}
 
 	// Highlight error messages
-	errorPattern := regexp.MustCompile(`(error|failed|failure|unable to|cannot|timeout)`)
-	message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("ccm", "errors") {
+		message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
-	return fmt.Sprintf("%s [%s] [%s] %s", timestamp, level, component, message)
+	return message
 }
 
-// colorizeSchedulerLog applies color formatting specific to scheduler logs
+// colorizeSchedulerLog applies color formatting specific to scheduler logs and returns the colored message
 func (lc *LogColorizer) colorizeSchedulerLog(entry LogEntry) string {
-	timestamp := color.New(color.FgHiBlack).SprintFunc()(entry.Timestamp.UTC().Format(time.RFC3339))
-	component := color.New(color.FgGreen).SprintFunc()(entry.Component)
-	level := getLevelColor(entry.Level).SprintFunc()(entry.Level)
-
 	message := entry.Message
 
 	// Highlight scheduling related keywords
-	schedPattern := regexp.MustCompile(`\b(schedule|scheduling|scheduled|unschedulable|predicates|priorities|binding|bound)\b`)
-	message = schedPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgMagenta).Sprint(s)
-	})
+	if lc.ruleEnabled("scheduler", "keywords") {
+		message = schedulerKeywordPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgMagenta).Sprint(s)
+		})
+	}
 
 	// Highlight pod names
-	podPattern := regexp.MustCompile(`pod/([a-zA-Z0-9-_.]+)`)
-	message = podPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("scheduler", "pods") {
+		message = podPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight node names
-	nodePattern := regexp.MustCompile(`node/([a-zA-Z0-9-_.]+)`)
-	message = nodePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgYellow).Sprint(s)
-	})
+	if lc.ruleEnabled("scheduler", "nodes") {
+		message = nodePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgYellow).Sprint(s)
+		})
+	}
 
-	return fmt.Sprintf("%s [%s] [%s] %s", timestamp, level, component, message)
+	return message
 }
 
-// colorizeDefaultLog applies default color formatting to logs
-func (lc *LogColorizer) colorizeDefaultLog(entry LogEntry) string {
-	timestamp := color.New(color.FgHiBlack).SprintFunc()(entry.Timestamp.UTC().Format(time.RFC3339))
-	component := color.New(color.FgGreen).SprintFunc()(entry.Component)
-	level := getLevelColor(entry.Level).SprintFunc()(entry.Level)
+// color returns the color of the given attributes using the configured theme. Colors are
+// built once and reused, as highlighting calls this for every match.
+func (lc *LogColorizer) color(attributes ...color.Attribute) *color.Color {
+	key := colorKey{n: len(attributes)}
+	if key.n > len(key.attributes) {
+		return lc.config.Theme.Color(attributes...)
+	}
+	copy(key.attributes[:], attributes)
+	if cached, ok := lc.colors.Load(key); ok {
+		return cached.(*color.Color)
+	}
+	c, _ := lc.colors.LoadOrStore(key, lc.config.Theme.Color(attributes...))
+	return c.(*color.Color)
+}
 
-	return fmt.Sprintf("%s [%s] [%s] %s", timestamp, level, component, entry.Message)
+// colorizeDefaultLog returns the message of logs without a type-specific color scheme
+func (lc *LogColorizer) colorizeDefaultLog(entry LogEntry) string {
+	return entry.Message
 }
 
 // getLevelColor returns the appropriate color for a log level
-func getLevelColor(level string) *color.Color {
+func (lc *LogColorizer) getLevelColor(level string) *color.Color {
 	switch strings.ToLower(level) {
 	case "info":
-		return color.New(color.FgBlue)
+		return lc.color(color.FgBlue)
 	case "warning", "warn":
-		return color.New(color.FgYellow)
+		return lc.color(color.FgYellow)
 	case "error", "err":
-		return color.New(color.FgRed)
+		return lc.color(color.FgRed)
 	case "fatal", "crit":
-		return color.New(color.FgHiRed)
+		return lc.color(color.FgHiRed)
 	default:
-		return color.New()
+		return lc.color()
 	}
 }
 
 // ColorizeMessageOnly applies color formatting to just the message part based on log type
 func (lc *LogColorizer) ColorizeMessageOnly(message string, logType string, level string) string {
-	if !lc.config.ShouldUseColor() {
+	if !lc.useColor {
 		return message
 	}
 
 	// Apply color based on log type
 	switch logType {
 	case "api":
-		return lc.colorizeAPIMessage(message, level)
+		message = lc.colorizeAPIMessage(message, level)
 	case "audit":
-		return lc.colorizeAuditMessage(message, level)
+		message = lc.colorizeAuditMessage(message, level)
 	case "authenticator":
-		return lc.colorizeAuthenticatorMessage(message, level)
+		message = lc.colorizeAuthenticatorMessage(message, level)
 	case "kcm":
-		return lc.colorizeControllerManagerMessage(message, level)
+		message = lc.colorizeControllerManagerMessage(message, level)
 	case "ccm":
-		return lc.colorizeCloudControllerManagerMessage(message, level)
+		message = lc.colorizeCloudControllerManagerMessage(message, level)
 	case "scheduler":
-		return lc.colorizeSchedulerMessage(message, level)
+		message = lc.colorizeSchedulerMessage(message, level)
 	default:
-		return lc.colorizeDefaultMessage(message, level)
+		message = lc.colorizeDefaultMessage(message, level)
 	}
+
+	return lc.applyCustomRules(logType, message)
 }
 
 // colorizeAPIMessage applies color formatting specific to API server messages
 func (lc *LogColorizer) colorizeAPIMessage(message string, level string) string {
 	// Highlight error messages
-	errorPattern := regexp.MustCompile(`(error|failed|failure|unable to|cannot|timeout)`)
-	message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "errors") {
+		message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
 	// Highlight resource names
-	resourcePattern := regexp.MustCompile(`(pod|node|service|deployment|daemonset|statefulset|configmap|secret|namespace)/([a-zA-Z0-9-_.]+)`)
-	message = resourcePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "resources") {
+		message = resourcePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight success messages
-	successPattern := regexp.MustCompile(`(success|successfully|created|updated|deleted)`)
-	message = successPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgGreen).Sprint(s)
-	})
+	if lc.ruleEnabled("api", "success") {
+		message = successPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgGreen).Sprint(s)
+		})
+	}
 
 	return message
 }
@@ -624,7 +831,7 @@ func (lc *LogColorizer) colorizeAPIMessage(message string, level string) string
 // colorizeAuditMessage applies color formatting specific to audit messages
 func (lc *LogColorizer) colorizeAuditMessage(message string, level string) string {
 	// For audit logs, try to parse the JSON and highlight specific fields
-	if strings.HasPrefix(strings.TrimSpace(message), "{") {
+	if lc.ruleEnabled("audit", "json") && strings.HasPrefix(strings.TrimSpace(message), "{") {
 		var auditData map[string]interface{}
 		err := json.Unmarshal([]byte(message), &auditData)
 		if err == nil {
@@ -639,25 +846,28 @@ func (lc *LogColorizer) colorizeAuditMessage(message string, level string) strin
 // colorizeAuthenticatorMessage applies color formatting specific to authenticator messages
 func (lc *LogColorizer) colorizeAuthenticatorMessage(message string, level string) string {
 	// Highlight ARNs
-	arnPattern := regexp.MustCompile(`arn:aws:[a-zA-Z0-9-]+:[a-zA-Z0-9-]*:[0-9]+:[a-zA-Z0-9-:/]+`)
-	message = arnPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgYellow).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "arns") {
+		message = arnPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgYellow).Sprint(s)
+		})
+	}
 
 	// Highlight access granted/denied
-	accessPattern := regexp.MustCompile(`access (granted|denied)`)
-	message = accessPattern.ReplaceAllStringFunc(message, func(match string) string {
-		if strings.Contains(match, "granted") {
-			return color.New(color.FgGreen).Sprint(match)
-		}
-		return color.New(color.FgRed).Sprint(match)
-	})
+	if lc.ruleEnabled("authenticator", "access") {
+		message = accessPattern.ReplaceAllStringFunc(message, func(match string) string {
+			if strings.Contains(match, "granted") {
+				return lc.color(color.FgGreen).Sprint(match)
+			}
+			return lc.color(color.FgRed).Sprint(match)
+		})
+	}
 
 	// Highlight usernames
-	usernamePattern := regexp.MustCompile(`username="([^"]+)"`)
-	message = usernamePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("authenticator", "usernames") {
+		message = usernamePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	return message
 }
@@ -665,22 +875,25 @@ func (lc *LogColorizer) colorizeAuthenticatorMessage(message string, level strin
 // colorizeControllerManagerMessage applies color formatting specific to controller manager messages
 func (lc *LogColorizer) colorizeControllerManagerMessage(message string, level string) string {
 	// Highlight controller names
-	controllerPattern := regexp.MustCompile(`\b([a-zA-Z0-9-]+)_controller\b`)
-	message = controllerPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgMagenta).Sprint(s)
-	})
+	if lc.ruleEnabled("kcm", "controllers") {
+		message = controllerPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgMagenta).Sprint(s)
+		})
+	}
 
 	// Highlight resource names
-	resourcePattern := regexp.MustCompile(`(pod|node|service|deployment|daemonset|statefulset|configmap|secret|namespace)/([a-zA-Z0-9-_.]+)`)
-	message = resourcePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("kcm", "resources") {
+		message = resourcePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight error messages
-	errorPattern := regexp.MustCompile(`(error|failed|failure|unable to|cannot|timeout)`)
-	message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("kcm", "errors") {
+		message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
 	return message
 }
@@ -688,22 +901,25 @@ func (lc *LogColorizer) colorizeControllerManagerMessage(message string, level s
 // colorizeCloudControllerManagerMessage applies color formatting specific to cloud controller manager messages
 func (lc *LogColorizer) colorizeCloudControllerManagerMessage(message string, level string) string {
 	// Highlight AWS resource IDs
-	awsResourcePattern := regexp.MustCompile(`\b(vpc-|subnet-|sg-|i-|vol-|rtb-|igw-|nat-|eni-|eip-|acl-)[a-f0-9]+\b`)
-	message = awsResourcePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("ccm", "aws-resources") {
+		message = awsResourcePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight controller names
-	controllerPattern := regexp.MustCompile(`\b([a-zA-Z0-9-]+)_controller\b`)
-	message = controllerPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgMagenta).Sprint(s)
-	})
+	if lc.ruleEnabled("ccm", "controllers") {
+		message = controllerPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgMagenta).Sprint(s)
+		})
+	}
 
 	// Highlight error messages
-	errorPattern := regexp.MustCompile(`(error|failed|failure|unable to|cannot|timeout)`)
-	message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("ccm", "errors") {
+		message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
 	return message
 }
@@ -711,22 +927,25 @@ func (lc *LogColorizer) colorizeCloudControllerManagerMessage(message string, le
 // colorizeSchedulerMessage applies color formatting specific to scheduler messages
 func (lc *LogColorizer) colorizeSchedulerMessage(message string, level string) string {
 	// Highlight scheduling related keywords
-	schedPattern := regexp.MustCompile(`\b(schedule|scheduling|scheduled|unschedulable|predicates|priorities|binding|bound)\b`)
-	message = schedPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgMagenta).Sprint(s)
-	})
+	if lc.ruleEnabled("scheduler", "keywords") {
+		message = schedulerKeywordPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgMagenta).Sprint(s)
+		})
+	}
 
 	// Highlight pod names
-	podPattern := regexp.MustCompile(`pod/([a-zA-Z0-9-_.]+)`)
-	message = podPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgCyan).Sprint(s)
-	})
+	if lc.ruleEnabled("scheduler", "pods") {
+		message = podPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgCyan).Sprint(s)
+		})
+	}
 
 	// Highlight node names
-	nodePattern := regexp.MustCompile(`node/([a-zA-Z0-9-_.]+)`)
-	message = nodePattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgYellow).Sprint(s)
-	})
+	if lc.ruleEnabled("scheduler", "nodes") {
+		message = nodePattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgYellow).Sprint(s)
+		})
+	}
 
 	return message
 }
@@ -734,16 +953,18 @@ func (lc *LogColorizer) colorizeSchedulerMessage(message string, level string) s
 // colorizeDefaultMessage applies default color formatting to messages
 func (lc *LogColorizer) colorizeDefaultMessage(message string, level string) string {
 	// Highlight error messages
-	errorPattern := regexp.MustCompile(`(error|failed|failure|unable to|cannot|timeout)`)
-	message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgRed).Sprint(s)
-	})
+	if lc.ruleEnabled("default", "errors") {
+		message = errorPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgRed).Sprint(s)
+		})
+	}
 
 	// Highlight success messages
-	successPattern := regexp.MustCompile(`(success|successfully|created|updated|deleted)`)
-	message = successPattern.ReplaceAllStringFunc(message, func(s string) string {
-		return color.New(color.FgGreen).Sprint(s)
-	})
+	if lc.ruleEnabled("default", "success") {
+		message = successPattern.ReplaceAllStringFunc(message, func(s string) string {
+			return lc.color(color.FgGreen).Sprint(s)
+		})
+	}
 
 	return message
 }
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldUseColorEnvironment(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     ColorMode
+		env      map[string]string
+		expected bool
+	}{
+		{name: "auto without terminal", mode: ColorModeAuto, expected: false},
+		{name: "NO_COLOR", mode: ColorModeAuto, env: map[string]string{"NO_COLOR": "1", "CLICOLOR_FORCE": "1"}, expected: false},
+		{name: "CLICOLOR_FORCE", mode: ColorModeAuto, env: map[string]string{"CLICOLOR_FORCE": "1"}, expected: true},
+		{name: "CLICOLOR_FORCE=0", mode: ColorModeAuto, env: map[string]string{"CLICOLOR_FORCE": "0"}, expected: false},
+		{name: "EKSLOGS_COLOR=always overrides NO_COLOR", mode: ColorModeAuto, env: map[string]string{"EKSLOGS_COLOR": "always", "NO_COLOR": "1"}, expected: true},
+		{name: "EKSLOGS_COLOR=never", mode: ColorModeAuto, env: map[string]string{"EKSLOGS_COLOR": "never", "CLICOLOR_FORCE": "1"}, expected: false},
+		{name: "explicit always ignores NO_COLOR", mode: ColorModeAlways, env: map[string]string{"NO_COLOR": "1"}, expected: true},
+		{name: "explicit never ignores CLICOLOR_FORCE", mode: ColorModeNever, env: map[string]string{"CLICOLOR_FORCE": "1"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"EKSLOGS_COLOR", "NO_COLOR", "CLICOLOR_FORCE"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			config := &ColorConfig{Mode: tt.mode}
+			assert.Equal(t, tt.expected, config.ShouldUseColor())
+		})
+	}
+}
+
+// TestColorizerResolvesColorOnce tests that the color decision and the colors are built once
+func TestColorizerResolvesColorOnce(t *testing.T) {
+	t.Setenv("CLICOLOR_FORCE", "1")
+	colorizer := NewLogColorizer(&ColorConfig{Mode: ColorModeAuto})
+	assert.True(t, colorizer.useColor)
+
+	assert.Same(t, colorizer.color(color.FgRed, color.Bold), colorizer.color(color.FgRed, color.Bold))
+	assert.NotSame(t, colorizer.color(color.FgRed), colorizer.color(color.FgRed, color.Bold))
+}
+
+// TestColorizeComponentPerLogType tests that the component column has a color per log type
+func TestColorizeComponentPerLogType(t *testing.T) {
+	original := color.NoColor
+	defer func() { color.NoColor = original }()
+	colorizer := NewLogColorizer(&ColorConfig{Mode: ColorModeAlways})
+
+	api := colorizer.ColorizeLog(LogEntry{Component: "kube-apiserver", LogStream: "kube-apiserver-abc"})
+	audit := colorizer.ColorizeLog(LogEntry{Component: "kube-apiserver-audit", LogStream: "kube-apiserver-audit-abc"})
+	scheduler := colorizer.ColorizeLog(LogEntry{Component: "kube-scheduler", LogStream: "kube-scheduler-abc"})
+	assert.Contains(t, api, color.New(color.FgGreen).Sprint("kube-apiserver"))
+	assert.Contains(t, audit, color.New(color.FgBlue).Sprint("kube-apiserver-audit"))
+	assert.Contains(t, scheduler, color.New(color.FgMagenta).Sprint("kube-scheduler"))
+	assert.Equal(t, color.FgGreen, logTypeColor("unknown"))
+}
+
+func TestLabelColor(t *testing.T) {
+	assert.Equal(t, LabelColor("prod/prod-cluster"), LabelColor("prod/prod-cluster"))
+	seen := make(map[color.Attribute]bool)
+	for _, label := range []string{"prod/a", "prod/b", "staging/a", "staging/b", "dev/a"} {
+		seen[LabelColor(label)] = true
+	}
+	assert.Greater(t, len(seen), 1)
+}
+
+func benchmarkEntries() []LogEntry {
+	return []LogEntry{
+		{
+			Level:     "error",
+			Component: "kube-apiserver",
+			LogStream: "kube-apiserver-123456",
+			Message:   "E0719 06:09:10.476002 1 controller.go:123] failed to sync pod/nginx-7d4b9 in namespace/default: timeout",
+		},
+		{
+			Level:     "info",
+			Component: "aws-iam-authenticator",
+			LogStream: "authenticator-123456",
+			Message:   `time="2024-07-19T06:09:10Z" level=info msg="access granted" arn="arn:aws:iam::123456789012:role/admin" client="10.0.0.1:443" method=POST path=/authenticate username="kubernetes-admin"`,
+		},
+		{
+			Level:     "info",
+			Component: "kube-scheduler",
+			LogStream: "kube-scheduler-123456",
+			Message:   "I0719 06:09:10.476002 1 schedule_one.go:252] Successfully bound pod/nginx-7d4b9 to node/ip-10-0-0-1",
+		},
+	}
+}
+
+func BenchmarkColorizeLog(b *testing.B) {
+	colorizer := NewLogColorizer(&ColorConfig{Mode: ColorModeAlways})
+	entries := benchmarkEntries()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = colorizer.colorizeLog(entries[i%len(entries)], nil)
+	}
+}
+
+func BenchmarkColorizeMessageOnly(b *testing.B) {
+	colorizer := NewLogColorizer(&ColorConfig{Mode: ColorModeAlways})
+	entries := benchmarkEntries()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entry := entries[i%len(entries)]
+		_ = colorizer.ColorizeMessageOnly(entry.Message, NormalizeLogType(ExtractLogTypeFromStreamName(entry.LogStream)), entry.Level)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package log
+
+import "os"
+
+// enableVirtualTerminal reports whether the terminal of file interprets the ANSI escape
+// sequences of colors, which every terminal but the Windows console does
+func enableVirtualTerminal(file *os.File) bool {
+	return true
+}
+
+// syncOutput flushes output written to file, so it shows immediately when piped
+func syncOutput(file *os.File) {
+	_ = file.Sync()
+}
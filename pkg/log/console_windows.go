@@ -0,0 +1,27 @@
+package log
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVirtualTerminal makes a Windows console interpret the ANSI escape sequences of colors
+// written to file, which older consoles print as garbage otherwise. It reports whether the
+// console supports them; files that are not consoles, such as pipes, are left alone and
+// reported as supporting them, as the reading program interprets the sequences.
+func enableVirtualTerminal(file *os.File) bool {
+	handle := windows.Handle(file.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(handle, &mode); err != nil {
+		return true
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(handle, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
+
+// syncOutput does nothing on Windows: writes to os.Stdout are not buffered, and flushing a
+// pipe blocks until the reading program has read everything written to it
+func syncOutput(file *os.File) {}
@@ -0,0 +1,21 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEnableVirtualTerminalFile tests that files which are not consoles are left alone
+func TestEnableVirtualTerminalFile(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out.log"))
+	assert.NoError(t, err)
+	defer func() { _ = file.Close() }()
+
+	assert.True(t, enableVirtualTerminal(file))
+	_, err = file.WriteString("\x1b[31mred\x1b[0m\n")
+	assert.NoError(t, err)
+	syncOutput(file)
+}
@@ -0,0 +1,238 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+const (
+	// OutputFormatMarkdown renders a report as a Markdown document
+	OutputFormatMarkdown OutputFormat = "markdown"
+	// OutputFormatHTML renders a report as a self-contained HTML page
+	OutputFormatHTML OutputFormat = "html"
+)
+
+// ParseReportFormat validates the output format of a report, which can also be a Markdown
+// or HTML document
+func ParseReportFormat(format string) (OutputFormat, error) {
+	switch OutputFormat(format) {
+	case OutputFormatMarkdown:
+		return OutputFormatMarkdown, nil
+	case OutputFormatHTML:
+		return OutputFormatHTML, nil
+	}
+	parsed, err := ParseOutputFormat(format)
+	if err != nil {
+		return "", fmt.Errorf("invalid output format '%s' (supported: text, json, markdown, html)", format)
+	}
+	return parsed, nil
+}
+
+// IsDocument reports whether the format renders a report as a document
+func (f OutputFormat) IsDocument() bool {
+	return f == OutputFormatMarkdown || f == OutputFormatHTML
+}
+
+// documentStyle is the stylesheet of HTML documents, kept inline so the page can be
+// attached to an incident as a single file
+const documentStyle = `body{font-family:-apple-system,"Segoe UI",Helvetica,Arial,sans-serif;margin:2em;color:#1f2328}
+table{border-collapse:collapse;margin:1em 0}
+th,td{border:1px solid #d0d7de;padding:4px 8px;text-align:left;vertical-align:top}
+th{background:#f6f8fa}
+pre{background:#f6f8fa;padding:1em;overflow-x:auto}`
+
+// Document renders the output of a report as a Markdown or HTML document, for pasting into
+// incident write-ups. Text written to it becomes code blocks, keeping the alignment of
+// histograms and timelines, and the tab-separated rows written to a Table become tables.
+// Nothing is written before Close.
+type Document struct {
+	w       io.Writer
+	format  OutputFormat
+	title   string
+	details []string
+	blocks  []documentBlock
+	line    []byte // text written after the last newline
+}
+
+// documentBlock is a code block of text lines, or a table when rows is set
+type documentBlock struct {
+	lines []string
+	rows  [][]string
+}
+
+// NewDocument returns a document with a title and a list of details, such as the cluster
+// and time range of the report, written to w in format by Close
+func NewDocument(w io.Writer, format OutputFormat, title string, details ...string) *Document {
+	return &Document{w: w, format: format, title: title, details: details}
+}
+
+// Write adds text to the document. Consecutive lines form a code block.
+func (d *Document) Write(p []byte) (int, error) {
+	d.line = append(d.line, p...)
+	for {
+		i := bytes.IndexByte(d.line, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		d.addLine(string(d.line[:i]))
+		d.line = d.line[i+1:]
+	}
+}
+
+// addLine appends a line of text to the last code block, starting one if needed
+func (d *Document) addLine(line string) {
+	if len(d.blocks) == 0 || d.blocks[len(d.blocks)-1].rows != nil {
+		d.blocks = append(d.blocks, documentBlock{})
+	}
+	block := &d.blocks[len(d.blocks)-1]
+	block.lines = append(block.lines, strings.TrimRight(line, " \t\r"))
+}
+
+// Table returns a writer of tab-separated rows, the first being the header, added to the
+// document as a table when flushed, like a tabwriter.Writer
+func (d *Document) Table() *DocumentTable {
+	return &DocumentTable{document: d}
+}
+
+// DocumentTable collects the rows of a table of a Document
+type DocumentTable struct {
+	document *Document
+	buf      bytes.Buffer
+}
+
+// Write adds tab-separated rows to the table
+func (t *DocumentTable) Write(p []byte) (int, error) {
+	return t.buf.Write(p)
+}
+
+// Flush adds the rows written so far to the document as a table
+func (t *DocumentTable) Flush() error {
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimRight(t.buf.String(), "\n"), "\n") {
+		if line != "" {
+			rows = append(rows, strings.Split(line, "\t"))
+		}
+	}
+	t.buf.Reset()
+	if len(rows) > 0 {
+		t.document.blocks = append(t.document.blocks, documentBlock{rows: rows})
+	}
+	return nil
+}
+
+// Close writes the document
+func (d *Document) Close() error {
+	if len(d.line) > 0 {
+		d.addLine(string(d.line))
+		d.line = nil
+	}
+	var buf bytes.Buffer
+	if d.format == OutputFormatHTML {
+		d.renderHTML(&buf)
+	} else {
+		d.renderMarkdown(&buf)
+	}
+	_, err := d.w.Write(buf.Bytes())
+	return err
+}
+
+// renderMarkdown writes the document as Markdown: the title as a heading, the details as a
+// list, text as fenced code blocks and tables as pipe tables
+func (d *Document) renderMarkdown(buf *bytes.Buffer) {
+	fmt.Fprintf(buf, "# %s\n", d.title)
+	if len(d.details) > 0 {
+		buf.WriteString("\n")
+		for _, detail := range d.details {
+			fmt.Fprintf(buf, "- %s\n", detail)
+		}
+	}
+	for _, block := range d.blocks {
+		if block.rows != nil {
+			buf.WriteString("\n")
+			for i, row := range block.rows {
+				cells := make([]string, len(row))
+				for j, cell := range row {
+					cells[j] = strings.ReplaceAll(strings.TrimSpace(cell), "|", `\|`)
+				}
+				fmt.Fprintf(buf, "| %s |\n", strings.Join(cells, " | "))
+				if i == 0 {
+					fmt.Fprintf(buf, "|%s\n", strings.Repeat(" --- |", len(row)))
+				}
+			}
+			continue
+		}
+		lines := trimBlankLines(block.lines)
+		if len(lines) == 0 {
+			continue
+		}
+		fence := codeFence(lines)
+		fmt.Fprintf(buf, "\n%s\n%s\n%s\n", fence, strings.Join(lines, "\n"), fence)
+	}
+}
+
+// renderHTML writes the document as an HTML page with an inline stylesheet
+func (d *Document) renderHTML(buf *bytes.Buffer) {
+	title := html.EscapeString(d.title)
+	fmt.Fprintf(buf, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n<h1>%s</h1>\n", title, documentStyle, title)
+	if len(d.details) > 0 {
+		buf.WriteString("<ul>\n")
+		for _, detail := range d.details {
+			fmt.Fprintf(buf, "<li>%s</li>\n", html.EscapeString(detail))
+		}
+		buf.WriteString("</ul>\n")
+	}
+	for _, block := range d.blocks {
+		if block.rows != nil {
+			buf.WriteString("<table>\n")
+			for i, row := range block.rows {
+				tag := "td"
+				if i == 0 {
+					tag = "th"
+					buf.WriteString("<thead>\n")
+				} else if i == 1 {
+					buf.WriteString("<tbody>\n")
+				}
+				buf.WriteString("<tr>")
+				for _, cell := range row {
+					fmt.Fprintf(buf, "<%s>%s</%s>", tag, html.EscapeString(strings.TrimSpace(cell)), tag)
+				}
+				buf.WriteString("</tr>\n")
+				if i == 0 {
+					buf.WriteString("</thead>\n")
+				}
+			}
+			if len(block.rows) > 1 {
+				buf.WriteString("</tbody>\n")
+			}
+			buf.WriteString("</table>\n")
+			continue
+		}
+		if lines := trimBlankLines(block.lines); len(lines) > 0 {
+			fmt.Fprintf(buf, "<pre>%s</pre>\n", html.EscapeString(strings.Join(lines, "\n")))
+		}
+	}
+	buf.WriteString("</body>\n</html>\n")
+}
+
+// trimBlankLines removes the blank lines at the start and end of a code block
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// codeFence returns a Markdown code fence longer than any run of backticks in lines
+func codeFence(lines []string) string {
+	fence := "```"
+	for strings.Contains(strings.Join(lines, "\n"), fence) {
+		fence += "`"
+	}
+	return fence
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReportFormat(t *testing.T) {
+	format, err := ParseReportFormat("markdown")
+	assert.NoError(t, err)
+	assert.True(t, format.IsDocument())
+
+	format, err = ParseReportFormat("html")
+	assert.NoError(t, err)
+	assert.Equal(t, OutputFormatHTML, format)
+
+	format, err = ParseReportFormat("")
+	assert.NoError(t, err)
+	assert.Equal(t, OutputFormatText, format)
+	assert.False(t, format.IsDocument())
+
+	_, err = ParseReportFormat("pdf")
+	assert.ErrorContains(t, err, "markdown, html")
+	_, err = ParseOutputFormat("markdown")
+	assert.Error(t, err, "log entries cannot be written as a document")
+}
+
+// writeSampleReport writes a report made of a summary line, a table and a histogram
+func writeSampleReport(doc *Document) {
+	_, _ = fmt.Fprintln(doc, "shop/web (2 decisions)")
+	table := doc.Table()
+	_, _ = fmt.Fprintln(table, "TIME\tKIND\tDETAILS")
+	_, _ = fmt.Fprintln(table, "10:00\thpa rescale\t3 -> 5 replicas")
+	_, _ = fmt.Fprintln(table, "10:05\teviction\tweb-1 | <node-a>")
+	_ = table.Flush()
+	_, _ = fmt.Fprintln(doc)
+	_, _ = fmt.Fprint(doc, "10:00  latency    3  ###\n10:05  latency    1  #")
+}
+
+func TestDocumentMarkdown(t *testing.T) {
+	var out bytes.Buffer
+	doc := NewDocument(&out, OutputFormatMarkdown, "ekslogs scaling my-cluster", "Cluster: my-cluster")
+	writeSampleReport(doc)
+	assert.Empty(t, out.String(), "nothing is written before Close")
+	assert.NoError(t, doc.Close())
+
+	assert.Equal(t, "# ekslogs scaling my-cluster\n\n"+
+		"- Cluster: my-cluster\n\n"+
+		"```\nshop/web (2 decisions)\n```\n\n"+
+		"| TIME | KIND | DETAILS |\n"+
+		"| --- | --- | --- |\n"+
+		"| 10:00 | hpa rescale | 3 -> 5 replicas |\n"+
+		"| 10:05 | eviction | web-1 \\| <node-a> |\n\n"+
+		"```\n10:00  latency    3  ###\n10:05  latency    1  #\n```\n", out.String())
+}
+
+func TestDocumentHTML(t *testing.T) {
+	var out bytes.Buffer
+	doc := NewDocument(&out, OutputFormatHTML, "ekslogs scaling <my-cluster>")
+	writeSampleReport(doc)
+	assert.NoError(t, doc.Close())
+
+	page := out.String()
+	assert.Contains(t, page, "<!DOCTYPE html>")
+	assert.Contains(t, page, "<style>")
+	assert.Contains(t, page, "<h1>ekslogs scaling &lt;my-cluster&gt;</h1>")
+	assert.Contains(t, page, "<pre>shop/web (2 decisions)</pre>")
+	assert.Contains(t, page, "<thead>\n<tr><th>TIME</th><th>KIND</th><th>DETAILS</th></tr>\n</thead>\n<tbody>\n")
+	assert.Contains(t, page, "<tr><td>10:05</td><td>eviction</td><td>web-1 | &lt;node-a&gt;</td></tr>\n</tbody>\n</table>")
+	assert.Contains(t, page, "<pre>10:00  latency    3  ###\n10:05  latency    1  #</pre>")
+	assert.NotContains(t, page, "<ul>", "no details")
+}
+
+func TestCodeFence(t *testing.T) {
+	assert.Equal(t, "```", codeFence([]string{"plain"}))
+	assert.Equal(t, "````", codeFence([]string{"a ``` fence"}))
+}
@@ -0,0 +1,106 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// formatFields maps the placeholders accepted by --format to column names
+var formatFields = map[string]string{
+	"time":       "time",
+	"timestamp":  "time",
+	"type":       "type",
+	"level":      "level",
+	"component":  "component",
+	"msg":        "msg",
+	"message":    "msg",
+	"log_group":  "log_group",
+	"log_stream": "log_stream",
+	"lag":        "lag",
+}
+
+// extraColumns lists the optional columns shown by the default line layout, in order
+var extraColumns = []string{"lag"}
+
+// LineFormat is a parsed line template such as "{time} {type} {level} {msg}"
+type LineFormat struct {
+	segments []formatSegment
+}
+
+// formatSegment is either literal text or a column placeholder
+type formatSegment struct {
+	literal string
+	column  string
+}
+
+// ParseLineFormat parses a line template. Placeholders are written in braces:
+// {time}, {type}, {level}, {component}, {msg}, {log_group}, {log_stream} and {lag}.
+func ParseLineFormat(format string) (*LineFormat, error) {
+	f := &LineFormat{}
+	rest := format
+	for rest != "" {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			f.segments = append(f.segments, formatSegment{literal: rest})
+			break
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			f.segments = append(f.segments, formatSegment{literal: rest})
+			break
+		}
+		end += start
+
+		if start > 0 {
+			f.segments = append(f.segments, formatSegment{literal: rest[:start]})
+		}
+
+		name := strings.TrimSpace(rest[start+1 : end])
+		column, ok := formatFields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown format field '{%s}' (supported: %s)", name, strings.Join(formatFieldNames(), ", "))
+		}
+		f.segments = append(f.segments, formatSegment{column: column})
+		rest = rest[end+1:]
+	}
+
+	return f, nil
+}
+
+// formatFieldNames returns the supported placeholders
+func formatFieldNames() []string {
+	names := make([]string, 0, len(formatFields))
+	for name := range formatFields {
+		names = append(names, "{"+name+"}")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Uses reports whether the template references the given column
+func (f *LineFormat) Uses(column string) bool {
+	for _, segment := range f.segments {
+		if segment.column == column {
+			return true
+		}
+	}
+	return false
+}
+
+// Render fills the template with the given columns. Missing columns are rendered as "-".
+func (f *LineFormat) Render(columns map[string]string) string {
+	var b strings.Builder
+	for _, segment := range f.segments {
+		if segment.column == "" {
+			b.WriteString(segment.literal)
+			continue
+		}
+		value, ok := columns[segment.column]
+		if !ok || value == "" {
+			value = "-"
+		}
+		b.WriteString(value)
+	}
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLineFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		columns  map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "reordered columns",
+			format:   "{time} {type} {level} {msg}",
+			columns:  map[string]string{"time": "T", "type": "api", "level": "info", "msg": "hello"},
+			expected: "T api info hello",
+		},
+		{
+			name:     "aliases and literals",
+			format:   "[{timestamp}] {log_stream}: {message}",
+			columns:  map[string]string{"time": "T", "log_stream": "kube-apiserver-1", "msg": "hello"},
+			expected: "[T] kube-apiserver-1: hello",
+		},
+		{
+			name:     "missing column",
+			format:   "{level}|{msg}",
+			columns:  map[string]string{"msg": "hello"},
+			expected: "-|hello",
+		},
+		{
+			name:     "unterminated brace is literal",
+			format:   "{msg} {oops",
+			columns:  map[string]string{"msg": "hello"},
+			expected: "hello {oops",
+		},
+		{
+			name:    "unknown field",
+			format:  "{time} {pod}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseLineFormat(tt.format)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, f.Render(tt.columns))
+		})
+	}
+}
+
+func TestPrinterLineFormat(t *testing.T) {
+	entry := LogEntry{
+		Timestamp:     time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC),
+		IngestionTime: time.Date(2024, 7, 19, 6, 9, 14, 0, time.UTC),
+		Level:         "info",
+		Component:     "kube-apiserver",
+		Message:       "Starting controller",
+		LogGroup:      "/aws/eks/test/cluster",
+		LogStream:     "kube-apiserver-123456",
+	}
+
+	f, err := ParseLineFormat("{time} {type} {log_group} {lag} {msg}")
+	assert.NoError(t, err)
+
+	printer := NewPrinter(OutputOptions{LineFormat: f}, &ColorConfig{Mode: ColorModeNever})
+	result, ok := printer.Format(entry)
+	assert.True(t, ok)
+	assert.Equal(t, "2024-07-19T06:09:12Z api /aws/eks/test/cluster lag 2s Starting controller", result)
+}
@@ -0,0 +1,164 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// HighlightRule colors every match of a pattern in messages of a log type
+type HighlightRule struct {
+	// LogType restricts the rule to a log type (api, audit, authenticator, kcm, ccm, scheduler).
+	// An empty log type or "*" applies the rule to all log types.
+	LogType string
+	Pattern *regexp.Regexp
+	Color   *color.Color
+}
+
+// builtinRuleNames lists the built-in highlight rules of each log type
+var builtinRuleNames = map[string][]string{
+	"api":           {"errors", "resources", "crds", "keywords", "file-paths", "success"},
+	"audit":         {"json"},
+	"authenticator": {"arns", "usernames", "errors", "aws-error-codes", "aws-error-types", "http-status", "ip-addresses", "http-methods", "paths", "levels", "access"},
+	"kcm":           {"controllers", "resources", "errors"},
+	"ccm":           {"aws-resources", "controllers", "errors"},
+	"scheduler":     {"keywords", "pods", "nodes"},
+	"default":       {"errors", "success"},
+}
+
+// colorAttributes maps color spec names to color attributes
+var colorAttributes = map[string]color.Attribute{
+	"black":      color.FgBlack,
+	"red":        color.FgRed,
+	"green":      color.FgGreen,
+	"yellow":     color.FgYellow,
+	"blue":       color.FgBlue,
+	"magenta":    color.FgMagenta,
+	"cyan":       color.FgCyan,
+	"white":      color.FgWhite,
+	"hi-black":   color.FgHiBlack,
+	"hi-red":     color.FgHiRed,
+	"hi-green":   color.FgHiGreen,
+	"hi-yellow":  color.FgHiYellow,
+	"hi-blue":    color.FgHiBlue,
+	"hi-magenta": color.FgHiMagenta,
+	"hi-cyan":    color.FgHiCyan,
+	"hi-white":   color.FgHiWhite,
+	"bg-black":   color.BgBlack,
+	"bg-red":     color.BgRed,
+	"bg-green":   color.BgGreen,
+	"bg-yellow":  color.BgYellow,
+	"bg-blue":    color.BgBlue,
+	"bg-magenta": color.BgMagenta,
+	"bg-cyan":    color.BgCyan,
+	"bg-white":   color.BgWhite,
+	"bold":       color.Bold,
+	"faint":      color.Faint,
+	"italic":     color.Italic,
+	"underline":  color.Underline,
+}
+
+// ParseColorSpec parses a color specification such as "red", "hi-yellow,bold" or "white+bg-red"
+func ParseColorSpec(spec string) (*color.Color, error) {
+	var attributes []color.Attribute
+	for _, name := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '+' || r == ' ' }) {
+		attribute, ok := colorAttributes[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown color '%s'", name)
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	if len(attributes) == 0 {
+		return nil, fmt.Errorf("empty color specification")
+	}
+
+	return color.New(attributes...), nil
+}
+
+// NewHighlightRule compiles a custom highlight rule
+func NewHighlightRule(logType, pattern, colorSpec string) (HighlightRule, error) {
+	if logType != "" && logType != "*" {
+		normalized := NormalizeLogType(logType)
+		if _, ok := builtinRuleNames[normalized]; !ok || normalized == "default" {
+			return HighlightRule{}, fmt.Errorf("unknown log type '%s'", logType)
+		}
+		logType = normalized
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return HighlightRule{}, fmt.Errorf("invalid highlight pattern '%s': %w", pattern, err)
+	}
+
+	c, err := ParseColorSpec(colorSpec)
+	if err != nil {
+		return HighlightRule{}, fmt.Errorf("invalid highlight color for pattern '%s': %w", pattern, err)
+	}
+
+	return HighlightRule{LogType: logType, Pattern: re, Color: c}, nil
+}
+
+// BuiltinHighlightRules returns the names of the built-in highlight rules as "type.rule"
+func BuiltinHighlightRules() []string {
+	var names []string
+	for logType, rules := range builtinRuleNames {
+		for _, rule := range rules {
+			names = append(names, logType+"."+rule)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DisableRule turns off a built-in highlight rule. The name is either "type.rule"
+// (e.g. "api.keywords") or a bare rule name disabling it for every log type (e.g. "errors").
+func (c *ColorConfig) DisableRule(name string) error {
+	if !isBuiltinRule(name) {
+		return fmt.Errorf("unknown highlight rule '%s'", name)
+	}
+
+	if c.disabledRules == nil {
+		c.disabledRules = make(map[string]bool)
+	}
+	c.disabledRules[name] = true
+	return nil
+}
+
+// isBuiltinRule reports whether name refers to at least one built-in highlight rule
+func isBuiltinRule(name string) bool {
+	logType, rule, qualified := strings.Cut(name, ".")
+	for t, rules := range builtinRuleNames {
+		if qualified && t != logType {
+			continue
+		}
+		for _, r := range rules {
+			if (qualified && r == rule) || (!qualified && r == name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ruleEnabled reports whether a built-in highlight rule is enabled
+func (lc *LogColorizer) ruleEnabled(logType, rule string) bool {
+	disabled := lc.config.disabledRules
+	return !disabled[logType+"."+rule] && !disabled[rule]
+}
+
+// applyCustomRules colors the matches of the custom highlight rules for a log type
+func (lc *LogColorizer) applyCustomRules(logType, message string) string {
+	for _, rule := range lc.config.Rules {
+		if rule.LogType != "" && rule.LogType != "*" && rule.LogType != logType {
+			continue
+		}
+		message = rule.Pattern.ReplaceAllStringFunc(message, func(s string) string {
+			return rule.Color.Sprint(s)
+		})
+	}
+	return message
+}
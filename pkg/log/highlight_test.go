@@ -0,0 +1,90 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColorSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "red"},
+		{spec: "hi-yellow,bold"},
+		{spec: "white+bg-red"},
+		{spec: "Magenta"},
+		{spec: "purple", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			c, err := ParseColorSpec(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, c)
+		})
+	}
+}
+
+func TestNewHighlightRule(t *testing.T) {
+	rule, err := NewHighlightRule("auth", `arn:aws:\S+`, "cyan")
+	assert.NoError(t, err)
+	assert.Equal(t, "authenticator", rule.LogType)
+
+	_, err = NewHighlightRule("unknown", "x", "red")
+	assert.Error(t, err)
+
+	_, err = NewHighlightRule("api", "(", "red")
+	assert.Error(t, err)
+
+	_, err = NewHighlightRule("api", "x", "purple")
+	assert.Error(t, err)
+}
+
+func TestDisableRule(t *testing.T) {
+	config := &ColorConfig{Mode: ColorModeAlways}
+	assert.NoError(t, config.DisableRule("api.keywords"))
+	assert.NoError(t, config.DisableRule("success"))
+	assert.Error(t, config.DisableRule("api.nodes"))
+	assert.Error(t, config.DisableRule("bogus"))
+
+	lc := NewLogColorizer(config)
+	assert.False(t, lc.ruleEnabled("api", "keywords"))
+	assert.False(t, lc.ruleEnabled("default", "success"))
+	assert.True(t, lc.ruleEnabled("api", "errors"))
+	assert.True(t, lc.ruleEnabled("scheduler", "keywords"))
+}
+
+func TestCustomHighlightRules(t *testing.T) {
+	original := color.NoColor
+	defer func() { color.NoColor = original }()
+
+	rule, err := NewHighlightRule("api", "etcd", "magenta")
+	assert.NoError(t, err)
+
+	config := &ColorConfig{Mode: ColorModeAlways, Rules: []HighlightRule{rule}}
+	assert.NoError(t, config.DisableRule("api.errors"))
+	lc := NewLogColorizer(config)
+
+	result := lc.ColorizeMessageOnly("etcd request failed", "api", "info")
+	assert.Contains(t, result, color.New(color.FgMagenta).Sprint("etcd"))
+	assert.Contains(t, result, " request failed")
+
+	// Rules for other log types are not applied
+	result = lc.ColorizeMessageOnly("etcd request", "scheduler", "info")
+	assert.NotContains(t, result, color.New(color.FgMagenta).Sprint("etcd"))
+}
+
+func TestBuiltinHighlightRules(t *testing.T) {
+	names := BuiltinHighlightRules()
+	assert.Contains(t, names, "api.keywords")
+	assert.Contains(t, names, "audit.json")
+	assert.IsNonDecreasing(t, names)
+}
@@ -0,0 +1,93 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/itchyny/gojq"
+)
+
+// JQFilter applies a compiled jq expression to JSON log messages
+type JQFilter struct {
+	code      *gojq.Code
+	onError   func(error)
+	errorOnce sync.Once
+}
+
+// NewJQFilter parses and compiles a jq expression
+func NewJQFilter(expression string) (*JQFilter, error) {
+	query, err := gojq.Parse(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq expression '%s': %w", expression, err)
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile jq expression '%s': %w", expression, err)
+	}
+
+	return &JQFilter{code: code}, nil
+}
+
+// SetErrorHandler sets a function called with the first error the expression raises on a
+// message, e.g. when indexing a field that is not an object. Later errors are not reported
+// again, as they usually repeat for every message of the same shape.
+func (f *JQFilter) SetErrorHandler(handler func(error)) {
+	f.onError = handler
+}
+
+// reportError passes the first runtime error to the error handler
+func (f *JQFilter) reportError(err error) {
+	if f.onError == nil {
+		return
+	}
+	f.errorOnce.Do(func() { f.onError(err) })
+}
+
+// Apply runs the expression against a JSON message.
+// Each result is rendered on its own line: strings are emitted as-is and other
+// values as compact JSON. If the expression yields no results (e.g. a select()
+// that does not match), keep is false and the entry should be skipped.
+// Messages that are not JSON, or on which the expression fails, are returned unchanged;
+// the first failure is passed to the error handler.
+func (f *JQFilter) Apply(message string) (result string, keep bool) {
+	data, ok := parseJSONMessage(message)
+	if !ok {
+		return message, true
+	}
+
+	var lines []string
+	iter := f.code.Run(data)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, isErr := v.(error); isErr {
+			if haltErr, isHalt := err.(*gojq.HaltError); isHalt && haltErr.Value() == nil {
+				break
+			}
+			f.reportError(err)
+			return message, true
+		}
+
+		switch val := v.(type) {
+		case string:
+			lines = append(lines, val)
+		default:
+			encoded, err := gojq.Marshal(val)
+			if err != nil {
+				f.reportError(err)
+				return message, true
+			}
+			lines = append(lines, string(encoded))
+		}
+	}
+
+	if len(lines) == 0 {
+		return "", false
+	}
+
+	return strings.Join(lines, "\n"), true
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJQFilter(t *testing.T) {
+	_, err := NewJQFilter(".verb")
+	assert.NoError(t, err)
+
+	_, err = NewJQFilter(".verb |")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid jq expression")
+}
+
+func TestJQFilterApply(t *testing.T) {
+	auditEvent := `{"verb":"delete","user":{"username":"admin"},"objectRef":{"resource":"pods"},"resourceVersion":12345678901234567890}`
+
+	tests := []struct {
+		name       string
+		expression string
+		message    string
+		expected   string
+		keep       bool
+	}{
+		{
+			name:       "string result is emitted raw",
+			expression: ".user.username",
+			message:    auditEvent,
+			expected:   "admin",
+			keep:       true,
+		},
+		{
+			name:       "object result is compact json",
+			expression: "{verb, resource: .objectRef.resource}",
+			message:    auditEvent,
+			expected:   `{"resource":"pods","verb":"delete"}`,
+			keep:       true,
+		},
+		{
+			name:       "multiple results on separate lines",
+			expression: ".verb, .objectRef.resource",
+			message:    auditEvent,
+			expected:   "delete\npods",
+			keep:       true,
+		},
+		{
+			name:       "large numbers keep precision",
+			expression: ".resourceVersion",
+			message:    auditEvent,
+			expected:   "12345678901234567890",
+			keep:       true,
+		},
+		{
+			name:       "select without match skips entry",
+			expression: `select(.verb == "get")`,
+			message:    auditEvent,
+			expected:   "",
+			keep:       false,
+		},
+		{
+			name:       "runtime error keeps original message",
+			expression: ".verb.foo",
+			message:    auditEvent,
+			expected:   auditEvent,
+			keep:       true,
+		},
+		{
+			name:       "non json message is unchanged",
+			expression: ".verb",
+			message:    "I0719 06:09:10.476002 1 controller.go:123] Starting controller",
+			expected:   "I0719 06:09:10.476002 1 controller.go:123] Starting controller",
+			keep:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewJQFilter(tt.expression)
+			assert.NoError(t, err)
+
+			result, keep := filter.Apply(tt.message)
+			assert.Equal(t, tt.keep, keep)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestPrinterFormatJQ(t *testing.T) {
+	filter, err := NewJQFilter(`select(.verb == "delete") | {verb}`)
+	assert.NoError(t, err)
+
+	printer := NewPrinter(OutputOptions{MessageOnly: true, JQ: filter}, &ColorConfig{Mode: ColorModeNever})
+
+	result, ok := printer.Format(LogEntry{Message: `{"verb":"delete"}`, LogStream: "kube-apiserver-audit-1"})
+	assert.True(t, ok)
+	assert.Equal(t, `{"verb":"delete"}`, result)
+
+	_, ok = printer.Format(LogEntry{Message: `{"verb":"get"}`, LogStream: "kube-apiserver-audit-1"})
+	assert.False(t, ok)
+}
+
+func TestJQFilterErrorHandler(t *testing.T) {
+	filter, err := NewJQFilter(".verb.foo")
+	assert.NoError(t, err)
+
+	var errs []error
+	filter.SetErrorHandler(func(err error) { errs = append(errs, err) })
+
+	// Only the first failing message is reported
+	for _, message := range []string{`{"verb":"get"}`, `{"verb":"list"}`} {
+		result, keep := filter.Apply(message)
+		assert.True(t, keep)
+		assert.Equal(t, message, result)
+	}
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "expected an object")
+}
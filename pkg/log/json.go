@@ -0,0 +1,219 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// splitJSONPayload splits a message into a non-JSON prefix (such as a klog header)
+// and a trailing JSON object payload
+func splitJSONPayload(message string) (string, string, bool) {
+	idx := strings.Index(message, "{")
+	if idx < 0 {
+		return "", "", false
+	}
+
+	payload := strings.TrimSpace(message[idx:])
+	if !json.Valid([]byte(payload)) {
+		return "", "", false
+	}
+
+	return message[:idx], payload, true
+}
+
+// PrettyJSON renders a JSON message with indentation and sorted keys.
+// A klog header preceding the JSON payload is preserved on the first line.
+// It returns the original message and false if no JSON object is found.
+func PrettyJSON(message string) (string, bool) {
+	prefix, payload, ok := splitJSONPayload(message)
+	if !ok {
+		return message, false
+	}
+
+	// Decode numbers as json.Number to avoid losing precision on large values
+	var data interface{}
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return message, false
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return message, false
+	}
+
+	return prefix + strings.TrimRight(buf.String(), "\n"), true
+}
+
+// parseJSONMessage decodes the JSON object payload of a message
+func parseJSONMessage(message string) (map[string]interface{}, bool) {
+	_, payload, ok := splitJSONPayload(message)
+	if !ok {
+		return nil, false
+	}
+
+	var data map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, false
+	}
+
+	return data, true
+}
+
+// splitFieldPath splits a field path such as "user.username", "$.sourceIPs[0]"
+// or ".objectRef.name" into its segments
+func splitFieldPath(path string) []string {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// LookupJSONField returns the value at the given dot-separated path in decoded JSON data.
+// Numeric segments index into arrays.
+func LookupJSONField(data interface{}, path string) (interface{}, bool) {
+	current := data
+	for _, segment := range splitFieldPath(path) {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, exists := node[segment]
+			if !exists {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// formatFieldValue renders a projected JSON value as a compact string
+func formatFieldValue(value interface{}) string {
+	switch val := value.(type) {
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	case nil:
+		return "null"
+	default:
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(val); err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return strings.TrimRight(buf.String(), "\n")
+	}
+}
+
+// ProjectJSONFields extracts the given fields from a JSON message and returns them
+// as tab-separated columns. Missing fields are rendered as "-".
+// It returns the original message and false if the message is not JSON.
+func ProjectJSONFields(message string, fields []string) (string, bool) {
+	data, ok := parseJSONMessage(message)
+	if !ok {
+		return message, false
+	}
+
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		value, exists := LookupJSONField(data, field)
+		if !exists {
+			columns = append(columns, "-")
+			continue
+		}
+		columns = append(columns, formatFieldValue(value))
+	}
+
+	return strings.Join(columns, "\t"), true
+}
+
+// FlattenJSON renders a JSON message as key.path=value pairs on one line, in logfmt style
+// with sorted keys. Array elements are keyed by their index (sourceIPs.0) and values
+// holding spaces, quotes or '=' are quoted. A klog header preceding the JSON payload is
+// preserved. It returns the original message and false if the message is not JSON.
+func FlattenJSON(message string) (string, bool) {
+	prefix, _, ok := splitJSONPayload(message)
+	if !ok {
+		return message, false
+	}
+	data, ok := parseJSONMessage(message)
+	if !ok {
+		return message, false
+	}
+
+	var pairs []string
+	flattenJSONValue("", data, &pairs)
+	return prefix + strings.Join(pairs, " "), true
+}
+
+// flattenJSONValue appends the key=value pairs of a decoded JSON value under key
+func flattenJSONValue(key string, value interface{}, pairs *[]string) {
+	join := func(child string) string {
+		if key == "" {
+			return child
+		}
+		return key + "." + child
+	}
+
+	switch node := value.(type) {
+	case map[string]interface{}:
+		if len(node) == 0 && key != "" {
+			*pairs = append(*pairs, key+"={}")
+			return
+		}
+		keys := make([]string, 0, len(node))
+		for k := range node {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flattenJSONValue(join(k), node[k], pairs)
+		}
+	case []interface{}:
+		if len(node) == 0 {
+			*pairs = append(*pairs, key+"=[]")
+			return
+		}
+		for i, element := range node {
+			flattenJSONValue(join(strconv.Itoa(i)), element, pairs)
+		}
+	default:
+		*pairs = append(*pairs, key+"="+logfmtValue(formatFieldValue(node)))
+	}
+}
+
+// logfmtValue quotes a value that would otherwise not read back as a single logfmt value
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
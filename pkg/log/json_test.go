@@ -0,0 +1,156 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "audit json with sorted keys",
+			message:  `{"verb":"get","kind":"Event","user":{"username":"admin"}}`,
+			expected: "{\n  \"kind\": \"Event\",\n  \"user\": {\n    \"username\": \"admin\"\n  },\n  \"verb\": \"get\"\n}",
+			ok:       true,
+		},
+		{
+			name:     "klog header with json payload",
+			message:  `I0719 06:09:10.476002 1 audit.go:12] {"b":1,"a":2}`,
+			expected: "I0719 06:09:10.476002 1 audit.go:12] {\n  \"a\": 2,\n  \"b\": 1\n}",
+			ok:       true,
+		},
+		{
+			name:     "large numbers keep precision",
+			message:  `{"resourceVersion":12345678901234567890}`,
+			expected: "{\n  \"resourceVersion\": 12345678901234567890\n}",
+			ok:       true,
+		},
+		{
+			name:     "html characters are not escaped",
+			message:  `{"requestURI":"/api/v1/pods?a=1&b=<2>"}`,
+			expected: "{\n  \"requestURI\": \"/api/v1/pods?a=1&b=<2>\"\n}",
+			ok:       true,
+		},
+		{
+			name:     "plain text message",
+			message:  "I0719 06:09:10.476002 1 controller.go:123] Starting controller",
+			expected: "I0719 06:09:10.476002 1 controller.go:123] Starting controller",
+			ok:       false,
+		},
+		{
+			name:     "braces that are not json",
+			message:  "E0719 failed to sync {pod foo}",
+			expected: "E0719 failed to sync {pod foo}",
+			ok:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := PrettyJSON(tt.message)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestPrinterFormatPretty(t *testing.T) {
+	entry := LogEntry{
+		Message:   `{"verb":"get","kind":"Event"}`,
+		Component: "kube-apiserver-audit",
+		LogStream: "kube-apiserver-audit-123456",
+	}
+
+	printer := NewPrinter(OutputOptions{MessageOnly: true, Pretty: true}, &ColorConfig{Mode: ColorModeNever})
+	result, ok := printer.Format(entry)
+	assert.True(t, ok)
+	assert.Equal(t, "{\n  \"kind\": \"Event\",\n  \"verb\": \"get\"\n}", result)
+
+	printer = NewPrinter(OutputOptions{MessageOnly: true}, &ColorConfig{Mode: ColorModeNever})
+	result, ok = printer.Format(entry)
+	assert.True(t, ok)
+	assert.Equal(t, entry.Message, result)
+}
+
+func TestColorizeAuditJSONPretty(t *testing.T) {
+	colorizer := NewLogColorizer(&ColorConfig{Mode: ColorModeNever})
+	colorizer.pretty = true
+
+	result := colorizer.formatColoredJSON(map[string]interface{}{
+		"kind":      "Event",
+		"sourceIPs": []interface{}{"10.0.0.1"},
+	}, 0)
+
+	assert.Equal(t, "{\n  \"kind\": \"Event\",\n  \"sourceIPs\": [\n    \"10.0.0.1\"\n  ]\n}", result)
+}
+
+func TestProjectJSONFields(t *testing.T) {
+	auditEvent := `{"verb":"delete","user":{"username":"admin","groups":["system:masters"]},` +
+		`"objectRef":{"resource":"pods","namespace":"default"},"sourceIPs":["10.0.0.1"],"responseStatus":{"code":200}}`
+
+	tests := []struct {
+		name     string
+		message  string
+		fields   []string
+		expected string
+		ok       bool
+	}{
+		{
+			name:     "audit fields",
+			message:  auditEvent,
+			fields:   []string{"verb", "user.username", "objectRef.resource", "responseStatus.code"},
+			expected: "delete\tadmin\tpods\t200",
+			ok:       true,
+		},
+		{
+			name:     "array index and jsonpath prefix",
+			message:  auditEvent,
+			fields:   []string{"$.sourceIPs[0]", ".user.groups.0"},
+			expected: "10.0.0.1\tsystem:masters",
+			ok:       true,
+		},
+		{
+			name:     "missing field and object value",
+			message:  auditEvent,
+			fields:   []string{"objectRef.name", "objectRef"},
+			expected: "-\t{\"namespace\":\"default\",\"resource\":\"pods\"}",
+			ok:       true,
+		},
+		{
+			name:     "non json message",
+			message:  "I0719 06:09:10.476002 1 controller.go:123] Starting controller",
+			fields:   []string{"verb"},
+			expected: "I0719 06:09:10.476002 1 controller.go:123] Starting controller",
+			ok:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := ProjectJSONFields(tt.message, tt.fields)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestFlattenJSON(t *testing.T) {
+	message := `{"verb":"get","user":{"username":"admin","groups":["system:masters"]},"objectRef":{},"sourceIPs":[],"requestURI":"/api/v1/pods?limit=500","code":200,"userAgent":"kubectl/v1.30 (linux/amd64)"}`
+	flattened, ok := FlattenJSON(message)
+	assert.True(t, ok)
+	assert.Equal(t, `code=200 objectRef={} requestURI="/api/v1/pods?limit=500" sourceIPs=[] user.groups.0=system:masters `+
+		`user.username=admin userAgent="kubectl/v1.30 (linux/amd64)" verb=get`, flattened)
+
+	flattened, ok = FlattenJSON(`I0612 10:00:00.000000 1 event.go:1] {"reason":"Started"}`)
+	assert.True(t, ok)
+	assert.Equal(t, `I0612 10:00:00.000000 1 event.go:1] reason=Started`, flattened)
+
+	_, ok = FlattenJSON("plain text")
+	assert.False(t, ok)
+}
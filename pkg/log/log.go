@@ -2,7 +2,6 @@ package log
 
 import (
 	"fmt"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -10,12 +9,15 @@ import (
 )
 
 type LogEntry struct {
-	Timestamp time.Time `json:"@timestamp"`
-	Level     string    `json:"level,omitempty"`
-	Component string    `json:"component"`
-	Message   string    `json:"message"`
-	LogGroup  string    `json:"log_group"`
-	LogStream string    `json:"log_stream"`
+	Timestamp     time.Time `json:"@timestamp"`
+	Level         string    `json:"level,omitempty"`
+	Component     string    `json:"component"`
+	Message       string    `json:"message"`
+	LogGroup      string    `json:"log_group"`
+	LogStream     string    `json:"log_stream"`
+	EventID       string    `json:"event_id,omitempty"` // CloudWatch Logs event ID, unique within the log group
+	IngestionTime time.Time `json:"-"`                  // Time the event was ingested by CloudWatch Logs
+	Label         string    `json:"label,omitempty"`    // Name of the query it matched when several are followed, e.g. a preset
 }
 
 func ParseTimeString(timeStr string) (*time.Time, error) {
@@ -37,14 +39,16 @@ func ParseTimeString(timeStr string) (*time.Time, error) {
 	return &t, nil
 }
 
+// relativeTimePattern matches relative times such as -1h, -15m, -30s or -2d
+var relativeTimePattern = regexp.MustCompile(`^-(\d+)([smhd])$`)
+
 func parseRelativeTime(relativeTime string) (*time.Time, error) {
 	if relativeTime == "" {
 		return nil, nil
 	}
 
 	// Check relative time pattern (e.g., -1h, -15m, -30s, -2d)
-	re := regexp.MustCompile(`^-(\d+)([smhd])$`)
-	matches := re.FindStringSubmatch(relativeTime)
+	matches := relativeTimePattern.FindStringSubmatch(relativeTime)
 
 	if len(matches) != 3 {
 		return nil, fmt.Errorf("invalid relative time format: %s (expected format: -1h, -15m, -30s, -2d)", relativeTime)
@@ -75,34 +79,49 @@ func parseRelativeTime(relativeTime string) (*time.Time, error) {
 	return &result, nil
 }
 
+// logTypeAliases maps the accepted log type names to the canonical ones
+var logTypeAliases = map[string]string{
+	// Short names (as is)
+	"api":           "api",
+	"audit":         "audit",
+	"auth":          "authenticator",
+	"authenticator": "authenticator",
+	"kcm":           "kcm",
+	"ccm":           "ccm",
+	"sched":         "scheduler",
+	"scheduler":     "scheduler",
+
+	// Long names (for compatibility)
+	"kubeControllerManager":    "kcm",
+	"cloudControllerManager":   "ccm",
+	"kube-controller-manager":  "kcm",
+	"cloud-controller-manager": "ccm",
+	"controller":               "kcm", // Common abbreviation
+	"cloud":                    "ccm", // Common abbreviation
+
+	// Container Insights node and pod logs
+	"kubelet":     "kubelet",
+	"containerd":  "containerd",
+	"docker":      "docker",
+	"host":        "host",
+	"dataplane":   "dataplane",
+	"application": "application",
+	"app":         "application",
+}
+
 func NormalizeLogType(logType string) string {
-	// Log type mapping (long names -> short names)
-	logTypeMap := map[string]string{
-		// Short names (as is)
-		"api":           "api",
-		"audit":         "audit",
-		"auth":          "authenticator",
-		"authenticator": "authenticator",
-		"kcm":           "kcm",
-		"ccm":           "ccm",
-		"sched":         "scheduler",
-		"scheduler":     "scheduler",
-
-		// Long names (for compatibility)
-		"kubeControllerManager":    "kcm",
-		"cloudControllerManager":   "ccm",
-		"kube-controller-manager":  "kcm",
-		"cloud-controller-manager": "ccm",
-		"controller":               "kcm", // Common abbreviation
-		"cloud":                    "ccm", // Common abbreviation
-	}
-
-	if normalized, exists := logTypeMap[logType]; exists {
+	if normalized, exists := logTypeAliases[logType]; exists {
 		return normalized
 	}
 	return logType // Return as is if not in the mapping
 }
 
+// IsLogType reports whether name is a log type or one of its aliases
+func IsLogType(name string) bool {
+	_, exists := logTypeAliases[name]
+	return exists
+}
+
 func GetLogTypeDescription(availableLogTypes []string) string {
 	descriptions := map[string]string{
 		"api":           "api (kube-apiserver)",
@@ -117,6 +136,8 @@ func GetLogTypeDescription(availableLogTypes []string) string {
 	for _, logType := range availableLogTypes {
 		if desc, exists := descriptions[logType]; exists {
 			result = append(result, desc)
+		} else if custom, exists := customLogTypes[logType]; exists && custom.Description != "" {
+			result = append(result, logType+" ("+custom.Description+")")
 		} else {
 			result = append(result, logType)
 		}
@@ -125,13 +146,26 @@ func GetLogTypeDescription(availableLogTypes []string) string {
 	return strings.Join(result, ", ")
 }
 
+var (
+	// levelFieldPattern matches the level of a JSON line ("level":"warn", "severity":"INFO")
+	// or of a logfmt line as written by logrus in the authenticator log (level=error)
+	levelFieldPattern = regexp.MustCompile(`"(?:level|severity)"\s*:\s*"([A-Za-z]+)"|(?:^|\s)level="?([A-Za-z]+)`)
+	// auditStatusPattern matches the response status code of an audit event
+	auditStatusPattern = regexp.MustCompile(`"responseStatus"\s*:\s*\{(?:[^{}]|\{[^{}]*\})*?"code"\s*:\s*(\d{3})`)
+)
+
+// ExtractLogLevel returns the level of a log message: "debug", "info", "warning", "error"
+// or "fatal", or "" when it has none. It reads the klog header of the control plane
+// components, the level field of JSON and logfmt (logrus) lines in any case, and for audit
+// events, whose level field is the audit level, the response status: error from 500,
+// warning from 400.
 func ExtractLogLevel(message string) string {
 	if len(message) == 0 {
 		return ""
 	}
 
 	// Kubernetes log format: I0719 06:09:10.476002 ...
-	if len(message) > 0 {
+	if klogHeaderPattern.MatchString(message) {
 		switch message[0] {
 		case 'I':
 			return "info"
@@ -144,77 +178,77 @@ func ExtractLogLevel(message string) string {
 		}
 	}
 
-	// For JSON format logs
-	if strings.Contains(message, `"level":"`) {
-		if strings.Contains(message, `"level":"info"`) {
-			return "info"
-		} else if strings.Contains(message, `"level":"warning"`) {
-			return "warning"
-		} else if strings.Contains(message, `"level":"error"`) {
-			return "error"
+	if strings.Contains(message, `"auditID"`) {
+		if matches := auditStatusPattern.FindStringSubmatch(message); matches != nil {
+			code, _ := strconv.Atoi(matches[1])
+			switch {
+			case code >= 500:
+				return "error"
+			case code >= 400:
+				return "warning"
+			default:
+				return "info"
+			}
 		}
+		return ""
 	}
 
+	// JSON and logfmt formats
+	if matches := levelFieldPattern.FindStringSubmatch(message); matches != nil {
+		return normalizeLevel(matches[1] + matches[2])
+	}
 	return ""
 }
 
+// normalizeLevel maps the level names of the logging libraries to the levels returned by
+// ExtractLogLevel
+func normalizeLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "trace", "debug":
+		return "debug"
+	case "info", "notice":
+		return "info"
+	case "warn", "warning":
+		return "warning"
+	case "error", "err":
+		return "error"
+	case "fatal", "panic", "crit", "critical":
+		return "fatal"
+	default:
+		return ""
+	}
+}
+
+// ExtractComponentFromStreamName returns the component writing a control plane log stream,
+// such as "kube-apiserver", or the stream prefix of a registered log type it matches. The
+// component of a stream named like a control plane one (<component>-<ID>) but unknown to
+// ekslogs is returned as it is; other names are "unknown".
 func ExtractComponentFromStreamName(streamName string) string {
-	if strings.HasPrefix(streamName, "kube-apiserver-audit-") {
-		return "kube-apiserver-audit"
-	} else if strings.HasPrefix(streamName, "kube-apiserver-") {
-		return "kube-apiserver"
-	} else if strings.HasPrefix(streamName, "authenticator-") {
-		return "authenticator"
-	} else if strings.HasPrefix(streamName, "kube-controller-manager-") {
-		return "kube-controller-manager"
-	} else if strings.HasPrefix(streamName, "cloud-controller-manager-") {
-		return "cloud-controller-manager"
-	} else if strings.HasPrefix(streamName, "kube-scheduler-") {
-		return "kube-scheduler"
-	}
-	return "unknown"
+	component, id := ParseStreamName(streamName)
+	if _, known := streamComponents[component]; known {
+		return component
+	}
+	if _, prefix := customStreamType(streamName); prefix != "" {
+		return strings.TrimRight(prefix, "-_./")
+	}
+	if id == "" {
+		return "unknown"
+	}
+	return component
 }
 
+// ExtractLogTypeFromStreamName returns the log type of a control plane log stream, or "" for
+// a stream of an unknown component (see IsKnownStream)
 func ExtractLogTypeFromStreamName(streamName string) string {
-	// Determine log type based on EKS log stream name pattern
-	if strings.HasPrefix(streamName, "kube-apiserver-audit-") {
-		return "audit"
-	} else if strings.HasPrefix(streamName, "kube-apiserver-") {
-		return "api"
-	} else if strings.HasPrefix(streamName, "authenticator-") {
-		return "authenticator"
-	} else if strings.HasPrefix(streamName, "kube-controller-manager-") {
-		return "kcm"
-	} else if strings.HasPrefix(streamName, "cloud-controller-manager-") {
-		return "ccm"
-	} else if strings.HasPrefix(streamName, "kube-scheduler-") {
-		return "scheduler"
+	component, _ := ParseStreamName(streamName)
+	if logType, known := streamComponents[component]; known {
+		return logType
 	}
-	return ""
+	logType, _ := customStreamType(streamName)
+	return logType
 }
 
+// PrintLog writes a single log entry to stdout
 func PrintLog(log LogEntry, messageOnly bool, colorConfig *ColorConfig) {
-	// Use the colorizer
-	colorizer := NewLogColorizer(colorConfig)
-
-	if messageOnly {
-		// Apply color to message only if colors are enabled
-		if colorConfig.ShouldUseColor() {
-			// Get the log type to determine which colorization to apply
-			logType := NormalizeLogType(ExtractLogTypeFromStreamName(log.LogStream))
-			coloredMessage := colorizer.ColorizeMessageOnly(log.Message, logType, log.Level)
-			fmt.Println(coloredMessage)
-		} else {
-			fmt.Println(log.Message)
-		}
-		// Flush stdout to ensure immediate output when piped
-		_ = os.Stdout.Sync()
-		return
-	}
-
-	// Full log output with colors
-	fmt.Println(colorizer.ColorizeLog(log))
-
-	// Flush stdout to ensure immediate output when piped
-	_ = os.Stdout.Sync()
+	NewPrinter(OutputOptions{MessageOnly: messageOnly}, colorConfig).Print(log)
 }
@@ -142,6 +142,19 @@ func TestNormalizeLogType(t *testing.T) {
 	}
 }
 
+func TestIsLogType(t *testing.T) {
+	for _, name := range []string{"api", "auth", "kube-controller-manager", "app"} {
+		if !IsLogType(name) {
+			t.Errorf("IsLogType(%q) = false, expected true", name)
+		}
+	}
+	for _, name := range []string{"", "prod-cluster", "unknown"} {
+		if IsLogType(name) {
+			t.Errorf("IsLogType(%q) = true, expected false", name)
+		}
+	}
+}
+
 func TestGetLogTypeDescription(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -226,6 +239,56 @@ func TestExtractLogLevel(t *testing.T) {
 			message:  `{"level":"error","msg":"Error occurred"}`,
 			expected: "error",
 		},
+		{
+			name:     "json warn log",
+			message:  `{"level":"warn","ts":1700000000,"msg":"slow request"}`,
+			expected: "warning",
+		},
+		{
+			name:     "json capitalized level",
+			message:  `{"level": "INFO", "msg": "Starting controller"}`,
+			expected: "info",
+		},
+		{
+			name:     "json severity",
+			message:  `{"severity":"ERROR","message":"sync failed"}`,
+			expected: "error",
+		},
+		{
+			name:     "logrus authenticator log",
+			message:  `time="2024-01-01T10:00:00Z" level=error msg="access denied" arn="arn:aws:iam::123456789012:role/dev"`,
+			expected: "error",
+		},
+		{
+			name:     "logrus quoted level",
+			message:  `time="2024-01-01T10:00:00Z" level="warning" msg="cache miss"`,
+			expected: "warning",
+		},
+		{
+			name:     "audit server error",
+			message:  `{"kind":"Event","level":"Metadata","auditID":"a1","verb":"get","responseStatus":{"metadata":{},"code":503}}`,
+			expected: "error",
+		},
+		{
+			name:     "audit forbidden",
+			message:  `{"kind":"Event","level":"Metadata","auditID":"a1","verb":"get","responseStatus":{"metadata":{},"status":"Failure","code":403}}`,
+			expected: "warning",
+		},
+		{
+			name:     "audit success",
+			message:  `{"kind":"Event","level":"RequestResponse","auditID":"a1","verb":"get","responseStatus":{"metadata":{},"code":200}}`,
+			expected: "info",
+		},
+		{
+			name:     "message starting like a klog header",
+			message:  "Invalid value for field",
+			expected: "",
+		},
+		{
+			name:     "unknown level",
+			message:  `{"level":"verbose","msg":"x"}`,
+			expected: "",
+		},
 		{
 			name:     "unknown format",
 			message:  "Starting controller",
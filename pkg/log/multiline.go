@@ -0,0 +1,145 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxJoinedLines bounds the lines joined into one entry, so a runaway dump is printed in parts
+const maxJoinedLines = 500
+
+// klogLogTypes are the log types whose entries start with a klog header (I0612 10:00:00.000000);
+// any other line of their streams continues the previous entry
+var klogLogTypes = map[string]bool{
+	"api":       true,
+	"kcm":       true,
+	"ccm":       true,
+	"scheduler": true,
+	"kubelet":   true,
+}
+
+// continuationPrefixes start the lines of Go and Java stack traces
+var continuationPrefixes = []string{"goroutine ", "created by ", "Caused by: "}
+
+// entryPrefixes start a new entry in a klog stream even without a klog header
+var entryPrefixes = []string{"panic: ", "fatal error: ", "{"}
+
+// goFramePattern matches the function line of a Go stack frame, e.g.
+// "k8s.io/apimachinery/pkg/util/wait.(*Backoff).Step(0xc000123456)"
+var goFramePattern = regexp.MustCompile(`^[\w.\-/]+\.(\(\*?\w+\)\.)?[\w.\[\]]+\(.*\)$`)
+
+// IsContinuation reports whether a message of a log type continues the previous entry of
+// its stream rather than starting a new one: an indented line, a stack frame, or, in klog
+// streams, a line without a klog header. Audit and CloudTrail events are always complete.
+func IsContinuation(logType, message string) bool {
+	switch logType {
+	case "audit", "cloudtrail":
+		return false
+	}
+	if message == "" || message[0] == ' ' || message[0] == '\t' {
+		return true
+	}
+	for _, prefix := range continuationPrefixes {
+		if strings.HasPrefix(message, prefix) {
+			return true
+		}
+	}
+	if goFramePattern.MatchString(message) {
+		return true
+	}
+	if !klogLogTypes[logType] || klogHeaderPattern.MatchString(message) {
+		return false
+	}
+	for _, prefix := range entryPrefixes {
+		if strings.HasPrefix(message, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// MultilineJoiner joins the continuation lines of each log stream (see IsContinuation)
+// into the entry they follow, separated by newlines. An entry is passed on when the next
+// entry of its stream starts, so it may be printed after later entries of other streams.
+// It keeps the timestamp, level and event ID of its first line.
+type MultilineJoiner struct {
+	next func(LogEntry)
+
+	mu      sync.Mutex
+	pending map[string]*joinedEntry
+}
+
+// joinedEntry is an entry waiting for further continuation lines
+type joinedEntry struct {
+	entry   LogEntry
+	lines   int
+	updated time.Time
+}
+
+// NewMultilineJoiner creates a joiner passing the joined entries to next
+func NewMultilineJoiner(next func(LogEntry)) *MultilineJoiner {
+	return &MultilineJoiner{next: next, pending: make(map[string]*joinedEntry)}
+}
+
+// Print adds an entry, in the order of its stream. It is safe for concurrent use.
+func (j *MultilineJoiner) Print(entry LogEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	key := entry.LogGroup + "\x00" + entry.LogStream
+	pending := j.pending[key]
+	if pending != nil && pending.lines < maxJoinedLines && IsContinuation(LogTypeFor(entry.LogGroup, entry.LogStream), entry.Message) {
+		pending.entry.Message += "\n" + entry.Message
+		pending.lines++
+		pending.updated = time.Now()
+		return
+	}
+	if pending != nil {
+		j.next(pending.entry)
+	}
+	j.pending[key] = &joinedEntry{entry: entry, lines: 1, updated: time.Now()}
+}
+
+// FlushIdle passes on the entries that received no line for idle, e.g. between the polls
+// of tail mode, oldest first
+func (j *MultilineJoiner) FlushIdle(idle time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	cutoff := time.Now().Add(-idle)
+	var idleEntries []LogEntry
+	for key, pending := range j.pending {
+		if !pending.updated.After(cutoff) {
+			idleEntries = append(idleEntries, pending.entry)
+			delete(j.pending, key)
+		}
+	}
+	SortEntries(idleEntries, SortOrderAsc, TimestampSourceEvent)
+	for _, entry := range idleEntries {
+		j.next(entry)
+	}
+}
+
+// Flush passes on all pending entries, at the end of the output
+func (j *MultilineJoiner) Flush() {
+	j.FlushIdle(0)
+}
+
+// JoinMultiline joins the continuation lines of a slice of entries, see MultilineJoiner.
+// The entries are returned in ascending time order.
+func JoinMultiline(entries []LogEntry) []LogEntry {
+	sorted := make([]LogEntry, len(entries))
+	copy(sorted, entries)
+	SortEntries(sorted, SortOrderAsc, TimestampSourceEvent)
+
+	joined := make([]LogEntry, 0, len(sorted))
+	joiner := NewMultilineJoiner(func(entry LogEntry) { joined = append(joined, entry) })
+	for _, entry := range sorted {
+		joiner.Print(entry)
+	}
+	joiner.Flush()
+	SortEntries(joined, SortOrderAsc, TimestampSourceEvent)
+	return joined
+}
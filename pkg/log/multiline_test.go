@@ -0,0 +1,72 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsContinuation(t *testing.T) {
+	tests := []struct {
+		logType string
+		message string
+		want    bool
+	}{
+		{"kcm", "I0612 10:00:00.000000       1 controller.go:123] Starting", false},
+		{"kcm", "goroutine 1 [running]:", true},
+		{"kcm", "k8s.io/apimachinery/pkg/util/wait.(*Backoff).Step(0xc000123456)", true},
+		{"kcm", "\t/workspace/pkg/controller/controller.go:42 +0x1a", true},
+		{"kcm", "wrapped remainder of a long message", true},
+		{"kcm", "panic: runtime error: invalid memory address", false},
+		{"kcm", `{"ts":1718186400,"msg":"started"}`, false},
+		{"authenticator", `time="2024-06-12T10:00:00Z" level=info msg="access granted"`, false},
+		{"authenticator", "  continued", true},
+		{"authenticator", "wrapped remainder of a long message", false},
+		{"audit", " {}", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.logType+" "+tt.message, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsContinuation(tt.logType, tt.message))
+		})
+	}
+}
+
+func TestMultilineJoiner(t *testing.T) {
+	base := time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC)
+	kcm := func(offset time.Duration, message string) LogEntry {
+		return LogEntry{Timestamp: base.Add(offset), Message: message, LogGroup: "/aws/eks/prod/cluster", LogStream: "kube-controller-manager-abc"}
+	}
+	scheduler := LogEntry{Timestamp: base.Add(time.Second), Message: "I0612 10:00:01.000000 1 scheduler.go:1] Scheduled", LogGroup: "/aws/eks/prod/cluster", LogStream: "kube-scheduler-abc"}
+
+	var printed []LogEntry
+	joiner := NewMultilineJoiner(func(entry LogEntry) { printed = append(printed, entry) })
+	joiner.Print(kcm(0, "E0612 10:00:00.000000 1 runtime.go:79] Observed a panic"))
+	joiner.Print(kcm(0, "goroutine 1 [running]:"))
+	joiner.Print(scheduler)
+	joiner.Print(kcm(0, "main.main()"))
+	joiner.Print(kcm(2*time.Second, "I0612 10:00:02.000000 1 controller.go:1] Restarted"))
+	assert.Len(t, printed, 1)
+	assert.Equal(t, "E0612 10:00:00.000000 1 runtime.go:79] Observed a panic\ngoroutine 1 [running]:\nmain.main()", printed[0].Message)
+	assert.Equal(t, base, printed[0].Timestamp)
+
+	// Pending entries are flushed oldest first
+	joiner.Flush()
+	assert.Len(t, printed, 3)
+	assert.Equal(t, scheduler, printed[1])
+	assert.True(t, strings.HasSuffix(printed[2].Message, "Restarted"))
+}
+
+func TestJoinMultiline(t *testing.T) {
+	base := time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{Timestamp: base.Add(time.Second), Message: "I0612 10:00:01.000000 1 a.go:1] next", LogStream: "kube-apiserver-abc"},
+		{Timestamp: base, Message: "E0612 10:00:00.000000 1 a.go:1] failed", LogStream: "kube-apiserver-abc"},
+		{Timestamp: base, Message: "    detail", LogStream: "kube-apiserver-abc"},
+	}
+	joined := JoinMultiline(entries)
+	assert.Len(t, joined, 2)
+	assert.Equal(t, "E0612 10:00:00.000000 1 a.go:1] failed\n    detail", joined[0].Message)
+	assert.Len(t, entries, 3, "the input is left unchanged")
+}
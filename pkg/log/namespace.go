@@ -0,0 +1,60 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+)
+
+// NamespaceFilter keeps the entries concerning a namespace, for the log types whose
+// namespace is not a JSON field CloudWatch Logs can filter on (see --namespace)
+type NamespaceFilter struct {
+	namespace string
+	// reference matches the namespace in text messages: namespace="ns", namespace=ns,
+	// "namespace":"ns", or an object reference such as pod="ns/name"
+	reference *regexp.Regexp
+}
+
+// NewNamespaceFilter creates a filter keeping the entries of namespace
+func NewNamespaceFilter(namespace string) *NamespaceFilter {
+	ns := regexp.QuoteMeta(namespace)
+	return &NamespaceFilter{
+		namespace: namespace,
+		reference: regexp.MustCompile(`(?:\bnamespace"?\s*[=:]\s*"?` + ns + `(?:["\s,}]|$))|(?:(?:^|[\s"'=(\[])` + ns + `/[\w.-])`),
+	}
+}
+
+// Match reports whether an entry concerns the namespace: the object of an audit event,
+// the Kubernetes metadata or the stream of a Container Insights application log, or a
+// reference in the message for the other log types
+func (f *NamespaceFilter) Match(entry LogEntry) bool {
+	logType := LogTypeFor(entry.LogGroup, entry.LogStream)
+	if logType == "audit" {
+		data, ok := parseJSONMessage(entry.Message)
+		if !ok {
+			return false
+		}
+		namespace, _ := LookupJSONField(data, "objectRef.namespace")
+		return namespace == f.namespace
+	}
+	if logType == "application" {
+		// Fluent Bit names the streams <pod>_<namespace>_<container>-<id>
+		if strings.Contains(entry.LogStream, "_"+f.namespace+"_") {
+			return true
+		}
+		if data, ok := parseJSONMessage(entry.Message); ok {
+			if namespace, ok := LookupJSONField(data, "kubernetes.namespace_name"); ok {
+				return namespace == f.namespace
+			}
+		}
+	}
+	return f.reference.MatchString(entry.Message)
+}
+
+// Wrap returns a print function that only forwards the entries of the namespace
+func (f *NamespaceFilter) Wrap(printFunc func(LogEntry)) func(LogEntry) {
+	return func(entry LogEntry) {
+		if f.Match(entry) {
+			printFunc(entry)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaceFilterMatch(t *testing.T) {
+	f := NewNamespaceFilter("team-a")
+	tests := []struct {
+		name  string
+		entry LogEntry
+		want  bool
+	}{
+		{"audit object", LogEntry{LogStream: "kube-apiserver-audit-abc", Message: `{"verb":"create","objectRef":{"resource":"pods","namespace":"team-a"}}`}, true},
+		{"audit other namespace", LogEntry{LogStream: "kube-apiserver-audit-abc", Message: `{"verb":"create","objectRef":{"resource":"pods","namespace":"team-ab"}}`}, false},
+		{"audit cluster-scoped", LogEntry{LogStream: "kube-apiserver-audit-abc", Message: `{"verb":"get","objectRef":{"resource":"nodes"},"user":{"username":"team-a"}}`}, false},
+		{"klog object reference", LogEntry{LogStream: "kube-scheduler-abc", Message: `I0612 10:00:00.000000 1 schedule_one.go:252] "Successfully bound pod to node" pod="team-a/web-7d9f" node="ip-10-0-1-2"`}, true},
+		{"klog namespace key", LogEntry{LogStream: "kube-controller-manager-abc", Message: `I0612 10:00:00.000000 1 replica_set.go:676] "Finished syncing" kind="ReplicaSet" namespace="team-a"`}, true},
+		{"logfmt namespace", LogEntry{LogStream: "authenticator-abc", Message: `level=info msg="access granted" namespace=team-a`}, true},
+		{"longer namespace", LogEntry{LogStream: "kube-scheduler-abc", Message: `pod="team-ab/web-7d9f" namespace="team-ab"`}, false},
+		{"bare word", LogEntry{LogStream: "kube-apiserver-abc", Message: "team-a is not a reference"}, false},
+		{"application stream", LogEntry{LogGroup: "/aws/containerinsights/prod/application", LogStream: "web-7d9f_team-a_web-0123abcd", Message: "GET /healthz"}, true},
+		{"application metadata", LogEntry{LogGroup: "/aws/containerinsights/prod/application", LogStream: "web", Message: `{"log":"GET /","kubernetes":{"namespace_name":"team-b"}}`}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, f.Match(tt.entry))
+		})
+	}
+}
+
+func TestNamespaceFilterWrap(t *testing.T) {
+	var printed []LogEntry
+	printFunc := NewNamespaceFilter("team-a").Wrap(func(entry LogEntry) { printed = append(printed, entry) })
+	printFunc(LogEntry{LogStream: "kube-scheduler-abc", Message: `pod="team-a/web"`})
+	printFunc(LogEntry{LogStream: "kube-scheduler-abc", Message: `pod="team-b/web"`})
+	assert.Len(t, printed, 1)
+}
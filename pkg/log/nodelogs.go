@@ -0,0 +1,99 @@
+package log
+
+import "strings"
+
+// ContainerInsightsPrefix is the prefix of the Container Insights log groups of a cluster
+const ContainerInsightsPrefix = "/aws/containerinsights/"
+
+// ContainerInsightsGroups are the Container Insights log groups holding node and pod logs
+// (the performance log group holds metrics and is not read)
+var ContainerInsightsGroups = []string{"application", "dataplane", "host"}
+
+// CloudTrailLogGroup is the log group of entries converted from CloudTrail events, which
+// are not read from CloudWatch Logs
+const CloudTrailLogGroup = "cloudtrail"
+
+// nodeLogTypes are the log types of the Container Insights log groups
+var nodeLogTypes = map[string]bool{
+	"kubelet":     true,
+	"containerd":  true,
+	"docker":      true,
+	"host":        true,
+	"dataplane":   true,
+	"application": true,
+}
+
+// IsNodeLogType reports whether name is a Container Insights log type or one of its aliases
+func IsNodeLogType(name string) bool {
+	return IsLogType(name) && nodeLogTypes[NormalizeLogType(name)]
+}
+
+// IsContainerInsightsGroup reports whether a log group is a Container Insights log group
+func IsContainerInsightsGroup(logGroup string) bool {
+	return strings.HasPrefix(logGroup, ContainerInsightsPrefix)
+}
+
+// ExtractNodeLogType determines the log type of a Container Insights log stream. Dataplane
+// streams are named after the node and the systemd unit or log file, e.g.
+// "ip-10-0-1-2.ec2.internal-dataplane.systemd.kubelet.service".
+func ExtractNodeLogType(logGroup, streamName string) string {
+	switch {
+	case strings.HasSuffix(logGroup, "/application"):
+		return "application"
+	case strings.HasSuffix(logGroup, "/host"):
+		return "host"
+	case strings.Contains(streamName, "kubelet"):
+		return "kubelet"
+	case strings.Contains(streamName, "containerd"):
+		return "containerd"
+	case strings.Contains(streamName, "docker"):
+		return "docker"
+	default:
+		return "dataplane"
+	}
+}
+
+// LogTypeFor determines the log type of a log stream of a control plane or Container Insights log group,
+// or of a CloudTrail event
+func LogTypeFor(logGroup, streamName string) string {
+	if logGroup == CloudTrailLogGroup {
+		return "cloudtrail"
+	}
+	if IsContainerInsightsGroup(logGroup) {
+		return ExtractNodeLogType(logGroup, streamName)
+	}
+	if logType, custom := customLogGroupType(logGroup, streamName); custom {
+		return logType
+	}
+	return ExtractLogTypeFromStreamName(streamName)
+}
+
+// ClusterFor returns the name of the cluster of a control plane or Container Insights log
+// group, or "" for other log groups
+func ClusterFor(logGroup string) string {
+	rest, ok := strings.CutPrefix(logGroup, ContainerInsightsPrefix)
+	if !ok {
+		if rest, ok = strings.CutPrefix(logGroup, "/aws/eks/"); !ok {
+			return ""
+		}
+	}
+	name, _, ok := strings.Cut(rest, "/")
+	if !ok {
+		return ""
+	}
+	return name
+}
+
+// ComponentFor determines the component of a log stream of a control plane or Container Insights log group
+func ComponentFor(logGroup, streamName string) string {
+	if IsContainerInsightsGroup(logGroup) {
+		return ExtractNodeLogType(logGroup, streamName)
+	}
+	if logType, custom := customLogGroupType(logGroup, streamName); custom {
+		if logType == "" {
+			return "unknown"
+		}
+		return logType
+	}
+	return ExtractComponentFromStreamName(streamName)
+}
@@ -0,0 +1,44 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogTypeFor(t *testing.T) {
+	tests := []struct {
+		logGroup  string
+		stream    string
+		logType   string
+		component string
+	}{
+		{"/aws/eks/test/cluster", "kube-apiserver-audit-123", "audit", "kube-apiserver-audit"},
+		{"/aws/eks/test/cluster", "kube-scheduler-123", "scheduler", "kube-scheduler"},
+		{"/aws/containerinsights/test/dataplane", "ip-10-0-1-2.ec2.internal-dataplane.systemd.kubelet.service", "kubelet", "kubelet"},
+		{"/aws/containerinsights/test/dataplane", "ip-10-0-1-2.ec2.internal-dataplane.systemd.containerd.service", "containerd", "containerd"},
+		{"/aws/containerinsights/test/dataplane", "ip-10-0-1-2.ec2.internal-dataplane.systemd.docker.service", "docker", "docker"},
+		{"/aws/containerinsights/test/dataplane", "ip-10-0-1-2.ec2.internal-dataplane.tail.var.log.containers.aws-node", "dataplane", "dataplane"},
+		{"/aws/containerinsights/test/host", "ip-10-0-1-2.ec2.internal-host.messages", "host", "host"},
+		{"/aws/containerinsights/test/application", "coredns-abc_kube-system_coredns-123", "application", "application"},
+		{"cloudtrail", "UpdateClusterConfig", "cloudtrail", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stream, func(t *testing.T) {
+			assert.Equal(t, tt.logType, LogTypeFor(tt.logGroup, tt.stream))
+			assert.Equal(t, tt.component, ComponentFor(tt.logGroup, tt.stream))
+		})
+	}
+
+	assert.True(t, IsContainerInsightsGroup("/aws/containerinsights/test/host"))
+	assert.False(t, IsContainerInsightsGroup("/aws/eks/test/cluster"))
+	assert.Equal(t, "application", NormalizeLogType("app"))
+}
+
+func TestClusterFor(t *testing.T) {
+	assert.Equal(t, "prod", ClusterFor("/aws/eks/prod/cluster"))
+	assert.Equal(t, "prod", ClusterFor("/aws/containerinsights/prod/application"))
+	assert.Equal(t, "", ClusterFor("cloudtrail"))
+	assert.Equal(t, "", ClusterFor("/aws/lambda/handler"))
+}
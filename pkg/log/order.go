@@ -0,0 +1,54 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SortOrder defines the order in which log entries are printed
+type SortOrder string
+
+const (
+	// SortOrderAsc prints the oldest entries first
+	SortOrderAsc SortOrder = "asc"
+	// SortOrderDesc prints the newest entries first
+	SortOrderDesc SortOrder = "desc"
+)
+
+// ParseSortOrder validates a sort order name
+func ParseSortOrder(order string) (SortOrder, error) {
+	switch SortOrder(order) {
+	case "", SortOrderAsc:
+		return SortOrderAsc, nil
+	case SortOrderDesc:
+		return SortOrderDesc, nil
+	default:
+		return "", fmt.Errorf("invalid order '%s' (supported: asc, desc)", order)
+	}
+}
+
+// SortEntries sorts log entries by the timestamp of the given source, keeping the retrieval
+// order of equal timestamps
+func SortEntries(entries []LogEntry, order SortOrder, source TimestampSource) {
+	// Message timestamps are parsed from the message, so they are extracted once per entry
+	keyed := make([]sortableEntry, len(entries))
+	for i, entry := range entries {
+		keyed[i] = sortableEntry{entry: entry, timestamp: entry.TimestampFor(source)}
+	}
+	sort.SliceStable(keyed, func(i, j int) bool {
+		if order == SortOrderDesc {
+			return keyed[i].timestamp.After(keyed[j].timestamp)
+		}
+		return keyed[i].timestamp.Before(keyed[j].timestamp)
+	})
+	for i := range keyed {
+		entries[i] = keyed[i].entry
+	}
+}
+
+// sortableEntry pairs a log entry with the timestamp it is sorted by
+type sortableEntry struct {
+	entry     LogEntry
+	timestamp time.Time
+}
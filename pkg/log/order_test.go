@@ -0,0 +1,60 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSortOrder(t *testing.T) {
+	order, err := ParseSortOrder("")
+	assert.NoError(t, err)
+	assert.Equal(t, SortOrderAsc, order)
+
+	order, err = ParseSortOrder("desc")
+	assert.NoError(t, err)
+	assert.Equal(t, SortOrderDesc, order)
+
+	_, err = ParseSortOrder("newest")
+	assert.Error(t, err)
+}
+
+func TestSortEntries(t *testing.T) {
+	base := time.Date(2024, 7, 19, 6, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{Timestamp: base.Add(2 * time.Second), Message: "c"},
+		{Timestamp: base, Message: "a"},
+		{Timestamp: base.Add(time.Second), Message: "b1"},
+		{Timestamp: base.Add(time.Second), Message: "b2"},
+	}
+
+	messages := func() []string {
+		var result []string
+		for _, e := range entries {
+			result = append(result, e.Message)
+		}
+		return result
+	}
+
+	SortEntries(entries, SortOrderAsc, TimestampSourceEvent)
+	assert.Equal(t, []string{"a", "b1", "b2", "c"}, messages())
+
+	SortEntries(entries, SortOrderDesc, TimestampSourceEvent)
+	assert.Equal(t, []string{"c", "b1", "b2", "a"}, messages())
+}
+
+// TestSortEntriesByTimestampSource tests sorting by the timestamp embedded in the messages
+func TestSortEntriesByTimestampSource(t *testing.T) {
+	base := time.Date(2024, 7, 19, 6, 0, 0, 0, time.UTC)
+	entries := []LogEntry{
+		{Timestamp: base, Message: `{"requestReceivedTimestamp":"2024-07-19T06:00:02Z","verb":"get"}`},
+		{Timestamp: base.Add(time.Second), Message: `{"requestReceivedTimestamp":"2024-07-19T06:00:01Z","verb":"list"}`},
+	}
+
+	SortEntries(entries, SortOrderAsc, TimestampSourceMessage)
+	assert.Equal(t, base.Add(time.Second), entries[0].Timestamp)
+
+	SortEntries(entries, SortOrderAsc, TimestampSourceEvent)
+	assert.Equal(t, base, entries[0].Timestamp)
+}
@@ -0,0 +1,369 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// OutputFormat defines how log entries are serialized
+type OutputFormat string
+
+const (
+	// OutputFormatText renders human readable, optionally colored lines
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSON renders one JSON object per line (NDJSON)
+	OutputFormatJSON OutputFormat = "json"
+	// OutputFormatJSONArray renders the JSON objects as the elements of a single JSON array
+	OutputFormatJSONArray OutputFormat = "json-array"
+)
+
+// ParseOutputFormat validates an output format name
+func ParseOutputFormat(format string) (OutputFormat, error) {
+	switch OutputFormat(format) {
+	case "", OutputFormatText:
+		return OutputFormatText, nil
+	case OutputFormatJSON:
+		return OutputFormatJSON, nil
+	default:
+		return "", fmt.Errorf("invalid output format '%s' (supported: text, json)", format)
+	}
+}
+
+// prefixFields are the fields --prefix can write before the message in message-only output
+var prefixFields = []string{"type", "stream", "cluster"}
+
+// ValidatePrefixFields checks the fields written before the message in message-only output
+func ValidatePrefixFields(fields []string) error {
+	for _, field := range fields {
+		if !slices.Contains(prefixFields, field) {
+			return fmt.Errorf("invalid prefix field '%s' (supported: %s)", field, strings.Join(prefixFields, ", "))
+		}
+	}
+	return nil
+}
+
+// ParseEntryOutputFormat validates the output format of log entries, which can also be a
+// JSON array
+func ParseEntryOutputFormat(format string) (OutputFormat, error) {
+	if OutputFormat(format) == OutputFormatJSONArray {
+		return OutputFormatJSONArray, nil
+	}
+	parsed, err := ParseOutputFormat(format)
+	if err != nil {
+		return "", fmt.Errorf("invalid output format '%s' (supported: text, json, json-array)", format)
+	}
+	return parsed, nil
+}
+
+// IsJSON reports whether the format renders entries as JSON objects
+func (f OutputFormat) IsJSON() bool {
+	return f == OutputFormatJSON || f == OutputFormatJSONArray
+}
+
+// OutputOptions controls how log entries are rendered by a Printer
+type OutputOptions struct {
+	// Format selects text or JSON output
+	Format OutputFormat
+	// MessageOnly prints only the log message without timestamp, level and component
+	MessageOnly bool
+	// Raw prints the message exactly as CloudWatch Logs returned it, each followed by a
+	// newline, ignoring every other option but Format, which must be text
+	Raw bool
+	// Prefix lists the fields (type, stream, cluster) written tab-separated before the message
+	// in message-only output
+	Prefix []string
+	// Pretty renders JSON messages with indentation and sorted keys
+	Pretty bool
+	// Flatten renders JSON messages as key.path=value pairs on one line
+	Flatten bool
+	// Fields projects the given JSON fields (e.g. "user.username") out of JSON messages
+	Fields []string
+	// JQ reshapes JSON messages with a jq expression before any other formatting
+	JQ *JQFilter
+	// TimestampSource selects which timestamp is displayed (event time by default)
+	TimestampSource TimestampSource
+	// ShowLag displays the delay between the logged time and CloudWatch ingestion
+	ShowLag bool
+	// IngestionTime adds the CloudWatch ingestion time of each entry to JSON output
+	IngestionTime bool
+	// LineFormat customizes the columns of text output and their order
+	LineFormat *LineFormat
+	// Truncate cuts text lines longer than this many characters (0 disables truncation)
+	Truncate int
+	// WrapWidth wraps text lines longer than this many characters with a hanging indent (0 disables wrapping)
+	WrapWidth int
+	// Redactor masks credentials in messages before any other formatting
+	Redactor *Redactor
+	// Metadata is added to each object of JSON output
+	Metadata *Metadata
+}
+
+// Metadata identifies the account and cluster the entries come from, so output collected
+// from several accounts can be told apart
+type Metadata struct {
+	Account         string `json:"account,omitempty"`
+	ClusterARN      string `json:"cluster_arn,omitempty"`
+	PlatformVersion string `json:"platform_version,omitempty"`
+}
+
+// jsonLogEntry is the JSON output representation of a log entry
+type jsonLogEntry struct {
+	LogEntry
+	*Metadata
+	IngestionTime  *time.Time `json:"ingestion_time,omitempty"`
+	IngestionLagMs *int64     `json:"ingestion_lag_ms,omitempty"`
+}
+
+// Printer writes formatted log entries to an output stream
+type Printer struct {
+	out         io.Writer
+	options     OutputOptions
+	colorConfig *ColorConfig
+	colorizer   *LogColorizer
+	mu          sync.Mutex
+	printed     int
+	closed      bool
+}
+
+// NewPrinter creates a new Printer that writes to stdout
+func NewPrinter(options OutputOptions, colorConfig *ColorConfig) *Printer {
+	colorizer := NewLogColorizer(colorConfig)
+	colorizer.pretty = options.Pretty
+	colorizer.format = options.LineFormat
+
+	return &Printer{
+		out:         os.Stdout,
+		options:     options,
+		colorConfig: colorConfig,
+		colorizer:   colorizer,
+	}
+}
+
+// SetOutput makes the printer write to w instead of stdout
+func (p *Printer) SetOutput(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.out = w
+}
+
+// Print formats a log entry and writes it to the output stream.
+// It is safe to call from multiple goroutines.
+func (p *Printer) Print(entry LogEntry) {
+	line, ok := p.Format(entry)
+	if !ok {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.options.Format == OutputFormatJSONArray {
+		if p.closed {
+			return
+		}
+		// The separator is written with the next element, so the array can be closed at any time
+		separator := ",\n"
+		if p.printed == 0 {
+			separator = "[\n"
+		}
+		_, _ = fmt.Fprint(p.out, separator+line)
+	} else {
+		_, _ = fmt.Fprintln(p.out, line)
+	}
+	p.printed++
+
+	// Flush stdout to ensure immediate output when piped
+	if f, ok := p.out.(*os.File); ok {
+		syncOutput(f)
+	}
+}
+
+// Close ends json-array output with the closing bracket, or writes an empty array when no
+// entry was printed. Entries printed afterwards are dropped. It does nothing for other formats.
+func (p *Printer) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.options.Format != OutputFormatJSONArray || p.closed {
+		return
+	}
+	p.closed = true
+	if p.printed == 0 {
+		_, _ = fmt.Fprintln(p.out, "[]")
+	} else {
+		_, _ = fmt.Fprint(p.out, "\n]\n")
+	}
+}
+
+// Printed returns the number of entries written so far
+func (p *Printer) Printed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.printed
+}
+
+// Format returns the rendered representation of a log entry.
+// It returns false if the entry was filtered out by the jq expression.
+func (p *Printer) Format(entry LogEntry) (string, bool) {
+	if p.options.Raw {
+		return entry.Message, true
+	}
+
+	// Compute the lag before the message or timestamp are rewritten
+	var lag time.Duration
+	var hasLag bool
+	if p.options.ShowLag || (!p.options.Format.IsJSON() && p.options.LineFormat != nil && p.options.LineFormat.Uses("lag")) {
+		lag, hasLag = entry.IngestionLag()
+	}
+
+	entry.Timestamp = entry.TimestampFor(p.options.TimestampSource)
+
+	if p.options.Redactor != nil {
+		entry.Message = p.options.Redactor.Redact(entry.Message)
+	}
+
+	if p.options.JQ != nil {
+		message, keep := p.options.JQ.Apply(entry.Message)
+		if !keep {
+			return "", false
+		}
+		entry.Message = message
+	}
+
+	if len(p.options.Fields) > 0 {
+		if projected, ok := ProjectJSONFields(entry.Message, p.options.Fields); ok {
+			entry.Message = projected
+		}
+	} else if p.options.Flatten && !p.options.Format.IsJSON() {
+		if flattened, ok := FlattenJSON(entry.Message); ok {
+			entry.Message = flattened
+		}
+	} else if p.options.Pretty && !p.options.Format.IsJSON() {
+		if pretty, ok := PrettyJSON(entry.Message); ok {
+			entry.Message = pretty
+		}
+	}
+
+	if p.options.Format.IsJSON() {
+		return p.formatJSON(entry, lag, hasLag)
+	}
+
+	return p.fitWidth(p.label(entry) + p.formatText(entry, lag, hasLag)), true
+}
+
+// label returns the "[label] " prefix of the entries of a labeled query, colored per label
+func (p *Printer) label(entry LogEntry) string {
+	if entry.Label == "" {
+		return ""
+	}
+	label := entry.Label
+	if p.colorizer.useColor {
+		label = p.colorizer.color(LabelColor(label)).Sprint(label)
+	}
+	return "[" + label + "] "
+}
+
+// formatText renders a log entry as a line of text
+func (p *Printer) formatText(entry LogEntry, lag time.Duration, hasLag bool) string {
+	if p.options.MessageOnly {
+		message := entry.Message
+		// Apply color to message only if colors are enabled
+		if p.colorizer.useColor {
+			// Get the log type to determine which colorization to apply
+			logType := NormalizeLogType(LogTypeFor(entry.LogGroup, entry.LogStream))
+			message = p.colorizer.ColorizeMessageOnly(entry.Message, logType, entry.Level)
+		}
+		return p.messagePrefix(entry) + message
+	}
+
+	extra := make(map[string]string)
+	if hasLag {
+		extra["lag"] = p.formatLag(lag)
+	}
+
+	// Full log output with colors
+	return p.colorizer.colorizeLog(entry, extra)
+}
+
+// messagePrefix returns the uncolored prefix fields of an entry, each followed by a tab, so
+// "cut -f" can split them from the message. Unknown values are written as "-".
+func (p *Printer) messagePrefix(entry LogEntry) string {
+	var b strings.Builder
+	for _, field := range p.options.Prefix {
+		var value string
+		switch field {
+		case "type":
+			value = LogTypeFor(entry.LogGroup, entry.LogStream)
+		case "stream":
+			value = entry.LogStream
+		case "cluster":
+			value = ClusterFor(entry.LogGroup)
+		}
+		if value == "" {
+			value = "-"
+		}
+		b.WriteString(value)
+		b.WriteByte('\t')
+	}
+	return b.String()
+}
+
+// fitWidth truncates or wraps a text line to the configured width
+func (p *Printer) fitWidth(line string) string {
+	if p.options.Truncate > 0 {
+		return Truncate(line, p.options.Truncate)
+	}
+	if p.options.WrapWidth > 0 {
+		return Wrap(line, p.options.WrapWidth)
+	}
+	return line
+}
+
+// formatJSON serializes a log entry as a single line of JSON
+func (p *Printer) formatJSON(entry LogEntry, lag time.Duration, hasLag bool) (string, bool) {
+	entry.Timestamp = entry.Timestamp.UTC()
+	output := jsonLogEntry{LogEntry: entry, Metadata: p.options.Metadata}
+	if p.options.IngestionTime && !entry.IngestionTime.IsZero() {
+		ingested := entry.IngestionTime.UTC()
+		output.IngestionTime = &ingested
+	}
+	if hasLag {
+		lagMs := lag.Milliseconds()
+		output.IngestionLagMs = &lagMs
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(output); err != nil {
+		return "", false
+	}
+
+	return string(bytes.TrimRight(buf.Bytes(), "\n")), true
+}
+
+// formatLag renders the ingestion lag column, highlighting large delays
+func (p *Printer) formatLag(lag time.Duration) string {
+	text := fmt.Sprintf("lag %s", lag.Round(time.Millisecond))
+	if !p.colorizer.useColor {
+		return text
+	}
+
+	switch {
+	case lag >= time.Minute:
+		return p.colorizer.color(color.FgRed, color.Bold).Sprint(text)
+	case lag >= 10*time.Second:
+		return p.colorizer.color(color.FgYellow).Sprint(text)
+	default:
+		return p.colorizer.color(color.FgHiBlack).Sprint(text)
+	}
+}
@@ -0,0 +1,214 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	format, err := ParseOutputFormat("")
+	assert.NoError(t, err)
+	assert.Equal(t, OutputFormatText, format)
+
+	format, err = ParseOutputFormat("json")
+	assert.NoError(t, err)
+	assert.Equal(t, OutputFormatJSON, format)
+
+	_, err = ParseOutputFormat("yaml")
+	assert.Error(t, err)
+
+	_, err = ParseOutputFormat("json-array")
+	assert.Error(t, err, "reports do not support json-array")
+	format, err = ParseEntryOutputFormat("json-array")
+	assert.NoError(t, err)
+	assert.True(t, format.IsJSON())
+	_, err = ParseEntryOutputFormat("yaml")
+	assert.Error(t, err)
+}
+
+func TestPrinterJSONArray(t *testing.T) {
+	entry := LogEntry{
+		Timestamp: time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC),
+		Message:   "Starting controller",
+		LogStream: "kube-apiserver-123456",
+	}
+
+	var buf bytes.Buffer
+	printer := NewPrinter(OutputOptions{Format: OutputFormatJSONArray}, &ColorConfig{Mode: ColorModeNever})
+	printer.SetOutput(&buf)
+	printer.Print(entry)
+	printer.Print(entry)
+	printer.Close()
+	printer.Close()
+	printer.Print(entry)
+
+	var decoded []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Len(t, decoded, 2)
+	assert.Equal(t, "Starting controller", decoded[1]["message"])
+	assert.Equal(t, 2, printer.Printed())
+
+	// An empty result is an empty array
+	buf.Reset()
+	printer = NewPrinter(OutputOptions{Format: OutputFormatJSONArray}, &ColorConfig{Mode: ColorModeNever})
+	printer.SetOutput(&buf)
+	printer.Close()
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+func TestPrinterFormatJSON(t *testing.T) {
+	entry := LogEntry{
+		Timestamp:     time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC),
+		IngestionTime: time.Date(2024, 7, 19, 6, 9, 13, 500000000, time.UTC),
+		Level:         "info",
+		Component:     "kube-apiserver",
+		Message:       "I0719 06:09:10.476002 1 controller.go:123] Starting <controller>",
+		LogGroup:      "/aws/eks/test/cluster",
+		LogStream:     "kube-apiserver-123456",
+	}
+
+	printer := NewPrinter(OutputOptions{Format: OutputFormatJSON}, &ColorConfig{Mode: ColorModeNever})
+	result, ok := printer.Format(entry)
+	assert.True(t, ok)
+	assert.Equal(t, `{"@timestamp":"2024-07-19T06:09:12Z","level":"info","component":"kube-apiserver",`+
+		`"message":"I0719 06:09:10.476002 1 controller.go:123] Starting <controller>",`+
+		`"log_group":"/aws/eks/test/cluster","log_stream":"kube-apiserver-123456"}`, result)
+
+	printer = NewPrinter(OutputOptions{Format: OutputFormatJSON, ShowLag: true}, &ColorConfig{Mode: ColorModeNever})
+	result, ok = printer.Format(entry)
+	assert.True(t, ok)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(result), &decoded))
+	assert.Equal(t, float64(3023), decoded["ingestion_lag_ms"])
+
+	metadata := &Metadata{
+		Account:         "123456789012",
+		ClusterARN:      "arn:aws:eks:us-east-1:123456789012:cluster/test",
+		PlatformVersion: "eks.7",
+	}
+	printer = NewPrinter(OutputOptions{Format: OutputFormatJSON, Metadata: metadata}, &ColorConfig{Mode: ColorModeNever})
+	result, ok = printer.Format(entry)
+	assert.True(t, ok)
+
+	decoded = nil
+	assert.NoError(t, json.Unmarshal([]byte(result), &decoded))
+	assert.Equal(t, "123456789012", decoded["account"])
+	assert.Equal(t, "arn:aws:eks:us-east-1:123456789012:cluster/test", decoded["cluster_arn"])
+	assert.Equal(t, "eks.7", decoded["platform_version"])
+}
+
+func TestPrinterFormatLag(t *testing.T) {
+	entry := LogEntry{
+		Timestamp:     time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC),
+		IngestionTime: time.Date(2024, 7, 19, 6, 9, 14, 0, time.UTC),
+		Level:         "info",
+		Component:     "kube-apiserver",
+		Message:       "Starting controller",
+		LogStream:     "kube-apiserver-123456",
+	}
+
+	printer := NewPrinter(OutputOptions{ShowLag: true}, &ColorConfig{Mode: ColorModeNever})
+	result, ok := printer.Format(entry)
+	assert.True(t, ok)
+	assert.Equal(t, "2024-07-19T06:09:12Z [info] [kube-apiserver] [lag 2s] Starting controller", result)
+
+	// No lag column when the ingestion time is unknown
+	entry.IngestionTime = time.Time{}
+	result, ok = printer.Format(entry)
+	assert.True(t, ok)
+	assert.Equal(t, "2024-07-19T06:09:12Z [info] [kube-apiserver] Starting controller", result)
+}
+
+func TestPrinterMessagePrefix(t *testing.T) {
+	entry := LogEntry{
+		Timestamp: time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC),
+		Message:   `{"verb":"get"}`,
+		LogGroup:  "/aws/eks/prod/cluster",
+		LogStream: "kube-apiserver-audit-123456",
+	}
+
+	printer := NewPrinter(OutputOptions{MessageOnly: true, Prefix: []string{"cluster", "type", "stream"}}, &ColorConfig{Mode: ColorModeNever})
+	result, ok := printer.Format(entry)
+	assert.True(t, ok)
+	assert.Equal(t, "prod\taudit\tkube-apiserver-audit-123456\t{\"verb\":\"get\"}", result)
+
+	entry.LogGroup = CloudTrailLogGroup
+	printer = NewPrinter(OutputOptions{MessageOnly: true, Prefix: []string{"cluster"}}, &ColorConfig{Mode: ColorModeNever})
+	result, _ = printer.Format(entry)
+	assert.Equal(t, "-\t{\"verb\":\"get\"}", result)
+
+	assert.NoError(t, ValidatePrefixFields([]string{"type", "stream"}))
+	assert.Error(t, ValidatePrefixFields([]string{"level"}))
+}
+
+func TestPrinterRaw(t *testing.T) {
+	message := "E0719 06:09:12.000000 1 controller.go:42] \x1b[1mfailed\x1b[0m token=abc  \r\n\tat frame\n"
+	entry := LogEntry{
+		Timestamp: time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC),
+		Message:   message,
+		LogGroup:  "/aws/eks/prod/cluster",
+		LogStream: "kube-controller-manager-123456",
+	}
+
+	// The message is printed as is, whatever the other options
+	printer := NewPrinter(OutputOptions{Raw: true, Redactor: NewRedactor(false), Truncate: 10}, &ColorConfig{Mode: ColorModeAlways})
+	var buf bytes.Buffer
+	printer.SetOutput(&buf)
+	printer.Print(entry)
+	assert.Equal(t, message+"\n", buf.String())
+}
+
+func TestPrinterLabel(t *testing.T) {
+	entry := LogEntry{
+		Timestamp: time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC),
+		Message:   "Unable to authenticate the request",
+		LogGroup:  "/aws/eks/prod/cluster",
+		LogStream: "kube-apiserver-123456",
+		Label:     "auth-failures",
+	}
+
+	printer := NewPrinter(OutputOptions{MessageOnly: true}, &ColorConfig{Mode: ColorModeNever})
+	result, ok := printer.Format(entry)
+	assert.True(t, ok)
+	assert.Equal(t, "[auth-failures] Unable to authenticate the request", result)
+
+	printer = NewPrinter(OutputOptions{Format: OutputFormatJSON}, &ColorConfig{Mode: ColorModeNever})
+	result, ok = printer.Format(entry)
+	assert.True(t, ok)
+	assert.Contains(t, result, `"label":"auth-failures"`)
+
+	// Entries of a single query are not labeled
+	entry.Label = ""
+	result, _ = printer.Format(entry)
+	assert.NotContains(t, result, "label")
+}
+
+func TestPrinterFormatJSONIngestionTime(t *testing.T) {
+	entry := LogEntry{
+		Timestamp:     time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC),
+		IngestionTime: time.Date(2024, 7, 19, 6, 9, 13, 500000000, time.UTC),
+		Message:       "Starting controller",
+		LogGroup:      "/aws/eks/test/cluster",
+		LogStream:     "kube-apiserver-123456",
+		EventID:       "38318629271012345678901234567890123456789012345678901234",
+	}
+
+	printer := NewPrinter(OutputOptions{Format: OutputFormatJSON, IngestionTime: true}, &ColorConfig{Mode: ColorModeNever})
+	result, ok := printer.Format(entry)
+	assert.True(t, ok)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(result), &decoded))
+	assert.Equal(t, "2024-07-19T06:09:13.5Z", decoded["ingestion_time"])
+	assert.Equal(t, entry.EventID, decoded["event_id"])
+
+	// Entries without an ingestion time, e.g. CloudTrail events, leave it out
+	entry.IngestionTime = time.Time{}
+	result, _ = printer.Format(entry)
+	assert.NotContains(t, result, "ingestion_time")
+}
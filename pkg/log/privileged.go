@@ -0,0 +1,156 @@
+package log
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MastersGroup is the Kubernetes group whose members bypass RBAC
+const MastersGroup = "system:masters"
+
+// Reasons an audit event is reported as privileged
+const (
+	ReasonImpersonation = "impersonation"
+	ReasonMastersWrite  = "system:masters write"
+)
+
+// writeVerbs are the audit verbs that change the cluster
+var writeVerbs = map[string]bool{
+	"create":           true,
+	"update":           true,
+	"patch":            true,
+	"delete":           true,
+	"deletecollection": true,
+}
+
+// auditEvent holds the fields of a Kubernetes audit event used to detect privileged use
+type auditEvent struct {
+	Verb                     string    `json:"verb"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	User                     struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+	} `json:"user"`
+	ImpersonatedUser *struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+	} `json:"impersonatedUser"`
+	ObjectRef struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+	} `json:"objectRef"`
+	RequestURI     string   `json:"requestURI"`
+	SourceIPs      []string `json:"sourceIPs"`
+	ResponseStatus struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+}
+
+// PrivilegedEvent is an audit event where a user impersonated another, or a member of
+// system:masters changed the cluster
+type PrivilegedEvent struct {
+	Time         time.Time `json:"time"`
+	User         string    `json:"user"`
+	Reason       string    `json:"reason"`
+	Impersonated string    `json:"impersonated,omitempty"`
+	Verb         string    `json:"verb"`
+	Resource     string    `json:"resource,omitempty"`
+	Namespace    string    `json:"namespace,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	RequestURI   string    `json:"request_uri"`
+	Code         int       `json:"code,omitempty"`
+	SourceIP     string    `json:"source_ip,omitempty"`
+}
+
+// UserTimeline is the privileged events of one user, oldest first
+type UserTimeline struct {
+	User   string            `json:"user"`
+	Events []PrivilegedEvent `json:"events"`
+}
+
+// ClassifyPrivileged reports whether an audit log entry used impersonation or was a
+// write by a member of system:masters, and describes it
+func ClassifyPrivileged(entry LogEntry) (PrivilegedEvent, bool) {
+	var event auditEvent
+	if err := json.Unmarshal([]byte(entry.Message), &event); err != nil {
+		return PrivilegedEvent{}, false
+	}
+
+	var reason, impersonated string
+	switch {
+	case event.ImpersonatedUser != nil && event.ImpersonatedUser.Username != "":
+		reason = ReasonImpersonation
+		impersonated = event.ImpersonatedUser.Username
+	case writeVerbs[event.Verb] && contains(event.User.Groups, MastersGroup):
+		reason = ReasonMastersWrite
+	default:
+		return PrivilegedEvent{}, false
+	}
+
+	resource := event.ObjectRef.Resource
+	if event.ObjectRef.Subresource != "" {
+		resource += "/" + event.ObjectRef.Subresource
+	}
+	timestamp := event.RequestReceivedTimestamp
+	if timestamp.IsZero() {
+		timestamp = entry.Timestamp
+	}
+	var sourceIP string
+	if len(event.SourceIPs) > 0 {
+		sourceIP = event.SourceIPs[0]
+	}
+
+	return PrivilegedEvent{
+		Time:         timestamp.UTC(),
+		User:         event.User.Username,
+		Reason:       reason,
+		Impersonated: impersonated,
+		Verb:         event.Verb,
+		Resource:     resource,
+		Namespace:    event.ObjectRef.Namespace,
+		Name:         event.ObjectRef.Name,
+		RequestURI:   event.RequestURI,
+		Code:         event.ResponseStatus.Code,
+		SourceIP:     sourceIP,
+	}, true
+}
+
+// GroupByUser groups privileged events into per-user timelines sorted by time. Users are
+// ordered by their number of events, most first.
+func GroupByUser(events []PrivilegedEvent) []UserTimeline {
+	byUser := make(map[string][]PrivilegedEvent)
+	for _, event := range events {
+		byUser[event.User] = append(byUser[event.User], event)
+	}
+
+	timelines := make([]UserTimeline, 0, len(byUser))
+	for user, userEvents := range byUser {
+		sort.SliceStable(userEvents, func(i, j int) bool {
+			return userEvents[i].Time.Before(userEvents[j].Time)
+		})
+		timelines = append(timelines, UserTimeline{User: user, Events: userEvents})
+	}
+	sort.Slice(timelines, func(i, j int) bool {
+		if len(timelines[i].Events) != len(timelines[j].Events) {
+			return len(timelines[i].Events) > len(timelines[j].Events)
+		}
+		return timelines[i].User < timelines[j].User
+	})
+	return timelines
+}
+
+// Target returns the object an event acted on, e.g. "secrets kube-system/aws-auth"
+func (e PrivilegedEvent) Target() string {
+	object := e.Name
+	if e.Namespace != "" {
+		object = e.Namespace + "/" + e.Name
+	}
+	if e.Resource == "" {
+		return e.RequestURI
+	}
+	return strings.TrimSpace(e.Resource + " " + object)
+}
@@ -0,0 +1,74 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyPrivileged(t *testing.T) {
+	impersonated := LogEntry{Message: `{"verb":"get","requestReceivedTimestamp":"2024-01-01T10:00:00.123456Z",` +
+		`"user":{"username":"alice","groups":["devs"]},"impersonatedUser":{"username":"bob"},` +
+		`"objectRef":{"resource":"pods","namespace":"default","name":"web"},"requestURI":"/api/v1/namespaces/default/pods/web",` +
+		`"sourceIPs":["10.0.1.5"],"responseStatus":{"code":200}}`}
+	event, ok := ClassifyPrivileged(impersonated)
+	assert.True(t, ok)
+	assert.Equal(t, PrivilegedEvent{
+		Time:         time.Date(2024, 1, 1, 10, 0, 0, 123456000, time.UTC),
+		User:         "alice",
+		Reason:       ReasonImpersonation,
+		Impersonated: "bob",
+		Verb:         "get",
+		Resource:     "pods",
+		Namespace:    "default",
+		Name:         "web",
+		RequestURI:   "/api/v1/namespaces/default/pods/web",
+		Code:         200,
+		SourceIP:     "10.0.1.5",
+	}, event)
+	assert.Equal(t, "pods default/web", event.Target())
+
+	mastersWrite := LogEntry{Message: `{"verb":"patch","user":{"username":"kubernetes-admin","groups":["system:masters","system:authenticated"]},` +
+		`"objectRef":{"resource":"configmaps","subresource":"status","namespace":"kube-system","name":"aws-auth"}}`}
+	event, ok = ClassifyPrivileged(mastersWrite)
+	assert.True(t, ok)
+	assert.Equal(t, ReasonMastersWrite, event.Reason)
+	assert.Equal(t, "configmaps/status kube-system/aws-auth", event.Target())
+
+	// Membership is found anywhere in the groups array, and mentions elsewhere are ignored
+	lateGroup := LogEntry{Message: `{"verb":"delete","user":{"username":"ops","groups":["a","b","c","d","e","system:masters"]},` +
+		`"objectRef":{"resource":"secrets","namespace":"default","name":"db"}}`}
+	_, ok = ClassifyPrivileged(lateGroup)
+	assert.True(t, ok)
+	_, ok = ClassifyPrivileged(LogEntry{Message: `{"verb":"create","user":{"username":"alice","groups":["devs"]},` +
+		`"objectRef":{"resource":"clusterrolebindings","name":"system:masters-viewer"}}`})
+	assert.False(t, ok)
+
+	// Reads by system:masters, ordinary writes and non-JSON messages are not reported
+	for _, message := range []string{
+		`{"verb":"list","user":{"username":"kubernetes-admin","groups":["system:masters"]}}`,
+		`{"verb":"create","user":{"username":"alice","groups":["devs"]}}`,
+		`I0101 10:00:00.000000 1 controller.go:1] not an audit event`,
+	} {
+		_, ok := ClassifyPrivileged(LogEntry{Message: message})
+		assert.False(t, ok, message)
+	}
+}
+
+func TestGroupByUser(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2024, 1, 1, 10, minute, 0, 0, time.UTC) }
+	events := []PrivilegedEvent{
+		{User: "bob", Time: at(5)},
+		{User: "alice", Time: at(3)},
+		{User: "bob", Time: at(1)},
+		{User: "carol", Time: at(2)},
+	}
+
+	timelines := GroupByUser(events)
+	assert.Len(t, timelines, 3)
+	assert.Equal(t, "bob", timelines[0].User)
+	assert.Equal(t, []time.Time{at(1), at(5)}, []time.Time{timelines[0].Events[0].Time, timelines[0].Events[1].Time})
+	assert.Equal(t, "alice", timelines[1].User)
+	assert.Equal(t, "carol", timelines[2].User)
+}
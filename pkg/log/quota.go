@@ -0,0 +1,166 @@
+package log
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of admission policies denying a request for its resource usage
+const (
+	KindResourceQuota = "ResourceQuota"
+	KindLimitRange    = "LimitRange"
+)
+
+var (
+	// quotaNamePattern matches the ResourceQuota of a quota denial, e.g.
+	// "exceeded quota: compute-resources, requested: ..." or "failed quota: compute: must specify ..."
+	quotaNamePattern = regexp.MustCompile(`(?:exceeded|failed) quota: ([^,:\s]+)`)
+	// limitRangePattern matches the constraint of a LimitRange denial, e.g.
+	// "maximum cpu usage per Container is 1, but limit is 2" or
+	// "cpu max limit to request ratio per Container is 2, but provided ratio is 4.000000"
+	limitRangePattern = regexp.MustCompile(`(?:(maximum|minimum) (\S+) usage|(\S+) max limit to request ratio) per (\w+) is`)
+)
+
+// QuotaDenial is a request denied by a ResourceQuota or LimitRange of its namespace
+type QuotaDenial struct {
+	Time      time.Time
+	Namespace string
+	Kind      string
+	// Policy is the ResourceQuota name, or the LimitRange constraint, e.g.
+	// "maximum cpu per Container"
+	Policy   string
+	Resource string
+	Name     string
+	User     string
+	Reason   string
+}
+
+// QuotaGroup counts the denials of one policy in a namespace
+type QuotaGroup struct {
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Policy    string    `json:"policy"`
+	Count     int       `json:"count"`
+	Users     []string  `json:"users"`
+	Reason    string    `json:"reason"`
+	First     time.Time `json:"first"`
+	Last      time.Time `json:"last"`
+}
+
+// auditDenialEvent holds the fields of an audit event used to attribute a denial
+type auditDenialEvent struct {
+	Stage                    string    `json:"stage"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	User                     struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectRef struct {
+		Resource  string `json:"resource"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+	} `json:"objectRef"`
+	ResponseStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"responseStatus"`
+}
+
+// ParseQuotaDenial extracts a ResourceQuota or LimitRange denial from an audit event
+func ParseQuotaDenial(entry LogEntry) (QuotaDenial, bool) {
+	var event auditDenialEvent
+	if err := json.Unmarshal([]byte(entry.Message), &event); err != nil || event.ResponseStatus.Code != 403 {
+		return QuotaDenial{}, false
+	}
+	if event.Stage != "" && event.Stage != "ResponseComplete" {
+		return QuotaDenial{}, false
+	}
+	message := event.ResponseStatus.Message
+
+	var kind, policy string
+	if match := quotaNamePattern.FindStringSubmatch(message); match != nil {
+		kind, policy = KindResourceQuota, match[1]
+	} else if match := limitRangePattern.FindStringSubmatch(message); match != nil {
+		kind = KindLimitRange
+		if match[1] != "" {
+			policy = match[1] + " " + match[2] + " per " + match[4]
+		} else {
+			policy = match[3] + " limit to request ratio per " + match[4]
+		}
+	} else {
+		return QuotaDenial{}, false
+	}
+
+	reason := message
+	if _, after, found := strings.Cut(message, "is forbidden: "); found {
+		reason = after
+	}
+	timestamp := event.RequestReceivedTimestamp
+	if timestamp.IsZero() {
+		timestamp = entry.Timestamp
+	}
+	return QuotaDenial{
+		Time:      timestamp.UTC(),
+		Namespace: event.ObjectRef.Namespace,
+		Kind:      kind,
+		Policy:    policy,
+		Resource:  event.ObjectRef.Resource,
+		Name:      event.ObjectRef.Name,
+		User:      event.User.Username,
+		Reason:    reason,
+	}, true
+}
+
+// GroupQuotaDenials counts the denials per namespace, kind and policy, keeping the reason
+// of the latest denial. Namespaces with the most denials come first, and their policies
+// with the most denials first.
+func GroupQuotaDenials(denials []QuotaDenial) []QuotaGroup {
+	type groupKey struct{ namespace, kind, policy string }
+	groups := make(map[groupKey]*QuotaGroup)
+	users := make(map[groupKey]map[string]bool)
+	perNamespace := make(map[string]int)
+	for _, denial := range denials {
+		key := groupKey{denial.Namespace, denial.Kind, denial.Policy}
+		group, exists := groups[key]
+		if !exists {
+			group = &QuotaGroup{Namespace: denial.Namespace, Kind: denial.Kind, Policy: denial.Policy, First: denial.Time, Last: denial.Time, Reason: denial.Reason}
+			groups[key] = group
+			users[key] = make(map[string]bool)
+		}
+		group.Count++
+		perNamespace[denial.Namespace]++
+		if denial.User != "" && !users[key][denial.User] {
+			users[key][denial.User] = true
+			group.Users = append(group.Users, denial.User)
+		}
+		if denial.Time.Before(group.First) {
+			group.First = denial.Time
+		}
+		if !denial.Time.Before(group.Last) {
+			group.Last = denial.Time
+			group.Reason = denial.Reason
+		}
+	}
+
+	result := make([]QuotaGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Strings(group.Users)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Namespace != b.Namespace {
+			if perNamespace[a.Namespace] != perNamespace[b.Namespace] {
+				return perNamespace[a.Namespace] > perNamespace[b.Namespace]
+			}
+			return a.Namespace < b.Namespace
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Kind+a.Policy < b.Kind+b.Policy
+	})
+	return result
+}
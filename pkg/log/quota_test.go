@@ -0,0 +1,80 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseQuotaDenial(t *testing.T) {
+	exceeded := LogEntry{Message: `{"stage":"ResponseComplete","requestReceivedTimestamp":"2024-01-01T10:00:00Z",` +
+		`"user":{"username":"system:serviceaccount:kube-system:replicaset-controller"},` +
+		`"objectRef":{"resource":"pods","namespace":"team-a","name":"web-7d9f"},` +
+		`"responseStatus":{"code":403,"message":"pods \"web-7d9f\" is forbidden: exceeded quota: compute, requested: limits.cpu=2, used: limits.cpu=4, limited: limits.cpu=5"}}`}
+	denial, ok := ParseQuotaDenial(exceeded)
+	assert.True(t, ok)
+	assert.Equal(t, QuotaDenial{
+		Time:      time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		Namespace: "team-a",
+		Kind:      KindResourceQuota,
+		Policy:    "compute",
+		Resource:  "pods",
+		Name:      "web-7d9f",
+		User:      "system:serviceaccount:kube-system:replicaset-controller",
+		Reason:    "exceeded quota: compute, requested: limits.cpu=2, used: limits.cpu=4, limited: limits.cpu=5",
+	}, denial)
+
+	tests := []struct {
+		message string
+		kind    string
+		policy  string
+	}{
+		{`pods \"web\" is forbidden: failed quota: compute: must specify limits.cpu for: app`, KindResourceQuota, "compute"},
+		{`pods \"web\" is forbidden: [maximum cpu usage per Container is 1, but limit is 2, maximum memory usage per Container is 1Gi, but limit is 2Gi]`, KindLimitRange, "maximum cpu per Container"},
+		{`pods \"web\" is forbidden: minimum memory usage per Pod is 64Mi, but request is 32Mi`, KindLimitRange, "minimum memory per Pod"},
+		{`pods \"web\" is forbidden: cpu max limit to request ratio per Container is 2, but provided ratio is 4.000000`, KindLimitRange, "cpu limit to request ratio per Container"},
+	}
+	for _, tt := range tests {
+		denial, ok := ParseQuotaDenial(LogEntry{Message: `{"objectRef":{"namespace":"team-a"},"responseStatus":{"code":403,"message":"` + tt.message + `"}}`})
+		assert.True(t, ok, tt.message)
+		assert.Equal(t, tt.kind, denial.Kind, tt.message)
+		assert.Equal(t, tt.policy, denial.Policy, tt.message)
+	}
+
+	for _, message := range []string{
+		`{"responseStatus":{"code":403,"message":"pods is forbidden: User \"alice\" cannot list resource \"pods\""}}`,
+		`{"responseStatus":{"code":201}}`,
+		`{"stage":"ResponseStarted","responseStatus":{"code":403,"message":"exceeded quota: compute"}}`,
+		`I0101 10:00:00.000000 1 controller.go:1] exceeded quota: compute`,
+	} {
+		_, ok := ParseQuotaDenial(LogEntry{Message: message})
+		assert.False(t, ok, message)
+	}
+}
+
+func TestGroupQuotaDenials(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2024, 1, 1, 10, minute, 0, 0, time.UTC) }
+	denials := []QuotaDenial{
+		{Time: at(1), Namespace: "team-a", Kind: KindResourceQuota, Policy: "compute", User: "rs-controller", Reason: "first"},
+		{Time: at(3), Namespace: "team-a", Kind: KindResourceQuota, Policy: "compute", User: "rs-controller", Reason: "latest"},
+		{Time: at(2), Namespace: "team-a", Kind: KindResourceQuota, Policy: "compute", User: "alice", Reason: "middle"},
+		{Time: at(4), Namespace: "team-a", Kind: KindLimitRange, Policy: "maximum cpu per Container", User: "alice"},
+		{Time: at(5), Namespace: "team-b", Kind: KindResourceQuota, Policy: "objects", User: "bob"},
+	}
+
+	groups := GroupQuotaDenials(denials)
+	assert.Len(t, groups, 3)
+	assert.Equal(t, QuotaGroup{
+		Namespace: "team-a",
+		Kind:      KindResourceQuota,
+		Policy:    "compute",
+		Count:     3,
+		Users:     []string{"alice", "rs-controller"},
+		Reason:    "latest",
+		First:     at(1),
+		Last:      at(3),
+	}, groups[0])
+	assert.Equal(t, KindLimitRange, groups[1].Kind)
+	assert.Equal(t, "team-b", groups[2].Namespace)
+}
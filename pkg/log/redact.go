@@ -0,0 +1,332 @@
+package log
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces masked values
+const redacted = "[REDACTED]"
+
+// lastAppliedAnnotation is the annotation in which kubectl apply stores the applied object
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+var (
+	// authorizationPattern matches Authorization header values in text and JSON
+	authorizationPattern = regexp.MustCompile(`(?i)(authorization"?\s*[:=]\s*\[?\s*"?)[^"\s,\]}]+(?:\s+[^"\s,\]}]+)?`)
+	// bearerPattern matches bearer tokens
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	// eksTokenPattern matches the tokens of aws-iam-authenticator
+	eksTokenPattern = regexp.MustCompile(`k8s-aws-v1\.[A-Za-z0-9_\-]+`)
+	// ipv4Pattern matches IPv4 addresses
+	ipv4Pattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// logfmtUserPattern matches usernames, user IDs and ARNs of logfmt messages, as logged by
+	// aws-iam-authenticator
+	logfmtUserPattern = regexp.MustCompile(`\b(username|user|uid|arn)=("?)([^"\s]+)`)
+)
+
+// secretKeys are the JSON keys whose string values are credentials
+var secretKeys = map[string]bool{
+	"token":         true,
+	"password":      true,
+	"secret":        true,
+	"clientsecret":  true,
+	"client_secret": true,
+	"accesskey":     true,
+	"secretkey":     true,
+}
+
+// Redactor masks credentials in log messages so they can be shared: bearer tokens,
+// Authorization headers, authenticator tokens, the data of Secrets (also in the
+// last-applied-configuration annotation) and credential fields of JSON messages. With
+// identities, source IPs, usernames, user IDs and IAM identities (ARNs, session names and
+// principal IDs of user.extra) are replaced by hashes that stay the same within a run, so
+// one actor's events can still be followed.
+type Redactor struct {
+	identities bool
+	salt       []byte
+}
+
+// NewRedactor creates a Redactor. The hashes of identities are salted with a random value,
+// so they cannot be reversed by hashing every IP address.
+func NewRedactor(identities bool) *Redactor {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	return &Redactor{identities: identities, salt: salt}
+}
+
+// Redact returns the message with credentials masked
+func (r *Redactor) Redact(message string) string {
+	trimmed := strings.TrimSpace(message)
+	if strings.HasPrefix(trimmed, "{") {
+		if result, ok := r.redactJSON(trimmed); ok {
+			return result
+		}
+	}
+	return r.redactText(message)
+}
+
+// redactText masks credentials in free text
+func (r *Redactor) redactText(text string) string {
+	text = authorizationPattern.ReplaceAllString(text, "${1}"+redacted)
+	text = bearerPattern.ReplaceAllString(text, "${1}"+redacted)
+	text = eksTokenPattern.ReplaceAllString(text, redacted)
+	if r.identities {
+		text = ipv4Pattern.ReplaceAllStringFunc(text, func(ip string) string {
+			return r.hash("ip", ip)
+		})
+		text = logfmtUserPattern.ReplaceAllStringFunc(text, func(match string) string {
+			parts := logfmtUserPattern.FindStringSubmatch(match)
+			kind := "user"
+			if parts[1] == "uid" || parts[1] == "arn" {
+				kind = parts[1]
+			}
+			return parts[1] + "=" + parts[2] + r.hash(kind, parts[3])
+		})
+	}
+	return text
+}
+
+// redactJSON masks credentials in a JSON message such as an audit event, keeping the order
+// of its keys. It returns false if the message is not a JSON object.
+func (r *Redactor) redactJSON(message string) (string, bool) {
+	decoder := json.NewDecoder(strings.NewReader(message))
+	decoder.UseNumber()
+	value, err := decodeOrdered(decoder)
+	if err != nil {
+		return "", false
+	}
+	if _, ok := value.(jsonObject); !ok {
+		return "", false
+	}
+
+	changed := false
+	redactedValue := r.redactValue("", value, &changed)
+	if !changed {
+		return message, true
+	}
+
+	var buf bytes.Buffer
+	if err := encodeOrdered(&buf, redactedValue); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// redactValue masks the credentials of a decoded JSON value found under key, setting
+// changed when anything was masked
+func (r *Redactor) redactValue(key string, value interface{}, changed *bool) interface{} {
+	switch v := value.(type) {
+	case jsonObject:
+		// The data of a Secret, in request and response objects of audit events
+		isSecret := v.get("kind") == "Secret"
+		isUser := r.identities && (key == "user" || key == "impersonatedUser")
+		for i, member := range v {
+			switch {
+			case isSecret && (member.key == "data" || member.key == "stringData"):
+				v[i].value = maskValues(member.value)
+				*changed = true
+			case isUser && (member.key == "uid" || member.key == "extra"):
+				// user.extra holds the IAM ARN, session name and principal ID of the caller
+				v[i].value = r.hashValues(member.key, member.value, changed)
+			default:
+				v[i].value = r.redactValue(member.key, member.value, changed)
+			}
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.redactValue(key, item, changed)
+		}
+		return v
+	case string:
+		result := r.redactString(key, v)
+		if result != v {
+			*changed = true
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// redactString masks a JSON string value found under key
+func (r *Redactor) redactString(key, value string) string {
+	if secretKeys[strings.ToLower(key)] && value != "" {
+		return redacted
+	}
+	if key == lastAppliedAnnotation {
+		// The annotation holds the applied object as JSON, including the data of a Secret
+		result, ok := r.redactJSON(value)
+		if !ok {
+			return redacted
+		}
+		return result
+	}
+	if r.identities {
+		switch key {
+		case "sourceIPs", "sourceIPAddress":
+			return r.hash("ip", value)
+		case "username":
+			return r.hash("user", value)
+		}
+	}
+	return r.redactText(value)
+}
+
+// hashValues replaces every string of a JSON value with its hash
+func (r *Redactor) hashValues(kind string, value interface{}, changed *bool) interface{} {
+	switch v := value.(type) {
+	case jsonObject:
+		for i, member := range v {
+			v[i].value = r.hashValues(member.key, member.value, changed)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = r.hashValues(kind, item, changed)
+		}
+		return v
+	case string:
+		*changed = true
+		return r.hash(kind, v)
+	default:
+		return value
+	}
+}
+
+// maskValues replaces every value of a JSON object with the redaction marker
+func maskValues(value interface{}) interface{} {
+	fields, ok := value.(jsonObject)
+	if !ok {
+		return redacted
+	}
+	for i := range fields {
+		fields[i].value = redacted
+	}
+	return fields
+}
+
+// jsonObject is a decoded JSON object keeping the order of its members, so redacted
+// messages read like the original
+type jsonObject []jsonMember
+
+// jsonMember is a key and value of a JSON object
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+// get returns the value of a key, or nil
+func (o jsonObject) get(key string) interface{} {
+	for _, member := range o {
+		if member.key == key {
+			return member.value
+		}
+	}
+	return nil
+}
+
+// decodeOrdered decodes the next JSON value, representing objects as jsonObject
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		object := jsonObject{}
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, _ := keyToken.(string)
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, jsonMember{key: key, value: value})
+		}
+		_, err = decoder.Token() // }
+		return object, err
+	case '[':
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token() // ]
+		return array, err
+	default:
+		return nil, fmt.Errorf("unexpected JSON delimiter %v", delim)
+	}
+}
+
+// encodeOrdered writes a value decoded by decodeOrdered as compact JSON
+func encodeOrdered(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case jsonObject:
+		buf.WriteByte('{')
+		for i, member := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeScalar(buf, member.key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeOrdered(buf, member.value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeOrdered(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	default:
+		return encodeScalar(buf, v)
+	}
+}
+
+// encodeScalar writes a string, number, boolean or null without escaping HTML characters
+func encodeScalar(buf *bytes.Buffer, value interface{}) error {
+	var scalar bytes.Buffer
+	encoder := json.NewEncoder(&scalar)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return err
+	}
+	buf.Write(bytes.TrimRight(scalar.Bytes(), "\n"))
+	return nil
+}
+
+// hash returns a short salted hash of an identity, e.g. "user-1a2b3c4d"
+func (r *Redactor) hash(kind, value string) string {
+	sum := sha256.Sum256(append(append([]byte{}, r.salt...), value...))
+	return kind + "-" + hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Sampler keeps a subset of log entries: either every Nth entry or a random fraction
+type Sampler struct {
+	every    int64   // keep every Nth entry (0 when sampling randomly)
+	fraction float64 // probability of keeping an entry
+
+	mu    sync.Mutex
+	count int64
+	rng   *rand.Rand
+}
+
+// ParseSampler parses a sampling specification: "1/N" keeps every Nth entry,
+// while a fraction ("0.02") or percentage ("2%") keeps entries at random with that probability
+func ParseSampler(spec string) (*Sampler, error) {
+	spec = strings.TrimSpace(spec)
+
+	if numerator, denominator, ok := strings.Cut(spec, "/"); ok {
+		if strings.TrimSpace(numerator) != "1" {
+			return nil, fmt.Errorf("invalid sample '%s': expected 1/N", spec)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(denominator), 10, 64)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid sample '%s': N must be a positive integer", spec)
+		}
+		return &Sampler{every: n}, nil
+	}
+
+	value := spec
+	scale := 1.0
+	if strings.HasSuffix(value, "%") {
+		value = strings.TrimSuffix(value, "%")
+		scale = 100
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 || f/scale > 1 {
+		return nil, fmt.Errorf("invalid sample '%s' (expected 1/N, a fraction such as 0.02 or a percentage such as 2%%)", spec)
+	}
+
+	return &Sampler{fraction: f / scale, rng: rand.New(rand.NewSource(rand.Int63()))}, nil
+}
+
+// Keep reports whether the next entry should be kept. It is safe for concurrent use.
+func (s *Sampler) Keep() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.every > 0 {
+		s.count++
+		return (s.count-1)%s.every == 0
+	}
+	return s.rng.Float64() < s.fraction
+}
+
+// Wrap returns a print function that only forwards sampled entries
+func (s *Sampler) Wrap(printFunc func(LogEntry)) func(LogEntry) {
+	return func(entry LogEntry) {
+		if s.Keep() {
+			printFunc(entry)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSampler(t *testing.T) {
+	tests := []struct {
+		spec     string
+		every    int64
+		fraction float64
+		wantErr  bool
+	}{
+		{spec: "1/50", every: 50},
+		{spec: "1 / 10", every: 10},
+		{spec: "0.02", fraction: 0.02},
+		{spec: "2%", fraction: 0.02},
+		{spec: "1", fraction: 1},
+		{spec: "2/50", wantErr: true},
+		{spec: "1/0", wantErr: true},
+		{spec: "0", wantErr: true},
+		{spec: "150%", wantErr: true},
+		{spec: "often", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			sampler, err := ParseSampler(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.every, sampler.every)
+			assert.InDelta(t, tt.fraction, sampler.fraction, 1e-9)
+		})
+	}
+}
+
+func TestSamplerEveryNth(t *testing.T) {
+	sampler, err := ParseSampler("1/3")
+	assert.NoError(t, err)
+
+	var kept []string
+	printFunc := sampler.Wrap(func(entry LogEntry) {
+		kept = append(kept, entry.Message)
+	})
+	for _, message := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		printFunc(LogEntry{Message: message})
+	}
+
+	assert.Equal(t, []string{"a", "d", "g"}, kept)
+}
+
+func TestSamplerFraction(t *testing.T) {
+	sampler, err := ParseSampler("10%")
+	assert.NoError(t, err)
+
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		if sampler.Keep() {
+			kept++
+		}
+	}
+	assert.InDelta(t, 1000, kept, 200)
+}
@@ -0,0 +1,225 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kinds of the replica and eviction decisions reported by TraceScaling
+const (
+	ScalingRescale         = "hpa rescale"
+	ScalingHPAFailure      = "hpa failed"
+	ScalingScale           = "scaled"
+	ScalingUpdated         = "updated"
+	ScalingEviction        = "evicted"
+	ScalingEvictionBlocked = "eviction blocked"
+	ScalingTaintEviction   = "taint eviction"
+	ScalingNodePressure    = "node-pressure eviction"
+)
+
+// scalableResources are the workload resources whose replicas an HPA or a user scales
+var scalableResources = map[string]bool{
+	"deployments":  true,
+	"statefulsets": true,
+	"replicasets":  true,
+}
+
+var (
+	// rescalePattern matches the rescale decisions of the HPA controller, structured
+	// ("Successful rescale" HPA="shop/web" currentReplicas=3 desiredReplicas=5 reason="...")
+	// or, before Kubernetes 1.27, formatted ("Successful rescale of web, old size: 3, new size: 5, reason: ...")
+	rescalePattern = regexp.MustCompile(`Successful rescale(?: of ([\w.-]+),)?.*?(?:currentReplicas=|old size: )(\d+).*?(?:desiredReplicas=|new size: )(\d+).*?reason[=:] ?"?([^"]*)"?`)
+	// hpaKeyPattern matches the HPA of a structured controller log line, e.g. HPA="shop/web"
+	hpaKeyPattern = regexp.MustCompile(`HPA="([^"]+)"`)
+	// evictedPodPattern matches the pod of an eviction line, e.g. pod="shop/web-7d9f-x2k4q"
+	evictedPodPattern = regexp.MustCompile(`pods?=\[?"([\w.-]+/[\w.-]+)"`)
+	// pressureResourcePattern matches the resource a kubelet reclaims, e.g. resourceName="memory"
+	pressureResourcePattern = regexp.MustCompile(`resourceName="([^"]+)"`)
+)
+
+// ScalingEvent is a decision changing the replicas of a workload or evicting one of its pods
+type ScalingEvent struct {
+	Time    time.Time `json:"time"`
+	LogType string    `json:"log_type"`
+	Kind    string    `json:"kind"`
+	Summary string    `json:"summary"`
+	Pod     string    `json:"pod,omitempty"`
+	User    string    `json:"user,omitempty"`
+}
+
+// Workload identifies the workload whose scaling is traced. Its pods are named after it,
+// e.g. web-7d9f-x2k4q for the Deployment web and web-0 for the StatefulSet web.
+type Workload struct {
+	Namespace string
+	Name      string
+}
+
+// key is how component logs refer to the workload and its HPA, e.g. "shop/web"
+func (w Workload) key() string {
+	return w.Namespace + "/" + w.Name
+}
+
+// ownsPod reports whether a pod, named namespace/name, belongs to the workload
+func (w Workload) ownsPod(pod string) bool {
+	return strings.HasPrefix(pod, w.key()+"-")
+}
+
+// FilterPattern returns a filter pattern selecting the log events mentioning the workload
+// or its pods; ScalingEventsFor then keeps the decisions about it
+func (w Workload) FilterPattern() string {
+	return fmt.Sprintf("%q", w.Name)
+}
+
+// auditScalingRequest holds the fields of an audit event used to trace scaling decisions
+type auditScalingRequest struct {
+	Stage                    string    `json:"stage"`
+	Verb                     string    `json:"verb"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	User                     struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectRef struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+	} `json:"objectRef"`
+	ResponseStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"responseStatus"`
+}
+
+// ScalingEventsFor returns the scaling decision about the workload an entry reports: an
+// HPA rescale or failure, or a taint eviction of the controller manager; a node-pressure
+// eviction of the kubelet; or a scale, update or eviction request of the audit log
+func ScalingEventsFor(entry LogEntry, workload Workload) (ScalingEvent, bool) {
+	logType := LogTypeFor(entry.LogGroup, entry.LogStream)
+	if logType == "audit" {
+		return auditScalingEvent(entry, workload)
+	}
+
+	message := klogMessagePattern.ReplaceAllString(strings.TrimSpace(entry.Message), "")
+	event := ScalingEvent{Time: entry.Timestamp.UTC(), LogType: logType, Summary: message}
+	switch logType {
+	case "kcm":
+		if match := rescalePattern.FindStringSubmatch(message); match != nil {
+			hpa := match[1]
+			if key := hpaKeyPattern.FindStringSubmatch(message); key != nil {
+				hpa = key[1]
+			}
+			if hpa != workload.Name && hpa != workload.key() {
+				return ScalingEvent{}, false
+			}
+			event.Kind = ScalingRescale
+			event.Summary = fmt.Sprintf("%s -> %s replicas: %s", match[2], match[3], match[4])
+			return event, true
+		}
+		// e.g. failed to compute desired number of replicas based on listed metrics for Deployment/shop/web: ...
+		computeFailed := strings.Contains(message, "failed to compute desired number of replicas") && strings.Contains(message, "/"+workload.key()+":")
+		if computeFailed || strings.Contains(message, `HPA="`+workload.key()+`"`) && strings.Contains(strings.ToLower(message), "fail") {
+			event.Kind = ScalingHPAFailure
+			return event, true
+		}
+		if strings.Contains(message, "Deleting pod") || strings.Contains(message, "is deleting pod") {
+			match := evictedPodPattern.FindStringSubmatch(message)
+			if match == nil || !workload.ownsPod(match[1]) {
+				return ScalingEvent{}, false
+			}
+			event.Kind, event.Pod = ScalingTaintEviction, match[1]
+			return event, true
+		}
+	case "kubelet":
+		if !strings.Contains(message, "Eviction manager") || !strings.Contains(message, "evicted") {
+			return ScalingEvent{}, false
+		}
+		for _, match := range evictedPodPattern.FindAllStringSubmatch(message, -1) {
+			if workload.ownsPod(match[1]) {
+				event.Kind, event.Pod = ScalingNodePressure, match[1]
+				if resource := pressureResourcePattern.FindStringSubmatch(message); resource != nil {
+					event.Summary = resource[1] + " pressure: " + message
+				}
+				return event, true
+			}
+		}
+	}
+	return ScalingEvent{}, false
+}
+
+// auditScalingEvent returns the scale or update of the workload, or the eviction of one of
+// its pods, an audit event records
+func auditScalingEvent(entry LogEntry, workload Workload) (ScalingEvent, bool) {
+	var event auditScalingRequest
+	if err := json.Unmarshal([]byte(entry.Message), &event); err != nil {
+		return ScalingEvent{}, false
+	}
+	if event.Stage != "" && event.Stage != "ResponseComplete" || event.ObjectRef.Namespace != workload.Namespace {
+		return ScalingEvent{}, false
+	}
+
+	timestamp := event.RequestReceivedTimestamp
+	if timestamp.IsZero() {
+		timestamp = entry.Timestamp
+	}
+	scaling := ScalingEvent{Time: timestamp.UTC(), LogType: "audit", User: event.User.Username}
+	ref := event.ObjectRef
+	switch {
+	case ref.Resource == "pods" && ref.Subresource == "eviction" && event.Verb == "create":
+		pod := ref.Namespace + "/" + ref.Name
+		if !workload.ownsPod(pod) {
+			return ScalingEvent{}, false
+		}
+		scaling.Pod = pod
+		switch {
+		case event.ResponseStatus.Code == 429:
+			// The API answers 429 when a PodDisruptionBudget does not allow the eviction
+			scaling.Kind = ScalingEvictionBlocked
+		case event.ResponseStatus.Code >= 400:
+			return ScalingEvent{}, false
+		default:
+			scaling.Kind = ScalingEviction
+		}
+		scaling.Summary = "eviction of " + pod
+		if event.ResponseStatus.Message != "" {
+			scaling.Summary += ": " + event.ResponseStatus.Message
+		}
+		return scaling, true
+	case scalableResources[ref.Resource] && ref.Name == workload.Name:
+		if event.ResponseStatus.Code >= 400 {
+			return ScalingEvent{}, false
+		}
+		switch {
+		case ref.Subresource == "scale" && (event.Verb == "update" || event.Verb == "patch"):
+			scaling.Kind = ScalingScale
+		case ref.Subresource == "" && (event.Verb == "update" || event.Verb == "patch"):
+			scaling.Kind = ScalingUpdated
+		default:
+			return ScalingEvent{}, false
+		}
+		scaling.Summary = event.Verb + " " + ref.Resource
+		if ref.Subresource != "" {
+			scaling.Summary += "/" + ref.Subresource
+		}
+		return scaling, true
+	}
+	return ScalingEvent{}, false
+}
+
+// TraceScaling returns the scaling decisions about the workload reported by entries,
+// oldest first
+func TraceScaling(entries []LogEntry, workload Workload) []ScalingEvent {
+	var events []ScalingEvent
+	for _, entry := range entries {
+		if event, ok := ScalingEventsFor(entry, workload); ok {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScalingEventsFor(t *testing.T) {
+	at := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	web := Workload{Namespace: "shop", Name: "web"}
+	kcm := func(message string) LogEntry {
+		return LogEntry{Timestamp: at, LogGroup: "/aws/eks/prod/cluster", LogStream: "kube-controller-manager-abc", Message: message}
+	}
+	kubelet := func(message string) LogEntry {
+		return LogEntry{Timestamp: at, LogGroup: "/aws/containerinsights/prod/dataplane", LogStream: "ip-10-0-1-5.ec2.internal-dataplane.systemd.kubelet.service", Message: message}
+	}
+	audit := func(message string) LogEntry {
+		return LogEntry{Timestamp: at, LogGroup: "/aws/eks/prod/cluster", LogStream: "kube-apiserver-audit-abc", Message: message}
+	}
+
+	tests := []struct {
+		name    string
+		entry   LogEntry
+		kind    string
+		summary string
+		pod     string
+	}{
+		{"structured rescale", kcm(`I0101 10:00:00.000000 1 horizontal.go:881] "Successful rescale" logger="horizontal-pod-autoscaler-controller" HPA="shop/web" currentReplicas=3 desiredReplicas=5 reason="cpu resource utilization (percentage of request) above target"`),
+			ScalingRescale, "3 -> 5 replicas: cpu resource utilization (percentage of request) above target", ""},
+		{"formatted rescale", kcm(`I0101 10:00:00.000000 1 horizontal.go:722] Successful rescale of web, old size: 5, new size: 2, reason: All metrics below target`),
+			ScalingRescale, "5 -> 2 replicas: All metrics below target", ""},
+		{"other hpa", kcm(`I0101 10:00:00.000000 1 horizontal.go:881] "Successful rescale" HPA="shop/api" currentReplicas=3 desiredReplicas=5 reason="cpu"`), "", "", ""},
+		{"hpa failure", kcm(`E0101 10:00:00.000000 1 horizontal.go:270] failed to compute desired number of replicas based on listed metrics for Deployment/shop/web: invalid metrics (1 invalid out of 1)`),
+			ScalingHPAFailure, "failed to compute desired number of replicas based on listed metrics for Deployment/shop/web: invalid metrics (1 invalid out of 1)", ""},
+		{"taint eviction", kcm(`I0101 10:00:00.000000 1 taint_eviction.go:110] "Deleting pod" controller="taint-eviction-controller" pod="shop/web-7d9f-x2k4q"`),
+			ScalingTaintEviction, `"Deleting pod" controller="taint-eviction-controller" pod="shop/web-7d9f-x2k4q"`, "shop/web-7d9f-x2k4q"},
+		{"taint eviction of another workload", kcm(`I0101 10:00:00.000000 1 taint_eviction.go:110] "Deleting pod" pod="shop/webhook-0"`), "", "", ""},
+		{"node pressure", kubelet(`I0101 10:00:00.000000 2048 eviction_manager.go:620] "Eviction manager: pod is evicted successfully" pod="shop/web-0"`),
+			ScalingNodePressure, `"Eviction manager: pod is evicted successfully" pod="shop/web-0"`, "shop/web-0"},
+		{"scale", audit(`{"stage":"ResponseComplete","verb":"update","user":{"username":"system:serviceaccount:kube-system:horizontal-pod-autoscaler"},` +
+			`"objectRef":{"resource":"deployments","subresource":"scale","namespace":"shop","name":"web"},"responseStatus":{"code":200}}`),
+			ScalingScale, "update deployments/scale", ""},
+		{"eviction", audit(`{"stage":"ResponseComplete","verb":"create","user":{"username":"alice"},` +
+			`"objectRef":{"resource":"pods","subresource":"eviction","namespace":"shop","name":"web-7d9f-x2k4q"},"responseStatus":{"code":201}}`),
+			ScalingEviction, "eviction of shop/web-7d9f-x2k4q", "shop/web-7d9f-x2k4q"},
+		{"eviction blocked", audit(`{"stage":"ResponseComplete","verb":"create","user":{"username":"alice"},` +
+			`"objectRef":{"resource":"pods","subresource":"eviction","namespace":"shop","name":"web-0"},` +
+			`"responseStatus":{"code":429,"message":"Cannot evict pod as it would violate the pod's disruption budget."}}`),
+			ScalingEvictionBlocked, "eviction of shop/web-0: Cannot evict pod as it would violate the pod's disruption budget.", "shop/web-0"},
+		{"status update", audit(`{"stage":"ResponseComplete","verb":"update","objectRef":{"resource":"deployments","subresource":"status","namespace":"shop","name":"web"},"responseStatus":{"code":200}}`), "", "", ""},
+		{"other namespace", audit(`{"stage":"ResponseComplete","verb":"patch","objectRef":{"resource":"deployments","namespace":"dev","name":"web"},"responseStatus":{"code":200}}`), "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, ok := ScalingEventsFor(tt.entry, web)
+			assert.Equal(t, tt.kind != "", ok)
+			assert.Equal(t, tt.kind, event.Kind)
+			assert.Equal(t, tt.summary, event.Summary)
+			assert.Equal(t, tt.pod, event.Pod)
+		})
+	}
+}
+
+func TestTraceScaling(t *testing.T) {
+	at := func(minute int) time.Time { return time.Date(2024, 1, 1, 10, minute, 0, 0, time.UTC) }
+	entries := []LogEntry{
+		{Timestamp: at(2), LogStream: "kube-controller-manager-abc", Message: `"Successful rescale" HPA="shop/web" currentReplicas=5 desiredReplicas=2 reason="All metrics below target"`},
+		{Timestamp: at(1), LogStream: "kube-controller-manager-abc", Message: `"Successful rescale" HPA="shop/web" currentReplicas=3 desiredReplicas=5 reason="cpu"`},
+		{Timestamp: at(1), LogStream: "kube-scheduler-abc", Message: `"Successfully bound pod to node" pod="shop/web-0"`},
+	}
+	events := TraceScaling(entries, Workload{Namespace: "shop", Name: "web"})
+	assert.Len(t, events, 2)
+	assert.Equal(t, "3 -> 5 replicas: cpu", events[0].Summary)
+	assert.Equal(t, at(2), events[1].Time)
+	assert.Equal(t, `"web"`, Workload{Namespace: "shop", Name: "web"}.FilterPattern())
+}
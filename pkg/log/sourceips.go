@@ -0,0 +1,174 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+// Scopes of a source IP address
+const (
+	ScopePublic    = "public"
+	ScopePrivate   = "private"
+	ScopeLoopback  = "loopback"
+	ScopeLinkLocal = "link-local"
+	ScopeInvalid   = "invalid"
+)
+
+// auditSourceEvent holds the fields of an audit event used to attribute a request to its
+// source address
+type auditSourceEvent struct {
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	User                     struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	SourceIPs []string `json:"sourceIPs"`
+}
+
+// UserCount is the number of requests of a user
+type UserCount struct {
+	User     string `json:"user"`
+	Requests int    `json:"requests"`
+}
+
+// SourceIPSummary counts the audited requests from one source address
+type SourceIPSummary struct {
+	IP       string      `json:"ip"`
+	Scope    string      `json:"scope"`
+	Requests int         `json:"requests"`
+	Users    []UserCount `json:"users"`
+	First    time.Time   `json:"first"`
+	Last     time.Time   `json:"last"`
+	// Outside is set when an allowlist is given and the address is in none of its CIDRs
+	Outside bool `json:"outside_allowlist"`
+}
+
+// ParseCIDRs parses an allowlist of CIDRs, e.g. 10.0.0.0/8; a bare address is a /32 or
+// /128 prefix
+func ParseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid CIDR '%s': %w", cidr, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// IPScope classifies an address as public, private (RFC 1918, RFC 4193 and the shared
+// address space of carrier-grade NAT), loopback or link-local. A lookup of its country
+// needs a GeoIP database and is left to the reader.
+func IPScope(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ScopeInvalid
+	}
+	addr = addr.Unmap()
+	switch {
+	case addr.IsLoopback():
+		return ScopeLoopback
+	case addr.IsLinkLocalUnicast():
+		return ScopeLinkLocal
+	case addr.IsPrivate() || sharedAddressSpace.Contains(addr):
+		return ScopePrivate
+	default:
+		return ScopePublic
+	}
+}
+
+// sharedAddressSpace is the RFC 6598 range used by carrier-grade NAT and some VPC setups
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// SummarizeSourceIPs counts the requests of audit log entries per source address, the
+// first of the sourceIPs of an event (the following ones are proxies), and per user.
+// Addresses in none of the allowlist CIDRs are marked outside; with an empty allowlist
+// none is. The addresses outside the allowlist come first, then the busiest.
+func SummarizeSourceIPs(entries []LogEntry, allowlist []netip.Prefix) []SourceIPSummary {
+	type sourceIP struct {
+		summary SourceIPSummary
+		users   map[string]int
+	}
+	byIP := make(map[string]*sourceIP)
+	for _, entry := range entries {
+		var event auditSourceEvent
+		if err := json.Unmarshal([]byte(entry.Message), &event); err != nil || len(event.SourceIPs) == 0 {
+			continue
+		}
+		timestamp := event.RequestReceivedTimestamp
+		if timestamp.IsZero() {
+			timestamp = entry.Timestamp
+		}
+		timestamp = timestamp.UTC()
+
+		ip := event.SourceIPs[0]
+		source, exists := byIP[ip]
+		if !exists {
+			source = &sourceIP{
+				summary: SourceIPSummary{IP: ip, Scope: IPScope(ip), First: timestamp, Last: timestamp, Outside: outsideAllowlist(ip, allowlist)},
+				users:   make(map[string]int),
+			}
+			byIP[ip] = source
+		}
+		source.summary.Requests++
+		source.users[event.User.Username]++
+		if timestamp.Before(source.summary.First) {
+			source.summary.First = timestamp
+		}
+		if timestamp.After(source.summary.Last) {
+			source.summary.Last = timestamp
+		}
+	}
+
+	summaries := make([]SourceIPSummary, 0, len(byIP))
+	for _, source := range byIP {
+		for user, requests := range source.users {
+			source.summary.Users = append(source.summary.Users, UserCount{User: user, Requests: requests})
+		}
+		sort.Slice(source.summary.Users, func(i, j int) bool {
+			a, b := source.summary.Users[i], source.summary.Users[j]
+			if a.Requests != b.Requests {
+				return a.Requests > b.Requests
+			}
+			return a.User < b.User
+		})
+		summaries = append(summaries, source.summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.Outside != b.Outside {
+			return a.Outside
+		}
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.IP < b.IP
+	})
+	return summaries
+}
+
+// outsideAllowlist reports whether an address is in none of the allowlist CIDRs, for a
+// non-empty allowlist. Addresses that do not parse are outside.
+func outsideAllowlist(ip string, allowlist []netip.Prefix) bool {
+	if len(allowlist) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, prefix := range allowlist {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCIDRs(t *testing.T) {
+	prefixes, err := ParseCIDRs([]string{"10.1.2.3/8", "203.0.113.7", "2001:db8::/32"})
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8", prefixes[0].String())
+	assert.Equal(t, "203.0.113.7/32", prefixes[1].String())
+	assert.Equal(t, "2001:db8::/32", prefixes[2].String())
+
+	_, err = ParseCIDRs([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+	_, err = ParseCIDRs([]string{"office"})
+	assert.Error(t, err)
+}
+
+func TestIPScope(t *testing.T) {
+	assert.Equal(t, ScopePrivate, IPScope("10.0.1.5"))
+	assert.Equal(t, ScopePrivate, IPScope("100.64.3.4"))
+	assert.Equal(t, ScopePrivate, IPScope("fd00::1"))
+	assert.Equal(t, ScopePublic, IPScope("203.0.113.7"))
+	assert.Equal(t, ScopeLoopback, IPScope("127.0.0.1"))
+	assert.Equal(t, ScopeLinkLocal, IPScope("169.254.169.254"))
+	assert.Equal(t, ScopeInvalid, IPScope("unknown"))
+}
+
+func TestSummarizeSourceIPs(t *testing.T) {
+	event := func(minute int, user string, ips string) LogEntry {
+		return LogEntry{Message: `{"requestReceivedTimestamp":"2024-01-01T10:0` + string(rune('0'+minute)) + `:00Z",` +
+			`"user":{"username":"` + user + `"},"sourceIPs":[` + ips + `]}`}
+	}
+	entries := []LogEntry{
+		event(1, "system:node:ip-10-0-1-5", `"10.0.1.5"`),
+		event(2, "system:node:ip-10-0-1-5", `"10.0.1.5"`),
+		event(3, "alice", `"203.0.113.7","10.0.0.10"`),
+		event(4, "system:node:ip-10-0-1-5", `"10.0.1.5"`),
+		event(5, "bob", `"203.0.113.7"`),
+		event(6, "alice", `"203.0.113.7"`),
+		{Message: `{"user":{"username":"nobody"}}`},
+		{Message: "not an audit event"},
+	}
+	allowlist, err := ParseCIDRs([]string{"10.0.0.0/8"})
+	assert.NoError(t, err)
+
+	summaries := SummarizeSourceIPs(entries, allowlist)
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, SourceIPSummary{
+		IP:       "203.0.113.7",
+		Scope:    ScopePublic,
+		Requests: 3,
+		Users:    []UserCount{{User: "alice", Requests: 2}, {User: "bob", Requests: 1}},
+		First:    time.Date(2024, 1, 1, 10, 3, 0, 0, time.UTC),
+		Last:     time.Date(2024, 1, 1, 10, 6, 0, 0, time.UTC),
+		Outside:  true,
+	}, summaries[0])
+	assert.Equal(t, "10.0.1.5", summaries[1].IP)
+	assert.Equal(t, 3, summaries[1].Requests)
+	assert.False(t, summaries[1].Outside)
+
+	// Without an allowlist nothing is outside, and the busiest address comes first
+	summaries = SummarizeSourceIPs(entries, nil)
+	assert.False(t, summaries[0].Outside)
+	assert.False(t, summaries[1].Outside)
+	assert.Equal(t, "10.0.1.5", summaries[0].IP)
+}
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Defaults of the spike detection
+const (
+	DefaultSpikeWindow  = time.Minute
+	defaultSpikeBuckets = 10
+	defaultSpikeMin     = 10
+)
+
+// SpikeOptions configures a SpikeDetector. Zero values select the defaults.
+type SpikeOptions struct {
+	// Factor is how many times the baseline rate the rate of a window must exceed
+	Factor float64
+	// Window is the width of the windows events are counted in
+	Window time.Duration
+	// Buckets is the number of past windows averaged into the baseline; no spike is
+	// reported before that many windows were seen
+	Buckets int
+	// MinEvents is the number of events of a window below which it is never a spike
+	MinEvents int
+}
+
+// Spike is a window whose number of matching events exceeded the baseline
+type Spike struct {
+	Time     time.Time     `json:"time"`
+	Events   int           `json:"events"`
+	Baseline float64       `json:"baseline"`
+	Ratio    float64       `json:"ratio"`
+	Window   time.Duration `json:"-"`
+}
+
+// String describes the spike, e.g. "540 events in 1m0s, 6.2x the baseline of 87.0"
+func (s Spike) String() string {
+	if s.Baseline == 0 {
+		return fmt.Sprintf("%d events in %s, up from none", s.Events, s.Window)
+	}
+	return fmt.Sprintf("%d events in %s, %.1fx the baseline of %.1f", s.Events, s.Window, s.Ratio, s.Baseline)
+}
+
+// SpikeDetector counts the events arriving in follow mode per window and reports a window
+// whose count exceeds Factor times the average of the previous windows. A spike is
+// reported once, as soon as the threshold is crossed, and again only after a window
+// below the threshold.
+type SpikeDetector struct {
+	options SpikeOptions
+	onSpike func(Spike)
+	now     func() time.Time
+
+	mu       sync.Mutex
+	start    time.Time // start of the current window
+	count    int       // events of the current window
+	history  []int     // counts of the previous windows, oldest first
+	alerting bool      // a spike was reported and the rate has not come down since
+}
+
+// NewSpikeDetector returns a detector calling onSpike for each spike
+func NewSpikeDetector(options SpikeOptions, onSpike func(Spike)) *SpikeDetector {
+	if options.Window <= 0 {
+		options.Window = DefaultSpikeWindow
+	}
+	if options.Buckets <= 0 {
+		options.Buckets = defaultSpikeBuckets
+	}
+	if options.MinEvents <= 0 {
+		options.MinEvents = defaultSpikeMin
+	}
+	return &SpikeDetector{options: options, onSpike: onSpike, now: time.Now}
+}
+
+// Observe records an event arriving at t. It is safe for concurrent use; onSpike is called
+// without holding the lock.
+func (d *SpikeDetector) Observe(t time.Time) {
+	d.mu.Lock()
+	d.advance(t)
+	d.count++
+	spike, ok := d.check(t)
+	d.mu.Unlock()
+
+	if ok {
+		d.onSpike(spike)
+	}
+}
+
+// advance closes the windows ended before t, including the empty ones
+func (d *SpikeDetector) advance(t time.Time) {
+	if d.start.IsZero() {
+		d.start = t.Truncate(d.options.Window)
+		return
+	}
+	for !t.Before(d.start.Add(d.options.Window)) {
+		if d.alerting && !d.exceeds(d.count) {
+			d.alerting = false
+		}
+		d.history = append(d.history, d.count)
+		if len(d.history) > d.options.Buckets {
+			d.history = d.history[1:]
+		}
+		d.count = 0
+		d.start = d.start.Add(d.options.Window)
+		// Skip long idle periods without looping over each window
+		if len(d.history) == d.options.Buckets && t.Sub(d.start) > time.Duration(d.options.Buckets)*d.options.Window {
+			d.history = make([]int, d.options.Buckets)
+			d.start = t.Truncate(d.options.Window)
+		}
+	}
+}
+
+// baseline returns the average count of the previous windows
+func (d *SpikeDetector) baseline() float64 {
+	var total int
+	for _, count := range d.history {
+		total += count
+	}
+	return float64(total) / float64(len(d.history))
+}
+
+// exceeds reports whether count is a spike compared with the baseline
+func (d *SpikeDetector) exceeds(count int) bool {
+	if len(d.history) < d.options.Buckets || count < d.options.MinEvents {
+		return false
+	}
+	return float64(count) > d.options.Factor*d.baseline()
+}
+
+// check returns the spike of the current window if it has just crossed the threshold
+func (d *SpikeDetector) check(t time.Time) (Spike, bool) {
+	if d.alerting || !d.exceeds(d.count) {
+		return Spike{}, false
+	}
+	d.alerting = true
+	spike := Spike{Time: t, Events: d.count, Baseline: d.baseline(), Window: d.options.Window}
+	if spike.Baseline > 0 {
+		spike.Ratio = float64(d.count) / spike.Baseline
+	}
+	return spike, true
+}
+
+// Wrap returns a print function that records the arrival of each entry before printing it
+func (d *SpikeDetector) Wrap(printFunc func(LogEntry)) func(LogEntry) {
+	return func(entry LogEntry) {
+		d.Observe(d.now())
+		printFunc(entry)
+	}
+}
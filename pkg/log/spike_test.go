@@ -0,0 +1,101 @@
+package log
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// observeWindows feeds counts[i] events into the i-th window of a minute
+func observeWindows(d *SpikeDetector, start time.Time, counts ...int) {
+	for i, count := range counts {
+		windowStart := start.Add(time.Duration(i) * time.Minute)
+		for j := 0; j < count; j++ {
+			d.Observe(windowStart.Add(time.Duration(j) * time.Millisecond))
+		}
+	}
+}
+
+func TestSpikeDetector(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	var spikes []Spike
+	d := NewSpikeDetector(SpikeOptions{Factor: 3, Buckets: 3, MinEvents: 5}, func(s Spike) {
+		spikes = append(spikes, s)
+	})
+
+	// No spike during the warmup, however busy
+	observeWindows(d, start, 50, 10, 10)
+	assert.Empty(t, spikes)
+
+	// Baseline (50+10+10)/3 = 23.3; the 71st event exceeds 3x
+	observeWindows(d, start.Add(3*time.Minute), 80)
+	if assert.Len(t, spikes, 1) {
+		assert.Equal(t, 71, spikes[0].Events)
+		assert.InDelta(t, 23.33, spikes[0].Baseline, 0.01)
+		assert.InDelta(t, 3.04, spikes[0].Ratio, 0.01)
+		assert.Equal(t, time.Minute, spikes[0].Window)
+		assert.Equal(t, "71 events in 1m0s, 3.0x the baseline of 23.3", spikes[0].String())
+	}
+
+	// A spike lasting several windows is reported once, and again after a quiet window
+	observeWindows(d, start.Add(4*time.Minute), 500, 5, 5000)
+	assert.Len(t, spikes, 2)
+}
+
+func TestSpikeDetectorMinEvents(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	var spikes []Spike
+	d := NewSpikeDetector(SpikeOptions{Factor: 2, Buckets: 2, MinEvents: 10}, func(s Spike) {
+		spikes = append(spikes, s)
+	})
+
+	// Nine times the baseline is not a spike below ten events
+	observeWindows(d, start, 1, 1, 9)
+	assert.Empty(t, spikes)
+	observeWindows(d, start.Add(3*time.Minute), 25)
+	if assert.Len(t, spikes, 1) {
+		assert.Equal(t, 11, spikes[0].Events)
+	}
+}
+
+func TestSpikeDetectorIdleGap(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	var spikes []Spike
+	d := NewSpikeDetector(SpikeOptions{Factor: 2, Buckets: 3, MinEvents: 1}, func(s Spike) {
+		spikes = append(spikes, s)
+	})
+
+	observeWindows(d, start, 100, 100, 100)
+	// After a day without events the baseline is empty windows
+	d.Observe(start.Add(24 * time.Hour))
+	if assert.Len(t, spikes, 1) {
+		assert.Equal(t, 0.0, spikes[0].Baseline)
+		assert.Equal(t, "1 events in 1m0s, up from none", spikes[0].String())
+	}
+}
+
+func TestSpikeDetectorWrapConcurrent(t *testing.T) {
+	d := NewSpikeDetector(SpikeOptions{Factor: 2}, func(Spike) {})
+	var mu sync.Mutex
+	var printed int
+	printFunc := d.Wrap(func(LogEntry) {
+		mu.Lock()
+		printed++
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				printFunc(LogEntry{})
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 800, printed)
+}
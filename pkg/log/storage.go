@@ -0,0 +1,95 @@
+package log
+
+import (
+	"strings"
+	"time"
+)
+
+// Kinds of storage warnings logged by the API server
+const (
+	// StorageLatency is a slow etcd request, e.g. "took too long" traces
+	StorageLatency = "latency"
+	// StorageTimeout is an etcd request that failed, e.g. "etcdserver: request timed out"
+	StorageTimeout = "timeout"
+	// StorageSize is an object or database too large, e.g. "etcdserver: request is too large"
+	StorageSize = "size"
+)
+
+// storageWarningPatterns maps lowercase message fragments to the kind of storage warning
+// they report. Size and timeout fragments are checked before latency, as failed requests
+// are often also slow.
+var storageWarningPatterns = []struct {
+	fragment string
+	kind     string
+}{
+	{"request is too large", StorageSize},
+	{"database space exceeded", StorageSize},
+	{"request entity too large", StorageSize},
+	{"too many objects", StorageSize},
+	{"etcdserver: request timed out", StorageTimeout},
+	{"etcdserver: leader changed", StorageTimeout},
+	{"etcdserver: too many requests", StorageTimeout},
+	{"context deadline exceeded", StorageTimeout},
+	{"took too long", StorageLatency},
+	{"slow request", StorageLatency},
+}
+
+// StorageBucket counts the storage warnings of a time interval
+type StorageBucket struct {
+	Start   time.Time `json:"start"`
+	Latency int       `json:"latency"`
+	Timeout int       `json:"timeout"`
+	Size    int       `json:"size"`
+}
+
+// Total returns the number of warnings of the bucket
+func (b StorageBucket) Total() int {
+	return b.Latency + b.Timeout + b.Size
+}
+
+// ClassifyStorageWarning returns the kind of storage warning an API server message reports.
+// Deadline errors only count when they mention etcd.
+func ClassifyStorageWarning(message string) (string, bool) {
+	lower := strings.ToLower(message)
+	for _, pattern := range storageWarningPatterns {
+		if !strings.Contains(lower, pattern.fragment) {
+			continue
+		}
+		if pattern.fragment == "context deadline exceeded" && !strings.Contains(lower, "etcd") {
+			continue
+		}
+		return pattern.kind, true
+	}
+	return "", false
+}
+
+// BucketStorageWarnings counts the storage warnings of entries in intervals of width between
+// start and end. Intervals without warnings are included, so regressions stand out.
+func BucketStorageWarnings(entries []LogEntry, start, end time.Time, width time.Duration) []StorageBucket {
+	if width <= 0 || !end.After(start) {
+		return nil
+	}
+
+	first := start.Truncate(width)
+	buckets := make([]StorageBucket, 0, int(end.Sub(first)/width)+1)
+	for t := first; t.Before(end); t = t.Add(width) {
+		buckets = append(buckets, StorageBucket{Start: t.UTC()})
+	}
+
+	for _, entry := range entries {
+		kind, ok := ClassifyStorageWarning(entry.Message)
+		if !ok || entry.Timestamp.Before(first) || !entry.Timestamp.Before(end) {
+			continue
+		}
+		bucket := &buckets[int(entry.Timestamp.Sub(first)/width)]
+		switch kind {
+		case StorageLatency:
+			bucket.Latency++
+		case StorageTimeout:
+			bucket.Timeout++
+		case StorageSize:
+			bucket.Size++
+		}
+	}
+	return buckets
+}
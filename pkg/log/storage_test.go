@@ -0,0 +1,56 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyStorageWarning(t *testing.T) {
+	tests := []struct {
+		message string
+		kind    string
+	}{
+		{`Trace[1234]: "Get" url:/api/v1/namespaces/default/pods/web (took too long: 1.2s)`, StorageLatency},
+		{`E0101 10:00:00.000000 1 status.go:71] apiserver received an error: etcdserver: request timed out`, StorageTimeout},
+		{`E0101 10:00:00.000000 1 watcher.go:1] etcd: context deadline exceeded`, StorageTimeout},
+		{`E0101 10:00:00.000000 1 writers.go:1] etcdserver: request is too large`, StorageSize},
+		{`E0101 10:00:00.000000 1 writers.go:1] etcdserver: mvcc: database space exceeded`, StorageSize},
+	}
+	for _, tt := range tests {
+		kind, ok := ClassifyStorageWarning(tt.message)
+		assert.True(t, ok, tt.message)
+		assert.Equal(t, tt.kind, kind, tt.message)
+	}
+
+	for _, message := range []string{
+		"I0101 10:00:00.000000 1 controller.go:1] Starting controller",
+		"E0101 10:00:00.000000 1 webhook.go:1] calling webhook: context deadline exceeded",
+	} {
+		_, ok := ClassifyStorageWarning(message)
+		assert.False(t, ok, message)
+	}
+}
+
+func TestBucketStorageWarnings(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	entries := []LogEntry{
+		{Timestamp: start.Add(time.Minute), Message: "request took too long"},
+		{Timestamp: start.Add(2 * time.Minute), Message: "etcdserver: request timed out"},
+		{Timestamp: start.Add(25 * time.Minute), Message: "etcdserver: request is too large"},
+		{Timestamp: start.Add(26 * time.Minute), Message: "Starting controller"},
+		{Timestamp: end.Add(time.Minute), Message: "request took too long"},
+	}
+
+	buckets := BucketStorageWarnings(entries, start, end, 10*time.Minute)
+	assert.Equal(t, []StorageBucket{
+		{Start: start, Latency: 1, Timeout: 1},
+		{Start: start.Add(10 * time.Minute)},
+		{Start: start.Add(20 * time.Minute), Size: 1},
+	}, buckets)
+	assert.Equal(t, 2, buckets[0].Total())
+
+	assert.Nil(t, BucketStorageWarnings(entries, end, start, time.Minute))
+}
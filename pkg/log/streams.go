@@ -0,0 +1,244 @@
+package log
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// streamComponents maps the control plane components writing to the log group of a
+// cluster to their log type. The log streams of a component are named after it, followed
+// by a dash and a hexadecimal ID, e.g. "kube-apiserver-audit-0a1b2c3d".
+var streamComponents = map[string]string{
+	"kube-apiserver":           "api",
+	"kube-apiserver-audit":     "audit",
+	"authenticator":            "authenticator",
+	"kube-controller-manager":  "kcm",
+	"cloud-controller-manager": "ccm",
+	"kube-scheduler":           "scheduler",
+}
+
+// LogTypeDefinition describes a log type added to the built-in ones, e.g. in the config
+// file: the log streams it is read from and the names it is given on the command line
+type LogTypeDefinition struct {
+	Name        string
+	Description string
+	// LogGroup is a log group read along with the control plane log group of the cluster,
+	// {cluster} standing for the cluster name. Its streams are all of the log type, unless
+	// Streams is set.
+	LogGroup string
+	// Streams are the prefixes of the names of the log streams of the type, in the control
+	// plane log group or in LogGroup
+	Streams []string
+	Aliases []string
+}
+
+// customLogType is a registered LogTypeDefinition
+type customLogType struct {
+	LogTypeDefinition
+	// logGroup matches the names of LogGroup for any cluster
+	logGroup *regexp.Regexp
+}
+
+// customLogTypes are the log types registered with RegisterLogType, by name
+var customLogTypes = map[string]customLogType{}
+
+// RegisterLogType adds a log type to the built-in ones, or adds streams and aliases to a
+// built-in log type, so the log types of custom streams and log groups can be selected and
+// filtered like those of the control plane. Registering a log type again replaces it.
+func RegisterLogType(def LogTypeDefinition) error {
+	if def.Name == "" || strings.ContainsAny(def.Name, " ,") {
+		return fmt.Errorf("invalid log type name '%s'", def.Name)
+	}
+	builtin := isBuiltinLogType(def.Name)
+	if !builtin && def.LogGroup == "" && len(def.Streams) == 0 {
+		return fmt.Errorf("log type '%s' needs a log group or stream prefixes", def.Name)
+	}
+	if builtin && def.LogGroup != "" {
+		return fmt.Errorf("the log group of the built-in log type '%s' cannot be changed", def.Name)
+	}
+	if target, exists := logTypeAliases[def.Name]; exists && target != def.Name {
+		return fmt.Errorf("log type '%s' is an alias of '%s'", def.Name, target)
+	}
+	for _, alias := range def.Aliases {
+		if target, exists := logTypeAliases[alias]; exists && target != def.Name {
+			return fmt.Errorf("alias '%s' of log type '%s' already names '%s'", alias, def.Name, target)
+		}
+	}
+	for _, prefix := range def.Streams {
+		if prefix == "" {
+			return fmt.Errorf("log type '%s' has an empty stream prefix", def.Name)
+		}
+		if other, exists := streamComponents[strings.TrimSuffix(prefix, "-")]; exists && other != def.Name {
+			return fmt.Errorf("streams '%s' of log type '%s' are already of log type '%s'", prefix, def.Name, other)
+		}
+	}
+
+	entry := customLogType{LogTypeDefinition: def}
+	if def.LogGroup != "" {
+		if !strings.HasPrefix(def.LogGroup, "/") {
+			return fmt.Errorf("log group '%s' of log type '%s' must start with /", def.LogGroup, def.Name)
+		}
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(def.LogGroup), regexp.QuoteMeta("{cluster}"), "[^/]+")
+		entry.logGroup = regexp.MustCompile("^" + pattern + "$")
+	}
+
+	if previous, exists := customLogTypes[def.Name]; exists {
+		for _, alias := range previous.Aliases {
+			delete(logTypeAliases, alias)
+		}
+	}
+	logTypeAliases[def.Name] = def.Name
+	for _, alias := range def.Aliases {
+		logTypeAliases[alias] = def.Name
+	}
+	customLogTypes[def.Name] = entry
+	return nil
+}
+
+// UnregisterLogType removes a log type registered with RegisterLogType, restoring the
+// built-in log type it extended
+func UnregisterLogType(name string) {
+	entry, exists := customLogTypes[name]
+	if !exists {
+		return
+	}
+	for _, alias := range entry.Aliases {
+		delete(logTypeAliases, alias)
+	}
+	if !isBuiltinLogType(name) {
+		delete(logTypeAliases, name)
+	}
+	delete(customLogTypes, name)
+}
+
+// isBuiltinLogType reports whether name is a log type of the control plane or Container
+// Insights
+func isBuiltinLogType(name string) bool {
+	for _, logType := range streamComponents {
+		if logType == name {
+			return true
+		}
+	}
+	return nodeLogTypes[name]
+}
+
+// CustomLogTypes returns the log types registered with RegisterLogType, sorted by name
+func CustomLogTypes() []LogTypeDefinition {
+	defs := make([]LogTypeDefinition, 0, len(customLogTypes))
+	for _, entry := range customLogTypes {
+		defs = append(defs, entry.LogTypeDefinition)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// CustomLogGroups returns the log groups of the registered log types for a cluster, sorted
+func CustomLogGroups(cluster string) []string {
+	var groups []string
+	for _, entry := range customLogTypes {
+		if entry.LogGroup != "" {
+			groups = append(groups, strings.ReplaceAll(entry.LogGroup, "{cluster}", cluster))
+		}
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// customLogGroupType returns the registered log type of the streams of a custom log group,
+// or "" and false when the log group is not the log group of a registered log type
+func customLogGroupType(logGroup, streamName string) (string, bool) {
+	for name, entry := range customLogTypes {
+		if entry.logGroup == nil || !entry.logGroup.MatchString(logGroup) {
+			continue
+		}
+		if len(entry.Streams) == 0 || matchingPrefix(entry.Streams, streamName) != "" {
+			return name, true
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// customStreamType returns the registered log type of a stream of the control plane log
+// group matching one of its stream prefixes, the longest prefix winning, and that prefix
+func customStreamType(streamName string) (string, string) {
+	var logType, longest string
+	for name, entry := range customLogTypes {
+		if entry.logGroup != nil {
+			continue
+		}
+		if prefix := matchingPrefix(entry.Streams, streamName); len(prefix) > len(longest) {
+			logType, longest = name, prefix
+		}
+	}
+	return logType, longest
+}
+
+// matchingPrefix returns the longest of prefixes streamName starts with, or ""
+func matchingPrefix(prefixes []string, streamName string) string {
+	var longest string
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(streamName, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	return longest
+}
+
+// ParseStreamName splits the name of a control plane log stream into the component writing
+// it and the ID of the stream. A name not ending with a hexadecimal ID is the component.
+func ParseStreamName(streamName string) (component, id string) {
+	i := strings.LastIndexByte(streamName, '-')
+	if i <= 0 || i == len(streamName)-1 || !isHex(streamName[i+1:]) {
+		return streamName, ""
+	}
+	return streamName[:i], streamName[i+1:]
+}
+
+// IsKnownStream reports whether a control plane log stream is written by a known component,
+// or matches the stream prefixes of a registered log type. The streams of components added
+// to EKS later, such as a new kube-apiserver-<suffix> variant, are not: their log type is
+// unknown.
+func IsKnownStream(streamName string) bool {
+	return ExtractLogTypeFromStreamName(streamName) != ""
+}
+
+// StreamPrefixes returns the prefixes of the names of the control plane log streams of the
+// log types, sorted and none starting with another: the names of their components and the
+// stream prefixes registered for them. A stream of the log types starts with one of them.
+func StreamPrefixes(logTypes []string) []string {
+	var prefixes []string
+	for _, logType := range logTypes {
+		for component, componentType := range streamComponents {
+			if componentType == logType {
+				prefixes = append(prefixes, component)
+			}
+		}
+		if entry, ok := customLogTypes[logType]; ok && entry.logGroup == nil {
+			prefixes = append(prefixes, entry.Streams...)
+		}
+	}
+	sort.Strings(prefixes)
+
+	// A prefix sorts right after the shorter prefix it starts with, if any
+	var distinct []string
+	for _, prefix := range prefixes {
+		if n := len(distinct); n > 0 && strings.HasPrefix(prefix, distinct[n-1]) {
+			continue
+		}
+		distinct = append(distinct, prefix)
+	}
+	return distinct
+}
+
+// isHex reports whether s is made of hexadecimal digits only
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return s != ""
+}
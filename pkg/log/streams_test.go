@@ -0,0 +1,122 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStreamName(t *testing.T) {
+	tests := []struct {
+		stream    string
+		component string
+		id        string
+	}{
+		{"kube-apiserver-0a1b2c3d4e5f67890a1b2c3d4e5f6789", "kube-apiserver", "0a1b2c3d4e5f67890a1b2c3d4e5f6789"},
+		{"kube-apiserver-audit-0a1b2c3d", "kube-apiserver-audit", "0a1b2c3d"},
+		{"kube-apiserver-egress-selector-0a1b", "kube-apiserver-egress-selector", "0a1b"},
+		{"kube-apiserver-audit", "kube-apiserver-audit", ""},
+		{"UpdateClusterConfig", "UpdateClusterConfig", ""},
+		{"authenticator-", "authenticator-", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stream, func(t *testing.T) {
+			component, id := ParseStreamName(tt.stream)
+			assert.Equal(t, tt.component, component)
+			assert.Equal(t, tt.id, id)
+		})
+	}
+}
+
+func TestUnknownStreams(t *testing.T) {
+	assert.True(t, IsKnownStream("kube-scheduler-0a1b"))
+	assert.True(t, IsKnownStream("cloud-controller-manager-0a1b"))
+
+	// A new variant of a component is not mistaken for it
+	assert.False(t, IsKnownStream("kube-apiserver-egress-0a1b"))
+	assert.Equal(t, "", ExtractLogTypeFromStreamName("kube-apiserver-egress-0a1b"))
+	assert.Equal(t, "kube-apiserver-egress", ExtractComponentFromStreamName("kube-apiserver-egress-0a1b"))
+	assert.Equal(t, "unknown", ExtractComponentFromStreamName("some-stream"))
+}
+
+func TestRegisterLogType(t *testing.T) {
+	t.Cleanup(func() {
+		UnregisterLogType("karpenter")
+		UnregisterLogType("istio")
+		UnregisterLogType("api")
+	})
+
+	assert.NoError(t, RegisterLogType(LogTypeDefinition{
+		Name:        "karpenter",
+		Description: "Karpenter controller logs",
+		LogGroup:    "/aws/eks/{cluster}/karpenter",
+		Streams:     []string{"controller-"},
+		Aliases:     []string{"kpt"},
+	}))
+	assert.NoError(t, RegisterLogType(LogTypeDefinition{Name: "istio", Streams: []string{"istiod-", "istio-proxy-"}}))
+	// Streams of a new API server component are read as api logs
+	assert.NoError(t, RegisterLogType(LogTypeDefinition{Name: "api", Streams: []string{"kube-apiserver-egress-"}, Aliases: []string{"apiserver"}}))
+
+	assert.True(t, IsLogType("kpt"))
+	assert.Equal(t, "karpenter", NormalizeLogType("kpt"))
+	assert.Equal(t, "api", NormalizeLogType("apiserver"))
+	assert.Equal(t, "karpenter", LogTypeFor("/aws/eks/prod/karpenter", "controller-7d9f"))
+	assert.Equal(t, "karpenter", ComponentFor("/aws/eks/prod/karpenter", "controller-7d9f"))
+	assert.Equal(t, "", LogTypeFor("/aws/eks/prod/karpenter", "webhook-7d9f"))
+	assert.Equal(t, "istio", LogTypeFor("/aws/eks/prod/cluster", "istio-proxy-0a1b"))
+	assert.Equal(t, "istio-proxy", ComponentFor("/aws/eks/prod/cluster", "istio-proxy-0a1b"))
+	assert.Equal(t, "api", LogTypeFor("/aws/eks/prod/cluster", "kube-apiserver-egress-0a1b"))
+	assert.Equal(t, "audit", LogTypeFor("/aws/eks/prod/cluster", "kube-apiserver-audit-0a1b"))
+	assert.Equal(t, []string{"/aws/eks/prod/karpenter"}, CustomLogGroups("prod"))
+	assert.Equal(t, "karpenter (Karpenter controller logs), istio", GetLogTypeDescription([]string{"karpenter", "istio"}))
+
+	// Registering again replaces the log type and its aliases
+	assert.NoError(t, RegisterLogType(LogTypeDefinition{Name: "karpenter", LogGroup: "/aws/eks/{cluster}/karpenter"}))
+	assert.False(t, IsLogType("kpt"))
+	assert.Len(t, CustomLogTypes(), 3)
+
+	UnregisterLogType("api")
+	assert.True(t, IsLogType("api"))
+	assert.False(t, IsLogType("apiserver"))
+	assert.Equal(t, "", LogTypeFor("/aws/eks/prod/cluster", "kube-apiserver-egress-0a1b"))
+}
+
+func TestStreamPrefixes(t *testing.T) {
+	t.Cleanup(func() {
+		UnregisterLogType("karpenter")
+		UnregisterLogType("istio")
+		UnregisterLogType("api")
+	})
+
+	// The API server prefix covers the audit streams
+	assert.Equal(t, []string{"kube-apiserver"}, StreamPrefixes([]string{"audit", "api"}))
+	assert.Equal(t, []string{"cloud-controller-manager", "kube-scheduler"}, StreamPrefixes([]string{"scheduler", "ccm"}))
+	assert.Empty(t, StreamPrefixes([]string{"application"}))
+
+	assert.NoError(t, RegisterLogType(LogTypeDefinition{Name: "karpenter", LogGroup: "/aws/eks/{cluster}/karpenter", Streams: []string{"controller-"}}))
+	assert.NoError(t, RegisterLogType(LogTypeDefinition{Name: "istio", Streams: []string{"istiod-", "istio-proxy-"}}))
+	assert.NoError(t, RegisterLogType(LogTypeDefinition{Name: "api", Streams: []string{"kube-apiserver-egress-"}}))
+	assert.Equal(t, []string{"istio-proxy-", "istiod-", "kube-apiserver"}, StreamPrefixes([]string{"api", "istio", "karpenter"}))
+}
+
+func TestRegisterLogTypeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		def  LogTypeDefinition
+		err  string
+	}{
+		{"no name", LogTypeDefinition{Streams: []string{"x-"}}, "invalid log type name"},
+		{"no streams", LogTypeDefinition{Name: "mine"}, "needs a log group or stream prefixes"},
+		{"alias taken", LogTypeDefinition{Name: "mine", Streams: []string{"x-"}, Aliases: []string{"auth"}}, "already names 'authenticator'"},
+		{"name is alias", LogTypeDefinition{Name: "sched", Streams: []string{"x-"}}, "alias of 'scheduler'"},
+		{"built-in streams", LogTypeDefinition{Name: "mine", Streams: []string{"kube-scheduler-"}}, "already of log type 'scheduler'"},
+		{"built-in log group", LogTypeDefinition{Name: "audit", LogGroup: "/audit"}, "cannot be changed"},
+		{"relative log group", LogTypeDefinition{Name: "mine", LogGroup: "mine/{cluster}"}, "must start with /"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, RegisterLogType(tt.def), tt.err)
+			assert.Empty(t, CustomLogTypes())
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package log
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of template changes between two time windows
+const (
+	ChangeNew       = "new"
+	ChangeIncreased = "increased"
+)
+
+var (
+	// templateQuotedPattern matches the key="value" fields of klog structured messages
+	templateQuotedPattern = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_.-]*)="(?:[^"\\]|\\.)*"`)
+	// templateUUIDPattern matches UUIDs, e.g. audit IDs
+	templateUUIDPattern = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	// templateIPPattern matches IPv4 addresses with an optional port
+	templateIPPattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}(?::\d+)?\b`)
+	// templateHexPattern matches hexadecimal strings of 8 characters or more; only those
+	// mixing digits and letters are identifiers
+	templateHexPattern = regexp.MustCompile(`\b(?:0x)?[0-9a-fA-F]{8,}\b`)
+	// templateNumberPattern matches numbers, durations and sizes, e.g. 42, 1.5s, 250ms
+	templateNumberPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h|Ki|Mi|Gi|KB|MB|GB|%)?\b`)
+)
+
+// templateKeptFields are the key="value" fields whose value tells messages apart, such as
+// the error of a failure; the values of the other fields are replaced
+var templateKeptFields = map[string]bool{"err": true, "error": true, "reason": true}
+
+// MessageTemplate reduces a message to its template, so the messages logged by the same
+// code compare equal: the klog header is removed and the values of structured fields,
+// UUIDs, IP addresses, hexadecimal identifiers and numbers are replaced with placeholders.
+// Audit events become "audit <verb> <resource> <code>".
+func MessageTemplate(message string) string {
+	message = strings.TrimSpace(message)
+	if strings.HasPrefix(message, "{") {
+		if template, ok := auditTemplate(message); ok {
+			return template
+		}
+	}
+
+	message = klogMessagePattern.ReplaceAllString(message, "")
+	message = templateQuotedPattern.ReplaceAllStringFunc(message, func(field string) string {
+		key, _, _ := strings.Cut(field, "=")
+		if templateKeptFields[key] {
+			return field
+		}
+		return key + "=<*>"
+	})
+	message = templateUUIDPattern.ReplaceAllString(message, "<uuid>")
+	message = templateIPPattern.ReplaceAllString(message, "<ip>")
+	message = templateHexPattern.ReplaceAllStringFunc(message, func(s string) string {
+		hex := strings.TrimPrefix(s, "0x")
+		if !strings.ContainsAny(hex, "0123456789") || !strings.ContainsAny(hex, "abcdefABCDEF") {
+			return s
+		}
+		return "<hex>"
+	})
+	return templateNumberPattern.ReplaceAllString(message, "<n>")
+}
+
+// auditTemplate returns the template of an audit event
+func auditTemplate(message string) (string, bool) {
+	var event auditObjectEvent
+	if err := json.Unmarshal([]byte(message), &event); err != nil || event.Verb == "" {
+		return "", false
+	}
+	resource := event.ObjectRef.Resource
+	if event.ObjectRef.Subresource != "" {
+		resource += "/" + event.ObjectRef.Subresource
+	}
+	template := "audit " + event.Verb
+	if resource != "" {
+		template += " " + resource
+	}
+	if event.ResponseStatus.Code != 0 {
+		template += " " + strconv.Itoa(event.ResponseStatus.Code)
+	}
+	return template, true
+}
+
+// TemplateCounts counts the messages of each log type and template
+type TemplateCounts map[TemplateKey]int
+
+// TemplateKey identifies a message template of a log type
+type TemplateKey struct {
+	LogType  string
+	Template string
+}
+
+// CountTemplates counts the templates of the messages of entries
+func CountTemplates(entries []LogEntry) TemplateCounts {
+	counts := make(TemplateCounts)
+	for _, entry := range entries {
+		key := TemplateKey{LogType: LogTypeFor(entry.LogGroup, entry.LogStream), Template: MessageTemplate(entry.Message)}
+		counts[key]++
+	}
+	return counts
+}
+
+// TemplateChange is a message template that appeared or became more frequent in a window
+// compared with a baseline window
+type TemplateChange struct {
+	Change   string  `json:"change"`
+	LogType  string  `json:"log_type"`
+	Template string  `json:"template"`
+	Baseline int     `json:"baseline"`
+	Count    int     `json:"count"`
+	Ratio    float64 `json:"ratio,omitempty"` // change of the hourly rate, 0 for new templates
+}
+
+// DiffOptions selects the changes reported by DiffTemplates
+type DiffOptions struct {
+	// MinCount is the number of messages of a template in the window below which it is ignored
+	MinCount int
+	// MinRatio is the increase of the rate of a template, e.g. 2 for twice as frequent
+	MinRatio float64
+}
+
+// DiffTemplates reports the templates of window that are absent from baseline, and those
+// whose rate increased by at least options.MinRatio. Rates are compared per hour, so windows
+// of different lengths can be compared. New templates come first, then the largest increases.
+func DiffTemplates(window, baseline TemplateCounts, windowSpan, baselineSpan time.Duration, options DiffOptions) []TemplateChange {
+	var changes []TemplateChange
+	for key, count := range window {
+		if count < options.MinCount {
+			continue
+		}
+		change := TemplateChange{LogType: key.LogType, Template: key.Template, Baseline: baseline[key], Count: count}
+		if change.Baseline == 0 {
+			change.Change = ChangeNew
+			changes = append(changes, change)
+			continue
+		}
+		if windowSpan <= 0 || baselineSpan <= 0 {
+			continue
+		}
+		rate := float64(count) / windowSpan.Hours()
+		baselineRate := float64(change.Baseline) / baselineSpan.Hours()
+		change.Ratio = rate / baselineRate
+		if change.Ratio >= options.MinRatio {
+			change.Change = ChangeIncreased
+			changes = append(changes, change)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Change != b.Change {
+			return a.Change == ChangeNew
+		}
+		if a.Change == ChangeNew && a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Ratio != b.Ratio {
+			return a.Ratio > b.Ratio
+		}
+		if a.LogType != b.LogType {
+			return a.LogType < b.LogType
+		}
+		return a.Template < b.Template
+	})
+	return changes
+}
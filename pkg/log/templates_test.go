@@ -0,0 +1,87 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{
+			`I0101 10:00:03.000000       1 schedule_one.go:286] "Successfully bound pod to node" pod="shop/web-5d8f7" node="ip-10-0-1-5" evaluatedNodes=3 feasibleNodes=2`,
+			`"Successfully bound pod to node" pod=<*> node=<*> evaluatedNodes=<n> feasibleNodes=<n>`,
+		},
+		{
+			`E0101 10:00:03.000000       1 controller.go:1] "Error syncing pod" pod="shop/web" err="context deadline exceeded"`,
+			`"Error syncing pod" pod=<*> err="context deadline exceeded"`,
+		},
+		{
+			`Trace[1234567]: "List" url:/api/v1/pods,user-agent:kubectl,audit-id:8f14e45f-ceea-467f-a0e6-ab1e2b1c3d4e,client:10.0.1.5:443 (01-Jan-2024 10:00:00.000) (total time: 1502ms)`,
+			`Trace[<n>]: "List" url:/api/v1/pods,user-agent:kubectl,audit-id:<uuid>,client:<ip> (<n>-Jan-<n> <n>:<n>:<n>) (total time: <n>)`,
+		},
+		{`time="2024-01-01T10:00:00Z" level=info msg="access granted" arn="arn:aws:iam::123456789012:role/a" uid="heptio-authenticator-aws:123456789012:AROA1234"`, `time=<*> level=info msg=<*> arn=<*> uid=<*>`},
+		{`container 3f2a9c4e1b7d exited`, `container <hex> exited`},
+		{`deadbeefcafe and accepted stay`, `deadbeefcafe and accepted stay`},
+		{`{"verb":"patch","objectRef":{"resource":"pods","subresource":"status"},"responseStatus":{"code":200}}`, `audit patch pods/status 200`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, MessageTemplate(tt.message), tt.message)
+	}
+
+	// The same code logging different objects compares equal
+	assert.Equal(t,
+		MessageTemplate(`I0101 10:00:03.000000       1 a.go:1] "Deleting pod" pod="a/b" after=5s`),
+		MessageTemplate(`I0102 11:00:04.123456       1 a.go:1] "Deleting pod" pod="c/d" after=30s`))
+}
+
+func TestDiffTemplates(t *testing.T) {
+	key := func(template string) TemplateKey { return TemplateKey{LogType: "kcm", Template: template} }
+	window := TemplateCounts{
+		key("steady"):        10,
+		key("doubled"):       20,
+		key("tripled"):       30,
+		key("new frequent"):  9,
+		key("new"):           6,
+		key("new but rare"):  2,
+		key("decreased"):     1,
+		key("rare increase"): 4,
+	}
+	baseline := TemplateCounts{
+		key("steady"):        10,
+		key("doubled"):       10,
+		key("tripled"):       10,
+		key("decreased"):     50,
+		key("rare increase"): 1,
+		key("gone"):          40,
+	}
+
+	changes := DiffTemplates(window, baseline, time.Hour, time.Hour, DiffOptions{MinCount: 5, MinRatio: 2})
+	var templates []string
+	for _, change := range changes {
+		templates = append(templates, change.Change+" "+change.Template)
+	}
+	assert.Equal(t, []string{"new new frequent", "new new", "increased tripled", "increased doubled"}, templates)
+	assert.Equal(t, 3.0, changes[2].Ratio)
+	assert.Equal(t, 10, changes[2].Baseline)
+
+	// Rates are compared per hour: 20 events in 1h is the rate of 40 events in 2h
+	changes = DiffTemplates(TemplateCounts{key("doubled"): 20}, TemplateCounts{key("doubled"): 40}, time.Hour, 2*time.Hour, DiffOptions{MinCount: 1, MinRatio: 1.5})
+	assert.Empty(t, changes)
+}
+
+func TestCountTemplates(t *testing.T) {
+	counts := CountTemplates([]LogEntry{
+		{LogStream: "kube-controller-manager-a", Message: `I0101 10:00:00.000000 1 a.go:1] "Deleting pod" pod="a/b"`},
+		{LogStream: "kube-controller-manager-a", Message: `I0101 10:00:01.000000 1 a.go:1] "Deleting pod" pod="a/c"`},
+		{LogStream: "kube-scheduler-a", Message: `I0101 10:00:01.000000 1 a.go:1] "Deleting pod" pod="a/c"`},
+	})
+	assert.Equal(t, TemplateCounts{
+		{LogType: "kcm", Template: `"Deleting pod" pod=<*>`}:       2,
+		{LogType: "scheduler", Template: `"Deleting pod" pod=<*>`}: 1,
+	}, counts)
+}
@@ -0,0 +1,129 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// Theme remaps the palette used for log colorization.
+// Each built-in color attribute is replaced by the theme's attributes;
+// attributes without an entry are kept as-is.
+type Theme struct {
+	Name    string
+	palette map[color.Attribute][]color.Attribute
+}
+
+// DefaultThemeName is the theme used when none is configured
+const DefaultThemeName = "dark"
+
+// xterm256 returns the attributes selecting a 256-color foreground
+func xterm256(n int) []color.Attribute {
+	return []color.Attribute{38, 5, color.Attribute(n)}
+}
+
+// themes holds the built-in themes by name
+var themes = map[string]*Theme{
+	// dark is the original palette, designed for dark terminal backgrounds
+	"dark": {Name: "dark"},
+	// light avoids bright, yellow and gray colors that are hard to read on light backgrounds
+	"light": {
+		Name: "light",
+		palette: map[color.Attribute][]color.Attribute{
+			color.FgHiBlack:   {color.FgBlack},
+			color.FgWhite:     {color.FgBlack},
+			color.FgHiWhite:   {color.FgBlack},
+			color.FgYellow:    {color.FgMagenta},
+			color.FgHiYellow:  {color.FgMagenta},
+			color.FgMagenta:   {color.FgBlue, color.Bold},
+			color.FgHiCyan:    {color.FgCyan},
+			color.FgHiRed:     {color.FgRed, color.Bold},
+			color.FgHiGreen:   {color.FgGreen},
+			color.FgHiBlue:    {color.FgBlue},
+			color.FgHiMagenta: {color.FgMagenta},
+		},
+	},
+	// monochrome-bold uses no colors: errors are bold, warnings underlined and secondary text faint
+	"monochrome-bold": {
+		Name: "monochrome-bold",
+		palette: map[color.Attribute][]color.Attribute{
+			color.FgRed:       {color.Bold},
+			color.FgHiRed:     {color.Bold},
+			color.FgYellow:    {color.Underline},
+			color.FgHiYellow:  {color.Underline},
+			color.FgHiBlack:   {color.Faint},
+			color.FgBlack:     {},
+			color.FgGreen:     {},
+			color.FgHiGreen:   {},
+			color.FgBlue:      {},
+			color.FgHiBlue:    {},
+			color.FgMagenta:   {},
+			color.FgHiMagenta: {},
+			color.FgCyan:      {},
+			color.FgHiCyan:    {},
+			color.FgWhite:     {},
+			color.FgHiWhite:   {},
+		},
+	},
+	// solarized uses the Solarized accent colors (256-color approximations)
+	"solarized": {
+		Name: "solarized",
+		palette: map[color.Attribute][]color.Attribute{
+			color.FgHiBlack:   xterm256(245), // base1
+			color.FgRed:       xterm256(160),
+			color.FgHiRed:     xterm256(166), // orange
+			color.FgGreen:     xterm256(64),
+			color.FgHiGreen:   xterm256(64),
+			color.FgYellow:    xterm256(136),
+			color.FgHiYellow:  xterm256(136),
+			color.FgBlue:      xterm256(33),
+			color.FgHiBlue:    xterm256(33),
+			color.FgMagenta:   xterm256(125),
+			color.FgHiMagenta: xterm256(61), // violet
+			color.FgCyan:      xterm256(37),
+			color.FgHiCyan:    xterm256(37),
+		},
+	},
+}
+
+// ParseTheme returns the built-in theme with the given name
+func ParseTheme(name string) (*Theme, error) {
+	if name == "" {
+		name = DefaultThemeName
+	}
+
+	theme, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("invalid theme '%s' (supported: %s)", name, strings.Join(ThemeNames(), ", "))
+	}
+	return theme, nil
+}
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Color builds a color from the given attributes, remapped through the theme palette
+func (t *Theme) Color(attributes ...color.Attribute) *color.Color {
+	if t == nil || len(t.palette) == 0 {
+		return color.New(attributes...)
+	}
+
+	var mapped []color.Attribute
+	for _, attribute := range attributes {
+		if replacement, ok := t.palette[attribute]; ok {
+			mapped = append(mapped, replacement...)
+			continue
+		}
+		mapped = append(mapped, attribute)
+	}
+	return color.New(mapped...)
+}
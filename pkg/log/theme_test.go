@@ -0,0 +1,54 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultThemeName, theme.Name)
+
+	for _, name := range []string{"dark", "light", "monochrome-bold", "solarized", "Light"} {
+		_, err := ParseTheme(name)
+		assert.NoError(t, err, name)
+	}
+
+	_, err = ParseTheme("neon")
+	assert.Error(t, err)
+}
+
+func TestThemeColor(t *testing.T) {
+	original := color.NoColor
+	color.NoColor = false
+	defer func() { color.NoColor = original }()
+
+	tests := []struct {
+		theme    string
+		input    []color.Attribute
+		expected *color.Color
+	}{
+		{theme: "dark", input: []color.Attribute{color.FgHiBlack}, expected: color.New(color.FgHiBlack)},
+		{theme: "light", input: []color.Attribute{color.FgHiBlack}, expected: color.New(color.FgBlack)},
+		{theme: "light", input: []color.Attribute{color.FgYellow}, expected: color.New(color.FgMagenta)},
+		{theme: "light", input: []color.Attribute{color.FgRed, color.Bold}, expected: color.New(color.FgRed, color.Bold)},
+		{theme: "monochrome-bold", input: []color.Attribute{color.FgRed}, expected: color.New(color.Bold)},
+		{theme: "monochrome-bold", input: []color.Attribute{color.FgCyan, color.Bold}, expected: color.New(color.Bold)},
+		{theme: "solarized", input: []color.Attribute{color.FgYellow}, expected: color.New(38, 5, 136)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.theme, func(t *testing.T) {
+			theme, err := ParseTheme(tt.theme)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected.Sprint("x"), theme.Color(tt.input...).Sprint("x"))
+		})
+	}
+
+	// A nil theme uses the original colors
+	var theme *Theme
+	assert.Equal(t, color.New(color.FgGreen).Sprint("x"), theme.Color(color.FgGreen).Sprint("x"))
+}
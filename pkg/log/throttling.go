@@ -0,0 +1,171 @@
+package log
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// httplogFieldPattern matches the key="value" and key=value fields of API server httplog lines
+var httplogFieldPattern = regexp.MustCompile(`([A-Za-z_-]+)=("(?:[^"\\]|\\.)*"|\S+)`)
+
+// ThrottledRequest is a request rejected with 429 Too Many Requests, usually by API Priority
+// and Fairness (APF)
+type ThrottledRequest struct {
+	Time          time.Time
+	AuditID       string
+	FlowSchema    string
+	PriorityLevel string
+	UserAgent     string
+	User          string
+	Verb          string
+}
+
+// ThrottleGroup counts the throttled requests of one flow schema, priority level, user
+// agent and user
+type ThrottleGroup struct {
+	FlowSchema    string    `json:"flow_schema"`
+	PriorityLevel string    `json:"priority_level"`
+	UserAgent     string    `json:"user_agent"`
+	User          string    `json:"user"`
+	Count         int       `json:"count"`
+	First         time.Time `json:"first"`
+	Last          time.Time `json:"last"`
+}
+
+// auditThrottleEvent holds the fields of an audit event used to attribute a 429 response
+type auditThrottleEvent struct {
+	AuditID                  string    `json:"auditID"`
+	Verb                     string    `json:"verb"`
+	UserAgent                string    `json:"userAgent"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	User                     struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ResponseStatus struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ParseThrottled extracts a 429 response from an audit event, or from an API server httplog
+// line such as `"HTTP" verb="LIST" URI="/api/v1/pods" ... userAgent="..." apf_fs="..." resp=429`
+func ParseThrottled(entry LogEntry) (ThrottledRequest, bool) {
+	message := strings.TrimSpace(entry.Message)
+	if strings.HasPrefix(message, "{") {
+		var event auditThrottleEvent
+		if err := json.Unmarshal([]byte(message), &event); err != nil || event.ResponseStatus.Code != 429 {
+			return ThrottledRequest{}, false
+		}
+		timestamp := event.RequestReceivedTimestamp
+		if timestamp.IsZero() {
+			timestamp = entry.Timestamp
+		}
+		return ThrottledRequest{
+			Time:          timestamp.UTC(),
+			AuditID:       event.AuditID,
+			FlowSchema:    event.Annotations["apf_fs"],
+			PriorityLevel: event.Annotations["apf_pl"],
+			UserAgent:     event.UserAgent,
+			User:          event.User.Username,
+			Verb:          event.Verb,
+		}, true
+	}
+
+	if !strings.Contains(message, "resp=429") {
+		return ThrottledRequest{}, false
+	}
+	fields := make(map[string]string)
+	for _, match := range httplogFieldPattern.FindAllStringSubmatch(message, -1) {
+		fields[match[1]] = strings.Trim(match[2], `"`)
+	}
+	if fields["resp"] != "429" {
+		return ThrottledRequest{}, false
+	}
+	return ThrottledRequest{
+		Time:          entry.Timestamp.UTC(),
+		AuditID:       fields["audit-ID"],
+		FlowSchema:    fields["apf_fs"],
+		PriorityLevel: fields["apf_pl"],
+		UserAgent:     fields["userAgent"],
+		Verb:          strings.ToLower(fields["verb"]),
+	}, true
+}
+
+// GroupThrottled counts throttled requests per flow schema, priority level, user agent and
+// user, the largest groups first. A request found in both the audit and API server logs
+// is counted once, combining the fields of both records.
+func GroupThrottled(requests []ThrottledRequest) []ThrottleGroup {
+	byAuditID := make(map[string]int)
+	var unique []ThrottledRequest
+	for _, request := range requests {
+		if request.AuditID == "" {
+			unique = append(unique, request)
+			continue
+		}
+		if i, seen := byAuditID[request.AuditID]; seen {
+			unique[i] = mergeThrottled(unique[i], request)
+			continue
+		}
+		byAuditID[request.AuditID] = len(unique)
+		unique = append(unique, request)
+	}
+
+	type groupKey struct{ flowSchema, priorityLevel, userAgent, user string }
+	groups := make(map[groupKey]*ThrottleGroup)
+	var ordered []*ThrottleGroup
+	for _, request := range unique {
+		key := groupKey{request.FlowSchema, request.PriorityLevel, request.UserAgent, request.User}
+		group, exists := groups[key]
+		if !exists {
+			group = &ThrottleGroup{
+				FlowSchema:    request.FlowSchema,
+				PriorityLevel: request.PriorityLevel,
+				UserAgent:     request.UserAgent,
+				User:          request.User,
+				First:         request.Time,
+				Last:          request.Time,
+			}
+			groups[key] = group
+			ordered = append(ordered, group)
+		}
+		group.Count++
+		if request.Time.Before(group.First) {
+			group.First = request.Time
+		}
+		if request.Time.After(group.Last) {
+			group.Last = request.Time
+		}
+	}
+
+	result := make([]ThrottleGroup, 0, len(ordered))
+	for _, group := range ordered {
+		result = append(result, *group)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Count > result[j].Count
+	})
+	return result
+}
+
+// mergeThrottled fills the empty fields of one record of a request from another
+func mergeThrottled(a, b ThrottledRequest) ThrottledRequest {
+	if a.FlowSchema == "" {
+		a.FlowSchema = b.FlowSchema
+	}
+	if a.PriorityLevel == "" {
+		a.PriorityLevel = b.PriorityLevel
+	}
+	if a.UserAgent == "" {
+		a.UserAgent = b.UserAgent
+	}
+	if a.User == "" {
+		a.User = b.User
+	}
+	if a.Verb == "" {
+		a.Verb = b.Verb
+	}
+	return a
+}
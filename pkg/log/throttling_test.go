@@ -0,0 +1,65 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseThrottled(t *testing.T) {
+	audit := LogEntry{Message: `{"auditID":"a1","verb":"list","userAgent":"karpenter/v0.33.0",` +
+		`"requestReceivedTimestamp":"2024-01-01T10:00:00Z","user":{"username":"system:serviceaccount:karpenter:karpenter"},` +
+		`"responseStatus":{"code":429},"annotations":{"apf_fs":"service-accounts","apf_pl":"workload-low"}}`}
+	request, ok := ParseThrottled(audit)
+	assert.True(t, ok)
+	assert.Equal(t, ThrottledRequest{
+		Time:          time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		AuditID:       "a1",
+		FlowSchema:    "service-accounts",
+		PriorityLevel: "workload-low",
+		UserAgent:     "karpenter/v0.33.0",
+		User:          "system:serviceaccount:karpenter:karpenter",
+		Verb:          "list",
+	}, request)
+
+	httplog := LogEntry{
+		Timestamp: time.Date(2024, 1, 1, 10, 0, 1, 0, time.UTC),
+		Message: `I0101 10:00:01.000000 1 httplog.go:132] "HTTP" verb="LIST" URI="/api/v1/pods" latency="1ms" ` +
+			`userAgent="kubectl/v1.29.0 (linux/amd64) kubernetes/3f7a50f" audit-ID="h1" srcIP="10.0.1.5:443" ` +
+			`apf_pl="workload-low" apf_fs="global-default" resp=429`,
+	}
+	request, ok = ParseThrottled(httplog)
+	assert.True(t, ok)
+	assert.Equal(t, "h1", request.AuditID)
+	assert.Equal(t, "global-default", request.FlowSchema)
+	assert.Equal(t, "workload-low", request.PriorityLevel)
+	assert.Equal(t, "kubectl/v1.29.0 (linux/amd64) kubernetes/3f7a50f", request.UserAgent)
+	assert.Equal(t, "list", request.Verb)
+
+	for _, message := range []string{
+		`{"auditID":"a2","verb":"get","responseStatus":{"code":200}}`,
+		`I0101 10:00:01.000000 1 httplog.go:132] "HTTP" verb="GET" URI="/healthz" resp=200`,
+		`I0101 10:00:01.000000 1 controller.go:1] processed 429 items`,
+	} {
+		_, ok := ParseThrottled(LogEntry{Message: message})
+		assert.False(t, ok, message)
+	}
+}
+
+func TestGroupThrottled(t *testing.T) {
+	at := func(second int) time.Time { return time.Date(2024, 1, 1, 10, 0, second, 0, time.UTC) }
+	requests := []ThrottledRequest{
+		// The same request in the API server and audit logs
+		{AuditID: "a1", Time: at(1), FlowSchema: "service-accounts", PriorityLevel: "workload-low", UserAgent: "karpenter/v0.33.0"},
+		{AuditID: "a1", Time: at(1), FlowSchema: "service-accounts", PriorityLevel: "workload-low", UserAgent: "karpenter/v0.33.0", User: "karpenter"},
+		{AuditID: "a2", Time: at(3), FlowSchema: "service-accounts", PriorityLevel: "workload-low", UserAgent: "karpenter/v0.33.0", User: "karpenter"},
+		{AuditID: "a3", Time: at(2), FlowSchema: "global-default", PriorityLevel: "global-default", UserAgent: "kubectl/v1.29.0", User: "alice"},
+	}
+
+	groups := GroupThrottled(requests)
+	assert.Equal(t, []ThrottleGroup{
+		{FlowSchema: "service-accounts", PriorityLevel: "workload-low", UserAgent: "karpenter/v0.33.0", User: "karpenter", Count: 2, First: at(1), Last: at(3)},
+		{FlowSchema: "global-default", PriorityLevel: "global-default", UserAgent: "kubectl/v1.29.0", User: "alice", Count: 1, First: at(2), Last: at(2)},
+	}, groups)
+}
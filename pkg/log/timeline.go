@@ -0,0 +1,297 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stages of an object's life reported in a timeline
+const (
+	StageCreated     = "created"
+	StageUpdated     = "updated"
+	StageStatus      = "status updated"
+	StageScheduled   = "scheduled"
+	StageUnscheduled = "unschedulable"
+	StageWebhook     = "admission webhook"
+	StageDeleted     = "deleted"
+	StageRejected    = "rejected"
+	StageController  = "controller"
+	StageAPIServer   = "api server"
+)
+
+// timelineKinds maps the kinds accepted by ParseObjectKind, singular and short names
+// included, to their API resource and whether they are namespaced
+var timelineKinds = map[string]struct {
+	resource   string
+	namespaced bool
+}{
+	"pod":                   {"pods", true},
+	"po":                    {"pods", true},
+	"deployment":            {"deployments", true},
+	"deploy":                {"deployments", true},
+	"replicaset":            {"replicasets", true},
+	"rs":                    {"replicasets", true},
+	"statefulset":           {"statefulsets", true},
+	"sts":                   {"statefulsets", true},
+	"daemonset":             {"daemonsets", true},
+	"ds":                    {"daemonsets", true},
+	"job":                   {"jobs", true},
+	"cronjob":               {"cronjobs", true},
+	"cj":                    {"cronjobs", true},
+	"service":               {"services", true},
+	"svc":                   {"services", true},
+	"configmap":             {"configmaps", true},
+	"cm":                    {"configmaps", true},
+	"secret":                {"secrets", true},
+	"serviceaccount":        {"serviceaccounts", true},
+	"sa":                    {"serviceaccounts", true},
+	"persistentvolumeclaim": {"persistentvolumeclaims", true},
+	"pvc":                   {"persistentvolumeclaims", true},
+	"ingress":               {"ingresses", true},
+	"ing":                   {"ingresses", true},
+	"node":                  {"nodes", false},
+	"no":                    {"nodes", false},
+	"namespace":             {"namespaces", false},
+	"ns":                    {"namespaces", false},
+	"persistentvolume":      {"persistentvolumes", false},
+	"pv":                    {"persistentvolumes", false},
+}
+
+// klogMessagePattern matches the klog header and source location preceding the message,
+// e.g. "I0101 10:00:00.000000       1 schedule_one.go:286] "
+var klogMessagePattern = regexp.MustCompile(`^[IWEF]\d{4} \d{2}:\d{2}:\d{2}\.\d{6}\s+\d+ [^\]]+\] `)
+
+// ObjectRef identifies the Kubernetes object a timeline is about
+type ObjectRef struct {
+	Resource  string
+	Namespace string
+	Name      string
+}
+
+// ParseObjectKind returns the reference to the object of a kind, e.g. "pod" or "deploy",
+// named name in namespace. The namespace is ignored for cluster-scoped kinds.
+func ParseObjectKind(kind, namespace, name string) (ObjectRef, error) {
+	lower := strings.ToLower(kind)
+	info, ok := timelineKinds[lower]
+	if !ok {
+		info, ok = timelineKinds[strings.TrimSuffix(lower, "s")]
+	}
+	if !ok {
+		return ObjectRef{}, fmt.Errorf("unsupported kind '%s' (supported: pod, deployment, replicaset, statefulset, daemonset, job, cronjob, service, configmap, secret, serviceaccount, persistentvolumeclaim, ingress, node, namespace, persistentvolume)", kind)
+	}
+	if name == "" {
+		return ObjectRef{}, fmt.Errorf("the name of the %s is required", lower)
+	}
+	ref := ObjectRef{Resource: info.resource, Name: name}
+	if info.namespaced {
+		ref.Namespace = namespace
+		if ref.Namespace == "" {
+			ref.Namespace = "default"
+		}
+	}
+	return ref, nil
+}
+
+// String returns the object as in kubectl, e.g. "pods bar/foo"
+func (r ObjectRef) String() string {
+	if r.Namespace == "" {
+		return r.Resource + " " + r.Name
+	}
+	return r.Resource + " " + r.Namespace + "/" + r.Name
+}
+
+// key is how component logs refer to the object, e.g. pod="bar/foo"
+func (r ObjectRef) key() string {
+	if r.Namespace == "" {
+		return r.Name
+	}
+	return r.Namespace + "/" + r.Name
+}
+
+// FilterPattern returns a filter pattern selecting the log events mentioning the object;
+// TimelineEventsFor then keeps those that are about it
+func (r ObjectRef) FilterPattern() string {
+	return fmt.Sprintf("%q", r.Name)
+}
+
+// TimelineEvent is a step in the life of an object, as seen by one component
+type TimelineEvent struct {
+	Time    time.Time `json:"time"`
+	LogType string    `json:"log_type"`
+	Stage   string    `json:"stage"`
+	Summary string    `json:"summary"`
+	User    string    `json:"user,omitempty"`
+}
+
+// auditObjectEvent holds the fields of an audit event used to build a timeline
+type auditObjectEvent struct {
+	Stage                    string    `json:"stage"`
+	Verb                     string    `json:"verb"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	User                     struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectRef struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+	} `json:"objectRef"`
+	ResponseStatus struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"responseStatus"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// admissionWebhookAnnotation is the audit annotation prefix of mutating webhook calls
+const admissionWebhookAnnotation = "mutation.webhook.admission.k8s.io/"
+
+// TimelineEventsFor returns the steps in the life of the object an entry of the audit,
+// scheduler, controller manager or API server logs reports. Reads (get, list, watch) and
+// audit stages other than ResponseComplete are left out.
+func TimelineEventsFor(entry LogEntry, ref ObjectRef) []TimelineEvent {
+	logType := LogTypeFor(entry.LogGroup, entry.LogStream)
+	if logType == "audit" {
+		return auditTimelineEvents(entry, ref)
+	}
+
+	message := klogMessagePattern.ReplaceAllString(strings.TrimSpace(entry.Message), "")
+	if !mentionsObject(message, ref) {
+		return nil
+	}
+	event := TimelineEvent{Time: entry.Timestamp.UTC(), LogType: logType, Summary: message}
+	switch logType {
+	case "scheduler":
+		switch {
+		case strings.Contains(message, "Successfully bound"):
+			event.Stage = StageScheduled
+		case strings.Contains(message, "Unable to schedule"), strings.Contains(message, "FailedScheduling"):
+			event.Stage = StageUnscheduled
+		default:
+			event.Stage = "scheduler"
+		}
+	case "kcm":
+		event.Stage = StageController
+	case "api":
+		event.Stage = StageAPIServer
+	default:
+		return nil
+	}
+	return []TimelineEvent{event}
+}
+
+// mentionsObject reports whether a component log message refers to the object, e.g. with
+// pod="bar/foo". The name must appear whole, so bar/foo-2 is not a mention of bar/foo.
+func mentionsObject(message string, ref ObjectRef) bool {
+	key := ref.key()
+	for start := 0; ; {
+		i := strings.Index(message[start:], key)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(key)
+		if (i == 0 || !isNameChar(message[i-1])) && (end == len(message) || !isNameChar(message[end])) {
+			return true
+		}
+		start = i + 1
+	}
+}
+
+// isNameChar reports whether c can be part of a Kubernetes object name or namespace
+func isNameChar(c byte) bool {
+	return c == '-' || c == '.' || c == '/' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// auditTimelineEvents returns the change an audit event made to the object and the
+// mutating webhooks called for it
+func auditTimelineEvents(entry LogEntry, ref ObjectRef) []TimelineEvent {
+	var event auditObjectEvent
+	if err := json.Unmarshal([]byte(entry.Message), &event); err != nil {
+		return nil
+	}
+	if event.Stage != "" && event.Stage != "ResponseComplete" {
+		return nil
+	}
+	if event.ObjectRef.Resource != ref.Resource || event.ObjectRef.Name != ref.Name || event.ObjectRef.Namespace != ref.Namespace {
+		return nil
+	}
+	if event.Verb == "get" || event.Verb == "list" || event.Verb == "watch" {
+		return nil
+	}
+
+	timestamp := event.RequestReceivedTimestamp
+	if timestamp.IsZero() {
+		timestamp = entry.Timestamp
+	}
+	base := TimelineEvent{Time: timestamp.UTC(), LogType: "audit", User: event.User.Username}
+
+	var events []TimelineEvent
+	var webhooks []string
+	for key := range event.Annotations {
+		if strings.HasPrefix(key, admissionWebhookAnnotation) {
+			webhooks = append(webhooks, key)
+		}
+	}
+	sort.Strings(webhooks)
+	for _, key := range webhooks {
+		var call struct {
+			Configuration string `json:"configuration"`
+			Webhook       string `json:"webhook"`
+			Mutated       bool   `json:"mutated"`
+		}
+		if err := json.Unmarshal([]byte(event.Annotations[key]), &call); err != nil {
+			continue
+		}
+		webhook := base
+		webhook.Stage = StageWebhook
+		webhook.Summary = fmt.Sprintf("%s (%s) on %s", call.Webhook, call.Configuration, event.Verb)
+		if call.Mutated {
+			webhook.Summary += ", mutated"
+		}
+		events = append(events, webhook)
+	}
+
+	change := base
+	change.Summary = event.Verb
+	if event.ObjectRef.Subresource != "" {
+		change.Summary += " " + event.ObjectRef.Subresource
+	}
+	switch {
+	case event.ResponseStatus.Code >= 400:
+		change.Stage = StageRejected
+		change.Summary += fmt.Sprintf(" %d", event.ResponseStatus.Code)
+		if event.ResponseStatus.Message != "" {
+			change.Summary += ": " + event.ResponseStatus.Message
+		}
+	case event.ObjectRef.Subresource == "binding":
+		change.Stage = StageScheduled
+	case event.ObjectRef.Subresource == "status":
+		change.Stage = StageStatus
+	case event.Verb == "create":
+		change.Stage = StageCreated
+	case event.Verb == "delete" || event.Verb == "deletecollection":
+		change.Stage = StageDeleted
+	default:
+		change.Stage = StageUpdated
+	}
+	return append(events, change)
+}
+
+// BuildTimeline returns the steps in the life of the object reported by entries, oldest
+// first. A step seen at the same time by several components keeps the order of entries.
+func BuildTimeline(entries []LogEntry, ref ObjectRef) []TimelineEvent {
+	var events []TimelineEvent
+	for _, entry := range entries {
+		events = append(events, TimelineEventsFor(entry, ref)...)
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseObjectKind(t *testing.T) {
+	ref, err := ParseObjectKind("pod", "shop", "web")
+	assert.NoError(t, err)
+	assert.Equal(t, ObjectRef{Resource: "pods", Namespace: "shop", Name: "web"}, ref)
+	assert.Equal(t, "pods shop/web", ref.String())
+	assert.Equal(t, `"web"`, ref.FilterPattern())
+
+	ref, err = ParseObjectKind("Deployments", "", "api")
+	assert.NoError(t, err)
+	assert.Equal(t, ObjectRef{Resource: "deployments", Namespace: "default", Name: "api"}, ref)
+
+	// Cluster-scoped kinds have no namespace
+	ref, err = ParseObjectKind("no", "shop", "ip-10-0-1-5.ec2.internal")
+	assert.NoError(t, err)
+	assert.Equal(t, "nodes ip-10-0-1-5.ec2.internal", ref.String())
+
+	_, err = ParseObjectKind("widget", "", "a")
+	assert.Error(t, err)
+	_, err = ParseObjectKind("pod", "", "")
+	assert.Error(t, err)
+}
+
+func TestBuildTimeline(t *testing.T) {
+	ref := ObjectRef{Resource: "pods", Namespace: "shop", Name: "web"}
+	at := func(second int) time.Time { return time.Date(2024, 1, 1, 10, 0, second, 0, time.UTC) }
+	audit := func(second int, message string) LogEntry {
+		return LogEntry{Timestamp: at(second), LogStream: "kube-apiserver-audit-abc", Message: message}
+	}
+
+	entries := []LogEntry{
+		{Timestamp: at(3), LogStream: "kube-scheduler-abc",
+			Message: `I0101 10:00:03.000000       1 schedule_one.go:286] "Successfully bound pod to node" pod="shop/web" node="ip-10-0-1-5"`},
+		audit(1, `{"stage":"ResponseComplete","verb":"create","requestReceivedTimestamp":"2024-01-01T10:00:01Z",`+
+			`"user":{"username":"system:serviceaccount:kube-system:replicaset-controller"},`+
+			`"objectRef":{"resource":"pods","namespace":"shop","name":"web"},"responseStatus":{"code":201},`+
+			`"annotations":{"mutation.webhook.admission.k8s.io/round_0_index_0":"{\"configuration\":\"pod-identity-webhook\",\"webhook\":\"iam-for-pods.amazonaws.com\",\"mutated\":true}"}}`),
+		// The same request at another stage, a read and another object are left out
+		audit(1, `{"stage":"RequestReceived","verb":"create","objectRef":{"resource":"pods","namespace":"shop","name":"web"}}`),
+		audit(2, `{"stage":"ResponseComplete","verb":"get","objectRef":{"resource":"pods","namespace":"shop","name":"web"}}`),
+		audit(2, `{"stage":"ResponseComplete","verb":"create","objectRef":{"resource":"pods","namespace":"shop","name":"web-2"}}`),
+		audit(4, `{"stage":"ResponseComplete","verb":"create","user":{"username":"system:kube-scheduler"},`+
+			`"objectRef":{"resource":"pods","namespace":"shop","name":"web","subresource":"binding"},"responseStatus":{"code":201}}`),
+		audit(5, `{"stage":"ResponseComplete","verb":"patch","user":{"username":"system:node:ip-10-0-1-5"},`+
+			`"objectRef":{"resource":"pods","namespace":"shop","name":"web","subresource":"status"},"responseStatus":{"code":200}}`),
+		audit(6, `{"stage":"ResponseComplete","verb":"delete","user":{"username":"alice"},`+
+			`"objectRef":{"resource":"pods","namespace":"shop","name":"web"},"responseStatus":{"code":403,"message":"forbidden"}}`),
+		{Timestamp: at(7), LogStream: "kube-controller-manager-abc",
+			Message: `I0101 10:00:07.000000       1 replica_set.go:1] "Too many replicas" pod="shop/web-2"`},
+		{Timestamp: at(8), LogStream: "kube-controller-manager-abc",
+			Message: `I0101 10:00:08.000000       1 taint_manager.go:1] "Deleting pod" pod="shop/web"`},
+	}
+
+	events := BuildTimeline(entries, ref)
+	var stages []string
+	for _, event := range events {
+		stages = append(stages, event.Stage)
+	}
+	assert.Equal(t, []string{StageWebhook, StageCreated, StageScheduled, StageScheduled, StageStatus, StageRejected, StageController}, stages)
+
+	assert.Equal(t, "iam-for-pods.amazonaws.com (pod-identity-webhook) on create, mutated", events[0].Summary)
+	assert.Equal(t, "system:serviceaccount:kube-system:replicaset-controller", events[1].User)
+	assert.Equal(t, "scheduler", events[2].LogType)
+	assert.Equal(t, `"Successfully bound pod to node" pod="shop/web" node="ip-10-0-1-5"`, events[2].Summary)
+	assert.Equal(t, "create binding", events[3].Summary)
+	assert.Equal(t, "delete 403: forbidden", events[5].Summary)
+	assert.Equal(t, at(8), events[6].Time)
+}
@@ -0,0 +1,144 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// TimestampSource defines which timestamp is displayed for a log entry
+type TimestampSource string
+
+const (
+	// TimestampSourceEvent uses the CloudWatch event timestamp
+	TimestampSourceEvent TimestampSource = "event"
+	// TimestampSourceIngestion uses the time CloudWatch ingested the event
+	TimestampSourceIngestion TimestampSource = "ingestion"
+	// TimestampSourceMessage uses the timestamp embedded in the log message (klog header, audit or logfmt fields)
+	TimestampSourceMessage TimestampSource = "message"
+)
+
+var (
+	// klogHeaderPattern matches the klog header, e.g. "I0719 06:09:10.476002"
+	klogHeaderPattern = regexp.MustCompile(`^[IWEF](\d{2})(\d{2}) (\d{2}):(\d{2}):(\d{2})\.(\d{6})`)
+	// logfmtTimePattern matches logrus-style time fields, e.g. time="2024-01-01T00:00:00Z"
+	logfmtTimePattern = regexp.MustCompile(`\btime="([^"]+)"`)
+	// jsonTimestampKeys lists JSON keys holding the event time, in order of preference
+	jsonTimestampKeys = []string{"requestReceivedTimestamp", "stageTimestamp", "ts", "time", "timestamp"}
+)
+
+// ParseTimestampSource validates a timestamp source name
+func ParseTimestampSource(source string) (TimestampSource, error) {
+	switch TimestampSource(source) {
+	case "", TimestampSourceEvent:
+		return TimestampSourceEvent, nil
+	case TimestampSourceIngestion:
+		return TimestampSourceIngestion, nil
+	case TimestampSourceMessage:
+		return TimestampSourceMessage, nil
+	default:
+		return "", fmt.Errorf("invalid timestamp source '%s' (supported: event, ingestion, message)", source)
+	}
+}
+
+// TimestampFor returns the entry's timestamp according to the given source,
+// falling back to the event timestamp when the requested one is unavailable
+func (e LogEntry) TimestampFor(source TimestampSource) time.Time {
+	switch source {
+	case TimestampSourceIngestion:
+		if !e.IngestionTime.IsZero() {
+			return e.IngestionTime
+		}
+	case TimestampSourceMessage:
+		if t, ok := ExtractMessageTimestamp(e.Message, e.Timestamp); ok {
+			return t
+		}
+	}
+	return e.Timestamp
+}
+
+// ExtractMessageTimestamp extracts the timestamp embedded in a log message.
+// klog headers do not carry a year, so it is taken from the reference time.
+func ExtractMessageTimestamp(message string, reference time.Time) (time.Time, bool) {
+	if matches := klogHeaderPattern.FindStringSubmatch(message); matches != nil {
+		return parseKlogTimestamp(matches, reference)
+	}
+
+	if data, ok := parseJSONMessage(message); ok {
+		for _, key := range jsonTimestampKeys {
+			if t, ok := parseJSONTimestamp(data[key]); ok {
+				return t, true
+			}
+		}
+	}
+
+	if matches := logfmtTimePattern.FindStringSubmatch(message); matches != nil {
+		if t, err := time.Parse(time.RFC3339Nano, matches[1]); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// parseKlogTimestamp builds a timestamp from klog header fields
+func parseKlogTimestamp(matches []string, reference time.Time) (time.Time, bool) {
+	var parts [6]int
+	for i := range parts {
+		value, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return time.Time{}, false
+		}
+		parts[i] = value
+	}
+
+	reference = reference.UTC()
+	t := time.Date(reference.Year(), time.Month(parts[0]), parts[1], parts[2], parts[3], parts[4], parts[5]*1000, time.UTC)
+
+	// Handle entries logged just before a new year but ingested after it
+	if t.Sub(reference) > 180*24*time.Hour {
+		t = t.AddDate(-1, 0, 0)
+	}
+
+	return t, true
+}
+
+// parseJSONTimestamp parses an RFC3339 string or a Unix epoch number (seconds or milliseconds)
+func parseJSONTimestamp(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil || f <= 0 {
+			return time.Time{}, false
+		}
+		if f > 1e12 {
+			return time.UnixMilli(int64(f)).UTC(), true
+		}
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// IngestionLag returns the delay between the time an event was logged (the timestamp
+// embedded in the message, or the event timestamp) and its ingestion by CloudWatch Logs.
+// It returns false if the ingestion time is unknown.
+func (e LogEntry) IngestionLag() (time.Duration, bool) {
+	if e.IngestionTime.IsZero() {
+		return 0, false
+	}
+
+	loggedAt := e.Timestamp
+	if t, ok := ExtractMessageTimestamp(e.Message, e.IngestionTime); ok {
+		loggedAt = t
+	}
+
+	return e.IngestionTime.Sub(loggedAt), true
+}
@@ -0,0 +1,105 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTimestampSource(t *testing.T) {
+	source, err := ParseTimestampSource("")
+	assert.NoError(t, err)
+	assert.Equal(t, TimestampSourceEvent, source)
+
+	source, err = ParseTimestampSource("message")
+	assert.NoError(t, err)
+	assert.Equal(t, TimestampSourceMessage, source)
+
+	_, err = ParseTimestampSource("wallclock")
+	assert.Error(t, err)
+}
+
+func TestExtractMessageTimestamp(t *testing.T) {
+	reference := time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		message   string
+		reference time.Time
+		expected  time.Time
+		ok        bool
+	}{
+		{
+			name:      "klog header",
+			message:   "I0719 06:09:10.476002 1 controller.go:123] Starting controller",
+			reference: reference,
+			expected:  time.Date(2024, 7, 19, 6, 9, 10, 476002000, time.UTC),
+			ok:        true,
+		},
+		{
+			name:      "klog header across new year",
+			message:   "W1231 23:59:59.900000 1 controller.go:123] Warning message",
+			reference: time.Date(2025, 1, 1, 0, 0, 1, 0, time.UTC),
+			expected:  time.Date(2024, 12, 31, 23, 59, 59, 900000000, time.UTC),
+			ok:        true,
+		},
+		{
+			name:      "audit request received timestamp",
+			message:   `{"kind":"Event","requestReceivedTimestamp":"2024-07-19T06:09:10.123456Z","stageTimestamp":"2024-07-19T06:09:10.200000Z"}`,
+			reference: reference,
+			expected:  time.Date(2024, 7, 19, 6, 9, 10, 123456000, time.UTC),
+			ok:        true,
+		},
+		{
+			name:      "structured klog epoch seconds",
+			message:   `{"ts":1721369350.5,"msg":"Starting controller"}`,
+			reference: reference,
+			expected:  time.Unix(1721369350, 500000000).UTC(),
+			ok:        true,
+		},
+		{
+			name:      "logfmt time field",
+			message:   `time="2024-07-19T06:09:10Z" level=info msg="access granted"`,
+			reference: reference,
+			expected:  time.Date(2024, 7, 19, 6, 9, 10, 0, time.UTC),
+			ok:        true,
+		},
+		{
+			name:      "no timestamp",
+			message:   "Starting controller",
+			reference: reference,
+			ok:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, ok := ExtractMessageTimestamp(tt.message, tt.reference)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.True(t, tt.expected.Equal(result), "expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestLogEntryTimestampFor(t *testing.T) {
+	eventTime := time.Date(2024, 7, 19, 6, 9, 12, 0, time.UTC)
+	ingestionTime := eventTime.Add(3 * time.Second)
+
+	entry := LogEntry{
+		Timestamp:     eventTime,
+		IngestionTime: ingestionTime,
+		Message:       "I0719 06:09:10.476002 1 controller.go:123] Starting controller",
+	}
+
+	assert.Equal(t, eventTime, entry.TimestampFor(TimestampSourceEvent))
+	assert.Equal(t, ingestionTime, entry.TimestampFor(TimestampSourceIngestion))
+	assert.Equal(t, time.Date(2024, 7, 19, 6, 9, 10, 476002000, time.UTC), entry.TimestampFor(TimestampSourceMessage))
+
+	// Falls back to the event time when the requested timestamp is unavailable
+	entry = LogEntry{Timestamp: eventTime, Message: "no timestamp here"}
+	assert.Equal(t, eventTime, entry.TimestampFor(TimestampSourceIngestion))
+	assert.Equal(t, eventTime, entry.TimestampFor(TimestampSourceMessage))
+}
@@ -0,0 +1,242 @@
+package log
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// shortWatchDuration is the duration under which an ended watch is counted as short-lived:
+// the API server keeps healthy watches open for 5 to 10 minutes
+const shortWatchDuration = time.Minute
+
+// WatchEvent is the start or end of a watch request
+type WatchEvent struct {
+	Time      time.Time
+	AuditID   string
+	Started   bool
+	UserAgent string
+	User      string
+	Resource  string
+	// Duration is the lifetime of an ended watch, 0 when unknown
+	Duration time.Duration
+}
+
+// WatchClient counts the watches started and ended by one user agent and user
+type WatchClient struct {
+	UserAgent string `json:"user_agent"`
+	User      string `json:"user"`
+	Started   int    `json:"started"`
+	Ended     int    `json:"ended"`
+	// ShortLived counts the ended watches that lasted less than a minute
+	ShortLived          int       `json:"short_lived"`
+	MeanDurationSeconds float64   `json:"mean_duration_seconds,omitempty"`
+	PerMinute           float64   `json:"per_minute"`
+	Resources           []string  `json:"resources"`
+	First               time.Time `json:"first"`
+	Last                time.Time `json:"last"`
+}
+
+// auditWatchEvent holds the fields of an audit event of a watch request
+type auditWatchEvent struct {
+	AuditID                  string    `json:"auditID"`
+	Stage                    string    `json:"stage"`
+	Verb                     string    `json:"verb"`
+	UserAgent                string    `json:"userAgent"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+	StageTimestamp           time.Time `json:"stageTimestamp"`
+	User                     struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ObjectRef struct {
+		Resource string `json:"resource"`
+	} `json:"objectRef"`
+}
+
+// ParseWatch extracts the start (ResponseStarted stage) or end (ResponseComplete stage) of a
+// watch from an audit event, or the end of a watch from an API server httplog line such as
+// `"HTTP" verb="WATCH" URI="/api/v1/pods?watch=true" latency="5m0.001s" userAgent="..."`
+func ParseWatch(entry LogEntry) (WatchEvent, bool) {
+	message := strings.TrimSpace(entry.Message)
+	if strings.HasPrefix(message, "{") {
+		var event auditWatchEvent
+		if err := json.Unmarshal([]byte(message), &event); err != nil || event.Verb != "watch" {
+			return WatchEvent{}, false
+		}
+		watch := WatchEvent{
+			AuditID:   event.AuditID,
+			UserAgent: event.UserAgent,
+			User:      event.User.Username,
+			Resource:  event.ObjectRef.Resource,
+		}
+		switch event.Stage {
+		case "ResponseStarted":
+			watch.Started = true
+			watch.Time = event.RequestReceivedTimestamp
+		case "ResponseComplete":
+			watch.Time = event.StageTimestamp
+			if !event.RequestReceivedTimestamp.IsZero() && !event.StageTimestamp.IsZero() {
+				watch.Duration = event.StageTimestamp.Sub(event.RequestReceivedTimestamp)
+			}
+		default:
+			return WatchEvent{}, false
+		}
+		if watch.Time.IsZero() {
+			watch.Time = entry.Timestamp
+		}
+		watch.Time = watch.Time.UTC()
+		return watch, true
+	}
+
+	if !strings.Contains(message, `verb="WATCH"`) {
+		return WatchEvent{}, false
+	}
+	fields := make(map[string]string)
+	for _, match := range httplogFieldPattern.FindAllStringSubmatch(message, -1) {
+		fields[match[1]] = strings.Trim(match[2], `"`)
+	}
+	if fields["verb"] != "WATCH" {
+		return WatchEvent{}, false
+	}
+	duration, _ := time.ParseDuration(fields["latency"])
+	return WatchEvent{
+		Time:      entry.Timestamp.UTC(),
+		AuditID:   fields["audit-ID"],
+		UserAgent: fields["userAgent"],
+		Resource:  resourceFromURI(fields["URI"]),
+		Duration:  duration,
+	}, true
+}
+
+// resourceFromURI returns the resource of a watch request URI, the last segment of its
+// path, e.g. "pods" for /api/v1/namespaces/default/pods?watch=true
+func resourceFromURI(uri string) string {
+	uriPath, _, _ := strings.Cut(uri, "?")
+	if uriPath == "" {
+		return ""
+	}
+	return path.Base(uriPath)
+}
+
+// GroupWatches counts watch starts and ends per user agent and user, over a window of the
+// given length for the per-minute rate, the busiest clients first. A watch end found in
+// both the audit and API server logs is counted once, combining the fields of both records.
+func GroupWatches(events []WatchEvent, window time.Duration) []WatchClient {
+	type recordKey struct {
+		auditID string
+		started bool
+	}
+	byRecord := make(map[recordKey]int)
+	var unique []WatchEvent
+	for _, event := range events {
+		if event.AuditID == "" {
+			unique = append(unique, event)
+			continue
+		}
+		key := recordKey{event.AuditID, event.Started}
+		if i, seen := byRecord[key]; seen {
+			unique[i] = mergeWatch(unique[i], event)
+			continue
+		}
+		byRecord[key] = len(unique)
+		unique = append(unique, event)
+	}
+
+	type clientKey struct{ userAgent, user string }
+	type client struct {
+		WatchClient
+		resources     map[string]int
+		totalDuration time.Duration
+		timedEnds     int
+	}
+	clients := make(map[clientKey]*client)
+	var ordered []*client
+	for _, event := range unique {
+		key := clientKey{event.UserAgent, event.User}
+		c, exists := clients[key]
+		if !exists {
+			c = &client{
+				WatchClient: WatchClient{UserAgent: event.UserAgent, User: event.User, First: event.Time, Last: event.Time},
+				resources:   make(map[string]int),
+			}
+			clients[key] = c
+			ordered = append(ordered, c)
+		}
+		if event.Started {
+			c.Started++
+		} else {
+			c.Ended++
+			if event.Duration > 0 {
+				c.totalDuration += event.Duration
+				c.timedEnds++
+				if event.Duration < shortWatchDuration {
+					c.ShortLived++
+				}
+			}
+		}
+		if event.Resource != "" {
+			c.resources[event.Resource]++
+		}
+		if event.Time.Before(c.First) {
+			c.First = event.Time
+		}
+		if event.Time.After(c.Last) {
+			c.Last = event.Time
+		}
+	}
+
+	result := make([]WatchClient, 0, len(ordered))
+	for _, c := range ordered {
+		if c.timedEnds > 0 {
+			c.MeanDurationSeconds = (c.totalDuration / time.Duration(c.timedEnds)).Seconds()
+		}
+		if window > 0 {
+			c.PerMinute = float64(c.churn()) / window.Minutes()
+		}
+		c.Resources = make([]string, 0, len(c.resources))
+		for resource := range c.resources {
+			c.Resources = append(c.Resources, resource)
+		}
+		resources := c.resources
+		sort.Slice(c.Resources, func(i, j int) bool {
+			a, b := c.Resources[i], c.Resources[j]
+			if resources[a] != resources[b] {
+				return resources[a] > resources[b]
+			}
+			return a < b
+		})
+		result = append(result, c.WatchClient)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].churn() > result[j].churn()
+	})
+	return result
+}
+
+// churn is the number of watches a client opened or closed, whichever was logged more:
+// the audit policy may only log one of the stages
+func (c WatchClient) churn() int {
+	if c.Started > c.Ended {
+		return c.Started
+	}
+	return c.Ended
+}
+
+// mergeWatch fills the empty fields of one record of a watch from another
+func mergeWatch(a, b WatchEvent) WatchEvent {
+	if a.UserAgent == "" {
+		a.UserAgent = b.UserAgent
+	}
+	if a.User == "" {
+		a.User = b.User
+	}
+	if a.Resource == "" {
+		a.Resource = b.Resource
+	}
+	if a.Duration == 0 {
+		a.Duration = b.Duration
+	}
+	return a
+}
@@ -0,0 +1,85 @@
+package log
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWatch(t *testing.T) {
+	started := LogEntry{Message: `{"auditID":"a1","stage":"ResponseStarted","verb":"watch","userAgent":"operator/v1.2.0",` +
+		`"requestReceivedTimestamp":"2024-01-01T10:00:00Z","stageTimestamp":"2024-01-01T10:00:00.01Z",` +
+		`"user":{"username":"system:serviceaccount:ops:operator"},"objectRef":{"resource":"configmaps"}}`}
+	event, ok := ParseWatch(started)
+	assert.True(t, ok)
+	assert.Equal(t, WatchEvent{
+		Time:      time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		AuditID:   "a1",
+		Started:   true,
+		UserAgent: "operator/v1.2.0",
+		User:      "system:serviceaccount:ops:operator",
+		Resource:  "configmaps",
+	}, event)
+
+	completed := LogEntry{Message: `{"auditID":"a1","stage":"ResponseComplete","verb":"watch","userAgent":"operator/v1.2.0",` +
+		`"requestReceivedTimestamp":"2024-01-01T10:00:00Z","stageTimestamp":"2024-01-01T10:00:05Z",` +
+		`"user":{"username":"system:serviceaccount:ops:operator"},"objectRef":{"resource":"configmaps"}}`}
+	event, ok = ParseWatch(completed)
+	assert.True(t, ok)
+	assert.False(t, event.Started)
+	assert.Equal(t, 5*time.Second, event.Duration)
+	assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC), event.Time)
+
+	httplog := LogEntry{Timestamp: time.Date(2024, 1, 1, 10, 0, 5, 0, time.UTC),
+		Message: `I0101 10:00:05.000000 10 httplog.go:132] "HTTP" verb="WATCH" URI="/api/v1/namespaces/ops/configmaps?watch=true" latency="5.0012s" userAgent="operator/v1.2.0" audit-ID="a1" resp=200`}
+	event, ok = ParseWatch(httplog)
+	assert.True(t, ok)
+	assert.Equal(t, "configmaps", event.Resource)
+	assert.Equal(t, "a1", event.AuditID)
+	assert.Equal(t, 5001200*time.Microsecond, event.Duration)
+
+	for _, message := range []string{
+		`{"auditID":"a2","stage":"ResponseComplete","verb":"list"}`,
+		`{"auditID":"a3","stage":"RequestReceived","verb":"watch"}`,
+		`I0101 10:00:05.000000 10 httplog.go:132] "HTTP" verb="LIST" URI="/api/v1/pods" resp=200`,
+	} {
+		_, ok := ParseWatch(LogEntry{Message: message})
+		assert.False(t, ok, message)
+	}
+}
+
+func TestGroupWatches(t *testing.T) {
+	at := func(second int) time.Time { return time.Date(2024, 1, 1, 10, 0, second, 0, time.UTC) }
+	operator := func(second int, auditID string, started bool, duration time.Duration) WatchEvent {
+		return WatchEvent{Time: at(second), AuditID: auditID, Started: started, UserAgent: "operator/v1.2.0",
+			User: "operator", Resource: "configmaps", Duration: duration}
+	}
+	events := []WatchEvent{
+		operator(0, "a1", true, 0),
+		operator(5, "a1", false, 5*time.Second),
+		{Time: at(5), AuditID: "a1", UserAgent: "operator/v1.2.0", Resource: "configmaps", Duration: 5 * time.Second},
+		operator(6, "a2", true, 0),
+		operator(20, "a2", false, 15*time.Second),
+		operator(21, "a3", true, 0),
+		{Time: at(30), AuditID: "k1", Started: true, UserAgent: "kubelet/v1.29.0", User: "system:node:a", Resource: "pods"},
+	}
+
+	clients := GroupWatches(events, 2*time.Minute)
+	assert.Len(t, clients, 2)
+	assert.Equal(t, WatchClient{
+		UserAgent:           "operator/v1.2.0",
+		User:                "operator",
+		Started:             3,
+		Ended:               2,
+		ShortLived:          2,
+		MeanDurationSeconds: 10,
+		PerMinute:           1.5,
+		Resources:           []string{"configmaps"},
+		First:               at(0),
+		Last:                at(21),
+	}, clients[0])
+	assert.Equal(t, "kubelet/v1.29.0", clients[1].UserAgent)
+	assert.Equal(t, 0.5, clients[1].PerMinute)
+	assert.Zero(t, clients[1].MeanDurationSeconds)
+}
@@ -0,0 +1,190 @@
+package log
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// defaultWrapWidth is used when the terminal width cannot be determined
+const defaultWrapWidth = 120
+
+// wrapIndent is the hanging indentation of continuation lines
+const wrapIndent = "    "
+
+// ansiReset ends all active SGR attributes
+const ansiReset = "\x1b[0m"
+
+// TerminalWidth returns the width of stdout, falling back to $COLUMNS and then 120 columns
+func TerminalWidth() int {
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultWrapWidth
+}
+
+// cell is a visible rune together with the escape sequences preceding it
+type cell struct {
+	escapes string
+	r       rune
+}
+
+// splitCells splits a line into visible cells, keeping ANSI escape sequences
+// attached to the following rune. Escapes after the last rune are returned separately.
+func splitCells(line string) ([]cell, string) {
+	var cells []cell
+	var escapes strings.Builder
+	for i := 0; i < len(line); {
+		if line[i] == '\x1b' && i+1 < len(line) && line[i+1] == '[' {
+			// CSI sequence: ESC [ parameters final-byte
+			j := i + 2
+			for j < len(line) && (line[j] < 0x40 || line[j] > 0x7e) {
+				j++
+			}
+			if j < len(line) {
+				j++
+			}
+			escapes.WriteString(line[i:j])
+			i = j
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		cells = append(cells, cell{escapes: escapes.String(), r: r})
+		escapes.Reset()
+		i += size
+	}
+	return cells, escapes.String()
+}
+
+// VisibleWidth returns the number of visible runes in a string, ignoring ANSI escape sequences
+func VisibleWidth(s string) int {
+	cells, _ := splitCells(s)
+	return len(cells)
+}
+
+// updateSGRState tracks the SGR sequences active after the given escapes
+func updateSGRState(active []string, escapes string) []string {
+	for escapes != "" {
+		end := strings.IndexByte(escapes[1:], '\x1b') + 1
+		if end == 0 {
+			end = len(escapes)
+		}
+		sequence := escapes[:end]
+		escapes = escapes[end:]
+
+		if !strings.HasSuffix(sequence, "m") {
+			continue
+		}
+		if sequence == ansiReset || sequence == "\x1b[m" {
+			active = active[:0]
+			continue
+		}
+		active = append(active, sequence)
+	}
+	return active
+}
+
+// Truncate shortens each line of s to at most width visible characters, marking cut
+// lines with an ellipsis. ANSI colors are preserved and reset after the cut.
+func Truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		cells, trailing := splitCells(line)
+		if len(cells) <= width {
+			continue
+		}
+
+		var b strings.Builder
+		colored := false
+		for _, c := range cells[:width-1] {
+			if c.escapes != "" {
+				colored = true
+			}
+			b.WriteString(c.escapes)
+			b.WriteRune(c.r)
+		}
+		b.WriteString("…")
+		if colored || trailing != "" {
+			b.WriteString(ansiReset)
+		}
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Wrap breaks each line of s into lines of at most width visible characters,
+// preferring to break at spaces. Continuation lines get a hanging indentation
+// and active ANSI colors are carried over to them.
+func Wrap(s string, width int) string {
+	if width <= len(wrapIndent) {
+		return s
+	}
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = wrapLine(line, width)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine wraps a single line without newlines
+func wrapLine(line string, width int) string {
+	cells, trailing := splitCells(line)
+	if len(cells) <= width {
+		return line
+	}
+
+	var b strings.Builder
+	var active []string
+	start := 0
+	limit := width
+	for len(cells)-start > limit {
+		end := start + limit
+
+		// Prefer breaking at the last space of the segment
+		for j := end; j > start; j-- {
+			if cells[j].r == ' ' {
+				end = j
+				break
+			}
+		}
+
+		for _, c := range cells[start:end] {
+			b.WriteString(c.escapes)
+			active = updateSGRState(active, c.escapes)
+			b.WriteRune(c.r)
+		}
+		if len(active) > 0 {
+			b.WriteString(ansiReset)
+		}
+
+		// Drop the space the line was broken at, keeping its escapes
+		start = end
+		if cells[start].r == ' ' {
+			active = updateSGRState(active, cells[start].escapes)
+			start++
+		}
+
+		b.WriteString("\n")
+		b.WriteString(wrapIndent)
+		b.WriteString(strings.Join(active, ""))
+		limit = width - len(wrapIndent)
+	}
+
+	for _, c := range cells[start:] {
+		b.WriteString(c.escapes)
+		b.WriteRune(c.r)
+	}
+	b.WriteString(trailing)
+	return b.String()
+}
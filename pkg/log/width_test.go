@@ -0,0 +1,64 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	red   = "\x1b[31m"
+	reset = "\x1b[0m"
+)
+
+func TestVisibleWidth(t *testing.T) {
+	assert.Equal(t, 5, VisibleWidth("hello"))
+	assert.Equal(t, 5, VisibleWidth(red+"hello"+reset))
+	assert.Equal(t, 3, VisibleWidth("日本語"))
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		expected string
+	}{
+		{name: "short line", input: "hello", width: 10, expected: "hello"},
+		{name: "plain", input: "hello world", width: 6, expected: "hello…"},
+		{name: "colored", input: red + "hello world" + reset, width: 6, expected: red + "hello…" + reset},
+		{name: "multi-line", input: "hello world\nok", width: 6, expected: "hello…\nok"},
+		{name: "disabled", input: "hello world", width: 0, expected: "hello world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Truncate(tt.input, tt.width))
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		width    int
+		expected string
+	}{
+		{name: "short line", input: "hello", width: 10, expected: "hello"},
+		{name: "break at space", input: "hello wonderful world", width: 14, expected: "hello\n    wonderful\n    world"},
+		{name: "hard break", input: "abcdefghijklmnop", width: 10, expected: "abcdefghij\n    klmnop"},
+		{
+			name:     "color carried over",
+			input:    red + "hello wonderful" + reset + " world",
+			width:    14,
+			expected: red + "hello" + reset + "\n    " + red + "wonderful" + reset + "\n    world",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Wrap(tt.input, tt.width))
+		})
+	}
+}